@@ -0,0 +1,8 @@
+#!/bin/bash
+# Copyright 2023 Intrinsic Innovation LLC
+
+# Prints the workspace status for stamped builds (--config=stamp). Skill images built with stamping
+# carry these values as build info labels, see skill.bzl.
+
+echo "STABLE_GIT_COMMIT $(git rev-parse HEAD 2>/dev/null)"
+echo "STABLE_INTRINSIC_SDK_VERSION $(git describe --tags --always 2>/dev/null)"
@@ -0,0 +1,131 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package imageutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// archiveCacheDirName is the directory in the user cache directory in which the installer
+// parameters of image archives are cached.
+const archiveCacheDirName = "intrinsic/skill_archives"
+
+var (
+	archiveCacheMu sync.Mutex
+	// archiveCacheDir is the directory of the archive cache. Empty disables the cache.
+	archiveCacheDir = defaultArchiveCacheDir()
+)
+
+func defaultArchiveCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, archiveCacheDirName)
+}
+
+// SetArchiveCacheEnabled enables or disables the on-disk cache of the installer parameters of
+// image archives, which makes repeated lookups of the skill ID of an unchanged archive or build
+// target fast. The cache is enabled by default, commands disable it with --no_cache.
+func SetArchiveCacheEnabled(enabled bool) {
+	archiveCacheMu.Lock()
+	defer archiveCacheMu.Unlock()
+	if enabled {
+		archiveCacheDir = defaultArchiveCacheDir()
+	} else {
+		archiveCacheDir = ""
+	}
+}
+
+// cachedArchiveParams is an entry of the archive cache.
+type cachedArchiveParams struct {
+	SkillID   string `json:"skillId"`
+	ImageName string `json:"imageName,omitempty"`
+}
+
+// archiveDigest returns the hex encoded sha256 digest of the content of the archive at path.
+func archiveDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// skillInstallerParamsFromArchive returns the installer parameters of the image archive at path.
+// They are cached by the digest of the archive, so that an archive which bazel did not rebuild is
+// not parsed again. Cache failures are logged and fall back to reading the archive.
+func skillInstallerParamsFromArchive(path string) (*SkillInstallerParams, error) {
+	archiveCacheMu.Lock()
+	dir := archiveCacheDir
+	archiveCacheMu.Unlock()
+
+	var entryPath string
+	if dir != "" {
+		digest, err := archiveDigest(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read archive %q: %v", path, err)
+		}
+		entryPath = filepath.Join(dir, digest+".json")
+		if b, err := os.ReadFile(entryPath); err == nil {
+			entry := &cachedArchiveParams{}
+			if err := json.Unmarshal(b, entry); err == nil && entry.SkillID != "" {
+				return &SkillInstallerParams{SkillID: entry.SkillID, ImageName: entry.ImageName}, nil
+			}
+		}
+	}
+
+	image, err := ReadImage(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read image: %v", err)
+	}
+	params, err := GetSkillInstallerParams(image)
+	if err != nil {
+		return nil, fmt.Errorf("could not extract installer parameters: %v", err)
+	}
+	if entryPath != "" {
+		entry := &cachedArchiveParams{SkillID: params.SkillID, ImageName: params.ImageName}
+		if err := writeArchiveCacheEntry(entryPath, entry); err != nil {
+			log.Printf("Warning: could not cache the installer parameters of %q: %v", path, err)
+		}
+	}
+	return params, nil
+}
+
+// writeArchiveCacheEntry writes entry atomically, so that concurrent invocations never read a
+// partial entry.
+func writeArchiveCacheEntry(path string, entry *cachedArchiveParams) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
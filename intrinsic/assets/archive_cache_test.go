@@ -0,0 +1,42 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package imageutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSkillInstallerParamsFromArchiveCache(t *testing.T) {
+	dir := t.TempDir()
+	archiveCacheDir = filepath.Join(dir, "cache")
+	defer SetArchiveCacheEnabled(true)
+
+	// The archive is not a valid image, so it can only be resolved from the cache.
+	archive := filepath.Join(dir, "skill.tar")
+	if err := os.WriteFile(archive, []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := archiveDigest(archive)
+	if err != nil {
+		t.Fatalf("archiveDigest() failed: %v", err)
+	}
+	entry := &cachedArchiveParams{SkillID: "com.example.skill"}
+	if err := writeArchiveCacheEntry(filepath.Join(archiveCacheDir, digest+".json"), entry); err != nil {
+		t.Fatalf("writeArchiveCacheEntry() failed: %v", err)
+	}
+
+	params, err := skillInstallerParamsFromArchive(archive)
+	if err != nil {
+		t.Fatalf("skillInstallerParamsFromArchive() failed: %v", err)
+	}
+	if params.SkillID != "com.example.skill" {
+		t.Errorf("skillInstallerParamsFromArchive() returned skill ID %q, want %q", params.SkillID, "com.example.skill")
+	}
+
+	SetArchiveCacheEnabled(false)
+	if _, err := skillInstallerParamsFromArchive(archive); err == nil {
+		t.Errorf("skillInstallerParamsFromArchive() with disabled cache succeeded for an invalid archive, want error")
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package imageutils
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// maxBuildEventSize is the maximum size of a single event in a build event protocol JSON file.
+const maxBuildEventSize = 64 * 1024 * 1024
+
+// buildEventFile is a file reported in the build event protocol.
+type buildEventFile struct {
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+}
+
+type buildEventFileSetID struct {
+	ID string `json:"id"`
+}
+
+// buildEvent contains the fields of a build event protocol event which are needed to find the
+// output files of a target. See
+// https://bazel.build/remote/bep for the format written by --build_event_json_file.
+type buildEvent struct {
+	ID struct {
+		TargetCompleted *struct {
+			Label string `json:"label"`
+		} `json:"targetCompleted"`
+		NamedSet *buildEventFileSetID `json:"namedSet"`
+	} `json:"id"`
+	Completed *struct {
+		Success     bool `json:"success"`
+		OutputGroup []struct {
+			Name     string                `json:"name"`
+			FileSets []buildEventFileSetID `json:"fileSets"`
+		} `json:"outputGroup"`
+		ImportantOutput []buildEventFile `json:"importantOutput"`
+	} `json:"completed"`
+	NamedSetOfFiles *struct {
+		Files    []buildEventFile      `json:"files"`
+		FileSets []buildEventFileSetID `json:"fileSets"`
+	} `json:"namedSetOfFiles"`
+}
+
+// FetchBuildOutput makes the image archive built for target available locally when the build did
+// not run on this machine, e.g., on a remote build execution or CI system, such that the local
+// `bazel cquery --output=files` path does not exist.
+//
+// source is either an http(s) URL of the built archive, or the path of a build event protocol
+// JSON file written by `bazel build --build_event_json_file`, which is searched for the output of
+// target. Remote outputs are downloaded to a temporary file, which is removed by the returned
+// cleanup function.
+func FetchBuildOutput(ctx context.Context, target string, source string) (path string, cleanup func(), err error) {
+	if isHTTPURL(source) {
+		return downloadBuildOutput(ctx, source)
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not open build event file: %w", err)
+	}
+	defer f.Close()
+	uri, err := outputURIFromBuildEvents(f, target)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not find output of %q in build event file %q: %w", target, source, err)
+	}
+
+	switch {
+	case isHTTPURL(uri):
+		return downloadBuildOutput(ctx, uri)
+	case strings.HasPrefix(uri, "file://"):
+		u, err := url.Parse(uri)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not parse output uri %q: %w", uri, err)
+		}
+		if _, err := os.Stat(u.Path); err != nil {
+			return "", nil, fmt.Errorf("output of %q does not exist on this machine, pass the URL of the uploaded artifact instead: %w", target, err)
+		}
+		return u.Path, func() {}, nil
+	default:
+		// E.g., bytestream:// uris of outputs which were only written to the remote cache.
+		return "", nil, fmt.Errorf("output %q of %q is not a file or http(s) uri, upload it as a CI artifact and pass its URL instead", uri, target)
+	}
+}
+
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// normalizeLabel strips the repository prefix of labels in the main repository, which bazel
+// reports as "@//pkg:name" or "@@//pkg:name" depending on its version.
+func normalizeLabel(label string) string {
+	return strings.TrimLeft(label, "@")
+}
+
+// outputURIFromBuildEvents returns the uri of the single .tar file in the default output group of
+// target.
+func outputURIFromBuildEvents(r io.Reader, target string) (string, error) {
+	target = normalizeLabel(target)
+	fileSets := map[string]*buildEvent{}
+	var completed *buildEvent
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxBuildEventSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		event := &buildEvent{}
+		if err := json.Unmarshal(line, event); err != nil {
+			return "", fmt.Errorf("could not parse build event: %w", err)
+		}
+		switch {
+		case event.ID.NamedSet != nil && event.NamedSetOfFiles != nil:
+			fileSets[event.ID.NamedSet.ID] = event
+		case event.ID.TargetCompleted != nil && normalizeLabel(event.ID.TargetCompleted.Label) == target:
+			completed = event
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("could not read build events: %w", err)
+	}
+
+	if completed == nil || completed.Completed == nil {
+		return "", fmt.Errorf("target was not built")
+	}
+	if !completed.Completed.Success {
+		return "", fmt.Errorf("target failed to build")
+	}
+
+	files := completed.Completed.ImportantOutput
+	visited := map[string]bool{}
+	var collect func(ids []buildEventFileSetID)
+	collect = func(ids []buildEventFileSetID) {
+		for _, id := range ids {
+			set, ok := fileSets[id.ID]
+			if !ok || visited[id.ID] {
+				continue
+			}
+			visited[id.ID] = true
+			files = append(files, set.NamedSetOfFiles.Files...)
+			collect(set.NamedSetOfFiles.FileSets)
+		}
+	}
+	for _, group := range completed.Completed.OutputGroup {
+		if group.Name == "default" {
+			collect(group.FileSets)
+		}
+	}
+
+	uris := map[string]bool{}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name, ".tar") {
+			uris[f.URI] = true
+		}
+	}
+	if len(uris) != 1 {
+		return "", fmt.Errorf("expected a single .tar output, got %d", len(uris))
+	}
+	for uri := range uris {
+		return uri, nil
+	}
+	return "", nil // Not reached.
+}
+
+// downloadBuildOutput downloads an image archive to a temporary file.
+func downloadBuildOutput(ctx context.Context, address string) (string, func(), error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not create request for %q: %w", address, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not download %q: %w", address, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("could not download %q: %s", address, resp.Status)
+	}
+
+	f, err := os.CreateTemp("", "inctl-build-output-*.tar")
+	if err != nil {
+		return "", nil, fmt.Errorf("could not create temporary file: %w", err)
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("could not download %q: %w", address, err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("could not write %q: %w", f.Name(), err)
+	}
+	return f.Name(), cleanup, nil
+}
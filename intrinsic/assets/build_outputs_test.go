@@ -0,0 +1,33 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package imageutils
+
+import (
+	"strings"
+	"testing"
+)
+
+const testBuildEvents = `{"id":{"started":{}},"started":{"command":"build"}}
+{"id":{"namedSet":{"id":"1"}},"namedSetOfFiles":{"files":[{"name":"abc/skill.tar","uri":"file:///remote/bazel-out/k8-opt/bin/abc/skill.tar"}]}}
+{"id":{"namedSet":{"id":"0"}},"namedSetOfFiles":{"files":[{"name":"abc/skill.json","uri":"file:///remote/bazel-out/k8-opt/bin/abc/skill.json"}],"fileSets":[{"id":"1"}]}}
+{"id":{"targetCompleted":{"label":"@//abc:skill.tar","configuration":{"id":"abc"}}},"completed":{"success":true,"outputGroup":[{"name":"default","fileSets":[{"id":"0"}]}]}}
+{"id":{"targetCompleted":{"label":"//abc:broken.tar","configuration":{"id":"abc"}}},"completed":{"success":false}}
+`
+
+func TestOutputURIFromBuildEvents(t *testing.T) {
+	got, err := outputURIFromBuildEvents(strings.NewReader(testBuildEvents), "//abc:skill.tar")
+	if err != nil {
+		t.Fatalf("outputURIFromBuildEvents() failed: %v", err)
+	}
+	if want := "file:///remote/bazel-out/k8-opt/bin/abc/skill.tar"; got != want {
+		t.Errorf("outputURIFromBuildEvents() = %q, want %q", got, want)
+	}
+}
+
+func TestOutputURIFromBuildEventsErrors(t *testing.T) {
+	for _, target := range []string{"//abc:broken.tar", "//abc:missing.tar"} {
+		if _, err := outputURIFromBuildEvents(strings.NewReader(testBuildEvents), target); err == nil {
+			t.Errorf("outputURIFromBuildEvents(%q) succeeded, want error", target)
+		}
+	}
+}
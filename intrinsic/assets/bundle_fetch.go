@@ -0,0 +1,120 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package bundleio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const (
+	// httpsPrefix marks bundle references which are downloaded over HTTPS.
+	httpsPrefix = "https://"
+	// ociPrefix marks bundle references to OCI artifacts in a container registry.
+	ociPrefix = "oci://"
+)
+
+// IsRemote returns whether ref refers to a bundle which Fetch downloads, i.e., an https:// URL or
+// an oci:// artifact reference.
+func IsRemote(ref string) bool {
+	return strings.HasPrefix(ref, httpsPrefix) || strings.HasPrefix(ref, ociPrefix)
+}
+
+// FetchOpts contains the clients used to download remote bundles.
+type FetchOpts struct {
+	// HTTPClient downloads https:// references. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+	// RemoteOptions are used to pull oci:// references, e.g., to authenticate with the registry.
+	RemoteOptions []remote.Option
+}
+
+// Fetch makes the bundle referenced by ref available on disk and returns its path, so that it can
+// be passed to ReadService or ProcessService. ref is one of:
+//   - a local path, which is returned unchanged;
+//   - an https:// URL, which is downloaded;
+//   - an OCI artifact reference oci://registry/repo@digest (or :tag) with a single layer, which
+//     holds the bundle archive.
+//
+// The returned cleanup function removes downloaded files. It must be called once the bundle is no
+// longer needed.
+func Fetch(ctx context.Context, ref string, opts FetchOpts) (string, func(), error) {
+	var open func(context.Context, string, FetchOpts) (io.ReadCloser, error)
+	switch {
+	case strings.HasPrefix(ref, httpsPrefix):
+		open = openHTTPS
+	case strings.HasPrefix(ref, ociPrefix):
+		open = openOCI
+	default:
+		return ref, func() {}, nil
+	}
+
+	r, err := open(ctx, ref, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+
+	f, err := os.CreateTemp("", "bundle-*.tar")
+	if err != nil {
+		return "", nil, fmt.Errorf("could not create file for bundle %q: %v", ref, err)
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("could not download bundle %q: %v", ref, err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("could not write bundle %q: %v", ref, err)
+	}
+	return f.Name(), cleanup, nil
+}
+
+func openHTTPS(ctx context.Context, ref string, opts FetchOpts) (io.ReadCloser, error) {
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle URL %q: %v", ref, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not download bundle %q: %v", ref, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("could not download bundle %q: %s", ref, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func openOCI(ctx context.Context, ref string, opts FetchOpts) (io.ReadCloser, error) {
+	r, err := name.ParseReference(strings.TrimPrefix(ref, ociPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle reference %q: %v", ref, err)
+	}
+	remoteOpts := append([]remote.Option{remote.WithContext(ctx)}, opts.RemoteOptions...)
+	img, err := remote.Image(r, remoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not pull bundle %q: %v", ref, err)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("could not read layers of bundle %q: %v", ref, err)
+	}
+	if len(layers) != 1 {
+		return nil, fmt.Errorf("bundle artifact %q must have exactly one layer, got %d", ref, len(layers))
+	}
+	// The layer holds the bundle archive as is, so read the blob without decompressing it.
+	return layers[0].Compressed()
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package bundleio
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestFetchLocal(t *testing.T) {
+	path, cleanup, err := Fetch(context.Background(), "abc/bundle.tar", FetchOpts{})
+	if err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	cleanup()
+	if path != "abc/bundle.tar" {
+		t.Errorf("Fetch() = %q, want the local path unchanged", path)
+	}
+}
+
+func TestFetchHTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bundle.tar" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("bundle"))
+	}))
+	defer server.Close()
+	opts := FetchOpts{HTTPClient: server.Client()}
+
+	path, cleanup, err := Fetch(context.Background(), server.URL+"/bundle.tar", opts)
+	if err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read fetched bundle: %v", err)
+	}
+	if string(got) != "bundle" {
+		t.Errorf("Fetch() wrote %q, want %q", got, "bundle")
+	}
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cleanup did not remove %q", path)
+	}
+
+	if _, _, err := Fetch(context.Background(), server.URL+"/missing.tar", opts); err == nil {
+		t.Errorf("Fetch() succeeded for a missing bundle, want error")
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"archive/tar"
 	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
@@ -22,9 +24,103 @@ import (
 )
 
 const (
-	serviceManifestPathInTar  = "service_manifest.binarypb"
+	serviceManifestPathInTar = "service_manifest.binarypb"
+	formatVersionPathInTar   = "bundle_format_version"
 )
 
+// FormatVersion identifies the layout of a bundle archive.
+type FormatVersion int
+
+const (
+	// FormatVersionLegacy is the original bundle layout, which has no format
+	// version entry.  It is understood by all SDK releases and clusters.
+	FormatVersionLegacy FormatVersion = 1
+	// FormatVersion2 records the format version in the archive so that readers
+	// can reject bundles they do not understand.
+	FormatVersion2 FormatVersion = 2
+
+	// CurrentFormatVersion is the newest format that this package can read and
+	// write.
+	CurrentFormatVersion = FormatVersion2
+)
+
+// UnsupportedFormatVersionError is returned when a bundle was written in a
+// format that is newer than the one supported by this SDK.
+type UnsupportedFormatVersionError struct {
+	Version FormatVersion
+}
+
+func (e *UnsupportedFormatVersionError) Error() string {
+	return fmt.Sprintf("bundle requires a newer SDK: bundle format version is %d, but this SDK only supports versions up to %d", e.Version, CurrentFormatVersion)
+}
+
+// parseFormatVersion parses the contents of the format version entry.
+func parseFormatVersion(b []byte) (FormatVersion, error) {
+	v, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid bundle format version %q: %v", b, err)
+	}
+	version := FormatVersion(v)
+	if version < FormatVersionLegacy {
+		return 0, fmt.Errorf("invalid bundle format version %d", v)
+	}
+	if version > CurrentFormatVersion {
+		return 0, &UnsupportedFormatVersionError{Version: version}
+	}
+	return version, nil
+}
+
+// readFormatVersion scans the tar file for the format version entry and checks
+// that it is supported.  Bundles without the entry are reported as
+// FormatVersionLegacy.  The reader is left at the end of the archive.
+func readFormatVersion(r io.Reader) (FormatVersion, error) {
+	t := tar.NewReader(r)
+	if err := tartooling.SeekTo(t, formatVersionPathInTar); err == io.EOF {
+		return FormatVersionLegacy, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("getting next file failed: %v", err)
+	}
+	b, err := io.ReadAll(t)
+	if err != nil {
+		return 0, fmt.Errorf("error reading %q: %v", formatVersionPathInTar, err)
+	}
+	return parseFormatVersion(b)
+}
+
+// negotiateFormatVersion reads the format version of the bundle in f, rewinds
+// f and adds a handler for the version entry to handlers if the bundle has
+// one.
+func negotiateFormatVersion(f io.ReadSeeker, handlers map[string]handler) (FormatVersion, error) {
+	version, err := readFormatVersion(f)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("could not seek: %v", err)
+	}
+	if version != FormatVersionLegacy {
+		handlers[formatVersionPathInTar] = ignoreHandler // already read this.
+	}
+	return version, nil
+}
+
+// ReadFormatVersion returns the format version of the bundle archive at path.
+// It returns an UnsupportedFormatVersionError if the bundle requires a newer
+// SDK.
+func ReadFormatVersion(path string) (FormatVersion, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("could not open %q: %v", path, err)
+	}
+	defer f.Close()
+
+	version, err := readFormatVersion(f)
+	if err != nil {
+		return 0, fmt.Errorf("error in tar file %q: %w", path, err)
+	}
+	return version, nil
+}
+
 type handler func(io.Reader) error
 type fallbackHandler func(string, io.Reader) error
 
@@ -32,6 +128,12 @@ type fallbackHandler func(string, io.Reader) error
 // filenames.  fallback can be nil.  Returns an error if all handlers in
 // handlers are not invoked.  It ignores all non-regular files.
 func walkTarFile(t *tar.Reader, handlers map[string]handler, fallback fallbackHandler) error {
+	return walkTarFileObserved(t, handlers, fallback, nil)
+}
+
+// walkTarFileObserved is walkTarFile, but also calls observe, if not nil, with
+// the header of every regular file before it is handled.
+func walkTarFileObserved(t *tar.Reader, handlers map[string]handler, fallback fallbackHandler, observe func(*tar.Header)) error {
 	for len(handlers) > 0 || fallback != nil {
 		hdr, err := t.Next()
 		if err == io.EOF {
@@ -43,6 +145,9 @@ func walkTarFile(t *tar.Reader, handlers map[string]handler, fallback fallbackHa
 		if hdr.Typeflag != tar.TypeReg {
 			continue
 		}
+		if observe != nil {
+			observe(hdr)
+		}
 
 		n := hdr.Name
 		if h, ok := handlers[n]; ok {
@@ -148,10 +253,12 @@ func makeServiceAssetHandlers(manifest *smpb.ServiceManifest, opts ProcessServic
 			handlers[p] = ignoreHandler
 		} else {
 			handlers[p] = func(r io.Reader) error {
+				start := time.Now()
 				img, err := opts.ImageProcessor(manifest.GetMetadata().GetId(), p, r)
 				if err != nil {
 					return fmt.Errorf("error processing image: %v", err)
 				}
+				opts.Report.addImage(p, img, time.Since(start))
 				if processedAssets.Images == nil {
 					processedAssets.Images = make(map[string]*ipb.Image)
 				}
@@ -173,6 +280,9 @@ func ReadService(path string) (*smpb.ServiceManifest, map[string][]byte, error)
 	defer f.Close()
 
 	m, handlers := makeOnlyServiceManifestHandlers()
+	if _, err := negotiateFormatVersion(f, handlers); err != nil {
+		return nil, nil, fmt.Errorf("error in tar file %q: %w", path, err)
+	}
 	inlined, fallback := makeCollectInlinedFallbackHandler()
 	if err := walkTarFile(tar.NewReader(f), handlers, fallback); err != nil {
 		return nil, nil, fmt.Errorf("error in tar file %q: %v", path, err)
@@ -190,6 +300,9 @@ func ReadServiceManifest(path string) (*smpb.ServiceManifest, error) {
 	defer f.Close()
 
 	m, handlers := makeOnlyServiceManifestHandlers()
+	if _, err := negotiateFormatVersion(f, handlers); err != nil {
+		return nil, fmt.Errorf("error in tar file %q: %w", path, err)
+	}
 	if err := walkTarFile(tar.NewReader(f), handlers, nil); err != nil {
 		return nil, fmt.Errorf("error in tar file %q: %v", path, err)
 	}
@@ -201,13 +314,36 @@ func ReadServiceManifest(path string) (*smpb.ServiceManifest, error) {
 // processed as well as the name of the specific image.  It is expected to
 // upload the image and produce a usable image spec.  The reader points to an
 // image archive.  This may be invoked multiple times.  Images are ignored if it
-// is not specified.
+// is not specified.  A processor may also only compute the image spec without
+// uploading, to produce the processed manifest before pushing any bytes.
 type ImageProcessor func(idProto *idpb.Id, filename string, r io.Reader) (*ipb.Image, error)
 
 // ProcessServiceOpts contains the necessary handlers to generate a processed
 // service manifest.
 type ProcessServiceOpts struct {
 	ImageProcessor
+	// Report, if not nil, is filled with the files, images and durations of
+	// the processing.
+	Report *ProcessingReport
+}
+
+// serviceFileKinds returns the kinds of the files which the manifest
+// references, keyed by filename.
+func serviceFileKinds(manifest *smpb.ServiceManifest) map[string]string {
+	kinds := map[string]string{
+		serviceManifestPathInTar: FileKindManifest,
+		formatVersionPathInTar:   FileKindFormatVersion,
+	}
+	if p := manifest.GetAssets().GetDefaultConfigurationFilename(); p != "" {
+		kinds[p] = FileKindDefaultConfiguration
+	}
+	if p := manifest.GetAssets().GetParameterDescriptorFilename(); p != "" {
+		kinds[p] = FileKindDescriptors
+	}
+	for _, p := range manifest.GetAssets().GetImageFilenames() {
+		kinds[p] = FileKindImage
+	}
+	return kinds
 }
 
 // ProcessService creates a processed manifest from a bundle on disk using the
@@ -223,6 +359,17 @@ func ProcessService(path string, opts ProcessServiceOpts) (*smpb.ProcessedServic
 
 	// Read the manifest and then reset the file once we have the information
 	// about the bundle we're going to process.
+	start := time.Now()
+	version, err := readFormatVersion(f)
+	if err != nil {
+		return nil, fmt.Errorf("error in tar file %q: %w", path, err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("could not seek in %q: %v", path, err)
+	}
+	opts.Report.addStep("read format version", start)
+
+	start = time.Now()
 	manifest, handlers := makeOnlyServiceManifestHandlers()
 	if err := walkTarFile(tar.NewReader(f), handlers, nil); err != nil {
 		return nil, fmt.Errorf("error in tar file %q: %v", path, err)
@@ -230,16 +377,29 @@ func ProcessService(path string, opts ProcessServiceOpts) (*smpb.ProcessedServic
 	if _, err := f.Seek(0, io.SeekStart); err != nil {
 		return nil, fmt.Errorf("could not seek in %q: %v", path, err)
 	}
+	opts.Report.addStep("read manifest", start)
 
 	// Initialize handlers for when we walk through the file again now that we
 	// know what we're looking for, but error on unexpected files this time.
+	start = time.Now()
 	processedAssets, handlers := makeServiceAssetHandlers(manifest, opts)
+	if version != FormatVersionLegacy {
+		handlers[formatVersionPathInTar] = ignoreHandler // already read this.
+	}
 	fallback := func(n string, r io.Reader) error {
 		return fmt.Errorf("unexpected file %q", n)
 	}
-	if err := walkTarFile(tar.NewReader(f), handlers, fallback); err != nil {
+	var observe func(*tar.Header)
+	if opts.Report != nil {
+		kinds := serviceFileKinds(manifest)
+		observe = func(hdr *tar.Header) {
+			opts.Report.Files = append(opts.Report.Files, FileReport{Name: hdr.Name, SizeBytes: hdr.Size, Kind: kinds[hdr.Name]})
+		}
+	}
+	if err := walkTarFileObserved(tar.NewReader(f), handlers, fallback, observe); err != nil {
 		return nil, fmt.Errorf("error in tar file %q: %v", path, err)
 	}
+	opts.Report.addStep("process assets", start)
 
 	return &smpb.ProcessedServiceManifest{
 		Metadata:   manifest.GetMetadata(),
@@ -296,6 +456,25 @@ type WriteServiceOpts struct {
 	Descriptors *descriptorpb.FileDescriptorSet
 	Config      *anypb.Any
 	ImageTars   []string
+	// FormatVersion is the bundle format to write.  Defaults to
+	// FormatVersionLegacy, which clusters running older releases can install.
+	// Bundles in newer formats are rejected by older readers.
+	FormatVersion FormatVersion
+}
+
+// writeFormatVersion adds the format version entry to the tar writer.  Nothing
+// is written for the legacy format.
+func writeFormatVersion(version FormatVersion, tw *tar.Writer) error {
+	if version == 0 {
+		version = FormatVersionLegacy
+	}
+	if version < FormatVersionLegacy || version > CurrentFormatVersion {
+		return fmt.Errorf("unsupported bundle format version %d", version)
+	}
+	if version == FormatVersionLegacy {
+		return nil
+	}
+	return tartooling.AddBytes([]byte(strconv.Itoa(int(version))), tw, formatVersionPathInTar)
 }
 
 // WriteService creates a tar archive at the specified path with the details
@@ -308,6 +487,11 @@ func WriteService(path string, opts WriteServiceOpts) error {
 	var tarBuf bytes.Buffer
 	tw := tar.NewWriter(&tarBuf)
 
+	// The version is written first so that readers can find it quickly.
+	if err := writeFormatVersion(opts.FormatVersion, tw); err != nil {
+		return fmt.Errorf("unable to write format version to bundle: %v", err)
+	}
+
 	opts.Manifest.Assets = new(smpb.ServiceAssets)
 	if opts.Descriptors != nil {
 		descriptorName := "descriptors-transitive-descriptor-set.proto.bin"
@@ -344,3 +528,102 @@ func WriteService(path string, opts WriteServiceOpts) error {
 	}
 	return nil
 }
+
+// RewriteService copies the service bundle at path to out and lets rewrite
+// modify the manifest on the way, e.g., to fix documentation or vendor
+// metadata without rebuilding the bundle.  The archive is streamed, all other
+// entries are copied unchanged and in their original order.  Changes to the
+// assets of the manifest are rejected, since they would have to match the
+// files in the bundle.  path and out may be the same file.
+func RewriteService(path string, rewrite func(*smpb.ServiceManifest) error, out string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open %q: %v", path, err)
+	}
+	defer f.Close()
+
+	// Refuse to rewrite bundles whose format we do not understand.
+	if _, err := readFormatVersion(f); err != nil {
+		return fmt.Errorf("error in tar file %q: %w", path, err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("could not seek in %q: %v", path, err)
+	}
+
+	// Write to a temporary file next to out, so that out is only replaced if
+	// the rewrite succeeds.
+	tmp, err := os.CreateTemp(filepath.Dir(out), filepath.Base(out)+".tmp")
+	if err != nil {
+		return fmt.Errorf("could not create temporary file for %q: %v", out, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := rewriteServiceManifest(tar.NewReader(f), tar.NewWriter(tmp), rewrite); err != nil {
+		return fmt.Errorf("could not rewrite %q: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write %q: %v", tmp.Name(), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("could not set permissions of %q: %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), out); err != nil {
+		return fmt.Errorf("could not write %q: %v", out, err)
+	}
+	return nil
+}
+
+// rewriteServiceManifest copies all entries from tr to tw and replaces the
+// service manifest with the result of rewrite.
+func rewriteServiceManifest(tr *tar.Reader, tw *tar.Writer, rewrite func(*smpb.ServiceManifest) error) error {
+	found := false
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("getting next file failed: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.Name != serviceManifestPathInTar {
+			if err := tw.WriteHeader(hdr); err != nil {
+				return fmt.Errorf("could not write header of %q: %v", hdr.Name, err)
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				return fmt.Errorf("could not copy %q: %v", hdr.Name, err)
+			}
+			continue
+		}
+
+		found = true
+		manifest := new(smpb.ServiceManifest)
+		if err := makeBinaryProtoHandler(manifest)(tr); err != nil {
+			return fmt.Errorf("error processing file %q: %v", hdr.Name, err)
+		}
+		assets := proto.Clone(manifest.GetAssets())
+		if err := rewrite(manifest); err != nil {
+			return err
+		}
+		if !proto.Equal(assets, manifest.GetAssets()) {
+			return fmt.Errorf("the assets of the manifest cannot be changed without rebuilding the bundle")
+		}
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(manifest)
+		if err != nil {
+			return fmt.Errorf("could not marshal manifest: %v", err)
+		}
+		// Keep all header fields except for the size of the new manifest.
+		newHdr := *hdr
+		newHdr.Size = int64(len(b))
+		if err := tw.WriteHeader(&newHdr); err != nil {
+			return fmt.Errorf("could not write header of %q: %v", hdr.Name, err)
+		}
+		if _, err := tw.Write(b); err != nil {
+			return fmt.Errorf("could not write %q: %v", hdr.Name, err)
+		}
+	}
+	if !found {
+		return fmt.Errorf("missing expected file %q", serviceManifestPathInTar)
+	}
+	return tw.Close()
+}
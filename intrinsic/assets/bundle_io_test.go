@@ -0,0 +1,287 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package bundleio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"archive/tar"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	idpb "intrinsic/assets/proto/id_go_proto"
+	smpb "intrinsic/assets/services/proto/service_manifest_go_proto"
+	"intrinsic/util/archive/tartooling"
+)
+
+// makeBundle returns a tar archive with the given format version entry and a
+// manifest, without the version entry if version is empty.
+func makeBundle(t *testing.T, version string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if version != "" {
+		if err := tartooling.AddBytes([]byte(version), tw, formatVersionPathInTar); err != nil {
+			t.Fatalf("AddBytes() failed: %v", err)
+		}
+	}
+	if err := tartooling.AddBinaryProto(&smpb.ServiceManifest{}, tw, serviceManifestPathInTar); err != nil {
+		t.Fatalf("AddBinaryProto() failed: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadFormatVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    FormatVersion
+		wantErr bool
+	}{
+		{name: "legacy", want: FormatVersionLegacy},
+		{name: "explicit legacy", version: "1", want: FormatVersionLegacy},
+		{name: "version 2", version: "2\n", want: FormatVersion2},
+		{name: "zero", version: "0", wantErr: true},
+		{name: "not a number", version: "two", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bundle.tar")
+			if err := os.WriteFile(path, makeBundle(t, tc.version), 0644); err != nil {
+				t.Fatalf("WriteFile() failed: %v", err)
+			}
+			got, err := ReadFormatVersion(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ReadFormatVersion() = %d, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadFormatVersion() failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("ReadFormatVersion() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadFormatVersionUnsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.tar")
+	if err := os.WriteFile(path, makeBundle(t, "3"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	_, err := ReadFormatVersion(path)
+	var verr *UnsupportedFormatVersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("ReadFormatVersion() returned %v, want an UnsupportedFormatVersionError", err)
+	}
+	if verr.Version != 3 {
+		t.Errorf("UnsupportedFormatVersionError.Version = %d, want 3", verr.Version)
+	}
+	if _, err := ReadServiceManifest(path); !errors.As(err, &verr) {
+		t.Errorf("ReadServiceManifest() returned %v, want an UnsupportedFormatVersionError", err)
+	}
+}
+
+func TestNegotiateFormatVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		want        FormatVersion
+		wantHandler bool
+	}{
+		{name: "legacy", want: FormatVersionLegacy},
+		{name: "version 2", version: "2", want: FormatVersion2, wantHandler: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := bytes.NewReader(makeBundle(t, tc.version))
+			_, handlers := makeOnlyServiceManifestHandlers()
+
+			got, err := negotiateFormatVersion(f, handlers)
+			if err != nil {
+				t.Fatalf("negotiateFormatVersion() failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("negotiateFormatVersion() = %d, want %d", got, tc.want)
+			}
+			if _, ok := handlers[formatVersionPathInTar]; ok != tc.wantHandler {
+				t.Errorf("negotiateFormatVersion() added a handler for %q: %t, want %t", formatVersionPathInTar, ok, tc.wantHandler)
+			}
+			// The archive has to be rewound and readable with the handlers.
+			if err := walkTarFile(tar.NewReader(f), handlers, nil); err != nil {
+				t.Errorf("walkTarFile() after negotiateFormatVersion() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteServiceFormatVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version FormatVersion
+		want    FormatVersion
+	}{
+		{name: "default", want: FormatVersionLegacy},
+		{name: "legacy", version: FormatVersionLegacy, want: FormatVersionLegacy},
+		{name: "version 2", version: FormatVersion2, want: FormatVersion2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bundle.tar")
+			err := WriteService(path, WriteServiceOpts{
+				Manifest: &smpb.ServiceManifest{
+					Metadata: &smpb.ServiceMetadata{Id: &idpb.Id{Package: "ai.intrinsic", Name: "test"}},
+				},
+				FormatVersion: tc.version,
+			})
+			if err != nil {
+				t.Fatalf("WriteService() failed: %v", err)
+			}
+			got, err := ReadFormatVersion(path)
+			if err != nil {
+				t.Fatalf("ReadFormatVersion() failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("ReadFormatVersion() = %d, want %d", got, tc.want)
+			}
+			// Legacy bundles must not contain the version entry, which old
+			// readers reject as an unexpected file.
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Open() failed: %v", err)
+			}
+			defer f.Close()
+			err = tartooling.SeekTo(tar.NewReader(f), formatVersionPathInTar)
+			if hasEntry := err != io.EOF; hasEntry != (tc.want != FormatVersionLegacy) {
+				t.Errorf("bundle has %q: %t, want %t", formatVersionPathInTar, hasEntry, tc.want != FormatVersionLegacy)
+			}
+		})
+	}
+	if err := WriteService(filepath.Join(t.TempDir(), "bundle.tar"), WriteServiceOpts{
+		Manifest:      &smpb.ServiceManifest{},
+		FormatVersion: CurrentFormatVersion + 1,
+	}); err == nil {
+		t.Errorf("WriteService() with format version %d succeeded, want an error", CurrentFormatVersion+1)
+	}
+}
+
+// tarEntry is a header and the content of an entry of a tar archive.
+type tarEntry struct {
+	hdr     *tar.Header
+	content []byte
+}
+
+func readTarEntries(t *testing.T, path string) []tarEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open(%q) failed: %v", path, err)
+	}
+	defer f.Close()
+	var entries []tarEntry
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("ReadAll(%q) failed: %v", hdr.Name, err)
+		}
+		entries = append(entries, tarEntry{hdr: hdr, content: content})
+	}
+}
+
+func TestRewriteService(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "image.tar")
+	if err := os.WriteFile(imagePath, []byte("image"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	config, err := anypb.New(&smpb.ServiceMetadata{DisplayName: "config"})
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	path := filepath.Join(dir, "bundle.tar")
+	if err := WriteService(path, WriteServiceOpts{
+		Manifest: &smpb.ServiceManifest{
+			Metadata: &smpb.ServiceMetadata{Id: &idpb.Id{Package: "ai.intrinsic", Name: "test"}, DisplayName: "Test"},
+		},
+		Descriptors:   &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{Name: proto.String("test.proto")}}},
+		Config:        config,
+		ImageTars:     []string{imagePath},
+		FormatVersion: FormatVersion2,
+	}); err != nil {
+		t.Fatalf("WriteService() failed: %v", err)
+	}
+
+	out := filepath.Join(dir, "rewritten.tar")
+	if err := RewriteService(path, func(m *smpb.ServiceManifest) error {
+		m.GetMetadata().DisplayName = "Rewritten"
+		return nil
+	}, out); err != nil {
+		t.Fatalf("RewriteService() failed: %v", err)
+	}
+
+	want := readTarEntries(t, path)
+	got := readTarEntries(t, out)
+	if len(got) != len(want) {
+		t.Fatalf("RewriteService() wrote %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if want[i].hdr.Name == serviceManifestPathInTar {
+			continue
+		}
+		if diff := cmp.Diff(want[i].hdr, got[i].hdr); diff != "" {
+			t.Errorf("RewriteService() changed the header of %q (-want +got):\n%s", want[i].hdr.Name, diff)
+		}
+		if !bytes.Equal(want[i].content, got[i].content) {
+			t.Errorf("RewriteService() changed the content of %q", want[i].hdr.Name)
+		}
+	}
+
+	manifest, err := ReadServiceManifest(out)
+	if err != nil {
+		t.Fatalf("ReadServiceManifest() failed: %v", err)
+	}
+	if got := manifest.GetMetadata().GetDisplayName(); got != "Rewritten" {
+		t.Errorf("ReadServiceManifest() returned display name %q, want %q", got, "Rewritten")
+	}
+	wantAssets, err := ReadServiceManifest(path)
+	if err != nil {
+		t.Fatalf("ReadServiceManifest() failed: %v", err)
+	}
+	if diff := cmp.Diff(wantAssets.GetAssets(), manifest.GetAssets(), protocmp.Transform()); diff != "" {
+		t.Errorf("RewriteService() changed the assets (-want +got):\n%s", diff)
+	}
+
+	// Changing the assets is rejected and leaves out untouched.
+	badOut := filepath.Join(dir, "bad.tar")
+	if err := RewriteService(path, func(m *smpb.ServiceManifest) error {
+		m.GetAssets().ImageFilenames = nil
+		return nil
+	}, badOut); err == nil {
+		t.Errorf("RewriteService() changing the assets succeeded, want error")
+	}
+	if _, err := os.Stat(badOut); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RewriteService() with error created %q: %v", badOut, err)
+	}
+}
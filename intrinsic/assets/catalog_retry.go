@@ -0,0 +1,73 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package clientutils
+
+import (
+	"context"
+	"time"
+
+	backoff "github.com/cenkalti/backoff/v4"
+	log "github.com/golang/glog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Number of times to retry a catalog call which was rejected because of rate limiting or overload.
+const catalogCallRetries = 6
+
+// retryableCatalogCodes are the codes with which the catalog rejects calls that may succeed later.
+var retryableCatalogCodes = map[codes.Code]bool{
+	codes.ResourceExhausted: true,
+	codes.Unavailable:       true,
+	codes.Aborted:           true,
+}
+
+// retryDelay returns the delay which the server asked for in a RetryInfo detail of err, or zero.
+func retryDelay(err error) time.Duration {
+	s, ok := status.FromError(err)
+	if !ok {
+		return 0
+	}
+	for _, d := range s.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return ri.GetRetryDelay().AsDuration()
+		}
+	}
+	return 0
+}
+
+// serverHintBackOff waits at least as long as the server asked for in the last error.
+type serverHintBackOff struct {
+	backoff.BackOff
+	hint time.Duration
+}
+
+func (b *serverHintBackOff) NextBackOff() time.Duration {
+	next := b.BackOff.NextBackOff()
+	if next != backoff.Stop && next < b.hint {
+		next = b.hint
+	}
+	return next
+}
+
+// RetryCatalogCall calls f until it succeeds or fails with an error other than rate limiting or
+// a temporarily unavailable catalog. Retries back off exponentially and wait at least for the
+// delay the catalog requested.
+func RetryCatalogCall(ctx context.Context, f func() error) error {
+	b := &serverHintBackOff{
+		BackOff: backoff.WithMaxRetries(backoff.NewExponentialBackOff(), catalogCallRetries),
+	}
+	return backoff.Retry(func() error {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if !retryableCatalogCodes[status.Code(err)] {
+			return backoff.Permanent(err)
+		}
+		b.hint = retryDelay(err)
+		log.Warningf("catalog call failed, retrying: %v", err)
+		return err
+	}, backoff.WithContext(b, ctx))
+}
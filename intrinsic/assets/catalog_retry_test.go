@@ -0,0 +1,57 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package clientutils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestRetryCatalogCall(t *testing.T) {
+	calls := 0
+	err := RetryCatalogCall(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return status.Error(codes.ResourceExhausted, "quota exceeded")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RetryCatalogCall() failed: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("RetryCatalogCall() made %d calls, want 3", calls)
+	}
+}
+
+func TestRetryCatalogCallPermanentError(t *testing.T) {
+	calls := 0
+	err := RetryCatalogCall(context.Background(), func() error {
+		calls++
+		return status.Error(codes.AlreadyExists, "exists")
+	})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("RetryCatalogCall() = %v, want AlreadyExists", err)
+	}
+	if calls != 1 {
+		t.Errorf("RetryCatalogCall() made %d calls, want 1", calls)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	s, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(3 * time.Second),
+	})
+	if err != nil {
+		t.Fatalf("WithDetails() failed: %v", err)
+	}
+	if got := retryDelay(s.Err()); got != 3*time.Second {
+		t.Errorf("retryDelay() = %v, want 3s", got)
+	}
+}
@@ -25,42 +25,27 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
-	clusterdiscoverygrpcpb "intrinsic/frontend/cloud/api/clusterdiscovery_api_go_grpc_proto"
-	solutiondiscoverygrpcpb "intrinsic/frontend/cloud/api/solutiondiscovery_api_go_grpc_proto"
+	"intrinsic/skills/tools/skill/cmd/solutionutil"
 	"intrinsic/tools/inctl/auth"
 )
 
 const (
 	maxMsgSize = math.MaxInt64
-	// policy for retrying failed gRPC requests as documented here:
-	// https://pkg.go.dev/google.golang.org/grpc/examples/features/retry
-	// Note that the Ingress will return UNIMPLEMENTED if the server it wants to forward to
-	// is unavailable, so we also check for UNIMPLEMENTED.
-	retryPolicy = `{
-		"methodConfig": [{
-				"waitForReady": true,
-
-				"retryPolicy": {
-						"MaxAttempts": 4,
-						"InitialBackoff": ".5s",
-						"MaxBackoff": ".5s",
-						"BackoffMultiplier": 1.5,
-						"RetryableStatusCodes": [ "UNAVAILABLE", "RESOURCE_EXHAUSTED", "UNIMPLEMENTED"]
-				}
-		}]
-}`
 
 	defaultCatalogProject = "intrinsic-assets-prod"
 )
 
 var (
-	// BaseDialOptions are the base dial options for catalog clients.
+	// BaseDialOptions are the base dial options for catalog clients. Failures of the cloud relay
+	// are reported as RelayErrors.
 	BaseDialOptions = []grpc.DialOption{
-		grpc.WithDefaultServiceConfig(retryPolicy),
+		grpc.WithDefaultServiceConfig(DefaultRetryPolicy().ServiceConfig()),
 		grpc.WithDefaultCallOptions(
 			grpc.MaxCallRecvMsgSize(maxMsgSize),
 			grpc.MaxCallSendMsgSize(maxMsgSize),
 		),
+		grpc.WithChainUnaryInterceptor(RelayErrorUnaryInterceptor),
+		grpc.WithChainStreamInterceptor(RelayErrorStreamInterceptor),
 	}
 
 	catalogEndpointAddressRegex = regexp.MustCompile(`(^|/)www\.endpoints\.([^\.]+).cloud.goog`)
@@ -85,35 +70,29 @@ type DialCatalogOptions struct {
 
 // DialClusterFromInctl creates a connection to a cluster from an inctl command.
 func DialClusterFromInctl(ctx context.Context, flags *cmdutils.CmdFlags) (context.Context, *grpc.ClientConn, string, error) {
-	project := flags.GetFlagProject()
-	org := flags.GetFlagOrganization()
-	address, cluster, solution, err := flags.GetFlagsAddressClusterSolution()
+	cluster, err := ResolveClusterFromInctl(ctx, flags)
 	if err != nil {
 		return ctx, nil, "", err
 	}
 
-	if solution != "" {
-		ctx, conn, _, err := dialConnectionCtx(ctx, dialInfoParams{
-			Address:  address,
-			CredName: project,
-			CredOrg:  org,
-		})
-		if err != nil {
-			return ctx, nil, "", fmt.Errorf("could not create connection options for cluster: %v", err)
-		}
-		defer conn.Close()
-
-		cluster, err = getClusterNameFromSolution(ctx, conn, solution)
-		if err != nil {
-			return ctx, nil, "", fmt.Errorf("could not get cluster name from solution: %v", err)
-		}
+	clientCert, err := flags.GetFlagsClientCertificate()
+	if err != nil {
+		return ctx, nil, "", err
 	}
 
+	address := flags.GetString(cmdutils.KeyAddress)
+	var policy *RetryPolicy
+	if maxAttempts := flags.GetFlagRPCMaxAttempts(); maxAttempts > 0 {
+		policy = defaultRetryPolicy(address)
+		policy.MaxAttempts = maxAttempts
+	}
 	ctx, conn, address, err := dialConnectionCtx(ctx, dialInfoParams{
-		Address:  address,
-		Cluster:  cluster,
-		CredName: project,
-		CredOrg:  org,
+		Address:     address,
+		Cluster:     cluster,
+		CredName:    flags.GetFlagProject(),
+		CredOrg:     flags.GetFlagOrganization(),
+		ClientCert:  clientCert,
+		RetryPolicy: policy,
 	})
 	if err != nil {
 		return ctx, nil, "", fmt.Errorf("could not create connection options for the installer: %v", err)
@@ -122,6 +101,47 @@ func DialClusterFromInctl(ctx context.Context, flags *cmdutils.CmdFlags) (contex
 	return ctx, conn, address, nil
 }
 
+// ResolveClusterFromInctl returns the cluster selected by the flags added by
+// AddFlagsAddressClusterSolution, looking up the cluster a solution is deployed on if --solution is
+// set. Returns an empty string if only --address is set.
+func ResolveClusterFromInctl(ctx context.Context, flags *cmdutils.CmdFlags) (string, error) {
+	project := flags.GetFlagProject()
+	org := flags.GetFlagOrganization()
+	address, cluster, solution, err := flags.GetFlagsAddressClusterSolution()
+	if err != nil {
+		return "", err
+	}
+	if solution == "" {
+		return cluster, nil
+	}
+	clientCert, err := flags.GetFlagsClientCertificate()
+	if err != nil {
+		return "", err
+	}
+
+	solutionKey := solutionClusterKey(address, project, org, solution)
+	if cached, ok := cachedSolutionCluster(solutionKey); ok {
+		return cached, nil
+	}
+	ctx, conn, _, err := dialConnectionCtx(ctx, dialInfoParams{
+		Address:    address,
+		CredName:   project,
+		CredOrg:    org,
+		ClientCert: clientCert,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not create connection options for cluster: %v", err)
+	}
+	defer conn.Close()
+
+	cluster, err = solutionutil.GetClusterNameFromSolution(ctx, conn, solution)
+	if err != nil {
+		return "", fmt.Errorf("could not get cluster name from solution: %v", err)
+	}
+	cacheSolutionCluster(solutionKey, cluster)
+	return cluster, nil
+}
+
 // DialCatalogFromInctl creates a connection to an asset catalog service from an inctl command.
 func DialCatalogFromInctl(cmd *cobra.Command, flags *cmdutils.CmdFlags) (*grpc.ClientConn, error) {
 
@@ -185,6 +205,20 @@ func GetTransportCredentialsDialOption() (grpc.DialOption, error) {
 	return grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(pool, "")), nil
 }
 
+// GetClientCertTransportCredentialsDialOption returns transport credentials which present the given
+// client certificate for mutual TLS. Falls back to GetTransportCredentialsDialOption if cert is nil.
+func GetClientCertTransportCredentialsDialOption(cert *auth.ClientCertificate) (grpc.DialOption, error) {
+	if cert == nil {
+		return GetTransportCredentialsDialOption()
+	}
+	config, err := cert.TLSConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load client certificate")
+	}
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
+
 // IsLocalAddress returns true if the address is a local address.
 func IsLocalAddress(address string) bool {
 	for _, localAddress := range []string{"127.0.0.1", "local", "xfa.lan"} {
@@ -293,6 +327,10 @@ type dialInfoParams struct {
 	CredAlias string // Optional alias for key to load
 	CredOrg   string // Optional the org-id header to set
 	CredToken string // Optional the credential value itself. This bypasses the store
+	// Optional client certificate for mTLS. Defaults to the one configured in the store.
+	ClientCert *auth.ClientCertificate
+	// Optional retry policy. Defaults to the policy returned by defaultRetryPolicy for Address.
+	RetryPolicy *RetryPolicy
 }
 
 func dialConnectionCtx(ctx context.Context, params dialInfoParams) (context.Context, *grpc.ClientConn, string, error) {
@@ -333,8 +371,13 @@ func dialInfoCtx(ctx context.Context, params dialInfoParams) (context.Context, *
 		ctx = metadata.AppendToOutgoingContext(ctx, auth.OrgIDHeader, strings.Split(params.CredOrg, "@")[0])
 	}
 
+	policy := params.RetryPolicy
+	if policy == nil {
+		policy = defaultRetryPolicy(params.Address)
+	}
+
 	if UseInsecureCredentials(params.Address) {
-		finalOpts := append(BaseDialOptions,
+		finalOpts := append(dialOptions(policy),
 			grpc.WithTransportCredentials(insecure.NewCredentials()),
 		)
 		return ctx, &finalOpts, params.Address, nil
@@ -344,19 +387,19 @@ func dialInfoCtx(ctx context.Context, params dialInfoParams) (context.Context, *
 		ctx = metadata.AppendToOutgoingContext(ctx, "x-server-name", params.Cluster)
 	}
 
-	rpcCredentials, err := createCredentials(params)
+	rpcCredentials, clientCert, err := createCredentials(params)
 	if err != nil {
 		return nil, nil, "", fmt.Errorf("cannot retrieve connection credentials: %w", err)
 	}
-	tcOption, err := GetTransportCredentialsDialOption()
+	tcOption, err := GetClientCertTransportCredentialsDialOption(clientCert)
 	if err != nil {
 		return nil, nil, "", fmt.Errorf("cannot retrieve transport credentials: %w", err)
 	}
 
-	finalOpts := append(BaseDialOptions,
-		grpc.WithPerRPCCredentials(rpcCredentials),
-		tcOption,
-	)
+	finalOpts := append(dialOptions(policy), tcOption)
+	if rpcCredentials != nil {
+		finalOpts = append(finalOpts, grpc.WithPerRPCCredentials(rpcCredentials))
+	}
 
 	return ctx, &finalOpts, params.Address, nil
 }
@@ -380,55 +423,41 @@ func resolveClusterAddress(address string, project string) (string, error) {
 	return fmt.Sprintf("dns:///www.endpoints.%s.cloud.goog:443", project), nil
 }
 
-func createCredentials(params dialInfoParams) (credentials.PerRPCCredentials, error) {
+// createCredentials returns the per-RPC credentials and the client certificate for the connection.
+// The per-RPC credentials are nil if the client certificate authenticates the caller on its own.
+func createCredentials(params dialInfoParams) (credentials.PerRPCCredentials, *auth.ClientCertificate, error) {
 	if params.CredToken != "" {
-		return &auth.ProjectToken{APIKey: params.CredToken}, nil
+		return &auth.ProjectToken{APIKey: params.CredToken}, params.ClientCert, nil
 	}
 
 	if params.CredName != "" {
 		configuration, err := auth.NewStore().GetConfiguration(params.CredName)
 		if err != nil {
-			return nil, fmt.Errorf("credentials not found: %w", err)
+			if params.ClientCert == nil {
+				return nil, nil, fmt.Errorf("credentials not found: %w", err)
+			}
+			configuration = auth.NewConfiguration(params.CredName)
+		}
+		if params.ClientCert != nil {
+			configuration.ClientCertificate = params.ClientCert
 		}
 
-		if params.CredAlias == "" {
-			return configuration.GetDefaultCredentials()
+		token, clientCert, err := configuration.Credentials(params.CredAlias)
+		if err != nil {
+			return nil, nil, err
+		}
+		if token == nil {
+			// Do not wrap the nil token into a non-nil interface.
+			return nil, clientCert, nil
 		}
-		return configuration.GetCredentials(params.CredAlias)
+		return token, clientCert, nil
 	}
 
-	if IsLocalAddress(params.Address) {
-		// local calls do not require any authentication
-		return nil, nil
+	if params.ClientCert != nil || IsLocalAddress(params.Address) {
+		// local calls and calls with a client certificate do not require an API key
+		return nil, params.ClientCert, nil
 	}
 	// credential name is required for non-local calls to resolve
 	// the corresponding API key.
-	return nil, fmt.Errorf("credential name is required")
-}
-
-// getClusterNameFromSolution returns the cluster in which a solution currently runs.
-func getClusterNameFromSolution(ctx context.Context, conn *grpc.ClientConn, solutionName string) (string, error) {
-	solution, err := getSolution(ctx, conn, solutionName)
-	if err != nil {
-		return "", fmt.Errorf("failed to get solution: %w", err)
-	}
-	if solution.GetState() == clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_NOT_RUNNING {
-		return "", fmt.Errorf("solution is not running")
-	}
-	if solution.GetClusterName() == "" {
-		return "", fmt.Errorf("unknown error: solution is running but cluster is empty")
-	}
-	return solution.GetClusterName(), nil
-}
-
-// getSolution gets solution data by name
-func getSolution(ctx context.Context, conn *grpc.ClientConn, solutionName string) (*solutiondiscoverygrpcpb.SolutionDescription, error) {
-	client := solutiondiscoverygrpcpb.NewSolutionDiscoveryServiceClient(conn)
-	req := &solutiondiscoverygrpcpb.GetSolutionDescriptionRequest{Name: solutionName}
-	resp, err := client.GetSolutionDescription(ctx, req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get solution description: %w", err)
-	}
-
-	return resp.GetSolution(), nil
+	return nil, nil, fmt.Errorf("credential name is required")
 }
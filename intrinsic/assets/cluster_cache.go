@@ -0,0 +1,47 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package clientutils
+
+import (
+	"strings"
+	"sync"
+)
+
+// solutionClusters caches the clusters which solutions were resolved to. It is only used if
+// enabled with CacheSolutionClusters.
+var solutionClusters = struct {
+	sync.Mutex
+	clusters map[string]string
+}{}
+
+// CacheSolutionClusters enables or disables caching the cluster of each solution in
+// DialClusterFromInctl. Long running processes like 'inctl shell' enable it to avoid resolving the
+// same solution for every command. Calling it clears the cache.
+func CacheSolutionClusters(enable bool) {
+	solutionClusters.Lock()
+	defer solutionClusters.Unlock()
+	solutionClusters.clusters = nil
+	if enable {
+		solutionClusters.clusters = map[string]string{}
+	}
+}
+
+func solutionClusterKey(address, project, org, solution string) string {
+	return strings.Join([]string{address, project, org, solution}, "\x00")
+}
+
+// cachedSolutionCluster returns the cached cluster of a solution, if caching is enabled.
+func cachedSolutionCluster(key string) (string, bool) {
+	solutionClusters.Lock()
+	defer solutionClusters.Unlock()
+	cluster, ok := solutionClusters.clusters[key]
+	return cluster, ok
+}
+
+func cacheSolutionCluster(key, cluster string) {
+	solutionClusters.Lock()
+	defer solutionClusters.Unlock()
+	if solutionClusters.clusters != nil {
+		solutionClusters.clusters[key] = cluster
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/spf13/viper"
 	"intrinsic/assets/imagetransfer"
 	"intrinsic/assets/imageutils"
+	"intrinsic/tools/inctl/auth"
 	"intrinsic/tools/inctl/util/orgutil"
 )
 
@@ -29,8 +30,14 @@ const (
 	KeyAuthUser = "auth_user"
 	// KeyAuthPassword is the name of the auth password flag.
 	KeyAuthPassword = "auth_password"
+	// KeyCACert is the name of the flag for the CA bundle used to verify mTLS servers.
+	KeyCACert = "ca_cert"
 	// KeyCatalogAddress is the name of the catalog address flag.
 	KeyCatalogAddress = "catalog_address"
+	// KeyClientCert is the name of the client certificate flag.
+	KeyClientCert = "client_cert"
+	// KeyClientKey is the name of the client key flag.
+	KeyClientKey = "client_key"
 	// KeyCluster is the name of the cluster flag.
 	KeyCluster = "cluster"
 	// KeyContext is the name of the context flag.
@@ -41,10 +48,14 @@ const (
 	KeyEnvironment = "environment"
 	// KeyDryRun is the name of the dry run flag.
 	KeyDryRun = "dry_run"
+	// KeyNoCache is the name of the flag to bypass cached results.
+	KeyNoCache = "no_cache"
 	// KeyFilter is the name of the filter flag.
 	KeyFilter = "filter"
 	// KeyIgnoreExisting is the name of the flag to ignore AlreadyExists errors.
 	KeyIgnoreExisting = "ignore_existing"
+	// KeyIgnoreUpgradeState is the name of the flag to install assets during a cluster upgrade.
+	KeyIgnoreUpgradeState = "ignore_upgrade_state"
 	// KeyInstallerAddress is the name of the installer address flag.
 	KeyInstallerAddress = "installer_address"
 	// KeyManifestFile is the file path to the manifest binary.
@@ -59,6 +70,8 @@ const (
 	// KeyProject is used as central flag name for passing a project name to inctl.
 	KeyProject  = orgutil.KeyProject
 	KeyRegistry = "registry"
+	// KeyRPCMaxAttempts is the name of the flag for the maximum number of attempts of a request.
+	KeyRPCMaxAttempts = "rpc_max_attempts"
 	// KeyReleaseNotes is the name of the release notes flag.
 	KeyReleaseNotes = "release_notes"
 	// KeySkipDirectUpload is boolean flag controlling direct upload behavior
@@ -75,6 +88,8 @@ const (
 	KeyUseInProcCatalog = "use_in_proc_catalog"
 	// KeyVendor is the name of the vendor flag.
 	KeyVendor = "vendor"
+	// KeyVerbose is the name of the flag for printing details of what a command does.
+	KeyVerbose = "verbose"
 	// KeyVersion is the name of the version flag.
 	KeyVersion = "version"
 
@@ -85,8 +100,9 @@ const (
 
 // CmdFlags abstracts interaction with inctl command flags.
 type CmdFlags struct {
-	cmd        *cobra.Command
-	viperLocal *viper.Viper
+	cmd         *cobra.Command
+	viperLocal  *viper.Viper
+	constraints []*FlagConstraint
 }
 
 // NewCmdFlags returns a new CmdFlags instance.
@@ -104,9 +120,11 @@ func NewCmdFlagsWithViper(viperLocal *viper.Viper) *CmdFlags {
 
 // SetCommand sets the cobra Command to interact with.
 //
-// The command must be set before any flags are added.
+// The command must be set before any flags are added. The flag constraints added to cf are
+// validated before the command runs.
 func (cf *CmdFlags) SetCommand(cmd *cobra.Command) {
 	cf.cmd = cmd
+	cmd.PreRunE = cf.validateFlagsPreRunE(cmd.PreRunE)
 }
 
 // AddFlagsCatalogInProcEnvironment adds flags for using an in-proc catalog and specifying the
@@ -114,7 +132,7 @@ func (cf *CmdFlags) SetCommand(cmd *cobra.Command) {
 func (cf *CmdFlags) AddFlagsCatalogInProcEnvironment() {
 	cf.OptionalBool(KeyUseInProcCatalog, false, "DEPRECATED DO NOT USE. Whether to use an in-proc catalog service.")
 	cf.OptionalString(KeyEnvironment, "", "DEPRECATED DO NOT USE. The Firestore DB environment (only used with the in-proc catalog).")
-	cf.cmd.MarkFlagsRequiredTogether(KeyUseInProcCatalog, KeyEnvironment)
+	cf.AddFlagsRequiredTogether(KeyUseInProcCatalog, KeyEnvironment)
 }
 
 // GetFlagsCatalogInProcEnvironment gets the values of the in-proc catalog and environment flags
@@ -157,6 +175,17 @@ func (cf *CmdFlags) GetFlagDryRun() bool {
 	return cf.GetBool(KeyDryRun)
 }
 
+// AddFlagNoCache adds a flag for bypassing the cache of image archives, see
+// imageutils.SetArchiveCacheEnabled.
+func (cf *CmdFlags) AddFlagNoCache() {
+	cf.OptionalBool(KeyNoCache, false, "Do not use cached information about image archives and build targets.")
+}
+
+// GetFlagNoCache gets the value of the no cache flag added by AddFlagNoCache.
+func (cf *CmdFlags) GetFlagNoCache() bool {
+	return cf.GetBool(KeyNoCache)
+}
+
 // AddFlagIgnoreExisting adds a flag to ignore AlreadyExists errors.
 func (cf *CmdFlags) AddFlagIgnoreExisting(assetType string) {
 	cf.OptionalBool(KeyIgnoreExisting, false, fmt.Sprintf("Ignore errors if the specified %s version already exists in the catalog.", assetType))
@@ -167,6 +196,17 @@ func (cf *CmdFlags) GetFlagIgnoreExisting() bool {
 	return cf.GetBool(KeyIgnoreExisting)
 }
 
+// AddFlagIgnoreUpgradeState adds a flag to install assets although the cluster is being upgraded,
+// see clientutils.CheckClusterUpgradeState.
+func (cf *CmdFlags) AddFlagIgnoreUpgradeState() {
+	cf.OptionalBool(KeyIgnoreUpgradeState, false, "Install even if an upgrade of the cluster is running or about to start.")
+}
+
+// GetFlagIgnoreUpgradeState gets the value of the flag added by AddFlagIgnoreUpgradeState.
+func (cf *CmdFlags) GetFlagIgnoreUpgradeState() bool {
+	return cf.GetBool(KeyIgnoreUpgradeState)
+}
+
 // AddFlagAddress adds a flag for the installer service address.
 func (cf *CmdFlags) AddFlagAddress() {
 	cf.OptionalEnvString(KeyAddress, "xfa.lan:17080", `The address of the cluster.
@@ -200,7 +240,21 @@ func (cf *CmdFlags) AddFlagsAddressClusterSolution() {
 	cf.OptionalString(KeyCluster, "", "The target Kubernetes cluster.")
 	cf.OptionalEnvString(KeySolution, "", "The target solution. Must be deployed.")
 
-	cf.cmd.MarkFlagsMutuallyExclusive(KeyCluster, KeySolution)
+	cf.AddFlagsConflict(KeyCluster, KeySolution).WithHint("--solution already selects the cluster the solution is deployed on")
+
+	cf.AddFlagsClientCertificate()
+	cf.AddFlagRPCMaxAttempts()
+}
+
+// AddFlagRPCMaxAttempts adds a flag for the maximum number of attempts of each request to a
+// cluster.
+func (cf *CmdFlags) AddFlagRPCMaxAttempts() {
+	cf.OptionalInt(KeyRPCMaxAttempts, 0, "Maximum number of attempts of each request to the cluster, including the first one. 1 disables retries, 0 uses the default. gRPC caps the value at 5.")
+}
+
+// GetFlagRPCMaxAttempts gets the value of the flag added by AddFlagRPCMaxAttempts.
+func (cf *CmdFlags) GetFlagRPCMaxAttempts() int {
+	return cf.GetInt(KeyRPCMaxAttempts)
 }
 
 // GetFlagsAddressClusterSolution gets the values of the address, cluster, and solution flags added
@@ -218,12 +272,40 @@ func (cf *CmdFlags) GetFlagsAddressClusterSolution() (string, string, string, er
 	return address, cluster, solution, err
 }
 
+// AddFlagsClientCertificate adds flags for authenticating with a client certificate against
+// clusters which require mutual TLS.
+func (cf *CmdFlags) AddFlagsClientCertificate() {
+	cf.OptionalEnvString(KeyClientCert, "", "Path to a PEM encoded client certificate for clusters requiring mTLS. Overrides the certificate configured with 'inctl auth set-client-cert'.")
+	cf.OptionalEnvString(KeyClientKey, "", "Path to the PEM encoded private key of --client_cert.")
+	cf.OptionalEnvString(KeyCACert, "", "Path to a PEM encoded CA bundle used to verify clusters requiring mTLS. Defaults to the system certificates. Requires --client_cert.")
+
+	cf.AddFlagsRequiredTogether(KeyClientCert, KeyClientKey)
+}
+
+// GetFlagsClientCertificate gets the client certificate specified by the flags added by
+// AddFlagsClientCertificate. Returns nil if no certificate was specified.
+func (cf *CmdFlags) GetFlagsClientCertificate() (*auth.ClientCertificate, error) {
+	certFile := cf.GetString(KeyClientCert)
+	caFile := cf.GetString(KeyCACert)
+	if certFile == "" {
+		if caFile != "" {
+			return nil, fmt.Errorf("--%s requires --%s", KeyCACert, KeyClientCert)
+		}
+		return nil, nil
+	}
+	return &auth.ClientCertificate{
+		CertFile: certFile,
+		KeyFile:  cf.GetString(KeyClientKey),
+		CAFile:   caFile,
+	}, nil
+}
+
 // AddFlagsManifest adds flags for specifying a manifest.
 func (cf *CmdFlags) AddFlagsManifest() {
 	cf.OptionalString(KeyManifestFile, "", "The path to the manifest binary file.")
 	cf.OptionalString(KeyManifestTarget, "", "The manifest bazel target.")
 
-	cf.cmd.MarkFlagsMutuallyExclusive(KeyManifestFile, KeyManifestTarget)
+	cf.AddFlagsOneOf(KeyManifestFile, KeyManifestTarget)
 }
 
 // GetFlagsManifest gets the values of the manifest flags added by AddFlagsManifest.
@@ -289,7 +371,7 @@ func (cf *CmdFlags) GetFlagRegistry() string {
 func (cf *CmdFlags) AddFlagsRegistryAuthUserPassword() {
 	cf.OptionalString(KeyAuthUser, "", "The username used to access the private container registry.")
 	cf.OptionalString(KeyAuthPassword, "", "The password used to authenticate private container registry access.")
-	cf.cmd.MarkFlagsRequiredTogether(KeyAuthUser, KeyAuthPassword)
+	cf.AddFlagsRequiredTogether(KeyAuthUser, KeyAuthPassword)
 }
 
 // GetFlagsRegistryAuthUserPassword gets the values of the user/password flags added by
@@ -380,6 +462,18 @@ func (cf *CmdFlags) GetFlagSideloadStopType() string {
 	return cf.GetString(KeyType)
 }
 
+// AddFlagSideloadStopTimeout adds a flag for the timeout when stopping an asset.
+func (cf *CmdFlags) AddFlagSideloadStopTimeout(assetType string) {
+	cf.OptionalString(KeyTimeout, "180s", fmt.Sprintf(`Maximum time to wait for the %s to
+be removed from the cluster after stopping it. Can be set to any valid duration
+(\"60s\", \"5m\", ...) or to \"0\" to disable waiting.`, assetType))
+}
+
+// GetFlagSideloadStopTimeout gets the value of the flag added by AddFlagSideloadStopTimeout.
+func (cf *CmdFlags) GetFlagSideloadStopTimeout() (time.Duration, string, error) {
+	return cf.GetFlagSideloadStartTimeout()
+}
+
 // AddFlagSideloadStartTimeout adds a flag for the timeout when starting an asset.
 func (cf *CmdFlags) AddFlagSideloadStartTimeout(assetType string) {
 	cf.OptionalString(KeyTimeout, "180s", fmt.Sprintf(`Maximum time to wait for the %s to
@@ -423,6 +517,16 @@ func (cf *CmdFlags) GetFlagVendor() string {
 	return cf.GetString(KeyVendor)
 }
 
+// AddFlagVerbose adds a flag for printing details of what a command does.
+func (cf *CmdFlags) AddFlagVerbose() {
+	cf.OptionalBool(KeyVerbose, false, "Print details of what the command does, e.g., how the bundle was processed.")
+}
+
+// GetFlagVerbose gets the value of the verbose flag added by AddFlagVerbose.
+func (cf *CmdFlags) GetFlagVerbose() bool {
+	return cf.GetBool(KeyVerbose)
+}
+
 // AddFlagVersion adds a flag for the asset version.
 func (cf *CmdFlags) AddFlagVersion(assetType string) {
 	cf.RequiredString(KeyVersion, fmt.Sprintf("The %s version, in sem-ver format.", assetType))
@@ -569,6 +673,12 @@ func (cf *CmdFlags) CreateRegistryOpts(ctx context.Context) imageutils.RegistryO
 	)
 }
 
+// CreateReadonlyTransferer creates a transferer which can read images from
+// remote registries using the registry credentials, but never writes to them.
+func (cf *CmdFlags) CreateReadonlyTransferer(ctx context.Context) imagetransfer.Transferer {
+	return imagetransfer.Readonly(remote.WithContext(ctx), cf.authOpt())
+}
+
 // CreateRegistryOptsWithTransferer creates registry options for processing images.
 func (cf *CmdFlags) CreateRegistryOptsWithTransferer(ctx context.Context, transferer imagetransfer.Transferer, registry string) imageutils.RegistryOptions {
 	opts := imageutils.RegistryOptions{
@@ -0,0 +1,91 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package cmdutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// KeyYes is the name of the flag to skip confirmation prompts.
+	KeyYes = "yes"
+
+	yesFlagUsage = "Skip the confirmation prompt. Required if the input is not a terminal."
+)
+
+// ErrNotConfirmed is returned by Confirm if the user did not confirm the action.
+var ErrNotConfirmed = errors.New("aborted by user")
+
+// AddFlagYes adds a flag for skipping the confirmation prompt of a destructive command.
+func (cf *CmdFlags) AddFlagYes() {
+	cf.OptionalBool(KeyYes, false, yesFlagUsage)
+}
+
+// GetFlagYes gets the value of the yes flag added by AddFlagYes.
+func (cf *CmdFlags) GetFlagYes() bool {
+	return cf.GetBool(KeyYes)
+}
+
+// Confirm asks the user to confirm action unless the yes flag added by AddFlagYes is set. See
+// Confirm for details.
+func (cf *CmdFlags) Confirm(action string, details ...string) error {
+	return Confirm(cf.cmd, cf.GetFlagYes(), action, details...)
+}
+
+// AddYesFlagVar adds the yes flag to commands which do not use CmdFlags.
+func AddYesFlagVar(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, KeyYes, false, yesFlagUsage)
+}
+
+// Confirm summarizes a destructive action and asks the user to confirm it.
+//
+// action is a short description such as `Delete cluster "foo"`, details optionally list what is
+// affected. Nothing is asked if yes is set. If the input of cmd is not a terminal, Confirm fails
+// instead of blocking, so that scripts have to opt in with --yes explicitly. ErrNotConfirmed is
+// returned if the user declines.
+func Confirm(cmd *cobra.Command, yes bool, action string, details ...string) error {
+	if yes {
+		return nil
+	}
+	in := cmd.InOrStdin()
+	if !isTerminal(in) {
+		return fmt.Errorf("%s requires confirmation, use --%s to confirm non-interactively", action, KeyYes)
+	}
+
+	// Prompts go to stderr so that they do not mix with structured output.
+	out := cmd.ErrOrStderr()
+	fmt.Fprintf(out, "%s\n", action)
+	for _, d := range details {
+		fmt.Fprintf(out, "  - %s\n", d)
+	}
+	fmt.Fprint(out, "Do you want to continue? [y/N]: ")
+
+	response, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("cannot read confirmation: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "y", "yes":
+		return nil
+	default:
+		return ErrNotConfirmed
+	}
+}
+
+// isTerminal reports whether r is an interactive terminal. Readers which are not files, e.g.
+// injected by tests, are treated as interactive.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return true
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
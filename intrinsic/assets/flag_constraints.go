@@ -0,0 +1,170 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package cmdutils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type constraintKind int
+
+const (
+	constraintRequires constraintKind = iota
+	constraintRequiresValue
+	constraintConflicts
+	constraintTogether
+	constraintOneOf
+)
+
+// FlagConstraint is a rule about which flags of a command may or must be used together. Constraints
+// are checked before the command runs, see SetCommand.
+type FlagConstraint struct {
+	kind  constraintKind
+	flag  string
+	flags []string
+	value string
+	hint  string
+}
+
+// WithHint sets a hint which is appended to the error message if the constraint is violated, e.g.,
+// to explain how to fix the command line.
+func (c *FlagConstraint) WithHint(hint string) *FlagConstraint {
+	c.hint = hint
+	return c
+}
+
+func (cf *CmdFlags) addConstraint(c *FlagConstraint) *FlagConstraint {
+	cf.constraints = append(cf.constraints, c)
+	return c
+}
+
+// AddFlagRequires adds a constraint that if flag is set, all of the required flags must be set too.
+func (cf *CmdFlags) AddFlagRequires(flag string, required ...string) *FlagConstraint {
+	return cf.addConstraint(&FlagConstraint{kind: constraintRequires, flag: flag, flags: required})
+}
+
+// AddFlagRequiresValue adds a constraint that flag can only be set if other has the given value.
+func (cf *CmdFlags) AddFlagRequiresValue(flag string, other string, value string) *FlagConstraint {
+	return cf.addConstraint(&FlagConstraint{kind: constraintRequiresValue, flag: flag, flags: []string{other}, value: value})
+}
+
+// AddFlagsConflict adds a constraint that at most one of flags is set on the command line. Values
+// from environment variables do not conflict with flags given on the command line.
+func (cf *CmdFlags) AddFlagsConflict(flags ...string) *FlagConstraint {
+	return cf.addConstraint(&FlagConstraint{kind: constraintConflicts, flags: flags})
+}
+
+// AddFlagsRequiredTogether adds a constraint that either all or none of flags are set.
+func (cf *CmdFlags) AddFlagsRequiredTogether(flags ...string) *FlagConstraint {
+	return cf.addConstraint(&FlagConstraint{kind: constraintTogether, flags: flags})
+}
+
+// AddFlagsOneOf adds a constraint that exactly one of flags is set.
+func (cf *CmdFlags) AddFlagsOneOf(flags ...string) *FlagConstraint {
+	return cf.addConstraint(&FlagConstraint{kind: constraintOneOf, flags: flags})
+}
+
+// ValidateFlags checks all constraints added to cf and returns an error describing the first
+// violated one.
+func (cf *CmdFlags) ValidateFlags() error {
+	for _, c := range cf.constraints {
+		if err := cf.check(c); err != nil {
+			if c.hint != "" {
+				return fmt.Errorf("%v: %s", err, c.hint)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// isSet reports whether a flag was set on the command line or via its environment variable.
+func (cf *CmdFlags) isSet(name string) bool {
+	return cf.changed(name) || cf.IsSet(name)
+}
+
+// changed reports whether a flag was set on the command line.
+func (cf *CmdFlags) changed(name string) bool {
+	f := cf.cmd.Flags().Lookup(name)
+	return f != nil && f.Changed
+}
+
+func (cf *CmdFlags) check(c *FlagConstraint) error {
+	switch c.kind {
+	case constraintRequires:
+		if !cf.isSet(c.flag) {
+			return nil
+		}
+		for _, r := range c.flags {
+			if !cf.isSet(r) {
+				return fmt.Errorf("--%s requires %s", c.flag, joinFlags(c.flags, "and"))
+			}
+		}
+	case constraintRequiresValue:
+		if cf.isSet(c.flag) && cf.GetString(c.flags[0]) != c.value {
+			return fmt.Errorf("--%s requires --%s=%s", c.flag, c.flags[0], c.value)
+		}
+	case constraintConflicts:
+		var set []string
+		for _, f := range c.flags {
+			if cf.changed(f) {
+				set = append(set, f)
+			}
+		}
+		if len(set) > 1 {
+			return fmt.Errorf("%s cannot be used together", joinFlags(set, "and"))
+		}
+	case constraintTogether:
+		var set, missing []string
+		for _, f := range c.flags {
+			if cf.isSet(f) {
+				set = append(set, f)
+			} else {
+				missing = append(missing, f)
+			}
+		}
+		if len(set) > 0 && len(missing) > 0 {
+			return fmt.Errorf("%s must be set together, missing %s", joinFlags(c.flags, "and"), joinFlags(missing, "and"))
+		}
+	case constraintOneOf:
+		var set []string
+		for _, f := range c.flags {
+			if cf.isSet(f) {
+				set = append(set, f)
+			}
+		}
+		if len(set) != 1 {
+			return fmt.Errorf("exactly one of %s must be set", joinFlags(c.flags, "or"))
+		}
+	}
+	return nil
+}
+
+// joinFlags formats flag names as "--a, --b and --c".
+func joinFlags(flags []string, conjunction string) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "--" + f
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return fmt.Sprintf("%s %s %s", strings.Join(names[:len(names)-1], ", "), conjunction, names[len(names)-1])
+}
+
+// validateFlagsPreRunE returns a PreRunE function which validates the flag constraints before
+// calling next, if any.
+func (cf *CmdFlags) validateFlagsPreRunE(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cf.ValidateFlags(); err != nil {
+			return err
+		}
+		if next != nil {
+			return next(cmd, args)
+		}
+		return nil
+	}
+}
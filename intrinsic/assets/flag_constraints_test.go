@@ -0,0 +1,92 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package cmdutils
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func newTestCmdFlags() (*cobra.Command, *CmdFlags) {
+	cmd := &cobra.Command{
+		Use:  "test",
+		RunE: func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cf := NewCmdFlags()
+	cf.SetCommand(cmd)
+	for _, name := range []string{"a", "b", "c", KeyType} {
+		cf.OptionalString(name, "", name)
+	}
+	return cmd, cf
+}
+
+func TestFlagConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		add     func(cf *CmdFlags)
+		args    []string
+		wantErr string
+	}{
+		{
+			name: "requires satisfied",
+			add:  func(cf *CmdFlags) { cf.AddFlagRequires("a", "b") },
+			args: []string{"--a=1", "--b=2"},
+		},
+		{
+			name:    "requires missing",
+			add:     func(cf *CmdFlags) { cf.AddFlagRequires("a", "b", "c") },
+			args:    []string{"--a=1", "--b=2"},
+			wantErr: "--a requires --b and --c",
+		},
+		{
+			name:    "requires value",
+			add:     func(cf *CmdFlags) { cf.AddFlagRequiresValue("a", KeyType, "build") },
+			args:    []string{"--a=1", "--type=archive"},
+			wantErr: "--a requires --type=build",
+		},
+		{
+			name:    "conflict with hint",
+			add:     func(cf *CmdFlags) { cf.AddFlagsConflict("a", "b").WithHint("use only --a") },
+			args:    []string{"--a=1", "--b=2"},
+			wantErr: "--a and --b cannot be used together: use only --a",
+		},
+		{
+			name:    "required together",
+			add:     func(cf *CmdFlags) { cf.AddFlagsRequiredTogether("a", "b") },
+			args:    []string{"--b=2"},
+			wantErr: "--a and --b must be set together, missing --a",
+		},
+		{
+			name:    "one of none set",
+			add:     func(cf *CmdFlags) { cf.AddFlagsOneOf("a", "b", "c") },
+			wantErr: "exactly one of --a, --b or --c must be set",
+		},
+		{
+			name: "one of",
+			add:  func(cf *CmdFlags) { cf.AddFlagsOneOf("a", "b", "c") },
+			args: []string{"--c=3"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, cf := newTestCmdFlags()
+			tc.add(cf)
+			cmd.SetArgs(tc.args)
+			err := cmd.Execute()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Execute() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Execute() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
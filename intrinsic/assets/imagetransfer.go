@@ -6,6 +6,7 @@ package imagetransfer
 
 import (
 	"fmt"
+	"net/http"
 
 	backoff "github.com/cenkalti/backoff/v4"
 	log "github.com/golang/glog"
@@ -34,8 +35,8 @@ func (r remoteImage) Write(ref name.Reference, img containerregistry.Image) erro
 	b := backoff.WithMaxRetries(backoff.NewExponentialBackOff(), remoteWriteTries)
 	if err := backoff.Retry(func() error {
 		err := remote.Write(ref, img, r.Opts...)
-		if err, ok := err.(*transport.Error); ok && err.StatusCode >= 500 {
-			// Retry server errors like 504 Gateway Timeout.
+		if err, ok := err.(*transport.Error); ok && (err.StatusCode >= 500 || err.StatusCode == http.StatusTooManyRequests) {
+			// Retry server errors like 504 Gateway Timeout and rate limiting.
 			return err
 		}
 		if err != nil {
@@ -93,3 +94,15 @@ func (NoOpTransferer) Read(ref name.Reference) (containerregistry.Image, error)
 func (NoOpTransferer) Write(ref name.Reference, img containerregistry.Image) error {
 	return fmt.Errorf("NoOpTransferer forbids writing an image")
 }
+
+// DiscardTransferer accepts every image without writing it anywhere.  Use it to
+// compute the image specs of a bundle without uploading the images.
+type DiscardTransferer struct{}
+
+func (DiscardTransferer) Read(ref name.Reference) (containerregistry.Image, error) {
+	return nil, fmt.Errorf("DiscardTransferer forbids reading an image")
+}
+
+func (DiscardTransferer) Write(ref name.Reference, img containerregistry.Image) error {
+	return nil
+}
@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"log"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	containerregistry "github.com/google/go-containerregistry/pkg/v1"
@@ -47,6 +49,11 @@ const (
 
 	dockerLabelSkillIDKey = "ai.intrinsic.asset-id"
 
+	// Build info labels, which are set by the skill build rules if the build is stamped.
+	dockerLabelGitCommitKey      = "ai.intrinsic.build.git-commit"
+	dockerLabelBuildTimestampKey = "ai.intrinsic.build.timestamp"
+	dockerLabelSDKVersionKey     = "ai.intrinsic.build.sdk-version"
+
 	// The following labels are DEPRECATED and should not be used other than for backwards
 	// compatibility.
 	deprecatedDockerLabelSkillIDProtoKey = "ai.intrinsic.skill-id"
@@ -343,7 +350,13 @@ func SkillIDFromTarget(target string, targetType TargetType, t imagetransfer.Tra
 			return "", fmt.Errorf("could not extract a skill id from the given build target %s: %v", target, err)
 		}
 		return SkillIDFromTarget(archivePath, Archive, t)
-	case Archive, Image:
+	case Archive:
+		installerParams, err := skillInstallerParamsFromArchive(target)
+		if err != nil {
+			return "", err
+		}
+		return installerParams.SkillID, nil
+	case Image:
 		image, err := GetImage(target, targetType, t)
 		if err != nil {
 			return "", fmt.Errorf("could not read image: %v", err)
@@ -379,14 +392,18 @@ func GetSkillInstallerParams(image containerregistry.Image) (*SkillInstallerPara
 	}
 	imageLabels := configFile.Config.Labels
 	skillID, ok := imageLabels[dockerLabelSkillIDKey]
-	if !ok {
+	if ok {
+		if err := idutils.ValidateID(skillID); err != nil {
+			return nil, fmt.Errorf("invalid image label %q=%q: expected an asset id of the form <package>.<name>", dockerLabelSkillIDKey, skillID)
+		}
+	} else {
 		// Backward-compatibility for deprecated image labels.
 		idProto := &idpb.Id{}
 		if skillIDBinary, ok := imageLabels[deprecatedDockerLabelSkillIDProtoKey]; !ok {
 			skillName, skillNameOK := imageLabels[deprecatedDockerLabelSkillName]
 			skillPackage, skillPackageOK := imageLabels[deprecatedDockerLabelPackageName]
 			if !skillNameOK || !skillPackageOK {
-				return nil, fmt.Errorf("cannot recover skill ID from image labels")
+				return nil, fmt.Errorf("cannot recover skill ID from image labels: expected label %q with an asset id of the form <package>.<name>", dockerLabelSkillIDKey)
 			} else if skillID, err = idutils.IDFrom(skillPackage, skillName); err != nil {
 				return nil, fmt.Errorf("invalid skill ID: %v", err)
 			}
@@ -407,6 +424,34 @@ func GetSkillInstallerParams(image containerregistry.Image) (*SkillInstallerPara
 	}, nil
 }
 
+// BuildInfo describes how an image was built. Fields are empty if the image does not have the
+// corresponding label, e.g., because the build was not stamped.
+type BuildInfo struct {
+	GitCommit string `json:"gitCommit,omitempty"`
+	// BuildTime is the time of the build in RFC 3339 format.
+	BuildTime  string `json:"buildTime,omitempty"`
+	SDKVersion string `json:"sdkVersion,omitempty"`
+}
+
+// GetBuildInfo retrieves the build info labels of an image.
+func GetBuildInfo(image containerregistry.Image) (*BuildInfo, error) {
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not extract build info labels from image file")
+	}
+	labels := configFile.Config.Labels
+	info := &BuildInfo{
+		GitCommit:  labels[dockerLabelGitCommitKey],
+		BuildTime:  labels[dockerLabelBuildTimestampKey],
+		SDKVersion: labels[dockerLabelSDKVersionKey],
+	}
+	// Bazel provides the build time in seconds since the epoch.
+	if seconds, err := strconv.ParseInt(info.BuildTime, 10, 64); err == nil {
+		info.BuildTime = time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+	}
+	return info, nil
+}
+
 // InstallContainerParams holds parameters for InstallContainer.
 type InstallContainerParams struct {
 	Address    string
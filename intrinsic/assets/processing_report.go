@@ -0,0 +1,113 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package bundleio
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ipb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
+)
+
+// Kinds of files in a bundle, as reported in FileReport.Kind.
+const (
+	FileKindManifest             = "manifest"
+	FileKindFormatVersion        = "format version"
+	FileKindDefaultConfiguration = "default configuration"
+	FileKindDescriptors          = "parameter descriptors"
+	FileKindImage                = "image"
+)
+
+// ProcessingReport describes what processing a bundle did.  It is filled in
+// if passed to ProcessService in ProcessServiceOpts.Report.
+type ProcessingReport struct {
+	// Files lists the regular files of the bundle in archive order.
+	Files []FileReport `json:"files"`
+	// Images lists the images which were processed, in archive order.
+	Images []ImageReport `json:"images"`
+	// Steps lists the steps of the processing in the order they ran.
+	Steps []StepReport `json:"steps"`
+}
+
+// FileReport describes a file in a bundle.
+type FileReport struct {
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"sizeBytes"`
+	// Kind is one of the FileKind constants.
+	Kind string `json:"kind"`
+}
+
+// ImageReport describes an image which was processed by the ImageProcessor.
+type ImageReport struct {
+	// Filename is the name of the image archive in the bundle.
+	Filename string `json:"filename"`
+	// Image is the reference of the image returned by the ImageProcessor.
+	Image string `json:"image"`
+	// Digest is the digest of the image, if the ImageProcessor returned the
+	// image by digest.
+	Digest   string        `json:"digest,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// StepReport describes a step of the processing.
+type StepReport struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Duration returns the total duration of all steps.
+func (r *ProcessingReport) Duration() time.Duration {
+	var d time.Duration
+	for _, s := range r.Steps {
+		d += s.Duration
+	}
+	return d
+}
+
+// addStep records a step which started at start and ends now.  r may be nil.
+func (r *ProcessingReport) addStep(name string, start time.Time) {
+	if r != nil {
+		r.Steps = append(r.Steps, StepReport{Name: name, Duration: time.Since(start)})
+	}
+}
+
+// addImage records an image which was processed in d.  r may be nil.
+func (r *ProcessingReport) addImage(filename string, img *ipb.Image, d time.Duration) {
+	if r == nil {
+		return
+	}
+	report := ImageReport{
+		Filename: filename,
+		Image:    img.GetRegistry() + "/" + img.GetName() + img.GetTag(),
+		Duration: d,
+	}
+	if strings.HasPrefix(img.GetTag(), "@sha256:") {
+		report.Digest = strings.TrimPrefix(img.GetTag(), "@")
+	}
+	r.Images = append(r.Images, report)
+}
+
+// String formats the report for humans, e.g., for a --verbose flag.
+func (r *ProcessingReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Processed bundle in %s\n", r.Duration().Round(time.Millisecond))
+	w := tabwriter.NewWriter(&b, 1, 1, 2, ' ', 0)
+	fmt.Fprintln(w, "Steps:")
+	for _, s := range r.Steps {
+		fmt.Fprintf(w, "  %s\t%s\n", s.Name, s.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintln(w, "Files:")
+	for _, f := range r.Files {
+		fmt.Fprintf(w, "  %s\t%d bytes\t%s\n", f.Name, f.SizeBytes, f.Kind)
+	}
+	if len(r.Images) > 0 {
+		fmt.Fprintln(w, "Images:")
+	}
+	for _, i := range r.Images {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", i.Filename, i.Image, i.Duration.Round(time.Millisecond))
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package bundleio
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	idpb "intrinsic/assets/proto/id_go_proto"
+	smpb "intrinsic/assets/services/proto/service_manifest_go_proto"
+	ipb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
+)
+
+func TestProcessServiceReport(t *testing.T) {
+	dir := t.TempDir()
+	imageTar := filepath.Join(dir, "image.tar")
+	if err := os.WriteFile(imageTar, []byte("not really an image"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	bundle := filepath.Join(dir, "bundle.tar")
+	err := WriteService(bundle, WriteServiceOpts{
+		Manifest: &smpb.ServiceManifest{
+			Metadata: &smpb.ServiceMetadata{Id: &idpb.Id{Package: "ai.intrinsic", Name: "test"}},
+		},
+		Config:        &anypb.Any{TypeUrl: "type.googleapis.com/test.Config"},
+		ImageTars:     []string{imageTar},
+		FormatVersion: FormatVersion2,
+	})
+	if err != nil {
+		t.Fatalf("WriteService() failed: %v", err)
+	}
+
+	report := &ProcessingReport{}
+	_, err = ProcessService(bundle, ProcessServiceOpts{
+		ImageProcessor: func(_ *idpb.Id, _ string, r io.Reader) (*ipb.Image, error) {
+			io.Copy(io.Discard, r)
+			return &ipb.Image{Registry: "gcr.io/test", Name: "test", Tag: "@sha256:abc"}, nil
+		},
+		Report: report,
+	})
+	if err != nil {
+		t.Fatalf("ProcessService() failed: %v", err)
+	}
+
+	kinds := map[string]string{}
+	for _, f := range report.Files {
+		kinds[f.Name] = f.Kind
+	}
+	want := map[string]string{
+		formatVersionPathInTar:    FileKindFormatVersion,
+		"default_config.binarypb": FileKindDefaultConfiguration,
+		"image.tar":               FileKindImage,
+		serviceManifestPathInTar:  FileKindManifest,
+	}
+	if len(kinds) != len(want) {
+		t.Errorf("report.Files = %v, want files %v", report.Files, want)
+	}
+	for name, kind := range want {
+		if kinds[name] != kind {
+			t.Errorf("kind of %q = %q, want %q", name, kinds[name], kind)
+		}
+	}
+	if len(report.Images) != 1 || report.Images[0].Digest != "sha256:abc" || report.Images[0].Image != "gcr.io/test/test@sha256:abc" {
+		t.Errorf("report.Images = %+v, want image.tar with digest sha256:abc", report.Images)
+	}
+	if len(report.Steps) != 3 {
+		t.Errorf("report.Steps = %+v, want 3 steps", report.Steps)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package progress reports the progress of install pipelines as structured events, so that the
+// same pipeline can be rendered as log lines for humans or as a JSON event stream for CI systems.
+package progress
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"intrinsic/tools/inctl/util/printer"
+)
+
+// Stage is a step of an install pipeline.
+type Stage string
+
+const (
+	// StageVerify checks the asset before it is uploaded.
+	StageVerify Stage = "verify"
+	// StagePush uploads the images of the asset.
+	StagePush Stage = "push"
+	// StageInstall installs the asset in the cluster.
+	StageInstall Stage = "install"
+	// StageWait waits until the installed asset is available.
+	StageWait Stage = "wait"
+	// StageDone is reported once the asset is installed and available.
+	StageDone Stage = "done"
+	// StageFailed is reported if the pipeline of the asset failed.
+	StageFailed Stage = "failed"
+)
+
+// stagePercent is the overall progress of an asset when a stage starts. Stages do not report
+// progress within the stage, so this is the only source of the percentage.
+var stagePercent = map[Stage]int{
+	StageVerify:  0,
+	StagePush:    10,
+	StageInstall: 60,
+	StageWait:    80,
+	StageDone:    100,
+	StageFailed:  100,
+}
+
+// Event is a single progress update of an install pipeline.
+type Event struct {
+	// Asset identifies the asset the event is about, e.g., its target or id_version.
+	Asset string `json:"asset,omitempty"`
+	// Stage is the stage the pipeline of the asset is in.
+	Stage Stage `json:"stage"`
+	// Percent is the estimated overall progress of the pipeline of the asset.
+	Percent int `json:"percent"`
+	// Message describes the event for humans.
+	Message string `json:"message,omitempty"`
+	// Time is when the event occurred.
+	Time time.Time `json:"time"`
+}
+
+// Reporter consumes progress events. A nil Reporter discards all events.
+type Reporter func(Event)
+
+// Report sends an event for the given asset and stage to r.
+func (r Reporter) Report(asset string, stage Stage, format string, args ...any) {
+	if r == nil {
+		return
+	}
+	r(Event{
+		Asset:   asset,
+		Stage:   stage,
+		Percent: stagePercent[stage],
+		Message: fmt.Sprintf(format, args...),
+		Time:    time.Now(),
+	})
+}
+
+// LogReporter returns a Reporter which logs the message of each event, like the install commands
+// did before they reported structured events. Failures are not logged, the commands return them.
+func LogReporter() Reporter {
+	return func(e Event) {
+		if e.Stage != StageFailed {
+			log.Print(e.Message)
+		}
+	}
+}
+
+// NewReporter returns a Reporter for the output format of an inctl command, see
+// printer.NewPrinter: the JSON format prints each event as a JSON object, the text format logs the
+// messages. The Reporter can be used from multiple goroutines.
+func NewReporter(outputFormat string) (Reporter, error) {
+	if outputFormat == printer.TextOutputFormat {
+		return LogReporter(), nil
+	}
+	p, err := printer.NewPrinter(outputFormat)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	return func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		p.Print(e)
+	}, nil
+}
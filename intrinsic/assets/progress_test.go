@@ -0,0 +1,28 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package progress
+
+import (
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	var got []Event
+	r := Reporter(func(e Event) { got = append(got, e) })
+	r.Report("a.tar", StagePush, "Publishing %q", "a.tar")
+	r.Report("a.tar", StageDone, "Done")
+
+	if len(got) != 2 {
+		t.Fatalf("Report() sent %d events, want 2", len(got))
+	}
+	if got[0].Asset != "a.tar" || got[0].Stage != StagePush || got[0].Message != `Publishing "a.tar"` || got[0].Time.IsZero() {
+		t.Errorf("Report() sent %+v, want a push event for a.tar", got[0])
+	}
+	if got[0].Percent >= got[1].Percent || got[1].Percent != 100 {
+		t.Errorf("Report() sent percentages %d and %d, want increasing to 100", got[0].Percent, got[1].Percent)
+	}
+
+	// A nil Reporter discards events.
+	var nilReporter Reporter
+	nilReporter.Report("a.tar", StageDone, "Done")
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package receipt writes installation receipts, which record which asset version was installed
+// where, when and by whom. Receipts help to trace what was put on a cluster during commissioning.
+package receipt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"intrinsic/assets/idutils"
+	ipb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
+)
+
+const (
+	receiptsDirectory = "intrinsic/receipts"
+	fileMode          = 0644
+	dirMode           = 0755
+)
+
+// Receipt describes one installation of an asset.
+type Receipt struct {
+	// IDVersion is the id_version of the installed asset.
+	IDVersion string `json:"idVersion"`
+	// PreviousIDVersion is the id_version which was installed before and has been replaced by this
+	// installation, if any.
+	PreviousIDVersion string `json:"previousIdVersion,omitempty"`
+	// AssetType is the type of the installed asset, e.g., "skill".
+	AssetType string `json:"assetType"`
+	// Image is the reference of the installed container image, if any.
+	Image string `json:"image,omitempty"`
+	// ImageDigest is the digest of the installed container image, if known.
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// Cluster, Solution and Address identify the installation target as far as they are known.
+	Cluster  string `json:"cluster,omitempty"`
+	Solution string `json:"solution,omitempty"`
+	Address  string `json:"address,omitempty"`
+	// Project and Org are the cloud project and organization used for the installation.
+	Project string `json:"project,omitempty"`
+	Org     string `json:"org,omitempty"`
+	// User is the local user who installed the asset.
+	User string `json:"user,omitempty"`
+	// InstallTime is the time at which the installation finished.
+	InstallTime time.Time `json:"installTime"`
+}
+
+// SetImage records the reference and, if the image is referenced by digest, the digest of img.
+func (r *Receipt) SetImage(img *ipb.Image) {
+	r.Image = fmt.Sprintf("%s/%s%s", img.GetRegistry(), img.GetName(), img.GetTag())
+	if digest, ok := strings.CutPrefix(img.GetTag(), "@"); ok {
+		r.ImageDigest = digest
+	}
+}
+
+// ImageProto returns the installed container image as recorded by SetImage, or nil if the receipt
+// has no image.
+func (r *Receipt) ImageProto() *ipb.Image {
+	if r.Image == "" {
+		return nil
+	}
+	ref, tag := r.Image, ""
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		ref, tag = ref[:i], ref[i:]
+	} else if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, tag = ref[:i], ref[i:]
+	}
+	img := &ipb.Image{Name: ref, Tag: tag}
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		img.Registry, img.Name = ref[:i], ref[i+1:]
+	}
+	return img
+}
+
+// SameTarget reports whether r and o were installed to the same cluster, solution or address.
+func (r *Receipt) SameTarget(o *Receipt) bool {
+	return (r.Cluster != "" && r.Cluster == o.Cluster) ||
+		(r.Solution != "" && r.Solution == o.Solution) ||
+		(r.Address != "" && r.Address == o.Address)
+}
+
+// DefaultDir returns the directory in the user's config directory in which receipts are stored by
+// default.
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find the user config directory: %w", err)
+	}
+	return filepath.Join(configDir, receiptsDirectory), nil
+}
+
+// CurrentUser returns the name of the local user, or an empty string if it cannot be determined.
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Write stores r as a JSON file in dir and returns the path of the file. The file name is derived
+// from the installation time and the id_version, so receipts sort chronologically.
+func Write(dir string, r *Receipt) (string, error) {
+	if r.IDVersion == "" {
+		return "", fmt.Errorf("receipt has no id_version")
+	}
+	if r.InstallTime.IsZero() {
+		r.InstallTime = time.Now()
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("could not marshal receipt: %w", err)
+	}
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return "", fmt.Errorf("could not create receipt directory %q: %w", dir, err)
+	}
+	name := fmt.Sprintf("%s-%s.json", r.InstallTime.UTC().Format("20060102T150405Z"), r.IDVersion)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, append(b, '\n'), fileMode); err != nil {
+		return "", fmt.Errorf("could not write receipt %q: %w", path, err)
+	}
+	return path, nil
+}
+
+// List reads all receipts in dir, sorted by installation time. A missing directory yields no
+// receipts.
+func List(dir string) ([]*Receipt, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read receipt directory %q: %w", dir, err)
+	}
+	var receipts []*Receipt
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read receipt %q: %w", path, err)
+		}
+		r := &Receipt{}
+		if err := json.Unmarshal(b, r); err != nil {
+			return nil, fmt.Errorf("could not parse receipt %q: %w", path, err)
+		}
+		receipts = append(receipts, r)
+	}
+	sort.SliceStable(receipts, func(i, j int) bool {
+		return receipts[i].InstallTime.Before(receipts[j].InstallTime)
+	})
+	return receipts, nil
+}
+
+// Rollback finds the receipts needed to roll back the asset with the given id on the target of
+// target, which only needs its Cluster, Solution or Address set. It returns the receipt of the
+// latest installation of the asset and the receipt of the version it replaced, which records the
+// image to reinstall.
+func Rollback(receipts []*Receipt, id string, target *Receipt) (current *Receipt, previous *Receipt, err error) {
+	var candidates []*Receipt
+	for _, r := range receipts {
+		p, err := idutils.NewIDVersionParts(r.IDVersion)
+		if err != nil || p.ID() != id || !r.SameTarget(target) {
+			continue
+		}
+		candidates = append(candidates, r)
+	}
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("no installation receipt found for %q on this target", id)
+	}
+	current = candidates[len(candidates)-1]
+	for i := len(candidates) - 2; i >= 0; i-- {
+		r := candidates[i]
+		if r.IDVersion == current.IDVersion {
+			continue
+		}
+		if current.PreviousIDVersion != "" && r.IDVersion != current.PreviousIDVersion {
+			continue
+		}
+		return current, r, nil
+	}
+	if current.PreviousIDVersion != "" {
+		return nil, nil, fmt.Errorf("%q replaced %q, but no installation receipt with its image was found", current.IDVersion, current.PreviousIDVersion)
+	}
+	return nil, nil, fmt.Errorf("no version of %q installed before %q found", id, current.IDVersion)
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package receipt
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	ipb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
+)
+
+func TestImageProto(t *testing.T) {
+	for _, img := range []*ipb.Image{
+		{Registry: "gcr.io/my-project", Name: "skill-abc", Tag: "@sha256:20ab4f"},
+		{Registry: "localhost:5000", Name: "skill-abc", Tag: ":latest"},
+		{Registry: "localhost:5000", Name: "skill-abc"},
+	} {
+		r := &Receipt{}
+		r.SetImage(img)
+		if got := r.ImageProto(); !proto.Equal(got, img) {
+			t.Errorf("ImageProto() of %q = %v, want %v", r.Image, got, img)
+		}
+	}
+}
+
+func TestListAndRollback(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	for i, r := range []*Receipt{
+		{IDVersion: "ai.intrinsic.my_skill.0.0.1+a", Cluster: "cluster-a"},
+		{IDVersion: "ai.intrinsic.my_skill.0.0.1+b", Cluster: "cluster-b"},
+		{IDVersion: "ai.intrinsic.other.0.0.1+c", Cluster: "cluster-a"},
+		{IDVersion: "ai.intrinsic.my_skill.0.0.1+d", PreviousIDVersion: "ai.intrinsic.my_skill.0.0.1+a", Cluster: "cluster-a"},
+	} {
+		r.InstallTime = start.Add(time.Duration(i) * time.Minute)
+		if _, err := Write(dir, r); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+
+	receipts, err := List(dir)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(receipts) != 4 {
+		t.Fatalf("List() returned %d receipts, want 4", len(receipts))
+	}
+
+	current, previous, err := Rollback(receipts, "ai.intrinsic.my_skill", &Receipt{Cluster: "cluster-a"})
+	if err != nil {
+		t.Fatalf("Rollback() failed: %v", err)
+	}
+	if current.IDVersion != "ai.intrinsic.my_skill.0.0.1+d" || previous.IDVersion != "ai.intrinsic.my_skill.0.0.1+a" {
+		t.Errorf("Rollback() = %q, %q, want to replace +d with +a", current.IDVersion, previous.IDVersion)
+	}
+
+	if _, _, err := Rollback(receipts, "ai.intrinsic.my_skill", &Receipt{Cluster: "cluster-b"}); err == nil {
+		t.Error("Rollback() succeeded for a single installation, want error")
+	}
+}
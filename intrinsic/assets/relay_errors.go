@@ -0,0 +1,137 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package clientutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrClusterOffline is reported if the cloud relay cannot reach the cluster (HTTP 502).
+	ErrClusterOffline = errors.New("cluster is not connected to the cloud relay")
+	// ErrClusterTimeout is reported if the cluster did not answer in time via the relay (HTTP 504).
+	ErrClusterTimeout = errors.New("cluster did not respond in time")
+	// ErrRelayAuthExpired is reported if the relay rejected the credentials (HTTP 401).
+	ErrRelayAuthExpired = errors.New("authorization with the cloud relay failed")
+
+	// httpStatusRegex matches the error grpc-go reports if the server answered with plain HTTP.
+	httpStatusRegex = regexp.MustCompile(`unexpected HTTP status code received from server: (\d{3})`)
+)
+
+// RelayError is a failure of the cloud relay in front of a cluster. It wraps one of the Err*
+// sentinels of this package, which can be matched with errors.Is, and the original gRPC error.
+type RelayError struct {
+	// Kind is one of ErrClusterOffline, ErrClusterTimeout or ErrRelayAuthExpired.
+	Kind error
+	// Cluster is the cluster the request was sent to, if known.
+	Cluster string
+	// Err is the original error.
+	Err error
+}
+
+func (e *RelayError) Error() string {
+	target := "the cluster"
+	if e.Cluster != "" {
+		target = fmt.Sprintf("cluster %q", e.Cluster)
+	}
+	var hint string
+	switch e.Kind {
+	case ErrClusterOffline:
+		hint = fmt.Sprintf("Make sure %s is turned on and connected to the internet. If it restarted in the last minutes, wait a couple of minutes and try again.", target)
+	case ErrClusterTimeout:
+		hint = fmt.Sprintf("%s may be overloaded or have a slow connection, try again later.", target)
+	case ErrRelayAuthExpired:
+		hint = "Your login key has expired or been replaced, run 'inctl auth login' to update it."
+	}
+	return fmt.Sprintf("%v: %s\n(%v)", e.Kind, hint, e.Err)
+}
+
+// Unwrap returns the kind and the original error.
+func (e *RelayError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// GRPCStatus returns the status of the original error, so that status.Code keeps working.
+func (e *RelayError) GRPCStatus() *status.Status {
+	s, _ := status.FromError(e.Err)
+	return s
+}
+
+// ConvertRelayError returns a RelayError if err was caused by the cloud relay answering with an
+// HTTP error instead of forwarding the request, and err otherwise.
+func ConvertRelayError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var relayErr *RelayError
+	if errors.As(err, &relayErr) {
+		return err
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	m := httpStatusRegex.FindStringSubmatch(s.Message())
+	if m == nil {
+		return err
+	}
+	code, _ := strconv.Atoi(m[1])
+	var kind error
+	switch code {
+	case 502:
+		kind = ErrClusterOffline
+	case 504:
+		kind = ErrClusterTimeout
+	case 401:
+		kind = ErrRelayAuthExpired
+	default:
+		return err
+	}
+	var cluster string
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if v := md.Get("x-server-name"); len(v) > 0 {
+			cluster = v[len(v)-1]
+		}
+	}
+	return &RelayError{Kind: kind, Cluster: cluster, Err: err}
+}
+
+// RelayErrorUnaryInterceptor converts relay failures of unary calls to RelayErrors.
+func RelayErrorUnaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return ConvertRelayError(ctx, invoker(ctx, method, req, reply, cc, opts...))
+}
+
+// RelayErrorStreamInterceptor converts relay failures of streaming calls to RelayErrors.
+func RelayErrorStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, ConvertRelayError(ctx, err)
+	}
+	return &relayErrorStream{ClientStream: s, ctx: ctx}, nil
+}
+
+type relayErrorStream struct {
+	grpc.ClientStream
+	ctx context.Context
+}
+
+func (s *relayErrorStream) SendMsg(m any) error {
+	return ConvertRelayError(s.ctx, s.ClientStream.SendMsg(m))
+}
+
+func (s *relayErrorStream) RecvMsg(m any) error {
+	return ConvertRelayError(s.ctx, s.ClientStream.RecvMsg(m))
+}
+
+func (s *relayErrorStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	return md, ConvertRelayError(s.ctx, err)
+}
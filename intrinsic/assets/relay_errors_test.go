@@ -0,0 +1,142 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package clientutils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// httpError returns the error grpc-go reports if the relay answered with the given HTTP status.
+func httpError(code string) error {
+	return status.Errorf(codes.Unavailable, "unexpected HTTP status code received from server: %s; transport: received unexpected content-type \"text/html\"", code)
+}
+
+func TestConvertRelayError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error
+	}{
+		{name: "bad gateway", err: httpError("502 (Bad Gateway)"), wantKind: ErrClusterOffline},
+		{name: "gateway timeout", err: httpError("504 (Gateway Timeout)"), wantKind: ErrClusterTimeout},
+		{name: "unauthorized", err: httpError("401 (Unauthorized)"), wantKind: ErrRelayAuthExpired},
+		{name: "other HTTP status", err: httpError("503 (Service Unavailable)")},
+		{name: "gRPC error", err: status.Error(codes.NotFound, "skill not found")},
+		{name: "non-gRPC error", err: errors.New("connection refused")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ConvertRelayError(context.Background(), tc.err)
+			if tc.wantKind == nil {
+				if got != tc.err {
+					t.Errorf("ConvertRelayError(%v) = %v, want the error unchanged", tc.err, got)
+				}
+				return
+			}
+			var relayErr *RelayError
+			if !errors.As(got, &relayErr) {
+				t.Fatalf("ConvertRelayError(%v) = %v, want a *RelayError", tc.err, got)
+			}
+			if !errors.Is(got, tc.wantKind) {
+				t.Errorf("ConvertRelayError(%v) = %v, want it to match %v", tc.err, got, tc.wantKind)
+			}
+			if !errors.Is(got, tc.err) {
+				t.Errorf("ConvertRelayError(%v) = %v, want it to wrap the original error", tc.err, got)
+			}
+			if status.Code(got) != codes.Unavailable {
+				t.Errorf("status.Code(ConvertRelayError(%v)) = %v, want %v", tc.err, status.Code(got), codes.Unavailable)
+			}
+			if again := ConvertRelayError(context.Background(), got); again != got {
+				t.Errorf("ConvertRelayError() of a RelayError = %v, want it unchanged", again)
+			}
+		})
+	}
+	if err := ConvertRelayError(context.Background(), nil); err != nil {
+		t.Errorf("ConvertRelayError(nil) = %v, want nil", err)
+	}
+}
+
+func TestConvertRelayErrorCluster(t *testing.T) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-server-name", "my-cluster")
+
+	err := ConvertRelayError(ctx, httpError("502 (Bad Gateway)"))
+	var relayErr *RelayError
+	if !errors.As(err, &relayErr) {
+		t.Fatalf("ConvertRelayError() = %v, want a *RelayError", err)
+	}
+	if relayErr.Cluster != "my-cluster" {
+		t.Errorf("RelayError.Cluster = %q, want %q", relayErr.Cluster, "my-cluster")
+	}
+	if !strings.Contains(err.Error(), `cluster "my-cluster"`) {
+		t.Errorf("ConvertRelayError() = %q, want it to name the cluster", err)
+	}
+}
+
+// fakeClientStream returns the given errors from SendMsg and RecvMsg.
+type fakeClientStream struct {
+	grpc.ClientStream
+	sendErr error
+	recvErr error
+}
+
+func (s *fakeClientStream) SendMsg(any) error { return s.sendErr }
+func (s *fakeClientStream) RecvMsg(any) error { return s.recvErr }
+
+func TestRelayErrorStreamInterceptor(t *testing.T) {
+	ctx := context.Background()
+	newStream := func(fake *fakeClientStream) grpc.ClientStream {
+		t.Helper()
+		s, err := RelayErrorStreamInterceptor(ctx, &grpc.StreamDesc{}, nil, "/test.Service/Stream",
+			func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+				return fake, nil
+			})
+		if err != nil {
+			t.Fatalf("RelayErrorStreamInterceptor() failed: %v", err)
+		}
+		return s
+	}
+
+	t.Run("RecvMsg EOF", func(t *testing.T) {
+		if err := newStream(&fakeClientStream{recvErr: io.EOF}).RecvMsg(nil); err != io.EOF {
+			t.Errorf("RecvMsg() = %v, want io.EOF", err)
+		}
+	})
+	t.Run("RecvMsg relay error", func(t *testing.T) {
+		if err := newStream(&fakeClientStream{recvErr: httpError("504 (Gateway Timeout)")}).RecvMsg(nil); !errors.Is(err, ErrClusterTimeout) {
+			t.Errorf("RecvMsg() = %v, want %v", err, ErrClusterTimeout)
+		}
+	})
+	t.Run("SendMsg relay error", func(t *testing.T) {
+		if err := newStream(&fakeClientStream{sendErr: httpError("502 (Bad Gateway)")}).SendMsg(nil); !errors.Is(err, ErrClusterOffline) {
+			t.Errorf("SendMsg() = %v, want %v", err, ErrClusterOffline)
+		}
+	})
+	t.Run("stream creation", func(t *testing.T) {
+		_, err := RelayErrorStreamInterceptor(ctx, &grpc.StreamDesc{}, nil, "/test.Service/Stream",
+			func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+				return nil, httpError("401 (Unauthorized)")
+			})
+		if !errors.Is(err, ErrRelayAuthExpired) {
+			t.Errorf("RelayErrorStreamInterceptor() = %v, want %v", err, ErrRelayAuthExpired)
+		}
+	})
+}
+
+func TestRelayErrorUnaryInterceptor(t *testing.T) {
+	err := RelayErrorUnaryInterceptor(context.Background(), "/test.Service/Call", nil, nil, nil,
+		func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			return httpError("502 (Bad Gateway)")
+		})
+	if !errors.Is(err, ErrClusterOffline) {
+		t.Errorf("RelayErrorUnaryInterceptor() = %v, want %v", err, ErrClusterOffline)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package clientutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Number of consecutive failed requests after which the circuit breaker of a connection opens.
+	circuitBreakerThreshold = 5
+	// How long the circuit breaker of a connection fails requests fast once it opened.
+	circuitBreakerCooldown = 10 * time.Second
+)
+
+// ErrCircuitOpen is reported for requests which were not sent because the previous requests on the
+// same connection failed, see CircuitBreaker.
+var ErrCircuitOpen = errors.New("too many failed requests")
+
+// RetryPolicy configures how gRPC retries failed requests, see
+// https://pkg.go.dev/google.golang.org/grpc/examples/features/retry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request, including the first one. gRPC caps
+	// it at 5. A value of 1 disables retries.
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	// RetryableCodes are the status codes with which a request is retried.
+	RetryableCodes []codes.Code
+}
+
+// DefaultRetryPolicy returns the retry policy for requests which pass the cloud ingress.
+//
+// The ingress returns UNIMPLEMENTED if the server it wants to forward to is unavailable, so
+// UNIMPLEMENTED is retried as well.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:       4,
+		InitialBackoff:    500 * time.Millisecond,
+		MaxBackoff:        500 * time.Millisecond,
+		BackoffMultiplier: 1.5,
+		RetryableCodes:    []codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.Unimplemented},
+	}
+}
+
+// DirectClusterRetryPolicy returns the retry policy for requests sent directly to a cluster.
+//
+// Unlike DefaultRetryPolicy it does not retry UNIMPLEMENTED, which a cluster only returns if the
+// API is actually missing.
+func DirectClusterRetryPolicy() *RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.RetryableCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted}
+	return p
+}
+
+// defaultRetryPolicy returns DirectClusterRetryPolicy if address is the address of a cluster which
+// is dialed without the cloud ingress, see UseInsecureCredentials, and DefaultRetryPolicy otherwise.
+func defaultRetryPolicy(address string) *RetryPolicy {
+	if UseInsecureCredentials(address) {
+		return DirectClusterRetryPolicy()
+	}
+	return DefaultRetryPolicy()
+}
+
+// ServiceConfig returns the gRPC service config which applies the policy to all methods.
+func (p *RetryPolicy) ServiceConfig() string {
+	methodConfig := map[string]any{"waitForReady": true}
+	if p.MaxAttempts > 1 {
+		methodConfig["retryPolicy"] = map[string]any{
+			"MaxAttempts":       p.MaxAttempts,
+			"InitialBackoff":    durationString(p.InitialBackoff),
+			"MaxBackoff":        durationString(p.MaxBackoff),
+			"BackoffMultiplier": p.BackoffMultiplier,
+			// The codes are serialized as numbers, which the service config accepts as well.
+			"RetryableStatusCodes": p.RetryableCodes,
+		}
+	}
+	config, err := json.Marshal(map[string]any{"methodConfig": []any{methodConfig}})
+	if err != nil {
+		// Cannot happen, all values are serializable.
+		panic(fmt.Sprintf("cannot serialize service config: %v", err))
+	}
+	return string(config)
+}
+
+// durationString formats d in the JSON format of google.protobuf.Duration.
+func durationString(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
+
+// dialOptions returns BaseDialOptions with the given retry policy and a new circuit breaker.
+func dialOptions(policy *RetryPolicy) []grpc.DialOption {
+	breaker := NewCircuitBreaker(circuitBreakerThreshold, circuitBreakerCooldown)
+	// Copy BaseDialOptions, appending to it directly could modify its backing array.
+	options := append([]grpc.DialOption{}, BaseDialOptions...)
+	return append(options,
+		grpc.WithDefaultServiceConfig(policy.ServiceConfig()),
+		grpc.WithChainUnaryInterceptor(breaker.UnaryInterceptor),
+		grpc.WithChainStreamInterceptor(breaker.StreamInterceptor),
+	)
+}
+
+// CircuitBreaker fails requests fast once a number of consecutive requests failed because the
+// server was unavailable or did not answer in time, instead of letting every request run into its
+// own timeout and retries. After a cooldown it lets requests through again.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	lastErr   error
+}
+
+// NewCircuitBreaker returns a CircuitBreaker which opens after threshold consecutive failures and
+// stays open for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+type circuitOpenError struct {
+	failures int
+	retryIn  time.Duration
+	lastErr  error
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("%v: the last %d requests failed, not sending requests for another %s. Last error: %v",
+		ErrCircuitOpen, e.failures, e.retryIn.Round(time.Second), e.lastErr)
+}
+
+func (e *circuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// GRPCStatus reports the error as UNAVAILABLE, so that callers which wait for a server keep
+// waiting.
+func (e *circuitOpenError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+// allow returns an error wrapping ErrCircuitOpen if the breaker is open.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	if now := b.now(); now.Before(b.openUntil) {
+		return &circuitOpenError{failures: b.failures, retryIn: b.openUntil.Sub(now), lastErr: b.lastErr}
+	}
+	// The cooldown passed, let requests through until the next failure.
+	return nil
+}
+
+// record updates the breaker with the result of a request.
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		if errors.Is(ctx.Err(), context.Canceled) {
+			// The caller gave up, this says nothing about the server.
+			return
+		}
+		b.failures++
+		b.lastErr = err
+		if b.failures >= b.threshold {
+			b.openUntil = b.now().Add(b.cooldown)
+		}
+	default:
+		b.failures = 0
+		b.lastErr = nil
+	}
+}
+
+// UnaryInterceptor fails unary calls fast while the breaker is open.
+func (b *CircuitBreaker) UnaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	b.record(ctx, err)
+	return err
+}
+
+// StreamInterceptor fails the creation of streams fast while the breaker is open. Errors of
+// established streams are not recorded.
+func (b *CircuitBreaker) StreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	b.record(ctx, err)
+	return s, err
+}
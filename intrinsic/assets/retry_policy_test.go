@@ -0,0 +1,75 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package clientutils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryPolicyServiceConfig(t *testing.T) {
+	for _, policy := range []*RetryPolicy{DefaultRetryPolicy(), DirectClusterRetryPolicy(), {MaxAttempts: 1}} {
+		// Dialing fails if the default service config is invalid.
+		conn, err := grpc.Dial("localhost:0",
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultServiceConfig(policy.ServiceConfig()))
+		if err != nil {
+			t.Errorf("grpc.Dial() with service config %s failed: %v", policy.ServiceConfig(), err)
+			continue
+		}
+		conn.Close()
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	var calls int
+	var callErr error
+	invoke := func() error {
+		return b.UnaryInterceptor(ctx, "/test.Service/Method", nil, nil, nil,
+			func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+				calls++
+				return callErr
+			})
+	}
+
+	callErr = status.Error(codes.Unavailable, "unavailable")
+	invoke()
+	// Other errors reset the count.
+	callErr = status.Error(codes.NotFound, "not found")
+	invoke()
+	callErr = status.Error(codes.Unavailable, "unavailable")
+	invoke()
+	invoke()
+	if calls != 4 {
+		t.Fatalf("got %d calls before the breaker opened, want 4", calls)
+	}
+
+	err := invoke()
+	if !errors.Is(err, ErrCircuitOpen) || status.Code(err) != codes.Unavailable {
+		t.Errorf("request to open breaker returned %v, want an UNAVAILABLE error wrapping %v", err, ErrCircuitOpen)
+	}
+	if calls != 4 {
+		t.Errorf("open breaker sent the request")
+	}
+
+	now = now.Add(time.Minute)
+	callErr = nil
+	if err := invoke(); err != nil {
+		t.Errorf("request after the cooldown failed: %v", err)
+	}
+	if err := invoke(); err != nil {
+		t.Errorf("request to closed breaker failed: %v", err)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"intrinsic/assets/bundleio"
 	"intrinsic/assets/idutils"
 	smpb "intrinsic/assets/services/proto/service_manifest_go_proto"
+	"intrinsic/util/proto/descriptorset"
 	"intrinsic/util/proto/protoio"
 	"intrinsic/util/proto/registryutil"
 )
@@ -29,6 +30,8 @@ type ServiceData struct {
 	Manifest string
 	// Bundle tar path.
 	OutputBundle string
+	// Optional bundle format version to write.  Zero selects the legacy format.
+	BundleFormatVersion int
 }
 
 func validateManifest(m *smpb.ServiceManifest) error {
@@ -89,7 +92,7 @@ func CreateService(d *ServiceData) error {
 	if d.FileDescriptorSets != "" {
 		fds = strings.Split(d.FileDescriptorSets, ",")
 	}
-	set, err := registryutil.LoadFileDescriptorSets(fds)
+	set, err := descriptorset.ReadTransitive(fds)
 	if err != nil {
 		return fmt.Errorf("unable to build FileDescriptorSet: %v", err)
 	}
@@ -117,10 +120,11 @@ func CreateService(d *ServiceData) error {
 	}
 
 	if err := bundleio.WriteService(d.OutputBundle, bundleio.WriteServiceOpts{
-		Manifest:    m,
-		Descriptors: set,
-		Config:      defaultConfig,
-		ImageTars:   imageTarsList,
+		Manifest:      m,
+		Descriptors:   set,
+		Config:        defaultConfig,
+		ImageTars:     imageTarsList,
+		FormatVersion: bundleio.FormatVersion(d.BundleFormatVersion),
 	}); err != nil {
 		return fmt.Errorf("unable to write service bundle: %v", err)
 	}
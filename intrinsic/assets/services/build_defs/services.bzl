@@ -62,6 +62,8 @@ def _intrinsic_service_impl(ctx):
     if ctx.file.default_config:
         inputs.append(ctx.file.default_config)
         args.add("--default_config", ctx.file.default_config.path)
+    if ctx.attr.bundle_format_version:
+        args.add("--bundle_format_version", ctx.attr.bundle_format_version)
 
     ctx.actions.run(
         inputs = depset(inputs, transitive = transitive_inputs),
@@ -87,6 +89,14 @@ def _intrinsic_service_impl(ctx):
 intrinsic_service = rule(
     implementation = _intrinsic_service_impl,
     attrs = {
+        "bundle_format_version": attr.int(
+            default = 0,
+            doc = (
+                "The bundle format version to write. Defaults to the legacy " +
+                "format, which all clusters can install. Set to 2 to record the " +
+                "format version in the bundle, which older releases cannot read."
+            ),
+        ),
         "default_config": attr.label(
             allow_single_file = [".pbtxt", ".textproto"],
         ),
@@ -8,9 +8,7 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"time"
 
-	oppb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/proto"
 	anypb "google.golang.org/protobuf/types/known/anypb"
@@ -22,6 +20,7 @@ import (
 	atpb "intrinsic/assets/proto/asset_type_go_proto"
 	"intrinsic/assets/version"
 	rrgrpcpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+	"intrinsic/util/grpc/lroutil"
 )
 
 const (
@@ -99,14 +98,9 @@ $ inctl service add ai.intrinsic.basler_camera \
 			}
 
 			log.Printf("Awaiting completion of the add operation")
-			for !op.GetDone() {
-				time.Sleep(15 * time.Millisecond)
-				op, err = client.GetOperation(ctx, &oppb.GetOperationRequest{
-					Name: op.GetName(),
-				})
-				if err != nil {
-					return fmt.Errorf("unable to check status of create operation for %q: %v", name, err)
-				}
+			op, err = lroutil.WaitWithBackoff(ctx, client, op, lroutil.WaitOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to check status of create operation for %q: %v", name, err)
 			}
 
 			if err := op.GetError(); err != nil {
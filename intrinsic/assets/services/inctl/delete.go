@@ -7,14 +7,13 @@ package delete
 import (
 	"fmt"
 	"log"
-	"time"
 
-	oppb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/spf13/cobra"
 	"intrinsic/assets/clientutils"
 	"intrinsic/assets/cmdutils"
 	adgrpcpb "intrinsic/assets/proto/asset_deployment_go_grpc_proto"
 	adpb "intrinsic/assets/proto/asset_deployment_go_grpc_proto"
+	"intrinsic/util/grpc/lroutil"
 )
 
 // GetCommand returns a command to delete a service instance from a solution.
@@ -38,6 +37,10 @@ $ inctl service delete --project=my_project --cluster=some_cluster my_instance
 			}
 			defer conn.Close()
 
+			if err := flags.Confirm(fmt.Sprintf("Delete service instance %q", name)); err != nil {
+				return err
+			}
+
 			log.Printf("Requesting deletion of %q", name)
 			client := adgrpcpb.NewAssetDeploymentServiceClient(conn)
 			op, err := client.DeleteResource(ctx, &adpb.DeleteResourceRequest{
@@ -49,14 +52,9 @@ $ inctl service delete --project=my_project --cluster=some_cluster my_instance
 			}
 
 			log.Printf("Awaiting completion of the delete operation")
-			for !op.GetDone() {
-				time.Sleep(15 * time.Millisecond)
-				op, err = client.GetOperation(ctx, &oppb.GetOperationRequest{
-					Name: op.GetName(),
-				})
-				if err != nil {
-					return fmt.Errorf("unable to check status of delete operation for %q: %v", name, err)
-				}
+			op, err = lroutil.WaitWithBackoff(ctx, client, op, lroutil.WaitOptions{})
+			if err != nil {
+				return fmt.Errorf("unable to check status of delete operation for %q: %v", name, err)
 			}
 
 			if err := op.GetError(); err != nil {
@@ -71,6 +69,7 @@ $ inctl service delete --project=my_project --cluster=some_cluster my_instance
 	flags.SetCommand(cmd)
 	flags.AddFlagsAddressClusterSolution()
 	flags.AddFlagsProjectOrg()
+	flags.AddFlagYes()
 
 	return cmd
 }
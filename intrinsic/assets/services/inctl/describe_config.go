@@ -0,0 +1,200 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package describeconfig defines the command which describes the configuration of a service.
+package describeconfig
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+	"intrinsic/assets/bundleio"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	rrgrpcpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+	"intrinsic/util/proto/registryutil"
+)
+
+const (
+	keyInstance = "instance"
+	keyMessage  = "message"
+)
+
+// writeSchema writes the fields of md and of the messages they contain, with their leading
+// comments if the descriptors have source code info. Messages which were already written, e.g.,
+// recursive ones, are only referenced by name.
+func writeSchema(w io.Writer, md protoreflect.MessageDescriptor) {
+	fmt.Fprintf(w, "%s\n", md.FullName())
+	writeComment(w, md, "  ")
+	writeFields(w, md, "  ", map[protoreflect.FullName]bool{md.FullName(): true})
+}
+
+func writeFields(w io.Writer, md protoreflect.MessageDescriptor, indent string, seen map[protoreflect.FullName]bool) {
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		fmt.Fprintf(w, "%s%s: %s\n", indent, fd.Name(), fieldType(fd))
+		writeComment(w, fd, indent+"    ")
+
+		var nested protoreflect.MessageDescriptor
+		switch {
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			nested = fd.MapValue().Message()
+		case !fd.IsMap() && fd.Message() != nil:
+			nested = fd.Message()
+		}
+		if nested == nil || seen[nested.FullName()] || isWellKnown(nested) {
+			continue
+		}
+		seen[nested.FullName()] = true
+		writeFields(w, nested, indent+"  ", seen)
+	}
+}
+
+// fieldType returns the type of fd as it would be written in a .proto file.
+func fieldType(fd protoreflect.FieldDescriptor) string {
+	kind := func(fd protoreflect.FieldDescriptor) string {
+		switch fd.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			return string(fd.Message().FullName())
+		case protoreflect.EnumKind:
+			values := fd.Enum().Values()
+			names := make([]string, values.Len())
+			for i := range names {
+				names[i] = string(values.Get(i).Name())
+			}
+			return fmt.Sprintf("%s {%s}", fd.Enum().FullName(), strings.Join(names, ", "))
+		default:
+			return fd.Kind().String()
+		}
+	}
+	switch {
+	case fd.IsMap():
+		return fmt.Sprintf("map<%s, %s>", kind(fd.MapKey()), kind(fd.MapValue()))
+	case fd.IsList():
+		return "repeated " + kind(fd)
+	case fd.HasPresence() && fd.Message() == nil && fd.ContainingOneof() == nil:
+		return "optional " + kind(fd)
+	default:
+		return kind(fd)
+	}
+}
+
+// isWellKnown reports whether md is one of the google.protobuf types, which are not expanded.
+func isWellKnown(md protoreflect.MessageDescriptor) bool {
+	return md.ParentFile().Package() == "google.protobuf"
+}
+
+// writeComment writes the leading comment of d, if any, with each line prefixed by indent.
+func writeComment(w io.Writer, d protoreflect.Descriptor, indent string) {
+	comment := strings.TrimSpace(d.ParentFile().SourceLocations().ByDescriptor(d).LeadingComments)
+	if comment == "" {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		fmt.Fprintf(w, "%s# %s\n", indent, strings.TrimSpace(line))
+	}
+}
+
+// GetCommand returns a command to describe the configuration of a service.
+func GetCommand() *cobra.Command {
+	flags := cmdutils.NewCmdFlags()
+	cmd := &cobra.Command{
+		Use:   "describe-config bundle",
+		Short: "Describe the configuration of a service",
+		Long: `Prints the schema of the configuration message of a service bundle, i.e., its fields and
+their types and comments, followed by the default configuration.
+
+If --instance is set, the configuration currently applied to that service instance in the
+solution is printed as well. The schema is read from the bundle since the cluster does not
+provide the descriptors of installed services.`,
+		Example: `
+	Describe the configuration of a service bundle:
+	$ inctl service describe-config abc/service_bundle.tar
+
+	Also print the configuration of a service instance in a solution:
+	$ inctl service describe-config abc/service_bundle.tar \
+			--instance my_instance \
+			--org my_org \
+			--solution my_solution_id
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := args[0]
+			manifest, err := bundleio.ProcessService(target, bundleio.ProcessServiceOpts{})
+			if err != nil {
+				return fmt.Errorf("could not read bundle file %q: %v", target, err)
+			}
+			assets := manifest.GetAssets()
+			types, err := registryutil.NewTypesFromFileDescriptorSet(assets.GetFileDescriptorSet())
+			if err != nil {
+				return fmt.Errorf("invalid configuration descriptors in %q: %v", target, err)
+			}
+
+			name := flags.GetString(keyMessage)
+			if name == "" {
+				if assets.GetDefaultConfiguration() == nil {
+					return fmt.Errorf("%q has no default configuration, use --%s to select the configuration message", target, keyMessage)
+				}
+				name = string(assets.GetDefaultConfiguration().MessageName())
+			}
+			mt, err := types.FindMessageByName(protoreflect.FullName(name))
+			if err != nil {
+				return fmt.Errorf("configuration message %q not found in %q: %v", name, target, err)
+			}
+
+			out := cmd.OutOrStdout()
+			writeSchema(out, mt.Descriptor())
+			format := func(cfg *anypb.Any) string {
+				options := prototext.MarshalOptions{Multiline: true, Resolver: types}
+				msg, err := anypb.UnmarshalNew(cfg, proto.UnmarshalOptions{Resolver: types})
+				if err != nil {
+					return options.Format(cfg)
+				}
+				return options.Format(msg)
+			}
+			if cfg := assets.GetDefaultConfiguration(); cfg != nil {
+				fmt.Fprintf(out, "\nDefault configuration:\n%s", format(cfg))
+			}
+
+			instance := flags.GetString(keyInstance)
+			if instance == "" {
+				return nil
+			}
+			ctx, conn, _, err := clientutils.DialClusterFromInctl(cmd.Context(), flags)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			resp, err := rrgrpcpb.NewResourceRegistryClient(conn).GetResourceInstance(ctx, &rrgrpcpb.GetResourceInstanceRequest{
+				Name: instance,
+			})
+			if err != nil {
+				return fmt.Errorf("could not get service instance %q: %v", instance, err)
+			}
+			cfg := resp.GetConfiguration()
+			if cfg == nil {
+				fmt.Fprintf(out, "\nService instance %q has no configuration.\n", instance)
+				return nil
+			}
+			if cfg.MessageName() != mt.Descriptor().FullName() {
+				fmt.Fprintf(out, "\nWarning: service instance %q is configured with %s, not %s.\n", instance, cfg.MessageName(), name)
+			}
+			fmt.Fprintf(out, "\nConfiguration of %q:\n%s", instance, format(cfg))
+			return nil
+		},
+	}
+
+	flags.SetCommand(cmd)
+	flags.AddFlagsAddressClusterSolution()
+	flags.AddFlagsProjectOrg()
+	flags.OptionalString(keyInstance, "", "Name of a service instance in the solution whose applied configuration to print.")
+	flags.OptionalString(keyMessage, "", "Full name of the configuration message. Defaults to the type of the default configuration.")
+
+	return cmd
+}
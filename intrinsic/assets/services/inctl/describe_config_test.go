@@ -0,0 +1,66 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package describeconfig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	dpb "google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestWriteSchema(t *testing.T) {
+	fdp := &dpb.FileDescriptorProto{
+		Name:       proto.String("config.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/duration.proto"},
+		MessageType: []*dpb.DescriptorProto{{
+			Name: proto.String("Config"),
+			Field: []*dpb.FieldDescriptorProto{
+				{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Label: dpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: dpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("children"), JsonName: proto.String("children"), Number: proto.Int32(2), Label: dpb.FieldDescriptorProto_LABEL_REPEATED.Enum(), Type: dpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".test.Config")},
+				{Name: proto.String("timeout"), JsonName: proto.String("timeout"), Number: proto.Int32(3), Label: dpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: dpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".google.protobuf.Duration")},
+				{Name: proto.String("mode"), JsonName: proto.String("mode"), Number: proto.Int32(4), Label: dpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: dpb.FieldDescriptorProto_TYPE_ENUM.Enum(), TypeName: proto.String(".test.Mode")},
+			},
+		}},
+		EnumType: []*dpb.EnumDescriptorProto{{
+			Name: proto.String("Mode"),
+			Value: []*dpb.EnumValueDescriptorProto{
+				{Name: proto.String("MODE_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("MODE_FAST"), Number: proto.Int32(1)},
+			},
+		}},
+		SourceCodeInfo: &dpb.SourceCodeInfo{
+			Location: []*dpb.SourceCodeInfo_Location{
+				{Path: []int32{4, 0}, Span: []int32{1, 0, 10}, LeadingComments: proto.String(" Configuration of the service.\n")},
+				{Path: []int32{4, 0, 2, 0}, Span: []int32{3, 2, 20}, LeadingComments: proto.String(" Name of the robot.\n Must be unique.\n")},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() failed: %v", err)
+	}
+
+	var b strings.Builder
+	writeSchema(&b, fd.Messages().ByName("Config"))
+
+	want := `test.Config
+  # Configuration of the service.
+  name: string
+      # Name of the robot.
+      # Must be unique.
+  children: repeated test.Config
+  timeout: google.protobuf.Duration
+  mode: test.Mode {MODE_UNSPECIFIED, MODE_FAST}
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("writeSchema() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
@@ -4,9 +4,10 @@
 package install
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
-	"log"
+	"io"
 
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/spf13/cobra"
@@ -16,10 +17,13 @@ import (
 	"intrinsic/assets/cmdutils"
 	"intrinsic/assets/idutils"
 	"intrinsic/assets/imagetransfer"
+	"intrinsic/assets/progress"
 	installergrpcpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
 	installerpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
 	"intrinsic/skills/tools/resource/cmd/bundleimages"
 	"intrinsic/skills/tools/skill/cmd/directupload"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
 )
 
 // GetCommand returns a command to install (sideload) the service bundle.
@@ -41,75 +45,32 @@ func GetCommand() *cobra.Command {
 	$ inctl service install abc/service_bundle.tar \
 			--org my_org \
 			--cluster my_cluster
+
+	Bundles can also be downloaded from a URL or pulled from a container registry:
+	$ inctl service install https://example.com/releases/service_bundle.tar \
+			--org my_org \
+			--solution my_solution_id
+	$ inctl service install oci://gcr.io/my-project/service_bundle@sha256:20ab4f \
+			--org my_org \
+			--solution my_solution_id
 	`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 			target := args[0]
-
-			ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, flags)
+			reporter, err := progress.NewReporter(root.FlagOutput)
 			if err != nil {
 				return err
 			}
-			defer conn.Close()
-
-			// Determine the image transferer to use. Default to direct injection into the cluster.
-			registry := flags.GetFlagRegistry()
-			remoteOpt, err := clientutils.RemoteOpt(flags)
-			if err != nil {
-				return err
-			}
-			transfer := imagetransfer.RemoteTransferer(remote.WithContext(ctx), remoteOpt)
-			if !flags.GetFlagSkipDirectUpload() {
-				opts := []directupload.Option{
-					directupload.WithDiscovery(directupload.NewFromConnection(conn)),
-					directupload.WithOutput(cmd.OutOrStdout()),
-				}
-				if registry != "" {
-					// User set external registry, so we can use it as failover.
-					opts = append(opts, directupload.WithFailOver(transfer))
-				} else {
-					// Fake name that ends in .local in order to indicate that this is local, directly
-					// uploaded image.
-					registry = "direct.upload.local"
-				}
-				transfer = directupload.NewTransferer(ctx, opts...)
+			out := cmd.OutOrStdout()
+			if root.FlagOutput != printer.TextOutputFormat {
+				// Keep stdout parseable, it only receives the progress events.
+				out = cmd.ErrOrStderr()
 			}
-
-			opts := bundleio.ProcessServiceOpts{
-				ImageProcessor: bundleimages.CreateImageProcessor(flags.CreateRegistryOptsWithTransferer(ctx, transfer, registry)),
-			}
-			manifest, err := bundleio.ProcessService(target, opts)
-			if err != nil {
-				return fmt.Errorf("could not read bundle file %q: %v", target, err)
-			}
-
-			pkg := manifest.GetMetadata().GetId().GetPackage()
-			name := manifest.GetMetadata().GetId().GetName()
-			manifestBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(manifest)
-			if err != nil {
-				return fmt.Errorf("could not marshal manifest: %v", err)
-			}
-			version := fmt.Sprintf("0.0.1+%x", sha256.Sum256(manifestBytes))
-			idVersion, err := idutils.IDVersionFrom(pkg, name, version)
-			if err != nil {
-				return fmt.Errorf("could not create id_version: %w", err)
-			}
-			log.Printf("Installing service %q", idVersion)
-
-			client := installergrpcpb.NewInstallerServiceClient(conn)
-			authCtx := clientutils.AuthInsecureConn(ctx, address, flags.GetFlagProject())
-
-			// This needs an authorized context to pull from the catalog if not available.
-			resp, err := client.InstallService(authCtx, &installerpb.InstallServiceRequest{
-				Manifest: manifest,
-				Version:  version,
-			})
-			if err != nil {
-				return fmt.Errorf("could not install the service: %v", err)
+			if err := install(ctx, flags, target, out, reporter); err != nil {
+				reporter.Report(target, progress.StageFailed, "%v", err)
+				return err
 			}
-			log.Printf("Finished installing the service: %q", resp.GetIdVersion())
-
 			return nil
 		},
 	}
@@ -120,6 +81,108 @@ func GetCommand() *cobra.Command {
 	flags.AddFlagRegistry()
 	flags.AddFlagsRegistryAuthUserPassword()
 	flags.AddFlagSkipDirectUpload("service")
+	flags.AddFlagIgnoreUpgradeState()
+	flags.AddFlagVerbose()
 
 	return cmd
 }
+
+// fetchBundle downloads the bundle if target is a remote reference and returns its local path.
+func fetchBundle(ctx context.Context, target string, remoteOpt remote.Option, reporter progress.Reporter) (string, func(), error) {
+	if bundleio.IsRemote(target) {
+		reporter.Report(target, progress.StageVerify, "Downloading service bundle %q", target)
+	}
+	path, cleanup, err := bundleio.Fetch(ctx, target, bundleio.FetchOpts{
+		RemoteOptions: []remote.Option{remoteOpt},
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("could not fetch bundle %q: %w", target, err)
+	}
+	return path, cleanup, nil
+}
+
+// install sideloads the service bundle at target, which is a local path, an https:// URL or an
+// oci:// artifact reference.
+func install(ctx context.Context, flags *cmdutils.CmdFlags, target string, out io.Writer, reporter progress.Reporter) error {
+	ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, flags)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := clientutils.CheckClusterUpgradeState(ctx, conn, flags); err != nil {
+		return err
+	}
+
+	// Determine the image transferer to use. Default to direct injection into the cluster.
+	registry := flags.GetFlagRegistry()
+	remoteOpt, err := clientutils.RemoteOpt(flags)
+	if err != nil {
+		return err
+	}
+	transfer := imagetransfer.RemoteTransferer(remote.WithContext(ctx), remoteOpt)
+
+	bundlePath, cleanup, err := fetchBundle(ctx, target, remoteOpt, reporter)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if !flags.GetFlagSkipDirectUpload() {
+		opts := []directupload.Option{
+			directupload.WithDiscovery(directupload.NewFromConnection(conn)),
+			directupload.WithOutput(out),
+		}
+		if registry != "" {
+			// User set external registry, so we can use it as failover.
+			opts = append(opts, directupload.WithFailOver(transfer))
+		} else {
+			// Fake name that ends in .local in order to indicate that this is local, directly
+			// uploaded image.
+			registry = "direct.upload.local"
+		}
+		transfer = directupload.NewTransferer(ctx, opts...)
+	}
+
+	reporter.Report(target, progress.StagePush, "Processing service bundle %q", target)
+	opts := bundleio.ProcessServiceOpts{
+		ImageProcessor: bundleimages.CreateImageProcessor(flags.CreateRegistryOptsWithTransferer(ctx, transfer, registry)),
+	}
+	if flags.GetFlagVerbose() {
+		opts.Report = &bundleio.ProcessingReport{}
+	}
+	manifest, err := bundleio.ProcessService(bundlePath, opts)
+	if err != nil {
+		return fmt.Errorf("could not read bundle file %q: %v", target, err)
+	}
+	if opts.Report != nil {
+		fmt.Fprintln(out, opts.Report)
+	}
+
+	pkg := manifest.GetMetadata().GetId().GetPackage()
+	name := manifest.GetMetadata().GetId().GetName()
+	manifestBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("could not marshal manifest: %v", err)
+	}
+	version := fmt.Sprintf("0.0.1+%x", sha256.Sum256(manifestBytes))
+	idVersion, err := idutils.IDVersionFrom(pkg, name, version)
+	if err != nil {
+		return fmt.Errorf("could not create id_version: %w", err)
+	}
+	reporter.Report(target, progress.StageInstall, "Installing service %q", idVersion)
+
+	client := installergrpcpb.NewInstallerServiceClient(conn)
+	authCtx := clientutils.AuthInsecureConn(ctx, address, flags.GetFlagProject())
+
+	// This needs an authorized context to pull from the catalog if not available.
+	resp, err := client.InstallService(authCtx, &installerpb.InstallServiceRequest{
+		Manifest: manifest,
+		Version:  version,
+	})
+	if err != nil {
+		return fmt.Errorf("could not install the service: %v", err)
+	}
+	reporter.Report(target, progress.StageDone, "Finished installing the service: %q", resp.GetIdVersion())
+
+	return nil
+}
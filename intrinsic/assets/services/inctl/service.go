@@ -7,25 +7,31 @@ import (
 	"github.com/spf13/cobra"
 	"intrinsic/assets/services/inctl/add"
 	deletecmd "intrinsic/assets/services/inctl/delete"
+	"intrinsic/assets/services/inctl/describeconfig"
 	"intrinsic/assets/services/inctl/install"
 	"intrinsic/assets/services/inctl/list"
 	"intrinsic/assets/services/inctl/uninstall"
 	"intrinsic/tools/inctl/cmd/root"
 )
 
-// ServiceCmd is the super-command for everything to manage services.
-var serviceCmd = &cobra.Command{
-	Use:   root.ServiceCmdName,
-	Short: "Manages service assets",
-	Long:  "Manages service assets",
-}
+const serviceShort = "Manages service assets"
 
-func init() {
+// newServiceCmd returns the super-command for everything to manage services.
+func newServiceCmd() *cobra.Command {
+	serviceCmd := &cobra.Command{
+		Use:   root.ServiceCmdName,
+		Short: serviceShort,
+		Long:  serviceShort,
+	}
 	serviceCmd.AddCommand(add.GetCommand())
 	serviceCmd.AddCommand(deletecmd.GetCommand())
+	serviceCmd.AddCommand(describeconfig.GetCommand())
 	serviceCmd.AddCommand(install.GetCommand())
 	serviceCmd.AddCommand(list.GetCommand())
 	serviceCmd.AddCommand(uninstall.GetCommand())
+	return serviceCmd
+}
 
-	root.RootCmd.AddCommand(serviceCmd)
+func init() {
+	root.AddLazyCommand(root.ServiceCmdName, serviceShort, newServiceCmd)
 }
@@ -52,6 +52,12 @@ func GetCommand() *cobra.Command {
 			if err := version.Autofill(ctx, rrgrpcpb.NewResourceRegistryClient(conn), idv); err != nil {
 				return err
 			}
+			if idvStr, err := idutils.IDVersionFromProto(idv); err == nil {
+				idOrIDVersion = idvStr
+			}
+			if err := flags.Confirm(fmt.Sprintf("Uninstall service %q", idOrIDVersion)); err != nil {
+				return err
+			}
 
 			client := installergrpcpb.NewInstallerServiceClient(conn)
 			_, err = client.UninstallService(ctx, &installerpb.UninstallServiceRequest{
@@ -60,12 +66,6 @@ func GetCommand() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("could not uninstall the service: %w", err)
 			}
-			// Ignore the errors, since it was somehow successful already, just
-			// use this to provide more clarity about exactly which version was
-			// removed.
-			if idvStr, err := idutils.IDVersionFromProto(idv); err != nil {
-				idOrIDVersion = idvStr
-			}
 			log.Printf("Finished uninstalling %q", idOrIDVersion)
 
 			return nil
@@ -75,6 +75,7 @@ func GetCommand() *cobra.Command {
 	flags.SetCommand(cmd)
 	flags.AddFlagsAddressClusterSolution()
 	flags.AddFlagsProjectOrg()
+	flags.AddFlagYes()
 
 	return cmd
 }
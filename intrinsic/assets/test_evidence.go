@@ -0,0 +1,242 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package bundleio
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"intrinsic/util/archive/tartooling"
+)
+
+const (
+	// testEvidencePathInTar is the entry holding the test evidence.  Container
+	// image tooling ignores unknown entries, so the evidence can be added to
+	// skill image archives without affecting how they are pushed or installed.
+	testEvidencePathInTar = "intrinsic_test_evidence.json"
+)
+
+// ErrNoTestEvidence is returned by ReadTestEvidence if the bundle does not
+// contain test evidence.
+var ErrNoTestEvidence = errors.New("bundle does not contain test evidence")
+
+// TestCase is the result of a single test case.
+type TestCase struct {
+	Name     string        `json:"name"`
+	Suite    string        `json:"suite,omitempty"`
+	Passed   bool          `json:"passed"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// TestEvidence records the unit test results for the contents of a bundle.
+type TestEvidence struct {
+	// Targets are the test targets the results were collected from.
+	Targets []string `json:"targets,omitempty"`
+	// Cases are the results of the individual test cases.
+	Cases []TestCase `json:"cases"`
+	// RecordedAt is the time at which the evidence was added to the bundle.
+	RecordedAt time.Time `json:"recordedAt"`
+	// ResultsDigest is the sha256 digest of the raw test result files.
+	ResultsDigest string `json:"resultsDigest,omitempty"`
+}
+
+// Counts returns the number of passed, failed and skipped test cases.
+func (e *TestEvidence) Counts() (passed, failed, skipped int) {
+	for _, c := range e.Cases {
+		switch {
+		case c.Skipped:
+			skipped++
+		case c.Passed:
+			passed++
+		default:
+			failed++
+		}
+	}
+	return passed, failed, skipped
+}
+
+// Passed returns nil if at least one test case ran and no test case failed.
+func (e *TestEvidence) Passed() error {
+	passed, failed, _ := e.Counts()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test cases failed", failed, len(e.Cases))
+	}
+	if passed == 0 {
+		return fmt.Errorf("no test cases were run")
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	Suites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name   string           `xml:"name,attr"`
+	Suites []junitTestSuite `xml:"testsuite"`
+	Cases  []junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string     `xml:"name,attr"`
+	ClassName string     `xml:"classname,attr"`
+	Time      float64    `xml:"time,attr"`
+	Failures  []struct{} `xml:"failure"`
+	Errors    []struct{} `xml:"error"`
+	Skipped   *struct{}  `xml:"skipped"`
+}
+
+func (s *junitTestSuite) collect(cases []TestCase) []TestCase {
+	for _, c := range s.Cases {
+		suite := c.ClassName
+		if suite == "" {
+			suite = s.Name
+		}
+		cases = append(cases, TestCase{
+			Name:     c.Name,
+			Suite:    suite,
+			Passed:   len(c.Failures) == 0 && len(c.Errors) == 0,
+			Skipped:  c.Skipped != nil,
+			Duration: time.Duration(c.Time * float64(time.Second)),
+		})
+	}
+	for i := range s.Suites {
+		cases = s.Suites[i].collect(cases)
+	}
+	return cases
+}
+
+// ParseJUnitXML parses test cases from a JUnit XML report such as the
+// test.xml files written by bazel test.  Both a <testsuites> and a single
+// <testsuite> root element are accepted.
+func ParseJUnitXML(b []byte) ([]TestCase, error) {
+	var suites junitTestSuites
+	if err := xml.Unmarshal(b, &suites); err == nil && len(suites.Suites) > 0 {
+		var cases []TestCase
+		for i := range suites.Suites {
+			cases = suites.Suites[i].collect(cases)
+		}
+		return cases, nil
+	}
+	var suite junitTestSuite
+	if err := xml.Unmarshal(b, &suite); err != nil {
+		return nil, fmt.Errorf("invalid JUnit XML: %v", err)
+	}
+	return suite.collect(nil), nil
+}
+
+// NewTestEvidence creates test evidence from JUnit XML reports.  targets
+// optionally names the test targets that produced the reports.
+func NewTestEvidence(reportPaths []string, targets []string) (*TestEvidence, error) {
+	evidence := &TestEvidence{Targets: targets, RecordedAt: time.Now().UTC()}
+	h := sha256.New()
+	for _, p := range reportPaths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %q: %v", p, err)
+		}
+		h.Write(b)
+		cases, err := ParseJUnitXML(b)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %q: %v", p, err)
+		}
+		evidence.Cases = append(evidence.Cases, cases...)
+	}
+	evidence.ResultsDigest = fmt.Sprintf("sha256:%x", h.Sum(nil))
+	return evidence, nil
+}
+
+// WriteTestEvidence writes the bundle at inPath to outPath with the given test
+// evidence added.  Existing evidence is replaced.  inPath and outPath may be the
+// same file.
+func WriteTestEvidence(inPath string, outPath string, evidence *TestEvidence) error {
+	if evidence == nil {
+		return fmt.Errorf("evidence must not be nil")
+	}
+	b, err := json.MarshalIndent(evidence, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal test evidence: %v", err)
+	}
+
+	in, err := os.Open(inPath)
+	if err != nil {
+		return fmt.Errorf("could not open %q: %v", inPath, err)
+	}
+	defer in.Close()
+
+	// Write to a temporary file first so that outPath is never left incomplete.
+	out, err := os.CreateTemp(filepath.Dir(outPath), filepath.Base(outPath)+".tmp")
+	if err != nil {
+		return fmt.Errorf("could not create temporary file: %v", err)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	tr := tar.NewReader(in)
+	tw := tar.NewWriter(out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error in tar file %q: %v", inPath, err)
+		}
+		if hdr.Name == testEvidencePathInTar {
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("could not write header for %q: %v", hdr.Name, err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return fmt.Errorf("could not copy %q: %v", hdr.Name, err)
+		}
+	}
+	if err := tartooling.AddBytes(b, tw, testEvidencePathInTar); err != nil {
+		return fmt.Errorf("unable to write test evidence to bundle: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(out.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(out.Name(), outPath); err != nil {
+		return fmt.Errorf("failed to write %q: %w", outPath, err)
+	}
+	return nil
+}
+
+// ReadTestEvidence reads the test evidence from the bundle at path.  It
+// returns ErrNoTestEvidence if the bundle does not contain any.
+func ReadTestEvidence(path string) (*TestEvidence, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %q: %v", path, err)
+	}
+	defer f.Close()
+
+	t := tar.NewReader(f)
+	if err := tartooling.SeekTo(t, testEvidencePathInTar); err == io.EOF {
+		return nil, ErrNoTestEvidence
+	} else if err != nil {
+		return nil, fmt.Errorf("error in tar file %q: %v", path, err)
+	}
+	evidence := &TestEvidence{}
+	if err := json.NewDecoder(t).Decode(evidence); err != nil {
+		return nil, fmt.Errorf("invalid test evidence in %q: %v", path, err)
+	}
+	return evidence, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package bundleio
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"intrinsic/util/archive/tartooling"
+)
+
+func TestParseJUnitXML(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		want    []TestCase
+		wantErr bool
+	}{
+		{
+			name: "pass",
+			xml: `<testsuites>
+  <testsuite name="suite">
+    <testcase name="TestA" time="1.5"></testcase>
+  </testsuite>
+</testsuites>`,
+			want: []TestCase{{Name: "TestA", Suite: "suite", Passed: true, Duration: 1500 * time.Millisecond}},
+		},
+		{
+			name: "failure",
+			xml: `<testsuite name="suite">
+  <testcase name="TestA" classname="pkg.Class"><failure message="boom"></failure></testcase>
+</testsuite>`,
+			want: []TestCase{{Name: "TestA", Suite: "pkg.Class", Passed: false}},
+		},
+		{
+			name: "error",
+			xml: `<testsuites>
+  <testsuite name="outer">
+    <testsuite name="inner">
+      <testcase name="TestA"><error message="panic"></error></testcase>
+    </testsuite>
+  </testsuite>
+</testsuites>`,
+			want: []TestCase{{Name: "TestA", Suite: "inner", Passed: false}},
+		},
+		{
+			name: "skipped",
+			xml: `<testsuite name="suite">
+  <testcase name="TestA"><skipped></skipped></testcase>
+  <testcase name="TestB"></testcase>
+</testsuite>`,
+			want: []TestCase{
+				{Name: "TestA", Suite: "suite", Passed: true, Skipped: true},
+				{Name: "TestB", Suite: "suite", Passed: true},
+			},
+		},
+		{
+			name:    "malformed",
+			xml:     `<testsuite name="suite"><testcase name="TestA">`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseJUnitXML([]byte(tc.xml))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ParseJUnitXML() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJUnitXML() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParseJUnitXML() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteAndReadTestEvidence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "skill.tar")
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tartooling.AddBytes([]byte("layer"), tw, "layer.tar"); err != nil {
+		t.Fatalf("AddBytes() failed: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	if _, err := ReadTestEvidence(path); !errors.Is(err, ErrNoTestEvidence) {
+		t.Fatalf("ReadTestEvidence() returned error %v, want %v", err, ErrNoTestEvidence)
+	}
+
+	want := &TestEvidence{
+		Targets:       []string{"//my:test"},
+		Cases:         []TestCase{{Name: "TestA", Suite: "suite", Passed: true, Duration: time.Second}},
+		RecordedAt:    time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+		ResultsDigest: "sha256:0123",
+	}
+	// Write twice to check that existing evidence is replaced and not duplicated.
+	for i := 0; i < 2; i++ {
+		if err := WriteTestEvidence(path, path, want); err != nil {
+			t.Fatalf("WriteTestEvidence() failed: %v", err)
+		}
+	}
+
+	got, err := ReadTestEvidence(path)
+	if err != nil {
+		t.Fatalf("ReadTestEvidence() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadTestEvidence() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if diff := cmp.Diff([]string{"layer.tar", testEvidencePathInTar}, names); diff != "" {
+		t.Errorf("WriteTestEvidence() wrote unexpected entries (-want +got):\n%s", diff)
+	}
+}
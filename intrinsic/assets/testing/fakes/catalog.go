@@ -0,0 +1,198 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package fakes
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	"intrinsic/assets/idutils"
+	atpb "intrinsic/assets/proto/asset_type_go_proto"
+	idpb "intrinsic/assets/proto/id_go_proto"
+	mpb "intrinsic/assets/proto/metadata_go_proto"
+	rtpb "intrinsic/assets/proto/release_tag_go_proto"
+	viewpb "intrinsic/assets/proto/view_go_proto"
+	skillcatalogpb "intrinsic/skills/catalog/proto/skill_catalog_go_grpc_proto"
+)
+
+// SkillCatalog is an in-memory skill catalog.
+//
+// All views return the full metadata of a skill.
+type SkillCatalog struct {
+	skillcatalogpb.UnimplementedSkillCatalogServer
+
+	mu       sync.Mutex
+	skills   map[string]*skillcatalogpb.Skill // By id_version.
+	requests map[string]*skillcatalogpb.CreateSkillRequest
+}
+
+// NewSkillCatalog creates an empty SkillCatalog.
+func NewSkillCatalog() *SkillCatalog {
+	return &SkillCatalog{
+		skills:   map[string]*skillcatalogpb.Skill{},
+		requests: map[string]*skillcatalogpb.CreateSkillRequest{},
+	}
+}
+
+// Register registers the catalog on s.
+func (c *SkillCatalog) Register(s *grpc.Server) {
+	skillcatalogpb.RegisterSkillCatalogServer(s, c)
+}
+
+// AddSkill adds a skill version to the catalog. The metadata must have a valid id_version. If the
+// release tag of the metadata is RELEASE_TAG_DEFAULT, the version becomes the default version of
+// the skill.
+func (c *SkillCatalog) AddSkill(metadata *mpb.Metadata) error {
+	idVersion, err := idutils.IDVersionFromProto(metadata.GetIdVersion())
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addLocked(idVersion, proto.Clone(metadata).(*mpb.Metadata))
+	return nil
+}
+
+// CreateRequest returns the request with which a skill version was created, e.g., to check the
+// uploaded image or manifest, or nil if the version was not created via CreateSkill.
+func (c *SkillCatalog) CreateRequest(idVersion string) *skillcatalogpb.CreateSkillRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	req, ok := c.requests[idVersion]
+	if !ok {
+		return nil
+	}
+	return proto.Clone(req).(*skillcatalogpb.CreateSkillRequest)
+}
+
+// addLocked adds a version and moves the default to it if it is tagged as default.
+func (c *SkillCatalog) addLocked(idVersion string, metadata *mpb.Metadata) {
+	if metadata.GetReleaseTag() == rtpb.ReleaseTag_RELEASE_TAG_DEFAULT {
+		c.clearDefaultLocked(metadata.GetIdVersion().GetId())
+	}
+	c.skills[idVersion] = &skillcatalogpb.Skill{Metadata: metadata}
+}
+
+// clearDefaultLocked removes the default tag from all versions of id and reports whether there
+// was a default version.
+func (c *SkillCatalog) clearDefaultLocked(id *idpb.Id) bool {
+	found := false
+	for _, s := range c.skills {
+		if proto.Equal(s.GetMetadata().GetIdVersion().GetId(), id) && s.GetMetadata().GetReleaseTag() == rtpb.ReleaseTag_RELEASE_TAG_DEFAULT {
+			s.GetMetadata().ReleaseTag = rtpb.ReleaseTag_RELEASE_TAG_UNSPECIFIED
+			found = true
+		}
+	}
+	return found
+}
+
+// GetSkill returns the requested skill version.
+func (c *SkillCatalog) GetSkill(ctx context.Context, req *skillcatalogpb.GetSkillRequest) (*skillcatalogpb.Skill, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	skill, ok := c.skills[req.GetIdVersion()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "skill %q not found", req.GetIdVersion())
+	}
+	return proto.Clone(skill).(*skillcatalogpb.Skill), nil
+}
+
+// ListSkills returns a page of skills sorted by id and version.
+func (c *SkillCatalog) ListSkills(ctx context.Context, req *skillcatalogpb.ListSkillsRequest) (*skillcatalogpb.ListSkillsResponse, error) {
+	filter := req.GetStrictFilter()
+	releaseTag := rtpb.ReleaseTag_RELEASE_TAG_DEFAULT
+	if req.GetView() == viewpb.AssetViewType_ASSET_VIEW_TYPE_VERSIONS {
+		releaseTag = rtpb.ReleaseTag_RELEASE_TAG_UNSPECIFIED
+	}
+	if filter != nil && filter.ReleaseTag != nil {
+		releaseTag = filter.GetReleaseTag()
+	}
+
+	c.mu.Lock()
+	var skills []*skillcatalogpb.Skill
+	for idVersion, s := range c.skills {
+		m := s.GetMetadata()
+		switch {
+		case releaseTag != rtpb.ReleaseTag_RELEASE_TAG_UNSPECIFIED && m.GetReleaseTag() != releaseTag:
+		case filter != nil && filter.Id != nil && !strings.HasPrefix(idVersion, filter.GetId()):
+		case filter != nil && filter.DisplayName != nil && !strings.HasPrefix(m.GetDisplayName(), filter.GetDisplayName()):
+		default:
+			skills = append(skills, proto.Clone(s).(*skillcatalogpb.Skill))
+		}
+	}
+	c.mu.Unlock()
+
+	sort.Slice(skills, func(i, j int) bool {
+		a, b := skills[i].GetMetadata().GetIdVersion(), skills[j].GetMetadata().GetIdVersion()
+		aID, _ := idutils.IDFromProto(a.GetId())
+		bID, _ := idutils.IDFromProto(b.GetId())
+		if aID != bID {
+			return aID < bID
+		}
+		if cmp, err := idutils.CompareVersions(a.GetVersion(), b.GetVersion()); err == nil && cmp != 0 {
+			return cmp < 0
+		}
+		return a.GetVersion() < b.GetVersion()
+	})
+	page, next, err := paginate(skills, req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &skillcatalogpb.ListSkillsResponse{Skills: page, NextPageToken: next}, nil
+}
+
+// CreateSkill adds a new skill version to the catalog.
+func (c *SkillCatalog) CreateSkill(ctx context.Context, req *skillcatalogpb.CreateSkillRequest) (*skillcatalogpb.Skill, error) {
+	manifest := req.GetManifest()
+	id := manifest.GetId()
+	idVersion, err := idutils.IDVersionFrom(id.GetPackage(), id.GetName(), req.GetVersion())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid skill: %v", err)
+	}
+	if req.GetDefault() && req.GetOrgPrivate() {
+		return nil, status.Errorf(codes.InvalidArgument, "a private skill cannot be the default version")
+	}
+	idVersionProto, err := idutils.IDVersionProtoFrom(id.GetPackage(), id.GetName(), req.GetVersion())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid skill: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.skills[idVersion]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "skill %q already exists", idVersion)
+	}
+	metadata := &mpb.Metadata{
+		IdVersion:     idVersionProto,
+		DisplayName:   manifest.GetDisplayName(),
+		Vendor:        manifest.GetVendor(),
+		Documentation: manifest.GetDocumentation(),
+		ReleaseNotes:  req.GetReleaseNotes(),
+		UpdateTime:    timestamppb.Now(),
+		AssetType:     atpb.AssetType_ASSET_TYPE_SKILL,
+	}
+	if req.GetDefault() {
+		metadata.ReleaseTag = rtpb.ReleaseTag_RELEASE_TAG_DEFAULT
+	}
+	c.addLocked(idVersion, metadata)
+	c.requests[idVersion] = proto.Clone(req).(*skillcatalogpb.CreateSkillRequest)
+	return proto.Clone(c.skills[idVersion]).(*skillcatalogpb.Skill), nil
+}
+
+// ClearDefault removes the default version of a skill.
+func (c *SkillCatalog) ClearDefault(ctx context.Context, req *skillcatalogpb.ClearDefaultRequest) (*emptypb.Empty, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.clearDefaultLocked(req.GetId()) {
+		return nil, status.Errorf(codes.NotFound, "skill %s.%s has no default version", req.GetId().GetPackage(), req.GetId().GetName())
+	}
+	return &emptypb.Empty{}, nil
+}
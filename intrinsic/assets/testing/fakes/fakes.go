@@ -0,0 +1,56 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package fakes provides in-memory implementations of the asset catalog, the installer and the
+// skill registry for tests.
+//
+// The fakes implement the gRPC server interfaces and can be registered on a grpc.Server, e.g., one
+// started with grpctest.StartServerT:
+//
+//	registry := fakes.NewSkillRegistry()
+//	installer := fakes.NewInstallerService(registry)
+//	server := grpc.NewServer()
+//	registry.Register(server)
+//	installer.Register(server)
+//	address := grpctest.StartServerT(t, server)
+//
+// Installing a skill with the fake installer makes it available in the fake registry, so command
+// flows such as install and wait can be tested without a cluster.
+package fakes
+
+import (
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 200
+)
+
+// paginate returns the page of items described by pageSize and pageToken, and the token of the
+// next page. Page tokens are offsets into items, so the fakes are only consistent as long as
+// items are not modified between calls.
+func paginate[T any](items []T, pageSize int64, pageToken string) ([]T, string, error) {
+	switch {
+	case pageSize < 0:
+		return nil, "", status.Errorf(codes.InvalidArgument, "negative page size %d", pageSize)
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+	start := 0
+	if pageToken != "" {
+		var err error
+		if start, err = strconv.Atoi(pageToken); err != nil || start < 0 || start > len(items) {
+			return nil, "", status.Errorf(codes.InvalidArgument, "invalid page token %q", pageToken)
+		}
+	}
+	end := start + int(pageSize)
+	if end >= len(items) {
+		return items[start:], "", nil
+	}
+	return items[start:end], strconv.Itoa(end), nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package fakes
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/status"
+	idpb "intrinsic/assets/proto/id_go_proto"
+	viewpb "intrinsic/assets/proto/view_go_proto"
+	ipb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
+	installerpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
+	skillcatalogpb "intrinsic/skills/catalog/proto/skill_catalog_go_grpc_proto"
+	smpb "intrinsic/skills/proto/skill_manifest_go_proto"
+	srgrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	"intrinsic/testing/grpctest"
+)
+
+func mustDial(t *testing.T, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+	server := grpc.NewServer()
+	register(server)
+	address := grpctest.StartServerT(t, server)
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(local.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestInstallSkill(t *testing.T) {
+	ctx := context.Background()
+	registry := NewSkillRegistry()
+	installer := NewInstallerService(registry)
+	conn := mustDial(t, func(s *grpc.Server) {
+		registry.Register(s)
+		installer.Register(s)
+	})
+	installerClient := installerpb.NewInstallerServiceClient(conn)
+	registryClient := srgrpcpb.NewSkillRegistryClient(conn)
+
+	if _, err := installerClient.InstallContainerAddon(ctx, &installerpb.InstallContainerAddonRequest{
+		Id:      "ai.intrinsic.foo",
+		Version: "0.0.1+abc",
+		Type:    installerpb.AddonType_ADDON_TYPE_SKILL,
+		Images:  []*ipb.Image{{Registry: "gcr.io/test", Name: "foo", Tag: ":latest"}},
+	}); err != nil {
+		t.Fatalf("InstallContainerAddon() failed: %v", err)
+	}
+
+	resp, err := registryClient.GetSkill(ctx, &srgrpcpb.GetSkillRequest{Id: "ai.intrinsic.foo"})
+	if err != nil {
+		t.Fatalf("GetSkill() failed: %v", err)
+	}
+	if got, want := resp.GetSkill().GetIdVersion(), "ai.intrinsic.foo.0.0.1+abc"; got != want {
+		t.Errorf("GetSkill() returned id_version %q, want %q", got, want)
+	}
+	if !resp.GetSkill().GetSideloaded() {
+		t.Errorf("GetSkill() returned a skill which is not sideloaded, want sideloaded")
+	}
+
+	if _, err := installerClient.RemoveContainerAddon(ctx, &installerpb.RemoveContainerAddonRequest{
+		Id:   "ai.intrinsic.foo",
+		Type: installerpb.AddonType_ADDON_TYPE_SKILL,
+	}); err != nil {
+		t.Fatalf("RemoveContainerAddon() failed: %v", err)
+	}
+	if _, err := registryClient.GetSkill(ctx, &srgrpcpb.GetSkillRequest{Id: "ai.intrinsic.foo"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetSkill() after removal returned %v, want NotFound", err)
+	}
+	if got := installer.Addons(); len(got) != 0 {
+		t.Errorf("Addons() = %v, want none", got)
+	}
+}
+
+func TestInstallerError(t *testing.T) {
+	installer := NewInstallerService(nil)
+	installer.SetError(status.Error(codes.Unavailable, "down"))
+	client := installerpb.NewInstallerServiceClient(mustDial(t, installer.Register))
+
+	_, err := client.InstallContainerAddon(context.Background(), &installerpb.InstallContainerAddonRequest{
+		Id:     "ai.intrinsic.foo",
+		Type:   installerpb.AddonType_ADDON_TYPE_SKILL,
+		Images: []*ipb.Image{{Registry: "gcr.io/test", Name: "foo", Tag: ":latest"}},
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("InstallContainerAddon() returned %v, want Unavailable", err)
+	}
+}
+
+func TestSkillCatalogListSkills(t *testing.T) {
+	ctx := context.Background()
+	catalog := NewSkillCatalog()
+	client := skillcatalogpb.NewSkillCatalogClient(mustDial(t, catalog.Register))
+
+	for _, v := range []struct {
+		version   string
+		isDefault bool
+	}{
+		{version: "1.10.0", isDefault: false},
+		{version: "1.2.0", isDefault: true},
+		{version: "1.9.0", isDefault: true},
+	} {
+		if _, err := client.CreateSkill(ctx, &skillcatalogpb.CreateSkillRequest{
+			Version:  v.version,
+			Manifest: &smpb.Manifest{Id: &idpb.Id{Package: "ai.intrinsic", Name: "foo"}},
+			Default:  v.isDefault,
+		}); err != nil {
+			t.Fatalf("CreateSkill(%q) failed: %v", v.version, err)
+		}
+	}
+
+	var versions []string
+	pageToken := ""
+	for {
+		resp, err := client.ListSkills(ctx, &skillcatalogpb.ListSkillsRequest{
+			PageSize:  2,
+			PageToken: pageToken,
+			View:      viewpb.AssetViewType_ASSET_VIEW_TYPE_VERSIONS,
+		})
+		if err != nil {
+			t.Fatalf("ListSkills() failed: %v", err)
+		}
+		for _, s := range resp.GetSkills() {
+			versions = append(versions, s.GetMetadata().GetIdVersion().GetVersion())
+		}
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+	if want := []string{"1.2.0", "1.9.0", "1.10.0"}; !slices.Equal(versions, want) {
+		t.Errorf("ListSkills() returned versions %v, want %v", versions, want)
+	}
+
+	// Only the latest version created as default is the default version.
+	resp, err := client.ListSkills(ctx, &skillcatalogpb.ListSkillsRequest{})
+	if err != nil {
+		t.Fatalf("ListSkills() failed: %v", err)
+	}
+	if len(resp.GetSkills()) != 1 || resp.GetSkills()[0].GetMetadata().GetIdVersion().GetVersion() != "1.9.0" {
+		t.Errorf("ListSkills() returned %v, want only the default version 1.9.0", resp.GetSkills())
+	}
+}
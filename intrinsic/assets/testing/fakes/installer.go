@@ -0,0 +1,248 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package fakes
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	"intrinsic/assets/idutils"
+	installerpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
+	spb "intrinsic/skills/proto/skills_go_proto"
+)
+
+const defaultAddonVersion = "0.0.1"
+
+type addonKey struct {
+	addonType installerpb.AddonType
+	id        string
+}
+
+// InstallerService is an in-memory installer, which records installed container addons and
+// services.
+type InstallerService struct {
+	installerpb.UnimplementedInstallerServiceServer
+
+	registry *SkillRegistry
+
+	mu       sync.Mutex
+	err      error
+	addons   map[addonKey]*installerpb.InstallContainerAddonRequest
+	services map[string]*installerpb.InstallServiceRequest // By id_version.
+}
+
+// NewInstallerService creates an InstallerService without any installed assets. If registry is
+// not nil, installed skills are added to and removed from it.
+func NewInstallerService(registry *SkillRegistry) *InstallerService {
+	return &InstallerService{
+		registry: registry,
+		addons:   map[addonKey]*installerpb.InstallContainerAddonRequest{},
+		services: map[string]*installerpb.InstallServiceRequest{},
+	}
+}
+
+// Register registers the installer on s.
+func (i *InstallerService) Register(s *grpc.Server) {
+	installerpb.RegisterInstallerServiceServer(s, i)
+}
+
+// SetError makes all subsequent calls fail with err, e.g., to test error handling. A nil err
+// restores normal operation.
+func (i *InstallerService) SetError(err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.err = err
+}
+
+// Addons returns the installed container addons sorted by type and id.
+func (i *InstallerService) Addons() []*installerpb.InstallContainerAddonRequest {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	addons := make([]*installerpb.InstallContainerAddonRequest, 0, len(i.addons))
+	for _, a := range i.addons {
+		addons = append(addons, proto.Clone(a).(*installerpb.InstallContainerAddonRequest))
+	}
+	sort.Slice(addons, func(a, b int) bool {
+		if addons[a].GetType() != addons[b].GetType() {
+			return addons[a].GetType() < addons[b].GetType()
+		}
+		return addons[a].GetId() < addons[b].GetId()
+	})
+	return addons
+}
+
+// Services returns the id_versions of the installed services in sorted order.
+func (i *InstallerService) Services() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	services := make([]string, 0, len(i.services))
+	for idVersion := range i.services {
+		services = append(services, idVersion)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// GetInstalledSpec reports a healthy workcell.
+func (i *InstallerService) GetInstalledSpec(ctx context.Context, req *emptypb.Empty) (*installerpb.GetInstalledSpecResponse, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.err != nil {
+		return nil, i.err
+	}
+	return &installerpb.GetInstalledSpecResponse{
+		Name:   "fake",
+		Status: installerpb.GetInstalledSpecResponse_HEALTHY,
+	}, nil
+}
+
+// InstallContainerAddon installs or replaces a container addon.
+func (i *InstallerService) InstallContainerAddon(ctx context.Context, req *installerpb.InstallContainerAddonRequest) (*emptypb.Empty, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.err != nil {
+		return nil, i.err
+	}
+	if err := i.installAddonLocked(req); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// InstallContainerAddons installs all addons of the request or none of them.
+func (i *InstallerService) InstallContainerAddons(ctx context.Context, req *installerpb.InstallContainerAddonsRequest) (*emptypb.Empty, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.err != nil {
+		return nil, i.err
+	}
+	for _, r := range req.GetRequests() {
+		if err := validateAddon(r); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range req.GetRequests() {
+		if err := i.installAddonLocked(r); err != nil {
+			return nil, err
+		}
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// RemoveContainerAddon removes an installed container addon.
+func (i *InstallerService) RemoveContainerAddon(ctx context.Context, req *installerpb.RemoveContainerAddonRequest) (*emptypb.Empty, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.err != nil {
+		return nil, i.err
+	}
+	key := addonKey{addonType: req.GetType(), id: addonID(req.GetId(), req.GetName())}
+	if _, ok := i.addons[key]; !ok {
+		return nil, status.Errorf(codes.NotFound, "addon %q of type %v is not installed", key.id, key.addonType)
+	}
+	delete(i.addons, key)
+	if key.addonType == installerpb.AddonType_ADDON_TYPE_SKILL && i.registry != nil {
+		i.registry.RemoveSkill(key.id)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// InstallService installs a service. Installing the same id_version again replaces it.
+func (i *InstallerService) InstallService(ctx context.Context, req *installerpb.InstallServiceRequest) (*installerpb.InstallServiceResponse, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.err != nil {
+		return nil, i.err
+	}
+	id := req.GetManifest().GetMetadata().GetId()
+	idVersion, err := idutils.IDVersionFrom(id.GetPackage(), id.GetName(), req.GetVersion())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid service: %v", err)
+	}
+	i.services[idVersion] = proto.Clone(req).(*installerpb.InstallServiceRequest)
+	return &installerpb.InstallServiceResponse{IdVersion: idVersion}, nil
+}
+
+// UninstallService uninstalls an installed service.
+func (i *InstallerService) UninstallService(ctx context.Context, req *installerpb.UninstallServiceRequest) (*emptypb.Empty, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.err != nil {
+		return nil, i.err
+	}
+	idVersion, err := idutils.IDVersionFromProto(req.GetIdVersion())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid id_version: %v", err)
+	}
+	if _, ok := i.services[idVersion]; !ok {
+		return nil, status.Errorf(codes.NotFound, "service %q is not installed", idVersion)
+	}
+	delete(i.services, idVersion)
+	return &emptypb.Empty{}, nil
+}
+
+func (i *InstallerService) installAddonLocked(req *installerpb.InstallContainerAddonRequest) error {
+	if err := validateAddon(req); err != nil {
+		return err
+	}
+	addon := proto.Clone(req).(*installerpb.InstallContainerAddonRequest)
+	if addon.GetVersion() == "" {
+		addon.Version = defaultAddonVersion
+	}
+	key := addonKey{addonType: addon.GetType(), id: addonID(addon.GetId(), addon.GetName())}
+	i.addons[key] = addon
+
+	if key.addonType == installerpb.AddonType_ADDON_TYPE_SKILL && i.registry != nil {
+		pkg, _ := idutils.PackageFrom(key.id)
+		name, _ := idutils.NameFrom(key.id)
+		i.registry.AddSkill(&spb.Skill{
+			Id:          key.id,
+			IdVersion:   key.id + "." + addon.GetVersion(),
+			PackageName: pkg,
+			SkillName:   name,
+			// Skills installed from the command line are sideloaded with a unique build metadata
+			// suffix in their version.
+			Sideloaded: strings.Contains(addon.GetVersion(), "+"),
+		})
+	}
+	return nil
+}
+
+func validateAddon(req *installerpb.InstallContainerAddonRequest) error {
+	if req.GetType() == installerpb.AddonType_ADDON_TYPE_UNKNOWN {
+		return status.Errorf(codes.InvalidArgument, "addon type is required")
+	}
+	id := addonID(req.GetId(), req.GetName())
+	if id == "" {
+		return status.Errorf(codes.InvalidArgument, "addon id is required")
+	}
+	if req.GetType() == installerpb.AddonType_ADDON_TYPE_SKILL {
+		if err := idutils.ValidateID(id); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid skill: %v", err)
+		}
+	}
+	if v := req.GetVersion(); v != "" {
+		if err := idutils.ValidateVersion(v); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid addon: %v", err)
+		}
+	}
+	if len(req.GetImages()) == 0 {
+		return status.Errorf(codes.InvalidArgument, "addon %q has no images", id)
+	}
+	return nil
+}
+
+// addonID returns the id of an addon, falling back to the deprecated name.
+func addonID(id, name string) string {
+	if id != "" {
+		return id
+	}
+	return name
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package fakes
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	srgrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	spb "intrinsic/skills/proto/skills_go_proto"
+)
+
+const sideloadedFilter = "sideloaded"
+
+// SkillRegistry is an in-memory skill registry, which holds the skills installed on a cluster.
+type SkillRegistry struct {
+	srgrpcpb.UnimplementedSkillRegistryServer
+
+	mu     sync.Mutex
+	skills map[string]*spb.Skill // By id.
+}
+
+// NewSkillRegistry creates an empty SkillRegistry.
+func NewSkillRegistry() *SkillRegistry {
+	return &SkillRegistry{skills: map[string]*spb.Skill{}}
+}
+
+// Register registers the registry on s.
+func (r *SkillRegistry) Register(s *grpc.Server) {
+	srgrpcpb.RegisterSkillRegistryServer(s, r)
+}
+
+// AddSkill adds a skill to the registry, replacing any skill with the same id.
+func (r *SkillRegistry) AddSkill(skill *spb.Skill) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skills[skill.GetId()] = proto.Clone(skill).(*spb.Skill)
+}
+
+// RemoveSkill removes the skill with the given id and reports whether it was present.
+func (r *SkillRegistry) RemoveSkill(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.skills[id]
+	delete(r.skills, id)
+	return ok
+}
+
+// sortedSkills returns copies of all skills sorted by id.
+func (r *SkillRegistry) sortedSkills() []*spb.Skill {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	skills := make([]*spb.Skill, 0, len(r.skills))
+	for _, s := range r.skills {
+		skills = append(skills, proto.Clone(s).(*spb.Skill))
+	}
+	sort.Slice(skills, func(i, j int) bool { return skills[i].GetId() < skills[j].GetId() })
+	return skills
+}
+
+// GetSkills returns all skills.
+func (r *SkillRegistry) GetSkills(ctx context.Context, req *emptypb.Empty) (*srgrpcpb.GetSkillsResponse, error) {
+	return &srgrpcpb.GetSkillsResponse{Skills: r.sortedSkills()}, nil
+}
+
+// GetSkill returns the skill with the requested id.
+func (r *SkillRegistry) GetSkill(ctx context.Context, req *srgrpcpb.GetSkillRequest) (*srgrpcpb.GetSkillResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	skill, ok := r.skills[req.GetId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "skill %q not found", req.GetId())
+	}
+	return &srgrpcpb.GetSkillResponse{Skill: proto.Clone(skill).(*spb.Skill)}, nil
+}
+
+// ListSkills returns a page of skills. The only supported filters are "sideloaded" and
+// "-sideloaded".
+func (r *SkillRegistry) ListSkills(ctx context.Context, req *srgrpcpb.ListSkillsRequest) (*srgrpcpb.ListSkillsResponse, error) {
+	var keep func(*spb.Skill) bool
+	switch req.GetFilter() {
+	case "":
+		keep = func(*spb.Skill) bool { return true }
+	case sideloadedFilter:
+		keep = func(s *spb.Skill) bool { return s.GetSideloaded() }
+	case "-" + sideloadedFilter:
+		keep = func(s *spb.Skill) bool { return !s.GetSideloaded() }
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported filter %q", req.GetFilter())
+	}
+	var skills []*spb.Skill
+	for _, s := range r.sortedSkills() {
+		if keep(s) {
+			skills = append(skills, s)
+		}
+	}
+	page, next, err := paginate(skills, int64(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	return &srgrpcpb.ListSkillsResponse{Skills: page, NextPageToken: next}, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package clientutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"intrinsic/assets/cmdutils"
+	clustermanagerpb "intrinsic/frontend/cloud/api/clustermanager_api_go_grpc_proto"
+)
+
+// ErrClusterUpgrading is reported by CheckClusterUpgradeState if assets should not be installed
+// because a platform upgrade of the cluster is running or about to start.
+var ErrClusterUpgrading = errors.New("cluster is being upgraded")
+
+// clusterGetter is the part of the clusters service used by CheckClusterUpgradeState.
+type clusterGetter interface {
+	GetCluster(ctx context.Context, in *clustermanagerpb.GetClusterRequest, opts ...grpc.CallOption) (*clustermanagerpb.Cluster, error)
+}
+
+// CheckClusterUpgradeState checks that no platform upgrade of the cluster selected by the flags of
+// an inctl command is running or about to start, since installing assets can race with the
+// upgrade. conn is a connection returned by DialClusterFromInctl.
+//
+// Returns an error wrapping ErrClusterUpgrading if the upgrade state blocks installing assets,
+// unless the flag added by AddFlagIgnoreUpgradeState is set. The check is skipped with a warning
+// if the upgrade state cannot be queried, e.g., because the cluster is dialed with --address.
+func CheckClusterUpgradeState(ctx context.Context, conn *grpc.ClientConn, flags *cmdutils.CmdFlags) error {
+	cluster, err := ResolveClusterFromInctl(ctx, flags)
+	if err != nil {
+		return err
+	}
+	if cluster == "" {
+		log.Printf("Warning: not checking the upgrade state of the cluster, it is only known by its address")
+		return nil
+	}
+	err = checkClusterUpgradeState(ctx, clustermanagerpb.NewClustersServiceClient(conn), flags.GetFlagProject(), flags.GetFlagOrganization(), cluster)
+	if errors.Is(err, ErrClusterUpgrading) && flags.GetFlagIgnoreUpgradeState() {
+		log.Printf("Warning: %v", err)
+		return nil
+	}
+	return err
+}
+
+func checkClusterUpgradeState(ctx context.Context, client clusterGetter, project, org, cluster string) error {
+	c, err := client.GetCluster(ctx, &clustermanagerpb.GetClusterRequest{
+		Project:   project,
+		Org:       org,
+		ClusterId: cluster,
+	})
+	if err != nil {
+		// The cluster was selected by the user, failing to query its upgrade state should not keep
+		// them from installing assets.
+		log.Printf("Warning: could not query the upgrade state of cluster %q: %v", cluster, status.Convert(err).Message())
+		return nil
+	}
+
+	switch c.GetUpdateState() {
+	case clustermanagerpb.UpdateState_UPDATE_STATE_UPDATING:
+		return fmt.Errorf("%w: cluster %q is running an upgrade to %q, wait until it finished (see 'inctl cluster upgrade') or pass --%s", ErrClusterUpgrading, cluster, c.GetPlatformVersion(), cmdutils.KeyIgnoreUpgradeState)
+	case clustermanagerpb.UpdateState_UPDATE_STATE_PENDING:
+		if c.GetUpdateMode() == clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_AUTOMATIC {
+			return fmt.Errorf("%w: an upgrade of cluster %q is pending and starts automatically, wait until it finished (see 'inctl cluster upgrade') or pass --%s", ErrClusterUpgrading, cluster, cmdutils.KeyIgnoreUpgradeState)
+		}
+		log.Printf("Warning: an upgrade of cluster %q is pending, do not run it while installing assets", cluster)
+	case clustermanagerpb.UpdateState_UPDATE_STATE_FAULT:
+		log.Printf("Warning: the last upgrade of cluster %q failed, installed assets may not work as expected", cluster)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package clientutils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	clustermanagerpb "intrinsic/frontend/cloud/api/clustermanager_api_go_grpc_proto"
+)
+
+type fakeClusterGetter struct {
+	cluster *clustermanagerpb.Cluster
+	err     error
+}
+
+func (f *fakeClusterGetter) GetCluster(ctx context.Context, in *clustermanagerpb.GetClusterRequest, opts ...grpc.CallOption) (*clustermanagerpb.Cluster, error) {
+	return f.cluster, f.err
+}
+
+func TestCheckClusterUpgradeState(t *testing.T) {
+	tests := []struct {
+		desc    string
+		getter  *fakeClusterGetter
+		wantErr bool
+	}{
+		{
+			desc: "deployed",
+			getter: &fakeClusterGetter{cluster: &clustermanagerpb.Cluster{
+				UpdateState: clustermanagerpb.UpdateState_UPDATE_STATE_DEPLOYED,
+			}},
+		},
+		{
+			desc: "updating",
+			getter: &fakeClusterGetter{cluster: &clustermanagerpb.Cluster{
+				UpdateState: clustermanagerpb.UpdateState_UPDATE_STATE_UPDATING,
+			}},
+			wantErr: true,
+		},
+		{
+			desc: "pending on demand",
+			getter: &fakeClusterGetter{cluster: &clustermanagerpb.Cluster{
+				UpdateState: clustermanagerpb.UpdateState_UPDATE_STATE_PENDING,
+				UpdateMode:  clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_ON,
+			}},
+		},
+		{
+			desc: "pending automatic",
+			getter: &fakeClusterGetter{cluster: &clustermanagerpb.Cluster{
+				UpdateState: clustermanagerpb.UpdateState_UPDATE_STATE_PENDING,
+				UpdateMode:  clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_AUTOMATIC,
+			}},
+			wantErr: true,
+		},
+		{
+			desc:   "unavailable",
+			getter: &fakeClusterGetter{err: status.Error(codes.Unimplemented, "unknown service")},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := checkClusterUpgradeState(context.Background(), tc.getter, "project", "org", "cluster")
+			if gotErr := errors.Is(err, ErrClusterUpgrading); gotErr != tc.wantErr || (err != nil && !gotErr) {
+				t.Errorf("checkClusterUpgradeState() = %v, want ErrClusterUpgrading: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
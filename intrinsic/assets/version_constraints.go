@@ -0,0 +1,418 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package idutils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// partialVersionRegex matches versions in constraints, where the minor and patch parts can be
+	// omitted (e.g., "1" or "1.4"). A pre-release is only allowed on a full version.
+	partialVersionRegex = regexp.MustCompile(`(?P<version>^(?P<major>0|[1-9]\d*)(?:\.(?P<minor>0|[1-9]\d*)(?:\.(?P<patch>0|[1-9]\d*)(?:-(?P<prerelease>(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?)?)?$)`)
+
+	constraintOperators = []string{">=", "<=", "!=", ">", "<", "=", "~", "^"}
+)
+
+// semver is a parsed semantic version.
+type semver struct {
+	major, minor, patch uint64
+	preRelease          []string
+	buildMetadata       string
+}
+
+func parseUint(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// parseSemver parses a version as described in IsVersion.
+func parseSemver(version string) (*semver, error) {
+	m, err := getNamedMatches(version, versionRegex, []string{"major", "minor", "patch", "prerelease", "buildmetadata"})
+	if err != nil {
+		return nil, err
+	}
+	v := &semver{buildMetadata: m["buildmetadata"]}
+	if v.major, err = parseUint(m["major"]); err != nil {
+		return nil, fmt.Errorf("invalid major version in %q: %v", version, err)
+	}
+	if v.minor, err = parseUint(m["minor"]); err != nil {
+		return nil, fmt.Errorf("invalid minor version in %q: %v", version, err)
+	}
+	if v.patch, err = parseUint(m["patch"]); err != nil {
+		return nil, fmt.Errorf("invalid patch version in %q: %v", version, err)
+	}
+	if m["prerelease"] != "" {
+		v.preRelease = strings.Split(m["prerelease"], ".")
+	}
+	return v, nil
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePreReleaseIdentifier compares two dot-separated pre-release identifiers as described by
+// semver.org: numeric identifiers compare numerically and have lower precedence than alphanumeric
+// ones, which compare in ASCII order.
+func comparePreReleaseIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compare returns the precedence of v relative to o. Build metadata is ignored.
+func (v *semver) compare(o *semver) int {
+	if c := v.compareCore(o); c != 0 {
+		return c
+	}
+	// A version without pre-release has higher precedence than one with.
+	switch {
+	case len(v.preRelease) == 0 && len(o.preRelease) == 0:
+		return 0
+	case len(v.preRelease) == 0:
+		return 1
+	case len(o.preRelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.preRelease) && i < len(o.preRelease); i++ {
+		if c := comparePreReleaseIdentifier(v.preRelease[i], o.preRelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.preRelease)), uint64(len(o.preRelease)))
+}
+
+// compareCore compares major, minor and patch of v and o.
+func (v *semver) compareCore(o *semver) int {
+	if c := compareUint(v.major, o.major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.minor, o.minor); c != 0 {
+		return c
+	}
+	return compareUint(v.patch, o.patch)
+}
+
+// CompareVersions compares two versions by their precedence as described by semver.org.
+//
+// Returns -1 if a < b, 0 if a == b and 1 if a > b. Build metadata is ignored, so versions which
+// only differ in their build metadata compare equal.
+//
+// Returns an error if either version is not valid.
+func CompareVersions(a, b string) (int, error) {
+	av, err := parseSemver(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, err := parseSemver(b)
+	if err != nil {
+		return 0, err
+	}
+	return av.compare(bv), nil
+}
+
+type comparatorOp int
+
+const (
+	opEQ comparatorOp = iota
+	opNE
+	opGT
+	opGE
+	opLT
+	opLE
+)
+
+// comparator is a single primitive comparison against a version.
+type comparator struct {
+	op comparatorOp
+	v  *semver
+}
+
+func (c *comparator) matches(v *semver) bool {
+	cmp := v.compare(c.v)
+	switch c.op {
+	case opEQ:
+		return cmp == 0
+	case opNE:
+		return cmp != 0
+	case opGT:
+		return cmp > 0
+	case opGE:
+		return cmp >= 0
+	case opLT:
+		return cmp < 0
+	case opLE:
+		return cmp <= 0
+	}
+	return false
+}
+
+// comparatorSet is a list of comparators which all have to match.
+type comparatorSet []*comparator
+
+func (s comparatorSet) matches(v *semver) bool {
+	for _, c := range s {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	if len(v.preRelease) == 0 {
+		return true
+	}
+	// Pre-releases only match if the set explicitly refers to a pre-release of the same
+	// major.minor.patch, so that, e.g., ">=1.2.0" does not pick up "2.0.0-rc.1".
+	for _, c := range s {
+		if len(c.v.preRelease) > 0 && c.v.compareCore(v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// VersionConstraint is a set of requirements on asset versions.
+//
+// See ParseVersionConstraint for the supported syntax.
+type VersionConstraint struct {
+	constraint string
+	sets       []comparatorSet
+}
+
+// ParseVersionConstraint parses a version constraint.
+//
+// A constraint consists of one or more alternatives separated by "||". An alternative consists of
+// comparisons separated by spaces or commas, all of which have to match. A comparison is a version
+// with an optional operator:
+//
+//   - "=1.2.3" or "1.2.3": exactly 1.2.3.
+//   - "!=1.2.3": any version except 1.2.3.
+//   - ">1.2.3", ">=1.2.3", "<1.2.3", "<=1.2.3": the respective ordering.
+//   - "~1.4": any patch release of 1.4, i.e., ">=1.4.0 <1.5.0". "~1.4.2" means ">=1.4.2 <1.5.0"
+//     and "~1" means ">=1.0.0 <2.0.0".
+//   - "^1.4.2": any release compatible with 1.4.2, i.e., ">=1.4.2 <2.0.0". For 0.x versions only
+//     the minor (or, for 0.0.x, the patch) version is kept, e.g., "^0.3.1" means ">=0.3.1 <0.4.0".
+//   - "*": any version.
+//
+// The minor and patch versions can be omitted, in which case "1.4" means any version 1.4.x,
+// ">1.4" means ">=1.5.0" and "<=1.4" means "<1.5.0".
+//
+// Pre-release versions only match if a comparison of the same alternative refers to a
+// pre-release of the same major, minor and patch version. For example, ">=1.2.0-rc.1 <2.0.0"
+// matches "1.2.0-rc.2" but not "1.3.0-rc.1". Build metadata is ignored when comparing versions and
+// cannot be used in constraints.
+//
+// Example: ">=1.2.0 <2.0.0 || ~3.1".
+func ParseVersionConstraint(constraint string) (*VersionConstraint, error) {
+	c := &VersionConstraint{constraint: strings.TrimSpace(constraint)}
+	if c.constraint == "" {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+	for _, alternative := range strings.Split(c.constraint, "||") {
+		set, err := parseComparatorSet(alternative)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+// String returns the constraint as it was parsed.
+func (c *VersionConstraint) String() string {
+	return c.constraint
+}
+
+// Matches reports whether version satisfies the constraint. Invalid versions never match.
+func (c *VersionConstraint) Matches(version string) bool {
+	v, err := parseSemver(version)
+	if err != nil {
+		return false
+	}
+	return c.matches(v)
+}
+
+func (c *VersionConstraint) matches(v *semver) bool {
+	for _, set := range c.sets {
+		if set.matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the version with the highest precedence in versions that satisfies the
+// constraint, e.g., to pick the latest compatible version from the versions in the catalog.
+//
+// Invalid versions are ignored. If several matching versions only differ in their build metadata,
+// the one with the lexicographically greatest build metadata is returned, so the result does not
+// depend on the order of versions.
+//
+// Returns an error if no version satisfies the constraint.
+func (c *VersionConstraint) Latest(versions []string) (string, error) {
+	var latest string
+	var latestV *semver
+	for _, version := range versions {
+		v, err := parseSemver(version)
+		if err != nil || !c.matches(v) {
+			continue
+		}
+		if latestV != nil {
+			cmp := v.compare(latestV)
+			if cmp < 0 || (cmp == 0 && v.buildMetadata <= latestV.buildMetadata) {
+				continue
+			}
+		}
+		latest, latestV = version, v
+	}
+	if latestV == nil {
+		return "", fmt.Errorf("no version satisfies %q", c.constraint)
+	}
+	return latest, nil
+}
+
+func parseComparatorSet(alternative string) (comparatorSet, error) {
+	fields := strings.Fields(strings.ReplaceAll(alternative, ",", " "))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty alternative")
+	}
+	var set comparatorSet
+	for i := 0; i < len(fields); i++ {
+		term := fields[i]
+		// Allow whitespace between an operator and its version, e.g., ">= 1.2.0".
+		if isOperator(term) {
+			if i+1 == len(fields) {
+				return nil, fmt.Errorf("operator %q without version", term)
+			}
+			i++
+			term += fields[i]
+		}
+		comparators, err := parseComparison(term)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+func isOperator(s string) bool {
+	for _, op := range constraintOperators {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseComparison expands a single comparison into primitive comparators.
+func parseComparison(term string) ([]*comparator, error) {
+	if term == "*" {
+		return nil, nil
+	}
+	var op string
+	for _, o := range constraintOperators {
+		if strings.HasPrefix(term, o) {
+			op = o
+			break
+		}
+	}
+	version := strings.TrimPrefix(term, op)
+	if strings.Contains(version, "+") {
+		return nil, fmt.Errorf("build metadata is not allowed in constraints: %q", term)
+	}
+	m, err := getNamedMatches(version, partialVersionRegex, []string{"major", "minor", "patch", "prerelease"})
+	if err != nil {
+		return nil, err
+	}
+	// parts is the number of given parts: 1 for "1", 2 for "1.4" and 3 for "1.4.2".
+	parts := 1
+	if m["minor"] != "" {
+		parts = 2
+	}
+	if m["patch"] != "" {
+		parts = 3
+	}
+	full := m["major"] + ".0.0"
+	if parts == 2 {
+		full = m["major"] + "." + m["minor"] + ".0"
+	} else if parts == 3 {
+		full = version
+	}
+	v, err := parseSemver(full)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "", "=":
+		if parts == 3 {
+			return []*comparator{{opEQ, v}}, nil
+		}
+		return []*comparator{{opGE, v}, {opLT, v.bump(parts)}}, nil
+	case "!=":
+		if parts != 3 {
+			return nil, fmt.Errorf("%q requires a full version", term)
+		}
+		return []*comparator{{opNE, v}}, nil
+	case ">":
+		if parts == 3 {
+			return []*comparator{{opGT, v}}, nil
+		}
+		return []*comparator{{opGE, v.bump(parts)}}, nil
+	case ">=":
+		return []*comparator{{opGE, v}}, nil
+	case "<":
+		return []*comparator{{opLT, v}}, nil
+	case "<=":
+		if parts == 3 {
+			return []*comparator{{opLE, v}}, nil
+		}
+		return []*comparator{{opLT, v.bump(parts)}}, nil
+	case "~":
+		if parts == 1 {
+			return []*comparator{{opGE, v}, {opLT, v.bump(1)}}, nil
+		}
+		return []*comparator{{opGE, v}, {opLT, v.bump(2)}}, nil
+	case "^":
+		keep := 3
+		switch {
+		case v.major > 0 || parts == 1:
+			keep = 1
+		case v.minor > 0 || parts == 2:
+			keep = 2
+		}
+		return []*comparator{{opGE, v}, {opLT, v.bump(keep)}}, nil
+	}
+	return nil, fmt.Errorf("unknown operator in %q", term)
+}
+
+// bump returns the lowest release version above all versions which share the first parts parts
+// of v, e.g., 1.5.0 for 1.4.2 with parts == 2.
+func (v *semver) bump(parts int) *semver {
+	switch parts {
+	case 1:
+		return &semver{major: v.major + 1}
+	case 2:
+		return &semver{major: v.major, minor: v.minor + 1}
+	}
+	return &semver{major: v.major, minor: v.minor, patch: v.patch + 1}
+}
@@ -0,0 +1,268 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package idutils
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{a: "1.0.0", b: "1.0.0", want: 0},
+		{a: "1.0.0", b: "2.0.0", want: -1},
+		{a: "2.1.0", b: "2.0.9", want: 1},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "1.0.0-alpha", b: "1.0.0", want: -1},
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", want: -1},
+		{a: "1.0.0-alpha.beta", b: "1.0.0-beta", want: -1},
+		{a: "1.0.0-beta.2", b: "1.0.0-beta.11", want: -1},
+		{a: "1.0.0-beta.11", b: "1.0.0-rc.1", want: -1},
+		{a: "1.0.0-rc.1", b: "1.0.0", want: -1},
+		{a: "1.0.0+sideloaded", b: "1.0.0", want: 0},
+		{a: "1.0.0-rc.1+build.5", b: "1.0.0-rc.1+build.6", want: 0},
+		{a: "18446744073709551615.0.0", b: "1.0.0", want: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.a+" vs "+tc.b, func(t *testing.T) {
+			got, err := CompareVersions(tc.a, tc.b)
+			if err != nil {
+				t.Fatalf("CompareVersions(%q, %q) failed: %v", tc.a, tc.b, err)
+			}
+			if got != tc.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersionsInvalid(t *testing.T) {
+	for _, v := range []string{"", "1", "1.2", "01.2.3", "1.2.3-", "1.2.3+", "v1.2.3", "18446744073709551616.0.0"} {
+		if _, err := CompareVersions(v, "1.0.0"); err == nil {
+			t.Errorf("CompareVersions(%q, \"1.0.0\") succeeded, want error", v)
+		}
+	}
+}
+
+func TestVersionConstraintMatches(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matches    []string
+		noMatches  []string
+	}{
+		{
+			constraint: "1.2.3",
+			matches:    []string{"1.2.3", "1.2.3+sideloaded"},
+			noMatches:  []string{"1.2.4", "1.2.3-rc.1"},
+		},
+		{
+			constraint: "=1.2.3-rc.1",
+			matches:    []string{"1.2.3-rc.1", "1.2.3-rc.1+build"},
+			noMatches:  []string{"1.2.3", "1.2.3-rc.2"},
+		},
+		{
+			constraint: "!=1.2.3",
+			matches:    []string{"1.2.2", "1.2.4"},
+			noMatches:  []string{"1.2.3", "1.2.3+build", "1.2.4-rc.1"},
+		},
+		{
+			constraint: ">=1.2.0 <2.0.0",
+			matches:    []string{"1.2.0", "1.9.9", "1.99.0+build"},
+			noMatches:  []string{"1.1.9", "2.0.0", "2.0.0-rc.1", "1.5.0-rc.1"},
+		},
+		{
+			constraint: ">= 1.2.0, < 2.0.0",
+			matches:    []string{"1.2.0", "1.9.9"},
+			noMatches:  []string{"1.1.9", "2.0.0"},
+		},
+		{
+			constraint: ">1.2",
+			matches:    []string{"1.3.0", "2.0.0"},
+			noMatches:  []string{"1.2.0", "1.2.9"},
+		},
+		{
+			constraint: "<=1.2",
+			matches:    []string{"1.2.9", "0.1.0"},
+			noMatches:  []string{"1.3.0"},
+		},
+		{
+			constraint: "<1.2",
+			matches:    []string{"1.1.9"},
+			noMatches:  []string{"1.2.0", "1.2.0-rc.1"},
+		},
+		{
+			constraint: "1.4",
+			matches:    []string{"1.4.0", "1.4.7"},
+			noMatches:  []string{"1.3.9", "1.5.0"},
+		},
+		{
+			constraint: "~1.4",
+			matches:    []string{"1.4.0", "1.4.7"},
+			noMatches:  []string{"1.3.9", "1.5.0", "1.4.8-rc.1"},
+		},
+		{
+			constraint: "~1.4.2",
+			matches:    []string{"1.4.2", "1.4.7"},
+			noMatches:  []string{"1.4.1", "1.5.0"},
+		},
+		{
+			constraint: "~1",
+			matches:    []string{"1.0.0", "1.9.0"},
+			noMatches:  []string{"0.9.0", "2.0.0"},
+		},
+		{
+			constraint: "^1.4.2",
+			matches:    []string{"1.4.2", "1.9.0"},
+			noMatches:  []string{"1.4.1", "2.0.0"},
+		},
+		{
+			constraint: "^0.3.1",
+			matches:    []string{"0.3.1", "0.3.9"},
+			noMatches:  []string{"0.3.0", "0.4.0"},
+		},
+		{
+			constraint: "^0.0.3",
+			matches:    []string{"0.0.3"},
+			noMatches:  []string{"0.0.4"},
+		},
+		{
+			constraint: "^0",
+			matches:    []string{"0.0.1", "0.9.0"},
+			noMatches:  []string{"1.0.0"},
+		},
+		{
+			constraint: ">=1.2.0-rc.1 <2.0.0",
+			matches:    []string{"1.2.0-rc.1", "1.2.0-rc.2", "1.2.0", "1.3.0"},
+			noMatches:  []string{"1.2.0-beta", "1.3.0-rc.1"},
+		},
+		{
+			constraint: "~1.4.2-beta",
+			matches:    []string{"1.4.2-beta", "1.4.2-beta.2", "1.4.2", "1.4.3"},
+			noMatches:  []string{"1.4.2-alpha", "1.4.3-beta"},
+		},
+		{
+			constraint: "~1.4 || >=3.0.0",
+			matches:    []string{"1.4.2", "3.0.0", "4.1.0"},
+			noMatches:  []string{"2.0.0", "1.5.0"},
+		},
+		{
+			constraint: "*",
+			matches:    []string{"0.0.1", "99.0.0+build"},
+			noMatches:  []string{"1.0.0-rc.1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.constraint, func(t *testing.T) {
+			c, err := ParseVersionConstraint(tc.constraint)
+			if err != nil {
+				t.Fatalf("ParseVersionConstraint(%q) failed: %v", tc.constraint, err)
+			}
+			for _, v := range tc.matches {
+				if !c.Matches(v) {
+					t.Errorf("%q does not match %q, want match", tc.constraint, v)
+				}
+			}
+			for _, v := range tc.noMatches {
+				if c.Matches(v) {
+					t.Errorf("%q matches %q, want no match", tc.constraint, v)
+				}
+			}
+		})
+	}
+}
+
+func TestVersionConstraintMatchesInvalidVersion(t *testing.T) {
+	c, err := ParseVersionConstraint("*")
+	if err != nil {
+		t.Fatalf("ParseVersionConstraint(\"*\") failed: %v", err)
+	}
+	for _, v := range []string{"", "1.2", "latest"} {
+		if c.Matches(v) {
+			t.Errorf("\"*\" matches invalid version %q, want no match", v)
+		}
+	}
+}
+
+func TestParseVersionConstraintInvalid(t *testing.T) {
+	for _, constraint := range []string{
+		"",
+		"   ",
+		"||",
+		">=1.0.0 ||",
+		">=",
+		"1.2.3 >=",
+		"v1.2.3",
+		"1.2.x",
+		"1.2-rc.1",
+		"=>1.2.3",
+		"~>1.2",
+		">=1.2.3+build",
+		"!=1.2",
+		"01.2.3",
+	} {
+		if _, err := ParseVersionConstraint(constraint); err == nil {
+			t.Errorf("ParseVersionConstraint(%q) succeeded, want error", constraint)
+		}
+	}
+}
+
+func TestVersionConstraintLatest(t *testing.T) {
+	versions := []string{
+		"1.2.0",
+		"1.4.0",
+		"1.4.3",
+		"1.4.3+b",
+		"1.4.3+a",
+		"1.5.0-rc.1",
+		"2.0.0",
+		"not-a-version",
+	}
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{constraint: "*", want: "2.0.0"},
+		{constraint: ">=1.2.0 <2.0.0", want: "1.4.3+b"},
+		{constraint: "~1.4", want: "1.4.3+b"},
+		{constraint: "^1.2", want: "1.4.3+b"},
+		{constraint: ">=1.5.0-rc.1 <2.0.0", want: "1.5.0-rc.1"},
+		{constraint: "<1.4", want: "1.2.0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.constraint, func(t *testing.T) {
+			c, err := ParseVersionConstraint(tc.constraint)
+			if err != nil {
+				t.Fatalf("ParseVersionConstraint(%q) failed: %v", tc.constraint, err)
+			}
+			got, err := c.Latest(versions)
+			if err != nil {
+				t.Fatalf("Latest() failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Latest() = %q, want %q", got, tc.want)
+			}
+			// The result must not depend on the order of the versions.
+			reversed := make([]string, len(versions))
+			for i, v := range versions {
+				reversed[len(versions)-1-i] = v
+			}
+			if got, err := c.Latest(reversed); err != nil || got != tc.want {
+				t.Errorf("Latest(reversed) = %q, %v, want %q", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestVersionConstraintLatestNoMatch(t *testing.T) {
+	c, err := ParseVersionConstraint(">=3.0.0")
+	if err != nil {
+		t.Fatalf("ParseVersionConstraint() failed: %v", err)
+	}
+	if got, err := c.Latest([]string{"1.0.0", "2.0.0", "3.0.0-rc.1"}); err == nil {
+		t.Errorf("Latest() = %q, want error", got)
+	}
+}
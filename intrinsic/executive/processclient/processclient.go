@@ -0,0 +1,166 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package processclient gets and sets the active process (behavior tree) of the executive.
+//
+// It has no dependency on the inctl command line tooling and can be used to embed these
+// operations in other Go programs.
+package processclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	apb "intrinsic/executive/proto/annotations_go_proto"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	rmdpb "intrinsic/executive/proto/run_metadata_go_proto"
+)
+
+var (
+	// ErrNoProcess is returned by Get if no process is loaded into the executive.
+	ErrNoProcess = errors.New("no operations found, did you load a behavior tree into the executive?")
+	// ErrMultipleProcesses is returned by Set if more than one process is loaded into the executive.
+	ErrMultipleProcesses = errors.New("more than one concurrently loaded behavior tree/executive operation, please delete all but one")
+)
+
+var (
+	protoNameBehaviorTree     = proto.MessageName(new(btpb.BehaviorTree))
+	protoNameBehaviorTreeNode = proto.MessageName(new(btpb.BehaviorTree_Node))
+)
+
+// Client gets and sets the active process of an executive.
+type Client struct {
+	executive execgrpcpb.ExecutiveServiceClient
+}
+
+// New returns a client for the executive behind conn.
+func New(conn grpc.ClientConnInterface) *Client {
+	return NewFromExecutive(execgrpcpb.NewExecutiveServiceClient(conn))
+}
+
+// NewFromExecutive returns a client that uses the given executive service client.
+func NewFromExecutive(executive execgrpcpb.ExecutiveServiceClient) *Client {
+	return &Client{executive: executive}
+}
+
+// GetOptions configures Get.
+type GetOptions struct {
+	// ClearTreeID clears the tree_id field of the returned behavior tree.
+	ClearTreeID bool
+	// ClearNodeIDs clears the id fields of the nodes of the returned behavior tree.
+	ClearNodeIDs bool
+}
+
+// Get returns the active process. If several processes are loaded, the first one is returned.
+//
+// Output only fields are cleared from the returned behavior tree.
+func (c *Client) Get(ctx context.Context, opts GetOptions) (*btpb.BehaviorTree, error) {
+	resp, err := c.executive.ListOperations(ctx, &lrpb.ListOperationsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list executive operations: %w", err)
+	}
+	if len(resp.GetOperations()) == 0 {
+		return nil, ErrNoProcess
+	}
+
+	metadata := new(rmdpb.RunMetadata)
+	if err := resp.GetOperations()[0].GetMetadata().UnmarshalTo(metadata); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal RunMetadata proto: %w", err)
+	}
+	bt := metadata.GetBehaviorTree()
+	ClearTree(bt, opts.ClearTreeID, opts.ClearNodeIDs)
+	return bt, nil
+}
+
+// SetOptions configures Set.
+type SetOptions struct {
+	// ClearTreeID clears the tree_id field of the behavior tree before it is loaded.
+	ClearTreeID bool
+	// ClearNodeIDs clears the id fields of the nodes of the behavior tree before it is loaded.
+	ClearNodeIDs bool
+}
+
+// Set replaces the active process with bt. Output only fields are cleared before the behavior
+// tree is loaded, bt itself is not modified.
+//
+// Returns ErrMultipleProcesses if more than one process is loaded, since it is unclear which one
+// to replace.
+func (c *Client) Set(ctx context.Context, bt *btpb.BehaviorTree, opts SetOptions) error {
+	resp, err := c.executive.ListOperations(ctx, &lrpb.ListOperationsRequest{})
+	if err != nil {
+		return fmt.Errorf("unable to list executive operations: %w", err)
+	}
+	switch len(resp.GetOperations()) {
+	case 0:
+	case 1:
+		if _, err := c.executive.DeleteOperation(ctx, &lrpb.DeleteOperationRequest{
+			Name: resp.GetOperations()[0].GetName(),
+		}); err != nil {
+			return fmt.Errorf("unable to delete operation: %w", err)
+		}
+	default:
+		return ErrMultipleProcesses
+	}
+
+	bt = proto.Clone(bt).(*btpb.BehaviorTree)
+	ClearTree(bt, opts.ClearTreeID, opts.ClearNodeIDs)
+	req := &execgrpcpb.CreateOperationRequest{
+		RunnableType: &execgrpcpb.CreateOperationRequest_BehaviorTree{BehaviorTree: bt},
+	}
+	if _, err := c.executive.CreateOperation(ctx, req); err != nil {
+		return fmt.Errorf("unable to create executive operation: %w", err)
+	}
+	return nil
+}
+
+// ClearTree clears all output only fields from m and its sub-messages in place. If clearTreeID
+// or clearNodeIDs is set, the tree_id fields of behavior trees or the id fields of their nodes are
+// cleared as well.
+func ClearTree(m proto.Message, clearTreeID bool, clearNodeIDs bool) {
+	refl := m.ProtoReflect()
+
+	n := proto.MessageName(m)
+	if clearTreeID && n == protoNameBehaviorTree {
+		clearField("tree_id", refl)
+	}
+	if clearNodeIDs && n == protoNameBehaviorTreeNode {
+		clearField("id", refl)
+	}
+
+	for i := 0; i < refl.Descriptor().Fields().Len(); i++ {
+		field := refl.Descriptor().Fields().Get(i)
+		if !refl.Has(field) {
+			continue
+		}
+		options := field.Options().(*descriptorpb.FieldOptions)
+		if proto.GetExtension(options, apb.E_OutputOnly).(bool) {
+			refl.Clear(field)
+			continue
+		}
+
+		if field.Kind() != protoreflect.MessageKind {
+			continue
+		}
+		if field.IsList() {
+			list := refl.Get(field).List()
+			for j := 0; j < list.Len(); j++ {
+				ClearTree(list.Get(j).Message().Interface(), clearTreeID, clearNodeIDs)
+			}
+		} else if !field.IsMap() {
+			ClearTree(refl.Get(field).Message().Interface(), clearTreeID, clearNodeIDs)
+		}
+	}
+}
+
+func clearField(fieldName string, refl protoreflect.Message) {
+	field := refl.Descriptor().Fields().ByTextName(fieldName)
+	if refl.Has(field) {
+		refl.Clear(field)
+	}
+}
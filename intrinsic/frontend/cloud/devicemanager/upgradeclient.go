@@ -0,0 +1,251 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package upgradeclient controls the software upgrades of a cluster.
+//
+// It has no dependency on the inctl command line tooling and can be used to embed these
+// operations in other Go programs.
+package upgradeclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc"
+
+	fmpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	clustermanagerpb "intrinsic/frontend/cloud/api/clustermanager_api_go_grpc_proto"
+	"intrinsic/frontend/cloud/devicemanager/info"
+	"intrinsic/frontend/cloud/devicemanager/messages"
+	"intrinsic/frontend/frontendclient"
+)
+
+// Mode is the update mechanism mode of a cluster.
+type Mode string
+
+const (
+	// ModeOff means that no updates can run.
+	ModeOff Mode = "off"
+	// ModeOn means that updates run on demand, when triggered by the user.
+	ModeOn Mode = "on"
+	// ModeAutomatic means that updates run as soon as they are available.
+	ModeAutomatic Mode = "automatic"
+	// ModeUnknown is returned for modes this package does not know about.
+	ModeUnknown Mode = "unknown"
+)
+
+// Authorizer adds credentials to HTTP requests, e.g., an inctl auth.ProjectToken.
+type Authorizer = frontendclient.Authorizer
+
+// Options configures a Client.
+type Options struct {
+	// Project is the cloud project the cluster is registered in.
+	Project string
+	// Org is the organization the cluster belongs to.
+	Org string
+	// Cluster is the name of the cluster.
+	Cluster string
+	// HTTPClient is used for the requests to the cluster update API. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Client queries and controls the upgrades of a single cluster.
+type Client struct {
+	opts     Options
+	auth     Authorizer
+	clusters clustermanagerpb.ClustersServiceClient
+}
+
+// New returns a client for the cluster given in opts. conn is a connection to the cluster manager
+// of the project, auth authorizes the requests to the cluster update API.
+func New(conn grpc.ClientConnInterface, auth Authorizer, opts Options) *Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	return &Client{
+		opts:     opts,
+		auth:     auth,
+		clusters: clustermanagerpb.NewClustersServiceClient(conn),
+	}
+}
+
+// Status returns the update status of the cluster.
+func (c *Client) Status(ctx context.Context) (*info.Info, error) {
+	b, err := c.runReq(ctx, http.MethodGet, c.updateURL("/state", nil))
+	if err != nil {
+		return nil, err
+	}
+	ui := &info.Info{}
+	if err := json.Unmarshal(b, ui); err != nil {
+		return nil, fmt.Errorf("unmarshal json response for status: %w", err)
+	}
+	return ui, nil
+}
+
+// ProjectTarget returns the versions the cluster should run to be considered up to date for its
+// environment.
+func (c *Client) ProjectTarget(ctx context.Context) (*messages.ClusterProjectTargetResponse, error) {
+	b, err := c.runReq(ctx, http.MethodGet, c.updateURL("/projecttarget", nil))
+	if err != nil {
+		return nil, err
+	}
+	r := &messages.ClusterProjectTargetResponse{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("unmarshal json response for project target: %w", err)
+	}
+	return r, nil
+}
+
+// RunOptions configures Run.
+type RunOptions struct {
+	// Rollback triggers a rollback to the previous versions instead of an upgrade.
+	Rollback bool
+}
+
+// Run starts an update if one is pending. The cluster might reboot in the process.
+func (c *Client) Run(ctx context.Context, opts RunOptions) error {
+	v := url.Values{}
+	if opts.Rollback {
+		v.Set("rollback", "y")
+	}
+	_, err := c.runReq(ctx, http.MethodPost, c.updateURL("/run", v))
+	return err
+}
+
+// Mode returns the update mechanism mode of the cluster.
+func (c *Client) Mode(ctx context.Context) (Mode, error) {
+	cluster, err := c.clusters.GetCluster(ctx, &clustermanagerpb.GetClusterRequest{
+		Project:   c.opts.Project,
+		Org:       c.opts.Org,
+		ClusterId: c.opts.Cluster,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cluster status: %w", err)
+	}
+	return DecodeMode(cluster.GetUpdateMode()), nil
+}
+
+// SetMode sets the update mechanism mode of the cluster.
+func (c *Client) SetMode(ctx context.Context, mode Mode) error {
+	pbm := EncodeMode(mode)
+	if pbm == clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_UNSPECIFIED {
+		return fmt.Errorf("invalid mode: %s", mode)
+	}
+	if _, err := c.clusters.UpdateCluster(ctx, &clustermanagerpb.UpdateClusterRequest{
+		Project: c.opts.Project,
+		Org:     c.opts.Org,
+		Cluster: &clustermanagerpb.Cluster{
+			ClusterName: c.opts.Cluster,
+			UpdateMode:  pbm,
+		},
+		UpdateMask: &fmpb.FieldMask{Paths: []string{"update_mode"}},
+	}); err != nil {
+		return fmt.Errorf("update cluster: %w", err)
+	}
+	return nil
+}
+
+// WaitForVersion polls the update status every pollInterval until the cluster runs the given
+// versions or ctx is done. Errors are tolerated while waiting, since the cluster may reboot during
+// the upgrade.
+func (c *Client) WaitForVersion(ctx context.Context, base, osVersion string, pollInterval time.Duration) error {
+	for {
+		ui, err := c.Status(ctx)
+		if err == nil && ui.CurrentBase == base && ui.CurrentOS == osVersion {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("cluster did not reach flowstate %q and os %q: %w (last error: %v)", base, osVersion, ctx.Err(), err)
+			}
+			return fmt.Errorf("cluster did not reach flowstate %q and os %q: %w (running flowstate %q and os %q)", base, osVersion, ctx.Err(), ui.CurrentBase, ui.CurrentOS)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Finished reports whether no upgrade is in progress on the cluster, i.e., it is deployed and runs
+// its target versions.
+func Finished(ui *info.Info) bool {
+	return ui.UpdateDone() &&
+		(ui.TargetBase == "" || ui.TargetBase == ui.CurrentBase) &&
+		(ui.TargetOS == "" || ui.TargetOS == ui.CurrentOS)
+}
+
+// Watch polls the update status every pollInterval until done returns true for it or ctx is done.
+// It calls changed with the previous and the new status whenever the state, mode or versions of
+// the cluster change, and for the first status with a nil previous status. Errors are tolerated
+// like in WaitForVersion.
+func (c *Client) Watch(ctx context.Context, pollInterval time.Duration, changed func(prev, cur *info.Info), done func(*info.Info) bool) error {
+	var prev *info.Info
+	for {
+		ui, err := c.Status(ctx)
+		if err == nil {
+			if prev == nil || statusChanged(prev, ui) {
+				changed(prev, ui)
+				prev = ui
+			}
+			if done(ui) {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("stopped watching the upgrade: %w (last error: %v)", ctx.Err(), err)
+			}
+			return fmt.Errorf("stopped watching the upgrade: %w (state %q)", ctx.Err(), ui.State)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func statusChanged(prev, cur *info.Info) bool {
+	return prev.State != cur.State || prev.Mode != cur.Mode ||
+		prev.CurrentBase != cur.CurrentBase || prev.CurrentOS != cur.CurrentOS ||
+		prev.TargetBase != cur.TargetBase || prev.TargetOS != cur.TargetOS
+}
+
+// EncodeMode converts a mode to its proto representation. Unknown modes are encoded as
+// PLATFORM_UPDATE_MODE_UNSPECIFIED.
+func EncodeMode(mode Mode) clustermanagerpb.PlatformUpdateMode {
+	switch mode {
+	case ModeOff:
+		return clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_OFF
+	case ModeOn:
+		return clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_ON
+	case ModeAutomatic:
+		return clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_AUTOMATIC
+	default:
+		return clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_UNSPECIFIED
+	}
+}
+
+// DecodeMode converts the proto representation of a mode. Unknown modes are decoded as
+// ModeUnknown.
+func DecodeMode(mode clustermanagerpb.PlatformUpdateMode) Mode {
+	switch mode {
+	case clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_OFF:
+		return ModeOff
+	case clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_ON:
+		return ModeOn
+	case clustermanagerpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_AUTOMATIC:
+		return ModeAutomatic
+	default:
+		return ModeUnknown
+	}
+}
+
+func (c *Client) updateURL(subPath string, values url.Values) url.URL {
+	return frontendclient.ClusterUpdateURL(c.opts.Project, c.opts.Cluster, subPath, values)
+}
+
+// runReq runs a method request with url and returns the response body.
+func (c *Client) runReq(ctx context.Context, method string, u url.URL) ([]byte, error) {
+	return frontendclient.Call(ctx, c.opts.HTTPClient, c.auth, method, u, nil)
+}
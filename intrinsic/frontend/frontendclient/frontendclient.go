@@ -0,0 +1,239 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package frontendclient calls the HTTP endpoints of the flowstate frontend of a cluster, either
+// via the relay of the cluster in the cloud or directly on the local network, and the cluster
+// update and device manager endpoints of a project.
+//
+// It has no dependency on the inctl command line tooling and can be used to embed these
+// operations in other Go programs.
+package frontendclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+const (
+	// LocalAddress is the address of the frontend of a cluster running on the local machine, e.g.,
+	// in minikube.
+	LocalAddress = "localhost:17080"
+
+	// XSRFTokenHeader is the header in which the XSRF token is sent to the frontend.
+	XSRFTokenHeader = "X-XSRF-TOKEN"
+
+	tokenEndpoint       = "token"
+	consoleLogsEndpoint = "consoleLogs"
+)
+
+// ProjectHost returns the host of the cloud endpoints of project.
+func ProjectHost(project string) string {
+	return fmt.Sprintf("www.endpoints.%s.cloud.goog", project)
+}
+
+// RelayURL returns the base URL of the frontend API of cluster via its relay in project.
+func RelayURL(project, cluster string) url.URL {
+	return url.URL{
+		Scheme: "https",
+		Host:   ProjectHost(project),
+		Path:   fmt.Sprintf("frontend/client/%s/api", cluster),
+	}
+}
+
+// LocalURL returns the base URL of the frontend API of a cluster reachable at address without the
+// relay, e.g., LocalAddress or the address of a cluster on the local network.
+func LocalURL(address string) url.URL {
+	return url.URL{Scheme: "http", Host: address, Path: "frontend/api"}
+}
+
+// ClusterUpdateURL returns the URL of the cluster update endpoint subPath for cluster in project,
+// e.g., "/state" or "/run", with the given query values.
+func ClusterUpdateURL(project, cluster, subPath string, values url.Values) url.URL {
+	v := url.Values{}
+	for key, vals := range values {
+		v[key] = append([]string(nil), vals...)
+	}
+	v.Set("cluster", cluster)
+	return url.URL{
+		Scheme:   "https",
+		Host:     ProjectHost(project),
+		Path:     path.Join("/api/clusterupdate/", subPath),
+		RawQuery: v.Encode(),
+	}
+}
+
+// DeviceURL returns the URL of the device manager endpoint subPath for the device deviceID of cluster
+// in project, e.g., "configure" or "relay/v1alpha1/status".
+func DeviceURL(project, cluster, deviceID, subPath string) url.URL {
+	return url.URL{
+		Scheme:   "https",
+		Host:     ProjectHost(project),
+		Path:     path.Join("/api/devices/", subPath),
+		RawQuery: url.Values{"device-id": []string{deviceID}, "cluster": []string{cluster}}.Encode(),
+	}
+}
+
+// Authorizer adds credentials to HTTP requests, e.g., an inctl auth.ProjectToken.
+type Authorizer interface {
+	HTTPAuthorization(req *http.Request) (*http.Request, error)
+}
+
+// ResponseError is returned for responses with a status other than 200 OK.
+type ResponseError struct {
+	// StatusCode is the HTTP status code of the response, e.g., 503.
+	StatusCode int
+	// Status is the HTTP status of the response, e.g., "503 Service Unavailable".
+	Status string
+	// Body is the body of the response, which may describe the error. Empty for streams.
+	Body []byte
+}
+
+func (e *ResponseError) Error() string {
+	if body := strings.TrimSpace(string(e.Body)); body != "" {
+		return fmt.Sprintf("unexpected response: %s: %s", e.Status, body)
+	}
+	return fmt.Sprintf("unexpected response: %s", e.Status)
+}
+
+// Do sends a request to u with the given header and the credentials of auth, which may be nil. It
+// returns the response if its status is 200 OK, the caller has to close its body. Otherwise a
+// *ResponseError is returned.
+func Do(ctx context.Context, client *http.Client, auth Authorizer, method string, u url.URL, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create %s request for %s: %w", method, u.Path, err)
+	}
+	if header != nil {
+		req.Header = header.Clone()
+	}
+	if auth != nil {
+		if req, err = auth.HTTPAuthorization(req); err != nil {
+			return nil, fmt.Errorf("cannot obtain credentials: %w", err)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request to %s failed: %w", method, u.Path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		// The body may explain the error.
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &ResponseError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+	}
+	return resp, nil
+}
+
+// Call is like Do, but returns the body of the response.
+func Call(ctx context.Context, client *http.Client, auth Authorizer, method string, u url.URL, header http.Header) ([]byte, error) {
+	resp, err := Do(ctx, client, auth, method, u, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read response of %s: %w", u.Path, err)
+	}
+	return b, nil
+}
+
+// Options configures a Client.
+type Options struct {
+	// HTTPClient is used for the requests, e.g., to present a client certificate. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+	// Auth authorizes the requests. Nil if the frontend needs no credentials, e.g., locally.
+	Auth Authorizer
+}
+
+// Client calls the endpoints of the frontend API of a single cluster.
+type Client struct {
+	baseURL url.URL
+	opts    Options
+
+	mu        sync.Mutex
+	xsrfToken string
+}
+
+// New returns a client for the frontend API at baseURL, see RelayURL and LocalURL.
+func New(baseURL url.URL, opts Options) *Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	return &Client{baseURL: baseURL, opts: opts}
+}
+
+// URL returns the URL of endpoint with the given query values.
+func (c *Client) URL(endpoint string, values url.Values) url.URL {
+	u := c.baseURL
+	u.Path = path.Join(u.EscapedPath(), endpoint)
+	u.RawQuery = values.Encode()
+	return u
+}
+
+// XSRFToken returns the XSRF token the frontend requires for all other endpoints. It is fetched
+// from the token endpoint on first use.
+func (c *Client) XSRFToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.xsrfToken != "" {
+		return c.xsrfToken, nil
+	}
+	b, err := Call(ctx, c.opts.HTTPClient, c.opts.Auth, http.MethodGet, c.URL(tokenEndpoint, nil), nil)
+	if err != nil {
+		return "", fmt.Errorf("could not obtain xsrf token: %w", err)
+	}
+	c.xsrfToken = string(b)
+	return c.xsrfToken, nil
+}
+
+// Header returns the headers which authenticate requests to the endpoints of the frontend, i.e.,
+// the XSRF token and the credentials. Use it for requests which are not sent with Do, e.g., to
+// open websockets.
+func (c *Client) Header(ctx context.Context) (http.Header, error) {
+	token, err := c.XSRFToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set(XSRFTokenHeader, token)
+	if c.opts.Auth != nil {
+		if _, err := c.opts.Auth.HTTPAuthorization(&http.Request{Header: header}); err != nil {
+			return nil, fmt.Errorf("cannot obtain credentials: %w", err)
+		}
+	}
+	return header, nil
+}
+
+// Do sends a request to endpoint with the XSRF token and credentials, see the function Do.
+func (c *Client) Do(ctx context.Context, method, endpoint string, values url.Values) (*http.Response, error) {
+	token, err := c.XSRFToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set(XSRFTokenHeader, token)
+	return Do(ctx, c.opts.HTTPClient, c.opts.Auth, method, c.URL(endpoint, values), header)
+}
+
+// ConsoleLogsURL returns the URL of the consoleLogs endpoint with the given query values.
+func (c *Client) ConsoleLogsURL(values url.Values) url.URL {
+	return c.URL(consoleLogsEndpoint, values)
+}
+
+// ConsoleLogs returns the logs selected by the query values, e.g., resourceName and tailLines,
+// from the consoleLogs endpoint. The caller has to close the returned reader. With follow set, the
+// logs are streamed until ctx is done.
+func (c *Client) ConsoleLogs(ctx context.Context, values url.Values) (io.ReadCloser, error) {
+	resp, err := c.Do(ctx, http.MethodGet, consoleLogsEndpoint, values)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
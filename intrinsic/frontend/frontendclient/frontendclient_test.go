@@ -0,0 +1,159 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package frontendclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type fakeAuth struct{}
+
+func (fakeAuth) HTTPAuthorization(req *http.Request) (*http.Request, error) {
+	req.Header.Set("Authorization", "Bearer key")
+	return req, nil
+}
+
+// newFrontend returns the base URL of a frontend which serves the token and consoleLogs endpoints
+// and counts the requests for tokens.
+func newFrontend(t *testing.T, tokenRequests *int) url.URL {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Bearer key":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/frontend/api/token":
+			*tokenRequests++
+			fmt.Fprint(w, "xsrf")
+		case r.Header.Get(XSRFTokenHeader) != "xsrf":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/frontend/api/consoleLogs":
+			fmt.Fprintf(w, "logs of %s\n", r.URL.Query().Get("resourceName"))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, "relay is down")
+		}
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() failed: %v", err)
+	}
+	u.Path = "/frontend/api"
+	return *u
+}
+
+func TestConsoleLogs(t *testing.T) {
+	tokenRequests := 0
+	c := New(newFrontend(t, &tokenRequests), Options{Auth: fakeAuth{}})
+
+	for i := 0; i < 2; i++ {
+		logs, err := c.ConsoleLogs(context.Background(), url.Values{"resourceName": []string{"executive"}})
+		if err != nil {
+			t.Fatalf("ConsoleLogs() failed: %v", err)
+		}
+		got, err := io.ReadAll(logs)
+		logs.Close()
+		if err != nil {
+			t.Fatalf("io.ReadAll() failed: %v", err)
+		}
+		if want := "logs of executive\n"; string(got) != want {
+			t.Errorf("ConsoleLogs() returned %q, want %q", got, want)
+		}
+	}
+	if tokenRequests != 1 {
+		t.Errorf("ConsoleLogs() requested %d tokens, want 1", tokenRequests)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	tokenRequests := 0
+	c := New(newFrontend(t, &tokenRequests), Options{Auth: fakeAuth{}})
+
+	header, err := c.Header(context.Background())
+	if err != nil {
+		t.Fatalf("Header() failed: %v", err)
+	}
+	if got := header.Get(XSRFTokenHeader); got != "xsrf" {
+		t.Errorf("Header() has XSRF token %q, want %q", got, "xsrf")
+	}
+	if got := header.Get("Authorization"); got != "Bearer key" {
+		t.Errorf("Header() has authorization %q, want %q", got, "Bearer key")
+	}
+}
+
+func TestDoReturnsResponseError(t *testing.T) {
+	tokenRequests := 0
+	c := New(newFrontend(t, &tokenRequests), Options{Auth: fakeAuth{}})
+
+	_, err := c.Do(context.Background(), http.MethodGet, "unknown", nil)
+	var rerr *ResponseError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Do() returned %v, want a *ResponseError", err)
+	}
+	if rerr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Do() returned status %d, want %d", rerr.StatusCode, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(err.Error(), "relay is down") {
+		t.Errorf("Do() returned %q, want it to contain the response body", err)
+	}
+}
+
+func TestXSRFTokenWithoutCredentials(t *testing.T) {
+	tokenRequests := 0
+	c := New(newFrontend(t, &tokenRequests), Options{})
+
+	_, err := c.XSRFToken(context.Background())
+	var rerr *ResponseError
+	if !errors.As(err, &rerr) || rerr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("XSRFToken() returned %v, want status %d", err, http.StatusUnauthorized)
+	}
+}
+
+func TestURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		got  url.URL
+		want string
+	}{
+		{
+			name: "relay",
+			got:  RelayURL("my-project", "my-cluster"),
+			want: "https://www.endpoints.my-project.cloud.goog/frontend/client/my-cluster/api",
+		},
+		{
+			name: "local",
+			got:  LocalURL(LocalAddress),
+			want: "http://localhost:17080/frontend/api",
+		},
+		{
+			name: "console logs",
+			got:  New(RelayURL("my-project", "my-cluster"), Options{}).ConsoleLogsURL(url.Values{"tailLines": []string{"10"}}),
+			want: "https://www.endpoints.my-project.cloud.goog/frontend/client/my-cluster/api/consoleLogs?tailLines=10",
+		},
+		{
+			name: "cluster update",
+			got:  ClusterUpdateURL("my-project", "my-cluster", "/run", url.Values{"rollback": []string{"y"}}),
+			want: "https://www.endpoints.my-project.cloud.goog/api/clusterupdate/run?cluster=my-cluster&rollback=y",
+		},
+		{
+			name: "device",
+			got:  DeviceURL("my-project", "my-cluster", "my-device", "configure:status"),
+			want: "https://www.endpoints.my-project.cloud.goog/api/devices/configure:status?cluster=my-cluster&device-id=my-device",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.got.String(); got != tc.want {
+				t.Errorf("got URL %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// add_skill_test_evidence adds unit test results to a skill image archive so
+// that `inctl skill release` can verify that the skill was tested.
+//
+// It is typically run after the skill's tests, e.g.:
+//
+//	bazel test //my/skill:all
+//	bazel run //intrinsic/skills/build_defs:add_skill_test_evidence -- \
+//	  --bundle=$(pwd)/bazel-bin/my/skill/skill_image.tar \
+//	  --junit_xml=$(pwd)/bazel-testlogs/my/skill/skill_test/test.xml
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"flag"
+	log "github.com/golang/glog"
+	"intrinsic/assets/bundleio"
+	intrinsic "intrinsic/production/intrinsic"
+)
+
+var (
+	flagBundle      = flag.String("bundle", "", "Path to the skill image archive.")
+	flagJUnitXML    = flag.String("junit_xml", "", "Comma separated paths to JUnit XML test reports.")
+	flagTestTargets = flag.String("test_targets", "", "Optional comma separated labels of the test targets the reports belong to.")
+	flagOutput      = flag.String("output_bundle", "", "Path to write the archive with test evidence to. Defaults to --bundle.")
+)
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func addTestEvidence() error {
+	if *flagBundle == "" || *flagJUnitXML == "" {
+		return fmt.Errorf("--bundle and --junit_xml are required")
+	}
+	output := *flagOutput
+	if output == "" {
+		output = *flagBundle
+	}
+
+	evidence, err := bundleio.NewTestEvidence(splitList(*flagJUnitXML), splitList(*flagTestTargets))
+	if err != nil {
+		return err
+	}
+	if err := bundleio.WriteTestEvidence(*flagBundle, output, evidence); err != nil {
+		return err
+	}
+
+	passed, failed, skipped := evidence.Counts()
+	log.Infof("added test evidence to %s: %d passed, %d failed, %d skipped", output, passed, failed, skipped)
+	return nil
+}
+
+func main() {
+	intrinsic.Init()
+	if err := addTestEvidence(); err != nil {
+		log.Exitf("Failed to add test evidence: %v", err)
+	}
+}
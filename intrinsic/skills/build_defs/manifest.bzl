@@ -48,6 +48,31 @@ def _skill_manifest_impl(ctx):
         mnemonic = "SkillManifest",
     )
 
+    # The linter runs as a validation action, so that manifest problems are reported when building
+    # any target depending on the manifest. They only fail the build with strict_lint.
+    lint_out = ctx.actions.declare_file(ctx.label.name + "_lint.ok")
+    lint_args = ctx.actions.args().add(
+        "--manifest",
+        pbtxt,
+    ).add(
+        "--output",
+        lint_out,
+    ).add_joined(
+        "--file_descriptor_sets",
+        transitive_descriptor_sets,
+        join_with = ",",
+    )
+    if ctx.attr.strict_lint:
+        lint_args.add("--fail_on_problems")
+    ctx.actions.run(
+        outputs = [lint_out],
+        inputs = depset([pbtxt], transitive = [transitive_descriptor_sets]),
+        executable = ctx.executable._skillmanifestlint,
+        arguments = [lint_args],
+        mnemonic = "SkillManifestLint",
+        progress_message = "Linting skill manifest %s" % pbtxt.short_path,
+    )
+
     return [
         DefaultInfo(
             files = depset(outputs),
@@ -57,6 +82,7 @@ def _skill_manifest_impl(ctx):
             manifest_binary_file = outputfile,
             file_descriptor_set = file_descriptor_set_out,
         ),
+        OutputGroupInfo(_validation = depset([lint_out])),
     ]
 
 skill_manifest = rule(
@@ -87,10 +113,20 @@ skill_manifest = rule(
             providers = [ProtoInfo],
             aspects = [gen_source_code_info_descriptor_set],
         ),
+        "strict_lint": attr.bool(
+            default = False,
+            doc = "Fail the build if the manifest linter finds problems instead of reporting " +
+                  "them as warnings.",
+        ),
         "_skillmanifestgen": attr.label(
             default = Label("//intrinsic/skills/build_defs:skillmanifestgen"),
             executable = True,
             cfg = "exec",
         ),
+        "_skillmanifestlint": attr.label(
+            default = Label("//intrinsic/skills/build_defs:skillmanifestlint"),
+            executable = True,
+            cfg = "exec",
+        ),
     },
 )
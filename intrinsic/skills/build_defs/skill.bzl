@@ -290,16 +290,35 @@ def build_symlinks(skill_service_name, skill_service_config_name):
 
 # Generate a file containing the Docker image labels. This only works for rules_oci, as
 # rules_docker does not support a file containing labels as input to docker_build.
+#
+# If the build is stamped, build info labels are added from the workspace status: the git commit
+# (STABLE_GIT_COMMIT), the SDK version (STABLE_INTRINSIC_SDK_VERSION) and the build time. Keys which
+# the workspace status command does not provide are skipped.
 def _skill_labels_impl(ctx):
     skill_id = ctx.attr.skill_id[SkillIdInfo]
     outputfile = ctx.actions.declare_file(ctx.label.name + ".labels")
+    inputs = [skill_id.id_filename]
     cmd = """
     echo "ai.intrinsic.asset-id=$(cat {id_filename})" > {output}""".format(
         id_filename = skill_id.id_filename.path,
         output = outputfile.path,
     )
+    if ctx.attr.stamp:
+        inputs += [ctx.info_file, ctx.version_file]
+        cmd += """
+    label() {{
+      value=$(sed -n "s/^$2 //p" "$3")
+      if [ -n "$value" ]; then echo "$1=$value" >> {output}; fi
+    }}
+    label ai.intrinsic.build.git-commit STABLE_GIT_COMMIT {info_file}
+    label ai.intrinsic.build.sdk-version STABLE_INTRINSIC_SDK_VERSION {info_file}
+    label ai.intrinsic.build.timestamp BUILD_TIMESTAMP {version_file}""".format(
+            info_file = ctx.info_file.path,
+            version_file = ctx.version_file.path,
+            output = outputfile.path,
+        )
     ctx.actions.run_shell(
-        inputs = [skill_id.id_filename],
+        inputs = inputs,
         outputs = [outputfile],
         command = cmd,
     )
@@ -313,9 +332,19 @@ _skill_labels = rule(
             mandatory = True,
             providers = [SkillIdInfo],
         ),
+        "stamp": attr.bool(
+            default = False,
+            doc = "Whether to add build info labels from the workspace status.",
+        ),
     },
 )
 
+def _stamp():
+    return select({
+        Label("//intrinsic/skills/build_defs:stamp"): True,
+        "//conditions:default": False,
+    })
+
 def cc_skill(
         name,
         deps,
@@ -402,6 +431,7 @@ def cc_skill(
     _skill_labels(
         name = labels,
         skill_id = skill_id_name,
+        stamp = _stamp(),
         visibility = ["//visibility:private"],
         tags = ["manual", "avoid_dep"],
     )
@@ -507,6 +537,7 @@ def py_skill(
     _skill_labels(
         name = labels_name,
         skill_id = skill_id_name,
+        stamp = _stamp(),
         visibility = ["//visibility:private"],
         tags = ["manual", "avoid_dep"],
     )
@@ -9,11 +9,14 @@ import (
 
 	"flag"
 	log "github.com/golang/glog"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"intrinsic/assets/idutils"
 	"intrinsic/assets/metadatafieldlimits"
 	intrinsic "intrinsic/production/intrinsic"
 	smpb "intrinsic/skills/proto/skill_manifest_go_proto"
+	"intrinsic/util/proto/descriptorexport"
+	"intrinsic/util/proto/descriptorset"
 	"intrinsic/util/proto/protoio"
 	"intrinsic/util/proto/registryutil"
 )
@@ -58,12 +61,28 @@ func validateManifest(m *smpb.Manifest, types *protoregistry.Types) error {
 	return nil
 }
 
+// pruneFileDescriptorSet returns the subset of set which is needed to resolve
+// the parameter and return messages of the skill. The set is returned as is if
+// the skill has neither.
+func pruneFileDescriptorSet(m *smpb.Manifest, set *descriptorpb.FileDescriptorSet) (*descriptorpb.FileDescriptorSet, error) {
+	var roots []string
+	for _, name := range []string{m.GetParameter().GetMessageFullName(), m.GetReturnType().GetMessageFullName()} {
+		if name != "" {
+			roots = append(roots, name)
+		}
+	}
+	if len(roots) == 0 {
+		return set, nil
+	}
+	return descriptorexport.PruneFileDescriptorSet(roots, set)
+}
+
 func createSkillManifest() error {
 	var fds []string
 	if *flagFileDescriptorSets != "" {
 		fds = strings.Split(*flagFileDescriptorSets, ",")
 	}
-	set, err := registryutil.LoadFileDescriptorSets(fds)
+	set, err := descriptorset.ReadTransitive(fds)
 	if err != nil {
 		return fmt.Errorf("unable to build FileDescriptorSet: %v", err)
 	}
@@ -80,6 +99,9 @@ func createSkillManifest() error {
 	if err := validateManifest(m, types); err != nil {
 		return err
 	}
+	if set, err = pruneFileDescriptorSet(m, set); err != nil {
+		return fmt.Errorf("could not prune file descriptor set: %v", err)
+	}
 	if err := protoio.WriteBinaryProto(*flagOutput, m, protoio.WithDeterministic(true)); err != nil {
 		return fmt.Errorf("could not write skill manifest proto: %v", err)
 	}
@@ -0,0 +1,68 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package main
+
+import (
+	"testing"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/reflect/protodesc"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	smpb "intrinsic/skills/proto/skill_manifest_go_proto"
+)
+
+func TestPruneFileDescriptorSet(t *testing.T) {
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(emptypb.File_google_protobuf_empty_proto),
+			protodesc.ToFileDescriptorProto(durationpb.File_google_protobuf_duration_proto),
+			protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		manifest *smpb.Manifest
+		want     []string
+	}{
+		{
+			name: "parameter and return type",
+			manifest: &smpb.Manifest{
+				Parameter:  &smpb.ParameterMetadata{MessageFullName: "google.protobuf.Empty"},
+				ReturnType: &smpb.ReturnMetadata{MessageFullName: "google.protobuf.Duration"},
+			},
+			want: []string{"google/protobuf/empty.proto", "google/protobuf/duration.proto"},
+		},
+		{
+			name: "parameter only",
+			manifest: &smpb.Manifest{
+				Parameter: &smpb.ParameterMetadata{MessageFullName: "google.protobuf.Empty"},
+			},
+			want: []string{"google/protobuf/empty.proto"},
+		},
+		{
+			name:     "no messages",
+			manifest: &smpb.Manifest{},
+			want:     []string{"google/protobuf/empty.proto", "google/protobuf/duration.proto", "google/protobuf/timestamp.proto"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pruneFileDescriptorSet(tc.manifest, set)
+			if err != nil {
+				t.Fatalf("pruneFileDescriptorSet() failed: %v", err)
+			}
+			var names []string
+			for _, f := range got.GetFile() {
+				names = append(names, f.GetName())
+			}
+			if diff := cmp.Diff(tc.want, names); diff != "" {
+				t.Errorf("pruneFileDescriptorSet() returned unexpected files (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
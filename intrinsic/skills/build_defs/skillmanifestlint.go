@@ -0,0 +1,235 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// main lints a skill manifest text proto at build time.
+//
+// All problems are reported at once, each with the path of the offending field, so that they can
+// be fixed in the manifest instead of surfacing one by one when the skill is installed. Problems are
+// reported as warnings unless --fail_on_problems is set.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"flag"
+	log "github.com/golang/glog"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"intrinsic/assets/idutils"
+	"intrinsic/assets/metadatafieldlimits"
+	intrinsic "intrinsic/production/intrinsic"
+	smpb "intrinsic/skills/proto/skill_manifest_go_proto"
+	"intrinsic/util/proto/descriptorset"
+	"intrinsic/util/proto/protoio"
+	"intrinsic/util/proto/registryutil"
+)
+
+var (
+	flagManifest           = flag.String("manifest", "", "Path to a SkillManifest pbtxt file.")
+	flagFileDescriptorSets = flag.String("file_descriptor_sets", "", "Comma separated paths to binary file descriptor set protos.")
+	flagOutput             = flag.String("output", "", "Optional path of a file which is written if linting succeeds.")
+	flagFailOnProblems     = flag.Bool("fail_on_problems", false, "Fail if the manifest has problems instead of reporting them as warnings.")
+)
+
+var (
+	cppQualifiedNameRegex = regexp.MustCompile(`^(::)?[A-Za-z_]\w*(::[A-Za-z_]\w*)*$`)
+	pythonModuleRegex     = regexp.MustCompile(`^[A-Za-z_]\w*(\.[A-Za-z_]\w*)*$`)
+)
+
+// linter collects the problems of a manifest.
+type linter struct {
+	types    *protoregistry.Types
+	problems []string
+}
+
+func (l *linter) addf(field string, format string, args ...any) {
+	l.problems = append(l.problems, fmt.Sprintf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+func (l *linter) lintID(m *smpb.Manifest) {
+	if err := idutils.ValidatePackage(m.GetId().GetPackage()); err != nil {
+		l.addf("id.package", "%v", err)
+	}
+	if err := idutils.ValidateName(m.GetId().GetName()); err != nil {
+		l.addf("id.name", "%v", err)
+	}
+	if err := metadatafieldlimits.ValidateNameLength(m.GetId().GetName()); err != nil {
+		l.addf("id.name", "%v", err)
+	}
+}
+
+func (l *linter) lintDisplayNames(m *smpb.Manifest) {
+	if m.GetDisplayName() == "" {
+		l.addf("display_name", "must be set")
+	} else if err := metadatafieldlimits.ValidateDisplayNameLength(m.GetDisplayName()); err != nil {
+		l.addf("display_name", "%v", err)
+	}
+	if m.GetVendor().GetDisplayName() == "" {
+		l.addf("vendor.display_name", "must be set")
+	}
+}
+
+func (l *linter) lintDocumentation(m *smpb.Manifest) {
+	description := m.GetDocumentation().GetDescription()
+	if strings.TrimSpace(description) == "" {
+		l.addf("documentation.description", "must be set, it is shown to users of the skill")
+	} else if err := metadatafieldlimits.ValidateDescriptionLength(description); err != nil {
+		l.addf("documentation.description", "%v", err)
+	}
+}
+
+func (l *linter) lintOptions(m *smpb.Manifest) {
+	opts := m.GetOptions()
+	if opts.GetCancellationReadyTimeout() != nil {
+		if !opts.GetSupportsCancellation() {
+			l.addf("options.cancellation_ready_timeout", "is set, but options.supports_cancellation is false")
+		}
+		if err := opts.GetCancellationReadyTimeout().CheckValid(); err != nil {
+			l.addf("options.cancellation_ready_timeout", "%v", err)
+		} else if opts.GetCancellationReadyTimeout().AsDuration() <= 0 {
+			l.addf("options.cancellation_ready_timeout", "must be positive, got %v", opts.GetCancellationReadyTimeout().AsDuration())
+		}
+	}
+
+	switch cfg := opts.GetLanguageSpecificOptions().(type) {
+	case *smpb.Options_PythonConfig:
+		py := cfg.PythonConfig
+		for _, f := range []struct {
+			name  string
+			value string
+		}{
+			{"skill_module", py.GetSkillModule()},
+			{"proto_module", py.GetProtoModule()},
+			{"create_skill", py.GetCreateSkill()},
+		} {
+			if !pythonModuleRegex.MatchString(f.value) {
+				l.addf("options.python_config."+f.name, "%q is not a valid Python module or symbol", f.value)
+			}
+		}
+		if py.GetSkillModule() != "" && !strings.HasPrefix(py.GetCreateSkill(), py.GetSkillModule()+".") {
+			l.addf("options.python_config.create_skill", "%q is not declared in skill_module %q", py.GetCreateSkill(), py.GetSkillModule())
+		}
+	case *smpb.Options_CcConfig:
+		if !cppQualifiedNameRegex.MatchString(cfg.CcConfig.GetCreateSkill()) {
+			l.addf("options.cc_config.create_skill", "%q is not a valid C++ qualified name", cfg.CcConfig.GetCreateSkill())
+		}
+	}
+}
+
+func (l *linter) lintDependencies(m *smpb.Manifest) {
+	equipment := m.GetDependencies().GetRequiredEquipment()
+	slots := make([]string, 0, len(equipment))
+	for slot := range equipment {
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+	for _, slot := range slots {
+		field := fmt.Sprintf("dependencies.required_equipment[%q]", slot)
+		if strings.TrimSpace(slot) == "" || strings.ContainsAny(slot, " \t\n") {
+			l.addf(field, "slot names must not be empty or contain whitespace")
+		}
+		names := equipment[slot].GetCapabilityNames()
+		if len(names) == 0 {
+			l.addf(field+".capability_names", "must not be empty, the selector would match any resource")
+		}
+		seen := make(map[string]bool)
+		for i, name := range names {
+			switch {
+			case strings.TrimSpace(name) == "" || strings.ContainsAny(name, " \t\n"):
+				l.addf(fmt.Sprintf("%s.capability_names[%d]", field, i), "%q is not a valid capability name", name)
+			case seen[name]:
+				l.addf(fmt.Sprintf("%s.capability_names[%d]", field, i), "duplicate capability %q", name)
+			}
+			seen[name] = true
+		}
+	}
+}
+
+func (l *linter) findMessage(field string, name string) protoreflect.MessageType {
+	mt, err := l.types.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		l.addf(field, "message %q not found in the proto deps of the manifest: %v", name, err)
+		return nil
+	}
+	return mt
+}
+
+func (l *linter) lintTypes(m *smpb.Manifest) {
+	param := m.GetParameter()
+	if name := param.GetMessageFullName(); name != "" {
+		l.findMessage("parameter.message_full_name", name)
+	}
+	if param.GetDefaultValue() != nil {
+		got := param.GetDefaultValue().MessageName()
+		switch {
+		case param.GetMessageFullName() == "":
+			l.addf("parameter.default_value", "is set, but parameter.message_full_name is empty")
+		case string(got) != param.GetMessageFullName():
+			l.addf("parameter.default_value", "has type %q, expected %q", got, param.GetMessageFullName())
+		}
+	}
+	if m.ReturnType != nil {
+		if name := m.GetReturnType().GetMessageFullName(); name == "" {
+			l.addf("return_type.message_full_name", "must be set if return_type is set")
+		} else {
+			l.findMessage("return_type.message_full_name", name)
+		}
+	}
+}
+
+// lint returns all problems of the manifest.
+func lint(m *smpb.Manifest, types *protoregistry.Types) []string {
+	l := &linter{types: types}
+	l.lintID(m)
+	l.lintDisplayNames(m)
+	l.lintDocumentation(m)
+	l.lintOptions(m)
+	l.lintDependencies(m)
+	l.lintTypes(m)
+	return l.problems
+}
+
+func lintSkillManifest() error {
+	var fds []string
+	if *flagFileDescriptorSets != "" {
+		fds = strings.Split(*flagFileDescriptorSets, ",")
+	}
+	set, err := descriptorset.ReadTransitive(fds)
+	if err != nil {
+		return fmt.Errorf("unable to build FileDescriptorSet: %v", err)
+	}
+	types, err := registryutil.NewTypesFromFileDescriptorSet(set)
+	if err != nil {
+		return fmt.Errorf("failed to populate the registry: %v", err)
+	}
+
+	m := new(smpb.Manifest)
+	if err := protoio.ReadTextProto(*flagManifest, m, protoio.WithResolver(types)); err != nil {
+		return fmt.Errorf("failed to read manifest: %v", err)
+	}
+	if problems := lint(m, types); len(problems) > 0 {
+		msg := fmt.Sprintf("%s has %d problem(s):\n  %s", *flagManifest, len(problems), strings.Join(problems, "\n  "))
+		if *flagFailOnProblems {
+			return errors.New(msg)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+	}
+
+	if *flagOutput != "" {
+		if err := os.WriteFile(*flagOutput, nil, 0644); err != nil {
+			return fmt.Errorf("could not write %q: %v", *flagOutput, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	intrinsic.Init()
+	if err := lintSkillManifest(); err != nil {
+		log.Exitf("Skill manifest lint failed: %v", err)
+	}
+}
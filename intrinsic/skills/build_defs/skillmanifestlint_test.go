@@ -0,0 +1,169 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	documentationpb "intrinsic/assets/proto/documentation_go_proto"
+	idpb "intrinsic/assets/proto/id_go_proto"
+	vendorpb "intrinsic/assets/proto/vendor_go_proto"
+	equipmentpb "intrinsic/skills/proto/equipment_go_proto"
+	smpb "intrinsic/skills/proto/skill_manifest_go_proto"
+)
+
+func goodManifest() *smpb.Manifest {
+	return &smpb.Manifest{
+		Id:            &idpb.Id{Package: "ai.intrinsic", Name: "my_skill"},
+		DisplayName:   "My skill",
+		Vendor:        &vendorpb.Vendor{DisplayName: "Intrinsic"},
+		Documentation: &documentationpb.Documentation{Description: "Does things."},
+		Options: &smpb.Options{
+			SupportsCancellation:     true,
+			CancellationReadyTimeout: durationpb.New(5 * time.Second),
+			LanguageSpecificOptions: &smpb.Options_CcConfig{
+				CcConfig: &smpb.CcServiceConfig{CreateSkill: "::my::skill::Create"},
+			},
+		},
+		Dependencies: &smpb.Dependencies{
+			RequiredEquipment: map[string]*equipmentpb.ResourceSelector{
+				"robot": {CapabilityNames: []string{"Icon2Connection"}},
+			},
+		},
+		Parameter:  &smpb.ParameterMetadata{MessageFullName: "google.protobuf.Empty"},
+		ReturnType: &smpb.ReturnMetadata{MessageFullName: "google.protobuf.Empty"},
+	}
+}
+
+func TestLint(t *testing.T) {
+	types := new(protoregistry.Types)
+	if err := types.RegisterMessage((&emptypb.Empty{}).ProtoReflect().Type()); err != nil {
+		t.Fatalf("RegisterMessage() failed: %v", err)
+	}
+	mustAny := func(m proto.Message) *anypb.Any {
+		a, err := anypb.New(m)
+		if err != nil {
+			t.Fatalf("anypb.New() failed: %v", err)
+		}
+		return a
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *smpb.Manifest)
+		// want are the prefixes of the expected problems, in order.
+		want []string
+	}{
+		{
+			name:   "good",
+			modify: func(m *smpb.Manifest) {},
+		},
+		{
+			name:   "invalid package",
+			modify: func(m *smpb.Manifest) { m.Id.Package = "ai..intrinsic" },
+			want:   []string{"id.package: "},
+		},
+		{
+			name:   "invalid name",
+			modify: func(m *smpb.Manifest) { m.Id.Name = "My-Skill" },
+			want:   []string{"id.name: "},
+		},
+		{
+			name: "missing display names",
+			modify: func(m *smpb.Manifest) {
+				m.DisplayName = ""
+				m.Vendor = nil
+			},
+			want: []string{"display_name: must be set", "vendor.display_name: must be set"},
+		},
+		{
+			name:   "missing description",
+			modify: func(m *smpb.Manifest) { m.Documentation.Description = " \n" },
+			want:   []string{"documentation.description: must be set, it is shown to users of the skill"},
+		},
+		{
+			name:   "cancellation timeout without cancellation",
+			modify: func(m *smpb.Manifest) { m.Options.SupportsCancellation = false },
+			want:   []string{"options.cancellation_ready_timeout: is set, but options.supports_cancellation is false"},
+		},
+		{
+			name:   "negative cancellation timeout",
+			modify: func(m *smpb.Manifest) { m.Options.CancellationReadyTimeout = durationpb.New(-time.Second) },
+			want:   []string{"options.cancellation_ready_timeout: must be positive, got -1s"},
+		},
+		{
+			name: "invalid cc create_skill",
+			modify: func(m *smpb.Manifest) {
+				m.Options.LanguageSpecificOptions = &smpb.Options_CcConfig{CcConfig: &smpb.CcServiceConfig{CreateSkill: "my::1skill"}}
+			},
+			want: []string{`options.cc_config.create_skill: "my::1skill" is not a valid C++ qualified name`},
+		},
+		{
+			name: "python create_skill outside of skill_module",
+			modify: func(m *smpb.Manifest) {
+				m.Options.LanguageSpecificOptions = &smpb.Options_PythonConfig{PythonConfig: &smpb.PythonServiceConfig{
+					SkillModule: "my.skill",
+					ProtoModule: "my.skill_pb2",
+					CreateSkill: "other.Skill",
+				}}
+			},
+			want: []string{`options.python_config.create_skill: "other.Skill" is not declared in skill_module "my.skill"`},
+		},
+		{
+			name: "empty capability names",
+			modify: func(m *smpb.Manifest) {
+				m.Dependencies.RequiredEquipment["robot"].CapabilityNames = nil
+			},
+			want: []string{`dependencies.required_equipment["robot"].capability_names: must not be empty, the selector would match any resource`},
+		},
+		{
+			name: "invalid slot and duplicate capability",
+			modify: func(m *smpb.Manifest) {
+				m.Dependencies.RequiredEquipment["my robot"] = &equipmentpb.ResourceSelector{CapabilityNames: []string{"A", "A"}}
+			},
+			want: []string{
+				`dependencies.required_equipment["my robot"]: slot names must not be empty or contain whitespace`,
+				`dependencies.required_equipment["my robot"].capability_names[1]: duplicate capability "A"`,
+			},
+		},
+		{
+			name:   "unknown parameter message",
+			modify: func(m *smpb.Manifest) { m.Parameter.MessageFullName = "my.Unknown" },
+			want:   []string{`parameter.message_full_name: message "my.Unknown" not found in the proto deps of the manifest`},
+		},
+		{
+			name:   "default value of the wrong type",
+			modify: func(m *smpb.Manifest) { m.Parameter.DefaultValue = mustAny(durationpb.New(0)) },
+			want:   []string{`parameter.default_value: has type "google.protobuf.Duration", expected "google.protobuf.Empty"`},
+		},
+		{
+			name:   "return type without message",
+			modify: func(m *smpb.Manifest) { m.ReturnType.MessageFullName = "" },
+			want:   []string{"return_type.message_full_name: must be set if return_type is set"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := goodManifest()
+			tc.modify(m)
+
+			got := lint(m, types)
+			if len(got) != len(tc.want) {
+				t.Fatalf("lint() returned %d problems %q, want %d", len(got), got, len(tc.want))
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tc.want[i]) {
+					t.Errorf("lint() returned problem %q, want prefix %q", got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
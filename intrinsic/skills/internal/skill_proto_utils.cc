@@ -56,10 +56,28 @@ struct MessageData {
   const google::protobuf::FileDescriptorSet& file_descriptor_set;
 };
 
+// Initializes a SourceCodeInfoView for `file_descriptor_set`. Returns nullptr
+// if the set is missing source_code_info, in which case no comment map can be
+// computed.
+absl::StatusOr<std::shared_ptr<SourceCodeInfoView>> MakeSourceCodeInfoView(
+    const google::protobuf::FileDescriptorSet& file_descriptor_set) {
+  auto source_code_info = std::make_shared<SourceCodeInfoView>();
+  if (absl::Status status = source_code_info->InitStrict(file_descriptor_set);
+      !status.ok()) {
+    if (status.code() == absl::StatusCode::kNotFound) {
+      return nullptr;
+    }
+    return status;
+  }
+  return source_code_info;
+}
+
 // Add file descriptor set for a skill's parameter and clear source code info.
+// `source_code_info` may be nullptr if the set has no source code info.
 absl::Status AddParameterDescription(
     const MessageData& parameter_data,
     std::unique_ptr<google::protobuf::Message> default_value,
+    SourceCodeInfoView* source_code_info,
     intrinsic_proto::skills::Skill& skill_proto) {
   intrinsic_proto::skills::ParameterDescription& parameter_description =
       *skill_proto.mutable_parameter_description();
@@ -73,23 +91,15 @@ absl::Status AddParameterDescription(
 
   *parameter_description.mutable_parameter_descriptor_fileset() =
       parameter_data.file_descriptor_set;
-  SourceCodeInfoView source_code_info;
-  if (absl::Status status =
-          source_code_info.InitStrict(parameter_data.file_descriptor_set);
-      !status.ok()) {
-    if (status.code() == absl::StatusCode::kInvalidArgument) {
-      return status;
-    }
-    if (status.code() == absl::StatusCode::kNotFound) {
-      LOG(INFO) << "parameter FileDescriptorSet missing source_code_info, "
-                   "comment map will be empty.";
-      return absl::OkStatus();
-    }
+  if (source_code_info == nullptr) {
+    LOG(INFO) << "parameter FileDescriptorSet missing source_code_info, "
+                 "comment map will be empty.";
+    return absl::OkStatus();
   }
 
   INTR_ASSIGN_OR_RETURN(
       *parameter_description.mutable_parameter_field_comments(),
-      source_code_info.GetNestedFieldCommentMap(
+      source_code_info->GetNestedFieldCommentMap(
           parameter_data.message_full_name));
   StripSourceCodeInfo(
       *parameter_description.mutable_parameter_descriptor_fileset());
@@ -98,8 +108,9 @@ absl::Status AddParameterDescription(
 }
 
 // Add file descriptor set for a skill's return and clear source code info.
+// `source_code_info` may be nullptr if the set has no source code info.
 absl::Status AddReturnValueDescription(
-    const MessageData& return_value_data,
+    const MessageData& return_value_data, SourceCodeInfoView* source_code_info,
     intrinsic_proto::skills::Skill& skill_proto) {
   intrinsic_proto::skills::ReturnValueDescription& return_value_description =
       *skill_proto.mutable_return_value_description();
@@ -109,23 +120,15 @@ absl::Status AddReturnValueDescription(
 
   *return_value_description.mutable_descriptor_fileset() =
       return_value_data.file_descriptor_set;
-  SourceCodeInfoView source_code_info;
-  if (absl::Status status =
-          source_code_info.InitStrict(return_value_data.file_descriptor_set);
-      !status.ok()) {
-    if (status.code() == absl::StatusCode::kInvalidArgument) {
-      return status;
-    }
-    if (status.code() == absl::StatusCode::kNotFound) {
-      LOG(INFO) << "return type FileDescriptorSet missing source_code_info, "
-                   "comment map will be empty.";
-      return absl::OkStatus();
-    }
+  if (source_code_info == nullptr) {
+    LOG(INFO) << "return type FileDescriptorSet missing source_code_info, "
+                 "comment map will be empty.";
+    return absl::OkStatus();
   }
 
   INTR_ASSIGN_OR_RETURN(
       *return_value_description.mutable_return_value_field_comments(),
-      source_code_info.GetNestedFieldCommentMap(
+      source_code_info->GetNestedFieldCommentMap(
           return_value_data.message_full_name));
   StripSourceCodeInfo(*return_value_description.mutable_descriptor_fileset());
 
@@ -137,14 +140,32 @@ absl::Status AddFileDescriptorSetWithoutSourceCodeInfo(
     std::unique_ptr<MessageData> return_value_data,
     std::unique_ptr<google::protobuf::Message> default_parameter_value,
     intrinsic_proto::skills::Skill& skill_proto) {
+  // The parameter and return messages usually share one file descriptor set.
+  // Build the descriptor pool for it only once, so that the comment maps of
+  // shared submessages are computed only once as well.
+  std::shared_ptr<SourceCodeInfoView> parameter_source_code_info;
   if (parameter_data != nullptr) {
+    INTR_ASSIGN_OR_RETURN(
+        parameter_source_code_info,
+        MakeSourceCodeInfoView(parameter_data->file_descriptor_set));
     INTR_RETURN_IF_ERROR(AddParameterDescription(
-        *parameter_data, std::move(default_parameter_value), skill_proto));
+        *parameter_data, std::move(default_parameter_value),
+        parameter_source_code_info.get(), skill_proto));
   }
 
   if (return_value_data != nullptr) {
-    INTR_RETURN_IF_ERROR(
-        AddReturnValueDescription(*return_value_data, skill_proto));
+    std::shared_ptr<SourceCodeInfoView> return_value_source_code_info;
+    if (parameter_data != nullptr &&
+        &parameter_data->file_descriptor_set ==
+            &return_value_data->file_descriptor_set) {
+      return_value_source_code_info = parameter_source_code_info;
+    } else {
+      INTR_ASSIGN_OR_RETURN(
+          return_value_source_code_info,
+          MakeSourceCodeInfoView(return_value_data->file_descriptor_set));
+    }
+    INTR_RETURN_IF_ERROR(AddReturnValueDescription(
+        *return_value_data, return_value_source_code_info.get(), skill_proto));
   }
 
   return absl::OkStatus();
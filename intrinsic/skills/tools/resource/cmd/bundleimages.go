@@ -11,6 +11,7 @@ import (
 
 	"intrinsic/assets/bundleio"
 	"intrinsic/assets/idutils"
+	"intrinsic/assets/imagetransfer"
 	"intrinsic/assets/imageutils"
 	idpb "intrinsic/assets/proto/id_go_proto"
 	ipb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
@@ -54,3 +55,16 @@ func CreateImageProcessor(reg imageutils.RegistryOptions) bundleio.ImageProcesso
 		return imageutils.PushArchive(func() (io.ReadCloser, error) { return opener() }, opts, reg)
 	}
 }
+
+// CreateDigestImageProcessor returns a closure like CreateImageProcessor which
+// does not push the images.  It only reads each image to compute its digest and
+// returns the spec that CreateImageProcessor would return for the registry.
+// The spec references the image by digest, so it is reproducible.  Use it to
+// check a bundle, e.g., for reproducibility or deduplication in a catalog,
+// before uploading it.
+func CreateDigestImageProcessor(registry string) bundleio.ImageProcessor {
+	return CreateImageProcessor(imageutils.RegistryOptions{
+		URI:        registry,
+		Transferer: imagetransfer.DiscardTransferer{},
+	})
+}
@@ -15,9 +15,11 @@ import (
 	"strings"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"intrinsic/assets/clientutils"
 	"intrinsic/tools/inctl/auth"
 )
@@ -63,6 +65,9 @@ type DialInfoParams struct {
 	CredAlias string // Optional alias for key to load
 	CredOrg   string // Optional the org-id header to set
 	CredToken string // Optional the credential value itself. This bypasses the store
+	// Optional client certificate presented to clusters requiring mTLS. Defaults to the
+	// certificate configured for CredName in auth.Store.
+	ClientCert *auth.ClientCertificate
 }
 
 // ErrCredentialsRequired indicates that the credential name is not set in the
@@ -82,6 +87,16 @@ func (e *ErrCredentialsNotFound) Error() string {
 
 func (e *ErrCredentialsNotFound) Unwrap() error { return e.Err }
 
+// IsCredentialsRejected reports whether err, or an error it wraps, is a gRPC Unauthenticated
+// error, e.g., because the stored API key expired or was revoked.
+func IsCredentialsRejected(err error) bool {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return false
+	}
+	return grpcErr.GRPCStatus().Code() == codes.Unauthenticated
+}
+
 // DialConnectionCtx creates and returns a gRPC connection that is created based on the DialInfoParams.
 // DialConnectionCtx will fill the ServerAddr or Credname if necessary.
 // The CredName is filled from the organization information. It's equal to the project's name.
@@ -112,7 +127,8 @@ func DialConnectionCtx(ctx context.Context, params DialInfoParams) (context.Cont
 //
 // Returns insecure connection data if the address is a local network address (such as
 // `localhost:17080`), otherwise retrieves cert from system cert pool, and sets up the metadata for
-// a TLS cert with per-RPC basic auth credentials.
+// a TLS cert with per-RPC basic auth credentials. If a client certificate is configured, it is
+// presented to the server for mutual TLS.
 func dialInfoCtx(ctx context.Context, params DialInfoParams) (context.Context, *[]grpc.DialOption, string, error) {
 	address, err := resolveAddress(params.Address, params.CredName)
 	if err != nil {
@@ -135,19 +151,19 @@ func dialInfoCtx(ctx context.Context, params DialInfoParams) (context.Context, *
 		ctx = metadata.AppendToOutgoingContext(ctx, "x-server-name", params.Cluster)
 	}
 
-	rpcCredentials, err := createCredentials(params)
+	rpcCredentials, clientCert, err := createCredentials(params)
 	if err != nil {
 		return nil, nil, "", fmt.Errorf("cannot retrieve connection credentials: %w", err)
 	}
-	tcOption, err := clientutils.GetTransportCredentialsDialOption()
+	tcOption, err := clientutils.GetClientCertTransportCredentialsDialOption(clientCert)
 	if err != nil {
 		return nil, nil, "", fmt.Errorf("cannot retrieve transport credentials: %w", err)
 	}
 
-	finalOpts := append(clientutils.BaseDialOptions,
-		grpc.WithPerRPCCredentials(rpcCredentials),
-		tcOption,
-	)
+	finalOpts := append(clientutils.BaseDialOptions, tcOption)
+	if rpcCredentials != nil {
+		finalOpts = append(finalOpts, grpc.WithPerRPCCredentials(rpcCredentials))
+	}
 
 	return ctx, &finalOpts, params.Address, nil
 }
@@ -169,30 +185,43 @@ func UseInsecureCredentials(address string) bool {
 	return port != 443
 }
 
-func createCredentials(params DialInfoParams) (credentials.PerRPCCredentials, error) {
+// createCredentials returns the per-RPC credentials and the client certificate for the connection.
+// The per-RPC credentials are nil if the client certificate authenticates the caller on its own.
+func createCredentials(params DialInfoParams) (credentials.PerRPCCredentials, *auth.ClientCertificate, error) {
 	if params.CredToken != "" {
-		return &auth.ProjectToken{APIKey: params.CredToken}, nil
+		return &auth.ProjectToken{APIKey: params.CredToken}, params.ClientCert, nil
 	}
 
 	if params.CredName != "" {
 		configuration, err := auth.NewStore().GetConfiguration(params.CredName)
 		if err != nil {
-			return nil, &ErrCredentialsNotFound{Err: err, CredentialName: params.CredName}
+			if params.ClientCert == nil {
+				return nil, nil, &ErrCredentialsNotFound{Err: err, CredentialName: params.CredName}
+			}
+			configuration = auth.NewConfiguration(params.CredName)
+		}
+		if params.ClientCert != nil {
+			configuration.ClientCertificate = params.ClientCert
 		}
 
-		if params.CredAlias == "" {
-			return configuration.GetDefaultCredentials()
+		token, clientCert, err := configuration.Credentials(params.CredAlias)
+		if err != nil {
+			return nil, nil, err
+		}
+		if token == nil {
+			// Do not wrap the nil token into a non-nil interface.
+			return nil, clientCert, nil
 		}
-		return configuration.GetCredentials(params.CredAlias)
+		return token, clientCert, nil
 	}
 
-	if clientutils.IsLocalAddress(params.Address) {
-		// local calls do not require any authentication
-		return nil, nil
+	if params.ClientCert != nil || clientutils.IsLocalAddress(params.Address) {
+		// local calls and calls with a client certificate do not require an API key
+		return nil, params.ClientCert, nil
 	}
 	// credential name is required for non-local calls to resolve
 	// the corresponding API key.
-	return nil, ErrCredentialsRequired
+	return nil, nil, ErrCredentialsRequired
 }
 
 func resolveAddress(address string, project string) (string, error) {
@@ -32,6 +32,14 @@ func WithMaxRetries(maxRetries int) Option {
 	}
 }
 
+// WithUploadParallelism sets the maximum number of image parts which are uploaded at the same
+// time. Defaults to 1.
+func WithUploadParallelism(parallelism int) Option {
+	return func(transfer *directTransfer) {
+		transfer.uploadParallelism = parallelism
+	}
+}
+
 // WithClient allows caller to set client side implementation. If this option
 // is specified, the client will be used to create an uploader instance,
 // ignoring discovery strategy set by WithDiscovery
@@ -69,8 +77,9 @@ func WithFailOver(failOver imagetransfer.Transferer) Option {
 // and applies options if specified.
 func NewTransferer(ctx context.Context, opts ...Option) imagetransfer.Transferer {
 	transfer := &directTransfer{
-		maxRetries: 5,
-		ctx:        ctx,
+		maxRetries:        5,
+		uploadParallelism: 1,
+		ctx:               ctx,
 	}
 
 	for _, opt := range opts {
@@ -88,12 +97,13 @@ func NewTransferer(ctx context.Context, opts ...Option) imagetransfer.Transferer
 }
 
 type directTransfer struct {
-	maxRetries int
-	failOver   imagetransfer.Transferer
-	uploader   client.Uploader
-	client     artifactgrpcpb.ArtifactServiceApiClient
-	ctx        context.Context
-	discovery  TargetDiscovery
+	maxRetries        int
+	uploadParallelism int
+	failOver          imagetransfer.Transferer
+	uploader          client.Uploader
+	client            artifactgrpcpb.ArtifactServiceApiClient
+	ctx               context.Context
+	discovery         TargetDiscovery
 }
 
 func (dt *directTransfer) Write(ref name.Reference, img crv1.Image) error {
@@ -105,9 +115,10 @@ func (dt *directTransfer) Write(ref name.Reference, img crv1.Image) error {
 		}
 		dt.uploader, err = client.NewUploader(apiClient, client.WithSequentialUpload(),
 			// To mitigate b/330747118; this is not full fix, but should help.
-			// This setting is forcing to run only 1 upload task at a time.
-			// We are taking significant performance penalty.
-			client.WithUploadParallelism(1))
+			// By default only 1 upload task runs at a time, taking a significant
+			// performance penalty. Targets which cope with parallel uploads, like
+			// the catalog, can raise it with WithUploadParallelism.
+			client.WithUploadParallelism(dt.uploadParallelism))
 		if err != nil {
 			return fmt.Errorf("cannot create uploader: %w", err)
 		}
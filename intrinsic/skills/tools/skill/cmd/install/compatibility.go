@@ -0,0 +1,421 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package install
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"intrinsic/assets/imagetransfer"
+	"intrinsic/assets/imageutils"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	rmdpb "intrinsic/executive/proto/run_metadata_go_proto"
+	skillregistrygrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	sscpb "intrinsic/skills/proto/skill_service_config_go_proto"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+)
+
+const (
+	keyCheckCompatibility = "check_compatibility"
+
+	// skillServiceConfigPath is the location of the skill service config in skill
+	// images, see build_symlinks in skill.bzl.
+	skillServiceConfigPath = "skills/skill_service_config.proto.bin"
+	// maxSymlinkDepth limits how many symlinks are followed in the image.
+	maxSymlinkDepth = 8
+)
+
+var errNoSkillServiceConfig = errors.New("skill image does not contain a skill service config")
+
+// incompatibility describes a use of a skill in a behavior tree which is not
+// compatible with the parameters of a new version of the skill.
+type incompatibility struct {
+	treeName string
+	nodeID   uint32
+	nodeName string
+	field    string
+	reason   string
+}
+
+func (i incompatibility) String() string {
+	node := fmt.Sprintf("node %d", i.nodeID)
+	if i.nodeName != "" {
+		node = fmt.Sprintf("%s (%q)", node, i.nodeName)
+	}
+	return fmt.Sprintf("tree %q, %s, field %q: %s", i.treeName, node, i.field, i.reason)
+}
+
+// readImageFile returns the content of the file at the given path in the image.
+// Symbolic links are followed.
+func readImageFile(img containerregistry.Image, name string) ([]byte, error) {
+	for i := 0; i < maxSymlinkDepth; i++ {
+		content, link, err := readImageEntry(img, name)
+		if err != nil {
+			return nil, err
+		}
+		if link == "" {
+			return content, nil
+		}
+		if path.IsAbs(link) {
+			name = strings.TrimPrefix(link, "/")
+		} else {
+			name = path.Join(path.Dir(name), link)
+		}
+	}
+	return nil, fmt.Errorf("too many levels of symbolic links for %q", name)
+}
+
+// readImageEntry returns either the content of the regular file or the target
+// of the symbolic link at the given path in the flattened image.
+func readImageEntry(img containerregistry.Image, name string) ([]byte, string, error) {
+	rc := mutate.Extract(img)
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, "", fmt.Errorf("%w: %q not found", errNoSkillServiceConfig, name)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("could not read image contents: %w", err)
+		}
+		if path.Clean(strings.TrimPrefix(hdr.Name, "./")) != name {
+			continue
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			return nil, hdr.Linkname, nil
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, "", fmt.Errorf("could not read %q from image: %w", name, err)
+		}
+		return content, "", nil
+	}
+}
+
+// parameterDescriptionFromImage reads the parameter description of the skill
+// in the given image.
+func parameterDescriptionFromImage(img containerregistry.Image) (*skillspb.ParameterDescription, error) {
+	content, err := readImageFile(img, skillServiceConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	config := &sscpb.SkillServiceConfig{}
+	if err := proto.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("could not parse skill service config: %w", err)
+	}
+	return config.GetSkillDescription().GetParameterDescription(), nil
+}
+
+// installedParameterDescription returns the parameter description of the
+// currently installed version of the skill, or nil if it is not installed.
+func installedParameterDescription(ctx context.Context, conn *grpc.ClientConn, skillID string) (*skillspb.ParameterDescription, error) {
+	client := skillregistrygrpcpb.NewSkillRegistryClient(conn)
+	resp, err := client.GetSkill(ctx, &skillregistrygrpcpb.GetSkillRequest{Id: skillID})
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get installed skill %q: %w", skillID, err)
+	}
+	return resp.GetSkill().GetParameterDescription(), nil
+}
+
+// parameterMessage returns the descriptor of the parameter message of a skill.
+func parameterMessage(pd *skillspb.ParameterDescription) (protoreflect.MessageDescriptor, error) {
+	files, err := protodesc.NewFiles(pd.GetParameterDescriptorFileset())
+	if err != nil {
+		return nil, fmt.Errorf("could not create file descriptors: %w", err)
+	}
+	name := protoreflect.FullName(pd.GetParameterMessageFullName())
+	d, err := files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not find parameter message %q: %w", name, err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a message", name)
+	}
+	return md, nil
+}
+
+// skillCall is a task node of a behavior tree which calls a skill.
+type skillCall struct {
+	treeName string
+	node     *btpb.BehaviorTree_Node
+}
+
+// loadedBehaviorTrees returns the behavior trees of all executive operations.
+func loadedBehaviorTrees(ctx context.Context, conn *grpc.ClientConn) ([]*btpb.BehaviorTree, error) {
+	client := execgrpcpb.NewExecutiveServiceClient(conn)
+	resp, err := client.ListOperations(ctx, &lrpb.ListOperationsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list executive operations: %w", err)
+	}
+	var trees []*btpb.BehaviorTree
+	for _, op := range resp.GetOperations() {
+		metadata := &rmdpb.RunMetadata{}
+		if err := op.GetMetadata().UnmarshalTo(metadata); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal RunMetadata of operation %q: %w", op.GetName(), err)
+		}
+		if bt := metadata.GetBehaviorTree(); bt != nil {
+			trees = append(trees, bt)
+		}
+	}
+	return trees, nil
+}
+
+// findSkillCalls returns all task nodes in m (including nested sub trees) which
+// call the given skill.
+func findSkillCalls(m protoreflect.Message, treeName string, skillID string) []skillCall {
+	var calls []skillCall
+	if bt, ok := m.Interface().(*btpb.BehaviorTree); ok {
+		treeName = bt.GetName()
+	}
+	if node, ok := m.Interface().(*btpb.BehaviorTree_Node); ok {
+		if node.GetTask().GetCallBehavior().GetSkillId() == skillID {
+			calls = append(calls, skillCall{treeName: treeName, node: node})
+		}
+	}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind || fd.IsMap() {
+			return true
+		}
+		if fd.IsList() {
+			for i := 0; i < v.List().Len(); i++ {
+				calls = append(calls, findSkillCalls(v.List().Get(i).Message(), treeName, skillID)...)
+			}
+			return true
+		}
+		calls = append(calls, findSkillCalls(v.Message(), treeName, skillID)...)
+		return true
+	})
+	return calls
+}
+
+// fieldProblem describes why a field can not be used with a new message
+// definition.
+type fieldProblem struct {
+	field  string
+	reason string
+}
+
+// compareFields reports all fields set in m which can not be represented the
+// same way by the message newMD.
+func compareFields(m protoreflect.Message, newMD protoreflect.MessageDescriptor, prefix string) []fieldProblem {
+	var problems []fieldProblem
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		name := prefix + string(fd.Name())
+		report := func(format string, a ...any) {
+			problems = append(problems, fieldProblem{field: name, reason: fmt.Sprintf(format, a...)})
+		}
+		nfd := newMD.Fields().ByNumber(fd.Number())
+		switch {
+		case nfd == nil:
+			report("field was removed")
+			return true
+		case nfd.Kind() != fd.Kind():
+			report("type changed from %s to %s", fd.Kind(), nfd.Kind())
+			return true
+		case nfd.IsList() != fd.IsList() || nfd.IsMap() != fd.IsMap():
+			report("cardinality changed from %s to %s", fd.Cardinality(), nfd.Cardinality())
+			return true
+		case nfd.Name() != fd.Name():
+			report("field was renamed to %q, text serialized trees will break", nfd.Name())
+		}
+
+		switch {
+		case fd.Kind() == protoreflect.EnumKind:
+			var values []protoreflect.EnumNumber
+			if fd.IsList() {
+				for i := 0; i < v.List().Len(); i++ {
+					values = append(values, v.List().Get(i).Enum())
+				}
+			} else if !fd.IsMap() {
+				values = append(values, v.Enum())
+			}
+			for _, e := range values {
+				if nfd.Enum().Values().ByNumber(e) == nil {
+					report("enum value %d was removed", e)
+				}
+			}
+		case fd.Message() == nil:
+		case fd.IsMap():
+			if nfd.MapValue().Kind() != fd.MapValue().Kind() {
+				report("map value type changed from %s to %s", fd.MapValue().Kind(), nfd.MapValue().Kind())
+				break
+			}
+			if fd.MapValue().Message() == nil {
+				break
+			}
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				problems = append(problems, compareFields(mv.Message(), nfd.MapValue().Message(), fmt.Sprintf("%s[%v].", name, k.Interface()))...)
+				return true
+			})
+		case fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				problems = append(problems, compareFields(v.List().Get(i).Message(), nfd.Message(), fmt.Sprintf("%s[%d].", name, i))...)
+			}
+		default:
+			problems = append(problems, compareFields(v.Message(), nfd.Message(), name+".")...)
+		}
+		return true
+	})
+	return problems
+}
+
+// checkParameterPath reports an error if the given parameter path of an
+// assignment does not exist in md.
+func checkParameterPath(md protoreflect.MessageDescriptor, parameterPath string) string {
+	for _, part := range strings.Split(parameterPath, ".") {
+		if i := strings.Index(part, "["); i >= 0 {
+			part = part[:i]
+		}
+		if md == nil {
+			return "parameter path refers to a field of a non-message field"
+		}
+		fd := md.Fields().ByName(protoreflect.Name(part))
+		if fd == nil {
+			return fmt.Sprintf("assigned field %q does not exist anymore", part)
+		}
+		md = fd.Message()
+	}
+	return ""
+}
+
+// checkSkillCall checks whether the parameters of the given call can be used
+// with the new parameter message. oldMD may be nil if the skill is not
+// installed, in which case the parameters are only checked for unknown fields.
+func checkSkillCall(call skillCall, oldMD, newMD protoreflect.MessageDescriptor) ([]incompatibility, error) {
+	behaviorCall := call.node.GetTask().GetCallBehavior()
+	report := func(field, reason string) incompatibility {
+		return incompatibility{
+			treeName: call.treeName,
+			nodeID:   call.node.GetId(),
+			nodeName: call.node.GetName(),
+			field:    field,
+			reason:   reason,
+		}
+	}
+
+	var result []incompatibility
+	if params := behaviorCall.GetParameters(); params != nil {
+		if got, want := string(params.MessageName()), string(newMD.FullName()); got != want {
+			result = append(result, report("", fmt.Sprintf("parameter type changed from %s to %s", got, want)))
+		} else if oldMD != nil {
+			m := dynamicpb.NewMessage(oldMD)
+			if err := proto.Unmarshal(params.GetValue(), m); err != nil {
+				return nil, fmt.Errorf("could not parse parameters of node %d: %w", call.node.GetId(), err)
+			}
+			for _, p := range compareFields(m, newMD, "") {
+				result = append(result, report(p.field, p.reason))
+			}
+		} else {
+			m := dynamicpb.NewMessage(newMD)
+			if err := proto.Unmarshal(params.GetValue(), m); err != nil {
+				result = append(result, report("", fmt.Sprintf("parameters can not be parsed: %v", err)))
+			} else if len(m.GetUnknown()) > 0 {
+				result = append(result, report("", "parameters contain fields unknown to the new version"))
+			}
+		}
+	}
+	for _, a := range behaviorCall.GetAssignments() {
+		if reason := checkParameterPath(newMD, a.GetParameterPath()); reason != "" {
+			result = append(result, report(a.GetParameterPath(), reason))
+		}
+	}
+	return result, nil
+}
+
+// checkCompatibility verifies that all uses of the skill in the behavior trees
+// loaded into the executive are compatible with the parameters of the skill in
+// the given image.
+func checkCompatibility(ctx context.Context, conn *grpc.ClientConn, skillID string, img containerregistry.Image) ([]incompatibility, error) {
+	newPD, err := parameterDescriptionFromImage(img)
+	if err != nil {
+		return nil, err
+	}
+	if newPD.GetParameterMessageFullName() == "" {
+		// The new version does not take parameters, so there is nothing to check.
+		return nil, nil
+	}
+	newMD, err := parameterMessage(newPD)
+	if err != nil {
+		return nil, fmt.Errorf("new skill version: %w", err)
+	}
+
+	oldPD, err := installedParameterDescription(ctx, conn, skillID)
+	if err != nil {
+		return nil, err
+	}
+	var oldMD protoreflect.MessageDescriptor
+	if oldPD.GetParameterMessageFullName() != "" {
+		if oldMD, err = parameterMessage(oldPD); err != nil {
+			return nil, fmt.Errorf("installed skill version: %w", err)
+		}
+	}
+
+	trees, err := loadedBehaviorTrees(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var result []incompatibility
+	for _, bt := range trees {
+		for _, call := range findSkillCalls(bt.ProtoReflect(), bt.GetName(), skillID) {
+			problems, err := checkSkillCall(call, oldMD, newMD)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, problems...)
+		}
+	}
+	return result, nil
+}
+
+// verifyCompatibility checks the skill in the given target against the
+// behavior trees loaded into the executive and returns an error listing all
+// incompatibilities, if there are any.
+func verifyCompatibility(ctx context.Context, conn *grpc.ClientConn, target string, targetType imageutils.TargetType, t imagetransfer.Transferer, w io.Writer) error {
+	img, err := imageutils.GetImage(target, targetType, t)
+	if err != nil {
+		return fmt.Errorf("could not read image: %w", err)
+	}
+	installerParams, err := imageutils.GetSkillInstallerParams(img)
+	if err != nil {
+		return fmt.Errorf("could not extract labels from image object: %w", err)
+	}
+
+	problems, err := checkCompatibility(ctx, conn, installerParams.SkillID, img)
+	if errors.Is(err, errNoSkillServiceConfig) {
+		fmt.Fprintf(w, "Skipping compatibility check: %v\n", err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not check compatibility of skill %q: %w", installerParams.SkillID, err)
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(w, "Parameters of skill %q are compatible with all loaded behavior trees.\n", installerParams.SkillID)
+		return nil
+	}
+	fmt.Fprintf(w, "Found %d incompatible use(s) of skill %q in loaded behavior trees:\n", len(problems), installerParams.SkillID)
+	for _, p := range problems {
+		fmt.Fprintf(w, "\t%s\n", p)
+	}
+	return fmt.Errorf("new version of skill %q is not compatible with the loaded behavior trees, install without --%s to ignore", installerParams.SkillID, keyCheckCompatibility)
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package install
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/assets/imagetransfer"
+	"intrinsic/assets/imageutils"
+	sscpb "intrinsic/skills/proto/skill_service_config_go_proto"
+	"intrinsic/skills/tools/skill/cmd"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+var inspectFlags = cmdutils.NewCmdFlags()
+
+// imageInfo describes a skill image and how it was built.
+type imageInfo struct {
+	ID string `json:"id"`
+	// IDVersion is the id_version from the skill manifest in the image, if set.
+	IDVersion string `json:"idVersion,omitempty"`
+	imageutils.BuildInfo
+}
+
+func (i *imageInfo) String() string {
+	orUnknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ID:          %s\n", i.ID)
+	if i.IDVersion != "" {
+		fmt.Fprintf(&sb, "ID version:  %s\n", i.IDVersion)
+	}
+	fmt.Fprintf(&sb, "Git commit:  %s\n", orUnknown(i.GitCommit))
+	fmt.Fprintf(&sb, "Build time:  %s\n", orUnknown(i.BuildTime))
+	fmt.Fprintf(&sb, "SDK version: %s", orUnknown(i.SDKVersion))
+	if i.BuildInfo == (imageutils.BuildInfo{}) {
+		sb.WriteString("\nThe image has no build info, build it with --config=stamp to add it.")
+	}
+	return sb.String()
+}
+
+// inspectImage returns the ID and build info of the skill in img.
+func inspectImage(img containerregistry.Image) (*imageInfo, error) {
+	installerParams, err := imageutils.GetSkillInstallerParams(img)
+	if err != nil {
+		return nil, fmt.Errorf("could not extract labels from image object: %w", err)
+	}
+	buildInfo, err := imageutils.GetBuildInfo(img)
+	if err != nil {
+		return nil, err
+	}
+	info := &imageInfo{ID: installerParams.SkillID, BuildInfo: *buildInfo}
+
+	content, err := readImageFile(img, skillServiceConfigPath)
+	if errors.Is(err, errNoSkillServiceConfig) {
+		return info, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config := &sscpb.SkillServiceConfig{}
+	if err := proto.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("could not parse skill service config: %w", err)
+	}
+	info.IDVersion = config.GetSkillDescription().GetIdVersion()
+	return info, nil
+}
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect --type=TYPE TARGET",
+	Short: "Show the ID and build info of a skill image",
+	Long: `Show the ID and build info of a skill image: the git commit and SDK version it was
+built from and the time of the build. Build info is only available for images built
+with --config=stamp.`,
+	Example: `Inspect a skill using its build target
+$ inctl skill inspect --type=build //abc:skill.tar
+
+Inspect an already-built image file
+$ inctl skill inspect --type=archive abc/skill.tar
+
+Inspect an already-pushed image
+$ inctl skill inspect --type=image gcr.io/my-workcell/abc@sha256:20ab4f
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(command *cobra.Command, args []string) error {
+		target := args[0]
+		targetType := imageutils.TargetType(inspectFlags.GetFlagSideloadStartType())
+		if targetType != imageutils.Build && targetType != imageutils.Archive && targetType != imageutils.Image {
+			return fmt.Errorf("type must be one of (%s, %s, %s)", imageutils.Build, imageutils.Archive, imageutils.Image)
+		}
+
+		remoteOpt, err := clientutils.RemoteOpt(inspectFlags)
+		if err != nil {
+			return err
+		}
+		transfer := imagetransfer.RemoteTransferer(remote.WithContext(command.Context()), remoteOpt)
+		img, err := imageutils.GetImage(target, targetType, transfer)
+		if err != nil {
+			return fmt.Errorf("could not read image: %w", err)
+		}
+		info, err := inspectImage(img)
+		if err != nil {
+			return err
+		}
+
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+		prtr.Print(info)
+		return nil
+	},
+}
+
+func init() {
+	cmd.SkillCmd.AddCommand(inspectCmd)
+	inspectFlags.SetCommand(inspectCmd)
+	inspectFlags.AddFlagSideloadStartType()
+	inspectFlags.AddFlagsRegistryAuthUserPassword()
+}
@@ -4,28 +4,312 @@
 package install
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/pborman/uuid"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"intrinsic/assets/clientutils"
 	"intrinsic/assets/cmdutils"
 	"intrinsic/assets/idutils"
 	"intrinsic/assets/imagetransfer"
 	"intrinsic/assets/imageutils"
+	"intrinsic/assets/progress"
+	"intrinsic/assets/receipt"
 	imagepb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
 	installerpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
+	skillregistrygrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
 	"intrinsic/skills/tools/skill/cmd"
 	"intrinsic/skills/tools/skill/cmd/directupload"
 	"intrinsic/skills/tools/skill/cmd/registry"
 	"intrinsic/skills/tools/skill/cmd/waitforskill"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+const (
+	keyBuildOutput    = "build_output"
+	keyInstallTimeout = "install_timeout"
+	keyParallelism    = "parallelism"
+	keyPushTimeout    = "push_timeout"
+	keyReceipt        = "receipt"
+	keyReceiptDir     = "receipt_dir"
 )
 
 var cmdFlags = cmdutils.NewCmdFlags()
 
+// installedIDVersion returns the id_version of the currently installed version of the skill, or an
+// empty string if it is not installed or cannot be determined.
+func installedIDVersion(ctx context.Context, conn *grpc.ClientConn, skillID string) string {
+	client := skillregistrygrpcpb.NewSkillRegistryClient(conn)
+	resp, err := client.GetSkill(ctx, &skillregistrygrpcpb.GetSkillRequest{Id: skillID})
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			log.Printf("Warning: could not determine the installed version of %q: %v", skillID, err)
+		}
+		return ""
+	}
+	return resp.GetSkill().GetIdVersion()
+}
+
+// writeReceipt records the installation of a skill. Failures are only logged, since the skill has
+// already been installed at this point.
+func writeReceipt(idVersion, previousIDVersion string, img *imagepb.Image, address string) {
+	dir := cmdFlags.GetString(keyReceiptDir)
+	if dir == "" {
+		var err error
+		if dir, err = receipt.DefaultDir(); err != nil {
+			log.Printf("Warning: could not write installation receipt: %v", err)
+			return
+		}
+	}
+	r := &receipt.Receipt{
+		IDVersion:         idVersion,
+		PreviousIDVersion: previousIDVersion,
+		AssetType:         "skill",
+		Cluster:           cmdFlags.GetString(cmdutils.KeyCluster),
+		Solution:          cmdFlags.GetString(cmdutils.KeySolution),
+		Address:           address,
+		Project:           cmdFlags.GetFlagProject(),
+		Org:               cmdFlags.GetString(cmdutils.KeyOrganization),
+		User:              receipt.CurrentUser(),
+	}
+	r.SetImage(img)
+	path, err := receipt.Write(dir, r)
+	if err != nil {
+		log.Printf("Warning: could not write installation receipt: %v", err)
+		return
+	}
+	log.Printf("Wrote installation receipt to %s", path)
+}
+
+// expandArchives replaces directories in targets by the skill archives (*.tar) they contain and
+// expands glob patterns, e.g., if they were quoted to keep the shell from expanding them.
+func expandArchives(targets []string) ([]string, error) {
+	var expanded []string
+	for _, target := range targets {
+		pattern := target
+		if info, err := os.Stat(target); err == nil && info.IsDir() {
+			pattern = filepath.Join(target, "*.tar")
+		} else if !strings.ContainsAny(target, "*?[") {
+			expanded = append(expanded, target)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %w", target, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no skill archives match %q", target)
+		}
+		expanded = append(expanded, matches...)
+	}
+	return expanded, nil
+}
+
+// parseTimeout parses the value of the duration flag name. Zero disables the timeout.
+func parseTimeout(name, value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value passed for --%s: %w", name, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid value passed for --%s: duration must not be negative, but got %q", name, value)
+	}
+	return timeout, nil
+}
+
+// withTimeout runs f with a context which expires after timeout, or with ctx itself if timeout
+// is zero. If the timeout expires, the returned error names flag, which sets the timeout.
+func withTimeout(ctx context.Context, timeout time.Duration, flag string, f func(context.Context) error) error {
+	if timeout == 0 {
+		return f(ctx)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := f(timeoutCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("did not finish within --%s=%s: %w", flag, timeout, err)
+	}
+	return err
+}
+
+// installParams are the parameters shared by the installations of all targets.
+type installParams struct {
+	conn           *grpc.ClientConn
+	address        string
+	targetType     imageutils.TargetType
+	timeout        time.Duration
+	timeoutStr     string
+	pushTimeout    time.Duration
+	installTimeout time.Duration
+	out            io.Writer
+	progress       progress.Reporter
+	// receipt is set if an installation receipt should be written.
+	receipt bool
+}
+
+// installSkill installs the skill of a single target and waits until it is available.
+func installSkill(ctx context.Context, p *installParams, target string) error {
+	if err := runInstallSkill(ctx, p, target); err != nil {
+		p.progress.Report(target, progress.StageFailed, "%v", err)
+		return err
+	}
+	return nil
+}
+
+// pushSkill pushes the image of target to the container registry. Uploads are aborted when ctx is
+// done.
+func pushSkill(ctx context.Context, p *installParams, target string, remoteOpt remote.Option) (*imagepb.Image, *imageutils.SkillInstallerParams, error) {
+	// Install the skill to the registry
+	flagRegistry := cmdFlags.GetFlagRegistry()
+
+	// Upload skill, directly, to workcell, with fail-over legacy transfer if possible
+	transfer := imagetransfer.RemoteTransferer(remote.WithContext(ctx), remoteOpt)
+	// if --type=image we are going to skip direct injection as image is already
+	// available in the repository and as such push is essentially no-op. Given
+	// than underlying code requires image inspection, command have to have
+	// access to given image and thus there should not be an issue to get
+	// image during installation. The main reason we are skipping here
+	// is that direct injection does not allow to read image from workcell
+	// thus making request of --type=image invalid from DI perspective.
+	if p.targetType != imageutils.Image &&
+		!cmdFlags.GetFlagSkipDirectUpload() {
+		opts := []directupload.Option{
+			directupload.WithDiscovery(directupload.NewFromConnection(p.conn)),
+			directupload.WithOutput(p.out),
+		}
+		if flagRegistry != "" {
+			// User set external registry, so we can use it as fail-over.
+			opts = append(opts, directupload.WithFailOver(transfer))
+		} else {
+			// Fake name that ends in .local in order to indicate that this is local, directly uploaded
+			// image.
+			flagRegistry = "direct.upload.local"
+		}
+		transfer = directupload.NewTransferer(ctx, opts...)
+	}
+
+	authUser, authPwd := cmdFlags.GetFlagsRegistryAuthUserPassword()
+	return registry.PushSkill(target, registry.PushOptions{
+		AuthUser:   authUser,
+		AuthPwd:    authPwd,
+		Registry:   flagRegistry,
+		Type:       string(p.targetType),
+		Transferer: transfer,
+	})
+}
+
+func runInstallSkill(ctx context.Context, p *installParams, target string) error {
+	p.progress.Report(target, progress.StageVerify, "Verifying %q", target)
+	remoteOpt, err := clientutils.RemoteOpt(cmdFlags)
+	if err != nil {
+		return err
+	}
+	transfer := imagetransfer.RemoteTransferer(remote.WithContext(ctx), remoteOpt)
+
+	// Catch stale images, e.g., an image which was built before the skill was renamed.
+	if err := verifyImageLabels(target, p.targetType, transfer, p.out); err != nil {
+		return err
+	}
+	if cmdFlags.GetBool(keyCheckCompatibility) {
+		if err := verifyCompatibility(ctx, p.conn, target, p.targetType, transfer, p.out); err != nil {
+			return err
+		}
+	}
+
+	p.progress.Report(target, progress.StagePush, "Publishing skill image as %q", target)
+	var imgpb *imagepb.Image
+	var installerParams *imageutils.SkillInstallerParams
+	if err := withTimeout(ctx, p.pushTimeout, keyPushTimeout, func(ctx context.Context) error {
+		var err error
+		imgpb, installerParams, err = pushSkill(ctx, p, target, remoteOpt)
+		return err
+	}); err != nil {
+		return fmt.Errorf("could not push target %q to the container registry: %w", target, err)
+	}
+	return installPushedSkill(ctx, p, target, installerParams.SkillID, imgpb)
+}
+
+// installPushedSkill installs the skill with the given id from the pushed image img and waits until
+// it is available.
+func installPushedSkill(ctx context.Context, p *installParams, target string, skillID string, imgpb *imagepb.Image) error {
+	pkg, err := idutils.PackageFrom(skillID)
+	if err != nil {
+		return fmt.Errorf("could not parse package from ID: %w", err)
+	}
+	name, err := idutils.NameFrom(skillID)
+	if err != nil {
+		return fmt.Errorf("could not parse name from ID: %w", err)
+	}
+	// No deterministic data is available for generating the sideloaded version here. Use a random
+	// string instead to keep the version unique. Ideally we would probably use the digest of the
+	// skill image or similar.
+	version := fmt.Sprintf("0.0.1+%s", uuid.New())
+	idVersion, err := idutils.IDVersionFrom(pkg, name, version)
+	if err != nil {
+		return fmt.Errorf("could not create id_version: %w", err)
+	}
+	// Remember the replaced version, so that it can be restored with 'inctl asset rollback'.
+	previousIDVersion := installedIDVersion(ctx, p.conn, skillID)
+	p.progress.Report(target, progress.StageInstall, "Installing skill %q", idVersion)
+
+	err = withTimeout(ctx, p.installTimeout, keyInstallTimeout, func(ctx context.Context) error {
+		return imageutils.InstallContainer(ctx,
+			&imageutils.InstallContainerParams{
+				Address:    p.address,
+				Connection: p.conn,
+				Request: &installerpb.InstallContainerAddonRequest{
+					Id:      skillID,
+					Version: version,
+					Type:    installerpb.AddonType_ADDON_TYPE_SKILL,
+					Images: []*imagepb.Image{
+						imgpb,
+					},
+				},
+			})
+	})
+	if err != nil {
+		return fmt.Errorf("could not install the skill: %w", err)
+	}
+	if p.receipt {
+		writeReceipt(idVersion, previousIDVersion, imgpb, p.address)
+	}
+
+	if p.timeout == 0 {
+		p.progress.Report(target, progress.StageDone, "Finished installing %q, skill container is now starting", idVersion)
+		return nil
+	}
+
+	p.progress.Report(target, progress.StageWait, "Finished installing %q, waiting for the skill to be available for a maximum of %s", idVersion, p.timeoutStr)
+	err = waitforskill.WaitForSkill(ctx,
+		&waitforskill.Params{
+			Connection:     p.conn,
+			SkillID:        skillID,
+			SkillIDVersion: idVersion,
+			WaitDuration:   p.timeout,
+		})
+	if err != nil {
+		return fmt.Errorf("failed waiting for skill: %w", err)
+	}
+	p.progress.Report(target, progress.StageDone, "The skill %q is now available.", idVersion)
+	return nil
+}
+
 var installCmd = &cobra.Command{
-	Use:   "install --type=TYPE TARGET",
+	Use:   "install --type=TYPE TARGET...",
 	Short: "Install a skill",
 	Example: `Build a skill, upload it to a container registry, and install the skill
 $ inctl skill install --type=build //abc:skill.tar --registry=gcr.io/my-registry --cluster=my_cluster
@@ -38,127 +322,126 @@ $ inctl skill install --type=image gcr.io/my-workcell/abc@sha256:20ab4f --cluste
 
 Use the solution flag to automatically resolve the cluster (requires the solution to run)
 $ inctl skill install --type=image gcr.io/my-workcell/abc@sha256:20ab4f --solution=my-solution
+
+Install a skill built on a remote build execution or CI system, using the build event protocol
+file of the build or the URL of the uploaded archive to locate the output
+$ inctl skill install --type=build //abc:skill.tar --build_output=/tmp/bep.json --cluster=my_cluster
+$ inctl skill install --type=build //abc:skill.tar --build_output=https://ci.example.com/artifacts/skill.tar --cluster=my_cluster
+
+Check that the parameters of the new skill version are compatible with the behavior trees loaded
+into the executive before installing it
+$ inctl skill install --type=build //abc:skill.tar --cluster=my_cluster --check_compatibility
+
+Install all skill archives in a directory, four at a time
+$ inctl skill install --type=archive bazel-bin/skills/ --cluster=my_cluster --parallelism=4
 `,
-	Args: cobra.ExactArgs(1),
+	Args: cobra.MinimumNArgs(1),
 	Aliases: []string{
 		"load",
 		"start",
 	},
 	RunE: func(command *cobra.Command, args []string) error {
 		ctx := command.Context()
-		target := args[0]
+		targets := args
+		targetType := imageutils.TargetType(cmdFlags.GetFlagSideloadStartType())
+
+		if buildOutput := cmdFlags.GetString(keyBuildOutput); buildOutput != "" {
+			if len(targets) != 1 {
+				return fmt.Errorf("--%s requires a single target", keyBuildOutput)
+			}
+			path, cleanup, err := imageutils.FetchBuildOutput(ctx, targets[0], buildOutput)
+			if err != nil {
+				return fmt.Errorf("could not locate the build output of %q: %w", targets[0], err)
+			}
+			defer cleanup()
+			log.Printf("Using build output %q of %q", path, targets[0])
+			targets, targetType = []string{path}, imageutils.Archive
+		}
+		if targetType == imageutils.Archive {
+			var err error
+			if targets, err = expandArchives(targets); err != nil {
+				return err
+			}
+		}
+		parallelism := cmdFlags.GetInt(keyParallelism)
+		if parallelism < 1 {
+			return fmt.Errorf("--%s must be positive, got %d", keyParallelism, parallelism)
+		}
 
 		timeout, timeoutStr, err := cmdFlags.GetFlagSideloadStartTimeout()
 		if err != nil {
 			return err
 		}
-
-		ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, cmdFlags)
+		pushTimeout, err := parseTimeout(keyPushTimeout, cmdFlags.GetString(keyPushTimeout))
 		if err != nil {
 			return err
 		}
-		defer conn.Close()
-
-		// Install the skill to the registry
-		flagRegistry := cmdFlags.GetFlagRegistry()
-
-		// Upload skill, directly, to workcell, with fail-over legacy transfer if possible
-		remoteOpt, err := clientutils.RemoteOpt(cmdFlags)
+		installTimeout, err := parseTimeout(keyInstallTimeout, cmdFlags.GetString(keyInstallTimeout))
 		if err != nil {
 			return err
 		}
-		transfer := imagetransfer.RemoteTransferer(remoteOpt)
-		// if --type=image we are going to skip direct injection as image is already
-		// available in the repository and as such push is essentially no-op. Given
-		// than underlying code requires image inspection, command have to have
-		// access to given image and thus there should not be an issue to get
-		// image during installation. The main reason we are skipping here
-		// is that direct injection does not allow to read image from workcell
-		// thus making request of --type=image invalid from DI perspective.
-		if imageutils.TargetType(cmdFlags.GetFlagSideloadStartType()) != imageutils.Image &&
-			!cmdFlags.GetFlagSkipDirectUpload() {
-			opts := []directupload.Option{
-				directupload.WithDiscovery(directupload.NewFromConnection(conn)),
-				directupload.WithOutput(command.OutOrStdout()),
-			}
-			if flagRegistry != "" {
-				// User set external registry, so we can use it as fail-over.
-				opts = append(opts, directupload.WithFailOver(transfer))
-			} else {
-				// Fake name that ends in .local in order to indicate that this is local, directly uploaded
-				// image.
-				flagRegistry = "direct.upload.local"
-			}
-			transfer = directupload.NewTransferer(ctx, opts...)
-		}
 
-		log.Printf("Publishing skill image as %q", target)
-		authUser, authPwd := cmdFlags.GetFlagsRegistryAuthUserPassword()
-		imgpb, installerParams, err := registry.PushSkill(target, registry.PushOptions{
-			AuthUser:   authUser,
-			AuthPwd:    authPwd,
-			Registry:   flagRegistry,
-			Type:       cmdFlags.GetFlagSideloadStartType(),
-			Transferer: transfer,
-		})
+		ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, cmdFlags)
 		if err != nil {
-			return fmt.Errorf("could not push target %q to the container registry: %v", target, err)
+			return err
+		}
+		defer conn.Close()
+		if err := clientutils.CheckClusterUpgradeState(ctx, conn, cmdFlags); err != nil {
+			return err
 		}
 
-		pkg, err := idutils.PackageFrom(installerParams.SkillID)
+		reporter, err := progress.NewReporter(root.FlagOutput)
 		if err != nil {
-			return fmt.Errorf("could not parse package from ID: %w", err)
+			return err
 		}
-		name, err := idutils.NameFrom(installerParams.SkillID)
-		if err != nil {
-			return fmt.Errorf("could not parse name from ID: %w", err)
+		out := command.OutOrStdout()
+		if root.FlagOutput != printer.TextOutputFormat {
+			// Keep stdout parseable, it only receives the progress events.
+			out = command.ErrOrStderr()
 		}
-		// No deterministic data is available for generating the sideloaded version here. Use a random
-		// string instead to keep the version unique. Ideally we would probably use the digest of the
-		// skill image or similar.
-		version := fmt.Sprintf("0.0.1+%s", uuid.New())
-		idVersion, err := idutils.IDVersionFrom(pkg, name, version)
-		if err != nil {
-			return fmt.Errorf("could not create id_version: %w", err)
+		p := &installParams{
+			conn:           conn,
+			address:        address,
+			targetType:     targetType,
+			timeout:        timeout,
+			timeoutStr:     timeoutStr,
+			pushTimeout:    pushTimeout,
+			installTimeout: installTimeout,
+			out:            out,
+			progress:       reporter,
+			receipt:        cmdFlags.GetBool(keyReceipt),
 		}
-		log.Printf("Installing skill %q", idVersion)
-
-		installerCtx := ctx
-
-		err = imageutils.InstallContainer(installerCtx,
-			&imageutils.InstallContainerParams{
-				Address:    address,
-				Connection: conn,
-				Request: &installerpb.InstallContainerAddonRequest{
-					Id:      installerParams.SkillID,
-					Version: version,
-					Type:    installerpb.AddonType_ADDON_TYPE_SKILL,
-					Images: []*imagepb.Image{
-						imgpb,
-					},
-				},
-			})
-		if err != nil {
-			return fmt.Errorf("could not install the skill: %w", err)
+		if len(targets) == 1 {
+			return installSkill(ctx, p, targets[0])
 		}
-		log.Printf("Finished installing, skill container is now starting")
 
-		if timeout == 0 {
-			return nil
+		log.Printf("Installing %d skills, %d at a time", len(targets), parallelism)
+		errs := make([]error, len(targets))
+		sem := make(chan struct{}, parallelism)
+		var wg sync.WaitGroup
+		for i, target := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				if errs[i] = installSkill(ctx, p, target); errs[i] != nil {
+					errs[i] = fmt.Errorf("%s: %w", target, errs[i])
+				}
+			}()
 		}
+		wg.Wait()
 
-		log.Printf("Waiting for the skill to be available for a maximum of %s", timeoutStr)
-		err = waitforskill.WaitForSkill(ctx,
-			&waitforskill.Params{
-				Connection:     conn,
-				SkillID:        installerParams.SkillID,
-				SkillIDVersion: idVersion,
-				WaitDuration:   timeout,
-			})
-		if err != nil {
-			return fmt.Errorf("failed waiting for skill: %w", err)
+		var failed []error
+		for _, err := range errs {
+			if err != nil {
+				failed = append(failed, err)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("could not install %d of %d skills:\n%w", len(failed), len(targets), errors.Join(failed...))
 		}
-		log.Printf("The skill is now available.")
+		log.Printf("Installed %d skills", len(targets))
 		return nil
 	},
 }
@@ -172,6 +455,27 @@ func init() {
 	cmdFlags.AddFlagRegistry()
 	cmdFlags.AddFlagsRegistryAuthUserPassword()
 	cmdFlags.AddFlagSideloadStartTimeout("skill")
+	cmdFlags.OptionalString(keyPushTimeout, "30m", "Maximum time to push the image of each skill "+
+		"to the container registry or the cluster. Can be set to any valid duration (\"60s\", "+
+		"\"5m\", ...) or to \"0\" to disable the timeout.")
+	cmdFlags.OptionalString(keyInstallTimeout, "10m", "Maximum time for the installer of the "+
+		"cluster to install each skill, before waiting for it to become available. Can be set to "+
+		"any valid duration (\"60s\", \"5m\", ...) or to \"0\" to disable the timeout.")
 	cmdFlags.AddFlagSideloadStartType()
 	cmdFlags.AddFlagSkipDirectUpload("skill")
+	cmdFlags.AddFlagIgnoreUpgradeState()
+	cmdFlags.OptionalBool(keyCheckCompatibility, false, "Before installing, check that the "+
+		"parameters of the new skill version are compatible with all uses of the skill in the "+
+		"behavior trees loaded into the executive, and abort the installation if they are not.")
+	cmdFlags.OptionalString(keyBuildOutput, "", "For --type=build, locate the built archive without "+
+		"running bazel, e.g., when it was built on a remote build execution or CI system. Either the "+
+		"http(s) URL of the archive or the path of a build event protocol JSON file written by "+
+		"'bazel build --build_event_json_file'.")
+	cmdFlags.AddFlagRequiresValue(keyBuildOutput, cmdutils.KeyType, string(imageutils.Build))
+	cmdFlags.OptionalInt(keyParallelism, 4, "Maximum number of skills to install at the same time "+
+		"if multiple targets are given.")
+	cmdFlags.OptionalBool(keyReceipt, true, "Write an installation receipt (id_version, image "+
+		"digest, cluster, time, user and org) after a successful installation.")
+	cmdFlags.OptionalString(keyReceiptDir, "", "Directory to write installation receipts to. "+
+		"Defaults to intrinsic/receipts in the user config directory.")
 }
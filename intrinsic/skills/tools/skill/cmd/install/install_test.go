@@ -0,0 +1,199 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package install
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/status"
+	"intrinsic/assets/testing/fakes"
+	imagepb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
+	installerpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
+	srgrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	"intrinsic/testing/grpctest"
+)
+
+func TestExpandArchives(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.tar", "a.tar", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b := filepath.Join(dir, "a.tar"), filepath.Join(dir, "b.tar")
+
+	tests := []struct {
+		name    string
+		targets []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "files",
+			targets: []string{b, "missing.tar"},
+			want:    []string{b, "missing.tar"},
+		},
+		{
+			name:    "directory",
+			targets: []string{dir},
+			want:    []string{a, b},
+		},
+		{
+			name:    "glob",
+			targets: []string{filepath.Join(dir, "a*")},
+			want:    []string{a},
+		},
+		{
+			name:    "no match",
+			targets: []string{filepath.Join(dir, "c*")},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := expandArchives(tc.targets)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expandArchives(%v) = %v, want error", tc.targets, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandArchives(%v) failed: %v", tc.targets, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("expandArchives(%v) returned unexpected diff (-want +got):\n%s", tc.targets, diff)
+			}
+		})
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "30m", want: 30 * time.Minute},
+		{value: "0", want: 0},
+		{value: "-1s", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := parseTimeout(keyPushTimeout, tc.value)
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "--"+keyPushTimeout) {
+					t.Errorf("parseTimeout(%q) = %v, %v, want an error naming the flag", tc.value, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTimeout(%q) failed: %v", tc.value, err)
+			}
+			if got != tc.want {
+				t.Errorf("parseTimeout(%q) = %v, want %v", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+// waitForCancel blocks until ctx is done, like a stalled upload or RPC.
+func waitForCancel(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWithTimeout(t *testing.T) {
+	t.Run("expires", func(t *testing.T) {
+		err := withTimeout(context.Background(), 10*time.Millisecond, keyInstallTimeout, waitForCancel)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("withTimeout() returned %v, want %v", err, context.DeadlineExceeded)
+		}
+		if err == nil || !strings.Contains(err.Error(), "--"+keyInstallTimeout) {
+			t.Errorf("withTimeout() returned %v, want an error naming --%s", err, keyInstallTimeout)
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		err := withTimeout(context.Background(), 0, keyInstallTimeout, func(ctx context.Context) error {
+			if _, ok := ctx.Deadline(); ok {
+				return errors.New("context has a deadline")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("withTimeout() returned %v, want nil", err)
+		}
+	})
+	t.Run("canceled by caller", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := withTimeout(ctx, time.Hour, keyPushTimeout, waitForCancel)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("withTimeout() returned %v, want %v", err, context.Canceled)
+		}
+		if err != nil && strings.Contains(err.Error(), keyPushTimeout) {
+			t.Errorf("withTimeout() returned %v, want no mention of --%s", err, keyPushTimeout)
+		}
+	})
+}
+
+func TestInstallPushedSkill(t *testing.T) {
+	ctx := context.Background()
+	registry := fakes.NewSkillRegistry()
+	installer := fakes.NewInstallerService(registry)
+	server := grpc.NewServer()
+	registry.Register(server)
+	installer.Register(server)
+	address := grpctest.StartServerT(t, server)
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(local.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial server: %v", err)
+	}
+	defer conn.Close()
+
+	p := &installParams{
+		conn:       conn,
+		address:    address,
+		timeout:    10 * time.Second,
+		timeoutStr: "10s",
+		out:        io.Discard,
+	}
+	img := &imagepb.Image{Registry: "direct.upload.local", Name: "skill", Tag: "@sha256:0123"}
+	for i := 0; i < 2; i++ {
+		if err := installPushedSkill(ctx, p, "skill.tar", "ai.intrinsic.my_skill", img); err != nil {
+			t.Fatalf("installPushedSkill() failed: %v", err)
+		}
+	}
+
+	addons := installer.Addons()
+	if len(addons) != 1 {
+		t.Fatalf("installPushedSkill() installed %d addons, want 1 replaced addon", len(addons))
+	}
+	if got := addons[0]; got.GetId() != "ai.intrinsic.my_skill" || got.GetType() != installerpb.AddonType_ADDON_TYPE_SKILL || !strings.HasPrefix(got.GetVersion(), "0.0.1+") {
+		t.Errorf("installPushedSkill() installed %v, want a sideloaded skill ai.intrinsic.my_skill", got)
+	}
+	resp, err := srgrpcpb.NewSkillRegistryClient(conn).GetSkill(ctx, &srgrpcpb.GetSkillRequest{Id: "ai.intrinsic.my_skill"})
+	if err != nil {
+		t.Fatalf("GetSkill() failed: %v", err)
+	}
+	if got, want := resp.GetSkill().GetIdVersion(), "ai.intrinsic.my_skill."+addons[0].GetVersion(); got != want {
+		t.Errorf("GetSkill() returned id_version %q, want %q", got, want)
+	}
+
+	installer.SetError(status.Error(codes.FailedPrecondition, "cluster is upgrading"))
+	if err := installPushedSkill(ctx, p, "skill.tar", "ai.intrinsic.my_skill", img); err == nil {
+		t.Errorf("installPushedSkill() succeeded although the installer failed, want error")
+	}
+}
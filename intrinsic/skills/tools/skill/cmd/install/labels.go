@@ -0,0 +1,111 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package install
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"google.golang.org/protobuf/proto"
+	"intrinsic/assets/idutils"
+	"intrinsic/assets/imagetransfer"
+	"intrinsic/assets/imageutils"
+	sscpb "intrinsic/skills/proto/skill_service_config_go_proto"
+)
+
+// labelMismatch is a field of the skill service config (the manifest of the
+// skill) which does not agree with the labels of the image containing it.
+type labelMismatch struct {
+	field    string
+	label    string
+	manifest string
+}
+
+func (m labelMismatch) String() string {
+	return fmt.Sprintf("%s: image label %q, manifest %q", m.field, m.label, m.manifest)
+}
+
+// compareLabels returns the fields of the skill service config which do not
+// match the skill ID from the image labels.
+func compareLabels(labelID string, config *sscpb.SkillServiceConfig) ([]labelMismatch, error) {
+	desc := config.GetSkillDescription()
+	var result []labelMismatch
+	if id := desc.GetId(); id != "" && id != labelID {
+		result = append(result, labelMismatch{field: "id", label: labelID, manifest: id})
+	}
+	if idVersion := desc.GetIdVersion(); idVersion != "" {
+		parts, err := idutils.NewIDVersionParts(idVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id_version %q in skill service config: %w", idVersion, err)
+		}
+		if parts.ID() != labelID {
+			result = append(result, labelMismatch{field: "id_version", label: labelID, manifest: idVersion})
+		}
+	}
+	name, err := idutils.NameFrom(labelID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid skill ID %q in image labels: %w", labelID, err)
+	}
+	if n := desc.GetSkillName(); n != "" && n != name {
+		result = append(result, labelMismatch{field: "skill_name", label: name, manifest: n})
+	}
+	if n := config.GetSkillName(); n != "" && n != name {
+		result = append(result, labelMismatch{field: "skill_service_config.skill_name", label: name, manifest: n})
+	}
+	pkg, err := idutils.PackageFrom(labelID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid skill ID %q in image labels: %w", labelID, err)
+	}
+	if p := desc.GetPackageName(); p != "" && p != pkg {
+		result = append(result, labelMismatch{field: "package_name", label: pkg, manifest: p})
+	}
+	return result, nil
+}
+
+// checkImageLabels returns an error listing the mismatches between the labels
+// of the image and the skill service config it contains. Images without a
+// skill service config are not checked.
+func checkImageLabels(img containerregistry.Image, w io.Writer) error {
+	installerParams, err := imageutils.GetSkillInstallerParams(img)
+	if err != nil {
+		return fmt.Errorf("could not extract labels from image object: %w", err)
+	}
+	content, err := readImageFile(img, skillServiceConfigPath)
+	if errors.Is(err, errNoSkillServiceConfig) {
+		fmt.Fprintf(w, "Skipping label check: %v\n", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	config := &sscpb.SkillServiceConfig{}
+	if err := proto.Unmarshal(content, config); err != nil {
+		return fmt.Errorf("could not parse skill service config: %w", err)
+	}
+
+	mismatches, err := compareLabels(installerParams.SkillID, config)
+	if err != nil {
+		return err
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	lines := make([]string, len(mismatches))
+	for i, m := range mismatches {
+		lines[i] = "\t" + m.String()
+	}
+	return fmt.Errorf("the labels of the skill image do not match its manifest, the image may be stale and should be rebuilt:\n%s", strings.Join(lines, "\n"))
+}
+
+// verifyImageLabels checks that the labels of the skill image in the given
+// target match the skill service config in the image.
+func verifyImageLabels(target string, targetType imageutils.TargetType, t imagetransfer.Transferer, w io.Writer) error {
+	img, err := imageutils.GetImage(target, targetType, t)
+	if err != nil {
+		return fmt.Errorf("could not read image: %w", err)
+	}
+	return checkImageLabels(img, w)
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package install
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	sscpb "intrinsic/skills/proto/skill_service_config_go_proto"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+)
+
+func TestCompareLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *sscpb.SkillServiceConfig
+		want   []labelMismatch
+	}{
+		{
+			name: "matching",
+			config: &sscpb.SkillServiceConfig{
+				SkillDescription: &skillspb.Skill{
+					Id:          "ai.intrinsic.my_skill",
+					IdVersion:   "ai.intrinsic.my_skill.0.0.1",
+					SkillName:   "my_skill",
+					PackageName: "ai.intrinsic",
+				},
+			},
+		},
+		{
+			name:   "no skill description",
+			config: &sscpb.SkillServiceConfig{},
+		},
+		{
+			name: "renamed skill",
+			config: &sscpb.SkillServiceConfig{
+				SkillName: "other_skill",
+				SkillDescription: &skillspb.Skill{
+					Id:          "ai.intrinsic.other_skill",
+					IdVersion:   "ai.intrinsic.other_skill.0.0.1",
+					SkillName:   "other_skill",
+					PackageName: "ai.intrinsic",
+				},
+			},
+			want: []labelMismatch{
+				{field: "id", label: "ai.intrinsic.my_skill", manifest: "ai.intrinsic.other_skill"},
+				{field: "id_version", label: "ai.intrinsic.my_skill", manifest: "ai.intrinsic.other_skill.0.0.1"},
+				{field: "skill_name", label: "my_skill", manifest: "other_skill"},
+				{field: "skill_service_config.skill_name", label: "my_skill", manifest: "other_skill"},
+			},
+		},
+		{
+			name: "moved package",
+			config: &sscpb.SkillServiceConfig{
+				SkillDescription: &skillspb.Skill{
+					SkillName:   "my_skill",
+					PackageName: "com.example",
+				},
+			},
+			want: []labelMismatch{
+				{field: "package_name", label: "ai.intrinsic", manifest: "com.example"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := compareLabels("ai.intrinsic.my_skill", tc.config)
+			if err != nil {
+				t.Fatalf("compareLabels() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(labelMismatch{})); diff != "" {
+				t.Errorf("compareLabels() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
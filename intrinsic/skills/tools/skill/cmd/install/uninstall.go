@@ -16,6 +16,7 @@ import (
 	"intrinsic/assets/imageutils"
 	installerpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
 	"intrinsic/skills/tools/skill/cmd"
+	"intrinsic/skills/tools/skill/cmd/waitforskill"
 )
 
 var cmdFlags = cmdutils.NewCmdFlags()
@@ -55,17 +56,27 @@ $ inctl skill uninstall --type=id skill
 			return fmt.Errorf("type must be one of (%s, %s, %s, %s, %s)", imageutils.Build, imageutils.Archive, imageutils.Image, imageutils.ID, imageutils.Name)
 		}
 
+		timeout, timeoutStr, err := cmdFlags.GetFlagSideloadStopTimeout()
+		if err != nil {
+			return err
+		}
+
 		ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, cmdFlags)
 		if err != nil {
 			return err
 		}
 		defer conn.Close()
 
+		imageutils.SetArchiveCacheEnabled(!cmdFlags.GetFlagNoCache())
 		skillID, err := imageutils.SkillIDFromTarget(target, imageutils.TargetType(targetType), imagetransfer.RemoteTransferer(remote.WithAuthFromKeychain(google.Keychain)))
 		if err != nil {
 			return fmt.Errorf("could not get skill ID: %v", err)
 		}
 
+		if err := cmdFlags.Confirm(fmt.Sprintf("Uninstall skill %q", skillID)); err != nil {
+			return err
+		}
+
 		log.Printf("Removing skill %q", skillID)
 		if err := imageutils.RemoveContainer(ctx, &imageutils.RemoveContainerParams{
 			Address:    address,
@@ -79,6 +90,20 @@ $ inctl skill uninstall --type=id skill
 		}
 		log.Print("Finished removing the skill")
 
+		if timeout == 0 {
+			return nil
+		}
+
+		log.Printf("Waiting for the skill to be removed for a maximum of %s", timeoutStr)
+		if err := waitforskill.WaitForSkillRemoval(ctx, &waitforskill.Params{
+			Connection:   conn,
+			SkillID:      skillID,
+			WaitDuration: timeout,
+		}); err != nil {
+			return fmt.Errorf("failed waiting for the skill to be removed: %w", err)
+		}
+		log.Print("The skill is no longer registered.")
+
 		return nil
 	},
 }
@@ -89,5 +114,8 @@ func init() {
 
 	cmdFlags.AddFlagsAddressClusterSolution()
 	cmdFlags.AddFlagsProjectOrg()
+	cmdFlags.AddFlagSideloadStopTimeout("skill")
 	cmdFlags.AddFlagSideloadStopType("skill")
+	cmdFlags.AddFlagNoCache()
+	cmdFlags.AddFlagYes()
 }
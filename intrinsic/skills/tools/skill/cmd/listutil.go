@@ -10,7 +10,10 @@ import (
 	"sort"
 	"strings"
 
+	"google.golang.org/protobuf/proto"
 	"intrinsic/assets/idutils"
+	rtpb "intrinsic/assets/proto/release_tag_go_proto"
+	viewpb "intrinsic/assets/proto/view_go_proto"
 	skillcataloggrpcpb "intrinsic/skills/catalog/proto/skill_catalog_go_grpc_proto"
 	skillcatalogpb "intrinsic/skills/catalog/proto/skill_catalog_go_grpc_proto"
 	spb "intrinsic/skills/proto/skills_go_proto"
@@ -26,6 +29,7 @@ type SkillDescription struct {
 	ID           string `json:"id,omitempty"`
 	IDVersion    string `json:"idVersion,omitempty"`
 	ReleaseNotes string `json:"releaseNotes,omitempty"`
+	ReleaseTag   string `json:"releaseTag,omitempty"`
 	Description  string `json:"description,omitempty"`
 }
 
@@ -58,6 +62,7 @@ func SkillDescriptionsFromCatalogSkills(skills []*skillcatalogpb.Skill) (*SkillD
 			ID:           ivp.ID(),
 			IDVersion:    idVersion,
 			ReleaseNotes: metadata.GetReleaseNotes(),
+			ReleaseTag:   releaseTagName(metadata.GetReleaseTag()),
 			Description:  metadata.GetDocumentation().GetDescription(),
 		}
 	}
@@ -65,6 +70,15 @@ func SkillDescriptionsFromCatalogSkills(skills []*skillcatalogpb.Skill) (*SkillD
 	return &out, nil
 }
 
+// releaseTagName returns a short name for the given release tag, or "" if no
+// tag is set.
+func releaseTagName(tag rtpb.ReleaseTag) string {
+	if tag == rtpb.ReleaseTag_RELEASE_TAG_UNSPECIFIED {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(tag.String(), "RELEASE_TAG_"))
+}
+
 // SkillDescriptionsFromSkills creates a SkillDescriptions instance from Skill protos
 func SkillDescriptionsFromSkills(skills []*spb.Skill) *SkillDescriptions {
 	out := SkillDescriptions{Skills: make([]SkillDescription, len(skills))}
@@ -99,6 +113,66 @@ func (sd SkillDescriptions) String() string {
 	return strings.Join(lines, "\n")
 }
 
+// ReleasedVersions wraps the output of commands which list the released versions of an asset.
+// Versions are ordered newest first.
+type ReleasedVersions SkillDescriptions
+
+// MarshalJSON converts a ReleasedVersions to a byte slice.
+func (rv ReleasedVersions) MarshalJSON() ([]byte, error) {
+	return SkillDescriptions(rv).MarshalJSON()
+}
+
+// String converts a ReleasedVersions to a string, listing the release tag and notes of each
+// version.
+func (rv ReleasedVersions) String() string {
+	var sb strings.Builder
+	for _, skill := range rv.Skills {
+		sb.WriteString(skill.IDVersion)
+		if skill.ReleaseTag != "" {
+			fmt.Fprintf(&sb, " [%s]", skill.ReleaseTag)
+		}
+		if skill.UpdateTime != "" {
+			fmt.Fprintf(&sb, "\n\treleased: %s", skill.UpdateTime)
+		}
+		if skill.ReleaseNotes != "" {
+			fmt.Fprintf(&sb, "\n\tnotes: %s", strings.ReplaceAll(skill.ReleaseNotes, "\n", "\n\t       "))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// ReleasedVersionsFromCatalogSkills creates a ReleasedVersions instance from catalog.Skill protos,
+// ordering them by their update time, newest first.
+func ReleasedVersionsFromCatalogSkills(skills []*skillcatalogpb.Skill) (*ReleasedVersions, error) {
+	sorted := make([]*skillcatalogpb.Skill, len(skills))
+	copy(sorted, skills)
+	// Sort on the timestamps, their formatted strings do not order chronologically.
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetMetadata().GetUpdateTime().AsTime().After(sorted[j].GetMetadata().GetUpdateTime().AsTime())
+	})
+	sd, err := SkillDescriptionsFromCatalogSkills(sorted)
+	if err != nil {
+		return nil, err
+	}
+	return (*ReleasedVersions)(sd), nil
+}
+
+// ListReleasedVersions lists all released versions of the skill with the given id, newest first.
+func ListReleasedVersions(ctx context.Context, client skillcataloggrpcpb.SkillCatalogClient, id string) (*ReleasedVersions, error) {
+	skills, err := ListWithCatalogClient(ctx, client, &skillcatalogpb.ListSkillsRequest{
+		View:     viewpb.AssetViewType_ASSET_VIEW_TYPE_VERSIONS,
+		PageSize: 50,
+		StrictFilter: &skillcatalogpb.ListSkillsRequest_Filter{
+			Id: proto.String(id),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list versions of %q: %w", id, err)
+	}
+	return ReleasedVersionsFromCatalogSkills(skills)
+}
+
 type clientWrapper struct {
 	client skillcataloggrpcpb.SkillCatalogClient
 }
@@ -11,13 +11,13 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"os"
-	"path"
 	"time"
 
 	"github.com/spf13/cobra"
 	"intrinsic/assets/cmdutils"
 	"intrinsic/assets/imagetransfer"
 	"intrinsic/assets/imageutils"
+	"intrinsic/frontend/frontendclient"
 	"intrinsic/skills/tools/skill/cmd"
 	"intrinsic/skills/tools/skill/cmd/dialerutil"
 	"intrinsic/skills/tools/skill/cmd/solutionutil"
@@ -41,7 +41,7 @@ const (
 )
 
 const (
-	localhostURL = "localhost:17080"
+	localhostURL = frontendclient.LocalAddress
 )
 
 var (
@@ -54,15 +54,9 @@ var cmdFlags = cmdutils.NewCmdFlags()
 type bodyReader = func(context.Context, io.Reader) (string, error)
 
 func createFrontendURL(projectName string, clusterName string) *url.URL {
-	var frontendURL url.URL
-	if projectName == "" {
-		frontendURL = url.URL{Host: localhostURL, Path: "frontend/api", Scheme: "http"}
-	} else {
-		frontendURL = url.URL{
-			Host:   fmt.Sprintf("www.endpoints.%s.cloud.goog", projectName),
-			Path:   fmt.Sprintf("frontend/client/%s/api", clusterName),
-			Scheme: "https",
-		}
+	frontendURL := frontendclient.LocalURL(localhostURL)
+	if projectName != "" {
+		frontendURL = frontendclient.RelayURL(projectName, clusterName)
 	}
 	return &frontendURL
 }
@@ -70,6 +64,7 @@ func createFrontendURL(projectName string, clusterName string) *url.URL {
 type cmdParams struct {
 	targetType  imageutils.TargetType
 	target      string
+	transferer  imagetransfer.Transferer
 	frontendURL *url.URL
 	follow      bool
 	timestamps  bool
@@ -78,30 +73,27 @@ type cmdParams struct {
 }
 
 func runLogsCmd(ctx context.Context, params *cmdParams, w io.Writer) error {
-	skillID, err := imageutils.SkillIDFromTarget(params.target, params.targetType, imagetransfer.NoOpTransferer{})
+	skillID, err := imageutils.SkillIDFromTarget(params.target, params.targetType, params.transferer)
 	if err != nil {
 		return fmt.Errorf("could not extract a skill id from the given target %s: %w", params.target, err)
 	}
 
 	verboseOut.Write([]byte(fmt.Sprintf("%s\n", params.frontendURL.Path)))
-	tokenURL := *params.frontendURL
-	tokenURL.Path = path.Join(tokenURL.EscapedPath(), "token")
 	authToken, err := getAuthToken(params.projectName)
 	if err != nil {
 		return err
 	}
+	opts := frontendclient.Options{}
+	if authToken != nil {
+		opts.Auth = authToken
+	}
+	frontend := frontendclient.New(*params.frontendURL, opts)
 
-	xsrfToken, err := callEndpoint(ctx, http.MethodGet, &tokenURL, authToken, nil, nil,
-		func(_ context.Context, body io.Reader) (string, error) {
-			token, err := io.ReadAll(body)
-			return string(token), err
-		})
+	xsrfToken, err := frontend.XSRFToken(ctx)
 	if err != nil {
-		return fmt.Errorf("could not obtain xsrf token: %w", err)
+		return err
 	}
 
-	consoleLogsURL := *params.frontendURL
-	consoleLogsURL.Path = path.Join(consoleLogsURL.EscapedPath(), "consoleLogs")
 	consoleLogsQuery := make(url.Values)
 	consoleLogsQuery.Set(paramSkillID, skillID)
 	if params.follow {
@@ -122,9 +114,9 @@ func runLogsCmd(ctx context.Context, params *cmdParams, w io.Writer) error {
 		return fmt.Errorf("cannot parse parameter %s: %w", keySinceSec, err)
 	}
 
-	consoleLogsURL.RawQuery = consoleLogsQuery.Encode()
+	consoleLogsURL := frontend.ConsoleLogsURL(consoleLogsQuery)
 
-	xsrfHeader := http.Header{"X-XSRF-TOKEN": []string{xsrfToken}}
+	xsrfHeader := http.Header{frontendclient.XSRFTokenHeader: []string{xsrfToken}}
 
 	_, err = callEndpoint(ctx, http.MethodGet, &consoleLogsURL, authToken, xsrfHeader, nil,
 		func(_ context.Context, body io.Reader) (string, error) {
@@ -281,9 +273,11 @@ var logsCmd = &cobra.Command{
 			return fmt.Errorf("could not resolve solution to cluster: %s", err)
 		}
 
+		imageutils.SetArchiveCacheEnabled(!cmdFlags.GetFlagNoCache())
 		return runLogsCmd(ctx, &cmdParams{
 			targetType:  imageutils.TargetType(cmdFlags.GetString(cmdutils.KeyType)),
 			target:      target,
+			transferer:  cmdFlags.CreateReadonlyTransferer(ctx),
 			frontendURL: createFrontendURL(project, cluster),
 			follow:      cmdFlags.GetBool(keyFollow),
 			timestamps:  cmdFlags.GetBool(keyTimestamps),
@@ -303,13 +297,15 @@ func init() {
 
 	cmdFlags.RequiredString(cmdutils.KeyType, fmt.Sprintf(`The target's type:
 %s	skill id
-%s	build target of the skill image`, imageutils.ID, imageutils.Build))
+%s	build target of the skill image
+%s	name of an already pushed skill image`, imageutils.ID, imageutils.Build, imageutils.Image))
+	cmdFlags.AddFlagNoCache()
 	cmdFlags.OptionalBool(keyFollow, false, "Whether to follow the skill logs.")
 	cmdFlags.OptionalBool(keyTimestamps, false, "Whether to include timestamps on each log line.")
 	cmdFlags.OptionalInt(keyTailLines, 10, "The number of recent log lines to display. An input number less than 0 shows all log lines.")
 	cmdFlags.OptionalString(keySinceSec, "", "Show logs starting since value. Value is either relative (e.g 10m) or \ndate time in RFC3339 format (e.g: 2006-01-02T15:04:05Z07:00)")
 
-	logsCmd.MarkFlagsMutuallyExclusive(cmdutils.KeyContext, cmdutils.KeySolution)
+	cmdFlags.AddFlagsConflict(cmdutils.KeyContext, cmdutils.KeySolution)
 }
 
 func getAuthToken(project string) (*auth.ProjectToken, error) {
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/v1/google"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
@@ -16,6 +17,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"intrinsic/assets/bundleio"
 	"intrinsic/assets/clientutils"
 	"intrinsic/assets/cmdutils"
 	"intrinsic/assets/idutils"
@@ -32,6 +34,8 @@ import (
 
 const (
 	keyDescription                    = "description"
+	keyRequireTestEvidence            = "require_test_evidence"
+	keyUploadParallelism              = "upload_parallelism"
 )
 
 var cmdFlags = cmdutils.NewCmdFlags()
@@ -109,6 +113,27 @@ func getOutputFiles(target string) ([]string, error) {
 	return strings.Split(strings.TrimSpace(string(out)), "\n"), nil
 }
 
+// checkTestEvidence verifies that the skill archive contains evidence of passing unit tests.
+func checkTestEvidence(target string, targetType string) error {
+	path, err := imageutils.GetImagePath(target, imageutils.TargetType(targetType))
+	if err != nil {
+		return fmt.Errorf("could not find skill archive: %v", err)
+	}
+	evidence, err := bundleio.ReadTestEvidence(path)
+	if errors.Is(err, bundleio.ErrNoTestEvidence) {
+		return fmt.Errorf("skill %q has no test evidence; add test results with //intrinsic/skills/build_defs:add_skill_test_evidence", target)
+	} else if err != nil {
+		return err
+	}
+	if err := evidence.Passed(); err != nil {
+		return fmt.Errorf("skill %q did not pass its tests: %v", target, err)
+	}
+	passed, _, skipped := evidence.Counts()
+	log.Printf("verified test evidence: %d test cases passed, %d skipped (recorded at %s)", passed, skipped, evidence.RecordedAt.Format(time.RFC3339))
+
+	return nil
+}
+
 func namePackageFromID(skillID string) (string, string, error) {
 	name, err := idutils.NameFrom(skillID)
 	if err != nil {
@@ -124,7 +149,11 @@ func namePackageFromID(skillID string) (string, string, error) {
 
 func release(cmd *cobra.Command, conn *grpc.ClientConn, req *skillcatalogpb.CreateSkillRequest, idVersion string) error {
 	client := skillcataloggrpcpb.NewSkillCatalogClient(conn)
-	if _, err := client.CreateSkill(cmd.Context(), req); err != nil {
+	err := clientutils.RetryCatalogCall(cmd.Context(), func() error {
+		_, err := client.CreateSkill(cmd.Context(), req)
+		return err
+	})
+	if err != nil {
 		if s, ok := status.FromError(err); ok && cmdFlags.GetFlagIgnoreExisting() && s.Code() == codes.AlreadyExists {
 			log.Printf("skipping release: skill %q already exists in the catalog", idVersion)
 			return nil
@@ -147,6 +176,9 @@ var releaseExamples = strings.Join(
   $ inctl skill release --type=build //abc:skill.tar ...`,
 		`Upload and release a skill image to the skill catalog:
   $ inctl skill release --type=archive /path/to/skill.tar ...`,
+		`Release a skill only if a smoke process using it succeeds on a staging cluster:
+  $ inctl skill release --type=build //abc:skill.tar --validate_on_cluster=staging-cluster \
+      --validation_org=my-org --validation_process=/path/to/smoke.textproto ...`,
 	},
 	"\n\n",
 )
@@ -161,12 +193,36 @@ var releaseCmd = &cobra.Command{
 		dryRun := cmdFlags.GetFlagDryRun()
 		targetType := cmdFlags.GetFlagSkillReleaseType()
 		project := clientutils.ResolveCatalogProjectFromInctl(cmdFlags)
+		if cmdFlags.GetInt(keyUploadParallelism) < 1 {
+			return fmt.Errorf("--%s must be at least 1", keyUploadParallelism)
+		}
 
 		manifest, err := getManifest()
 		if err != nil {
 			return err
 		}
 
+		// Remote images carry no test evidence, the check only applies to archives.
+		requireTestEvidence := cmdFlags.GetBool(keyRequireTestEvidence)
+		if imageutils.TargetType(targetType) == imageutils.Image {
+			if requireTestEvidence {
+				return fmt.Errorf("--%s cannot be used with --type=%s", keyRequireTestEvidence, imageutils.Image)
+			}
+		} else if err := checkTestEvidence(target, targetType); err != nil {
+			if requireTestEvidence {
+				return err
+			}
+			log.Printf("Warning: %v", err)
+		}
+
+		if cluster := cmdFlags.GetString(keyValidateOnCluster); cluster != "" && dryRun {
+			log.Printf("Skipping validation of skill %q on cluster %q (dry-run)", target, cluster)
+		} else if cluster != "" {
+			if err := validateOnCluster(cmd.Context(), target, targetType, cmd.OutOrStdout()); err != nil {
+				return err
+			}
+		}
+
 		req := &skillcatalogpb.CreateSkillRequest{
 			Manifest:     manifest,
 			Version:      cmdFlags.GetFlagVersion(),
@@ -201,6 +257,7 @@ var releaseCmd = &cobra.Command{
 				opts := []directupload.Option{
 					directupload.WithDiscovery(directupload.NewCatalogTarget(conn)),
 					directupload.WithOutput(cmd.OutOrStdout()),
+					directupload.WithUploadParallelism(cmdFlags.GetInt(keyUploadParallelism)),
 				}
 				transferer = directupload.NewTransferer(cmd.Context(), opts...)
 			}
@@ -261,6 +318,16 @@ func init() {
 	cmdFlags.AddFlagReleaseNotes("skill")
 	cmdFlags.AddFlagSkillReleaseType()
 	cmdFlags.AddFlagVersion("skill")
+	cmdFlags.OptionalBool(keyRequireTestEvidence, false, "Refuse to release the skill without evidence of passing unit tests. "+
+		"Otherwise missing or failing test evidence is only reported as a warning.")
+	cmdFlags.OptionalInt(keyUploadParallelism, 4, "Maximum number of image layers to upload to the catalog at the same time.")
+	cmdFlags.OptionalString(keyValidateOnCluster, "", fmt.Sprintf("Before releasing, install the skill on this cluster and run --%s there. "+
+		"The skill is only released if the process succeeds. The process replaces the active process of the cluster.", keyValidationProcess))
+	cmdFlags.OptionalString(keyValidationOrg, "", fmt.Sprintf("The organization of --%s.", keyValidateOnCluster))
+	cmdFlags.OptionalString(keyValidationProcess, "", fmt.Sprintf("File with the smoke process (behavior tree) to run on --%s, "+
+		"in textproto format or binary proto format if the file ends in .binpb.", keyValidateOnCluster))
+	cmdFlags.OptionalString(keyValidationTimeout, "15m", "Maximum time for installing the skill on the validation cluster and running the process.")
+	cmdFlags.AddFlagsRequiredTogether(keyValidateOnCluster, keyValidationOrg, keyValidationProcess)
 
 
 }
@@ -0,0 +1,201 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package release
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pborman/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"intrinsic/assets/idutils"
+	"intrinsic/assets/imagetransfer"
+	"intrinsic/assets/imageutils"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	imagepb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
+	installerpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
+	"intrinsic/skills/tools/skill/cmd/dialerutil"
+	"intrinsic/skills/tools/skill/cmd/directupload"
+	"intrinsic/skills/tools/skill/cmd/registry"
+	"intrinsic/skills/tools/skill/cmd/waitforskill"
+	"intrinsic/tools/inctl/auth"
+	"intrinsic/tools/inctl/cmd/process"
+)
+
+const (
+	keyValidateOnCluster = "validate_on_cluster"
+	keyValidationOrg     = "validation_org"
+	keyValidationProcess = "validation_process"
+	keyValidationTimeout = "validation_timeout"
+
+	// skillStartTimeout is how long to wait for the sideloaded skill to become available.
+	skillStartTimeout = 5 * time.Minute
+	// waitOperationTimeout is the timeout of a single WaitOperation call.
+	waitOperationTimeout = time.Minute
+)
+
+// processFormat returns the format of 'inctl process set' for the process file at path.
+func processFormat(path string) string {
+	switch filepath.Ext(path) {
+	case ".binpb", ".pb":
+		return process.BinaryProtoFormat
+	default:
+		return process.TextProtoFormat
+	}
+}
+
+// validateOnCluster sideloads the skill in target to the validation cluster, runs the smoke
+// process there and returns an error unless the process succeeds. The smoke process replaces the
+// active process of the cluster.
+func validateOnCluster(ctx context.Context, target string, targetType string, w io.Writer) error {
+	cluster := cmdFlags.GetString(keyValidateOnCluster)
+	org := cmdFlags.GetString(keyValidationOrg)
+	processFile := cmdFlags.GetString(keyValidationProcess)
+	timeout, err := time.ParseDuration(cmdFlags.GetString(keyValidationTimeout))
+	if err != nil {
+		return fmt.Errorf("invalid --%s: %w", keyValidationTimeout, err)
+	}
+	content, err := os.ReadFile(processFile)
+	if err != nil {
+		return fmt.Errorf("could not read the validation process: %w", err)
+	}
+
+	info, err := auth.NewStore().ReadOrgInfo(org)
+	if err != nil {
+		return fmt.Errorf("could not find the project of organization %q, run 'inctl auth login --org %s': %w", org, org, err)
+	}
+	ctx, conn, err := dialerutil.DialConnectionCtx(ctx, dialerutil.DialInfoParams{
+		Cluster:  cluster,
+		CredName: info.Project,
+		CredOrg:  org,
+	})
+	if err != nil {
+		return fmt.Errorf("could not connect to validation cluster %q: %w", cluster, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	idVersion, err := sideloadSkill(ctx, conn, target, targetType, w)
+	if err != nil {
+		return fmt.Errorf("could not install the skill on validation cluster %q: %w", cluster, err)
+	}
+	log.Printf("Running validation process %q on cluster %q", processFile, cluster)
+	if err := process.SetProcess(ctx, conn, processFormat(processFile), content); err != nil {
+		return fmt.Errorf("could not load the validation process: %w", err)
+	}
+	if err := runProcess(ctx, conn); err != nil {
+		return fmt.Errorf("validation of skill %q on cluster %q failed: %w", idVersion, cluster, err)
+	}
+	log.Printf("Validation of skill %q on cluster %q passed", idVersion, cluster)
+	return nil
+}
+
+// sideloadSkill installs the skill in target like 'inctl skill install' and waits until it is
+// available. Returns the id_version of the installed skill.
+func sideloadSkill(ctx context.Context, conn *grpc.ClientConn, target string, targetType string, w io.Writer) (string, error) {
+	// Images in a registry cannot be uploaded directly, the cluster pulls them.
+	var transfer imagetransfer.Transferer
+	reg := ""
+	if imageutils.TargetType(targetType) == imageutils.Image {
+		transfer = imagetransfer.RemoteTransferer(remote.WithContext(ctx), remoteOpt())
+	} else {
+		transfer = directupload.NewTransferer(ctx,
+			directupload.WithDiscovery(directupload.NewFromConnection(conn)),
+			directupload.WithOutput(w))
+		reg = "direct.upload.local"
+	}
+	imgpb, installerParams, err := registry.PushSkill(target, registry.PushOptions{
+		Registry:   reg,
+		Type:       targetType,
+		Transferer: transfer,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not push target %q: %w", target, err)
+	}
+
+	pkg, err := idutils.PackageFrom(installerParams.SkillID)
+	if err != nil {
+		return "", fmt.Errorf("could not parse package from ID: %w", err)
+	}
+	name, err := idutils.NameFrom(installerParams.SkillID)
+	if err != nil {
+		return "", fmt.Errorf("could not parse name from ID: %w", err)
+	}
+	version := fmt.Sprintf("0.0.1+%s", uuid.New())
+	idVersion, err := idutils.IDVersionFrom(pkg, name, version)
+	if err != nil {
+		return "", fmt.Errorf("could not create id_version: %w", err)
+	}
+
+	log.Printf("Installing skill %q for validation", idVersion)
+	if err := imageutils.InstallContainer(ctx, &imageutils.InstallContainerParams{
+		Connection: conn,
+		Request: &installerpb.InstallContainerAddonRequest{
+			Id:      installerParams.SkillID,
+			Version: version,
+			Type:    installerpb.AddonType_ADDON_TYPE_SKILL,
+			Images:  []*imagepb.Image{imgpb},
+		},
+	}); err != nil {
+		return "", err
+	}
+	if err := waitforskill.WaitForSkill(ctx, &waitforskill.Params{
+		Connection:     conn,
+		SkillID:        installerParams.SkillID,
+		SkillIDVersion: idVersion,
+		WaitDuration:   skillStartTimeout,
+	}); err != nil {
+		return "", fmt.Errorf("failed waiting for skill: %w", err)
+	}
+	return idVersion, nil
+}
+
+// runProcess starts the operation of the process loaded into the executive and waits until it is
+// done. Returns an error unless the process succeeded. The operation is cancelled if ctx expires.
+func runProcess(ctx context.Context, conn *grpc.ClientConn) error {
+	client := execgrpcpb.NewExecutiveServiceClient(conn)
+	ops, err := client.ListOperations(ctx, &lrpb.ListOperationsRequest{})
+	if err != nil {
+		return fmt.Errorf("unable to list executive operations: %w", err)
+	}
+	if len(ops.GetOperations()) != 1 {
+		return fmt.Errorf("expected one executive operation, found %d", len(ops.GetOperations()))
+	}
+	name := ops.GetOperations()[0].GetName()
+
+	op, err := client.StartOperation(ctx, &execgrpcpb.StartOperationRequest{Name: name})
+	if err != nil {
+		return fmt.Errorf("unable to start the process: %w", err)
+	}
+	for !op.GetDone() {
+		op, err = client.WaitOperation(ctx, &lrpb.WaitOperationRequest{
+			Name:    name,
+			Timeout: durationpb.New(waitOperationTimeout),
+		})
+		if ctx.Err() != nil {
+			// Do not leave the process running on the cluster.
+			if _, err := client.CancelOperation(context.WithoutCancel(ctx), &lrpb.CancelOperationRequest{Name: name}); err != nil {
+				log.Printf("Warning: could not cancel executive operation: %v", err)
+			}
+			return fmt.Errorf("the process did not finish in time: %w", ctx.Err())
+		}
+		if err != nil {
+			return fmt.Errorf("unable to wait for the process: %w", err)
+		}
+	}
+	if op.GetError() != nil {
+		return fmt.Errorf("the process failed: %w", status.ErrorProto(op.GetError()))
+	}
+	return nil
+}
@@ -5,25 +5,265 @@ package solutionutil
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	clusterdiscoverygrpcpb "intrinsic/frontend/cloud/api/clusterdiscovery_api_go_grpc_proto"
-	"intrinsic/tools/inctl/cmd/solution"
+	solutiondiscoverygrpcpb "intrinsic/frontend/cloud/api/solutiondiscovery_api_go_grpc_proto"
 )
 
-// GetClusterNameFromSolution returns the cluster in which a solution currently runs.
-func GetClusterNameFromSolution(ctx context.Context, conn *grpc.ClientConn, solutionName string) (string, error) {
-	solution, err := solution.GetSolution(ctx, conn, solutionName)
+const (
+	// lastSeenFileName is the file in the user cache directory in which the
+	// clusters solutions were last seen on are recorded.
+	lastSeenFileName = "intrinsic/inctl/solution_clusters.json"
+)
+
+var (
+	// ErrSolutionNotFound is returned if no solution matches the given name.
+	ErrSolutionNotFound = errors.New("solution not found")
+	// ErrSolutionNotRunning is returned if the solution is not deployed to any
+	// cluster.
+	ErrSolutionNotRunning = errors.New("solution is not running")
+	// ErrAmbiguousSolution is returned if a display name matches more than one
+	// solution.
+	ErrAmbiguousSolution = errors.New("solution name is ambiguous")
+)
+
+// ResolutionError describes why a solution could not be resolved to a cluster.
+// It wraps one of the errors above, or the error returned by the solution
+// discovery service.
+type ResolutionError struct {
+	// Solution is the solution name as given by the user.
+	Solution string
+	// State is the state of the solution if it is known.
+	State clusterdiscoverygrpcpb.SolutionState
+	// LastSeenCluster is the cluster the solution was running on when it was
+	// last resolved successfully, if known.
+	LastSeenCluster string
+	// LastSeen is the time at which the solution was last resolved successfully.
+	LastSeen time.Time
+	// Candidates are the names of all solutions matching an ambiguous name.
+	Candidates []string
+
+	err error
+}
+
+func (e *ResolutionError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "could not resolve solution %q: %v", e.Solution, e.err)
+	if e.State != clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_UNSPECIFIED {
+		fmt.Fprintf(&sb, " (state: %s)", e.State)
+	}
+	if len(e.Candidates) > 0 {
+		fmt.Fprintf(&sb, "; matching solutions: %s", strings.Join(e.Candidates, ", "))
+	}
+	if e.LastSeenCluster != "" {
+		fmt.Fprintf(&sb, "; it was last seen running on cluster %q at %s", e.LastSeenCluster, e.LastSeen.Format(time.RFC3339))
+	}
+	return sb.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *ResolutionError) Unwrap() error {
+	return e.err
+}
+
+type solutionDiscoveryClient interface {
+	GetSolutionDescription(ctx context.Context, in *solutiondiscoverygrpcpb.GetSolutionDescriptionRequest, opts ...grpc.CallOption) (*solutiondiscoverygrpcpb.GetSolutionDescriptionResponse, error)
+	ListSolutionDescriptions(ctx context.Context, in *solutiondiscoverygrpcpb.ListSolutionDescriptionsRequest, opts ...grpc.CallOption) (*solutiondiscoverygrpcpb.ListSolutionDescriptionsResponse, error)
+}
+
+type lastSeenEntry struct {
+	Cluster string    `json:"cluster"`
+	Time    time.Time `json:"time"`
+}
+
+// Resolver resolves solutions to the clusters they run on. Successful
+// resolutions are cached for the lifetime of the resolver, so that commands
+// which resolve the same solution several times only query the solution
+// discovery service once.
+type Resolver struct {
+	mu    sync.Mutex
+	cache map[string]*solutiondiscoverygrpcpb.SolutionDescription
+	// lastSeenPath is the file in which successful resolutions are recorded to
+	// improve error messages later on. Empty disables recording.
+	lastSeenPath string
+}
+
+// NewResolver creates a new Resolver with an empty cache.
+func NewResolver() *Resolver {
+	r := &Resolver{cache: make(map[string]*solutiondiscoverygrpcpb.SolutionDescription)}
+	if dir, err := os.UserCacheDir(); err == nil {
+		r.lastSeenPath = filepath.Join(dir, lastSeenFileName)
+	}
+	return r
+}
+
+// defaultResolver is shared by all commands of a single inctl invocation.
+var defaultResolver = NewResolver()
+
+// DefaultResolver returns the resolver used by the package level functions.
+func DefaultResolver() *Resolver {
+	return defaultResolver
+}
+
+// Resolve returns the description of the given running solution. The solution
+// is looked up by its name first and by its display name otherwise. Errors are
+// of type *ResolutionError.
+func (r *Resolver) Resolve(ctx context.Context, conn *grpc.ClientConn, solutionName string) (*solutiondiscoverygrpcpb.SolutionDescription, error) {
+	return r.resolve(ctx, solutiondiscoverygrpcpb.NewSolutionDiscoveryServiceClient(conn), solutionName)
+}
+
+func (r *Resolver) resolve(ctx context.Context, client solutionDiscoveryClient, solutionName string) (*solutiondiscoverygrpcpb.SolutionDescription, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[solutionName]
+	r.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	solution, err := lookupSolution(ctx, client, solutionName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get solution: %w", err)
+		return nil, r.newError(solutionName, nil, err)
 	}
 	if solution.GetState() == clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_NOT_RUNNING {
-		return "", fmt.Errorf("solution is not running")
+		return nil, r.newError(solutionName, solution, ErrSolutionNotRunning)
 	}
 	if solution.GetClusterName() == "" {
-		return "", fmt.Errorf("unknown error: solution is running but cluster is empty")
+		return nil, r.newError(solutionName, solution, errors.New("unknown error: solution is running but cluster is empty"))
+	}
+
+	r.mu.Lock()
+	r.cache[solutionName] = solution
+	r.mu.Unlock()
+	r.recordLastSeen(solution)
+	return solution, nil
+}
+
+// lookupSolution finds the solution with the given name or, if there is none,
+// the only solution with the given display name.
+func lookupSolution(ctx context.Context, client solutionDiscoveryClient, solutionName string) (*solutiondiscoverygrpcpb.SolutionDescription, error) {
+	resp, err := client.GetSolutionDescription(ctx, &solutiondiscoverygrpcpb.GetSolutionDescriptionRequest{Name: solutionName})
+	if err == nil {
+		return resp.GetSolution(), nil
+	}
+	if status.Code(err) != codes.NotFound {
+		return nil, fmt.Errorf("failed to get solution description: %w", err)
+	}
+
+	list, err := client.ListSolutionDescriptions(ctx, &solutiondiscoverygrpcpb.ListSolutionDescriptionsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list solutions: %w", err)
+	}
+	var matches []*solutiondiscoverygrpcpb.SolutionDescription
+	for _, s := range list.GetSolutions() {
+		if s.GetDisplayName() == solutionName {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, ErrSolutionNotFound
+	case 1:
+		return matches[0], nil
+	}
+	// Prefer the only running solution if the display name is ambiguous.
+	var running []*solutiondiscoverygrpcpb.SolutionDescription
+	for _, s := range matches {
+		if s.GetState() != clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_NOT_RUNNING {
+			running = append(running, s)
+		}
+	}
+	if len(running) == 1 {
+		return running[0], nil
+	}
+	return nil, &ambiguousError{matches: matches}
+}
+
+// ambiguousError carries the candidates of an ambiguous display name.
+type ambiguousError struct {
+	matches []*solutiondiscoverygrpcpb.SolutionDescription
+}
+
+func (e *ambiguousError) Error() string {
+	return ErrAmbiguousSolution.Error()
+}
+
+func (e *ambiguousError) Unwrap() error {
+	return ErrAmbiguousSolution
+}
+
+func (r *Resolver) newError(solutionName string, solution *solutiondiscoverygrpcpb.SolutionDescription, err error) *ResolutionError {
+	resErr := &ResolutionError{
+		Solution: solutionName,
+		State:    solution.GetState(),
+		err:      err,
+	}
+	var ambiguous *ambiguousError
+	if errors.As(err, &ambiguous) {
+		for _, s := range ambiguous.matches {
+			resErr.Candidates = append(resErr.Candidates, fmt.Sprintf("%s (%s)", s.GetName(), s.GetState()))
+		}
+		sort.Strings(resErr.Candidates)
+	}
+	name := solutionName
+	if solution.GetName() != "" {
+		name = solution.GetName()
+	}
+	if entry, ok := r.readLastSeen()[name]; ok {
+		resErr.LastSeenCluster = entry.Cluster
+		resErr.LastSeen = entry.Time
+	}
+	return resErr
+}
+
+func (r *Resolver) readLastSeen() map[string]lastSeenEntry {
+	entries := make(map[string]lastSeenEntry)
+	if r.lastSeenPath == "" {
+		return entries
+	}
+	content, err := os.ReadFile(r.lastSeenPath)
+	if err != nil {
+		return entries
+	}
+	// A corrupt file only means that no last seen information is available.
+	json.Unmarshal(content, &entries)
+	return entries
+}
+
+// recordLastSeen stores the cluster the solution runs on. Failures are ignored
+// as the information is only used for diagnostics.
+func (r *Resolver) recordLastSeen(solution *solutiondiscoverygrpcpb.SolutionDescription) {
+	if r.lastSeenPath == "" {
+		return
+	}
+	entries := r.readLastSeen()
+	entries[solution.GetName()] = lastSeenEntry{Cluster: solution.GetClusterName(), Time: time.Now()}
+	content, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(r.lastSeenPath), 0755); err != nil {
+		return
+	}
+	os.WriteFile(r.lastSeenPath, content, 0644)
+}
+
+// GetClusterNameFromSolution returns the cluster in which a solution currently runs.
+func GetClusterNameFromSolution(ctx context.Context, conn *grpc.ClientConn, solutionName string) (string, error) {
+	solution, err := defaultResolver.Resolve(ctx, conn, solutionName)
+	if err != nil {
+		return "", err
 	}
 	return solution.GetClusterName(), nil
 }
@@ -32,12 +272,7 @@ func GetClusterNameFromSolution(ctx context.Context, conn *grpc.ClientConn, solu
 // return default otherwise.
 func GetClusterNameFromSolutionOrDefault(ctx context.Context, conn *grpc.ClientConn, solutionName string, defaultCluster string) (string, error) {
 	if solutionName != "" {
-		cluster, err := GetClusterNameFromSolution(ctx, conn, solutionName)
-		if err != nil {
-			return "", fmt.Errorf("could not resolve context from solution '%s'"+
-				"(please check if the solution is currently running): %w", solutionName, err)
-		}
-		return cluster, nil
+		return GetClusterNameFromSolution(ctx, conn, solutionName)
 	}
 	if defaultCluster == "" {
 		return "", errors.New("solution name and default cluster are empty - set exactly one of them")
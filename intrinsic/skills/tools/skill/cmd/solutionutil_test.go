@@ -0,0 +1,110 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package solutionutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	clusterdiscoverygrpcpb "intrinsic/frontend/cloud/api/clusterdiscovery_api_go_grpc_proto"
+	solutiondiscoverygrpcpb "intrinsic/frontend/cloud/api/solutiondiscovery_api_go_grpc_proto"
+)
+
+type fakeSolutionDiscoveryClient struct {
+	solutions []*solutiondiscoverygrpcpb.SolutionDescription
+	getCalls  int
+}
+
+func (c *fakeSolutionDiscoveryClient) GetSolutionDescription(ctx context.Context, in *solutiondiscoverygrpcpb.GetSolutionDescriptionRequest, opts ...grpc.CallOption) (*solutiondiscoverygrpcpb.GetSolutionDescriptionResponse, error) {
+	c.getCalls++
+	for _, s := range c.solutions {
+		if s.GetName() == in.GetName() {
+			return &solutiondiscoverygrpcpb.GetSolutionDescriptionResponse{Solution: s}, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "solution %q not found", in.GetName())
+}
+
+func (c *fakeSolutionDiscoveryClient) ListSolutionDescriptions(ctx context.Context, in *solutiondiscoverygrpcpb.ListSolutionDescriptionsRequest, opts ...grpc.CallOption) (*solutiondiscoverygrpcpb.ListSolutionDescriptionsResponse, error) {
+	return &solutiondiscoverygrpcpb.ListSolutionDescriptionsResponse{Solutions: c.solutions}, nil
+}
+
+func newTestResolver(t *testing.T) *Resolver {
+	t.Helper()
+	r := NewResolver()
+	r.lastSeenPath = t.TempDir() + "/solution_clusters.json"
+	return r
+}
+
+func TestResolve(t *testing.T) {
+	client := &fakeSolutionDiscoveryClient{solutions: []*solutiondiscoverygrpcpb.SolutionDescription{
+		{Name: "sol-1", DisplayName: "Pick and place", State: clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_RUNNING_ON_HW, ClusterName: "cluster-1"},
+		{Name: "sol-2", DisplayName: "Pick and place", State: clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_RUNNING_IN_SIM, ClusterName: "cluster-2"},
+		{Name: "sol-3", DisplayName: "Stopped", State: clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_NOT_RUNNING},
+		{Name: "sol-4", DisplayName: "Stopped", State: clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_RUNNING_IN_SIM, ClusterName: "cluster-4"},
+	}}
+
+	tests := []struct {
+		name        string
+		solution    string
+		wantCluster string
+		wantErr     error
+	}{
+		{name: "by name", solution: "sol-1", wantCluster: "cluster-1"},
+		{name: "by unique running display name", solution: "Stopped", wantCluster: "cluster-4"},
+		{name: "ambiguous display name", solution: "Pick and place", wantErr: ErrAmbiguousSolution},
+		{name: "not running", solution: "sol-3", wantErr: ErrSolutionNotRunning},
+		{name: "not found", solution: "unknown", wantErr: ErrSolutionNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := newTestResolver(t).resolve(context.Background(), client, tc.solution)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("resolve(%q) returned error %v, want %v", tc.solution, err, tc.wantErr)
+			}
+			if err != nil {
+				var resErr *ResolutionError
+				if !errors.As(err, &resErr) {
+					t.Errorf("resolve(%q) returned error of type %T, want *ResolutionError", tc.solution, err)
+				}
+				return
+			}
+			if got.GetClusterName() != tc.wantCluster {
+				t.Errorf("resolve(%q) = %q, want %q", tc.solution, got.GetClusterName(), tc.wantCluster)
+			}
+		})
+	}
+}
+
+func TestResolveCachesAndRecordsLastSeen(t *testing.T) {
+	solution := &solutiondiscoverygrpcpb.SolutionDescription{
+		Name: "sol-1", State: clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_RUNNING_ON_HW, ClusterName: "cluster-1"}
+	client := &fakeSolutionDiscoveryClient{solutions: []*solutiondiscoverygrpcpb.SolutionDescription{solution}}
+	r := newTestResolver(t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.resolve(context.Background(), client, "sol-1"); err != nil {
+			t.Fatalf("resolve() failed: %v", err)
+		}
+	}
+	if client.getCalls != 1 {
+		t.Errorf("GetSolutionDescription called %d times, want 1", client.getCalls)
+	}
+
+	// A new resolver does not share the cache but knows where the solution ran.
+	solution.State = clusterdiscoverygrpcpb.SolutionState_SOLUTION_STATE_NOT_RUNNING
+	solution.ClusterName = ""
+	r2 := &Resolver{cache: make(map[string]*solutiondiscoverygrpcpb.SolutionDescription), lastSeenPath: r.lastSeenPath}
+	_, err := r2.resolve(context.Background(), client, "sol-1")
+	var resErr *ResolutionError
+	if !errors.As(err, &resErr) {
+		t.Fatalf("resolve() returned error %v, want *ResolutionError", err)
+	}
+	if resErr.LastSeenCluster != "cluster-1" {
+		t.Errorf("LastSeenCluster = %q, want %q", resErr.LastSeenCluster, "cluster-1")
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package test defines the skill command which executes a skill on a cluster for smoke tests.
+package test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	bcpb "intrinsic/executive/proto/behavior_call_go_proto"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	bbgrpcpb "intrinsic/executive/proto/blackboard_service_go_grpc_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	srgrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+	"intrinsic/skills/tools/skill/cmd"
+	"intrinsic/util/proto/registryutil"
+	"intrinsic/util/status/extstatus"
+)
+
+const (
+	keyParams         = "params"
+	keyReplaceProcess = "replace_process"
+	keyTestTimeout    = "test_timeout"
+
+	// returnValueKey is the blackboard key the return value of the skill is written to.
+	returnValueKey = "skill_test_result"
+	// waitInterval is the longest time a single WaitOperation request blocks.
+	waitInterval = 10 * time.Second
+)
+
+var cmdFlags = cmdutils.NewCmdFlags()
+
+// parameters returns the parameters of skill read from the textproto file at path, or the default
+// parameters of the skill if path is empty.
+func parameters(skill *skillspb.Skill, path string) (*anypb.Any, error) {
+	desc := skill.GetParameterDescription()
+	if desc == nil {
+		if path != "" {
+			return nil, fmt.Errorf("skill %q has no parameters", skill.GetId())
+		}
+		return nil, nil
+	}
+	if path == "" {
+		return desc.GetDefaultValue(), nil
+	}
+	types, err := registryutil.NewTypesFromFileDescriptorSet(desc.GetParameterDescriptorFileset())
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameter descriptors of skill %q: %w", skill.GetId(), err)
+	}
+	mt, err := types.FindMessageByName(protoreflect.FullName(desc.GetParameterMessageFullName()))
+	if err != nil {
+		return nil, fmt.Errorf("parameter message of skill %q not found: %w", skill.GetId(), err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read parameters: %w", err)
+	}
+	params := mt.New().Interface()
+	if err := (prototext.UnmarshalOptions{Resolver: types}).Unmarshal(content, params); err != nil {
+		return nil, fmt.Errorf("could not parse %s as %s: %w", path, desc.GetParameterMessageFullName(), err)
+	}
+	return anypb.New(params)
+}
+
+// testTree returns a process which calls the skill once and writes its return value to the
+// blackboard.
+func testTree(skillID string, params *anypb.Any) *btpb.BehaviorTree {
+	name := "inctl skill test " + skillID
+	return &btpb.BehaviorTree{
+		Name: name,
+		Root: &btpb.BehaviorTree_Node{
+			Name: proto.String(name),
+			NodeType: &btpb.BehaviorTree_Node_Task{
+				Task: &btpb.BehaviorTree_TaskNode{
+					TaskType: &btpb.BehaviorTree_TaskNode_CallBehavior{
+						CallBehavior: &bcpb.BehaviorCall{
+							SkillId:         skillID,
+							Parameters:      params,
+							ReturnValueName: returnValueKey,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// clearExecutive deletes the operations of the executive, e.g., the loaded process, if replace is
+// set and fails otherwise, so that a test does not silently discard a process.
+func clearExecutive(ctx context.Context, client execgrpcpb.ExecutiveServiceClient, replace bool) error {
+	resp, err := client.ListOperations(ctx, &lrpb.ListOperationsRequest{})
+	if err != nil {
+		return fmt.Errorf("could not list executive operations: %w", err)
+	}
+	if len(resp.GetOperations()) == 0 {
+		return nil
+	}
+	if !replace {
+		return fmt.Errorf("a process is loaded into the executive, use --%s to replace it", keyReplaceProcess)
+	}
+	if err := cmdFlags.Confirm("Delete the process loaded into the executive"); err != nil {
+		return err
+	}
+	for _, op := range resp.GetOperations() {
+		if _, err := client.DeleteOperation(ctx, &lrpb.DeleteOperationRequest{Name: op.GetName()}); err != nil {
+			return fmt.Errorf("could not delete operation %q: %w", op.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// waitDone waits until the operation is done or ctx expires.
+func waitDone(ctx context.Context, client execgrpcpb.ExecutiveServiceClient, name string) (*lrpb.Operation, error) {
+	for {
+		op, err := client.WaitOperation(ctx, &lrpb.WaitOperationRequest{
+			Name:    name,
+			Timeout: durationpb.New(waitInterval),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not wait for the skill: %w", err)
+		}
+		if op.GetDone() {
+			return op, nil
+		}
+	}
+}
+
+// formatError formats the error of a failed operation, including its extended status if any.
+func formatError(op *lrpb.Operation) string {
+	err := status.ErrorProto(op.GetError())
+	es, esErr := extstatus.FromGRPCError(err)
+	if esErr != nil {
+		return err.Error()
+	}
+	return strings.TrimSpace(prototext.MarshalOptions{Multiline: true}.Format(es.Proto()))
+}
+
+// returnValue reads the return value of the skill from the blackboard of the operation.
+func returnValue(ctx context.Context, conn *grpc.ClientConn, skill *skillspb.Skill, operation string) (string, error) {
+	desc := skill.GetReturnValueDescription()
+	if desc == nil {
+		return "", nil
+	}
+	value, err := bbgrpcpb.NewExecutiveBlackboardClient(conn).GetBlackboardValue(ctx, &bbgrpcpb.GetBlackboardValueRequest{
+		Key:           returnValueKey,
+		OperationName: operation,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not read the return value: %w", err)
+	}
+	types, err := registryutil.NewTypesFromFileDescriptorSet(desc.GetDescriptorFileset())
+	if err != nil {
+		return "", fmt.Errorf("invalid return value descriptors of skill %q: %w", skill.GetId(), err)
+	}
+	return strings.TrimSpace(prototext.MarshalOptions{Multiline: true, Resolver: types}.Format(value.GetValue())), nil
+}
+
+var testCmd = &cobra.Command{
+	Use:   "test SKILL_ID",
+	Short: "Execute an installed skill on a cluster",
+	Long: `Executes an installed skill once with the given parameters and reports its return value or
+the extended status it failed with, e.g., for smoke tests on hardware.
+
+The skill is executed by the executive, which must not have a process loaded unless
+--replace_process is set. The parameters are read from a textproto file of the parameter message of
+the skill. The default parameters of the skill are used if no file is given.`,
+	Example: `Execute a skill with the parameters in params.textproto
+$ inctl skill test ai.intrinsic.move_robot --params=params.textproto --cluster=my-cluster --org=my-org
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(command *cobra.Command, args []string) error {
+		skillID := args[0]
+		timeout, err := time.ParseDuration(cmdFlags.GetString(keyTestTimeout))
+		if err != nil {
+			return fmt.Errorf("invalid value passed for --%s: %w", keyTestTimeout, err)
+		}
+
+		ctx, conn, _, err := clientutils.DialClusterFromInctl(command.Context(), cmdFlags)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		resp, err := srgrpcpb.NewSkillRegistryClient(conn).GetSkill(ctx, &srgrpcpb.GetSkillRequest{Id: skillID})
+		if err != nil {
+			return fmt.Errorf("could not get skill %q: %w", skillID, err)
+		}
+		skill := resp.GetSkill()
+		params, err := parameters(skill, cmdFlags.GetString(keyParams))
+		if err != nil {
+			return err
+		}
+
+		client := execgrpcpb.NewExecutiveServiceClient(conn)
+		if err := clearExecutive(ctx, client, cmdFlags.GetBool(keyReplaceProcess)); err != nil {
+			return err
+		}
+		op, err := client.CreateOperation(ctx, &execgrpcpb.CreateOperationRequest{
+			RunnableType: &execgrpcpb.CreateOperationRequest_BehaviorTree{
+				BehaviorTree: testTree(skill.GetId(), params),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not create executive operation: %w", err)
+		}
+		name := op.GetName()
+		defer func() {
+			if _, err := client.DeleteOperation(ctx, &lrpb.DeleteOperationRequest{Name: name}); err != nil {
+				log.Printf("Warning: could not delete executive operation %q: %v", name, err)
+			}
+		}()
+
+		log.Printf("Executing skill %q", skill.GetIdVersion())
+		start := time.Now()
+		if _, err := client.StartOperation(ctx, &execgrpcpb.StartOperationRequest{Name: name}); err != nil {
+			return fmt.Errorf("could not start executive operation: %w", err)
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		op, err = waitDone(waitCtx, client, name)
+		if err != nil {
+			if waitCtx.Err() != nil {
+				if _, err := client.CancelOperation(ctx, &lrpb.CancelOperationRequest{Name: name}); err != nil {
+					log.Printf("Warning: could not cancel executive operation: %v", err)
+				}
+				return fmt.Errorf("skill did not finish within %s", timeout)
+			}
+			return err
+		}
+		elapsed := time.Since(start).Round(time.Millisecond)
+
+		if op.GetError() != nil {
+			return fmt.Errorf("skill failed after %s:\n%s", elapsed, formatError(op))
+		}
+		value, err := returnValue(ctx, conn, skill, name)
+		if err != nil {
+			return err
+		}
+		log.Printf("Skill succeeded after %s", elapsed)
+		if value != "" {
+			fmt.Println(value)
+		}
+		return nil
+	},
+}
+
+func init() {
+	cmd.SkillCmd.AddCommand(testCmd)
+	cmdFlags.SetCommand(testCmd)
+
+	cmdFlags.AddFlagsAddressClusterSolution()
+	cmdFlags.AddFlagsProjectOrg()
+	cmdFlags.AddFlagYes()
+	cmdFlags.OptionalString(keyParams, "", "Textproto file with the parameters of the skill. Defaults to the default parameters of the skill.")
+	cmdFlags.OptionalBool(keyReplaceProcess, false, "Delete the process loaded into the executive to execute the skill.")
+	cmdFlags.OptionalString(keyTestTimeout, "5m", "Maximum time to wait for the skill to finish before canceling it.")
+}
@@ -1,6 +1,6 @@
 // Copyright 2023 Intrinsic Innovation LLC
 
-// Package waitforskill provides helpers to wait for skills to be available.
+// Package waitforskill provides helpers to wait for skills to be available or removed.
 package waitforskill
 
 import (
@@ -29,16 +29,26 @@ type Params struct {
 	SkillIDVersion string
 	// How long WaitForSkill should wait.
 	WaitDuration time.Duration
+	// How long to wait between polls of the skill registry. Defaults to one second.
+	PollInterval time.Duration
 }
 
+const defaultPollInterval = 1 * time.Second
+
 // TimeoutError is returned when [WaitForSkill] times out with its configured deadline. It contains
 // (but does not wrap!) the last error received from the skill registry.
 type TimeoutError struct {
 	ElapsedTime time.Duration
 	LastErr     error
+
+	// removal is set if the error was returned by WaitForSkillRemoval.
+	removal bool
 }
 
 func (e *TimeoutError) Error() string {
+	if e.removal {
+		return fmt.Sprintf("timed out after %q. Skill is still registered, it may still be stopping.", e.ElapsedTime)
+	}
 	lastErr := "n/a"
 	if e.LastErr != nil {
 		lastErr = e.LastErr.Error()
@@ -48,54 +58,111 @@ func (e *TimeoutError) Error() string {
 			"Last known error: %v", e.ElapsedTime, lastErr)
 }
 
-// WaitForSkill polls the skill registry until matching skill is found.
-func WaitForSkill(ctx context.Context, params *Params) error {
+// poll queries the skill registry for params.SkillID until done reports that the wait is over or
+// returns an error. done is called with the result of each query, unless ctx was canceled or
+// params.WaitDuration has passed. removal is passed on to the TimeoutError.
+func poll(ctx context.Context, params *Params, removal bool, done func(*srgrpcpb.GetSkillResponse, error) (bool, error)) error {
+	pollInterval := params.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, params.WaitDuration)
+	defer cancel()
+	deadline, _ := waitCtx.Deadline()
 
-	var client srgrpcpb.SkillRegistryClient
-	if params.Client != nil {
-		client = params.Client
-	} else {
+	client := params.Client
+	if client == nil {
 		client = srgrpcpb.NewSkillRegistryClient(params.Connection)
 	}
 	start := time.Now()
+	var lastErr error
 	for {
-		res, err := client.GetSkill(ctx, &srgrpcpb.GetSkillRequest{
+		res, err := client.GetSkill(waitCtx, &srgrpcpb.GetSkillRequest{
 			Id: params.SkillID,
 		})
-		if err == nil {
-			if params.SkillIDVersion == "" || res.GetSkill().GetIdVersion() == params.SkillIDVersion {
-				break
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("stopped waiting for skill %q: %w", params.SkillID, ctx.Err())
+		} else if err != nil && !time.Now().Before(deadline) {
+			// gRPC can fail the request at the deadline before waitCtx reports it. Report the last
+			// answer of the registry rather than the error of the cut off request.
+			if lastErr == nil {
+				lastErr = err
 			}
-			// If we reach this point, it means that another version of the skill is (still) running.
-		} else {
-			grpcStatus, ok := status.FromError(err)
+			return &TimeoutError{ElapsedTime: time.Since(start), LastErr: lastErr, removal: removal}
+		}
+		lastErr = err
+		if finished, doneErr := done(res, err); doneErr != nil {
+			return doneErr
+		} else if finished {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for skill %q: %w", params.SkillID, ctx.Err())
+		case <-waitCtx.Done():
+			return &TimeoutError{ElapsedTime: time.Since(start), LastErr: lastErr, removal: removal}
+		case <-time.After(pollInterval):
+		}
+	}
+}
 
-			if !ok {
-				return fmt.Errorf("querying skill registry failed: %w", err)
-			}
+// WaitForSkill polls the skill registry until matching skill is found.
+//
+// It returns a TimeoutError once params.WaitDuration has passed, including if a request to the
+// registry hangs, and the error of ctx if ctx is canceled first.
+func WaitForSkill(ctx context.Context, params *Params) error {
+	return poll(ctx, params, false, func(res *srgrpcpb.GetSkillResponse, err error) (bool, error) {
+		if err == nil {
+			// If the version does not match, another version of the skill is (still) running.
+			return params.SkillIDVersion == "" || res.GetSkill().GetIdVersion() == params.SkillIDVersion, nil
+		}
+		grpcStatus, ok := status.FromError(err)
+		if !ok {
+			return false, fmt.Errorf("querying skill registry failed: %w", err)
+		}
 
-			// Catch certain error codes and either retry or return an error message with a helpful hint.
-			switch grpcStatus.Code() {
-			case codes.Unimplemented:
-				// Ingress will return Unimplemented if no skill registry is running as part of a solution.
-				// Retry because it might not be running yet.
-			case codes.NotFound:
-				// Wait and retry because skill is not registered yet.
-			case codes.Unavailable:
-				// Wait and retry, likely due to one of:
-				// - Connection error: The skill registry is not reachable, possibly a transient error (e.g.
-				//   because of rate-limiting in the Ingress).
-				// - Server error: E.g., the skill is already registered but not available yet because the
-				//   skill's container is currently starting.
-			default:
-				return fmt.Errorf("wait failed with grpc error: %w", err)
-			}
+		// Catch certain error codes and either retry or return an error message with a helpful hint.
+		switch grpcStatus.Code() {
+		case codes.Unimplemented:
+			// Ingress will return Unimplemented if no skill registry is running as part of a solution.
+			// Retry because it might not be running yet.
+		case codes.NotFound:
+			// Wait and retry because skill is not registered yet.
+		case codes.Unavailable:
+			// Wait and retry, likely due to one of:
+			// - Connection error: The skill registry is not reachable, possibly a transient error (e.g.
+			//   because of rate-limiting in the Ingress).
+			// - Server error: E.g., the skill is already registered but not available yet because the
+			//   skill's container is currently starting.
+		default:
+			return false, fmt.Errorf("wait failed with grpc error: %w", err)
+		}
+		return false, nil
+	})
+}
+
+// WaitForSkillRemoval polls the skill registry until the skill is no longer registered. If
+// params.SkillIDVersion is non-empty, it only waits until that version is no longer registered.
+//
+// Use it after removing a skill to wait until the cluster has converged. Like WaitForSkill, it
+// returns a TimeoutError once params.WaitDuration has passed and the error of ctx if ctx is canceled
+// first.
+func WaitForSkillRemoval(ctx context.Context, params *Params) error {
+	return poll(ctx, params, true, func(res *srgrpcpb.GetSkillResponse, err error) (bool, error) {
+		if err == nil {
+			// The skill is still registered, unless another version replaced it.
+			return params.SkillIDVersion != "" && res.GetSkill().GetIdVersion() != params.SkillIDVersion, nil
 		}
-		timeSince := time.Since(start)
-		if timeSince > params.WaitDuration {
-			return &TimeoutError{ElapsedTime: timeSince, LastErr: err}
+		switch status.Code(err) {
+		case codes.NotFound:
+			return true, nil
+		case codes.Unimplemented, codes.Unavailable:
+			// Wait and retry, the skill registry is not reachable right now (see WaitForSkill).
+		case codes.DeadlineExceeded:
+			// The request ran into the deadline of the wait, which is reported as a TimeoutError.
+		default:
+			return false, fmt.Errorf("wait failed with grpc error: %w", err)
 		}
-		time.Sleep(1 * time.Second)
-	}
-	return nil
+		return false, nil
+	})
 }
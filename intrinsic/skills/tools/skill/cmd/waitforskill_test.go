@@ -0,0 +1,149 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package waitforskill
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/status"
+	srgrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	spb "intrinsic/skills/proto/skills_go_proto"
+	"intrinsic/testing/grpctest"
+)
+
+// fakeRegistry returns NotFound until it has been polled notFoundCount times.
+type fakeRegistry struct {
+	srgrpcpb.UnimplementedSkillRegistryServer
+
+	mu            sync.Mutex
+	calls         int
+	notFoundCount int
+	idVersion     string
+}
+
+func (r *fakeRegistry) GetSkill(ctx context.Context, req *srgrpcpb.GetSkillRequest) (*srgrpcpb.GetSkillResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.calls <= r.notFoundCount {
+		return nil, status.Errorf(codes.NotFound, "skill %q not found", req.GetId())
+	}
+	return &srgrpcpb.GetSkillResponse{
+		Skill: &spb.Skill{Id: req.GetId(), IdVersion: r.idVersion},
+	}, nil
+}
+
+// removingRegistry returns the skill until it has been polled foundCount times.
+type removingRegistry struct {
+	srgrpcpb.UnimplementedSkillRegistryServer
+
+	mu         sync.Mutex
+	calls      int
+	foundCount int
+}
+
+func (r *removingRegistry) GetSkill(ctx context.Context, req *srgrpcpb.GetSkillRequest) (*srgrpcpb.GetSkillResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.calls > r.foundCount {
+		return nil, status.Errorf(codes.NotFound, "skill %q not found", req.GetId())
+	}
+	return &srgrpcpb.GetSkillResponse{Skill: &spb.Skill{Id: req.GetId()}}, nil
+}
+
+func mustStartRegistry(t *testing.T, r srgrpcpb.SkillRegistryServer) srgrpcpb.SkillRegistryClient {
+	t.Helper()
+	server := grpc.NewServer()
+	srgrpcpb.RegisterSkillRegistryServer(server, r)
+	address := grpctest.StartServerT(t, server)
+	connection, err := grpc.Dial(address, grpc.WithTransportCredentials(local.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial server: %v", err)
+	}
+	t.Cleanup(func() { connection.Close() })
+
+	return srgrpcpb.NewSkillRegistryClient(connection)
+}
+
+func TestWaitForSkill(t *testing.T) {
+	client := mustStartRegistry(t, &fakeRegistry{notFoundCount: 2, idVersion: "ai.intrinsic.foo.0.0.1"})
+
+	err := WaitForSkill(context.Background(), &Params{
+		Client:         client,
+		SkillID:        "ai.intrinsic.foo",
+		SkillIDVersion: "ai.intrinsic.foo.0.0.1",
+		WaitDuration:   10 * time.Second,
+		PollInterval:   time.Millisecond,
+	})
+	if err != nil {
+		t.Errorf("WaitForSkill() failed: %v", err)
+	}
+}
+
+func TestWaitForSkillTimeout(t *testing.T) {
+	client := mustStartRegistry(t, &fakeRegistry{notFoundCount: 1 << 30})
+
+	err := WaitForSkill(context.Background(), &Params{
+		Client:       client,
+		SkillID:      "ai.intrinsic.foo",
+		WaitDuration: 50 * time.Millisecond,
+		PollInterval: time.Millisecond,
+	})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("WaitForSkill() returned %v, want a TimeoutError", err)
+	}
+	if status.Code(timeoutErr.LastErr) != codes.NotFound {
+		t.Errorf("WaitForSkill() returned last error %v, want code %v", timeoutErr.LastErr, codes.NotFound)
+	}
+}
+
+func TestWaitForSkillCanceled(t *testing.T) {
+	client := mustStartRegistry(t, &fakeRegistry{notFoundCount: 1 << 30})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err := WaitForSkill(ctx, &Params{
+		Client:       client,
+		SkillID:      "ai.intrinsic.foo",
+		WaitDuration: time.Minute,
+		PollInterval: time.Millisecond,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitForSkill() returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWaitForSkillRemoval(t *testing.T) {
+	client := mustStartRegistry(t, &removingRegistry{foundCount: 2})
+
+	err := WaitForSkillRemoval(context.Background(), &Params{
+		Client:       client,
+		SkillID:      "ai.intrinsic.foo",
+		WaitDuration: 10 * time.Second,
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Errorf("WaitForSkillRemoval() failed: %v", err)
+	}
+
+	client = mustStartRegistry(t, &removingRegistry{foundCount: 1 << 30})
+	err = WaitForSkillRemoval(context.Background(), &Params{
+		Client:       client,
+		SkillID:      "ai.intrinsic.foo",
+		WaitDuration: 50 * time.Millisecond,
+		PollInterval: time.Millisecond,
+	})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Errorf("WaitForSkillRemoval() returned %v, want a TimeoutError", err)
+	}
+}
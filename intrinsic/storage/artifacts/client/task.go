@@ -117,10 +117,6 @@ func runWithContext(ctx context.Context, task uploadTask) func() error {
 	}
 }
 
-func (t *streamingTask) run() error {
-	return t.runWithCtx(context.Background())
-}
-
 func (t *streamingTask) runWithCtx(ctx context.Context) error {
 	log.InfoContextf(ctx, "starting upload: %s", asShortName(t.name))
 	updateMonitor(t.monitor, asShortName(t.name), ProgressUpdate{
@@ -6,6 +6,7 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
@@ -25,6 +26,7 @@ const (
 
 	storeDirectory      = "intrinsic/projects"
 	orgStoreDirectory   = "intrinsic/organizations"
+	defaultOrgFile      = "intrinsic/default-org.json"
 	authConfigExtension = ".user-token"
 
 	// OrgIDHeader is the header name for providing the org in requests to our services.
@@ -113,6 +115,10 @@ type ProjectConfiguration struct {
 	// It is a map of alias: {api_key...}
 	Tokens map[string]*ProjectToken `json:"tokens,omitempty"`
 
+	// ClientCertificate is presented to clusters requiring mutual TLS, may be
+	// omitted.
+	ClientCertificate *ClientCertificate `json:"clientCertificate,omitempty"`
+
 	// LastUpdated tracks when the file was last written by store, may be omitted
 	LastUpdated *RFC3339Time `json:"lastUpdated,omitempty"`
 }
@@ -126,6 +132,10 @@ func (p *ProjectConfiguration) SetCredentials(alias string, apiKey string, valid
 		expires := RFC3339Time(validUntil[0])
 		token.ValidUntil = &expires
 	}
+	if p.Tokens == nil {
+		// Configurations with a client certificate only are stored without tokens.
+		p.Tokens = make(map[string]*ProjectToken)
+	}
 	p.Tokens[alias] = token
 
 	return p, token.Validate()
@@ -499,6 +509,12 @@ func (s *Store) RemoveOrganization(name string) error {
 		return fmt.Errorf("cannot remove organization: %w", err)
 	}
 
+	if info, err := s.ReadDefaultOrg(); err == nil && info != nil && info.Organization == name {
+		if err := s.ClearDefaultOrg(); err != nil {
+			log.Warningf("cannot clear default organization: %s", err)
+		}
+	}
+
 	if deleteProject {
 		// we are going to delete project only if there is only one organization
 		// using it. If there are more than one, we are leaving project intact
@@ -528,10 +544,82 @@ func (s *Store) RemoveAllKnownCredentials() error {
 	if err != nil {
 		return err
 	}
+	if err := s.ClearDefaultOrg(); err != nil {
+		return err
+	}
 
 	return filepath.WalkDir(location, s.deleteFiles)
 }
 
+func (s *Store) defaultOrgFilename() (string, error) {
+	configDir, err := s.getConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("get config directory: %w", err)
+	}
+	return filepath.Join(configDir, defaultOrgFile), nil
+}
+
+// WriteDefaultOrg marks the given organization as the one to use when no
+// organization or project is given.
+func (s *Store) WriteDefaultOrg(o *OrgInfo) error {
+	filename, err := s.defaultOrgFilename()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), directoryMode); err != nil {
+		return fmt.Errorf("create target directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filename, writeFileFlags, fileMode)
+	if err != nil {
+		return fmt.Errorf("open default organization file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(o); err != nil {
+		return fmt.Errorf("serialize default organization: %w", err)
+	}
+	return file.Sync()
+}
+
+// ReadDefaultOrg returns the organization set with WriteDefaultOrg, or nil if
+// there is none.
+func (s *Store) ReadDefaultOrg() (*OrgInfo, error) {
+	filename, err := s.defaultOrgFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open default organization: %w", err)
+	}
+	defer file.Close()
+
+	ret := &OrgInfo{}
+	if err := json.NewDecoder(file).Decode(ret); err != nil {
+		return nil, fmt.Errorf("deserialize default organization: %w", err)
+	}
+	return ret, nil
+}
+
+// ClearDefaultOrg removes the default organization, if any.
+func (s *Store) ClearDefaultOrg() error {
+	filename, err := s.defaultOrgFilename()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot remove default organization: %w", err)
+	}
+	return nil
+}
+
 func (s *Store) deleteFiles(path string, de fs.DirEntry, err error) error {
 	if err != nil {
 		if de == nil || de.IsDir() {
@@ -517,3 +517,37 @@ func TestStore_RemoveAllKnownCredentials(t *testing.T) {
 		})
 	}
 }
+
+func TestStore_DefaultOrg(t *testing.T) {
+	store := newStoreForTest(t)
+
+	if got, err := store.ReadDefaultOrg(); err != nil || got != nil {
+		t.Errorf("ReadDefaultOrg() = %v, %v; want nil, nil", got, err)
+	}
+
+	want := &OrgInfo{Organization: "otherorg", Project: "example-project"}
+	if err := store.WriteOrgInfo(want); err != nil {
+		t.Fatalf("WriteOrgInfo() failed: %v", err)
+	}
+	if err := store.WriteDefaultOrg(want); err != nil {
+		t.Fatalf("WriteDefaultOrg() failed: %v", err)
+	}
+	got, err := store.ReadDefaultOrg()
+	if err != nil {
+		t.Fatalf("ReadDefaultOrg() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadDefaultOrg() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if orgs, err := store.ListOrgs(); err != nil || len(orgs) != 1 {
+		t.Errorf("ListOrgs() = %v, %v; want only the written organization", orgs, err)
+	}
+
+	// Removing the organization also removes it as default.
+	if err := store.RemoveOrganization("otherorg"); err != nil {
+		t.Fatalf("RemoveOrganization() failed: %v", err)
+	}
+	if got, err := store.ReadDefaultOrg(); err != nil || got != nil {
+		t.Errorf("ReadDefaultOrg() after RemoveOrganization() = %v, %v; want nil, nil", got, err)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ClientCertificate references a client certificate and key used for mutual
+// TLS with clusters which front their services with a customer-managed PKI.
+// Only the paths are stored; the key material stays where the user put it.
+type ClientCertificate struct {
+	// CertFile is the path of the PEM encoded client certificate.
+	CertFile string `json:"certFile"`
+	// KeyFile is the path of the PEM encoded private key of the certificate.
+	KeyFile string `json:"keyFile"`
+	// CAFile is the optional path of a PEM encoded bundle of CA certificates
+	// used to verify the server. The system pool is used if empty.
+	CAFile string `json:"caFile,omitempty"`
+}
+
+// Validate checks that certificate and key are both set.
+func (c *ClientCertificate) Validate() error {
+	if c == nil {
+		return fmt.Errorf("nil client certificate")
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("client certificate and key must be set together")
+	}
+	return nil
+}
+
+// TLSConfig loads the certificate, key and CA bundle and returns a TLS
+// configuration presenting the certificate to the server.
+func (c *ClientCertificate) TLSConfig() (*tls.Config, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load client certificate: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load system certificates: %w", err)
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA certificates: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificates found in %q", c.CAFile)
+		}
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// HTTPClient returns an HTTP client presenting the certificate to the server. Returns
+// http.DefaultClient if c is nil.
+func (c *ClientCertificate) HTTPClient() (*http.Client, error) {
+	if c == nil {
+		return http.DefaultClient, nil
+	}
+	config, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{Transport: transport}, nil
+}
+
+// Credentials returns the API key stored under alias, or the default API key if alias is empty,
+// together with the client certificate of the configuration.
+//
+// A client certificate authenticates the caller on its own, so a missing API key is only an error
+// if no certificate is configured. The returned token is nil otherwise.
+func (p *ProjectConfiguration) Credentials(alias string) (*ProjectToken, *ClientCertificate, error) {
+	if alias == "" {
+		alias = AliasDefaultToken
+	}
+	token, err := p.GetCredentials(alias)
+	if err != nil {
+		if p.ClientCertificate == nil {
+			return nil, nil, err
+		}
+		token = nil
+	}
+	return token, p.ClientCertificate, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package auth
+
+import (
+	"testing"
+)
+
+func TestProjectConfiguration_Credentials(t *testing.T) {
+	cert := &ClientCertificate{CertFile: "cert.pem", KeyFile: "key.pem"}
+	token := &ProjectToken{APIKey: "key"}
+	tests := []struct {
+		name      string
+		config    *ProjectConfiguration
+		alias     string
+		wantToken *ProjectToken
+		wantCert  *ClientCertificate
+		wantErr   bool
+	}{
+		{
+			name:      "api key only",
+			config:    &ProjectConfiguration{Tokens: map[string]*ProjectToken{AliasDefaultToken: token}},
+			wantToken: token,
+		},
+		{
+			name:      "api key and client certificate",
+			config:    &ProjectConfiguration{Tokens: map[string]*ProjectToken{AliasDefaultToken: token}, ClientCertificate: cert},
+			wantToken: token,
+			wantCert:  cert,
+		},
+		{
+			name:     "client certificate only",
+			config:   &ProjectConfiguration{ClientCertificate: cert},
+			wantCert: cert,
+		},
+		{
+			name:      "alias",
+			config:    &ProjectConfiguration{Tokens: map[string]*ProjectToken{"other": token}},
+			alias:     "other",
+			wantToken: token,
+		},
+		{
+			name:    "missing alias",
+			config:  &ProjectConfiguration{Tokens: map[string]*ProjectToken{AliasDefaultToken: token}},
+			alias:   "other",
+			wantErr: true,
+		},
+		{
+			name:    "neither",
+			config:  &ProjectConfiguration{},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotToken, gotCert, err := tc.config.Credentials(tc.alias)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Credentials(%q) returned error %v, want error: %v", tc.alias, err, tc.wantErr)
+			}
+			if gotToken != tc.wantToken {
+				t.Errorf("Credentials(%q) returned token %v, want %v", tc.alias, gotToken, tc.wantToken)
+			}
+			if gotCert != tc.wantCert {
+				t.Errorf("Credentials(%q) returned certificate %v, want %v", tc.alias, gotCert, tc.wantCert)
+			}
+		})
+	}
+}
+
+func TestProjectConfiguration_SetCredentialsKeepsClientCertificate(t *testing.T) {
+	store := newStoreForTest(t)
+	cert := &ClientCertificate{CertFile: "cert.pem", KeyFile: "key.pem"}
+	if _, err := store.WriteConfiguration(&ProjectConfiguration{Name: "project", ClientCertificate: cert}); err != nil {
+		t.Fatalf("WriteConfiguration() failed: %v", err)
+	}
+
+	config, err := store.GetConfiguration("project")
+	if err != nil {
+		t.Fatalf("GetConfiguration() failed: %v", err)
+	}
+	if _, err := config.SetDefaultCredentials("key"); err != nil {
+		t.Fatalf("SetDefaultCredentials() failed: %v", err)
+	}
+	if _, err := store.WriteConfiguration(config); err != nil {
+		t.Fatalf("WriteConfiguration() failed: %v", err)
+	}
+
+	got, err := store.GetConfiguration("project")
+	if err != nil {
+		t.Fatalf("GetConfiguration() failed: %v", err)
+	}
+	if got.ClientCertificate == nil || *got.ClientCertificate != *cert {
+		t.Errorf("GetConfiguration() returned client certificate %v, want %v", got.ClientCertificate, cert)
+	}
+	if _, err := got.GetDefaultCredentials(); err != nil {
+		t.Errorf("GetDefaultCredentials() failed: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// exportHeader starts every exported store and identifies the format version.
+	exportHeader = "inctl-auth-export-v1\n"
+	saltSize     = 16
+	keySize      = 32
+)
+
+// ErrWrongPassphrase is returned by Import if the passphrase does not decrypt the export, or if
+// the export was modified.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted export")
+
+// exportedStore is the content of an exported store before encryption.
+type exportedStore struct {
+	Projects   []*ProjectConfiguration `json:"projects"`
+	Orgs       []*OrgInfo              `json:"orgs"`
+	DefaultOrg *OrgInfo                `json:"defaultOrg,omitempty"`
+}
+
+// ImportResult lists the configurations written by Import.
+type ImportResult struct {
+	Projects []string
+	Orgs     []string
+	// DefaultOrg is the imported default organization, empty if it was not imported.
+	DefaultOrg string
+}
+
+// deriveKey derives the AES key for an export from the passphrase with scrypt.
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keySize)
+}
+
+// Export returns all projects and organizations of the store, encrypted with a key derived from
+// passphrase. The paths of client certificates are exported, but not the certificate files.
+func (s *Store) Export(passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+	var store exportedStore
+	projects, err := s.ListConfigurations()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(projects)
+	for _, name := range projects {
+		config, err := s.GetConfiguration(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read configuration %q: %w", name, err)
+		}
+		store.Projects = append(store.Projects, config)
+	}
+	orgs, err := s.ListOrgs()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(orgs)
+	for _, name := range orgs {
+		info, err := s.ReadOrgInfo(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read organization %q: %w", name, err)
+		}
+		store.Orgs = append(store.Orgs, &info)
+	}
+	if store.DefaultOrg, err = s.ReadDefaultOrg(); err != nil {
+		return nil, err
+	}
+	if len(store.Projects) == 0 && len(store.Orgs) == 0 {
+		return nil, fmt.Errorf("no credentials to export")
+	}
+
+	plaintext, err := json.Marshal(&store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot serialize credentials: %w", err)
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := bytes.NewBufferString(exportHeader)
+	out.Write(salt)
+	out.Write(nonce)
+	out.Write(aead.Seal(nil, nonce, plaintext, []byte(exportHeader)))
+	return out.Bytes(), nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptExport returns the store in data, which was returned by Export.
+func decryptExport(data []byte, passphrase string) (*exportedStore, error) {
+	if !bytes.HasPrefix(data, []byte(exportHeader)) {
+		return nil, fmt.Errorf("not an exported credential store")
+	}
+	data = data[len(exportHeader):]
+	if len(data) < saltSize {
+		return nil, ErrWrongPassphrase
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(exportHeader))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	store := &exportedStore{}
+	if err := json.Unmarshal(plaintext, store); err != nil {
+		return nil, fmt.Errorf("cannot deserialize credentials: %w", err)
+	}
+	return store, nil
+}
+
+// Import writes the projects and organizations of data, which was returned by Export, to the
+// store. Unless overwrite is set, nothing is written if a project or organization already exists.
+// The default organization is only imported if the store has none.
+func (s *Store) Import(data []byte, passphrase string, overwrite bool) (*ImportResult, error) {
+	store, err := decryptExport(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if !overwrite {
+		var existing []string
+		for _, config := range store.Projects {
+			if s.HasConfiguration(config.Name) {
+				existing = append(existing, "project "+config.Name)
+			}
+		}
+		for _, info := range store.Orgs {
+			if _, err := s.ReadOrgInfo(info.Organization); err == nil {
+				existing = append(existing, "organization "+info.Organization)
+			}
+		}
+		if len(existing) > 0 {
+			return nil, fmt.Errorf("credentials already exist for %s", strings.Join(existing, ", "))
+		}
+	}
+
+	result := &ImportResult{}
+	for _, config := range store.Projects {
+		if config.Tokens == nil {
+			config.Tokens = map[string]*ProjectToken{}
+		}
+		if _, err := s.WriteConfiguration(config); err != nil {
+			return result, fmt.Errorf("cannot write configuration %q: %w", config.Name, err)
+		}
+		result.Projects = append(result.Projects, config.Name)
+	}
+	for _, info := range store.Orgs {
+		if err := s.WriteOrgInfo(info); err != nil {
+			return result, fmt.Errorf("cannot write organization %q: %w", info.Organization, err)
+		}
+		result.Orgs = append(result.Orgs, info.Organization)
+	}
+	if store.DefaultOrg != nil {
+		current, err := s.ReadDefaultOrg()
+		if err != nil {
+			return result, err
+		}
+		if current == nil {
+			if err := s.WriteDefaultOrg(store.DefaultOrg); err != nil {
+				return result, err
+			}
+			result.DefaultOrg = store.DefaultOrg.Organization
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStore_ExportImport(t *testing.T) {
+	src := newStoreForTest(t)
+	config, err := NewConfiguration("example-project").SetDefaultCredentials("secret-key")
+	if err != nil {
+		t.Fatalf("SetDefaultCredentials() failed: %v", err)
+	}
+	if _, err := src.WriteConfiguration(config); err != nil {
+		t.Fatalf("WriteConfiguration() failed: %v", err)
+	}
+	org := &OrgInfo{Organization: "exampleorg", Project: "example-project"}
+	if err := src.WriteOrgInfo(org); err != nil {
+		t.Fatalf("WriteOrgInfo() failed: %v", err)
+	}
+	if err := src.WriteDefaultOrg(org); err != nil {
+		t.Fatalf("WriteDefaultOrg() failed: %v", err)
+	}
+
+	data, err := src.Export("correct horse")
+	if err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	dst := newStoreForTest(t)
+	if _, err := dst.Import(data, "wrong horse", false); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Import() with wrong passphrase returned %v, want %v", err, ErrWrongPassphrase)
+	}
+	result, err := dst.Import(data, "correct horse", false)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	want := &ImportResult{Projects: []string{"example-project"}, Orgs: []string{"exampleorg"}, DefaultOrg: "exampleorg"}
+	if diff := cmp.Diff(want, result); diff != "" {
+		t.Errorf("Import() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	got, err := dst.GetConfiguration("example-project")
+	if err != nil {
+		t.Fatalf("GetConfiguration() failed: %v", err)
+	}
+	if diff := cmp.Diff(config.Tokens, got.Tokens); diff != "" {
+		t.Errorf("imported tokens differ (-want +got):\n%s", diff)
+	}
+	if got, err := dst.ReadDefaultOrg(); err != nil || got == nil || got.Organization != "exampleorg" {
+		t.Errorf("ReadDefaultOrg() = %v, %v; want the imported organization", got, err)
+	}
+
+	if _, err := dst.Import(data, "correct horse", false); err == nil {
+		t.Error("Import() of existing credentials succeeded without overwrite, want error")
+	}
+	if _, err := dst.Import(data, "correct horse", true); err != nil {
+		t.Errorf("Import() with overwrite failed: %v", err)
+	}
+}
+
+func TestStore_ImportRejectsModifiedExport(t *testing.T) {
+	src := newStoreForTest(t)
+	if err := src.WriteOrgInfo(&OrgInfo{Organization: "exampleorg", Project: "example-project"}); err != nil {
+		t.Fatalf("WriteOrgInfo() failed: %v", err)
+	}
+	data, err := src.Export("passphrase")
+	if err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+	data[len(data)-1] ^= 1
+	if _, err := newStoreForTest(t).Import(data, "passphrase", false); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Import() of modified export returned %v, want %v", err, ErrWrongPassphrase)
+	}
+	if _, err := newStoreForTest(t).Import([]byte("{}"), "passphrase", false); err == nil || errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Import() of non-export returned %v, want a format error", err)
+	}
+}
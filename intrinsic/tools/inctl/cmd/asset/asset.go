@@ -0,0 +1,152 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package asset contains commands which apply to assets of any type.
+package asset
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/assets/idutils"
+	"intrinsic/assets/imageutils"
+	"intrinsic/assets/receipt"
+	imagepb "intrinsic/kubernetes/workcell_spec/proto/image_go_proto"
+	installerpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
+	"intrinsic/tools/inctl/cmd/root"
+)
+
+const (
+	keyReceiptDir = "receipt_dir"
+)
+
+var rollbackFlags = cmdutils.NewCmdFlags()
+
+var assetCmd = &cobra.Command{
+	Use:   "asset",
+	Short: "Manages installed assets",
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback ID",
+	Short: "Reinstalls the version of an asset which was replaced by its latest installation",
+	Long: `Reinstalls the version of an asset which was replaced by its latest installation, to recover
+quickly from a bad release.
+
+The installed versions and their images are taken from the installation receipts which 'inctl skill
+install' writes on this machine. Rolling back writes a receipt as well, so rolling back twice
+restores the newer version again.`,
+	Example: `
+	$ inctl asset rollback ai.intrinsic.my_skill --cluster=my_cluster
+	$ inctl asset rollback ai.intrinsic.my_skill --solution=my_solution_id --receipt_dir=/mnt/usb/receipts
+	`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		id := args[0]
+		if err := idutils.ValidateID(id); err != nil {
+			return fmt.Errorf("invalid id: %w", err)
+		}
+
+		dir := rollbackFlags.GetString(keyReceiptDir)
+		if dir == "" {
+			var err error
+			if dir, err = receipt.DefaultDir(); err != nil {
+				return err
+			}
+		}
+		receipts, err := receipt.List(dir)
+		if err != nil {
+			return err
+		}
+		address, cluster, solution, err := rollbackFlags.GetFlagsAddressClusterSolution()
+		if err != nil {
+			return err
+		}
+		current, previous, err := receipt.Rollback(receipts, id, &receipt.Receipt{
+			Cluster:  cluster,
+			Solution: solution,
+			Address:  address,
+		})
+		if err != nil {
+			return err
+		}
+		if previous.AssetType != "skill" {
+			return fmt.Errorf("cannot roll back %q: rolling back assets of type %q is not supported", id, previous.AssetType)
+		}
+		img := previous.ImageProto()
+		if img == nil {
+			return fmt.Errorf("cannot roll back %q: the receipt of %q records no image", id, previous.IDVersion)
+		}
+		version, err := idutils.VersionFrom(previous.IDVersion)
+		if err != nil {
+			return err
+		}
+		if err := rollbackFlags.Confirm(fmt.Sprintf("Replace %q with %q", current.IDVersion, previous.IDVersion),
+			fmt.Sprintf("Image: %s", previous.Image),
+			fmt.Sprintf("Installed at %s by %s", previous.InstallTime.Local().Format("2006-01-02 15:04:05"), previous.User)); err != nil {
+			return err
+		}
+
+		ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, rollbackFlags)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := clientutils.CheckClusterUpgradeState(ctx, conn, rollbackFlags); err != nil {
+			return err
+		}
+
+		log.Printf("Installing skill %q", previous.IDVersion)
+		err = imageutils.InstallContainer(ctx, &imageutils.InstallContainerParams{
+			Address:    address,
+			Connection: conn,
+			Request: &installerpb.InstallContainerAddonRequest{
+				Id:      id,
+				Version: version,
+				Type:    installerpb.AddonType_ADDON_TYPE_SKILL,
+				Images:  []*imagepb.Image{img},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not roll back the skill: %w", err)
+		}
+		log.Printf("Finished rolling back, skill container is now starting")
+
+		r := &receipt.Receipt{
+			IDVersion:         previous.IDVersion,
+			PreviousIDVersion: current.IDVersion,
+			AssetType:         previous.AssetType,
+			Image:             previous.Image,
+			ImageDigest:       previous.ImageDigest,
+			Cluster:           cluster,
+			Solution:          solution,
+			Address:           address,
+			Project:           rollbackFlags.GetFlagProject(),
+			Org:               rollbackFlags.GetFlagOrganization(),
+			User:              receipt.CurrentUser(),
+		}
+		path, err := receipt.Write(dir, r)
+		if err != nil {
+			log.Printf("Warning: could not write installation receipt: %v", err)
+			return nil
+		}
+		log.Printf("Wrote installation receipt to %s", path)
+		return nil
+	},
+}
+
+func init() {
+	rollbackFlags.SetCommand(rollbackCmd)
+	rollbackFlags.AddFlagsAddressClusterSolution()
+	rollbackFlags.AddFlagsProjectOrg()
+	rollbackFlags.AddFlagYes()
+	rollbackFlags.AddFlagIgnoreUpgradeState()
+	rollbackFlags.OptionalString(keyReceiptDir, "", "Directory to read and write installation "+
+		"receipts. Defaults to intrinsic/receipts in the user config directory.")
+
+	assetCmd.AddCommand(rollbackCmd)
+	root.RootCmd.AddCommand(assetCmd)
+}
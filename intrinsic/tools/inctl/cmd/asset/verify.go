@@ -0,0 +1,323 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package asset
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/assets/idutils"
+	rrgrpcpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+	skillregistrygrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+const (
+	keyFromFile = "from_file"
+	keyWrite    = "write"
+
+	// exitCodeDrift is the exit code of 'inctl asset verify' if the cluster does not match the
+	// lockfile. Other failures exit with the code of their error class, see root.ExitCode.
+	exitCodeDrift = 2
+)
+
+// Kinds of drift between a lockfile and a cluster.
+const (
+	driftMissing         = "missing"
+	driftUnexpected      = "unexpected"
+	driftVersionMismatch = "version mismatch"
+	driftConfigMismatch  = "config mismatch"
+)
+
+var verifyFlags = cmdutils.NewCmdFlags()
+
+// lockfile is the expected state of the assets of a cluster.
+type lockfile struct {
+	// Assets are the id_versions of the installed skills and services.
+	Assets []string `json:"assets"`
+	// Instances are the service instances and the digests of their configuration.
+	Instances []lockedInstance `json:"instances"`
+}
+
+type lockedInstance struct {
+	Name   string `json:"name"`
+	TypeID string `json:"typeId"`
+	// ConfigSHA256 is the hex encoded SHA-256 of the serialized configuration, empty if the
+	// instance has none.
+	ConfigSHA256 string `json:"configSha256,omitempty"`
+}
+
+// drift is a difference between the lockfile and the cluster.
+type drift struct {
+	Kind string `json:"kind"`
+	// Name is the id of an asset or the name of a service instance.
+	Name     string `json:"name"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func (d drift) String() string {
+	switch d.Kind {
+	case driftMissing:
+		return fmt.Sprintf("%s: missing, expected %s", d.Name, d.Expected)
+	case driftUnexpected:
+		return fmt.Sprintf("%s: unexpected %s", d.Name, d.Actual)
+	default:
+		return fmt.Sprintf("%s: %s, expected %s, installed %s", d.Name, d.Kind, d.Expected, d.Actual)
+	}
+}
+
+type verifyResult struct {
+	Lockfile string  `json:"lockfile"`
+	Drift    []drift `json:"drift"`
+}
+
+func (r *verifyResult) String() string {
+	if len(r.Drift) == 0 {
+		return fmt.Sprintf("The cluster matches %s.", r.Lockfile)
+	}
+	lines := []string{fmt.Sprintf("The cluster does not match %s:", r.Lockfile)}
+	for _, d := range r.Drift {
+		lines = append(lines, "  "+d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readLockfile reads a lockfile written by writeLockfile.
+func readLockfile(path string) (*lockfile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read lockfile: %w", err)
+	}
+	l := &lockfile{}
+	if err := json.Unmarshal(b, l); err != nil {
+		return nil, fmt.Errorf("cannot parse lockfile %q: %w", path, err)
+	}
+	for _, a := range l.Assets {
+		if err := idutils.ValidateIDVersion(a); err != nil {
+			return nil, fmt.Errorf("invalid asset in lockfile %q: %w", path, err)
+		}
+	}
+	return l, nil
+}
+
+func writeLockfile(path string, l *lockfile) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write lockfile: %w", err)
+	}
+	return nil
+}
+
+// installedState lists the installed skills, services and service instances of a cluster.
+func installedState(ctx context.Context, skills skillregistrygrpcpb.SkillRegistryClient, resources rrgrpcpb.ResourceRegistryClient) (*lockfile, error) {
+	l := &lockfile{}
+	for pageToken := ""; ; {
+		resp, err := skills.ListSkills(ctx, &skillregistrygrpcpb.ListSkillsRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, fmt.Errorf("could not list skills: %w", err)
+		}
+		for _, s := range resp.GetSkills() {
+			l.Assets = append(l.Assets, s.GetIdVersion())
+		}
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+	for pageToken := ""; ; {
+		resp, err := resources.ListServices(ctx, &rrgrpcpb.ListServicesRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, fmt.Errorf("could not list services: %w", err)
+		}
+		for _, s := range resp.GetServices() {
+			idVersion, err := idutils.IDVersionFromProto(s.GetMetadata().GetIdVersion())
+			if err != nil {
+				return nil, fmt.Errorf("registry returned invalid id_version: %w", err)
+			}
+			l.Assets = append(l.Assets, idVersion)
+		}
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+	for pageToken := ""; ; {
+		resp, err := resources.ListResourceInstances(ctx, &rrgrpcpb.ListResourceInstanceRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, fmt.Errorf("could not list service instances: %w", err)
+		}
+		for _, i := range resp.GetInstances() {
+			instance := lockedInstance{Name: i.GetName(), TypeID: i.GetTypeId()}
+			if c := i.GetConfiguration(); c != nil {
+				sum := sha256.Sum256(c.GetValue())
+				instance.ConfigSHA256 = hex.EncodeToString(sum[:])
+			}
+			l.Instances = append(l.Instances, instance)
+		}
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+	sort.Strings(l.Assets)
+	sort.Slice(l.Instances, func(i, j int) bool { return l.Instances[i].Name < l.Instances[j].Name })
+	return l, nil
+}
+
+// diffState returns the differences between the expected and the actual state, sorted by name.
+func diffState(expected *lockfile, actual *lockfile) ([]drift, error) {
+	versions := func(idVersions []string) (map[string]string, error) {
+		m := map[string]string{}
+		for _, iv := range idVersions {
+			parts, err := idutils.NewIDVersionParts(iv)
+			if err != nil {
+				return nil, err
+			}
+			m[parts.ID()] = parts.Version()
+		}
+		return m, nil
+	}
+	want, err := versions(expected.Assets)
+	if err != nil {
+		return nil, err
+	}
+	got, err := versions(actual.Assets)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []drift
+	for id, v := range want {
+		switch installed, ok := got[id]; {
+		case !ok:
+			result = append(result, drift{Kind: driftMissing, Name: id, Expected: v})
+		case installed != v:
+			result = append(result, drift{Kind: driftVersionMismatch, Name: id, Expected: v, Actual: installed})
+		}
+	}
+	for id, v := range got {
+		if _, ok := want[id]; !ok {
+			result = append(result, drift{Kind: driftUnexpected, Name: id, Actual: v})
+		}
+	}
+
+	gotInstances := map[string]lockedInstance{}
+	for _, i := range actual.Instances {
+		gotInstances[i.Name] = i
+	}
+	for _, w := range expected.Instances {
+		g, ok := gotInstances[w.Name]
+		delete(gotInstances, w.Name)
+		switch {
+		case !ok:
+			result = append(result, drift{Kind: driftMissing, Name: w.Name, Expected: "instance of " + w.TypeID})
+		case g.TypeID != w.TypeID:
+			result = append(result, drift{Kind: driftVersionMismatch, Name: w.Name, Expected: w.TypeID, Actual: g.TypeID})
+		case g.ConfigSHA256 != w.ConfigSHA256:
+			result = append(result, drift{Kind: driftConfigMismatch, Name: w.Name, Expected: orNone(w.ConfigSHA256), Actual: orNone(g.ConfigSHA256)})
+		}
+	}
+	for _, g := range gotInstances {
+		result = append(result, drift{Kind: driftUnexpected, Name: g.Name, Actual: "instance of " + g.TypeID})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Kind < result[j].Kind
+	})
+	return result, nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Checks that the assets installed in a cluster match a lockfile",
+	Long: `Checks that the skills, services and service instances of a cluster match a lockfile and
+reports any drift, e.g., for nightly compliance checks of production cells.
+
+The lockfile lists the expected id_versions of the installed skills and services, and the service
+instances with a digest of their configuration. Use --write to create it from a cluster which is
+known to be good. Image digests are not checked separately: released versions always refer to the
+same images and sideloaded versions differ for every installation.
+
+Exits with 0 if the cluster matches, with 2 if it does not and with 1 if the check failed.`,
+	Example: `
+	$ inctl asset verify --cluster=my_cluster --from_file=cell.lock.json --write
+	$ inctl asset verify --cluster=my_cluster --from_file=cell.lock.json
+	`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := verifyFlags.GetString(keyFromFile)
+		var expected *lockfile
+		if !verifyFlags.GetBool(keyWrite) {
+			var err error
+			if expected, err = readLockfile(path); err != nil {
+				return err
+			}
+		}
+
+		ctx, conn, _, err := clientutils.DialClusterFromInctl(cmd.Context(), verifyFlags)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		actual, err := installedState(ctx, skillregistrygrpcpb.NewSkillRegistryClient(conn), rrgrpcpb.NewResourceRegistryClient(conn))
+		if err != nil {
+			return err
+		}
+
+		prtr, err := printer.NewPrinterWithWriter(root.FlagOutput, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		if expected == nil {
+			if err := writeLockfile(path, actual); err != nil {
+				return err
+			}
+			prtr.PrintSf("Wrote %d assets and %d service instances to %s", len(actual.Assets), len(actual.Instances), path)
+			return nil
+		}
+
+		drifts, err := diffState(expected, actual)
+		if err != nil {
+			return err
+		}
+		prtr.Print(&verifyResult{Lockfile: path, Drift: drifts})
+		if len(drifts) > 0 {
+			return &root.ExitCodeError{
+				Code: exitCodeDrift,
+				Err:  fmt.Errorf("found %d differences to %s", len(drifts), path),
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	verifyFlags.SetCommand(verifyCmd)
+	verifyFlags.AddFlagsAddressClusterSolution()
+	verifyFlags.AddFlagsProjectOrg()
+	verifyFlags.RequiredString(keyFromFile, "The lockfile with the expected assets of the cluster.")
+	verifyFlags.OptionalBool(keyWrite, false, "Write the assets of the cluster to --from_file instead of verifying them.")
+
+	assetCmd.AddCommand(verifyCmd)
+}
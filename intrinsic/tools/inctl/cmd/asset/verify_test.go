@@ -0,0 +1,65 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package asset
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffState(t *testing.T) {
+	expected := &lockfile{
+		Assets: []string{"ai.intrinsic.a.1.0.0", "ai.intrinsic.b.1.0.0", "ai.intrinsic.c.1.0.0"},
+		Instances: []lockedInstance{
+			{Name: "cam", TypeID: "ai.intrinsic.c", ConfigSHA256: "aa"},
+			{Name: "gripper", TypeID: "ai.intrinsic.b"},
+		},
+	}
+	actual := &lockfile{
+		Assets: []string{"ai.intrinsic.a.1.0.0", "ai.intrinsic.c.1.1.0", "ai.intrinsic.d.0.0.1+sideloaded"},
+		Instances: []lockedInstance{
+			{Name: "cam", TypeID: "ai.intrinsic.c", ConfigSHA256: "bb"},
+			{Name: "extra", TypeID: "ai.intrinsic.d"},
+		},
+	}
+
+	got, err := diffState(expected, actual)
+	if err != nil {
+		t.Fatalf("diffState() failed: %v", err)
+	}
+	want := []drift{
+		{Kind: driftMissing, Name: "ai.intrinsic.b", Expected: "1.0.0"},
+		{Kind: driftVersionMismatch, Name: "ai.intrinsic.c", Expected: "1.0.0", Actual: "1.1.0"},
+		{Kind: driftUnexpected, Name: "ai.intrinsic.d", Actual: "0.0.1+sideloaded"},
+		{Kind: driftConfigMismatch, Name: "cam", Expected: "aa", Actual: "bb"},
+		{Kind: driftUnexpected, Name: "extra", Actual: "instance of ai.intrinsic.d"},
+		{Kind: driftMissing, Name: "gripper", Expected: "instance of ai.intrinsic.b"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diffState() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if got, err := diffState(actual, actual); err != nil || len(got) != 0 {
+		t.Errorf("diffState() of equal states = %v, %v, want no drift", got, err)
+	}
+}
+
+func TestLockfileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cell.lock.json")
+	l := &lockfile{
+		Assets:    []string{"ai.intrinsic.a.1.0.0"},
+		Instances: []lockedInstance{{Name: "cam", TypeID: "ai.intrinsic.c", ConfigSHA256: "aa"}},
+	}
+	if err := writeLockfile(path, l); err != nil {
+		t.Fatalf("writeLockfile() failed: %v", err)
+	}
+	got, err := readLockfile(path)
+	if err != nil {
+		t.Fatalf("readLockfile() failed: %v", err)
+	}
+	if diff := cmp.Diff(l, got); diff != "" {
+		t.Errorf("readLockfile() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
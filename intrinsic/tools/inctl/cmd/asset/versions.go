@@ -0,0 +1,62 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package asset
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/assets/idutils"
+	skillcataloggrpcpb "intrinsic/skills/catalog/proto/skill_catalog_go_grpc_proto"
+	"intrinsic/skills/tools/skill/cmd/listutil"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+var versionsFlags = cmdutils.NewCmdFlags()
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions ID",
+	Short: "Lists the released versions of an asset in the catalog",
+	Long: `Lists all released versions of an asset in the catalog, newest first, together with their
+release tags and release notes.
+
+Only skills are released to the catalog at the moment.`,
+	Example: `
+	$ inctl asset versions ai.intrinsic.my_skill --project=my_project
+	`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id := args[0]
+		if err := idutils.ValidateID(id); err != nil {
+			return fmt.Errorf("invalid id: %w", err)
+		}
+
+		conn, err := clientutils.DialCatalogFromInctl(cmd, versionsFlags)
+		if err != nil {
+			return fmt.Errorf("failed to create client connection: %v", err)
+		}
+		defer conn.Close()
+
+		versions, err := listutil.ListReleasedVersions(cmd.Context(), skillcataloggrpcpb.NewSkillCatalogClient(conn), id)
+		if err != nil {
+			return err
+		}
+
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+		prtr.Print(versions)
+
+		return nil
+	},
+}
+
+func init() {
+	versionsFlags.SetCommand(versionsCmd)
+
+	assetCmd.AddCommand(versionsCmd)
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package auth
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"intrinsic/tools/inctl/auth"
+	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/tools/inctl/util/viperutil"
+)
+
+const (
+	keyClientCert = "client_cert"
+	keyClientKey  = "client_key"
+	keyCACert     = "ca_cert"
+	keyRemove     = "remove"
+)
+
+var clientCertParams *viper.Viper
+
+var clientCertCmd = &cobra.Command{
+	Use:   "set-client-cert",
+	Short: "Configures a client certificate for clusters requiring mTLS",
+	Long: `Configures the client certificate and key presented to on-prem clusters which front their
+services with mutual TLS using a customer-managed PKI.
+
+Only the paths to the certificate files are stored. An API key is not required for projects which
+are accessed with a client certificate only.
+
+Example:
+  inctl auth set-client-cert --org my-org --client_cert cert.pem --client_key key.pem --ca_cert ca.pem
+  inctl auth set-client-cert --org my-org --remove`,
+	Args: cobra.NoArgs,
+	RunE: setClientCertE,
+}
+
+func clientCertProject() (string, error) {
+	if org := clientCertParams.GetString(orgutil.KeyOrganization); org != "" {
+		info, err := authStore.ReadOrgInfo(org)
+		if err != nil {
+			return "", fmt.Errorf("cannot read organization %q, run 'inctl auth login' first or use --%s: %w", org, orgutil.KeyProject, err)
+		}
+		return info.Project, nil
+	}
+	if project := clientCertParams.GetString(orgutil.KeyProject); project != "" {
+		return project, nil
+	}
+	return "", fmt.Errorf("either --%s or --%s needs to be specified", orgutil.KeyOrganization, orgutil.KeyProject)
+}
+
+func setClientCertE(cmd *cobra.Command, _ []string) error {
+	project, err := clientCertProject()
+	if err != nil {
+		return err
+	}
+
+	config := auth.NewConfiguration(project)
+	if authStore.HasConfiguration(project) {
+		if config, err = authStore.GetConfiguration(project); err != nil {
+			return fmt.Errorf("cannot read configuration for %q: %w", project, err)
+		}
+	}
+
+	if clientCertParams.GetBool(keyRemove) {
+		config.ClientCertificate = nil
+		if _, err := authStore.WriteConfiguration(config); err != nil {
+			return fmt.Errorf("cannot write configuration: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed client certificate for project %q.\n", project)
+		return nil
+	}
+
+	cert := &auth.ClientCertificate{}
+	for _, f := range []struct {
+		key string
+		dst *string
+	}{
+		{keyClientCert, &cert.CertFile},
+		{keyClientKey, &cert.KeyFile},
+		{keyCACert, &cert.CAFile},
+	} {
+		if path := clientCertParams.GetString(f.key); path != "" {
+			// Store absolute paths so that the configuration works from any directory.
+			if *f.dst, err = filepath.Abs(path); err != nil {
+				return fmt.Errorf("invalid path for --%s: %w", f.key, err)
+			}
+		}
+	}
+	// Fail early instead of on the first connection attempt.
+	if _, err := cert.TLSConfig(); err != nil {
+		return fmt.Errorf("invalid client certificate: %w", err)
+	}
+
+	config.ClientCertificate = cert
+	if _, err := authStore.WriteConfiguration(config); err != nil {
+		return fmt.Errorf("cannot write configuration: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Configured client certificate for project %q.\n", project)
+	return nil
+}
+
+func init() {
+	authCmd.AddCommand(clientCertCmd)
+
+	flags := clientCertCmd.Flags()
+
+	flags.StringP(orgutil.KeyProject, keyProjectShort, "", "Project to configure the client certificate for")
+	flags.String(orgutil.KeyOrganization, "", "Name of the Intrinsic organization to configure the client certificate for")
+	flags.String(keyClientCert, "", "Path to the PEM encoded client certificate")
+	flags.String(keyClientKey, "", "Path to the PEM encoded private key of the client certificate")
+	flags.String(keyCACert, "", "Optional path to a PEM encoded CA bundle used to verify the cluster. Defaults to the system certificates.")
+	flags.Bool(keyRemove, false, "Removes the configured client certificate")
+
+	clientCertCmd.MarkFlagsRequiredTogether(keyClientCert, keyClientKey)
+	clientCertCmd.MarkFlagsMutuallyExclusive(keyRemove, keyClientCert)
+	clientCertCmd.MarkFlagsMutuallyExclusive(orgutil.KeyProject, orgutil.KeyOrganization)
+
+	clientCertParams = viperutil.BindToViper(flags, viperutil.BindToListEnv(orgutil.KeyProject))
+}
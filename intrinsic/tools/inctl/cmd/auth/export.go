@@ -0,0 +1,144 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const (
+	keyOut       = "out"
+	keyIn        = "in"
+	keyOverwrite = "overwrite"
+
+	// passphraseEnv is read instead of prompting for the passphrase, e.g., in CI.
+	passphraseEnv = "INCTL_AUTH_PASSPHRASE"
+)
+
+var (
+	flagExportOut       string
+	flagImportIn        string
+	flagImportOverwrite bool
+)
+
+// readPassphrase returns the passphrase from the environment or prompts for it. If confirm is set,
+// the passphrase has to be entered twice.
+func readPassphrase(cmd *cobra.Command, confirm bool) (string, error) {
+	if p := os.Getenv(passphraseEnv); p != "" {
+		return p, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to prompt for the passphrase, set %s", passphraseEnv)
+	}
+	prompt := func(text string) (string, error) {
+		fmt.Fprint(cmd.ErrOrStderr(), text)
+		p, err := term.ReadPassword(fd)
+		fmt.Fprintln(cmd.ErrOrStderr())
+		return string(p), err
+	}
+	p, err := prompt("Passphrase: ")
+	if err != nil {
+		return "", fmt.Errorf("cannot read passphrase: %w", err)
+	}
+	if p == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+	if confirm {
+		again, err := prompt("Repeat passphrase: ")
+		if err != nil {
+			return "", fmt.Errorf("cannot read passphrase: %w", err)
+		}
+		if again != p {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return p, nil
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports all local credentials to an encrypted file",
+	Long: `Exports the API keys of all projects and organizations to a file encrypted with a passphrase,
+e.g., to move them to another workstation or into a CI secret. Import the file with
+'inctl auth import'. Client certificates are referenced by path, their files are not exported.
+
+The passphrase is prompted for, or read from ` + passphraseEnv + ` if it is set.`,
+	Example: `
+	$ inctl auth export --out creds.enc
+	`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		passphrase, err := readPassphrase(cmd, true)
+		if err != nil {
+			return err
+		}
+		data, err := authStore.Export(passphrase)
+		if err != nil {
+			return fmt.Errorf("cannot export credentials: %w", err)
+		}
+		if err := os.WriteFile(flagExportOut, data, 0600); err != nil {
+			return fmt.Errorf("cannot write %q: %w", flagExportOut, err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Exported credentials to %s\n", flagExportOut)
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Imports credentials exported with 'inctl auth export'",
+	Long: `Imports the projects and organizations of a file written by 'inctl auth export'. Existing
+credentials are not changed unless --overwrite is set. The default organization is only imported if
+none is set.
+
+The passphrase is prompted for, or read from ` + passphraseEnv + ` if it is set.`,
+	Example: `
+	$ inctl auth import --in creds.enc
+	$ INCTL_AUTH_PASSPHRASE="$SECRET" inctl auth import --in creds.enc --overwrite
+	`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		data, err := os.ReadFile(flagImportIn)
+		if err != nil {
+			return fmt.Errorf("cannot read %q: %w", flagImportIn, err)
+		}
+		passphrase, err := readPassphrase(cmd, false)
+		if err != nil {
+			return err
+		}
+		result, err := authStore.Import(data, passphrase, flagImportOverwrite)
+		if err != nil {
+			return fmt.Errorf("cannot import credentials: %w", err)
+		}
+		w := bufio.NewWriter(cmd.OutOrStdout())
+		defer w.Flush()
+		if len(result.Orgs) > 0 {
+			fmt.Fprintf(w, "Imported organizations: %s\n", strings.Join(result.Orgs, ", "))
+		}
+		if len(result.Projects) > 0 {
+			fmt.Fprintf(w, "Imported projects: %s\n", strings.Join(result.Projects, ", "))
+		}
+		if result.DefaultOrg != "" {
+			fmt.Fprintf(w, "Default organization: %s\n", result.DefaultOrg)
+		}
+		return nil
+	},
+}
+
+func init() {
+	authCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&flagExportOut, keyOut, "", "Path of the encrypted file to write.")
+	exportCmd.MarkFlagRequired(keyOut)
+
+	authCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&flagImportIn, keyIn, "", "Path of the file written by 'inctl auth export'.")
+	importCmd.MarkFlagRequired(keyIn)
+	importCmd.Flags().BoolVar(&flagImportOverwrite, keyOverwrite, false, "Replace existing credentials of the same projects and organizations.")
+}
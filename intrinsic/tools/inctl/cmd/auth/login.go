@@ -142,11 +142,7 @@ func loginCmdE(cmd *cobra.Command, _ []string) (err error) {
 	}
 
 	if apiKey != "" && isBatch {
-		_, err = authStore.WriteConfiguration(&auth.ProjectConfiguration{
-			Name:   projectName,
-			Tokens: map[string]*auth.ProjectToken{alias: &auth.ProjectToken{APIKey: apiKey}},
-		})
-		return err
+		return writeCredentials(projectName, alias, apiKey)
 	}
 
 	if apiKey == "" {
@@ -169,16 +165,21 @@ func loginCmdE(cmd *cobra.Command, _ []string) (err error) {
 		}
 	}
 
-	var config *auth.ProjectConfiguration
+	return writeCredentials(projectName, alias, apiKey)
+}
+
+// writeCredentials stores apiKey under alias in the configuration of the project. Other settings
+// of an existing configuration, e.g., its client certificate, are kept.
+func writeCredentials(projectName string, alias string, apiKey string) error {
+	config := auth.NewConfiguration(projectName)
 	if authStore.HasConfiguration(projectName) {
+		var err error
 		if config, err = authStore.GetConfiguration(projectName); err != nil {
 			return fmt.Errorf("cannot load '%s' configuration: %w", projectName, err)
 		}
-	} else {
-		config = auth.NewConfiguration(projectName)
 	}
 
-	config, err = config.SetCredentials(alias, apiKey)
+	config, err := config.SetCredentials(alias, apiKey)
 	if err != nil {
 		return fmt.Errorf("aborting, invalid credentials: %w", err)
 	}
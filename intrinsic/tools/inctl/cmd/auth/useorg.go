@@ -0,0 +1,140 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"intrinsic/tools/inctl/auth"
+	"intrinsic/tools/inctl/util/orgutil"
+)
+
+const (
+	keyClear = "clear"
+)
+
+var useOrgCmd = &cobra.Command{
+	Use:   "use-org [org]",
+	Short: "Sets the default organization",
+	Long: `Sets the organization which is used when neither --org nor --project is given.
+
+The organization is validated against the organizations available to your logged-in projects and
+its stored project is updated if it changed. Without arguments, the current default organization
+is printed.`,
+	Example: `
+	$ inctl auth use-org my_org
+	$ inctl auth use-org intrinsic@my-project
+	$ inctl auth use-org --clear
+	`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: useOrgE,
+}
+
+func useOrgE(cmd *cobra.Command, args []string) error {
+	clearDefault, err := cmd.Flags().GetBool(keyClear)
+	if err != nil {
+		return err
+	}
+	if clearDefault {
+		if len(args) > 0 {
+			return fmt.Errorf("cannot set and --%s the default organization at the same time", keyClear)
+		}
+		if err := authStore.ClearDefaultOrg(); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Cleared the default organization.")
+		return nil
+	}
+
+	if len(args) == 0 {
+		info, err := authStore.ReadDefaultOrg()
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			fmt.Fprintln(cmd.OutOrStdout(), "No default organization is set.")
+			return nil
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Default organization: %s (project %s)\n", info.Organization, info.Project)
+		return nil
+	}
+
+	org := args[0]
+	info, err := resolveOrg(cmd, org)
+	if err != nil {
+		return err
+	}
+
+	stored, readErr := authStore.ReadOrgInfo(info.Organization)
+	if readErr != nil || stored.Project != info.Project {
+		if err := authStore.WriteOrgInfo(info); err != nil {
+			return fmt.Errorf("cannot store organization: %w", err)
+		}
+		if readErr == nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "Project of organization %q changed from %q to %q.\n", info.Organization, stored.Project, info.Project)
+		}
+	}
+	if err := authStore.WriteDefaultOrg(info); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Default organization set to %q (project %s).\n", info.Organization, info.Project)
+	return nil
+}
+
+// resolveOrg finds the project of an organization by asking the organization discovery API of the
+// logged-in projects. The project stored for the organization or given as org@project is asked
+// first.
+func resolveOrg(cmd *cobra.Command, org string) (*auth.OrgInfo, error) {
+	name, project, qualified := strings.Cut(org, "@")
+
+	var projects []string
+	if qualified {
+		if !authStore.HasConfiguration(project) {
+			return nil, fmt.Errorf("not logged in to project %q, use 'inctl auth login --org %s' first", project, org)
+		}
+		projects = []string{project}
+	} else {
+		if stored, err := authStore.ReadOrgInfo(org); err == nil {
+			projects = append(projects, stored.Project)
+		}
+		configured, err := authStore.ListConfigurations()
+		if err != nil {
+			return nil, fmt.Errorf("get projects: %w", err)
+		}
+		for _, p := range configured {
+			if len(projects) == 0 || p != projects[0] {
+				projects = append(projects, p)
+			}
+		}
+	}
+
+	var lastErr error
+	for _, p := range projects {
+		orgs, err := queryOrgs(cmd.Context(), p)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, o := range orgs {
+			if o.Organization != name {
+				continue
+			}
+			key := org
+			if !qualified && orgutil.SharedOrg(name) {
+				key = orgutil.QualifiedOrg(p, name)
+			}
+			return &auth.OrgInfo{Organization: key, Project: p}, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("organization %q not found, some projects could not be queried: %w", org, lastErr)
+	}
+	return nil, fmt.Errorf("organization %q not found in any logged-in project, use 'inctl auth login --org %s' first", org, org)
+}
+
+func init() {
+	authCmd.AddCommand(useOrgCmd)
+	useOrgCmd.Flags().Bool(keyClear, false, "Remove the default organization.")
+}
@@ -0,0 +1,279 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package bench contains commands which measure the performance of asset transfers and
+// installations against a cluster.
+package bench
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistry "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"intrinsic/assets/bundleio"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/assets/idutils"
+	"intrinsic/assets/imagetransfer"
+	installerpb "intrinsic/kubernetes/workcell_spec/proto/installer_go_grpc_proto"
+	"intrinsic/skills/tools/resource/cmd/bundleimages"
+	"intrinsic/skills/tools/skill/cmd/directupload"
+	"intrinsic/tools/inctl/cmd/root"
+)
+
+const (
+	keyReportFile = "report_file"
+	keyUninstall  = "uninstall"
+)
+
+// layerReport describes a single layer of a pushed image.
+type layerReport struct {
+	Digest string `json:"digest"`
+	// Size is the compressed size of the layer in bytes.
+	Size int64 `json:"size"`
+}
+
+// imageReport describes the push of a single image.
+type imageReport struct {
+	Reference string        `json:"reference"`
+	Layers    []layerReport `json:"layers"`
+	// Size is the sum of the compressed sizes of all layers in bytes.
+	Size        int64   `json:"size"`
+	PushSeconds float64 `json:"pushSeconds"`
+	// UploadRate is Size divided by the push time in bytes per second. Layers which already exist
+	// in the target are not uploaded again, so this is an upper bound for the effective rate.
+	UploadRate float64 `json:"uploadRate"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// report is the JSON output of `inctl bench install`.
+type report struct {
+	Bundle    string         `json:"bundle"`
+	Address   string         `json:"address,omitempty"`
+	IDVersion string         `json:"idVersion,omitempty"`
+	StartTime time.Time      `json:"startTime"`
+	Images    []*imageReport `json:"images"`
+	// PushedBytes is the sum of the sizes of all images.
+	PushedBytes int64 `json:"pushedBytes"`
+	// ProcessSeconds is the time to read the bundle and push all of its images.
+	ProcessSeconds float64 `json:"processSeconds"`
+	// UploadRate is PushedBytes divided by ProcessSeconds in bytes per second.
+	UploadRate float64 `json:"uploadRate"`
+	// InstallSeconds is the duration of the install request, i.e., until the installer reported
+	// the service as installed.
+	InstallSeconds   float64 `json:"installSeconds"`
+	UninstallSeconds float64 `json:"uninstallSeconds,omitempty"`
+	TotalSeconds     float64 `json:"totalSeconds"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// timingTransferer records the layer sizes and push duration of every image it writes.
+type timingTransferer struct {
+	imagetransfer.Transferer
+
+	mu     sync.Mutex
+	images []*imageReport
+}
+
+func (t *timingTransferer) Write(ref name.Reference, img containerregistry.Image) error {
+	r := &imageReport{Reference: ref.String()}
+	layers, err := img.Layers()
+	if err != nil {
+		return fmt.Errorf("could not get layers of %q: %w", ref, err)
+	}
+	for _, l := range layers {
+		digest, err := l.Digest()
+		if err != nil {
+			return fmt.Errorf("could not get layer digest of %q: %w", ref, err)
+		}
+		size, err := l.Size()
+		if err != nil {
+			return fmt.Errorf("could not get layer size of %q: %w", ref, err)
+		}
+		r.Layers = append(r.Layers, layerReport{Digest: digest.String(), Size: size})
+		r.Size += size
+	}
+
+	start := time.Now()
+	err = t.Transferer.Write(ref, img)
+	elapsed := time.Since(start)
+	r.PushSeconds = elapsed.Seconds()
+	r.UploadRate = rate(r.Size, elapsed)
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	t.mu.Lock()
+	t.images = append(t.images, r)
+	t.mu.Unlock()
+	return err
+}
+
+func rate(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) / d.Seconds()
+}
+
+func writeReport(w io.Writer, r *report) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal report: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measures the performance of asset transfers and installations",
+}
+
+func installCmd() *cobra.Command {
+	flags := cmdutils.NewCmdFlags()
+	cmd := &cobra.Command{
+		Use:   "install bundle",
+		Short: "Measure push and install timings of a service bundle",
+		Long: `Installs a service bundle like 'inctl service install' and reports how long each step took.
+
+The JSON report contains the layer sizes and push duration of every image in the bundle, the
+resulting upload rate and the duration of the install request. Use it to diagnose slow networks
+during commissioning or to compare the transfer performance of inctl releases.`,
+		Example: `
+	$ inctl bench install abc/service_bundle.tar --org my_org --cluster my_cluster \
+			--report_file /tmp/bench.json
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			r := &report{Bundle: args[0], StartTime: time.Now()}
+
+			ctx, conn, address, err := clientutils.DialClusterFromInctl(ctx, flags)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			r.Address = address
+
+			registry := flags.GetFlagRegistry()
+			remoteOpt, err := clientutils.RemoteOpt(flags)
+			if err != nil {
+				return err
+			}
+			transfer := imagetransfer.RemoteTransferer(remote.WithContext(ctx), remoteOpt)
+			if !flags.GetFlagSkipDirectUpload() {
+				opts := []directupload.Option{
+					directupload.WithDiscovery(directupload.NewFromConnection(conn)),
+					directupload.WithOutput(cmd.ErrOrStderr()),
+				}
+				if registry != "" {
+					opts = append(opts, directupload.WithFailOver(transfer))
+				} else {
+					registry = "direct.upload.local"
+				}
+				transfer = directupload.NewTransferer(ctx, opts...)
+			}
+			timing := &timingTransferer{Transferer: transfer}
+
+			runErr := func() error {
+				start := time.Now()
+				manifest, err := bundleio.ProcessService(r.Bundle, bundleio.ProcessServiceOpts{
+					ImageProcessor: bundleimages.CreateImageProcessor(flags.CreateRegistryOptsWithTransferer(ctx, timing, registry)),
+				})
+				processTime := time.Since(start)
+				r.ProcessSeconds = processTime.Seconds()
+				r.Images = timing.images
+				for _, img := range r.Images {
+					r.PushedBytes += img.Size
+				}
+				r.UploadRate = rate(r.PushedBytes, processTime)
+				if err != nil {
+					return fmt.Errorf("could not read bundle file %q: %v", r.Bundle, err)
+				}
+
+				id := manifest.GetMetadata().GetId()
+				manifestBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(manifest)
+				if err != nil {
+					return fmt.Errorf("could not marshal manifest: %v", err)
+				}
+				version := fmt.Sprintf("0.0.1+%x", sha256.Sum256(manifestBytes))
+				if r.IDVersion, err = idutils.IDVersionFrom(id.GetPackage(), id.GetName(), version); err != nil {
+					return fmt.Errorf("could not create id_version: %w", err)
+				}
+
+				client := installerpb.NewInstallerServiceClient(conn)
+				authCtx := clientutils.AuthInsecureConn(ctx, address, flags.GetFlagProject())
+				log.Printf("Installing service %q", r.IDVersion)
+				start = time.Now()
+				_, err = client.InstallService(authCtx, &installerpb.InstallServiceRequest{
+					Manifest: manifest,
+					Version:  version,
+				})
+				r.InstallSeconds = time.Since(start).Seconds()
+				if err != nil {
+					return fmt.Errorf("could not install the service: %v", err)
+				}
+
+				if flags.GetBool(keyUninstall) {
+					idVersion, err := idutils.IDVersionProtoFrom(id.GetPackage(), id.GetName(), version)
+					if err != nil {
+						return fmt.Errorf("could not create id_version: %w", err)
+					}
+					log.Printf("Uninstalling service %q", r.IDVersion)
+					start = time.Now()
+					_, err = client.UninstallService(authCtx, &installerpb.UninstallServiceRequest{IdVersion: idVersion})
+					r.UninstallSeconds = time.Since(start).Seconds()
+					if err != nil {
+						return fmt.Errorf("could not uninstall the service: %v", err)
+					}
+				}
+				return nil
+			}()
+			r.TotalSeconds = time.Since(r.StartTime).Seconds()
+			if runErr != nil {
+				r.Error = runErr.Error()
+			}
+
+			// Always write the report, a failed run still tells where the time went.
+			out := cmd.OutOrStdout()
+			if path := flags.GetString(keyReportFile); path != "" {
+				f, err := os.Create(path)
+				if err != nil {
+					return fmt.Errorf("could not create report file: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+			if err := writeReport(out, r); err != nil {
+				return err
+			}
+			return runErr
+		},
+	}
+
+	flags.SetCommand(cmd)
+	flags.AddFlagsAddressClusterSolution()
+	flags.AddFlagsProjectOrg()
+	flags.AddFlagRegistry()
+	flags.AddFlagsRegistryAuthUserPassword()
+	flags.AddFlagSkipDirectUpload("service")
+	flags.OptionalString(keyReportFile, "", "Write the JSON report to this file instead of stdout.")
+	flags.OptionalBool(keyUninstall, false, "Uninstall the service after the installation and report the duration.")
+
+	return cmd
+}
+
+func init() {
+	benchCmd.AddCommand(installCmd())
+	root.RootCmd.AddCommand(benchCmd)
+}
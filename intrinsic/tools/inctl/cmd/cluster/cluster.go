@@ -4,6 +4,7 @@
 package cluster
 
 import (
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"intrinsic/tools/inctl/cmd/root"
 	"intrinsic/tools/inctl/util/cobrautil"
@@ -23,5 +24,5 @@ var ClusterCmd = orgutil.WrapCmd(cobrautil.ParentOfNestedSubcommands(
 	root.ClusterCmdName, "Workcell cluster handling"), ClusterCmdViper)
 
 func init() {
-	root.RootCmd.AddCommand(ClusterCmd)
+	root.AddLazyCommand(ClusterCmd.Use, ClusterCmd.Short, func() *cobra.Command { return ClusterCmd }, ClusterCmd.Aliases...)
 }
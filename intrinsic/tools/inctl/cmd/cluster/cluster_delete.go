@@ -5,12 +5,25 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"time"
 
+	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
+	"intrinsic/assets/cmdutils"
 	clusterdeletiongrpcpb "intrinsic/frontend/cloud/api/clusterdeletion_api_go_grpc_proto"
+	clustermanagergrpcpb "intrinsic/frontend/cloud/api/clustermanager_api_go_grpc_proto"
 	"intrinsic/skills/tools/skill/cmd/dialerutil"
 	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/util/grpc/lroutil"
+)
+
+const defaultDeleteTimeout = 10 * time.Minute
+
+var (
+	deleteWait    bool
+	deleteTimeout time.Duration
+	deleteYes     bool
 )
 
 func deleteCluster(ctx context.Context, conn *grpc.ClientConn, cluster string) error {
@@ -23,6 +36,40 @@ func deleteCluster(ctx context.Context, conn *grpc.ClientConn, cluster string) e
 	return nil
 }
 
+// waitForOperation waits for a cluster manager operation and returns its error
+// if it failed.
+func waitForOperation(ctx context.Context, client clustermanagergrpcpb.ClustersServiceClient, op *lropb.Operation, timeout time.Duration) error {
+	op, err := lroutil.WaitWithBackoff(ctx, client, op, lroutil.WaitOptions{
+		MaxInterval: 10 * time.Second,
+		Timeout:     timeout,
+	})
+	if err != nil {
+		return err
+	}
+	if status := op.GetError(); status != nil {
+		return fmt.Errorf("operation %q failed: %s", op.GetName(), status.GetMessage())
+	}
+	return nil
+}
+
+// deleteClusterAndWait deletes the cluster through the cluster manager and
+// waits until it is gone.
+func deleteClusterAndWait(ctx context.Context, conn *grpc.ClientConn, project, org, cluster string) error {
+	client := clustermanagergrpcpb.NewClustersServiceClient(conn)
+	op, err := client.DeleteCluster(ctx, &clusterdeletiongrpcpb.DeleteClusterRequest{
+		ClusterName: cluster,
+		Project:     project,
+		Org:         org,
+	})
+	if err != nil {
+		return fmt.Errorf("request to delete cluster: %w", err)
+	}
+	if err := waitForOperation(ctx, client, op, deleteTimeout); err != nil {
+		return fmt.Errorf("delete cluster %q: %w", cluster, err)
+	}
+	return nil
+}
+
 var clusterDeleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete a cluster in a project",
@@ -30,6 +77,11 @@ var clusterDeleteCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, argv []string) error {
 		projectName := ClusterCmdViper.GetString(orgutil.KeyProject)
 		orgName := ClusterCmdViper.GetString(orgutil.KeyOrganization)
+		if err := cmdutils.Confirm(cmd, deleteYes, fmt.Sprintf("Delete cluster %q", argv[0]),
+			fmt.Sprintf("organization: %s", orgutil.QualifiedOrg(projectName, orgName)),
+			"all solutions and assets on the cluster will be lost"); err != nil {
+			return err
+		}
 
 		ctx, conn, err := dialerutil.DialConnectionCtx(cmd.Context(), dialerutil.DialInfoParams{
 			CredName: projectName,
@@ -38,11 +90,22 @@ var clusterDeleteCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("could not create connection for the cluster deletion service: %w", err)
 		}
+		defer conn.Close()
 
+		if deleteWait {
+			if err := deleteClusterAndWait(ctx, conn, projectName, orgName, argv[0]); err != nil {
+				return err
+			}
+			fmt.Printf("cluster %q deleted\n", argv[0])
+			return nil
+		}
 		return deleteCluster(ctx, conn, argv[0])
 	},
 }
 
 func init() {
 	ClusterCmd.AddCommand(clusterDeleteCmd)
+	clusterDeleteCmd.Flags().BoolVar(&deleteWait, "wait", false, "Wait until the cluster has been deleted.")
+	clusterDeleteCmd.Flags().DurationVar(&deleteTimeout, "timeout", defaultDeleteTimeout, "Maximum time to wait for the deletion with --wait.")
+	cmdutils.AddYesFlagVar(clusterDeleteCmd, &deleteYes)
 }
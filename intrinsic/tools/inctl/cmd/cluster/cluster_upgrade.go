@@ -4,185 +4,43 @@ package cluster
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 
-	fmpb "google.golang.org/protobuf/types/known/fieldmaskpb"
-	clustermanagergrpcpb "intrinsic/frontend/cloud/api/clustermanager_api_go_grpc_proto"
-
-	clustermanagercpb "intrinsic/frontend/cloud/api/clustermanager_api_go_grpc_proto"
+	"intrinsic/assets/cmdutils"
 	"intrinsic/frontend/cloud/devicemanager/info"
-	"intrinsic/frontend/cloud/devicemanager/messages"
+	"intrinsic/frontend/cloud/devicemanager/upgradeclient"
 	"intrinsic/skills/tools/skill/cmd/dialerutil"
 	"intrinsic/tools/inctl/auth"
+	"intrinsic/tools/inctl/cmd/root"
 	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+const (
+	defaultUpgradeWaitTimeout = 30 * time.Minute
+	upgradePollInterval       = 30 * time.Second
 )
 
 var (
-	clusterName  string
-	rollbackFlag bool
+	clusterName        string
+	rollbackFlag       bool
+	runYes             bool
+	runHooksFile       string
+	runUpgradeWaitTime time.Duration
 )
 
-// client helps run auth'ed requests for a specific cluster
+// client wraps the upgrade client of a cluster together with the connection it uses.
 type client struct {
-	client      *http.Client
-	tokenSource *auth.ProjectToken
-	cluster     string
-	project     string
-	org         string
-	grpcConn    *grpc.ClientConn
-	grpcClient  clustermanagergrpcpb.ClustersServiceClient
-}
-
-// do wraps http.Client.Do with Auth
-func (c *client) do(req *http.Request) (*http.Response, error) {
-	req, err := c.tokenSource.HTTPAuthorization(req)
-	if err != nil {
-		return nil, fmt.Errorf("auth token for %q %s: %w", req.Method, req.URL.String(), err)
-	}
-	return c.client.Do(req)
-}
-
-// runReq runs a |method| request with url and returns the response/error
-func (c *client) runReq(ctx context.Context, method string, url url.URL, body io.Reader) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, method, url.String(), body)
-	if err != nil {
-		return nil, fmt.Errorf("create %q request for %s: %w", method, url.String(), err)
-	}
-	resp, err := c.do(req)
-	if err != nil {
-		return nil, fmt.Errorf("%q request for %s: %w", req.Method, req.URL.String(), err)
-	}
-	// read body first as error response might also be in the body
-	rb, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("response %q request for %s: %w", req.Method, req.URL.String(), err)
-	}
-	switch resp.StatusCode {
-	case http.StatusOK:
-	default:
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, rb)
-	}
-	return rb, nil
-}
-
-// status queries the update status of a cluster
-func (c *client) status(ctx context.Context) (*info.Info, error) {
-	v := url.Values{}
-	v.Set("cluster", c.cluster)
-	u := newClusterUpdateURL(c.project, "/state", v)
-	b, err := c.runReq(ctx, http.MethodGet, u, nil)
-	if err != nil {
-		return nil, err
-	}
-	ui := &info.Info{}
-	if err := json.Unmarshal(b, ui); err != nil {
-		return nil, fmt.Errorf("unmarshal json response for status: %w", err)
-	}
-	return ui, nil
-}
-
-// setMode runs a request to set the update mode
-func (c *client) setMode(ctx context.Context, mode string) error {
-	pbm := encodeUpdateMode(mode)
-	if pbm == clustermanagercpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_UNSPECIFIED {
-		return fmt.Errorf("invalid mode: %s", mode)
-	}
-	req := clustermanagercpb.UpdateClusterRequest{
-		Project: c.project,
-		Org:     c.org,
-		Cluster: &clustermanagercpb.Cluster{
-			ClusterName: c.cluster,
-			UpdateMode:  pbm,
-		},
-		UpdateMask: &fmpb.FieldMask{Paths: []string{"update_mode"}},
-	}
-	_, err := c.grpcClient.UpdateCluster(ctx, &req)
-	if err != nil {
-		return fmt.Errorf("update cluster: %w", err)
-	}
-	return nil
-}
-
-// encodeUpdateMode encodes a mode string to a proto definition
-func encodeUpdateMode(mode string) clustermanagercpb.PlatformUpdateMode {
-	switch mode {
-	case "off":
-		return clustermanagercpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_OFF
-	case "on":
-		return clustermanagercpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_ON
-	case "automatic":
-		return clustermanagercpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_AUTOMATIC
-	default:
-		return clustermanagercpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_UNSPECIFIED
-	}
-}
-
-// decodeUpdateMode decodes a mode proto definition into a string
-func decodeUpdateMode(mode clustermanagercpb.PlatformUpdateMode) string {
-	switch mode {
-	case clustermanagercpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_OFF:
-		return "off"
-	case clustermanagercpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_ON:
-		return "on"
-	case clustermanagercpb.PlatformUpdateMode_PLATFORM_UPDATE_MODE_AUTOMATIC:
-		return "automatic"
-	default:
-		return "unknown"
-	}
-}
-
-// getMode runs a request to read the update mode
-func (c *client) getMode(ctx context.Context) (string, error) {
-	req := clustermanagercpb.GetClusterRequest{
-		Project:   c.project,
-		Org:       c.org,
-		ClusterId: c.cluster,
-	}
-	cluster, err := c.grpcClient.GetCluster(ctx, &req)
-	if err != nil {
-		return "", fmt.Errorf("cluster status: %w", err)
-	}
-	mode := cluster.GetUpdateMode()
-	return decodeUpdateMode(mode), nil
-}
-
-// clusterProjectTarget queries the update target for a cluster in a project
-func (c *client) clusterProjectTarget(ctx context.Context) (*messages.ClusterProjectTargetResponse, error) {
-	v := url.Values{}
-	v.Set("cluster", c.cluster)
-	u := newClusterUpdateURL(c.project, "/projecttarget", v)
-	b, err := c.runReq(ctx, http.MethodGet, u, nil)
-	if err != nil {
-		return nil, err
-	}
-	r := &messages.ClusterProjectTargetResponse{}
-	if err := json.Unmarshal(b, r); err != nil {
-		return nil, fmt.Errorf("unmarshal json response for status: %w", err)
-	}
-	return r, nil
-}
-
-// run runs an update if one is pending
-func (c *client) run(ctx context.Context, rollback bool) error {
-	v := url.Values{}
-	v.Set("cluster", c.cluster)
-	if rollback {
-		v.Set("rollback", "y")
-	}
-	u := newClusterUpdateURL(c.project, "/run", v)
-	_, err := c.runReq(ctx, http.MethodPost, u, nil)
-	return err
+	*upgradeclient.Client
+	grpcConn *grpc.ClientConn
 }
 
 func (c *client) close() error {
@@ -210,15 +68,6 @@ func newTokenSource(project string) (*auth.ProjectToken, error) {
 	return token, nil
 }
 
-func newClusterUpdateURL(project string, subPath string, values url.Values) url.URL {
-	return url.URL{
-		Scheme:   "https",
-		Host:     fmt.Sprintf("www.endpoints.%s.cloud.goog", project),
-		Path:     filepath.Join("/api/clusterupdate/", subPath),
-		RawQuery: values.Encode(),
-	}
-}
-
 func newClient(ctx context.Context, org, project, cluster string) (context.Context, client, error) {
 	ts, err := newTokenSource(project)
 	if err != nil {
@@ -234,13 +83,12 @@ func newClient(ctx context.Context, org, project, cluster string) (context.Conte
 		return nil, client{}, fmt.Errorf("create grpc client: %w", err)
 	}
 	return ctx, client{
-		client:      http.DefaultClient,
-		tokenSource: ts,
-		cluster:     cluster,
-		project:     project,
-		org:         org,
-		grpcConn:    conn,
-		grpcClient:  clustermanagergrpcpb.NewClustersServiceClient(conn),
+		Client: upgradeclient.New(conn, ts, upgradeclient.Options{
+			Project: project,
+			Org:     org,
+			Cluster: cluster,
+		}),
+		grpcConn: conn,
 	}, nil
 }
 
@@ -270,14 +118,14 @@ var modeCmd = &cobra.Command{
 		defer c.close()
 		switch len(args) {
 		case 0:
-			mode, err := c.getMode(ctx)
+			mode, err := c.Mode(ctx)
 			if err != nil {
 				return fmt.Errorf("get cluster upgrade mode:\n%w", err)
 			}
 			fmt.Printf("update mechanism mode: %s\n", mode)
 			return nil
 		case 1:
-			if err := c.setMode(ctx, args[0]); err != nil {
+			if err := c.SetMode(ctx, upgradeclient.Mode(args[0])); err != nil {
 				return fmt.Errorf("set cluster upgrade mode:\n%w", err)
 			}
 			return nil
@@ -314,7 +162,7 @@ var showTargetCmd = &cobra.Command{
 			return fmt.Errorf("cluster upgrade client:\n%w", err)
 		}
 		defer c.close()
-		r, err := c.clusterProjectTarget(ctx)
+		r, err := c.ProjectTarget(ctx)
 		if err != nil {
 			return fmt.Errorf("cluster status:\n%w", err)
 		}
@@ -331,6 +179,26 @@ Run an upgrade of the specified cluster, if new software is available.
 
 This command will execute right away. Please make sure the cluster is safe
 and ready to upgrade. It might reboot in the process.
+
+Use --hooks to gate the upgrade on checks. The hooks file is a YAML file with
+the lists 'pre_upgrade' and 'post_upgrade'. Each hook has a name and either a
+local 'command' or a cluster-side 'check' (supported: no_running_process), and
+optionally a 'timeout' and 'optional: true'. The upgrade only starts if all
+pre-upgrade hooks pass. Post-upgrade hooks run once the cluster runs the new
+version. Commands get the environment variables INTRINSIC_PROJECT,
+INTRINSIC_ORG, INTRINSIC_CLUSTER and INTRINSIC_UPGRADE_PHASE.
+
+Use --notify or --notify_desktop to wait for the upgrade and report every
+change of its state, like 'inctl cluster upgrade wait'.
+
+Example hooks file:
+  pre_upgrade:
+  - name: verify no process is running
+    check: no_running_process
+  post_upgrade:
+  - name: run smoke BT
+    command: ["./smoke_test.sh"]
+    timeout: 10m
 `
 
 // runCmd is the command to execute an update if available
@@ -345,22 +213,119 @@ var runCmd = &cobra.Command{
 		projectName := ClusterCmdViper.GetString(orgutil.KeyProject)
 		orgName := ClusterCmdViper.GetString(orgutil.KeyOrganization)
 		qOrgName := orgutil.QualifiedOrg(projectName, orgName)
+		hooks := &upgradeHooks{}
+		if runHooksFile != "" {
+			var err error
+			if hooks, err = readUpgradeHooks(runHooksFile); err != nil {
+				return err
+			}
+		}
+		action := fmt.Sprintf("Upgrade cluster %q in %q", clusterName, qOrgName)
+		if rollbackFlag {
+			action = fmt.Sprintf("Roll back cluster %q in %q", clusterName, qOrgName)
+		}
+		if err := cmdutils.Confirm(cmd, runYes, action,
+			"the update starts right away",
+			"the cluster might reboot and running solutions will be interrupted"); err != nil {
+			return err
+		}
 		ctx, c, err := newClient(ctx, orgName, projectName, clusterName)
 		if err != nil {
 			return fmt.Errorf("cluster upgrade client:\n%w", err)
 		}
 		defer c.close()
-		err = c.run(ctx, rollbackFlag)
+		env := hookEnv{project: projectName, org: orgName, cluster: clusterName}
+
+		results := runHooks(ctx, phasePreUpgrade, hooks.PreUpgrade, c.grpcConn, env)
+		if err := printHookResults(os.Stdout, results); err != nil {
+			return fmt.Errorf("upgrade not started, pre-upgrade hooks failed: %w", err)
+		}
+
+		// The version to wait for has to be determined before the upgrade starts.
+		var wantBase, wantOS string
+		if len(hooks.PostUpgrade) > 0 {
+			if rollbackFlag {
+				ui, err := c.Status(ctx)
+				if err != nil {
+					return fmt.Errorf("cluster status:\n%w", err)
+				}
+				wantBase, wantOS = ui.RollbackBase, ui.RollbackOS
+			} else {
+				r, err := c.ProjectTarget(ctx)
+				if err != nil {
+					return fmt.Errorf("cluster target:\n%w", err)
+				}
+				wantBase, wantOS = r.Base, r.OS
+			}
+		}
+
+		err = c.Run(ctx, upgradeclient.RunOptions{Rollback: rollbackFlag})
 		if err != nil {
 			return fmt.Errorf("cluster upgrade run:\n%w", err)
 		}
 
 		fmt.Printf("update for cluster %q in %q kicked off successfully.\n", clusterName, qOrgName)
-		fmt.Printf("monitor running `inctl cluster upgrade --org %s --cluster %s\n`", qOrgName, clusterName)
+		n := newUpgradeNotifier(projectName, clusterName)
+		if len(hooks.PostUpgrade) == 0 && n == nil {
+			fmt.Printf("monitor running `inctl cluster upgrade --org %s --cluster %s\n`", qOrgName, clusterName)
+			return nil
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, runUpgradeWaitTime)
+		defer cancel()
+		if len(hooks.PostUpgrade) == 0 {
+			return watchUpgrade(waitCtx, c, clusterName, os.Stdout, n, upgradeclient.Finished)
+		}
+		fmt.Printf("waiting for cluster %q to run flowstate %q and os %q\n", clusterName, wantBase, wantOS)
+		reached := func(ui *info.Info) bool {
+			return ui.CurrentBase == wantBase && ui.CurrentOS == wantOS
+		}
+		if err := watchUpgrade(waitCtx, c, clusterName, os.Stdout, n, reached); err != nil {
+			return fmt.Errorf("post-upgrade hooks not run: %w", err)
+		}
+		results = runHooks(ctx, phasePostUpgrade, hooks.PostUpgrade, c.grpcConn, env)
+		if err := printHookResults(os.Stdout, results); err != nil {
+			return fmt.Errorf("cluster was upgraded, but post-upgrade hooks failed: %w", err)
+		}
 		return nil
 	},
 }
 
+const waitCmdDesc = `
+Wait until the upgrade running on the cluster finished, i.e., the cluster is deployed and runs its
+target versions. Every change of the upgrade state is printed and, with --notify, posted as JSON
+to a webhook, e.g., of a Slack or Teams channel.
+`
+
+// waitCmd is the command to wait for a running upgrade
+var waitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Wait until the running upgrade finished.",
+	Long:  waitCmdDesc,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		projectName := ClusterCmdViper.GetString(orgutil.KeyProject)
+		orgName := ClusterCmdViper.GetString(orgutil.KeyOrganization)
+		ctx, c, err := newClient(ctx, orgName, projectName, clusterName)
+		if err != nil {
+			return fmt.Errorf("cluster upgrade client:\n%w", err)
+		}
+		defer c.close()
+
+		waitCtx, cancel := context.WithTimeout(ctx, runUpgradeWaitTime)
+		defer cancel()
+		return watchUpgrade(waitCtx, c, clusterName, os.Stdout, newUpgradeNotifier(projectName, clusterName), upgradeclient.Finished)
+	},
+}
+
+// addNotifyFlags adds the flags to notify about the upgrade status to cmd.
+func addNotifyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&notifyURL, "notify", "", "URL of a webhook to post every change of the upgrade status to as JSON.")
+	cmd.Flags().BoolVar(&notifyDesktop, "notify_desktop", false, "Show a desktop notification for every change of the upgrade status.")
+}
+
 // clusterUpgradeCmd is the base command to query the upgrade state
 var clusterUpgradeCmd = &cobra.Command{
 	Use:   "upgrade",
@@ -377,25 +342,64 @@ var clusterUpgradeCmd = &cobra.Command{
 			return fmt.Errorf("cluster upgrade client:\n%w", err)
 		}
 		defer c.close()
-		ui, err := c.status(ctx)
+		ui, err := c.Status(ctx)
 		if err != nil {
 			return fmt.Errorf("cluster status:\n%w", err)
 		}
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		rollback := ui.RollbackOS != "" && ui.RollbackBase != ""
-		fmt.Fprintf(w, "project\tcluster\tmode\tstate\trollback available\tflowstate\tos\n")
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\t%s\t%s\n", projectName, clusterName, ui.Mode, ui.State, rollback, ui.CurrentBase, ui.CurrentOS)
-		w.Flush()
+		if ui.Cluster == "" {
+			ui.Cluster = clusterName
+		}
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+		prtr.Print(&upgradeStatus{
+			Project:           projectName,
+			Info:              ui,
+			RollbackAvailable: ui.RollbackAvailable(),
+		})
 		return nil
 	},
 }
 
+// upgradeStatus is the update state of a cluster as printed by 'inctl cluster upgrade'.
+type upgradeStatus struct {
+	Project string `json:"project"`
+	*info.Info
+	RollbackAvailable bool `json:"rollbackAvailable"`
+}
+
+func (s *upgradeStatus) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "project\tcluster\tmode\tstate\trollback available\tflowstate\tos\n")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\t%s\t%s\n", s.Project, s.Cluster, s.Mode, s.State, s.RollbackAvailable, s.CurrentBase, s.CurrentOS)
+	w.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
 func init() {
 	ClusterCmd.AddCommand(clusterUpgradeCmd)
 	clusterUpgradeCmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "Name of cluster to upgrade.")
 	clusterUpgradeCmd.MarkPersistentFlagRequired("cluster")
 	clusterUpgradeCmd.AddCommand(runCmd)
 	runCmd.PersistentFlags().BoolVar(&rollbackFlag, "rollback", false, "Whether to trigger a rollback update instead")
+	cmdutils.AddYesFlagVar(runCmd, &runYes)
+	runCmd.Flags().StringVar(&runHooksFile, "hooks", "", "YAML file with pre- and post-upgrade hooks to run and gate on.")
+	runCmd.Flags().DurationVar(&runUpgradeWaitTime, "upgrade_timeout", defaultUpgradeWaitTimeout, "Maximum time to wait for the upgrade to finish if --hooks or --notify is set.")
+	addNotifyFlags(runCmd)
+	clusterUpgradeCmd.AddCommand(waitCmd)
+	waitCmd.Flags().DurationVar(&runUpgradeWaitTime, "upgrade_timeout", defaultUpgradeWaitTimeout, "Maximum time to wait for the upgrade to finish.")
+	addNotifyFlags(waitCmd)
+	clusterUpgradeCmd.AddCommand(runFleetCmd)
+	runFleetCmd.Flags().StringSliceVar(&fleetClusters, "clusters", nil, "Comma-separated names of the clusters to upgrade.")
+	runFleetCmd.Flags().BoolVar(&fleetAllInOrg, "all_in_org", false, "Upgrade all clusters of the organization.")
+	runFleetCmd.MarkFlagsMutuallyExclusive("clusters", "all_in_org")
+	runFleetCmd.Flags().IntVar(&fleetParallelism, "parallelism", 1, "Maximum number of clusters to upgrade at the same time.")
+	runFleetCmd.Flags().BoolVar(&rollbackFlag, "rollback", false, "Whether to trigger rollback updates instead")
+	cmdutils.AddYesFlagVar(runFleetCmd, &runYes)
+	runFleetCmd.Flags().DurationVar(&runUpgradeWaitTime, "upgrade_timeout", defaultUpgradeWaitTimeout, "Maximum time to wait for the upgrade of each cluster to finish.")
+	addNotifyFlags(runFleetCmd)
 	clusterUpgradeCmd.AddCommand(modeCmd)
 	clusterUpgradeCmd.AddCommand(showTargetCmd)
 }
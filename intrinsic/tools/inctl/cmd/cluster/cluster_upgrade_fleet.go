@@ -0,0 +1,276 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"intrinsic/assets/cmdutils"
+	clusterdiscoverygrpcpb "intrinsic/frontend/cloud/api/clusterdiscovery_api_go_grpc_proto"
+	"intrinsic/frontend/cloud/devicemanager/info"
+	"intrinsic/frontend/cloud/devicemanager/upgradeclient"
+	"intrinsic/skills/tools/skill/cmd/dialerutil"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+// Results of the upgrade of a single cluster of a fleet.
+const (
+	fleetResultUpgraded = "upgraded"
+	fleetResultUpToDate = "up to date"
+	fleetResultFailed   = "failed"
+)
+
+var (
+	fleetClusters    []string
+	fleetAllInOrg    bool
+	fleetParallelism int
+)
+
+const runFleetCmdDesc = `
+Run upgrades of several clusters in one invocation and wait for them to finish.
+
+Select the clusters with --clusters a,b,c or all clusters of the organization
+with --all_in_org. At most --parallelism clusters are upgraded at the same time,
+the next cluster starts once one of them finished. Clusters which already run
+their target versions are skipped. A failed cluster does not stop the others.
+
+Every change of the upgrade state of a cluster is printed and, with --notify or
+--notify_desktop, reported like by 'inctl cluster upgrade wait'. A summary of
+all clusters is printed at the end, in the format selected with --output. The
+command fails if the upgrade of any cluster failed.
+`
+
+// fleetClusterResult is the outcome of the upgrade of a single cluster.
+type fleetClusterResult struct {
+	Cluster  string        `json:"cluster"`
+	Result   string        `json:"result"`
+	Base     string        `json:"flowstate,omitempty"`
+	OS       string        `json:"os,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// fleetSummary is printed by 'inctl cluster upgrade run-fleet' once all clusters are done.
+type fleetSummary struct {
+	Project  string                `json:"project"`
+	Clusters []*fleetClusterResult `json:"clusters"`
+}
+
+func (s *fleetSummary) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "cluster\tresult\tflowstate\tos\tduration\terror\n")
+	for _, r := range s.Clusters {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Cluster, r.Result, r.Base, r.OS, r.Duration.Round(time.Second), r.Error)
+	}
+	w.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// failed returns the number of clusters which could not be upgraded.
+func (s *fleetSummary) failed() int {
+	n := 0
+	for _, r := range s.Clusters {
+		if r.Result == fleetResultFailed {
+			n++
+		}
+	}
+	return n
+}
+
+// listOrgClusters returns the names of all clusters of the organization.
+func listOrgClusters(ctx context.Context, org, project string) ([]string, error) {
+	ctx, conn, err := dialerutil.DialConnectionCtx(ctx, dialerutil.DialInfoParams{
+		CredName: project,
+		CredOrg:  org,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create connection options for the cluster discovery service: %w", err)
+	}
+	defer conn.Close()
+	resp, err := clusterdiscoverygrpcpb.NewClusterDiscoveryServiceClient(conn).ListClusterDescriptions(
+		ctx, &clusterdiscoverygrpcpb.ListClusterDescriptionsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("request to list clusters failed: %w", err)
+	}
+	var names []string
+	for _, c := range resp.GetClusters() {
+		names = append(names, c.GetClusterName())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// upgradeFleetCluster upgrades a single cluster and waits until it runs the new versions.
+func upgradeFleetCluster(ctx context.Context, org, project, cluster string, out io.Writer) *fleetClusterResult {
+	start := time.Now()
+	r := &fleetClusterResult{Cluster: cluster, Result: fleetResultFailed}
+	fail := func(format string, args ...any) *fleetClusterResult {
+		r.Error = fmt.Sprintf(format, args...)
+		r.Duration = time.Since(start)
+		fmt.Fprintf(out, "%s: upgrade of cluster %q failed: %s\n", time.Now().Format(time.TimeOnly), cluster, r.Error)
+		return r
+	}
+
+	ctx, c, err := newClient(ctx, org, project, cluster)
+	if err != nil {
+		return fail("cluster upgrade client: %v", err)
+	}
+	defer c.close()
+
+	// The version to wait for has to be determined before the upgrade starts.
+	ui, err := c.Status(ctx)
+	if err != nil {
+		return fail("cluster status: %v", err)
+	}
+	var wantBase, wantOS string
+	if rollbackFlag {
+		if !ui.RollbackAvailable() {
+			return fail("no rollback available")
+		}
+		wantBase, wantOS = ui.RollbackBase, ui.RollbackOS
+	} else {
+		t, err := c.ProjectTarget(ctx)
+		if err != nil {
+			return fail("cluster target: %v", err)
+		}
+		wantBase, wantOS = t.Base, t.OS
+	}
+	reached := func(ui *info.Info) bool {
+		return ui.CurrentBase == wantBase && ui.CurrentOS == wantOS
+	}
+	if !rollbackFlag && reached(ui) {
+		r.Result = fleetResultUpToDate
+		r.Base, r.OS = ui.CurrentBase, ui.CurrentOS
+		r.Duration = time.Since(start)
+		return r
+	}
+
+	if err := c.Run(ctx, upgradeclient.RunOptions{Rollback: rollbackFlag}); err != nil {
+		return fail("cluster upgrade run: %v", err)
+	}
+	fmt.Fprintf(out, "%s: update for cluster %q kicked off, waiting for flowstate %q and os %q\n",
+		time.Now().Format(time.TimeOnly), cluster, wantBase, wantOS)
+
+	waitCtx, cancel := context.WithTimeout(ctx, runUpgradeWaitTime)
+	defer cancel()
+	if err := watchUpgrade(waitCtx, c, cluster, out, newUpgradeNotifier(project, cluster), reached); err != nil {
+		return fail("%v", err)
+	}
+	r.Result = fleetResultUpgraded
+	r.Base, r.OS = wantBase, wantOS
+	r.Duration = time.Since(start)
+	return r
+}
+
+// upgradeFleet upgrades the clusters with at most parallelism upgrades at the same time. The
+// results are in the order of clusters.
+func upgradeFleet(ctx context.Context, org, project string, clusters []string, parallelism int, out io.Writer) []*fleetClusterResult {
+	results := make([]*fleetClusterResult, len(clusters))
+	// Progress of concurrent upgrades is written line by line.
+	out = &syncWriter{w: out}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = upgradeFleetCluster(ctx, org, project, cluster, out)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// syncWriter serializes the writes of concurrent upgrades.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// runFleetCmd is the command to upgrade several clusters
+var runFleetCmd = &cobra.Command{
+	Use:   "run-fleet",
+	Short: "Run upgrades of several clusters.",
+	Long:  runFleetCmdDesc,
+	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, _ []string) error {
+		// --cluster is required for all other upgrade commands. Cobra validates required flags after
+		// PreRunE, so it can be lifted here.
+		return cmd.InheritedFlags().SetAnnotation("cluster", cobra.BashCompOneRequiredFlag, []string{"false"})
+	},
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
+
+		if clusterName != "" {
+			return fmt.Errorf("--cluster is not supported by run-fleet, use --clusters instead")
+		}
+		if len(fleetClusters) == 0 && !fleetAllInOrg {
+			return fmt.Errorf("one of --clusters or --all_in_org is required")
+		}
+		if fleetParallelism < 1 {
+			return fmt.Errorf("--parallelism must be at least 1, got %d", fleetParallelism)
+		}
+		prtr, err := printer.NewPrinter(root.FlagOutput)
+		if err != nil {
+			return err
+		}
+
+		projectName := ClusterCmdViper.GetString(orgutil.KeyProject)
+		orgName := ClusterCmdViper.GetString(orgutil.KeyOrganization)
+		qOrgName := orgutil.QualifiedOrg(projectName, orgName)
+		clusters := fleetClusters
+		if fleetAllInOrg {
+			if clusters, err = listOrgClusters(ctx, orgName, projectName); err != nil {
+				return err
+			}
+			if len(clusters) == 0 {
+				return fmt.Errorf("no clusters found in %q", qOrgName)
+			}
+		}
+
+		action := fmt.Sprintf("Upgrade %d clusters in %q: %s", len(clusters), qOrgName, strings.Join(clusters, ", "))
+		if rollbackFlag {
+			action = fmt.Sprintf("Roll back %d clusters in %q: %s", len(clusters), qOrgName, strings.Join(clusters, ", "))
+		}
+		if err := cmdutils.Confirm(cmd, runYes, action,
+			"the updates start right away",
+			"the clusters might reboot and running solutions will be interrupted"); err != nil {
+			return err
+		}
+
+		// Progress goes to stderr if the summary is printed as JSON or YAML, so that it stays parseable.
+		var out io.Writer = os.Stdout
+		if root.FlagOutput != printer.TextOutputFormat {
+			out = os.Stderr
+		}
+		summary := &fleetSummary{
+			Project:  projectName,
+			Clusters: upgradeFleet(ctx, orgName, projectName, clusters, fleetParallelism, out),
+		}
+		prtr.Print(summary)
+		if n := summary.failed(); n > 0 {
+			return fmt.Errorf("upgrade failed for %d of %d clusters", n, len(clusters))
+		}
+		return nil
+	},
+}
@@ -0,0 +1,238 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"google.golang.org/grpc"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	rmdpb "intrinsic/executive/proto/run_metadata_go_proto"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultHookTimeout = 5 * time.Minute
+
+	phasePreUpgrade  = "pre-upgrade"
+	phasePostUpgrade = "post-upgrade"
+
+	// checkNoRunningProcess fails if the executive is running a process.
+	checkNoRunningProcess = "no_running_process"
+)
+
+// upgradeHooks is the format of the file passed with --hooks.
+//
+// Example:
+//
+//	pre_upgrade:
+//	- name: verify no process is running
+//	  check: no_running_process
+//	post_upgrade:
+//	- name: run smoke BT
+//	  command: ["./smoke_test.sh"]
+//	  timeout: 10m
+type upgradeHooks struct {
+	PreUpgrade  []*upgradeHook `json:"pre_upgrade"`
+	PostUpgrade []*upgradeHook `json:"post_upgrade"`
+}
+
+// upgradeHook is either a local command or a cluster-side check.
+type upgradeHook struct {
+	Name string `json:"name"`
+	// Command is run locally without a shell. Relative paths are resolved against the directory of
+	// the hooks file.
+	Command []string `json:"command,omitempty"`
+	// Check is the name of a cluster-side check.
+	Check string `json:"check,omitempty"`
+	// Timeout is a duration such as "30s". Defaults to five minutes.
+	Timeout string `json:"timeout,omitempty"`
+	// Optional hooks are reported, but do not gate the upgrade.
+	Optional bool `json:"optional,omitempty"`
+
+	timeout time.Duration
+}
+
+// clusterChecks are the cluster-side checks which hooks can refer to.
+var clusterChecks = map[string]func(ctx context.Context, conn *grpc.ClientConn) error{
+	checkNoRunningProcess: checkExecutiveIdle,
+}
+
+// hookEnv is passed to hook commands, so that scripts can target the upgraded cluster.
+type hookEnv struct {
+	project string
+	org     string
+	cluster string
+}
+
+func (e hookEnv) environ(phase string) []string {
+	return append(os.Environ(),
+		"INTRINSIC_PROJECT="+e.project,
+		"INTRINSIC_ORG="+e.org,
+		"INTRINSIC_CLUSTER="+e.cluster,
+		"INTRINSIC_UPGRADE_PHASE="+phase,
+	)
+}
+
+// hookResult is the outcome of a single hook.
+type hookResult struct {
+	phase    string
+	hook     *upgradeHook
+	err      error
+	duration time.Duration
+}
+
+func (r *hookResult) gates() bool {
+	return r.err != nil && !r.hook.Optional
+}
+
+// readUpgradeHooks reads and validates a hooks file.
+func readUpgradeHooks(path string) (*upgradeHooks, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read hooks file: %w", err)
+	}
+	hooks := &upgradeHooks{}
+	if err := yaml.UnmarshalStrict(b, hooks); err != nil {
+		return nil, fmt.Errorf("parse hooks file %q: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for i, h := range hooks.PreUpgrade {
+		if err := h.validate(dir); err != nil {
+			return nil, fmt.Errorf("%s hook %d (%q): %w", phasePreUpgrade, i, h.Name, err)
+		}
+	}
+	for i, h := range hooks.PostUpgrade {
+		if err := h.validate(dir); err != nil {
+			return nil, fmt.Errorf("%s hook %d (%q): %w", phasePostUpgrade, i, h.Name, err)
+		}
+	}
+	return hooks, nil
+}
+
+func (h *upgradeHook) validate(dir string) error {
+	if h.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch {
+	case len(h.Command) > 0 && h.Check != "":
+		return fmt.Errorf("only one of command and check can be set")
+	case len(h.Command) > 0:
+		if strings.Contains(h.Command[0], "/") && !filepath.IsAbs(h.Command[0]) {
+			h.Command[0] = filepath.Join(dir, h.Command[0])
+		}
+	case h.Check != "":
+		if _, ok := clusterChecks[h.Check]; !ok {
+			return fmt.Errorf("unknown check %q, supported checks: %s", h.Check, checkNoRunningProcess)
+		}
+	default:
+		return fmt.Errorf("one of command and check is required")
+	}
+	h.timeout = defaultHookTimeout
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+		h.timeout = d
+	}
+	return nil
+}
+
+// runHooks runs all hooks of a phase in order, also after failures so that all results are
+// reported.
+func runHooks(ctx context.Context, phase string, hooks []*upgradeHook, conn *grpc.ClientConn, env hookEnv) []*hookResult {
+	var results []*hookResult
+	for _, h := range hooks {
+		fmt.Printf("running %s hook %q\n", phase, h.Name)
+		start := time.Now()
+		hctx, cancel := context.WithTimeout(ctx, h.timeout)
+		var err error
+		if h.Check != "" {
+			err = clusterChecks[h.Check](hctx, conn)
+		} else {
+			err = runHookCommand(hctx, h.Command, env.environ(phase))
+		}
+		if errors.Is(hctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %v: %w", h.timeout, err)
+		}
+		cancel()
+		results = append(results, &hookResult{phase: phase, hook: h, err: err, duration: time.Since(start)})
+	}
+	return results
+}
+
+func runHookCommand(ctx context.Context, command []string, env []string) error {
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = env
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// checkExecutiveIdle fails if a behavior tree is being executed.
+func checkExecutiveIdle(ctx context.Context, conn *grpc.ClientConn) error {
+	client := execgrpcpb.NewExecutiveServiceClient(conn)
+	resp, err := client.ListOperations(ctx, &lrpb.ListOperationsRequest{})
+	if err != nil {
+		return fmt.Errorf("list executive operations: %w", err)
+	}
+	for _, op := range resp.GetOperations() {
+		metadata := &rmdpb.RunMetadata{}
+		if err := op.GetMetadata().UnmarshalTo(metadata); err != nil {
+			return fmt.Errorf("unmarshal RunMetadata of operation %q: %w", op.GetName(), err)
+		}
+		switch state := metadata.GetBehaviorTreeState(); state {
+		case btpb.BehaviorTree_RUNNING, btpb.BehaviorTree_SUSPENDING, btpb.BehaviorTree_CANCELING:
+			return fmt.Errorf("process %q is %s", metadata.GetBehaviorTree().GetName(), state)
+		}
+	}
+	return nil
+}
+
+// printHookResults prints one line per hook and returns an error if a required hook failed.
+func printHookResults(w io.Writer, results []*hookResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "phase\thook\tresult\tduration\tdetails\n")
+	var failed []string
+	for _, r := range results {
+		result, details := "passed", ""
+		if r.err != nil {
+			result, details = "failed", strings.ReplaceAll(r.err.Error(), "\n", " ")
+			if r.hook.Optional {
+				result = "failed (optional)"
+			}
+		}
+		if r.gates() {
+			failed = append(failed, r.hook.Name)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%s\n", r.phase, r.hook.Name, result, r.duration.Truncate(time.Millisecond), details)
+	}
+	tw.Flush()
+	if len(failed) > 0 {
+		return fmt.Errorf("%d hook(s) failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReadUpgradeHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *upgradeHooks
+		wantErr string
+	}{
+		{
+			name: "valid",
+			content: `
+pre_upgrade:
+- name: idle
+  check: no_running_process
+post_upgrade:
+- name: smoke test
+  command: ["./smoke_test.sh", "--fast"]
+  timeout: 10m
+  optional: true
+- name: from path
+  command: ["true"]
+`,
+			want: &upgradeHooks{
+				PreUpgrade: []*upgradeHook{
+					{Name: "idle", Check: checkNoRunningProcess, timeout: defaultHookTimeout},
+				},
+				PostUpgrade: []*upgradeHook{
+					{Name: "smoke test", Command: []string{"smoke_test.sh", "--fast"}, Timeout: "10m", Optional: true, timeout: 10 * time.Minute},
+					{Name: "from path", Command: []string{"true"}, timeout: defaultHookTimeout},
+				},
+			},
+		},
+		{
+			name:    "unknown field",
+			content: "pre_upgrade: [{name: a, check: no_running_process, retries: 3}]",
+			wantErr: "parse hooks file",
+		},
+		{
+			name:    "missing name",
+			content: "pre_upgrade: [{check: no_running_process}]",
+			wantErr: "name is required",
+		},
+		{
+			name:    "command and check",
+			content: `post_upgrade: [{name: a, check: no_running_process, command: ["true"]}]`,
+			wantErr: "only one of command and check can be set",
+		},
+		{
+			name:    "neither command nor check",
+			content: "post_upgrade: [{name: a}]",
+			wantErr: "one of command and check is required",
+		},
+		{
+			name:    "unknown check",
+			content: "pre_upgrade: [{name: a, check: cluster_healthy}]",
+			wantErr: `unknown check "cluster_healthy"`,
+		},
+		{
+			name:    "invalid timeout",
+			content: `pre_upgrade: [{name: a, command: ["true"], timeout: 5}]`,
+			wantErr: "invalid timeout",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "hooks.yaml")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("WriteFile(%q) failed: %v", path, err)
+			}
+
+			got, err := readUpgradeHooks(path)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("readUpgradeHooks() returned error %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readUpgradeHooks() failed: %v", err)
+			}
+			// Relative commands are resolved against the directory of the hooks file.
+			for _, h := range tc.want.PostUpgrade {
+				if strings.HasSuffix(h.Command[0], ".sh") {
+					h.Command[0] = filepath.Join(dir, h.Command[0])
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(upgradeHook{})); diff != "" {
+				t.Errorf("readUpgradeHooks() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := readUpgradeHooks(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("readUpgradeHooks() of a missing file succeeded, want error")
+	}
+}
+
+func TestRunHooksFailedPreUpgradeHookGates(t *testing.T) {
+	hooks := []*upgradeHook{
+		{Name: "fails", Command: []string{"sh", "-c", "echo boom; exit 1"}, timeout: time.Minute},
+		{Name: "optional", Command: []string{"false"}, Optional: true, timeout: time.Minute},
+		{Name: "phase", Command: []string{"sh", "-c", `test "$INTRINSIC_UPGRADE_PHASE" = pre-upgrade`}, timeout: time.Minute},
+		{Name: "slow", Command: []string{"sleep", "10"}, timeout: 10 * time.Millisecond},
+	}
+
+	results := runHooks(context.Background(), phasePreUpgrade, hooks, nil, hookEnv{})
+
+	var gotFailed []string
+	for _, r := range results {
+		if r.err != nil {
+			gotFailed = append(gotFailed, r.hook.Name)
+		}
+	}
+	if diff := cmp.Diff([]string{"fails", "optional", "slow"}, gotFailed, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("runHooks() returned unexpected failed hooks (-want +got):\n%s", diff)
+	}
+	if err := results[0].err; err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("runHooks() returned error %v for %q, want the output of the command", err, hooks[0].Name)
+	}
+	if err := results[3].err; err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("runHooks() returned error %v for %q, want timeout", err, hooks[3].Name)
+	}
+
+	var out bytes.Buffer
+	err := printHookResults(&out, results)
+	if err == nil {
+		t.Fatalf("printHookResults() succeeded, want error so that the upgrade is not started")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "2 hook(s) failed: fails, slow") {
+		t.Errorf("printHookResults() returned error %q, want only the required hooks", msg)
+	}
+	if got := strings.Count(out.String(), "\n"); got != len(hooks)+1 {
+		t.Errorf("printHookResults() printed %d lines, want a header and one line per hook:\n%s", got, out.String())
+	}
+}
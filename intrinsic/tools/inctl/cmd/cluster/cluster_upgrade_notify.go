@@ -0,0 +1,178 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"intrinsic/frontend/cloud/devicemanager/info"
+	"intrinsic/frontend/cloud/devicemanager/upgradeclient"
+)
+
+const notifyTimeout = 10 * time.Second
+
+var (
+	notifyURL     string
+	notifyDesktop bool
+)
+
+// upgradeEvent is the JSON body posted to the --notify URL for every change of the upgrade status
+// of a cluster.
+type upgradeEvent struct {
+	// Text summarizes the event, so that chat webhooks, e.g., of Slack or Teams, can display it
+	// without further configuration.
+	Text          string    `json:"text"`
+	Project       string    `json:"project"`
+	Cluster       string    `json:"cluster"`
+	PreviousState string    `json:"previousState,omitempty"`
+	State         string    `json:"state,omitempty"`
+	Mode          string    `json:"mode,omitempty"`
+	CurrentBase   string    `json:"currentBase,omitempty"`
+	CurrentOS     string    `json:"currentOS,omitempty"`
+	TargetBase    string    `json:"targetBase,omitempty"`
+	TargetOS      string    `json:"targetOS,omitempty"`
+	Finished      bool      `json:"finished"`
+	Error         string    `json:"error,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// upgradeNotifier reports the upgrade status of a cluster to a webhook and the desktop.
+type upgradeNotifier struct {
+	project string
+	cluster string
+	url     string
+	desktop bool
+	client  *http.Client
+}
+
+// newUpgradeNotifier returns a notifier for the --notify flags, or nil if none is set.
+func newUpgradeNotifier(project, cluster string) *upgradeNotifier {
+	if notifyURL == "" && !notifyDesktop {
+		return nil
+	}
+	return &upgradeNotifier{
+		project: project,
+		cluster: cluster,
+		url:     notifyURL,
+		desktop: notifyDesktop,
+		client:  &http.Client{Timeout: notifyTimeout},
+	}
+}
+
+// statusChanged notifies about a new status of the cluster. prev is nil for the first status.
+func (n *upgradeNotifier) statusChanged(ctx context.Context, prev, cur *info.Info) {
+	ev := upgradeEvent{
+		Project:     n.project,
+		Cluster:     n.cluster,
+		State:       cur.State,
+		Mode:        cur.Mode,
+		CurrentBase: cur.CurrentBase,
+		CurrentOS:   cur.CurrentOS,
+		TargetBase:  cur.TargetBase,
+		TargetOS:    cur.TargetOS,
+		Finished:    upgradeclient.Finished(cur),
+		Time:        time.Now(),
+	}
+	switch {
+	case ev.Finished:
+		ev.Text = fmt.Sprintf("Cluster %q runs flowstate %q and os %q", n.cluster, cur.CurrentBase, cur.CurrentOS)
+	case prev == nil:
+		ev.Text = fmt.Sprintf("Cluster %q is in state %q", n.cluster, cur.State)
+	default:
+		ev.PreviousState = prev.State
+		ev.Text = fmt.Sprintf("Cluster %q changed from state %q to %q", n.cluster, prev.State, cur.State)
+	}
+	n.notify(ctx, ev)
+}
+
+// failed notifies that the upgrade could not be completed or watched.
+func (n *upgradeNotifier) failed(ctx context.Context, err error) {
+	n.notify(ctx, upgradeEvent{
+		Text:    fmt.Sprintf("Upgrade of cluster %q failed: %v", n.cluster, err),
+		Project: n.project,
+		Cluster: n.cluster,
+		Error:   err.Error(),
+		Time:    time.Now(),
+	})
+}
+
+// notify sends ev. Failures are printed, but do not abort the upgrade.
+func (n *upgradeNotifier) notify(ctx context.Context, ev upgradeEvent) {
+	if n.url != "" {
+		if err := n.post(ctx, ev); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not notify %s: %v\n", n.url, err)
+		}
+	}
+	if n.desktop {
+		if err := notifyDesktopUser("inctl cluster upgrade", ev.Text); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not show desktop notification: %v\n", err)
+		}
+	}
+}
+
+func (n *upgradeNotifier) post(ctx context.Context, ev upgradeEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// notifyDesktopUser shows a notification with notify-send on Linux and osascript on macOS.
+func notifyDesktopUser(title, text string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("notify-send", title, text)
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", text, title))
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, out)
+	}
+	return nil
+}
+
+// watchUpgrade waits until done returns true for the status of the cluster, prints every change of
+// the status to out and notifies n, which may be nil, about it.
+func watchUpgrade(ctx context.Context, c client, cluster string, out io.Writer, n *upgradeNotifier, done func(*info.Info) bool) error {
+	changed := func(prev, cur *info.Info) {
+		fmt.Fprintf(out, "%s: cluster %q is in state %q, running flowstate %q and os %q\n",
+			time.Now().Format(time.TimeOnly), cluster, cur.State, cur.CurrentBase, cur.CurrentOS)
+		if n != nil {
+			n.statusChanged(ctx, prev, cur)
+		}
+	}
+	err := c.Watch(ctx, upgradePollInterval, changed, done)
+	if err != nil && n != nil {
+		// ctx may be done already, the failure should still be reported.
+		notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		defer cancel()
+		n.failed(notifyCtx, err)
+	}
+	return err
+}
@@ -18,6 +18,7 @@ import (
 	backoff "github.com/cenkalti/backoff/v4"
 	"github.com/spf13/cobra"
 	"go.uber.org/multierr"
+	"intrinsic/assets/cmdutils"
 	"intrinsic/frontend/cloud/devicemanager/shared"
 	"intrinsic/tools/inctl/cmd/device/projectclient"
 	"intrinsic/tools/inctl/cmd/root"
@@ -32,6 +33,8 @@ const (
 
 var (
 	errConfigGone = fmt.Errorf("config was rejected")
+
+	configSetYes bool
 )
 
 func prettyPrintStatusInterfaces(interfaces map[string]shared.StatusInterface) string {
@@ -230,6 +233,32 @@ func setConfig(ctx context.Context, client *projectclient.AuthedClient, clusterN
 	return nil
 }
 
+// parseNetworkConfig parses a network configuration in json format and checks the interface names.
+func parseNetworkConfig(configString string) (map[string]shared.Interface, error) {
+	var config map[string]shared.Interface
+	if err := json.Unmarshal([]byte(configString), &config); err != nil {
+		fmt.Fprintf(os.Stderr, "Provided configuration is not a valid configuration string.\n")
+		return nil, err
+	}
+
+	for name := range config {
+		// This is a soft error to allow for later changes
+		// The list should cover
+		// * en*: All wired interface names set by udev
+		// * wl*: All wireless interface names set by udev (usually wlp... or wlan#)
+		// * realtime_nic0: For our own naming scheme
+		if !strings.HasPrefix(name, "en") && !strings.HasPrefix(name, "wl") && !strings.HasPrefix(name, "realtime_nic") {
+			fmt.Fprintf(os.Stderr, "WARNING: Interface %q does not look like a valid interface.\n", name)
+		}
+
+		// This is an easy to make mistake in the config building.
+		if net.ParseIP(name) != nil {
+			return nil, fmt.Errorf("%q was used as interface name but is an IP address, please use \"en...\" for example", name)
+		}
+	}
+	return config, nil
+}
+
 var configSetCmd = &cobra.Command{
 	Use:   "set",
 	Short: "Set the network config",
@@ -252,26 +281,21 @@ var configSetCmd = &cobra.Command{
 			return fmt.Errorf("get project client: %w", err)
 		}
 
-		var config map[string]shared.Interface
-		if err := json.Unmarshal([]byte(configString), &config); err != nil {
-			fmt.Fprintf(os.Stderr, "Provided configuration is not a valid configuration string.\n")
+		config, err := parseNetworkConfig(configString)
+		if err != nil {
 			return err
 		}
 
+		names := make([]string, 0, len(config))
 		for name := range config {
-			// This is a soft error to allow for later changes
-			// The list should cover
-			// * en*: All wired interface names set by udev
-			// * wl*: All wireless interface names set by udev (usually wlp... or wlan#)
-			// * realtime_nic0: For our own naming scheme
-			if !strings.HasPrefix(name, "en") && !strings.HasPrefix(name, "wl") && !strings.HasPrefix(name, "realtime_nic") {
-				fmt.Fprintf(os.Stderr, "WARNING: Interface %q does not look like a valid interface.\n", name)
-			}
-
-			// This is an easy to make mistake in the config building.
-			if net.ParseIP(name) != nil {
-				return fmt.Errorf("%q was used as interface name but is an IP address, please use \"en...\" for example", name)
-			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if err := cmdutils.Confirm(cmd, configSetYes,
+			fmt.Sprintf("Apply a new network configuration to device %q of cluster %q", deviceID, clusterName),
+			fmt.Sprintf("interfaces: %s", strings.Join(names, ", ")),
+			"the device loses its connection if it cannot reach the configuration server with the new configuration"); err != nil {
+			return err
 		}
 
 		if err := setConfig(cmd.Context(), &client, clusterName, deviceID, configString); err != nil {
@@ -301,4 +325,5 @@ func init() {
 	deviceCmd.AddCommand(configCmd)
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetCmd)
+	cmdutils.AddYesFlagVar(configSetCmd, &configSetYes)
 }
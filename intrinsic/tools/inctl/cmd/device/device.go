@@ -32,9 +32,8 @@ var deviceCmd = orgutil.WrapCmd(
 		SuggestFor: []string{"devcie", "dve", "deviec"},
 	}, viperLocal)
 
-func init() {
-	root.RootCmd.AddCommand(deviceCmd)
-
+// loadDeviceCmd adds the flags shared by all device commands to deviceCmd and returns it.
+func loadDeviceCmd() *cobra.Command {
 	deviceCmd.PersistentFlags().StringVarP(&deviceID, "device_id", "", "", "The device ID of the device to claim")
 	deviceCmd.MarkPersistentFlagRequired("device_id")
 
@@ -45,4 +44,9 @@ func init() {
 		`The hostname for the device. If it's a control plane this will be the cluster name.`)
 
 	viperutil.BindFlags(viperLocal, deviceCmd.PersistentFlags(), viperutil.BindToListEnv(keyClusterName))
+	return deviceCmd
+}
+
+func init() {
+	root.AddLazyCommand(deviceCmd.Use, deviceCmd.Short, loadDeviceCmd, deviceCmd.Aliases...)
 }
@@ -10,10 +10,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
 
+	"intrinsic/frontend/frontendclient"
 	"intrinsic/skills/tools/skill/cmd/dialerutil"
 	"intrinsic/tools/inctl/auth"
 )
@@ -29,14 +28,18 @@ var (
 // AuthedClient injects an api key for the project into every request.
 type AuthedClient struct {
 	client       *http.Client
-	baseURL      url.URL
+	project      string
 	tokenSource  *auth.ProjectToken
 	organization string
 }
 
 // Do is the primary function of the http client interface.
 func (c *AuthedClient) Do(req *http.Request) (*http.Response, error) {
-	req, err := c.tokenSource.HTTPAuthorization(req)
+	var err error
+	// The token may be absent if the project is accessed with a client certificate only.
+	if c.tokenSource != nil {
+		req, err = c.tokenSource.HTTPAuthorization(req)
+	}
 	if c.organization != "" {
 		req.AddCookie(&http.Cookie{Name: auth.OrgIDHeader, Value: c.organization})
 	}
@@ -61,18 +64,18 @@ func Client(projectName string, orgName string) (AuthedClient, error) {
 		return AuthedClient{}, fmt.Errorf("get configuration: %w", err)
 	}
 
-	token, err := configuration.GetDefaultCredentials()
+	token, clientCert, err := configuration.Credentials(auth.AliasDefaultToken)
 	if err != nil {
 		return AuthedClient{}, fmt.Errorf("get default credential: %w", err)
 	}
+	client, err := clientCert.HTTPClient()
+	if err != nil {
+		return AuthedClient{}, fmt.Errorf("load client certificate: %w", err)
+	}
 
 	return AuthedClient{
-		client: http.DefaultClient,
-		baseURL: url.URL{
-			Scheme: "https",
-			Host:   fmt.Sprintf("www.endpoints.%s.cloud.goog", projectName),
-			Path:   "/api/devices/",
-		},
+		client:       client,
+		project:      projectName,
 		tokenSource:  token,
 		organization: orgName,
 	}, nil
@@ -80,11 +83,7 @@ func Client(projectName string, orgName string) (AuthedClient, error) {
 
 // PostDevice acts similar to [http.Post] but takes a context and injects base path of the device manager for the project.
 func (c *AuthedClient) PostDevice(ctx context.Context, cluster, deviceID, subPath string, body io.Reader) (*http.Response, error) {
-	reqURL := c.baseURL
-
-	reqURL.Path = filepath.Join(reqURL.Path, subPath)
-	reqURL.RawQuery = url.Values{"device-id": []string{deviceID}, "cluster": []string{cluster}}.Encode()
-
+	reqURL := frontendclient.DeviceURL(c.project, cluster, deviceID, subPath)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL.String(), body)
 	if err != nil {
 		return nil, err
@@ -95,11 +94,7 @@ func (c *AuthedClient) PostDevice(ctx context.Context, cluster, deviceID, subPat
 
 // GetDevice acts similar to [http.Get] but takes a context and injects base path of the device manager for the project.
 func (c *AuthedClient) GetDevice(ctx context.Context, cluster, deviceID, subPath string) (*http.Response, error) {
-	reqURL := c.baseURL
-
-	reqURL.Path = filepath.Join(reqURL.Path, subPath)
-	reqURL.RawQuery = url.Values{"device-id": []string{deviceID}, "cluster": []string{cluster}}.Encode()
-
+	reqURL := frontendclient.DeviceURL(c.project, cluster, deviceID, subPath)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
 	if err != nil {
 		return nil, err
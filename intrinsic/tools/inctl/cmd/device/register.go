@@ -28,14 +28,30 @@ const (
 	replaceKey          = "replace"
 )
 
-var (
-	deviceRole    = ""
-	deviceRegion  = ""
-	privateDevice = false
-	replaceDevice = false
-	noWait        = false
-	noUpdate      = false
-)
+// configureOptions holds the flags of the commands which send an initial configuration to a
+// device.
+type configureOptions struct {
+	role     string
+	region   string
+	private  bool
+	replace  bool
+	noWait   bool
+	noUpdate bool
+	// displayName and location are only set by setup.
+	displayName string
+	location    string
+}
+
+func (o *configureOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.role, "device_role", "", "control-plane", "The role the device has in the cluster. Either 'control-plane' or 'worker'")
+	cmd.Flags().BoolVarP(&o.private, "private", "", false, "If set to 'true', the device will not be visible to other organization members")
+	cmd.Flags().StringVarP(&o.region, "region", "", "unspecified", "This can be used for inventory tracking")
+	cmd.Flags().BoolVarP(&o.replace, replaceKey, "", false, "If set to 'true', an existing cluster with the same name will be replaced.\nThis is equivalent to calling 'inctl cluster delete' first")
+	cmd.Flags().BoolVarP(&o.noWait, "no-wait", "", false, "Set to true to avoid waiting for the cluster initialization.")
+	cmd.Flags().BoolVarP(&o.noUpdate, "no-update", "", false, "Do not enroll the cluster into automatic updates.")
+}
+
+var registerOpts configureOptions
 
 func validHostname(hostname string) (int, bool) {
 	match := regexp.MustCompile(hostnameRegexString).FindStringIndex(hostname)
@@ -80,6 +96,16 @@ func makeNameError(hostname string, index int) string {
 	return fmt.Sprintf("Cannot use %q in hostname", offender)
 }
 
+// sleepCtx waits for d or until ctx is done and returns the error of ctx in the latter case.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
 func waitForConfigDownload(ctx context.Context, client projectclient.AuthedClient, clusterName, deviceID string) error {
 	// This should usually only take 1-2 min.
 	// If it takes longer than 5 minutes, there' something wrong.
@@ -104,7 +130,11 @@ func waitForConfigDownload(ctx context.Context, client projectclient.AuthedClien
 			}
 		}
 		fmt.Printf(".")
-		time.Sleep(time.Second * 30)
+		if err := sleepCtx(ctx, time.Second*30); errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("the IPC did not reach cloud infrastructure.\nPlease make sure the IPC has a stable internet connection and retry")
+		} else if err != nil {
+			return err
+		}
 	}
 }
 
@@ -117,25 +147,34 @@ func waitForStatusAvailable(ctx context.Context, client projectclient.AuthedClie
 				return fmt.Errorf("the IPC failed to initialize.\nPlease make sure the IPC has as stable internet connection")
 			}
 
+			if ctx.Err() != nil {
+				return err
+			}
+
 			// This could be a transient network error.
 			log.WarningContextf(ctx, "Unexpected error while getting status, retrying: %v", err)
-		}
-		resp.Body.Close()
+		} else {
+			resp.Body.Close()
 
-		if resp.StatusCode == http.StatusOK {
-			fmt.Printf("\n")
-			return nil
-		}
+			if resp.StatusCode == http.StatusOK {
+				fmt.Printf("\n")
+				return nil
+			}
 
-		// StatusBadGateway is expected when the control plane isn't up yet.
-		// StatusNotFound is expected when a worker node isn't up yet.
-		if resp.StatusCode != http.StatusBadGateway && resp.StatusCode != http.StatusNotFound {
-			// This could be a transient nginx 5xx error.
-			log.WarningContextf(ctx, "Unexpected error while getting status, retrying: %d", resp.StatusCode)
+			// StatusBadGateway is expected when the control plane isn't up yet.
+			// StatusNotFound is expected when a worker node isn't up yet.
+			if resp.StatusCode != http.StatusBadGateway && resp.StatusCode != http.StatusNotFound {
+				// This could be a transient nginx 5xx error.
+				log.WarningContextf(ctx, "Unexpected error while getting status, retrying: %d", resp.StatusCode)
+			}
 		}
 
 		fmt.Printf(".")
-		time.Sleep(time.Second * 30)
+		if err := sleepCtx(ctx, time.Second*30); errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("the IPC failed to initialize.\nPlease make sure the IPC has as stable internet connection")
+		} else if err != nil {
+			return err
+		}
 	}
 }
 
@@ -156,24 +195,121 @@ func waitForCluster(ctx context.Context, client projectclient.AuthedClient, clus
 	return nil
 }
 
+// resolveHostname returns the hostname flag, defaulting to the device ID, and checks that it can be
+// used with the given role.
+func resolveHostname(role string) (string, error) {
+	hostname := viperLocal.GetString(keyHostname)
+	if hostname == "" {
+		hostname = deviceID
+	}
+	if role != "control-plane" && clusterName == "" {
+		fmt.Printf("--cluster_name needs to be provided for role %q\n", role)
+		return "", fmt.Errorf("invalid arguments")
+	}
+
+	if offender, ok := validHostname(hostname); !ok {
+		fmt.Printf("%q is not a valid as hostname. Provide a valid hostname.\nSee https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#dns-label-names for more information.\n", hostname)
+		return "", fmt.Errorf(makeNameError(hostname, offender))
+	}
+	return hostname, nil
+}
+
+// configureDevice sends the initial configuration to the device and, unless disabled, waits for the
+// device to apply it. extraConfig is merged into the device config, e.g., to set up the network.
+func configureDevice(ctx context.Context, client projectclient.AuthedClient, projectName, orgName, hostname string, opts *configureOptions, extraConfig map[string]any) error {
+	// This map represents a json mapping of a config struct.
+	config := map[string]any{
+		"hostname": hostname,
+		"cloudConnection": map[string]any{
+			"project": projectName,
+			"token":   "not-a-valid-token",
+			"name":    hostname,
+		},
+		"cluster": map[string]any{
+			"role": opts.role,
+			// Only relevant for worker, but this doesn't hurt the control-plane nodes.
+			"controlPlaneURI": fmt.Sprintf("%s:6443", clusterName),
+			"token":           shared.TokenPlaceholder,
+		},
+		"version": "v1alphav1",
+	}
+	// For now, assume that control planes have a GPU...
+	if opts.role == "control-plane" {
+		config["gpuConfig"] = map[string]any{
+			"enabled":  true,
+			"replicas": 8,
+		}
+	}
+	for k, v := range extraConfig {
+		config[k] = v
+	}
+	marshalled, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	data := shared.ConfigureData{
+		Hostname:    hostname,
+		Config:      marshalled,
+		Role:        opts.role,
+		Cluster:     clusterName,
+		Private:     opts.private,
+		Region:      opts.region,
+		Replace:     opts.replace,
+		AutoUpdate:  !opts.noUpdate,
+		DisplayName: opts.displayName,
+		Location:    opts.location,
+	}
+	if testID := os.Getenv("INCTL_CREATED_BY_TEST"); testID != "" {
+		// This is an automated test.
+		data.CreatedByTest = testID
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	resp, err := client.PostDevice(ctx, clusterName, deviceID, "configure", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		fmt.Printf("Sent configuration to server. The device will reboot and apply the configuration within a minute.\n")
+	case http.StatusConflict:
+		return fmt.Errorf("cluster %q already exists. Please use a unique value for --hostname if this is a new cluster.\nTo replace the old cluster, call with --%s", hostname, replaceKey)
+	case http.StatusPreconditionFailed:
+		return fmt.Errorf("cluster %q does not exist. Please make sure that --cluster_name matches the --hostname from a previously registered cluster.\nIf you want to create a new cluster, do not use --device_role", clusterName)
+	case http.StatusNotFound:
+		return fmt.Errorf("device %q does not exist. Please make sure you have the exact id from the device you are trying to register", deviceID)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("your login key has expired or been replaced.\nRun 'inctl auth login --org %s' to update it", orgutil.QualifiedOrg(projectName, orgName))
+	case http.StatusForbidden:
+		return fmt.Errorf("you do not have the necessary permissions to add a cluster on organization %q.\nOpen a support request to get the 'clusterProvisioner' role", orgutil.QualifiedOrg(projectName, orgName))
+	default:
+		io.Copy(os.Stderr, resp.Body)
+
+		return fmt.Errorf("request failed. http code: %v", resp.StatusCode)
+	}
+	if !opts.noWait {
+		if err := waitForCluster(ctx, client, clusterName, deviceID, hostname); err != nil {
+			return fmt.Errorf("wait for device: %w", err)
+		}
+	}
+
+	return nil
+}
+
 var registerCmd = &cobra.Command{
 	Use:   "register",
 	Short: "Tool to register hardware in setup flow",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		projectName := viperLocal.GetString(orgutil.KeyProject)
 		orgName := viperLocal.GetString(orgutil.KeyOrganization)
-		hostname := viperLocal.GetString(keyHostname)
-		if hostname == "" {
-			hostname = deviceID
-		}
-		if deviceRole != "control-plane" && clusterName == "" {
-			fmt.Printf("--cluster_name needs to be provided for role %q\n", deviceRole)
-			return fmt.Errorf("invalid arguments")
-		}
-
-		if offender, ok := validHostname(hostname); !ok {
-			fmt.Printf("%q is not a valid as hostname. Provide a valid hostname.\nSee https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#dns-label-names for more information.\n", hostname)
-			return fmt.Errorf(makeNameError(hostname, offender))
+		hostname, err := resolveHostname(registerOpts.role)
+		if err != nil {
+			return err
 		}
 
 		client, err := projectclient.Client(projectName, orgName)
@@ -181,92 +317,10 @@ var registerCmd = &cobra.Command{
 			return fmt.Errorf("get client for project: %w", err)
 		}
 
-		// This map represents a json mapping of a config struct.
-		config := map[string]any{
-			"hostname": hostname,
-			"cloudConnection": map[string]any{
-				"project": projectName,
-				"token":   "not-a-valid-token",
-				"name":    hostname,
-			},
-			"cluster": map[string]any{
-				"role": deviceRole,
-				// Only relevant for worker, but this doesn't hurt the control-plane nodes.
-				"controlPlaneURI": fmt.Sprintf("%s:6443", clusterName),
-				"token":           shared.TokenPlaceholder,
-			},
-			"version": "v1alphav1",
-		}
-		// For now, assume that control planes have a GPU...
-		if deviceRole == "control-plane" {
-			config["gpuConfig"] = map[string]any{
-				"enabled":  true,
-				"replicas": 8,
-			}
-		}
-		marshalled, err := json.Marshal(config)
-		if err != nil {
-			return fmt.Errorf("failed to marshal config: %w", err)
-		}
-		data := shared.ConfigureData{
-			Hostname:   hostname,
-			Config:     marshalled,
-			Role:       deviceRole,
-			Cluster:    clusterName,
-			Private:    privateDevice,
-			Region:     deviceRegion,
-			Replace:    replaceDevice,
-			AutoUpdate: !noUpdate,
-		}
-		if testID := os.Getenv("INCTL_CREATED_BY_TEST"); testID != "" {
-			// This is an automated test.
-			data.CreatedByTest = testID
-		}
-		body, err := json.Marshal(data)
-		if err != nil {
-			return fmt.Errorf("failed to marshal config: %w", err)
-		}
-
-		resp, err := client.PostDevice(cmd.Context(), clusterName, deviceID, "configure", bytes.NewBuffer(body))
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		switch resp.StatusCode {
-		case http.StatusOK:
-			fmt.Printf("Sent configuration to server. The device will reboot and apply the configuration within a minute.\n")
-		case http.StatusConflict:
-			return fmt.Errorf("cluster %q already exists. Please use a unique value for --hostname if this is a new cluster.\nTo replace the old cluster, call with --%s", hostname, replaceKey)
-		case http.StatusPreconditionFailed:
-			return fmt.Errorf("cluster %q does not exist. Please make sure that --cluster_name matches the --hostname from a previously registered cluster.\nIf you want to create a new cluster, do not use --device_role", clusterName)
-		case http.StatusNotFound:
-			return fmt.Errorf("device %q does not exist. Please make sure you have the exact id from the device you are trying to register", deviceID)
-		case http.StatusUnauthorized:
-			return fmt.Errorf("your login key has expired or been replaced.\nRun 'inctl auth login --org %s' to update it", orgutil.QualifiedOrg(projectName, orgName))
-		case http.StatusForbidden:
-			return fmt.Errorf("you do not have the necessary permissions to add a cluster on organization %q.\nOpen a support request to get the 'clusterProvisioner' role", orgutil.QualifiedOrg(projectName, orgName))
-		default:
-			io.Copy(os.Stderr, resp.Body)
-
-			return fmt.Errorf("request failed. http code: %v", resp.StatusCode)
-		}
-		if !noWait {
-			if err := waitForCluster(cmd.Context(), client, clusterName, deviceID, hostname); err != nil {
-				return fmt.Errorf("wait for device: %w", err)
-			}
-		}
-
-		return nil
+		return configureDevice(cmd.Context(), client, projectName, orgName, hostname, &registerOpts, nil)
 	}}
 
 func init() {
 	deviceCmd.AddCommand(registerCmd)
-
-	registerCmd.Flags().StringVarP(&deviceRole, "device_role", "", "control-plane", "The role the device has in the cluster. Either 'control-plane' or 'worker'")
-	registerCmd.Flags().BoolVarP(&privateDevice, "private", "", false, "If set to 'true', the device will not be visible to other organization members")
-	registerCmd.Flags().StringVarP(&deviceRegion, "region", "", "unspecified", "This can be used for inventory tracking")
-	registerCmd.Flags().BoolVarP(&replaceDevice, replaceKey, "", false, "If set to 'true', an existing cluster with the same name will be replaced.\nThis is equivalent to calling 'inctl cluster delete' first")
-	registerCmd.Flags().BoolVarP(&noWait, "no-wait", "", false, "Set to true to avoid waiting for the cluster initialization.")
-	registerCmd.Flags().BoolVarP(&noUpdate, "no-update", "", false, "Do not enroll the cluster into automatic updates.")
+	registerOpts.addFlags(registerCmd)
 }
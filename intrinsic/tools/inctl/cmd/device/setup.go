@@ -0,0 +1,73 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package device
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"intrinsic/tools/inctl/cmd/device/projectclient"
+	"intrinsic/tools/inctl/util/orgutil"
+)
+
+var (
+	setupOpts              configureOptions
+	setupNetworkConfigFile = ""
+)
+
+const setupCmdDesc = `
+Set up a new device in a single, scriptable step.
+
+This sends the hostname, the cluster membership and, optionally, the initial
+network configuration to the device and waits until the device has applied it.
+The network configuration has the same json format as for
+'inctl device config set'.
+
+Example:
+  inctl device setup --org my-org --device_id 1234abcd --hostname cell-1 \
+      --network_config_file network.json
+`
+
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Set hostname, cluster and initial network config of a new device",
+	Long:  setupCmdDesc,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectName := viperLocal.GetString(orgutil.KeyProject)
+		orgName := viperLocal.GetString(orgutil.KeyOrganization)
+		hostname, err := resolveHostname(setupOpts.role)
+		if err != nil {
+			return err
+		}
+
+		var extraConfig map[string]any
+		if setupNetworkConfigFile != "" {
+			b, err := os.ReadFile(setupNetworkConfigFile)
+			if err != nil {
+				return fmt.Errorf("read network config: %w", err)
+			}
+			network, err := parseNetworkConfig(string(b))
+			if err != nil {
+				return fmt.Errorf("invalid network config in %q: %w", setupNetworkConfigFile, err)
+			}
+			extraConfig = map[string]any{"network": network}
+		}
+
+		client, err := projectclient.Client(projectName, orgName)
+		if err != nil {
+			return fmt.Errorf("get client for project: %w", err)
+		}
+
+		return configureDevice(cmd.Context(), client, projectName, orgName, hostname, &setupOpts, extraConfig)
+	}}
+
+func init() {
+	deviceCmd.AddCommand(setupCmd)
+	setupOpts.addFlags(setupCmd)
+
+	setupCmd.Flags().StringVar(&setupNetworkConfigFile, "network_config_file", "", "Path to a json file with the initial network configuration of the device.")
+	setupCmd.Flags().StringVar(&setupOpts.displayName, "display_name", "", "Human readable name of the device.")
+	setupCmd.Flags().StringVar(&setupOpts.location, "location", "", "Physical location of the device, used for inventory tracking.")
+}
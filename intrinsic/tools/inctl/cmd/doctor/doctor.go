@@ -0,0 +1,340 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package doctor contains the command which checks the local development environment.
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"intrinsic/frontend/frontendclient"
+	"intrinsic/tools/inctl/auth"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+const (
+	// bazelTimeout is long enough for bazelisk to download Bazel on the first run.
+	bazelTimeout   = 2 * time.Minute
+	commandTimeout = 20 * time.Second
+	dialTimeout    = 10 * time.Second
+
+	containerdSocket = "/run/containerd/containerd.sock"
+)
+
+var (
+	flagOrg string
+
+	authStore = auth.NewStore()
+)
+
+type checkStatus string
+
+const (
+	statusOK      checkStatus = "ok"
+	statusWarning checkStatus = "warning"
+	statusFailed  checkStatus = "failed"
+	statusSkipped checkStatus = "skipped"
+)
+
+// checkResult is the result of a single check of the environment.
+type checkResult struct {
+	Name        string      `json:"name"`
+	Status      checkStatus `json:"status"`
+	Detail      string      `json:"detail,omitempty"`
+	Remediation string      `json:"remediation,omitempty"`
+}
+
+type report struct {
+	Checks []checkResult `json:"checks"`
+}
+
+// String prints the report for --output=text.
+func (r *report) String() string {
+	var b strings.Builder
+	for _, c := range r.Checks {
+		fmt.Fprintf(&b, "%-9s %s: %s\n", "["+string(c.Status)+"]", c.Name, c.Detail)
+		if c.Remediation != "" {
+			fmt.Fprintf(&b, "%-9s -> %s\n", "", c.Remediation)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// failed reports whether any check failed. Warnings do not fail the command.
+func (r *report) failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == statusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// findWorkspaceRoot returns the closest directory at or above dir which contains a Bazel
+// workspace file, or an empty string if there is none.
+func findWorkspaceRoot(dir string) string {
+	for {
+		for _, name := range []string{"MODULE.bazel", "WORKSPACE", "WORKSPACE.bazel"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseBazelVersion returns the version in the output of 'bazel --version', e.g., "bazel 7.1.0".
+func parseBazelVersion(output string) (string, error) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 || fields[0] != "bazel" {
+		return "", fmt.Errorf("unexpected output %q", strings.TrimSpace(output))
+	}
+	return fields[1], nil
+}
+
+// runCommand runs a command in dir and returns its combined output.
+func runCommand(ctx context.Context, timeout time.Duration, dir string, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return string(out), fmt.Errorf("%s did not finish within %s", name, timeout)
+	}
+	return string(out), err
+}
+
+func checkWorkspace(workspaceRoot string) checkResult {
+	c := checkResult{Name: "workspace"}
+	if workspaceRoot == "" {
+		c.Status = statusWarning
+		c.Detail = "not in a Bazel workspace, skipping the workspace checks"
+		c.Remediation = "run inctl doctor in your workspace, or create one with 'inctl bazel init'"
+		return c
+	}
+	if _, err := os.Stat(filepath.Join(workspaceRoot, ".bazelrc")); err != nil {
+		c.Status = statusFailed
+		c.Detail = fmt.Sprintf("%s has no .bazelrc with the configuration flags of the SDK", workspaceRoot)
+		c.Remediation = fmt.Sprintf("run 'inctl bazel init --workspace_root=%s --bazelrc_only' and pass --sdk_repository or --local_sdk_path", workspaceRoot)
+		return c
+	}
+	c.Status = statusOK
+	c.Detail = workspaceRoot
+	return c
+}
+
+func checkBazel(ctx context.Context, workspaceRoot string) checkResult {
+	c := checkResult{Name: "bazel"}
+	var bazel string
+	for _, name := range []string{"bazel", "bazelisk"} {
+		if path, err := exec.LookPath(name); err == nil {
+			bazel = path
+			break
+		}
+	}
+	if bazel == "" {
+		c.Status = statusFailed
+		c.Detail = "neither bazel nor bazelisk found in PATH"
+		c.Remediation = "install bazelisk, see https://github.com/bazelbuild/bazelisk"
+		return c
+	}
+	dir := workspaceRoot
+	if dir == "" {
+		dir = "."
+	}
+	out, err := runCommand(ctx, bazelTimeout, dir, bazel, "--version")
+	if err != nil {
+		c.Status = statusFailed
+		c.Detail = fmt.Sprintf("%s --version failed: %v", bazel, err)
+		c.Remediation = "check your Bazel installation by running 'bazel --version'"
+		return c
+	}
+	got, err := parseBazelVersion(out)
+	if err != nil {
+		c.Status = statusWarning
+		c.Detail = fmt.Sprintf("cannot determine the version of %s: %v", bazel, err)
+		return c
+	}
+	c.Status = statusOK
+	c.Detail = fmt.Sprintf("%s (%s)", got, bazel)
+	if workspaceRoot == "" {
+		return c
+	}
+	want, err := os.ReadFile(filepath.Join(workspaceRoot, ".bazelversion"))
+	if err != nil {
+		return c
+	}
+	if w := strings.TrimSpace(string(want)); w != "" && w != got {
+		c.Status = statusWarning
+		c.Detail = fmt.Sprintf("Bazel %s does not match version %s of .bazelversion", got, w)
+		c.Remediation = "install bazelisk as bazel, it runs the version of .bazelversion"
+	}
+	return c
+}
+
+func checkContainerRuntime(ctx context.Context) checkResult {
+	c := checkResult{Name: "container runtime"}
+	if _, err := exec.LookPath("docker"); err == nil {
+		out, err := runCommand(ctx, commandTimeout, ".", "docker", "info", "--format", "{{.ServerVersion}}")
+		if err != nil {
+			c.Status = statusFailed
+			c.Detail = fmt.Sprintf("cannot access the docker daemon: %s", strings.TrimSpace(out))
+			c.Remediation = "start the docker daemon and make sure your user is in the docker group"
+			return c
+		}
+		c.Status = statusOK
+		c.Detail = "docker " + strings.TrimSpace(out)
+		return c
+	}
+	if _, err := os.Stat(containerdSocket); err == nil {
+		c.Status = statusOK
+		c.Detail = "containerd at " + containerdSocket
+		return c
+	}
+	c.Status = statusFailed
+	c.Detail = "neither docker nor containerd found"
+	c.Remediation = "install docker, it is needed to build and inspect container images of assets"
+	return c
+}
+
+// resolveOrg returns the organization to check, either from --org or the default organization.
+func resolveOrg() (*auth.OrgInfo, error) {
+	if flagOrg == "" {
+		return authStore.ReadDefaultOrg()
+	}
+	info, err := authStore.ReadOrgInfo(flagOrg)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func checkAuth(org *auth.OrgInfo, orgErr error) checkResult {
+	c := checkResult{Name: "credentials"}
+	switch {
+	case orgErr != nil:
+		c.Status = statusFailed
+		c.Detail = fmt.Sprintf("cannot read the organization: %v", orgErr)
+		c.Remediation = "log in again with 'inctl auth login --org=ORG'"
+		return c
+	case org == nil && flagOrg != "":
+		c.Status = statusFailed
+		c.Detail = fmt.Sprintf("not logged in to organization %q", flagOrg)
+		c.Remediation = fmt.Sprintf("run 'inctl auth login --org=%s'", flagOrg)
+		return c
+	case org == nil:
+		c.Status = statusWarning
+		c.Detail = "no default organization, commands need --org"
+		c.Remediation = "set one with 'inctl auth use-org ORG', or check a specific organization with --org"
+		return c
+	}
+
+	remediation := fmt.Sprintf("run 'inctl auth login --org=%s'", org.Organization)
+	config, err := authStore.GetConfiguration(org.Project)
+	if err != nil {
+		c.Status = statusFailed
+		c.Detail = fmt.Sprintf("no credentials for project %q of organization %q: %v", org.Project, org.Organization, err)
+		c.Remediation = remediation
+		return c
+	}
+	token, err := config.GetDefaultCredentials()
+	if err == nil {
+		err = token.Validate()
+	}
+	if err != nil {
+		c.Status = statusFailed
+		c.Detail = fmt.Sprintf("invalid credentials for organization %q: %v", org.Organization, err)
+		c.Remediation = remediation
+		return c
+	}
+	c.Status = statusOK
+	c.Detail = fmt.Sprintf("organization %q in project %q", org.Organization, org.Project)
+	return c
+}
+
+func checkConnectivity(ctx context.Context, org *auth.OrgInfo) checkResult {
+	c := checkResult{Name: "connectivity"}
+	if org == nil {
+		c.Status = statusSkipped
+		c.Detail = "no organization to check"
+		return c
+	}
+	address := net.JoinHostPort(frontendclient.ProjectHost(org.Project), "443")
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: dialTimeout}}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		c.Status = statusFailed
+		c.Detail = fmt.Sprintf("cannot connect to %s: %v", address, err)
+		c.Remediation = "check your network connection and that proxies and firewalls allow HTTPS to *.cloud.goog"
+		return c
+	}
+	conn.Close()
+	c.Status = statusOK
+	c.Detail = address
+	return c
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks the local development environment",
+	Long: `Checks that the local development environment is set up to build and deploy with the
+Intrinsic SDK: Bazel and the workspace configuration, access to a container runtime, the
+credentials of the organization and the connection to its endpoints. Failed checks come with
+a suggestion how to fix them.`,
+	Example: `Check the environment for the default organization
+$ inctl doctor
+
+Check the environment for a specific organization
+$ inctl doctor --org=my_org`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
+		prtr, err := printer.NewPrinterWithWriter(root.FlagOutput, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+
+		workspaceRoot := ""
+		if wd, err := os.Getwd(); err == nil {
+			workspaceRoot = findWorkspaceRoot(wd)
+		}
+		org, orgErr := resolveOrg()
+		r := &report{Checks: []checkResult{
+			checkWorkspace(workspaceRoot),
+			checkBazel(ctx, workspaceRoot),
+			checkContainerRuntime(ctx),
+			checkAuth(org, orgErr),
+			checkConnectivity(ctx, org),
+		}}
+		prtr.Print(r)
+
+		if r.failed() {
+			return fmt.Errorf("some checks failed")
+		}
+		return nil
+	},
+}
+
+func init() {
+	root.RootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringVar(&flagOrg, orgutil.KeyOrganization, "", "Organization to check the credentials and connectivity of. Defaults to the organization set with 'inctl auth use-org'.")
+}
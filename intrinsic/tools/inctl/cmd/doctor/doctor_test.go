@@ -0,0 +1,49 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindWorkspaceRoot(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "skills", "my_skill")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := findWorkspaceRoot(nested); got != "" {
+		t.Errorf("findWorkspaceRoot(%q) = %q without a workspace file, want \"\"", nested, got)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "WORKSPACE"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := findWorkspaceRoot(nested); got != root {
+		t.Errorf("findWorkspaceRoot(%q) = %q, want %q", nested, got, root)
+	}
+}
+
+func TestParseBazelVersion(t *testing.T) {
+	if got, err := parseBazelVersion("bazel 7.1.0\n"); err != nil || got != "7.1.0" {
+		t.Errorf("parseBazelVersion() = %q, %v, want \"7.1.0\", nil", got, err)
+	}
+	if got, err := parseBazelVersion("Build label: 7.1.0"); err == nil {
+		t.Errorf("parseBazelVersion() = %q, want error", got)
+	}
+}
+
+func TestCheckWorkspace(t *testing.T) {
+	root := t.TempDir()
+	if got := checkWorkspace(root); got.Status != statusFailed || got.Remediation == "" {
+		t.Errorf("checkWorkspace() without .bazelrc = %+v, want a failure with remediation", got)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".bazelrc"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := checkWorkspace(root); got.Status != statusOK {
+		t.Errorf("checkWorkspace() = %+v, want ok", got)
+	}
+}
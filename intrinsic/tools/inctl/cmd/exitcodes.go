@@ -0,0 +1,129 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package root
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"intrinsic/assets/clientutils"
+	"intrinsic/skills/tools/skill/cmd/dialerutil"
+	"intrinsic/tools/inctl/util/orgutil"
+
+	grpccodes "google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// Exit codes of inctl, so that scripts can branch on the kind of failure instead of parsing
+// stderr. Code 2 is left to commands for their own results, e.g., 'inctl asset verify' exits with
+// 2 if it found drift. Commands can override the code of any error with an ExitCodeError.
+const (
+	// ExitCodeOK is returned if the command succeeded.
+	ExitCodeOK = 0
+	// ExitCodeFailure is returned for errors which do not fall into any of the classes below.
+	ExitCodeFailure = 1
+	// ExitCodeValidation is returned for invalid commands, flags or arguments, and for requests
+	// which the server rejected as invalid.
+	ExitCodeValidation = 3
+	// ExitCodeAuth is returned if credentials are missing, invalid or lack permissions.
+	ExitCodeAuth = 4
+	// ExitCodeNotFound is returned if a requested resource does not exist.
+	ExitCodeNotFound = 5
+	// ExitCodeConnectivity is returned if the server or cluster could not be reached in time.
+	ExitCodeConnectivity = 6
+	// ExitCodeServer is returned if the server failed to handle the request.
+	ExitCodeServer = 7
+)
+
+// exitCodeHelp documents the exit codes in the help of the root command.
+const exitCodeHelp = `Exit codes:
+  0  success
+  1  other failure
+  2  command specific result, see the help of the command
+  3  invalid command, flags, arguments or request
+  4  missing, invalid or insufficient credentials
+  5  resource not found
+  6  server or cluster not reachable
+  7  server error`
+
+// usageErrorPrefixes are the prefixes of the errors cobra returns for invalid command lines. Cobra
+// does not export types for them.
+var usageErrorPrefixes = []string{
+	"unknown command",
+	"unknown flag",
+	"unknown shorthand flag",
+	"invalid argument",
+	"required flag(s)",
+	"accepts ",
+	"requires at least",
+	"requires at most",
+	"if any flags in the group",
+}
+
+// grpcExitCodes maps gRPC codes to exit codes. Codes which are missing map to ExitCodeFailure.
+var grpcExitCodes = map[grpccodes.Code]int{
+	grpccodes.InvalidArgument:    ExitCodeValidation,
+	grpccodes.FailedPrecondition: ExitCodeValidation,
+	grpccodes.OutOfRange:         ExitCodeValidation,
+	grpccodes.AlreadyExists:      ExitCodeValidation,
+	grpccodes.Unauthenticated:    ExitCodeAuth,
+	grpccodes.PermissionDenied:   ExitCodeAuth,
+	grpccodes.NotFound:           ExitCodeNotFound,
+	grpccodes.Unavailable:        ExitCodeConnectivity,
+	grpccodes.DeadlineExceeded:   ExitCodeConnectivity,
+	grpccodes.Internal:           ExitCodeServer,
+	grpccodes.Unknown:            ExitCodeServer,
+	grpccodes.DataLoss:           ExitCodeServer,
+	grpccodes.Unimplemented:      ExitCodeServer,
+	grpccodes.Aborted:            ExitCodeServer,
+	grpccodes.ResourceExhausted:  ExitCodeServer,
+}
+
+// ExitCode returns the exit code of inctl for an error returned by a command.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeOK
+	}
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	// Relay errors wrap a gRPC error, but their kind is more specific.
+	switch {
+	case errors.Is(err, clientutils.ErrRelayAuthExpired):
+		return ExitCodeAuth
+	case errors.Is(err, clientutils.ErrClusterOffline), errors.Is(err, clientutils.ErrClusterTimeout):
+		return ExitCodeConnectivity
+	}
+
+	var credErr *dialerutil.ErrCredentialsNotFound
+	var orgErr *orgutil.ErrOrgNotFound
+	if errors.Is(err, dialerutil.ErrCredentialsRequired) || errors.As(err, &credErr) || errors.As(err, &orgErr) {
+		return ExitCodeAuth
+	}
+
+	if s, ok := grpcstatus.FromError(err); ok && s.Code() != grpccodes.OK {
+		if code, ok := grpcExitCodes[s.Code()]; ok {
+			return code
+		}
+		return ExitCodeFailure
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ExitCodeConnectivity
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ExitCodeConnectivity
+	}
+
+	for _, prefix := range usageErrorPrefixes {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return ExitCodeValidation
+		}
+	}
+	return ExitCodeFailure
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"intrinsic/assets/clientutils"
+	"intrinsic/skills/tools/skill/cmd/dialerutil"
+
+	grpccodes "google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: ExitCodeOK},
+		{name: "plain", err: errors.New("something failed"), want: ExitCodeFailure},
+		{name: "unknown command", err: errors.New(`unknown command "asdf" for "inctl"`), want: ExitCodeValidation},
+		{name: "invalid argument", err: grpcstatus.Error(grpccodes.InvalidArgument, "bad"), want: ExitCodeValidation},
+		{name: "unauthenticated", err: fmt.Errorf("list: %w", grpcstatus.Error(grpccodes.Unauthenticated, "no")), want: ExitCodeAuth},
+		{name: "credentials required", err: fmt.Errorf("dial: %w", dialerutil.ErrCredentialsRequired), want: ExitCodeAuth},
+		{name: "not found", err: grpcstatus.Error(grpccodes.NotFound, "missing"), want: ExitCodeNotFound},
+		{name: "unavailable", err: grpcstatus.Error(grpccodes.Unavailable, "down"), want: ExitCodeConnectivity},
+		{name: "deadline", err: fmt.Errorf("wait: %w", context.DeadlineExceeded), want: ExitCodeConnectivity},
+		{
+			name: "relay offline",
+			err:  &clientutils.RelayError{Kind: clientutils.ErrClusterOffline, Err: grpcstatus.Error(grpccodes.Unavailable, "502")},
+			want: ExitCodeConnectivity,
+		},
+		{
+			name: "relay auth",
+			err:  &clientutils.RelayError{Kind: clientutils.ErrRelayAuthExpired, Err: grpcstatus.Error(grpccodes.Unavailable, "401")},
+			want: ExitCodeAuth,
+		},
+		{name: "internal", err: grpcstatus.Error(grpccodes.Internal, "crash"), want: ExitCodeServer},
+		{name: "cancelled", err: grpcstatus.Error(grpccodes.Canceled, "interrupted"), want: ExitCodeFailure},
+		{
+			name: "explicit code",
+			err:  &ExitCodeError{Code: 2, Err: grpcstatus.Error(grpccodes.NotFound, "drift")},
+			want: 2,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ExitCode(tc.err); got != tc.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tc.err, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package root
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+// lazyCommands holds the factories of the placeholders added with AddLazyCommand.
+var lazyCommands = map[*cobra.Command]func() *cobra.Command{}
+
+// AddLazyCommand adds a top level command which is only created by factory when it is about to
+// run, so that building its subcommands and flags does not slow down other commands. Until then, a
+// placeholder with the name, aliases and short description of use, short and aliases is listed by
+// 'inctl --help'. The command returned by factory must have the same name and aliases.
+func AddLazyCommand(use, short string, factory func() *cobra.Command, aliases ...string) {
+	placeholder := &cobra.Command{
+		Use:                use,
+		Short:              short,
+		Aliases:            aliases,
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("command %q was not loaded", cmd.Name())
+		},
+	}
+	lazyCommands[placeholder] = factory
+	RootCmd.AddCommand(placeholder)
+}
+
+// LoadCommands creates the lazy commands which args may run. All lazy commands are created if
+// args is nil or requests shell completions, e.g., by 'inctl shell'.
+func LoadCommands(args []string) {
+	all := args == nil || slices.Contains(args, cobra.ShellCompRequestCmd) || slices.Contains(args, cobra.ShellCompNoDescRequestCmd)
+	for placeholder, factory := range lazyCommands {
+		if !all && !requested(placeholder, args) {
+			continue
+		}
+		RootCmd.RemoveCommand(placeholder)
+		RootCmd.AddCommand(factory())
+		delete(lazyCommands, placeholder)
+	}
+}
+
+// requested reports whether args contain the name or an alias of cmd. Flag values may be mistaken
+// for command names, which only creates a command needlessly.
+func requested(cmd *cobra.Command, args []string) bool {
+	for _, name := range append([]string{cmd.Name()}, cmd.Aliases...) {
+		if slices.Contains(args, name) {
+			return true
+		}
+	}
+	return false
+}
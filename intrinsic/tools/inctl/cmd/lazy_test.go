@@ -0,0 +1,45 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package root
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLoadCommands(t *testing.T) {
+	created := map[string]int{}
+	factory := func(name string, aliases ...string) func() *cobra.Command {
+		return func() *cobra.Command {
+			created[name]++
+			cmd := &cobra.Command{Use: name, Aliases: aliases}
+			cmd.AddCommand(&cobra.Command{Use: "sub", Run: func(*cobra.Command, []string) {}})
+			return cmd
+		}
+	}
+	AddLazyCommand("lazy_a", "A", factory("lazy_a", "la"), "la")
+	AddLazyCommand("lazy_b", "B", factory("lazy_b"))
+	t.Cleanup(func() {
+		for _, c := range RootCmd.Commands() {
+			if c.Name() == "lazy_a" || c.Name() == "lazy_b" {
+				RootCmd.RemoveCommand(c)
+			}
+		}
+		lazyCommands = map[*cobra.Command]func() *cobra.Command{}
+	})
+
+	LoadCommands([]string{"--output", "json", "la", "sub"})
+	if created["lazy_a"] != 1 || created["lazy_b"] != 0 {
+		t.Errorf("LoadCommands() created %v, want only lazy_a", created)
+	}
+	cmd, _, err := RootCmd.Find([]string{"la", "sub"})
+	if err != nil || cmd.Name() != "sub" {
+		t.Errorf("RootCmd.Find(la sub) = %v, %v, want the subcommand of lazy_a", cmd, err)
+	}
+
+	LoadCommands(nil)
+	if created["lazy_a"] != 1 || created["lazy_b"] != 1 {
+		t.Errorf("LoadCommands(nil) created %v, want each command once", created)
+	}
+}
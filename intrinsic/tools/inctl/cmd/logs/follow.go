@@ -0,0 +1,262 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"intrinsic/frontend/frontendclient"
+)
+
+const (
+	// transportHTTP streams logs in the body of a single HTTP response, using HTTP/2 where the
+	// relay supports it.
+	transportHTTP = "http"
+	// transportWebsocket streams logs as websocket messages, which proxies do not buffer.
+	transportWebsocket = "websocket"
+
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Transports accepted by --transport.
+var transports = []string{transportHTTP, transportWebsocket}
+
+// logStream opens a stream of log lines from the consoleLogs endpoint.
+type logStream func(ctx context.Context, endpoint *url.URL, header http.Header) (io.ReadCloser, error)
+
+// permanentError is returned by a logStream for errors which will not go away by reconnecting,
+// e.g., missing permissions.
+type permanentError struct {
+	error
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.error
+}
+
+// isPermanentStatus reports whether a request which failed with the given HTTP status code should
+// not be retried.
+func isPermanentStatus(code int) bool {
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// httpLogStream returns a logStream which reads the logs from the body of a GET request.
+func httpLogStream(client *http.Client) logStream {
+	return func(ctx context.Context, endpoint *url.URL, header http.Header) (io.ReadCloser, error) {
+		if verboseDebug {
+			fmt.Fprintf(verboseOut, "URL: '%s'\n", endpoint)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+		if err != nil {
+			return nil, &permanentError{fmt.Errorf("could not create request: %w", err)}
+		}
+		req.Header = header.Clone()
+		printRequest(req)
+		response, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request to target failed: %w", err)
+		}
+		if response.StatusCode != http.StatusOK {
+			printResponse(response)
+			response.Body.Close()
+			err := &frontendclient.ResponseError{StatusCode: response.StatusCode, Status: response.Status}
+			if isPermanentStatus(response.StatusCode) {
+				return nil, &permanentError{err}
+			}
+			return nil, err
+		}
+		return response.Body, nil
+	}
+}
+
+// websocketLogStream returns a logStream which reads the logs from the messages of a websocket
+// connection to the consoleLogs endpoint.
+func websocketLogStream(client *http.Client) logStream {
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+	}
+	// Present the same client certificate as the HTTP client.
+	if t, ok := client.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = t.TLSClientConfig
+	}
+	return func(ctx context.Context, endpoint *url.URL, header http.Header) (io.ReadCloser, error) {
+		wsURL := *endpoint
+		switch wsURL.Scheme {
+		case "https":
+			wsURL.Scheme = "wss"
+		case "http":
+			wsURL.Scheme = "ws"
+		}
+		if verboseDebug {
+			fmt.Fprintf(verboseOut, "URL: '%s'\n", &wsURL)
+		}
+		conn, response, err := dialer.DialContext(ctx, wsURL.String(), header)
+		if err != nil {
+			if response != nil {
+				printResponse(response)
+				err := fmt.Errorf("websocket handshake failed: %w", &frontendclient.ResponseError{StatusCode: response.StatusCode, Status: response.Status})
+				if isPermanentStatus(response.StatusCode) {
+					return nil, &permanentError{err}
+				}
+				return nil, err
+			}
+			return nil, fmt.Errorf("websocket connection failed: %w", err)
+		}
+		return &websocketReader{conn: conn}, nil
+	}
+}
+
+// websocketReader reads the concatenated messages of a websocket connection.
+type websocketReader struct {
+	conn    *websocket.Conn
+	message io.Reader
+}
+
+func (r *websocketReader) Read(p []byte) (int, error) {
+	for {
+		if r.message == nil {
+			var err error
+			if _, r.message, err = r.conn.NextReader(); err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+		}
+		n, err := r.message.Read(p)
+		if err == io.EOF {
+			r.message = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *websocketReader) Close() error {
+	return r.conn.Close()
+}
+
+// followPosition is the timestamp of the last log line written, used to resume a stream after a
+// reconnect without repeating or losing lines.
+type followPosition struct {
+	last time.Time
+	// linesAtLast is the number of lines written with timestamp last.
+	linesAtLast int
+}
+
+// followLogs streams the logs of consoleLogsURL to w until ctx is done. The stream is reopened
+// whenever it ends or fails, resuming at the timestamp of the last line written.
+func followLogs(ctx context.Context, stream logStream, consoleLogsURL url.URL, query url.Values, header http.Header, timestamps bool, w io.Writer) error {
+	// Timestamps are always requested, they are needed to resume the stream.
+	query.Set(paramTimestamps, "true")
+	pos := &followPosition{}
+	delay := minReconnectDelay
+	for {
+		consoleLogsURL.RawQuery = query.Encode()
+		body, err := stream(ctx, &consoleLogsURL, header)
+		if err == nil {
+			var written bool
+			written, err = copyLines(body, w, timestamps, pos)
+			body.Close()
+			if written {
+				delay = minReconnectDelay
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.error
+		}
+		if err == nil {
+			err = errors.New("stream closed by server")
+		}
+		fmt.Fprintf(verboseOut, "Log stream interrupted (%v), reconnecting in %s\n", err, delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+
+		if !pos.last.IsZero() {
+			// The server only accepts a relative start, so round it up and skip lines which were
+			// already written.
+			since := time.Since(pos.last).Truncate(time.Second) + time.Second
+			query.Set(paramSinceSec, fmt.Sprintf("%d", int64(since.Seconds())))
+			query.Del(paramTailLines)
+		}
+	}
+}
+
+// copyLines copies complete, timestamped log lines from r to w, skipping lines up to pos and
+// stripping the timestamps unless requested. Returns whether any line was written.
+func copyLines(r io.Reader, w io.Writer, timestamps bool, pos *followPosition) (bool, error) {
+	br := bufio.NewReader(r)
+	skipAtLast := pos.linesAtLast
+	written := false
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF {
+			// A partial line is dropped, it is repeated in full after resuming.
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+		ts, text, ok := splitTimestamp(line)
+		if ok {
+			switch {
+			case ts.Before(pos.last):
+				continue
+			case ts.Equal(pos.last):
+				if skipAtLast > 0 {
+					skipAtLast--
+					continue
+				}
+				pos.linesAtLast++
+			default:
+				pos.last, pos.linesAtLast = ts, 1
+				skipAtLast = 0
+			}
+			if !timestamps {
+				line = text
+			}
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return written, &permanentError{fmt.Errorf("error writing logs: %w", err)}
+		}
+		written = true
+	}
+}
+
+// splitTimestamp splits the RFC3339 timestamp which prefixes log lines when timestamps are
+// requested.
+func splitTimestamp(line string) (time.Time, string, bool) {
+	i := strings.IndexByte(line, ' ')
+	if i < 0 {
+		return time.Time{}, line, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, line[:i])
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return ts, line[i+1:], true
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCopyLinesResume(t *testing.T) {
+	pos := &followPosition{}
+	var out strings.Builder
+	first := "2024-01-02T15:04:05Z a\n2024-01-02T15:04:06Z b\n2024-01-02T15:04:06Z c\n2024-01-02T15:04:07Z par"
+	if _, err := copyLines(strings.NewReader(first), &out, false, pos); err != nil {
+		t.Fatalf("copyLines() failed: %v", err)
+	}
+	// After resuming, the server repeats lines starting at the rounded down timestamp.
+	second := "2024-01-02T15:04:06Z b\n2024-01-02T15:04:06Z c\n2024-01-02T15:04:07Z partial\n"
+	if _, err := copyLines(strings.NewReader(second), &out, false, pos); err != nil {
+		t.Fatalf("copyLines() failed: %v", err)
+	}
+	if got, want := out.String(), "a\nb\nc\npartial\n"; got != want {
+		t.Errorf("copyLines() wrote %q, want %q", got, want)
+	}
+}
+
+// cancelingWriter cancels a context once the last expected line was written.
+type cancelingWriter struct {
+	out    strings.Builder
+	cancel context.CancelFunc
+	last   string
+}
+
+func (w *cancelingWriter) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	if strings.HasSuffix(w.out.String(), w.last) {
+		w.cancel()
+	}
+	return n, err
+}
+
+func TestFollowLogsReconnects(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.Query())
+		n := len(queries)
+		mu.Unlock()
+		switch n {
+		case 1:
+			fmt.Fprint(w, "2024-01-02T15:04:05Z a\n2024-01-02T15:04:06Z b\n")
+		case 2:
+			fmt.Fprint(w, "2024-01-02T15:04:06Z b\n2024-01-02T15:04:07Z c\n")
+		}
+	}))
+	defer server.Close()
+
+	verboseOut = io.Discard
+	endpoint, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() failed: %v", err)
+	}
+	out := &cancelingWriter{cancel: cancel, last: "c\n"}
+	query := url.Values{paramTailLines: []string{"10"}}
+	if err := followLogs(ctx, httpLogStream(server.Client()), *endpoint, query, http.Header{}, true, out); err != nil {
+		t.Fatalf("followLogs() failed: %v", err)
+	}
+
+	if got, want := out.out.String(), "2024-01-02T15:04:05Z a\n2024-01-02T15:04:06Z b\n2024-01-02T15:04:07Z c\n"; got != want {
+		t.Errorf("followLogs() wrote %q, want %q", got, want)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("followLogs() made %d requests, want 2", len(queries))
+	}
+	if queries[1].Get(paramSinceSec) == "" || queries[1].Has(paramTailLines) {
+		t.Errorf("followLogs() resumed with query %v, want %s and no %s", queries[1], paramSinceSec, paramTailLines)
+	}
+}
+
+func TestFollowLogsPermanentError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() failed: %v", err)
+	}
+	if err := followLogs(context.Background(), httpLogStream(server.Client()), *endpoint, url.Values{}, http.Header{}, false, io.Discard); err == nil {
+		t.Error("followLogs() succeeded, want error for forbidden requests")
+	}
+}
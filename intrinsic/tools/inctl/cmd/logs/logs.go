@@ -4,6 +4,7 @@
 package logs
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -32,15 +33,23 @@ const (
 	keyTypeSkill    = "skill"
 	keyTypeResource = "resource"
 	keyHiddenDebug  = "debug"
+	keySeverity     = "severity"
+	keyContainer    = "container"
+	keyPodPhase     = "pod_phase"
+	keyTransport    = "transport"
 )
 
 var (
 	showLogs = &cobra.Command{
-		Use:        "logs",
-		Aliases:    []string{"slogs"},
-		Example:    "inctl logs --org ORGANIZATION --solution SOLUTION-ID --follow --service NAME",
-		Short:      "Prints logs from the solution",
-		Long:       "Prints resource logs (skill or service) from the instance running in given solution.",
+		Use:     "logs",
+		Aliases: []string{"slogs"},
+		Example: "inctl logs --org ORGANIZATION --solution SOLUTION-ID --follow --service NAME",
+		Short:   "Prints logs from the solution",
+		Long: `Prints resource logs (skill or service) from the instance running in given solution.
+
+Logs are read via the relay of the cluster in the cloud. If the relay cannot be reached but the
+cluster can be reached on the local network at --address, logs are read from there instead, and
+vice versa, with a notice.`,
 		Args:       cobra.ExactArgs(1),
 		ArgAliases: []string{"ID"},
 		RunE:       runLogsCmd,
@@ -59,26 +68,65 @@ func runLogsCmd(cmd *cobra.Command, args []string) error {
 	verboseDebug = cmdFlags.GetBool(keyHiddenDebug)
 	verboseOut = cmd.OutOrStderr()
 
-	context := cmdFlags.GetString(cmdutils.KeyContext)
-	project := cmdFlags.GetFlagProject()
-	org := cmdFlags.GetFlagOrganization()
+	ctx, project, cluster, err := resolveCluster(cmd.Context(), cmdFlags)
+	if err != nil {
+		return err
+	}
+
+	params := &cmdParams{
+		frontendURL: createFrontendURL(project, cluster),
+		follow:      cmdFlags.GetBool(keyFollow),
+		timestamps:  cmdFlags.GetBool(keyTimestamps),
+		tailLines:   cmdFlags.GetInt(keyTailLines),
+		projectName: project,
+		container:   cmdFlags.GetString(keyContainer),
+	}
+	if project != "" {
+		params.onpremURL = onpremFrontendURL(cmdFlags)
+	}
+	if params.severity, err = parseFilterValue(keySeverity, cmdFlags.GetString(keySeverity), severities); err != nil {
+		return err
+	}
+	if params.podPhase, err = parseFilterValue(keyPodPhase, cmdFlags.GetString(keyPodPhase), podPhases); err != nil {
+		return err
+	}
+	if params.transport, err = parseFilterValue(keyTransport, cmdFlags.GetString(keyTransport), transports); err != nil {
+		return err
+	}
+
+	if params.resourceType, err = getResourceType(cmdFlags); err != nil {
+		return err
+	}
+
+	if params.resourceID, err = getResourceID(params.resourceType, target); err != nil {
+		return err
+	}
+
+	return readLogsFromSolution(ctx, params, cmd.OutOrStdout())
+}
+
+// resolveCluster returns the project and the cluster running the solution given by the flags.
+func resolveCluster(ctx context.Context, flags *cmdutils.CmdFlags) (context.Context, string, string, error) {
+	kubeContext := flags.GetString(cmdutils.KeyContext)
+	project := flags.GetFlagProject()
+	org := flags.GetFlagOrganization()
 
 	var serverAddr string
-	if context == "minikube" {
+	if kubeContext == "minikube" {
 		serverAddr = localhostURL
 		project = ""
 	} else {
 		serverAddr = fmt.Sprintf("dns:///www.endpoints.%s.cloud.goog:443", project)
 	}
 
-	solution := cmdFlags.GetString(cmdutils.KeySolution)
-	ctx, conn, err := dialerutil.DialConnectionCtx(cmd.Context(), dialerutil.DialInfoParams{
+	solution := flags.GetString(cmdutils.KeySolution)
+	ctx, conn, err := dialerutil.DialConnectionCtx(ctx, dialerutil.DialInfoParams{
 		Address:  serverAddr,
 		CredName: project,
 		CredOrg:  org,
 	})
 	if err != nil {
-		return fmt.Errorf("could not create connection: %v", err)
+		return nil, "", "", fmt.Errorf("could not create connection: %v", err)
 	}
 	defer conn.Close()
 
@@ -86,29 +134,26 @@ func runLogsCmd(cmd *cobra.Command, args []string) error {
 		ctx,
 		conn,
 		solution,
-		context,
+		kubeContext,
 	)
 	if err != nil {
-		return fmt.Errorf("could not resolve solution to cluster: %s", err)
-	}
-
-	params := &cmdParams{
-		frontendURL: createFrontendURL(project, cluster),
-		follow:      cmdFlags.GetBool(keyFollow),
-		timestamps:  cmdFlags.GetBool(keyTimestamps),
-		tailLines:   cmdFlags.GetInt(keyTailLines),
-		projectName: project,
+		return nil, "", "", fmt.Errorf("could not resolve solution to cluster: %s", err)
 	}
+	return ctx, project, cluster, nil
+}
 
-	if params.resourceType, err = getResourceType(); err != nil {
-		return err
+// parseFilterValue returns the allowed value matching value case-insensitively. Empty values
+// disable the filter.
+func parseFilterValue(flag string, value string, allowed []string) (string, error) {
+	if value == "" {
+		return "", nil
 	}
-
-	if params.resourceID, err = getResourceID(params.resourceType, target); err != nil {
-		return err
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return a, nil
+		}
 	}
-
-	return readLogsFromSolution(ctx, params, cmd.OutOrStdout())
+	return "", fmt.Errorf("invalid value %q for --%s, must be one of %s", value, flag, strings.Join(allowed, ", "))
 }
 
 func getResourceID(resType resourceType, target string) (string, error) {
@@ -150,11 +195,11 @@ func getResourceID(resType resourceType, target string) (string, error) {
 	return k8sNormalized, nil
 }
 
-func getResourceType() (resourceType, error) {
-	if cmdFlags.IsSet(keyTypeSkill) {
+func getResourceType(flags *cmdutils.CmdFlags) (resourceType, error) {
+	if flags.IsSet(keyTypeSkill) {
 		return rtSkill, nil
 	}
-	if cmdFlags.IsSet(keyTypeService) {
+	if flags.IsSet(keyTypeService) {
 		return rtService, nil
 	}
 	// todo: make sure resource is mentioned in error internally.
@@ -180,6 +225,13 @@ func init() {
 	cmdFlags.OptionalInt(keyTailLines, 10, "The number of recent log lines to display. An input number less than 0 shows all log lines.")
 	cmdFlags.OptionalString(keySinceSec, "", "Show logs starting since value. Value is either relative (e.g 10m) or \ndate time in RFC3339 format (e.g: 2006-01-02T15:04:05Z07:00)")
 
+	cmdFlags.OptionalString(keySeverity, "", fmt.Sprintf("Only show lines with at least this severity, filtered on the server. One of: %s", strings.Join(severities, ", ")))
+	cmdFlags.OptionalString(keyContainer, "", "Only show logs of the container with this name, e.g. a sidecar of the service.")
+	cmdFlags.OptionalString(keyPodPhase, "", fmt.Sprintf("Only show logs of pods in this phase. One of: %s", strings.Join(podPhases, ", ")))
+
+	cmdFlags.OptionalString(keyTransport, transportHTTP, fmt.Sprintf("Transport used to stream logs with --%s, which "+
+		"reconnects and resumes after the last received line when the stream is cut. One of: %s", keyFollow, strings.Join(transports, ", ")))
+
 	cmdFlags.OptionalBool(keyTypeSkill, false, "Indicates logs source is the skill")
 	cmdFlags.OptionalBool(keyTypeService, false, "Indicates logs source is the service")
 
@@ -10,9 +10,9 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"os"
-	"path"
 	"time"
 
+	"intrinsic/frontend/frontendclient"
 	"intrinsic/tools/inctl/auth"
 )
 
@@ -23,10 +23,20 @@ const (
 	paramTimestamps = "timestamps"
 	paramTailLines  = "tailLines"
 	paramSinceSec   = "sinceSeconds"
+	paramSeverity   = "minSeverity"
+	paramContainer  = "container"
+	paramPodPhase   = "podPhase"
 )
 
+// Severities accepted by --severity, from lowest to highest. The server only returns lines at or
+// above the requested severity.
+var severities = []string{"debug", "info", "warning", "error"}
+
+// Pod phases accepted by --pod_phase, as reported by Kubernetes.
+var podPhases = []string{"Pending", "Running", "Succeeded", "Failed", "Unknown"}
+
 const (
-	localhostURL = "localhost:17080"
+	localhostURL = frontendclient.LocalAddress
 )
 
 var (
@@ -34,20 +44,11 @@ var (
 	verboseOut   io.Writer = os.Stderr
 )
 
-type bodyReader = func(context.Context, io.Reader) (string, error)
-
 func createFrontendURL(projectName string, clusterName string) url.URL {
-	var frontendURL url.URL
 	if projectName == "" {
-		frontendURL = url.URL{Host: localhostURL, Path: "frontend/api", Scheme: "http"}
-	} else {
-		frontendURL = url.URL{
-			Host:   fmt.Sprintf("www.endpoints.%s.cloud.goog", projectName),
-			Path:   fmt.Sprintf("frontend/client/%s/api", clusterName),
-			Scheme: "https",
-		}
+		return frontendclient.LocalURL(localhostURL)
 	}
-	return frontendURL
+	return frontendclient.RelayURL(projectName, clusterName)
 }
 
 type resourceType int
@@ -67,28 +68,20 @@ type cmdParams struct {
 	tailLines    int
 	projectName  string
 	sinceSeconds string
+	// severity is the minimum severity of returned lines, empty for all lines.
+	severity string
+	// container restricts logs to the container with this name.
+	container string
+	// podPhase restricts logs to pods in this phase.
+	podPhase string
+	// transport is used to stream logs with --follow, one of transports.
+	transport string
+	// onpremURL is the frontend of the cluster on the local network, used when frontendURL cannot
+	// be reached and vice versa. Nil if there is no such fallback.
+	onpremURL *url.URL
 }
 
 func readLogsFromSolution(ctx context.Context, params *cmdParams, w io.Writer) error {
-	verboseOut.Write([]byte(fmt.Sprintf("%s\n", params.frontendURL.Path)))
-	tokenURL := params.frontendURL
-	tokenURL.Path = path.Join(tokenURL.EscapedPath(), "token")
-	authToken, err := getAuthToken(params.projectName)
-	if err != nil {
-		return err
-	}
-
-	xsrfToken, err := callEndpoint(ctx, http.MethodGet, &tokenURL, authToken, nil, nil,
-		func(_ context.Context, body io.Reader) (string, error) {
-			token, err := io.ReadAll(body)
-			return string(token), err
-		})
-	if err != nil {
-		return fmt.Errorf("could not obtain xsrf token: %w", err)
-	}
-
-	consoleLogsURL := params.frontendURL
-	consoleLogsURL.Path = path.Join(consoleLogsURL.EscapedPath(), "consoleLogs")
 	consoleLogsQuery := setResourceID(params.resourceType, params.resourceID)
 	if params.follow {
 		consoleLogsQuery.Set(paramFollow, fmt.Sprintf("%t", params.follow))
@@ -96,6 +89,7 @@ func readLogsFromSolution(ctx context.Context, params *cmdParams, w io.Writer) e
 		consoleLogsQuery.Set(paramTailLines, fmt.Sprintf("%d", params.tailLines))
 	}
 	consoleLogsQuery.Set(paramTimestamps, fmt.Sprintf("%t", params.timestamps))
+	setFilters(consoleLogsQuery, params)
 
 	if d, ok, err := parseSinceSeconds(params.sinceSeconds); ok && err == nil {
 		// nit: our now is different from server now (at the time of processing),
@@ -108,19 +102,33 @@ func readLogsFromSolution(ctx context.Context, params *cmdParams, w io.Writer) e
 		return fmt.Errorf("cannot parse parameter --%s: %w", keySinceSec, err)
 	}
 
-	consoleLogsURL.RawQuery = consoleLogsQuery.Encode()
-
-	xsrfHeader := http.Header{"X-XSRF-TOKEN": []string{xsrfToken}}
+	if params.follow {
+		routes := newRouteSwitcher(logRoutes(params), params.transport)
+		return followLogs(ctx, routes.stream, url.URL{}, consoleLogsQuery, nil, params.timestamps, w)
+	}
 
-	_, err = callEndpoint(ctx, http.MethodGet, &consoleLogsURL, authToken, xsrfHeader, nil,
-		func(_ context.Context, body io.Reader) (string, error) {
-			if _, err := io.Copy(w, body); err != nil {
-				return "", fmt.Errorf("error reading/writing logs: %w", err)
-			}
-			return "", nil
-		})
+	routes := newRouteSwitcher(logRoutes(params), transportHTTP)
+	body, err := routes.stream(ctx, &url.URL{RawQuery: consoleLogsQuery.Encode()}, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if _, err := io.Copy(w, body); err != nil {
+		return fmt.Errorf("error reading/writing logs: %w", err)
+	}
+	return nil
+}
 
-	return err
+func setFilters(query url.Values, params *cmdParams) {
+	if params.severity != "" {
+		query.Set(paramSeverity, params.severity)
+	}
+	if params.container != "" {
+		query.Set(paramContainer, params.container)
+	}
+	if params.podPhase != "" {
+		query.Set(paramPodPhase, params.podPhase)
+	}
 }
 
 func setResourceID(resType resourceType, id string) url.Values {
@@ -136,47 +144,6 @@ func setResourceID(resType resourceType, id string) url.Values {
 	return result
 }
 
-// callEndpoint calls given endpoint URL and handles all edge cases. If response is 200 OK
-// and response body processing function (bodyFx) is present, response body is passed
-// for processing. Otherwise, "", nil is return value.
-func callEndpoint(ctx context.Context, method string, endpoint *url.URL, authToken *auth.ProjectToken, headers http.Header, payload io.Reader, bodyFx bodyReader) (string, error) {
-	if verboseDebug {
-		fmt.Fprintf(verboseOut, "URL: '%s'\n", endpoint)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), payload)
-	if len(headers) > 0 {
-		req.Header = headers
-	}
-	if err != nil {
-		return "", fmt.Errorf("could not create request: %w", err)
-	}
-
-	if authToken != nil {
-		req, err = authToken.HTTPAuthorization(req)
-		if err != nil {
-			return "", fmt.Errorf("cannot obtain credentials: %w", err)
-		}
-	}
-
-	printRequest(req)
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request to target failed: %w", err)
-	}
-	if response.StatusCode != http.StatusOK {
-		printResponse(response)
-		return "", fmt.Errorf("unexpected response: %s", response.Status)
-	}
-	defer response.Body.Close()
-	if bodyFx != nil {
-		return bodyFx(ctx, response.Body)
-	}
-
-	// empty body consumer is valid
-	return "", nil
-}
-
 // Prints request headers and body (if present) into std_err.
 func printRequest(req *http.Request) {
 	if !verboseDebug || req == nil {
@@ -237,15 +204,25 @@ func parseSinceSeconds(since string) (time.Duration, bool, error) {
 	return time.Now().Sub(t), true, nil
 }
 
-func getAuthToken(project string) (*auth.ProjectToken, error) {
+// getCredentials returns the API key and the HTTP client to authenticate requests to the
+// project. The API key is nil if the project is accessed with a client certificate only.
+func getCredentials(project string) (*auth.ProjectToken, *http.Client, error) {
 	if project == "" {
 		// No authorization required (e.g. local call in tests)
-		return nil, nil
+		return nil, http.DefaultClient, nil
 	}
 
 	config, err := auth.NewStore().GetConfiguration(project)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	token, clientCert, err := config.Credentials(auth.AliasDefaultToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := clientCert.HTTPClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot create HTTP client: %w", err)
 	}
-	return config.GetDefaultCredentials()
+	return token, client, nil
 }
@@ -0,0 +1,141 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"intrinsic/assets/cmdutils"
+	"intrinsic/frontend/frontendclient"
+)
+
+// logRoute is a way to reach the frontend of the cluster, e.g., via the relay in the cloud or
+// directly on the local network.
+type logRoute struct {
+	name        string
+	frontendURL url.URL
+	// project is used to authenticate requests, empty if they need no credentials.
+	project string
+}
+
+// openRoute is a route for which credentials and an XSRF token have been obtained.
+type openRoute struct {
+	consoleLogsURL url.URL
+	header         http.Header
+	stream         logStream
+}
+
+// routeSwitcher streams logs via the first of its routes which can reach the cluster. It stays on
+// a route until the route becomes unreachable, e.g., because the relay of the cluster went down or
+// the user left the local network of the cluster.
+type routeSwitcher struct {
+	routes    []logRoute
+	transport string
+	current   int
+	open      map[int]*openRoute
+}
+
+func newRouteSwitcher(routes []logRoute, transport string) *routeSwitcher {
+	return &routeSwitcher{routes: routes, transport: transport, open: map[int]*openRoute{}}
+}
+
+// logRoutes returns the routes to the frontend given by params, the preferred one first.
+func logRoutes(params *cmdParams) []logRoute {
+	name := "cloud relay"
+	if params.projectName == "" {
+		name = "local cluster"
+	}
+	routes := []logRoute{{name: name, frontendURL: params.frontendURL, project: params.projectName}}
+	if params.onpremURL != nil {
+		routes = append(routes, logRoute{name: "local network", frontendURL: *params.onpremURL})
+	}
+	return routes
+}
+
+// onpremFrontendURL returns the URL of the frontend of the cluster on the local network, nil if
+// the address flag does not name a cluster on the local network.
+func onpremFrontendURL(flags *cmdutils.CmdFlags) *url.URL {
+	address := flags.GetFlagAddress()
+	if address == "" || address == localhostURL || strings.Contains(address, "://") {
+		return nil
+	}
+	u := frontendclient.LocalURL(address)
+	return &u
+}
+
+// isUnreachable reports whether err shows that the frontend could not be reached, as opposed to
+// an error returned by the frontend itself.
+func isUnreachable(err error) bool {
+	var rerr *frontendclient.ResponseError
+	if errors.As(err, &rerr) {
+		return rerr.StatusCode == http.StatusBadGateway || rerr.StatusCode == http.StatusServiceUnavailable || rerr.StatusCode == http.StatusGatewayTimeout
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// stream is a logStream which ignores all of endpoint but its query and the header, which are
+// taken from the current route. If the current route is unreachable, the next one is tried.
+func (s *routeSwitcher) stream(ctx context.Context, endpoint *url.URL, _ http.Header) (io.ReadCloser, error) {
+	var err error
+	for range s.routes {
+		var body io.ReadCloser
+		body, err = s.streamCurrent(ctx, endpoint.RawQuery)
+		if err == nil || ctx.Err() != nil || !isUnreachable(err) || len(s.routes) == 1 {
+			return body, err
+		}
+		next := (s.current + 1) % len(s.routes)
+		fmt.Fprintf(verboseOut, "Cannot reach the cluster via the %s (%v), switching to the %s\n", s.routes[s.current].name, err, s.routes[next].name)
+		s.current = next
+	}
+	return nil, err
+}
+
+func (s *routeSwitcher) streamCurrent(ctx context.Context, rawQuery string) (io.ReadCloser, error) {
+	r, ok := s.open[s.current]
+	if !ok {
+		var err error
+		if r, err = s.openCurrent(ctx); err != nil {
+			return nil, err
+		}
+		s.open[s.current] = r
+	}
+	u := r.consoleLogsURL
+	u.RawQuery = rawQuery
+	return r.stream(ctx, &u, r.header)
+}
+
+// openCurrent obtains the credentials and XSRF token for the current route.
+func (s *routeSwitcher) openCurrent(ctx context.Context) (*openRoute, error) {
+	route := s.routes[s.current]
+	verboseOut.Write([]byte(fmt.Sprintf("%s\n", route.frontendURL.Path)))
+	authToken, client, err := getCredentials(route.project)
+	if err != nil {
+		return nil, err
+	}
+	opts := frontendclient.Options{HTTPClient: client}
+	if authToken != nil {
+		opts.Auth = authToken
+	}
+	frontend := frontendclient.New(route.frontendURL, opts)
+
+	// The header authenticates the stream, which is not sent with the frontend client since it may
+	// be a websocket.
+	header, err := frontend.Header(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &openRoute{consoleLogsURL: frontend.ConsoleLogsURL(nil), header: header, stream: httpLogStream(client)}
+	if s.transport == transportWebsocket {
+		r.stream = websocketLogStream(client)
+	}
+	return r, nil
+}
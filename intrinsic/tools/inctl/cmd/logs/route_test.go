@@ -0,0 +1,104 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newFrontend returns a server which serves the token and consoleLogs endpoints of a frontend, the
+// latter with the given status and body.
+func newFrontend(t *testing.T, code int, logs string) (*httptest.Server, url.URL) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/token"):
+			fmt.Fprint(w, "xsrf")
+		case r.Header.Get("X-XSRF-TOKEN") != "xsrf":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(code)
+			fmt.Fprint(w, logs)
+		}
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() failed: %v", err)
+	}
+	u.Path = "frontend/api"
+	return server, *u
+}
+
+func TestRouteSwitcherFallsBack(t *testing.T) {
+	var notices bytes.Buffer
+	verboseOut = &notices
+	_, relay := newFrontend(t, http.StatusServiceUnavailable, "")
+	_, local := newFrontend(t, http.StatusOK, "a\n")
+
+	s := newRouteSwitcher([]logRoute{{name: "cloud relay", frontendURL: relay}, {name: "local network", frontendURL: local}}, transportHTTP)
+	body, err := s.stream(context.Background(), &url.URL{}, nil)
+	if err != nil {
+		t.Fatalf("stream() failed: %v", err)
+	}
+	defer body.Close()
+	got, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("io.ReadAll() failed: %v", err)
+	}
+	if string(got) != "a\n" {
+		t.Errorf("stream() returned %q, want %q", got, "a\n")
+	}
+	if s.current != 1 {
+		t.Errorf("stream() is on route %d, want 1", s.current)
+	}
+	if !strings.Contains(notices.String(), "switching to the local network") {
+		t.Errorf("stream() printed %q, want a notice about switching routes", notices.String())
+	}
+}
+
+func TestRouteSwitcherSwitchesBack(t *testing.T) {
+	verboseOut = io.Discard
+	_, relay := newFrontend(t, http.StatusOK, "a\n")
+	localServer, local := newFrontend(t, http.StatusOK, "b\n")
+
+	s := newRouteSwitcher([]logRoute{{name: "cloud relay", frontendURL: relay}, {name: "local network", frontendURL: local}}, transportHTTP)
+	s.current = 1
+	if body, err := s.stream(context.Background(), &url.URL{}, nil); err != nil {
+		t.Fatalf("stream() failed: %v", err)
+	} else {
+		body.Close()
+	}
+
+	localServer.Close()
+	body, err := s.stream(context.Background(), &url.URL{}, nil)
+	if err != nil {
+		t.Fatalf("stream() failed after the local network went away: %v", err)
+	}
+	body.Close()
+	if s.current != 0 {
+		t.Errorf("stream() is on route %d, want 0", s.current)
+	}
+}
+
+func TestRouteSwitcherKeepsServerErrors(t *testing.T) {
+	verboseOut = io.Discard
+	_, relay := newFrontend(t, http.StatusInternalServerError, "")
+	_, local := newFrontend(t, http.StatusOK, "a\n")
+
+	s := newRouteSwitcher([]logRoute{{name: "cloud relay", frontendURL: relay}, {name: "local network", frontendURL: local}}, transportHTTP)
+	if _, err := s.stream(context.Background(), &url.URL{}, nil); err == nil {
+		t.Error("stream() succeeded, want the error of the frontend")
+	}
+	if s.current != 0 {
+		t.Errorf("stream() is on route %d, want 0", s.current)
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+)
+
+const (
+	keyTop     = "top"
+	keyBuckets = "buckets"
+
+	sevFatal   = "fatal"
+	sevUnknown = "unknown"
+
+	// maxTemplateLength is the length at which error signatures are cut off.
+	maxTemplateLength = 160
+	// histogramWidth is the width of the longest bar of the histogram in text output.
+	histogramWidth = 40
+	// levelWordPrefix is the length of the line prefix which is searched for levelWord, so that
+	// words in the message do not count.
+	levelWordPrefix = 48
+)
+
+// statsSeverities are the severities which lines are counted by, from lowest to highest.
+var statsSeverities = []string{"debug", "info", "warning", "error", sevFatal, sevUnknown}
+
+var (
+	// glogPrefix matches the prefix of lines logged by glog and absl, e.g.,
+	// "E0102 15:04:05.000000 1234 file.cc:12] message".
+	glogPrefix = regexp.MustCompile(`^([DIWEF])\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+\d+ [^\]]*\] ?`)
+	// levelWord matches the severity of other common log formats, e.g., "[ERROR]", "WARNING:" or
+	// "level=error".
+	levelWord = regexp.MustCompile(`\b(DEBUG|INFO|WARN|WARNING|ERROR|FATAL|CRITICAL)\b|\blevel=(debug|info|warn|warning|error|fatal)\b`)
+
+	// quotedString and numericToken match the parts of a message which vary between occurrences of
+	// the same error: quoted strings and tokens containing digits, like numbers, ids, addresses and
+	// times.
+	quotedString = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	numericToken = regexp.MustCompile(`\b(?:0x)?[0-9a-fA-F]*[0-9][0-9a-zA-Z._:/-]*`)
+	whitespace   = regexp.MustCompile(`\s+`)
+)
+
+var glogSeverities = map[string]string{
+	"D": "debug",
+	"I": "info",
+	"W": "warning",
+	"E": "error",
+	"F": sevFatal,
+}
+
+var levelWordSeverities = map[string]string{
+	"debug":    "debug",
+	"info":     "info",
+	"warn":     "warning",
+	"warning":  "warning",
+	"error":    "error",
+	"fatal":    sevFatal,
+	"critical": sevFatal,
+}
+
+// parseSeverity returns the severity of a log line without timestamp and its message, i.e., the
+// line without the glog prefix.
+func parseSeverity(line string) (string, string) {
+	if m := glogPrefix.FindStringSubmatch(line); m != nil {
+		return glogSeverities[m[1]], line[len(m[0]):]
+	}
+	prefix := line
+	if len(prefix) > levelWordPrefix {
+		prefix = prefix[:levelWordPrefix]
+	}
+	if m := levelWord.FindStringSubmatch(prefix); m != nil {
+		word := m[1]
+		if word == "" {
+			word = m[2]
+		}
+		return levelWordSeverities[strings.ToLower(word)], line
+	}
+	return sevUnknown, line
+}
+
+// errorTemplate replaces the variable parts of message by placeholders, so that occurrences of
+// the same error have the same template.
+func errorTemplate(message string) string {
+	t := quotedString.ReplaceAllString(message, "<*>")
+	t = numericToken.ReplaceAllString(t, "<*>")
+	t = strings.TrimSpace(whitespace.ReplaceAllString(t, " "))
+	if len(t) > maxTemplateLength {
+		t = t[:maxTemplateLength] + "..."
+	}
+	return t
+}
+
+// severityCounts counts lines per severity.
+type severityCounts map[string]int
+
+type resourceStats struct {
+	ID         string         `json:"id"`
+	Lines      int            `json:"lines"`
+	Severities severityCounts `json:"severities"`
+}
+
+type errorSignature struct {
+	Template  string    `json:"template"`
+	Example   string    `json:"example"`
+	Count     int       `json:"count"`
+	Resources []string  `json:"resources"`
+	First     time.Time `json:"first"`
+	Last      time.Time `json:"last"`
+}
+
+type histogramBucket struct {
+	Start  time.Time `json:"start"`
+	Lines  int       `json:"lines"`
+	Errors int       `json:"errors"`
+}
+
+// logStats summarizes the logs of resources for triage.
+type logStats struct {
+	Since      time.Time          `json:"since"`
+	Until      time.Time          `json:"until"`
+	Lines      int                `json:"lines"`
+	Severities severityCounts     `json:"severities"`
+	Resources  []*resourceStats   `json:"resources"`
+	TopErrors  []*errorSignature  `json:"topErrors"`
+	Histogram  []*histogramBucket `json:"histogram"`
+}
+
+// statsCollector aggregates log lines into logStats.
+type statsCollector struct {
+	stats      *logStats
+	bucketSize time.Duration
+	resources  map[string]*resourceStats
+	signatures map[string]*errorSignature
+}
+
+// newStatsCollector returns a collector for lines logged between since and until, which are
+// counted in the given number of histogram buckets.
+func newStatsCollector(since time.Time, until time.Time, buckets int) *statsCollector {
+	c := &statsCollector{
+		stats: &logStats{
+			Since:      since,
+			Until:      until,
+			Severities: severityCounts{},
+		},
+		bucketSize: until.Sub(since) / time.Duration(buckets),
+		resources:  map[string]*resourceStats{},
+		signatures: map[string]*errorSignature{},
+	}
+	for i := 0; i < buckets; i++ {
+		c.stats.Histogram = append(c.stats.Histogram, &histogramBucket{Start: since.Add(time.Duration(i) * c.bucketSize)})
+	}
+	return c
+}
+
+// add counts a log line of the given resource. The line is expected to be prefixed by a
+// timestamp, lines without are counted but not added to the histogram.
+func (c *statsCollector) add(resource string, line string) {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	ts, text, hasTimestamp := splitTimestamp(line)
+	severity, message := parseSeverity(text)
+
+	r, ok := c.resources[resource]
+	if !ok {
+		r = &resourceStats{ID: resource, Severities: severityCounts{}}
+		c.resources[resource] = r
+		c.stats.Resources = append(c.stats.Resources, r)
+	}
+	r.Lines++
+	r.Severities[severity]++
+	c.stats.Lines++
+	c.stats.Severities[severity]++
+
+	isError := severity == "error" || severity == sevFatal
+	if hasTimestamp && c.bucketSize > 0 && !ts.Before(c.stats.Since) {
+		i := int(ts.Sub(c.stats.Since) / c.bucketSize)
+		if i >= len(c.stats.Histogram) {
+			i = len(c.stats.Histogram) - 1
+		}
+		b := c.stats.Histogram[i]
+		b.Lines++
+		if isError {
+			b.Errors++
+		}
+	}
+
+	if !isError {
+		return
+	}
+	template := errorTemplate(message)
+	s, ok := c.signatures[template]
+	if !ok {
+		s = &errorSignature{Template: template, Example: strings.TrimSpace(message)}
+		c.signatures[template] = s
+	}
+	s.Count++
+	if !slices.Contains(s.Resources, resource) {
+		s.Resources = append(s.Resources, resource)
+	}
+	if hasTimestamp {
+		if s.First.IsZero() || ts.Before(s.First) {
+			s.First = ts
+		}
+		if ts.After(s.Last) {
+			s.Last = ts
+		}
+	}
+}
+
+// result returns the statistics with the top most frequent error signatures.
+func (c *statsCollector) result(top int) *logStats {
+	signatures := make([]*errorSignature, 0, len(c.signatures))
+	for _, s := range c.signatures {
+		signatures = append(signatures, s)
+	}
+	sort.Slice(signatures, func(i, j int) bool {
+		if signatures[i].Count != signatures[j].Count {
+			return signatures[i].Count > signatures[j].Count
+		}
+		return signatures[i].Template < signatures[j].Template
+	})
+	if top >= 0 && len(signatures) > top {
+		signatures = signatures[:top]
+	}
+	c.stats.TopErrors = signatures
+	return c.stats
+}
+
+func (s *logStats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d lines from %s to %s\n", s.Lines, s.Since.Local().Format(time.DateTime), s.Until.Local().Format(time.DateTime))
+
+	fmt.Fprintln(&b)
+	w := tabwriter.NewWriter(&b, 1, 1, 2, ' ', 0)
+	fmt.Fprint(w, "RESOURCE\t")
+	for _, sev := range statsSeverities {
+		fmt.Fprintf(w, "%s\t", strings.ToUpper(sev))
+	}
+	fmt.Fprintln(w, "TOTAL")
+	for _, r := range s.Resources {
+		fmt.Fprintf(w, "%s\t", r.ID)
+		for _, sev := range statsSeverities {
+			fmt.Fprintf(w, "%d\t", r.Severities[sev])
+		}
+		fmt.Fprintf(w, "%d\n", r.Lines)
+	}
+	if len(s.Resources) > 1 {
+		fmt.Fprint(w, "(all)\t")
+		for _, sev := range statsSeverities {
+			fmt.Fprintf(w, "%d\t", s.Severities[sev])
+		}
+		fmt.Fprintf(w, "%d\n", s.Lines)
+	}
+	w.Flush()
+
+	fmt.Fprintln(&b)
+	if len(s.TopErrors) == 0 {
+		fmt.Fprintln(&b, "No errors.")
+	} else {
+		fmt.Fprintln(&b, "Top errors:")
+		w = tabwriter.NewWriter(&b, 1, 1, 2, ' ', 0)
+		fmt.Fprintln(w, "COUNT\tLAST\tRESOURCES\tSIGNATURE")
+		for _, e := range s.TopErrors {
+			last := "-"
+			if !e.Last.IsZero() {
+				last = e.Last.Local().Format(time.TimeOnly)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", e.Count, last, strings.Join(e.Resources, ","), e.Template)
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "Lines over time (errors in parentheses):")
+	most := 0
+	for _, h := range s.Histogram {
+		if h.Lines > most {
+			most = h.Lines
+		}
+	}
+	w = tabwriter.NewWriter(&b, 1, 1, 2, ' ', 0)
+	for _, h := range s.Histogram {
+		bar := ""
+		if most > 0 {
+			bar = strings.Repeat("#", (h.Lines*histogramWidth+most-1)/most)
+		}
+		fmt.Fprintf(w, "%s\t%d\t(%d)\t%s\n", h.Start.Local().Format(time.DateTime), h.Lines, h.Errors, bar)
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// statsWriter feeds the lines written to it to a statsCollector.
+type statsWriter struct {
+	c        *statsCollector
+	resource string
+	partial  strings.Builder
+}
+
+func (w *statsWriter) Write(p []byte) (int, error) {
+	w.partial.Write(p)
+	buffered := w.partial.String()
+	i := strings.LastIndexByte(buffered, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	for _, line := range strings.Split(buffered[:i], "\n") {
+		w.c.add(w.resource, line)
+	}
+	w.partial.Reset()
+	w.partial.WriteString(buffered[i+1:])
+	return len(p), nil
+}
+
+// flush adds the last line if it is not terminated by a newline.
+func (w *statsWriter) flush() {
+	w.c.add(w.resource, w.partial.String())
+	w.partial.Reset()
+}
+
+var (
+	statsCmd = &cobra.Command{
+		Use:   "stats ID...",
+		Short: "Summarizes logs of resources for triage",
+		Long: `Fetches the logs of one or more resources (skills or services) and summarizes them: the number
+of lines per severity and resource, the most frequent errors and the number of lines over time.
+
+Errors are grouped by their message with numbers, ids and quoted strings replaced by <*>. The
+severity is taken from the glog prefix of a line or from a level like [ERROR] or level=error at its
+start, lines in other formats are counted as unknown.`,
+		Example: `
+	$ inctl logs stats --solution my-solution-id --since 1h --service my_service
+	$ inctl logs stats --solution my-solution-id --since 30m --skill ai.intrinsic.skill_a ai.intrinsic.skill_b --output json
+	`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runStatsCmd,
+	}
+
+	statsFlags = cmdutils.NewCmdFlagsWithViper(viper.New())
+)
+
+func runStatsCmd(cmd *cobra.Command, args []string) error {
+	verboseDebug = statsFlags.GetBool(keyHiddenDebug)
+	verboseOut = cmd.OutOrStderr()
+
+	sinceFlag := statsFlags.GetString(keySinceSec)
+	since, ok, err := parseSinceSeconds(sinceFlag)
+	if err != nil {
+		return fmt.Errorf("cannot parse parameter --%s: %w", keySinceSec, err)
+	}
+	if !ok || since == 0 {
+		return fmt.Errorf("--%s must not be empty", keySinceSec)
+	}
+	buckets := statsFlags.GetInt(keyBuckets)
+	if buckets < 1 {
+		return fmt.Errorf("--%s must be at least 1, got %d", keyBuckets, buckets)
+	}
+	resType, err := getResourceType(statsFlags)
+	if err != nil {
+		return err
+	}
+	ctx, project, cluster, err := resolveCluster(cmd.Context(), statsFlags)
+	if err != nil {
+		return err
+	}
+
+	until := time.Now()
+	c := newStatsCollector(until.Add(-since), until, buckets)
+	for _, target := range args {
+		params := &cmdParams{
+			resourceType: resType,
+			frontendURL:  createFrontendURL(project, cluster),
+			timestamps:   true,
+			tailLines:    -1,
+			projectName:  project,
+			sinceSeconds: sinceFlag,
+		}
+		if project != "" {
+			params.onpremURL = onpremFrontendURL(statsFlags)
+		}
+		if params.resourceID, err = getResourceID(resType, target); err != nil {
+			return err
+		}
+		w := &statsWriter{c: c, resource: params.resourceID}
+		if err := readLogsFromSolution(ctx, params, w); err != nil {
+			return fmt.Errorf("cannot read logs of %q: %w", target, err)
+		}
+		w.flush()
+	}
+
+	prtr, err := printer.NewPrinterWithWriter(root.FlagOutput, cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	prtr.Print(c.result(statsFlags.GetInt(keyTop)))
+	return nil
+}
+
+func init() {
+	showLogs.AddCommand(statsCmd)
+	statsFlags.SetCommand(statsCmd)
+
+	statsFlags.AddFlagProjectOptional()
+	statsFlags.OptionalEnvString(cmdutils.KeySolution, "", "Solution ID from which logs will be read.")
+	statsFlags.OptionalEnvString(cmdutils.KeyContext, "", fmt.Sprintf("The Kubernetes cluster to use or localhost if used with --%s", cmdutils.KeyAddress))
+	statsFlags.AddFlagAddress()
+	statsFlags.OptionalString(keySinceSec, "1h", "Summarize logs starting since value. Value is either relative (e.g 10m) or \ndate time in RFC3339 format (e.g: 2006-01-02T15:04:05Z07:00)")
+	statsFlags.OptionalInt(keyTop, 10, "The number of most frequent errors to show. An input number less than 0 shows all errors.")
+	statsFlags.OptionalInt(keyBuckets, 12, "The number of time intervals of the histogram.")
+
+	statsFlags.OptionalBool(keyTypeSkill, false, "Indicates logs source is the skill")
+	statsFlags.OptionalBool(keyTypeService, false, "Indicates logs source is the service")
+	statsFlags.OptionalBool(keyHiddenDebug, false, "Prints extensive debug messages")
+
+	statsFlags.MarkHidden(cmdutils.KeyContext, cmdutils.KeyProject)
+	statsFlags.AddFlagsConflict(keyTypeSkill, keyTypeService)
+}
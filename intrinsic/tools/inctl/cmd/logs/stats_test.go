@@ -0,0 +1,86 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package logs
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		line        string
+		wantSev     string
+		wantMessage string
+	}{
+		{"E0102 15:04:05.123456    12 server.cc:42] cannot connect", "error", "cannot connect"},
+		{"W0102 15:04:05.123456 12 main.go:7] slow", "warning", "slow"},
+		{"2024-01-02 15:04:05,123 - root - INFO - started", "info", "2024-01-02 15:04:05,123 - root - INFO - started"},
+		{`time="..." level=error msg="failed"`, "error", `time="..." level=error msg="failed"`},
+		{"[CRITICAL] out of memory", sevFatal, "[CRITICAL] out of memory"},
+		{"the request succeeded without a severity, the client did not report any ERROR", sevUnknown, "the request succeeded without a severity, the client did not report any ERROR"},
+	}
+	for _, tc := range tests {
+		sev, message := parseSeverity(tc.line)
+		if sev != tc.wantSev || message != tc.wantMessage {
+			t.Errorf("parseSeverity(%q) = %q, %q, want %q, %q", tc.line, sev, message, tc.wantSev, tc.wantMessage)
+		}
+	}
+}
+
+func TestErrorTemplate(t *testing.T) {
+	a := errorTemplate(`request 1234 to "10.0.0.1:8080" failed after 2.5s: id=0x7f3a`)
+	b := errorTemplate(`request 98 to "10.0.0.2:8080" failed after 300ms: id=0xdeadbeef`)
+	if a != b {
+		t.Errorf("errorTemplate() = %q and %q, want equal templates", a, b)
+	}
+	if want := "request <*> to <*> failed after <*> id=<*>"; a != want {
+		t.Errorf("errorTemplate() = %q, want %q", a, want)
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	since := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	c := newStatsCollector(since, since.Add(time.Hour), 4)
+	line := func(minute int, text string) string {
+		return fmt.Sprintf("%s %s\n", since.Add(time.Duration(minute)*time.Minute).Format(time.RFC3339Nano), text)
+	}
+	w := &statsWriter{c: c, resource: "skill_a"}
+	w.Write([]byte(line(1, "I0102 15:01:00.000000 1 a.go:1] hello") + line(20, "E0102 15:20:00.000000 1 a.go:2] timeout after 5s")))
+	w.Write([]byte(line(50, "E0102 15:50:00.000000 1 a.go:2] timeout after 7s")[:20]))
+	w.Write([]byte(line(50, "E0102 15:50:00.000000 1 a.go:2] timeout after 7s")[20:]))
+	w.flush()
+	w = &statsWriter{c: c, resource: "service_b"}
+	w.Write([]byte(line(59, "ERROR timeout after 1s") + "untimestamped line"))
+	w.flush()
+
+	s := c.result(10)
+	if s.Lines != 5 {
+		t.Errorf("Lines = %d, want 5", s.Lines)
+	}
+	if got := s.Severities["error"]; got != 3 {
+		t.Errorf("Severities[error] = %d, want 3", got)
+	}
+	if got := s.Severities[sevUnknown]; got != 1 {
+		t.Errorf("Severities[unknown] = %d, want 1", got)
+	}
+	if len(s.Resources) != 2 || s.Resources[0].ID != "skill_a" || s.Resources[0].Lines != 3 {
+		t.Errorf("Resources = %v, want skill_a with 3 lines and service_b", s.Resources)
+	}
+	// The glog and level word errors have different templates.
+	if len(s.TopErrors) != 2 {
+		t.Fatalf("TopErrors has %d signatures, want 2", len(s.TopErrors))
+	}
+	if e := s.TopErrors[0]; e.Template != "timeout after <*>" || e.Count != 2 || !e.Last.Equal(since.Add(50*time.Minute)) {
+		t.Errorf("TopErrors[0] = %+v, want 2 x %q", e, "timeout after <*>")
+	}
+	var lines, errors []int
+	for _, h := range s.Histogram {
+		lines = append(lines, h.Lines)
+		errors = append(errors, h.Errors)
+	}
+	if fmt.Sprint(lines) != "[1 1 0 2]" || fmt.Sprint(errors) != "[0 1 0 2]" {
+		t.Errorf("Histogram lines = %v, errors = %v, want [1 1 0 2], [0 1 0 2]", lines, errors)
+	}
+}
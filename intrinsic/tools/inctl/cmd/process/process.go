@@ -6,33 +6,24 @@ package process
 import (
 	"context"
 	"fmt"
-	"os"
 
-	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/executive/processclient"
 	"intrinsic/tools/inctl/cmd/root"
 	"intrinsic/tools/inctl/util/orgutil"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
-	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/reflect/protoreflect"
-	apb "intrinsic/executive/proto/annotations_go_proto"
 	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
-	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
-	rmdpb "intrinsic/executive/proto/run_metadata_go_proto"
-	skillregistrygrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
-	srpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
-	skillspb "intrinsic/skills/proto/skills_go_proto"
-	"intrinsic/skills/tools/skill/cmd/dialerutil"
-	"intrinsic/skills/tools/skill/cmd/solutionutil"
 )
 
 const (
 	keyFilter = "filter"
+	keyServer = "server"
 )
 
 const (
@@ -50,179 +41,58 @@ const (
 )
 
 var (
-	flagServerAddress string
-	flagSolutionName  string
-	flagClusterName   string
-	flagInputFile     string
-	flagOutputFile    string
-	flagClearTreeID   bool
-	flagClearNodeIDs  bool
-	flagProcessFormat string
+	flagServerAddress      string
+	flagInputFile          string
+	flagOutputFile         string
+	flagClearTreeID        bool
+	flagClearNodeIDs       bool
+	flagProcessFormat      string
+	flagAllSkills          bool
+	flagProtoConflicts     string
+	flagSkillLockfile      string
+	flagReflectionFallback bool
+	flagSubstitutions      []string
 )
 
 var (
 	viperLocal = viper.New()
+	// cmdFlags provides the --address, --cluster and --solution flags shared with the asset
+	// commands.
+	cmdFlags = cmdutils.NewCmdFlagsWithViper(viperLocal)
 )
 
-var (
-	protoNameBehaviorTree     = proto.MessageName(new(btpb.BehaviorTree))
-	protoNameBehaviorTreeNode = proto.MessageName(new(btpb.BehaviorTree_Node))
-)
-
-func clearField(fieldName string, refl protoreflect.Message) {
-	field := refl.Descriptor().Fields().ByTextName(fieldName)
-	if refl.Has(field) {
-		refl.Clear(field)
-	}
-}
-
-func clearTree(m proto.Message, clearTreeID bool, clearNodeIDs bool) error {
-	refl := m.ProtoReflect()
-
-	n := proto.MessageName(m)
-	if clearTreeID && n == protoNameBehaviorTree {
-		clearField("tree_id", refl)
-	}
-	if clearNodeIDs && n == protoNameBehaviorTreeNode {
-		clearField("id", refl)
-	}
-
-	for i := 0; i < refl.Descriptor().Fields().Len(); i++ {
-		field := refl.Descriptor().Fields().Get(i)
-		if !refl.Has(field) {
-			continue
-		}
-		options := field.Options().(*descriptorpb.FieldOptions)
-		outputOnly := proto.GetExtension(options, apb.E_OutputOnly).(bool)
-
-		if outputOnly {
-			refl.Clear(field)
-		}
+var protoNameBehaviorTreeNode = proto.MessageName(new(btpb.BehaviorTree_Node))
 
-		if field.Kind() == protoreflect.MessageKind {
-			if field.IsList() {
-				list := refl.Get(field).List()
-				for j := 0; j < list.Len(); j++ {
-					if err := clearTree(list.Get(j).Message().Interface(), clearTreeID, clearNodeIDs); err != nil {
-						return err
-					}
-				}
-			} else if !field.IsMap() {
-				if err := clearTree(refl.Get(field).Message().Interface(), clearTreeID, clearNodeIDs); err != nil {
-					return err
-				}
-			}
-		}
-	}
-	return nil
-}
-
-func connectToCluster(ctx context.Context, projectName string, orgName string, address string, solutionName string, clusterName string) (context.Context, *grpc.ClientConn, error) {
-	if solutionName != "" {
-		// Look up solution name via cloud portal.
-		ctx, conn, err := dialerutil.DialConnectionCtx(ctx, dialerutil.DialInfoParams{
-			CredName: projectName,
-			CredOrg:  orgName,
-		})
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to create client connection: %w", err)
-		}
-
-		clusterName, err = solutionutil.GetClusterNameFromSolution(ctx, conn, solutionName)
-		if err != nil {
-			return nil, nil, errors.Wrapf(err, "could not resolve solution to cluster")
-		}
-	}
-
-	// Establish a gRPC connection to server, cluster, or cloud.
-	ctx, conn, err := dialerutil.DialConnectionCtx(ctx, dialerutil.DialInfoParams{
-		Address:  address,
-		Cluster:  clusterName,
-		CredName: projectName,
-		CredOrg:  orgName,
-	})
+// connectToCluster dials the cluster selected by the --address, --cluster and --solution flags.
+func connectToCluster(ctx context.Context) (context.Context, *grpc.ClientConn, error) {
+	ctx, conn, _, err := clientutils.DialClusterFromInctl(ctx, cmdFlags)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create client connection: %w", err)
 	}
-
 	return ctx, conn, nil
 }
 
-func getBT(ctx context.Context, conn *grpc.ClientConn) (*btpb.BehaviorTree, error) {
-	client := execgrpcpb.NewExecutiveServiceClient(conn)
-	listOpResp, err := client.ListOperations(ctx, &lrpb.ListOperationsRequest{})
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to list executive operations")
-	}
-
-	if len(listOpResp.Operations) == 0 {
-		return nil, fmt.Errorf("no operations found. Did you load a behavior tree into the executive?")
+// applyDeprecatedServerFlag uses the value of the deprecated --server flag as --address.
+func applyDeprecatedServerFlag(cmd *cobra.Command) error {
+	// Forget the value of a previous run in the same process, e.g., in 'inctl shell'. A nil override
+	// is ignored by viper.
+	viperLocal.Set(cmdutils.KeyAddress, nil)
+	if !cmd.Flags().Changed(keyServer) {
+		return nil
 	}
-
-	if len(listOpResp.Operations) > 1 {
-		fmt.Fprintf(os.Stderr, "Found %d concurrent operations, getting first one", len(listOpResp.Operations))
-	}
-	operation := listOpResp.Operations[0]
-
-	metadata := new(rmdpb.RunMetadata)
-	if err := operation.GetMetadata().UnmarshalTo(metadata); err != nil {
-		return nil, errors.Wrap(err, "unable to unmarshal RunMetadata proto")
+	if cmd.Flags().Changed(cmdutils.KeyAddress) {
+		return fmt.Errorf("--%s and --%s cannot be used together", keyServer, cmdutils.KeyAddress)
 	}
-
-	return metadata.GetBehaviorTree(), nil
+	viperLocal.Set(cmdutils.KeyAddress, flagServerAddress)
+	return nil
 }
 
-func setBT(ctx context.Context, conn *grpc.ClientConn, bt *btpb.BehaviorTree) error {
-	client := execgrpcpb.NewExecutiveServiceClient(conn)
-
-	listOpResp, err := client.ListOperations(ctx, &lrpb.ListOperationsRequest{})
-	if err != nil {
-		return errors.Wrap(err, "unable to list executive operations")
-	}
-
-	if len(listOpResp.Operations) > 1 {
-		return errors.Errorf("More than one concurrently loaded BT/executive operation, please delete all but one")
-	}
-
-	if len(listOpResp.Operations) == 1 {
-		operationToDelete := listOpResp.Operations[0]
-		if _, err = client.DeleteOperation(ctx, &lrpb.DeleteOperationRequest{
-			Name: operationToDelete.Name,
-		}); err != nil {
-			return errors.Wrap(err, "unable to delete operation")
-		}
-	}
-
-	req := &execgrpcpb.CreateOperationRequest{}
-	req.RunnableType = &execgrpcpb.CreateOperationRequest_BehaviorTree{BehaviorTree: bt}
-
-	if _, err = client.CreateOperation(ctx, req); err != nil {
-		return errors.Wrap(err, "unable to create executive operation")
-	}
-
-	return nil
+func getBT(ctx context.Context, conn *grpc.ClientConn) (*btpb.BehaviorTree, error) {
+	return processclient.New(conn).Get(ctx, processclient.GetOptions{})
 }
 
-func getSkills(ctx context.Context, conn *grpc.ClientConn) ([]*skillspb.Skill, error) {
-	client := skillregistrygrpcpb.NewSkillRegistryClient(conn)
-	var (
-		skills        []*skillspb.Skill
-		nextPageToken string
-	)
-	for {
-		resp, err := client.ListSkills(ctx, &srpb.ListSkillsRequest{
-			PageToken: nextPageToken,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("could not list skills: %w", err)
-		}
-		skills = append(skills, resp.GetSkills()...)
-		nextPageToken = resp.GetNextPageToken()
-		if nextPageToken == "" {
-			break
-		}
-	}
-	return skills, nil
+func setBT(ctx context.Context, conn *grpc.ClientConn, bt *btpb.BehaviorTree) error {
+	return processclient.New(conn).Set(ctx, bt, processclient.SetOptions{})
 }
 
 var processCmd = orgutil.WrapCmd(&cobra.Command{
@@ -234,18 +104,48 @@ var processCmd = orgutil.WrapCmd(&cobra.Command{
 	Examples:
 
 	To download the current BT from the executive to a file:
-	inctl process get --solution my-solution-id --cluster my-cluster --output_file /tmp/process.textproto
+	inctl process get --solution my-solution-id --output_file /tmp/process.textproto
 
 	To upload a BT from file to the executive:
-	inctl process set --solution my-solution --cluster my-cluster --input_file /tmp/my-process.textproto
+	inctl process set --solution my-solution --input_file /tmp/my-process.textproto
+
+	To capture the state of the executive for a bug report:
+	inctl process dump-state --solution my-solution --output_file /tmp/executive-state.tar.gz
 
 `,
 	DisableFlagParsing: true,
 }, viperLocal)
 
-func init() {
+// loadProcessCmd adds the flags shared by all process commands to processCmd and returns it.
+func loadProcessCmd() *cobra.Command {
 	processCmd.PersistentFlags().BoolVar(&flagClearTreeID, "clear_tree_id", true, "Clear the tree_id field from the BT proto.")
 	processCmd.PersistentFlags().BoolVar(&flagClearNodeIDs, "clear_node_ids", true, "Clear the nodes' id fields from the BT proto.")
-	processCmd.PersistentFlags().StringVar(&flagServerAddress, "server", "", "Server address of the cluster. Format is {ADDRESS}:{PORT}, for example 'localhost:17080'")
-	root.RootCmd.AddCommand(processCmd)
+	processCmd.PersistentFlags().BoolVar(&flagAllSkills, "all_skills", false, "Fetch the parameter descriptors of all installed skills instead of only those of the skills called in the process.")
+	processCmd.PersistentFlags().StringVar(&flagProtoConflicts, "proto_conflicts", protoConflictWarn, fmt.Sprintf("What to do if installed skills define the same proto file differently, one of %v. The definition of the first skill is used unless the policy is %q.", protoConflictPolicies, protoConflictError))
+	processCmd.PersistentFlags().BoolVar(&flagReflectionFallback, "reflection_fallback", true, "Resolve types which the installed skills do not provide descriptors for, e.g., proprietary skills, using gRPC server reflection on the cluster.")
+	processCmd.PersistentFlags().StringVar(&flagServerAddress, keyServer, "", "Server address of the cluster. Format is {ADDRESS}:{PORT}, for example 'localhost:17080'")
+	processCmd.PersistentFlags().MarkDeprecated(keyServer, fmt.Sprintf("use --%s instead", cmdutils.KeyAddress))
+
+	cmdFlags.SetCommand(processCmd)
+	cmdFlags.AddFlagsAddressClusterSolution()
+	// SetCommand only validates the flags before processCmd itself runs, but they are used by the
+	// subcommands.
+	preRunE := processCmd.PersistentPreRunE
+	processCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := preRunE(cmd, args); err != nil {
+			return err
+		}
+		if cmd.DisableFlagParsing {
+			return nil
+		}
+		if err := applyDeprecatedServerFlag(cmd); err != nil {
+			return err
+		}
+		return cmdFlags.ValidateFlags()
+	}
+	return processCmd
+}
+
+func init() {
+	root.AddLazyCommand(processCmd.Use, processCmd.Short, loadProcessCmd, processCmd.Aliases...)
 }
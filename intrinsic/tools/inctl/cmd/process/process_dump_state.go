@@ -0,0 +1,287 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	lrpb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	bbgrpcpb "intrinsic/executive/proto/blackboard_service_go_grpc_proto"
+	execgrpcpb "intrinsic/executive/proto/executive_service_go_grpc_proto"
+	rmdpb "intrinsic/executive/proto/run_metadata_go_proto"
+	"intrinsic/frontend/frontendclient"
+	"intrinsic/tools/inctl/auth"
+	"intrinsic/tools/inctl/util/orgutil"
+	"intrinsic/util/archive/tartooling"
+)
+
+const executiveResourceName = "executive"
+
+var (
+	flagRedactBlackboard bool
+	flagLogTailLines     int
+)
+
+// dumpState collects the state of the executive in memory. Every entry maps a
+// path inside of the archive to its content.
+type dumpState struct {
+	names    []string
+	contents map[string][]byte
+}
+
+func (d *dumpState) add(name string, content []byte) {
+	if d.contents == nil {
+		d.contents = make(map[string][]byte)
+	}
+	if _, ok := d.contents[name]; !ok {
+		d.names = append(d.names, name)
+	}
+	d.contents[name] = content
+}
+
+// writeArchive writes all collected entries as a gzipped tar archive to w.
+func (d *dumpState) writeArchive(w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, name := range d.names {
+		if err := tartooling.AddBytes(d.contents[name], tw, name); err != nil {
+			return errors.Wrapf(err, "could not add %s to archive", name)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "could not finalize tar archive")
+	}
+	return gw.Close()
+}
+
+// formatNodeStates returns a human-readable overview of the state of every node
+// in the given tree, indented by nesting level.
+func formatNodeStates(bt *btpb.BehaviorTree) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "tree %q (id: %s) state: %s\n", bt.GetName(), bt.GetTreeId(), bt.GetState())
+	writeNodeStates(&sb, bt.ProtoReflect(), 1)
+	return sb.String()
+}
+
+func writeNodeStates(sb *strings.Builder, refl protoreflect.Message, depth int) {
+	if proto.MessageName(refl.Interface()) == protoNameBehaviorTreeNode {
+		node := refl.Interface().(*btpb.BehaviorTree_Node)
+		fmt.Fprintf(sb, "%s- node %d", strings.Repeat("  ", depth), node.GetId())
+		if node.GetName() != "" {
+			fmt.Fprintf(sb, " %q", node.GetName())
+		}
+		fmt.Fprintf(sb, " state: %s", node.GetState())
+		if node.FailureReason != nil {
+			fmt.Fprintf(sb, " failure_reason: %s", node.GetFailureReason())
+		}
+		sb.WriteString("\n")
+		depth++
+	}
+
+	refl.Range(func(field protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if field.Kind() != protoreflect.MessageKind || field.IsMap() {
+			return true
+		}
+		if field.IsList() {
+			list := v.List()
+			for j := 0; j < list.Len(); j++ {
+				writeNodeStates(sb, list.Get(j).Message(), depth)
+			}
+			return true
+		}
+		writeNodeStates(sb, v.Message(), depth)
+		return true
+	})
+}
+
+// operationDir returns the directory in the archive for the operation with the
+// given name.
+func operationDir(name string) string {
+	return path.Join("operations", strings.ReplaceAll(name, "/", "_"))
+}
+
+func collectOperations(ctx context.Context, conn *grpc.ClientConn, resolver *protoregistry.Types, redactBlackboard bool, state *dumpState) error {
+	client := execgrpcpb.NewExecutiveServiceClient(conn)
+	listOpResp, err := client.ListOperations(ctx, &lrpb.ListOperationsRequest{})
+	if err != nil {
+		return errors.Wrap(err, "unable to list executive operations")
+	}
+	if len(listOpResp.GetOperations()) == 0 {
+		fmt.Fprintln(os.Stderr, "Warning: the executive has no operations, no behavior tree state is included.")
+	}
+
+	marshaller := prototext.MarshalOptions{
+		Indent:    "  ",
+		Multiline: true,
+	}
+	if resolver != nil {
+		marshaller.Resolver = resolver
+	}
+	bbClient := bbgrpcpb.NewExecutiveBlackboardClient(conn)
+	view := bbgrpcpb.ListBlackboardValuesRequest_FULL
+	if redactBlackboard {
+		view = bbgrpcpb.ListBlackboardValuesRequest_ANY_TYPEURL_ONLY
+	}
+
+	for _, op := range listOpResp.GetOperations() {
+		dir := operationDir(op.GetName())
+
+		metadata := new(rmdpb.RunMetadata)
+		if err := op.GetMetadata().UnmarshalTo(metadata); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal RunMetadata of operation %s", op.GetName())
+		}
+		// The metadata is stored separately to expand skill parameters.
+		opWithoutMetadata := proto.Clone(op).(*lrpb.Operation)
+		opWithoutMetadata.Metadata = nil
+		state.add(path.Join(dir, "operation.textproto"), []byte(marshaller.Format(opWithoutMetadata)))
+		state.add(path.Join(dir, "run_metadata.textproto"), []byte(marshaller.Format(metadata)))
+		state.add(path.Join(dir, "node_states.txt"), []byte(formatNodeStates(metadata.GetBehaviorTree())))
+
+		bbResp, err := bbClient.ListBlackboardValues(ctx, &bbgrpcpb.ListBlackboardValuesRequest{
+			OperationName: op.GetName(),
+			View:          view,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "unable to list blackboard values of operation %s", op.GetName())
+		}
+		state.add(path.Join(dir, "blackboard.textproto"), []byte(marshaller.Format(bbResp)))
+	}
+	return nil
+}
+
+// fetchExecutiveLogs returns the most recent log lines of the executive from
+// the frontend of the given cluster.
+func fetchExecutiveLogs(ctx context.Context, projectName string, clusterName string, tailLines int) ([]byte, error) {
+	opts := frontendclient.Options{}
+	frontendURL := frontendclient.LocalURL(frontendclient.LocalAddress)
+	if projectName != "" {
+		if clusterName == "" {
+			return nil, fmt.Errorf("cluster is unknown, use --solution or --cluster")
+		}
+		frontendURL = frontendclient.RelayURL(projectName, clusterName)
+		config, err := auth.NewStore().GetConfiguration(projectName)
+		if err != nil {
+			return nil, err
+		}
+		authToken, clientCert, err := config.Credentials(auth.AliasDefaultToken)
+		if err != nil {
+			return nil, err
+		}
+		if authToken != nil {
+			opts.Auth = authToken
+		}
+		if opts.HTTPClient, err = clientCert.HTTPClient(); err != nil {
+			return nil, err
+		}
+	}
+
+	logs, err := frontendclient.New(frontendURL, opts).ConsoleLogs(ctx, url.Values{
+		"resourceName": []string{executiveResourceName},
+		"tailLines":    []string{fmt.Sprintf("%d", tailLines)},
+		"timestamps":   []string{"true"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer logs.Close()
+	return io.ReadAll(logs)
+}
+
+var processDumpStateCmd = &cobra.Command{
+	Use:   "dump-state",
+	Short: "Capture the state of the executive into an archive for bug reports.",
+	Long: `Capture the state of the executive of a currently deployed solution into a
+gzipped tar archive which can be attached to bug reports.
+
+The archive contains, for every executive operation, the active behavior tree
+including the current state of all nodes, an overview of the node states and
+the blackboard values. Recent log lines of the executive are added as well if
+they can be retrieved. Use --redact_blackboard to only include the types of
+blackboard values instead of their contents.
+
+Example:
+inctl process dump-state --solution my-solution-id [--output_file /tmp/executive-state.tar.gz] [--redact_blackboard]
+
+	`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectName := viperLocal.GetString(orgutil.KeyProject)
+		orgName := viperLocal.GetString(orgutil.KeyOrganization)
+		clusterName, err := clientutils.ResolveClusterFromInctl(cmd.Context(), cmdFlags)
+		if err != nil {
+			return err
+		}
+		ctx, conn, err := connectToCluster(cmd.Context())
+		if err != nil {
+			return errors.Wrapf(err, "could not dial connection")
+		}
+		defer conn.Close()
+
+		// Skill parameters can only be expanded if the skills are known. The
+		// state is still useful without them.
+		var resolver *protoregistry.Types
+		if s, err := newTextSerializer(ctx, conn); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skill parameters are not expanded: %v\n", err)
+		} else {
+			resolver = s.pt
+		}
+
+		state := &dumpState{}
+		state.add("info.txt", []byte(fmt.Sprintf("project: %s\norganization: %s\nsolution: %s\ncluster: %s\ncaptured: %s\nblackboard redacted: %t\n",
+			projectName, orgName, cmdFlags.GetString(cmdutils.KeySolution), clusterName, time.Now().UTC().Format(time.RFC3339), flagRedactBlackboard)))
+		if err := collectOperations(ctx, conn, resolver, flagRedactBlackboard, state); err != nil {
+			return errors.Wrapf(err, "could not capture executive state")
+		}
+
+		if flagLogTailLines > 0 {
+			logs, err := fetchExecutiveLogs(cmd.Context(), projectName, clusterName, flagLogTailLines)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: executive logs are not included: %v\n", err)
+			} else {
+				state.add("executive.log", logs)
+			}
+		}
+
+		outputFile := flagOutputFile
+		if outputFile == "" {
+			outputFile = fmt.Sprintf("executive-state-%s.tar.gz", time.Now().Format("20060102-150405"))
+		}
+		var buf bytes.Buffer
+		if err := state.writeArchive(&buf); err != nil {
+			return err
+		}
+		if err := os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+			return errors.Wrapf(err, "could not write to file %s", outputFile)
+		}
+		fmt.Printf("Wrote executive state to %s\n", outputFile)
+
+		return nil
+	},
+}
+
+func init() {
+	processDumpStateCmd.Flags().StringVar(&flagOutputFile, "output_file", "", "Archive to write. Defaults to executive-state-<timestamp>.tar.gz in the current directory.")
+	processDumpStateCmd.Flags().BoolVar(&flagRedactBlackboard, "redact_blackboard", false, "Only include the types of blackboard values, not their contents.")
+	processDumpStateCmd.Flags().IntVar(&flagLogTailLines, "log_tail_lines", 500, "Number of recent executive log lines to include. Set to 0 to skip the logs.")
+	processCmd.AddCommand(processDumpStateCmd)
+}
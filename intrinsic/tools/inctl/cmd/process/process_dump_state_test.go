@@ -0,0 +1,95 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+)
+
+func TestWriteArchive(t *testing.T) {
+	state := &dumpState{}
+	state.add("info.txt", []byte("first"))
+	state.add("operations/op_1/node_states.txt", []byte("states"))
+	// Adding a name again replaces the content but keeps the position.
+	state.add("info.txt", []byte("second"))
+
+	var buf bytes.Buffer
+	if err := state.writeArchive(&buf); err != nil {
+		t.Fatalf("writeArchive() failed: %v", err)
+	}
+
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() failed: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	type entry struct{ Name, Content string }
+	var got []entry
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar.Reader.Next() failed: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("io.ReadAll(%s) failed: %v", hdr.Name, err)
+		}
+		got = append(got, entry{hdr.Name, string(content)})
+	}
+	want := []entry{
+		{"info.txt", "second"},
+		{"operations/op_1/node_states.txt", "states"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("writeArchive() wrote unexpected entries (-want +got):\n%s", diff)
+	}
+}
+
+func TestFormatNodeStates(t *testing.T) {
+	bt := &btpb.BehaviorTree{
+		Name:   "tree",
+		TreeId: proto.String("tree-id"),
+		State:  btpb.BehaviorTree_FAILED.Enum(),
+		Root: &btpb.BehaviorTree_Node{
+			Id:    proto.Uint32(1),
+			State: btpb.BehaviorTree_Node_FAILED.Enum(),
+			NodeType: &btpb.BehaviorTree_Node_Sequence{Sequence: &btpb.BehaviorTree_SequenceNode{
+				Children: []*btpb.BehaviorTree_Node{
+					{
+						Id:    proto.Uint32(2),
+						Name:  proto.String("move"),
+						State: btpb.BehaviorTree_Node_SUCCEEDED.Enum(),
+					},
+					{
+						Id:            proto.Uint32(3),
+						Name:          proto.String("grasp"),
+						State:         btpb.BehaviorTree_Node_FAILED.Enum(),
+						FailureReason: btpb.BehaviorTree_Node_FAILED_EXECUTION.Enum(),
+					},
+				},
+			}},
+		},
+	}
+
+	got := formatNodeStates(bt)
+
+	want := `tree "tree" (id: tree-id) state: FAILED
+  - node 1 state: FAILED
+    - node 2 "move" state: SUCCEEDED
+    - node 3 "grasp" state: FAILED failure_reason: FAILED_EXECUTION
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("formatNodeStates() returned unexpected output (-want +got):\n%s", diff)
+	}
+}
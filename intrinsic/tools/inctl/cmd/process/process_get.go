@@ -13,12 +13,9 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/reflect/protodesc"
-	"google.golang.org/protobuf/reflect/protoregistry"
+	"intrinsic/executive/processclient"
 	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
 	"intrinsic/solutions/tools/pythonserializer"
-	"intrinsic/tools/inctl/util/orgutil"
-	"intrinsic/util/proto/registryutil"
 )
 
 var allowedGetFormats = []string{TextProtoFormat, BinaryProtoFormat, PythonScriptFormat, PythonMinimalFormat, PythonNotebookFormat}
@@ -105,7 +102,7 @@ type serializer interface {
 }
 
 type textSerializer struct {
-	pt *protoregistry.Types
+	pt typeResolver
 }
 
 // Serialize serializes the given behavior tree to textproto.
@@ -119,26 +116,10 @@ func (t *textSerializer) Serialize(bt *btpb.BehaviorTree) ([]byte, error) {
 	return []byte(s), nil
 }
 
-func newTextSerializer(ctx context.Context, conn *grpc.ClientConn) (*textSerializer, error) {
-	skills, err := getSkills(ctx, conn)
+func newTextSerializer(ctx context.Context, conn *grpc.ClientConn, bt *btpb.BehaviorTree) (*textSerializer, error) {
+	pt, err := fetchTypeResolver(ctx, conn, skillIDsInTree(bt), flagAllSkills)
 	if err != nil {
-		return nil, errors.Wrapf(err, "could not list skills")
-	}
-
-	r := new(protoregistry.Files)
-	for _, skill := range skills {
-		for _, parameterDescriptorFile := range skill.GetParameterDescription().GetParameterDescriptorFileset().GetFile() {
-			fd, err := protodesc.NewFile(parameterDescriptorFile, r)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed to add file to registry")
-			}
-			r.RegisterFile(fd)
-		}
-	}
-
-	pt := new(protoregistry.Types)
-	if err := registryutil.PopulateTypesFromFiles(pt, r); err != nil {
-		return nil, errors.Wrapf(err, "failed to populate types from files")
+		return nil, err
 	}
 	return &textSerializer{pt: pt}, nil
 }
@@ -165,14 +146,14 @@ func serializeBT(ctx context.Context, conn *grpc.ClientConn, bt *btpb.BehaviorTr
 	var err error
 	switch format {
 	case TextProtoFormat:
-		s, err = newTextSerializer(ctx, conn)
+		s, err = newTextSerializer(ctx, conn, bt)
 		if err != nil {
 			return nil, errors.Wrapf(err, "could not create textproto serializer")
 		}
 	case BinaryProtoFormat:
 		s = newBinarySerializer()
 	case PythonScriptFormat, PythonMinimalFormat, PythonNotebookFormat:
-		sk, err := getSkills(ctx, conn)
+		sk, err := fetchSkills(ctx, conn, skillIDsInTree(bt))
 		if err != nil {
 			return nil, errors.Wrapf(err, "could not list skills")
 		}
@@ -206,13 +187,21 @@ func serializeBT(ctx context.Context, conn *grpc.ClientConn, bt *btpb.BehaviorTr
 	return data, nil
 }
 
-func getProcess(ctx context.Context, conn *grpc.ClientConn, format string, clearTreeID bool, clearNodeIDs bool) ([]byte, error) {
+// getProcess returns the serialized active process. If skillLockfile is set, the installed versions
+// of the skills called in the process are written to it.
+func getProcess(ctx context.Context, conn *grpc.ClientConn, format string, clearTreeID bool, clearNodeIDs bool, skillLockfile string) ([]byte, error) {
 	bt, err := getBT(ctx, conn)
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not get behavior tree")
 	}
 
-	clearTree(bt, clearTreeID, clearNodeIDs)
+	if skillLockfile != "" {
+		if err := lockSkillsInTree(ctx, conn, skillIDsInTree(bt), skillLockfile); err != nil {
+			return nil, err
+		}
+	}
+
+	processclient.ClearTree(bt, clearTreeID, clearNodeIDs)
 
 	return serializeBT(ctx, conn, bt, format)
 }
@@ -223,22 +212,22 @@ var processGetCmd = &cobra.Command{
 	Long: `Get the active process (behavior tree) of a currently deployed solution.
 
 Example:
-inctl process get --solution my-solution-id --cluster my-cluster [--output_file /tmp/process.textproto] [--process_format textproto|binaryproto]
+inctl process get --solution my-solution-id [--output_file /tmp/process.textproto] [--process_format textproto|binaryproto]
+
+Use --skill_lockfile to also record the installed versions of the skills called in the process.
+'process set --skill_lockfile' checks that the same versions are installed before setting the
+process.
 
 	`,
 	Args: cobra.ExactArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		projectName := viperLocal.GetString(orgutil.KeyProject)
-		orgName := viperLocal.GetString(orgutil.KeyOrganization)
-		ctx, conn, err := connectToCluster(cmd.Context(), projectName,
-			orgName, flagServerAddress,
-			flagSolutionName, flagClusterName)
+		ctx, conn, err := connectToCluster(cmd.Context())
 		if err != nil {
 			return errors.Wrapf(err, "could not dial connection")
 		}
 		defer conn.Close()
 
-		content, err := getProcess(ctx, conn, flagProcessFormat, flagClearTreeID, flagClearNodeIDs)
+		content, err := getProcess(ctx, conn, flagProcessFormat, flagClearTreeID, flagClearNodeIDs, flagSkillLockfile)
 		if err != nil {
 			return errors.Wrapf(err, "could not get BT")
 		}
@@ -260,9 +249,8 @@ func init() {
 	processGetCmd.Flags().StringVar(
 		&flagProcessFormat, "process_format", TextProtoFormat,
 		fmt.Sprintf("(optional) output format. One of: (%s)", strings.Join(allowedGetFormats, ", ")))
-	processGetCmd.Flags().StringVar(&flagSolutionName, "solution", "", "Solution to get the process from. For example, use `inctl solutions list --project intrinsic-workcells --output json [--filter running_in_sim]` to see the list of solutions.")
-	processGetCmd.Flags().StringVar(&flagClusterName, "cluster", "", "Cluster to get the process from.")
 	processGetCmd.Flags().StringVar(&flagOutputFile, "output_file", "", "If set, writes the process to the given file instead of stdout.")
+	processGetCmd.Flags().StringVar(&flagSkillLockfile, "skill_lockfile", "", "If set, writes the id_versions of the skills called in the process to the given JSON file.")
 	processCmd.AddCommand(processGetCmd)
 
 }
@@ -0,0 +1,115 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"intrinsic/assets/idutils"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+)
+
+// skillLock records the versions of the skills called in a process, so that the process can later
+// be checked against or deployed with the same skill versions.
+type skillLock struct {
+	// Skills are the sorted id_versions of the skills which were installed when the process was
+	// exported.
+	Skills []string `json:"skills"`
+}
+
+// newSkillLock returns the lock of the given installed skills.
+func newSkillLock(skills []*skillspb.Skill) *skillLock {
+	l := &skillLock{Skills: []string{}}
+	for _, s := range skills {
+		l.Skills = append(l.Skills, s.GetIdVersion())
+	}
+	sort.Strings(l.Skills)
+	return l
+}
+
+func writeSkillLock(path string, l *skillLock) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "could not write skill lockfile")
+	}
+	return nil
+}
+
+func readSkillLock(path string) (*skillLock, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read skill lockfile")
+	}
+	l := &skillLock{}
+	if err := json.Unmarshal(b, l); err != nil {
+		return nil, errors.Wrapf(err, "could not parse skill lockfile %s", path)
+	}
+	return l, nil
+}
+
+// lockSkillsInTree writes the lock of the installed skills with the given ids to path.
+func lockSkillsInTree(ctx context.Context, conn *grpc.ClientConn, ids []string, path string) error {
+	skills, err := getSkillsByID(ctx, conn, ids)
+	if err != nil {
+		return err
+	}
+	return writeSkillLock(path, newSkillLock(skills))
+}
+
+// checkSkillLock returns an error listing all skills of l which are not installed in the locked
+// version.
+func checkSkillLock(l *skillLock, installed []*skillspb.Skill) error {
+	versions := map[string]string{}
+	for _, s := range installed {
+		versions[s.GetId()] = s.GetIdVersion()
+	}
+	var mismatches []string
+	for _, idVersion := range l.Skills {
+		parts, err := idutils.NewIDVersionParts(idVersion)
+		if err != nil {
+			return errors.Wrapf(err, "invalid skill in lockfile")
+		}
+		switch got, ok := versions[parts.ID()]; {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("%s: not installed", idVersion))
+		case got != idVersion:
+			mismatches = append(mismatches, fmt.Sprintf("%s: installed version is %s", idVersion, got))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("installed skills do not match the skill lockfile:\n  %s", strings.Join(mismatches, "\n  "))
+	}
+	return nil
+}
+
+// verifySkillLock checks that the skills in the lockfile at path are installed in their locked
+// versions.
+func verifySkillLock(ctx context.Context, conn *grpc.ClientConn, path string) error {
+	l, err := readSkillLock(path)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, idVersion := range l.Skills {
+		parts, err := idutils.NewIDVersionParts(idVersion)
+		if err != nil {
+			return errors.Wrapf(err, "invalid skill in lockfile")
+		}
+		ids = append(ids, parts.ID())
+	}
+	installed, err := getSkillsByID(ctx, conn, ids)
+	if err != nil {
+		return err
+	}
+	return checkSkillLock(l, installed)
+}
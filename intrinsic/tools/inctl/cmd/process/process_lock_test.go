@@ -0,0 +1,43 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+)
+
+func TestSkillLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skills.lock.json")
+	exported := []*skillspb.Skill{
+		{Id: "ai.intrinsic.move", IdVersion: "ai.intrinsic.move.1.2.0"},
+		{Id: "ai.intrinsic.grasp", IdVersion: "ai.intrinsic.grasp.0.1.0"},
+	}
+	if err := writeSkillLock(path, newSkillLock(exported)); err != nil {
+		t.Fatalf("writeSkillLock() failed: %v", err)
+	}
+	l, err := readSkillLock(path)
+	if err != nil {
+		t.Fatalf("readSkillLock() failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"ai.intrinsic.grasp.0.1.0", "ai.intrinsic.move.1.2.0"}, l.Skills); diff != "" {
+		t.Errorf("readSkillLock() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if err := checkSkillLock(l, exported); err != nil {
+		t.Errorf("checkSkillLock() with the exported skills failed: %v", err)
+	}
+	err = checkSkillLock(l, []*skillspb.Skill{{Id: "ai.intrinsic.move", IdVersion: "ai.intrinsic.move.1.3.0"}})
+	if err == nil {
+		t.Fatalf("checkSkillLock() with other skills succeeded, want error")
+	}
+	for _, want := range []string{"ai.intrinsic.grasp.0.1.0: not installed", "ai.intrinsic.move.1.2.0: installed version is ai.intrinsic.move.1.3.0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("checkSkillLock() error = %q, want it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	rv1alphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"intrinsic/util/proto/registryutil"
+)
+
+// typeResolver resolves the types of messages and extensions, e.g., of the values of Any fields.
+type typeResolver interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+}
+
+// reflectionResolver resolves the message types which are not part of the parameter descriptors
+// of the skills, e.g., of proprietary skills which do not publish them, using gRPC server
+// reflection on the cluster.
+type reflectionResolver struct {
+	ctx   context.Context
+	conn  grpc.ClientConnInterface
+	types *protoregistry.Types
+	// files are the files received from the server.
+	files *protoregistry.Files
+	// unavailable is set once the server turned out not to support reflection.
+	unavailable bool
+	// unknown are the symbols which could not be resolved through reflection.
+	unknown map[protoreflect.FullName]bool
+}
+
+func newReflectionResolver(ctx context.Context, conn grpc.ClientConnInterface, types *protoregistry.Types) *reflectionResolver {
+	return &reflectionResolver{
+		ctx:     ctx,
+		conn:    conn,
+		types:   types,
+		files:   new(protoregistry.Files),
+		unknown: map[protoreflect.FullName]bool{},
+	}
+}
+
+// FindMessageByName looks up a message by its full name, querying the server if it is unknown.
+func (r *reflectionResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	mt, err := r.types.FindMessageByName(name)
+	if err != protoregistry.NotFound || !r.fetch(name) {
+		return mt, err
+	}
+	return r.types.FindMessageByName(name)
+}
+
+// FindMessageByURL looks up a message by the type URL of an Any, querying the server if it is
+// unknown.
+func (r *reflectionResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	name := url
+	if i := strings.LastIndexByte(url, '/'); i >= 0 {
+		name = url[i+1:]
+	}
+	return r.FindMessageByName(protoreflect.FullName(name))
+}
+
+// FindExtensionByName looks up an extension by its full name, querying the server if it is
+// unknown.
+func (r *reflectionResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	xt, err := r.types.FindExtensionByName(field)
+	if err != protoregistry.NotFound || !r.fetch(field) {
+		return xt, err
+	}
+	return r.types.FindExtensionByName(field)
+}
+
+// FindExtensionByNumber looks up an extension among the known types only.
+func (r *reflectionResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return r.types.FindExtensionByNumber(message, field)
+}
+
+// fetch queries the server for the file defining symbol and adds its types. Returns whether any
+// files were added. Failures are reported as warnings, the symbol then stays unresolved.
+func (r *reflectionResolver) fetch(symbol protoreflect.FullName) bool {
+	if r.unavailable || r.unknown[symbol] {
+		return false
+	}
+	r.unknown[symbol] = true
+
+	files, err := fileContainingSymbol(r.ctx, r.conn, string(symbol))
+	if status.Code(err) == codes.Unimplemented {
+		r.unavailable = true
+		fmt.Fprintf(os.Stderr, "Warning: cannot resolve %q, the server does not support reflection\n", symbol)
+		return false
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot resolve %q using server reflection: %v\n", symbol, err)
+		return false
+	}
+	if err := r.register(files); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot resolve %q using server reflection: %v\n", symbol, err)
+		return false
+	}
+	delete(r.unknown, symbol)
+	return true
+}
+
+// register adds the given serialized files and their types. The files must include all
+// dependencies which are not known yet.
+func (r *reflectionResolver) register(serialized [][]byte) error {
+	pending := map[string]*descriptorpb.FileDescriptorProto{}
+	var names []string
+	for _, b := range serialized {
+		fdp := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(b, fdp); err != nil {
+			return fmt.Errorf("invalid file descriptor: %w", err)
+		}
+		pending[fdp.GetName()] = fdp
+		names = append(names, fdp.GetName())
+	}
+	for _, name := range names {
+		if err := r.registerFile(name, pending); err != nil {
+			return err
+		}
+	}
+	return registryutil.PopulateTypesFromFiles(r.types, r.files)
+}
+
+// registerFile registers the file with the given path after its dependencies.
+func (r *reflectionResolver) registerFile(path string, pending map[string]*descriptorpb.FileDescriptorProto) error {
+	if _, err := r.files.FindFileByPath(path); err == nil {
+		return nil
+	}
+	fdp, ok := pending[path]
+	if !ok {
+		// Not sent by the server, the dependency has to be linked into inctl.
+		return nil
+	}
+	delete(pending, path)
+	for _, dep := range fdp.GetDependency() {
+		if err := r.registerFile(dep, pending); err != nil {
+			return err
+		}
+	}
+	fd, err := protodesc.NewFile(fdp, fileResolver{r.files})
+	if err != nil {
+		return fmt.Errorf("invalid file descriptor %q: %w", path, err)
+	}
+	return r.files.RegisterFile(fd)
+}
+
+// fileResolver resolves the dependencies of received files, falling back to the files linked into
+// inctl.
+type fileResolver struct {
+	files *protoregistry.Files
+}
+
+func (f fileResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := f.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (f fileResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := f.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+// fileContainingSymbol returns the serialized file defining symbol and its dependencies. Servers
+// which only implement the v1alpha reflection service are supported as well.
+func fileContainingSymbol(ctx context.Context, conn grpc.ClientConnInterface, symbol string) ([][]byte, error) {
+	files, err := fileContainingSymbolV1(ctx, conn, symbol)
+	if status.Code(err) != codes.Unimplemented {
+		return files, err
+	}
+	return fileContainingSymbolV1Alpha(ctx, conn, symbol)
+}
+
+func fileContainingSymbolV1(ctx context.Context, conn grpc.ClientConnInterface, symbol string) ([][]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	}); err != nil {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+	}
+	return resp.GetFileDescriptorResponse().GetFileDescriptorProto(), nil
+}
+
+func fileContainingSymbolV1Alpha(ctx context.Context, conn grpc.ClientConnInterface, symbol string) ([][]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := rv1alphapb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&rv1alphapb.ServerReflectionRequest{
+		MessageRequest: &rv1alphapb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	}); err != nil {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+	}
+	return resp.GetFileDescriptorResponse().GetFileDescriptorProto(), nil
+}
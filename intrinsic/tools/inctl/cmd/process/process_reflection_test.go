@@ -0,0 +1,77 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/local"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"intrinsic/testing/grpctest"
+)
+
+func mustStartReflectionServer(t *testing.T, withReflection bool) *grpc.ClientConn {
+	t.Helper()
+	server := grpc.NewServer()
+	if withReflection {
+		reflection.Register(server)
+	}
+	address := grpctest.StartServerT(t, server)
+	connection, err := grpc.Dial(address, grpc.WithTransportCredentials(local.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial server: %v", err)
+	}
+	t.Cleanup(func() { connection.Close() })
+	return connection
+}
+
+func TestReflectionResolverFetchesMissingTypes(t *testing.T) {
+	conn := mustStartReflectionServer(t, true)
+	r := newReflectionResolver(context.Background(), conn, new(protoregistry.Types))
+
+	const name = "google.protobuf.FieldDescriptorProto"
+	mt, err := r.FindMessageByURL("type.googleapis.com/" + name)
+	if err != nil {
+		t.Fatalf("FindMessageByURL(%q) failed: %v", name, err)
+	}
+	if got := mt.Descriptor().FullName(); got != name {
+		t.Errorf("FindMessageByURL(%q) = %q, want %q", name, got, name)
+	}
+	// Types of the dependencies and nested enums are registered as well.
+	if _, err := r.types.FindEnumByName("google.protobuf.FieldDescriptorProto.Type"); err != nil {
+		t.Errorf("FindEnumByName() failed after fetching %q: %v", name, err)
+	}
+}
+
+func TestReflectionResolverUnknownSymbol(t *testing.T) {
+	conn := mustStartReflectionServer(t, true)
+	r := newReflectionResolver(context.Background(), conn, new(protoregistry.Types))
+
+	const name = protoreflect.FullName("ai.intrinsic.DoesNotExist")
+	if _, err := r.FindMessageByName(name); err != protoregistry.NotFound {
+		t.Errorf("FindMessageByName(%q) returned error %v, want %v", name, err, protoregistry.NotFound)
+	}
+	if !r.unknown[name] {
+		t.Errorf("FindMessageByName(%q) did not remember the unknown symbol", name)
+	}
+	if r.unavailable {
+		t.Errorf("FindMessageByName(%q) marked reflection as unavailable", name)
+	}
+}
+
+func TestReflectionResolverWithoutReflection(t *testing.T) {
+	conn := mustStartReflectionServer(t, false)
+	r := newReflectionResolver(context.Background(), conn, new(protoregistry.Types))
+
+	const name = protoreflect.FullName("google.protobuf.FieldDescriptorProto")
+	if _, err := r.FindMessageByName(name); err != protoregistry.NotFound {
+		t.Errorf("FindMessageByName(%q) returned error %v, want %v", name, err, protoregistry.NotFound)
+	}
+	if !r.unavailable {
+		t.Errorf("FindMessageByName(%q) did not mark reflection as unavailable", name)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -13,11 +14,8 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/reflect/protodesc"
-	"google.golang.org/protobuf/reflect/protoregistry"
+	"intrinsic/executive/processclient"
 	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
-	"intrinsic/tools/inctl/util/orgutil"
-	"intrinsic/util/proto/registryutil"
 )
 
 var allowedSetFormats = []string{TextProtoFormat, BinaryProtoFormat}
@@ -32,27 +30,31 @@ type textDeserializer struct {
 }
 
 func (t *textDeserializer) deserialize(content []byte) (*btpb.BehaviorTree, error) {
-	skills, err := getSkills(t.ctx, t.conn)
+	return parseTextProto(content, func(ids []string, allSkills bool) (typeResolver, error) {
+		return fetchTypeResolver(t.ctx, t.conn, ids, allSkills)
+	})
+}
+
+// parseTextProto parses a textproto behavior tree with the parameter types of the skills called
+// in it, which resolve returns. The ids of these skills are found in the text, which can miss
+// some, so parsing is retried with the types of all installed skills if it fails.
+func parseTextProto(content []byte, resolve func(ids []string, allSkills bool) (typeResolver, error)) (*btpb.BehaviorTree, error) {
+	pt, err := resolve(skillIDsInTextProto(content), flagAllSkills)
 	if err != nil {
-		return nil, errors.Wrapf(err, "could not list skills")
+		return nil, err
 	}
-
-	r := new(protoregistry.Files)
-	for _, skill := range skills {
-		for _, parameterDescriptorFile := range skill.GetParameterDescription().GetParameterDescriptorFileset().GetFile() {
-			fd, err := protodesc.NewFile(parameterDescriptorFile, r)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed to add file to registry")
-			}
-			r.RegisterFile(fd)
-		}
+	bt, err := unmarshalTextProto(content, pt)
+	if err == nil || flagAllSkills {
+		return bt, err
 	}
-
-	pt := new(protoregistry.Types)
-	if err := registryutil.PopulateTypesFromFiles(pt, r); err != nil {
-		return nil, errors.Wrapf(err, "failed to populate types from files")
+	fmt.Fprintf(os.Stderr, "Warning: %v\nRetrying with the parameter types of all installed skills.\n", err)
+	if pt, err = resolve(nil, true); err != nil {
+		return nil, err
 	}
+	return unmarshalTextProto(content, pt)
+}
 
+func unmarshalTextProto(content []byte, pt typeResolver) (*btpb.BehaviorTree, error) {
 	unmarshaller := prototext.UnmarshalOptions{
 		Resolver:       pt,
 		AllowPartial:   true,
@@ -90,6 +92,12 @@ type setProcessParams struct {
 	content      []byte
 	clearTreeID  bool
 	clearNodeIDs bool
+	// skillLockfile, if set, is a lockfile written by 'process get' whose skill versions must be
+	// installed.
+	skillLockfile string
+	// substitutions are the values of the ${KEY} placeholders in a textproto process. Placeholders
+	// are not replaced if empty.
+	substitutions map[string]string
 }
 
 func deserializeBT(ctx context.Context, conn *grpc.ClientConn, format string, content []byte) (*btpb.BehaviorTree, error) {
@@ -111,12 +119,30 @@ func deserializeBT(ctx context.Context, conn *grpc.ClientConn, format string, co
 }
 
 func setProcess(ctx context.Context, conn *grpc.ClientConn, params *setProcessParams) error {
-	bt, err := deserializeBT(ctx, conn, params.format, params.content)
+	// Check the versions first, parameters of other versions may fail to parse.
+	if params.skillLockfile != "" {
+		if err := verifySkillLock(ctx, conn, params.skillLockfile); err != nil {
+			return err
+		}
+	}
+
+	content := params.content
+	if len(params.substitutions) > 0 {
+		if params.format != TextProtoFormat {
+			return fmt.Errorf("--set is only supported for --process_format=%s", TextProtoFormat)
+		}
+		var err error
+		if content, err = substitutePlaceholders(content, params.substitutions); err != nil {
+			return err
+		}
+	}
+
+	bt, err := deserializeBT(ctx, conn, params.format, content)
 	if err != nil {
 		return errors.Wrapf(err, "could not deserialize BT")
 	}
 
-	clearTree(bt, params.clearTreeID, params.clearNodeIDs)
+	processclient.ClearTree(bt, params.clearTreeID, params.clearNodeIDs)
 
 	if err := setBT(ctx, conn, bt); err != nil {
 		return errors.Wrapf(err, "could not set behavior tree")
@@ -125,25 +151,46 @@ func setProcess(ctx context.Context, conn *grpc.ClientConn, params *setProcessPa
 	return nil
 }
 
+// SetProcess sets the active process of the solution on the cluster behind
+// conn.  format is one of the formats accepted by 'inctl process set'.
+func SetProcess(ctx context.Context, conn *grpc.ClientConn, format string, content []byte) error {
+	return setProcess(ctx, conn, &setProcessParams{
+		format:  format,
+		content: content,
+	})
+}
+
+// ParseProcess parses a process in one of the formats accepted by 'inctl process set'. Skill
+// parameters of textproto processes are resolved with the skills installed on the cluster behind
+// conn.
+func ParseProcess(ctx context.Context, conn *grpc.ClientConn, format string, content []byte) (*btpb.BehaviorTree, error) {
+	return deserializeBT(ctx, conn, format, content)
+}
+
 var processSetCmd = &cobra.Command{
 	Use:   "set",
 	Short: "Set process (behavior tree) of a solution. ",
 	Long: `Set the active process (behavior tree) of a currently deployed solution.
 
 Example:
-inctl process set --solution my-solution --cluster my-cluster --input_file /tmp/my-process.textproto [--process_format textproto|binaryproto]
+inctl process set --solution my-solution --input_file /tmp/my-process.textproto [--process_format textproto|binaryproto]
+
+A textproto process can contain placeholders like ${CELL_ID}, which are replaced before it is
+parsed, so that one process definition can be used for many similar cells. Use $$ for a literal $.
+inctl process set --solution my-solution --input_file /tmp/my-process.textproto \
+  --set CELL_ID=cell_3 --set TOOL_FRAME=gripper_tcp
 `,
 	Args: cobra.ExactArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if flagInputFile == "" {
 			return fmt.Errorf("--input_file must be specified")
 		}
+		substitutions, err := parseSubstitutions(flagSubstitutions)
+		if err != nil {
+			return err
+		}
 
-		projectName := viperLocal.GetString(orgutil.KeyProject)
-		orgName := viperLocal.GetString(orgutil.KeyOrganization)
-		ctx, conn, err := connectToCluster(cmd.Context(), projectName,
-			orgName, flagServerAddress,
-			flagSolutionName, flagClusterName)
+		ctx, conn, err := connectToCluster(cmd.Context())
 		if err != nil {
 			return errors.Wrapf(err, "could not dial connection")
 		}
@@ -155,10 +202,12 @@ inctl process set --solution my-solution --cluster my-cluster --input_file /tmp/
 		}
 
 		if err = setProcess(ctx, conn, &setProcessParams{
-			content:      content,
-			format:       flagProcessFormat,
-			clearTreeID:  flagClearTreeID,
-			clearNodeIDs: flagClearNodeIDs,
+			content:       content,
+			format:        flagProcessFormat,
+			clearTreeID:   flagClearTreeID,
+			clearNodeIDs:  flagClearNodeIDs,
+			skillLockfile: flagSkillLockfile,
+			substitutions: substitutions,
 		}); err != nil {
 			return errors.Wrapf(err, "could not set BT")
 		}
@@ -173,9 +222,9 @@ func init() {
 	processSetCmd.Flags().StringVar(
 		&flagProcessFormat, "process_format", TextProtoFormat,
 		fmt.Sprintf("(optional) input format. One of: (%s)", strings.Join(allowedSetFormats, ", ")))
-	processSetCmd.Flags().StringVar(&flagSolutionName, "solution", "", "Solution to set the process on. For example, use `inctl solutions list --project intrinsic-workcells --output json [--filter running_in_sim]` to see the list of solutions.")
-	processSetCmd.Flags().StringVar(&flagClusterName, "cluster", "", "Cluster to set the process on.")
 	processSetCmd.Flags().StringVar(&flagInputFile, "input_file", "", "File from which to read the process.")
+	processSetCmd.Flags().StringArrayVar(&flagSubstitutions, "set", nil, "KEY=value to replace the placeholder ${KEY} in a textproto process with. Can be repeated.")
+	processSetCmd.Flags().StringVar(&flagSkillLockfile, "skill_lockfile", "", "If set, fails unless the skills in the given lockfile written by 'process get --skill_lockfile' are installed in the same versions.")
 	processCmd.AddCommand(processSetCmd)
 
 }
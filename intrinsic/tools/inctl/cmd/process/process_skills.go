@@ -0,0 +1,310 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	bcpb "intrinsic/executive/proto/behavior_call_go_proto"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	skillregistrygrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	srpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+	"intrinsic/util/proto/registryutil"
+)
+
+// skillsPageSize is the number of skills requested per page. Skills carry their parameter
+// descriptors, so pages are kept small to bound the size of each response.
+const skillsPageSize = 20
+
+var (
+	protoNameBehaviorCall = proto.MessageName(new(bcpb.BehaviorCall))
+
+	// skillIDRegex finds the skills called in a textproto behavior tree, with the id in double or
+	// single quotes.
+	skillIDRegex = regexp.MustCompile(`\bskill_id\s*:\s*(?:"([^"]+)"|'([^']+)')`)
+)
+
+// listSkills calls f for each installed skill. Skills are fetched one page at a time and not kept,
+// so that large solutions do not need to be held in memory at once.
+func listSkills(ctx context.Context, conn *grpc.ClientConn, f func(*skillspb.Skill) error) error {
+	client := skillregistrygrpcpb.NewSkillRegistryClient(conn)
+	var nextPageToken string
+	for {
+		resp, err := client.ListSkills(ctx, &srpb.ListSkillsRequest{
+			PageSize:  skillsPageSize,
+			PageToken: nextPageToken,
+		})
+		if err != nil {
+			return fmt.Errorf("could not list skills: %w", err)
+		}
+		for _, skill := range resp.GetSkills() {
+			if err := f(skill); err != nil {
+				return err
+			}
+		}
+		nextPageToken = resp.GetNextPageToken()
+		if nextPageToken == "" {
+			return nil
+		}
+	}
+}
+
+// getSkillsByID fetches only the given skills from the skill registry. Skills which are not
+// installed are skipped with a warning, since a process may refer to skills which have been
+// uninstalled in the meantime.
+func getSkillsByID(ctx context.Context, conn *grpc.ClientConn, ids []string) ([]*skillspb.Skill, error) {
+	client := skillregistrygrpcpb.NewSkillRegistryClient(conn)
+	var skills []*skillspb.Skill
+	for _, id := range ids {
+		resp, err := client.GetSkill(ctx, &srpb.GetSkillRequest{Id: id})
+		if status.Code(err) == codes.NotFound {
+			fmt.Fprintf(os.Stderr, "Warning: skill %q used in the process is not installed\n", id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not get skill %q: %w", id, err)
+		}
+		skills = append(skills, resp.GetSkill())
+	}
+	return skills, nil
+}
+
+// skillIDsInTree returns the sorted ids of all skills called in bt.
+func skillIDsInTree(bt *btpb.BehaviorTree) []string {
+	ids := map[string]struct{}{}
+	collectSkillIDs(bt.ProtoReflect(), ids)
+	return sortedKeys(ids)
+}
+
+func collectSkillIDs(refl protoreflect.Message, ids map[string]struct{}) {
+	if refl.Descriptor().FullName() == protoNameBehaviorCall {
+		if id := refl.Interface().(*bcpb.BehaviorCall).GetSkillId(); id != "" {
+			ids[id] = struct{}{}
+		}
+	}
+	refl.Range(func(field protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if field.Kind() != protoreflect.MessageKind && field.Kind() != protoreflect.GroupKind {
+			return true
+		}
+		switch {
+		case field.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				collectSkillIDs(v.List().Get(i).Message(), ids)
+			}
+		case field.IsMap():
+			if field.MapValue().Kind() == protoreflect.MessageKind {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					collectSkillIDs(mv.Message(), ids)
+					return true
+				})
+			}
+		default:
+			collectSkillIDs(v.Message(), ids)
+		}
+		return true
+	})
+}
+
+// skillIDsInTextProto returns the sorted ids of all skills called in a textproto behavior tree.
+// The tree cannot be parsed before the skill parameter types are known, so the ids are extracted
+// from the text. This can miss ids which are written unusually, e.g., as concatenated strings, see
+// parseTextProto.
+func skillIDsInTextProto(content []byte) []string {
+	ids := map[string]struct{}{}
+	for _, m := range skillIDRegex.FindAllSubmatch(content, -1) {
+		ids[string(m[1])+string(m[2])] = struct{}{}
+	}
+	return sortedKeys(ids)
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fetchSkills returns the skills with the given ids, or all installed skills if --all_skills is
+// set.
+func fetchSkills(ctx context.Context, conn *grpc.ClientConn, ids []string) ([]*skillspb.Skill, error) {
+	if !flagAllSkills {
+		return getSkillsByID(ctx, conn, ids)
+	}
+	var skills []*skillspb.Skill
+	if err := listSkills(ctx, conn, func(skill *skillspb.Skill) error {
+		skills = append(skills, skill)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return skills, nil
+}
+
+// fetchSkillTypes returns a type resolver for the parameter types of the skills with the given
+// ids, or of all installed skills if allSkills is set.
+func fetchSkillTypes(ctx context.Context, conn *grpc.ClientConn, ids []string, allSkills bool) (*protoregistry.Types, error) {
+	if !slices.Contains(protoConflictPolicies, flagProtoConflicts) {
+		return nil, fmt.Errorf("invalid --proto_conflicts %q, must be one of %v", flagProtoConflicts, protoConflictPolicies)
+	}
+	if !allSkills {
+		skills, err := getSkillsByID(ctx, conn, ids)
+		if err != nil {
+			return nil, err
+		}
+		return newSkillTypes(skills, flagProtoConflicts)
+	}
+	// Register descriptors page by page instead of keeping all skills.
+	r := newSkillFiles(flagProtoConflicts)
+	if err := listSkills(ctx, conn, r.register); err != nil {
+		return nil, err
+	}
+	return r.types()
+}
+
+// fetchTypeResolver returns a type resolver for the parameter types of the skills with the given
+// ids like fetchSkillTypes. Unless --reflection_fallback is disabled, types which the skills do not
+// provide descriptors for are resolved using gRPC server reflection.
+func fetchTypeResolver(ctx context.Context, conn *grpc.ClientConn, ids []string, allSkills bool) (typeResolver, error) {
+	pt, err := fetchSkillTypes(ctx, conn, ids, allSkills)
+	if err != nil {
+		return nil, err
+	}
+	if !flagReflectionFallback {
+		return pt, nil
+	}
+	return newReflectionResolver(ctx, conn, pt), nil
+}
+
+// newSkillTypes creates a type resolver for the parameter types of the given skills. Conflicting
+// proto files are handled according to policy, one of the protoConflict* constants.
+func newSkillTypes(skills []*skillspb.Skill, policy string) (*protoregistry.Types, error) {
+	r := newSkillFiles(policy)
+	for _, skill := range skills {
+		if err := r.register(skill); err != nil {
+			return nil, err
+		}
+	}
+	return r.types()
+}
+
+// Policies for proto files which are defined differently by several skills.
+const (
+	// protoConflictWarn keeps the file of the first skill and prints a warning.
+	protoConflictWarn = "warn"
+	// protoConflictError fails once all skills have been registered.
+	protoConflictError = "error"
+	// protoConflictIgnore keeps the file of the first skill silently.
+	protoConflictIgnore = "ignore"
+)
+
+var protoConflictPolicies = []string{protoConflictWarn, protoConflictError, protoConflictIgnore}
+
+// fileSource is the skill which provided a proto file and the digest of its descriptor.
+type fileSource struct {
+	skillID string
+	digest  string
+}
+
+func (s fileSource) String() string {
+	return fmt.Sprintf("%s (sha256:%.12s)", s.skillID, s.digest)
+}
+
+// protoConflict is a proto file which is defined differently by two skills.
+type protoConflict struct {
+	path string
+	// kept is the skill whose definition of the file is used.
+	kept fileSource
+	// dropped is the skill whose definition of the file is ignored.
+	dropped fileSource
+}
+
+func (c protoConflict) String() string {
+	return fmt.Sprintf("%q is defined by skills %v and %v, using the one of %s", c.path, c.kept, c.dropped, c.kept.skillID)
+}
+
+// skillFiles registers the parameter descriptors of skills and detects proto files which are
+// defined differently by several skills. Only the first definition of a file can be registered, so
+// such skills can fail to parse parameters which use the other definition.
+type skillFiles struct {
+	files     *protoregistry.Files
+	sources   map[string]fileSource
+	conflicts []protoConflict
+	policy    string
+}
+
+func newSkillFiles(policy string) *skillFiles {
+	return &skillFiles{
+		files:   new(protoregistry.Files),
+		sources: map[string]fileSource{},
+		policy:  policy,
+	}
+}
+
+func (r *skillFiles) register(skill *skillspb.Skill) error {
+	for _, parameterDescriptorFile := range skill.GetParameterDescription().GetParameterDescriptorFileset().GetFile() {
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(parameterDescriptorFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal %q of skill %q", parameterDescriptorFile.GetName(), skill.GetId())
+		}
+		sum := sha256.Sum256(b)
+		source := fileSource{skillID: skill.GetId(), digest: hex.EncodeToString(sum[:])}
+
+		if kept, ok := r.sources[parameterDescriptorFile.GetName()]; ok {
+			if kept.digest != source.digest {
+				c := protoConflict{path: parameterDescriptorFile.GetName(), kept: kept, dropped: source}
+				r.conflicts = append(r.conflicts, c)
+				if r.policy == protoConflictWarn {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", c)
+				}
+			}
+			continue // Already registered by another skill.
+		}
+		fd, err := protodesc.NewFile(parameterDescriptorFile, r.files)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add file to registry")
+		}
+		r.files.RegisterFile(fd)
+		r.sources[parameterDescriptorFile.GetName()] = source
+	}
+	return nil
+}
+
+// types returns the types of all registered files, or an error listing all conflicts if the
+// policy is protoConflictError.
+func (r *skillFiles) types() (*protoregistry.Types, error) {
+	if r.policy == protoConflictError && len(r.conflicts) > 0 {
+		lines := make([]string, 0, len(r.conflicts))
+		for _, c := range r.conflicts {
+			lines = append(lines, "  "+c.String())
+		}
+		return nil, fmt.Errorf("skills define %d proto files differently:\n%s", len(r.conflicts), strings.Join(lines, "\n"))
+	}
+	return typesFromFiles(r.files)
+}
+
+func typesFromFiles(r *protoregistry.Files) (*protoregistry.Types, error) {
+	pt := new(protoregistry.Types)
+	if err := registryutil.PopulateTypesFromFiles(pt, r); err != nil {
+		return nil, errors.Wrapf(err, "failed to populate types from files")
+	}
+	return pt, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"strings"
+	"testing"
+
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	skillspb "intrinsic/skills/proto/skills_go_proto"
+)
+
+func skillWithFile(id string, file *dpb.FileDescriptorProto) *skillspb.Skill {
+	return &skillspb.Skill{
+		Id: id,
+		ParameterDescription: &skillspb.ParameterDescription{
+			ParameterDescriptorFileset: &dpb.FileDescriptorSet{File: []*dpb.FileDescriptorProto{file}},
+		},
+	}
+}
+
+func paramsFile(fields ...string) *dpb.FileDescriptorProto {
+	msg := &dpb.DescriptorProto{Name: proto.String("Params")}
+	for i, f := range fields {
+		msg.Field = append(msg.Field, &dpb.FieldDescriptorProto{
+			Name:   proto.String(f),
+			Number: proto.Int32(int32(i + 1)),
+			Type:   dpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			Label:  dpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		})
+	}
+	return &dpb.FileDescriptorProto{
+		Name:        proto.String("shared/params.proto"),
+		Package:     proto.String("shared"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*dpb.DescriptorProto{msg},
+	}
+}
+
+func TestNewSkillTypesConflicts(t *testing.T) {
+	skills := []*skillspb.Skill{
+		skillWithFile("ai.intrinsic.a", paramsFile("name")),
+		skillWithFile("ai.intrinsic.b", paramsFile("name")),
+		skillWithFile("ai.intrinsic.c", paramsFile("name", "speed")),
+	}
+
+	types, err := newSkillTypes(skills, protoConflictIgnore)
+	if err != nil {
+		t.Fatalf("newSkillTypes(%q) failed: %v", protoConflictIgnore, err)
+	}
+	mt, err := types.FindMessageByName("shared.Params")
+	if err != nil {
+		t.Fatalf("FindMessageByName() failed: %v", err)
+	}
+	if got := mt.Descriptor().Fields().Len(); got != 1 {
+		t.Errorf("shared.Params has %d fields, want the 1 field of the first skill", got)
+	}
+
+	_, err = newSkillTypes(skills, protoConflictError)
+	if err == nil {
+		t.Fatalf("newSkillTypes(%q) succeeded, want conflict error", protoConflictError)
+	}
+	for _, want := range []string{"1 proto files", "shared/params.proto", "ai.intrinsic.a (sha256:", "ai.intrinsic.c (sha256:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("newSkillTypes(%q) error = %q, want it to contain %q", protoConflictError, err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "ai.intrinsic.b") {
+		t.Errorf("newSkillTypes(%q) error = %q, want no conflict for the identical file of ai.intrinsic.b", protoConflictError, err)
+	}
+}
+
+func TestSkillIDsInTextProto(t *testing.T) {
+	content := []byte(`
+		root { task { call_behavior { skill_id: "ai.intrinsic.move" } } }
+		root { task { call_behavior { skill_id:'ai.intrinsic.grasp' } } }
+		root { task { call_behavior { skill_id: "ai.intrinsic.move" } } }
+	`)
+	want := []string{"ai.intrinsic.grasp", "ai.intrinsic.move"}
+	if diff := cmp.Diff(want, skillIDsInTextProto(content)); diff != "" {
+		t.Errorf("skillIDsInTextProto() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseTextProtoFallsBackToAllSkills(t *testing.T) {
+	installed := map[string]*skillspb.Skill{
+		"ai.intrinsic.a": skillWithFile("ai.intrinsic.a", paramsFile("name")),
+	}
+	// The id is a concatenated string, which is valid textproto, but only its first part is found in
+	// the text.
+	content := []byte(`
+		root {
+			task {
+				call_behavior {
+					skill_id: "ai.intrinsic." "a"
+					parameters {
+						[type.googleapis.com/shared.Params] { name: "box" }
+					}
+				}
+			}
+		}
+	`)
+
+	var calls []string
+	bt, err := parseTextProto(content, func(ids []string, allSkills bool) (typeResolver, error) {
+		var skills []*skillspb.Skill
+		if allSkills {
+			calls = append(calls, "all")
+			for _, skill := range installed {
+				skills = append(skills, skill)
+			}
+		} else {
+			calls = append(calls, strings.Join(ids, ","))
+			for _, id := range ids {
+				skills = append(skills, installed[id])
+			}
+		}
+		return newSkillTypes(skills, protoConflictIgnore)
+	})
+	if err != nil {
+		t.Fatalf("parseTextProto() failed: %v", err)
+	}
+	if got := bt.GetRoot().GetTask().GetCallBehavior().GetSkillId(); got != "ai.intrinsic.a" {
+		t.Errorf("parseTextProto() returned skill id %q, want %q", got, "ai.intrinsic.a")
+	}
+	if diff := cmp.Diff([]string{"ai.intrinsic.", "all"}, calls); diff != "" {
+		t.Errorf("parseTextProto() fetched unexpected skills (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderRegex matches the placeholders ${KEY} and the escape sequence $$, which stands for a
+// literal $.
+var placeholderRegex = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+var placeholderKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseSubstitutions parses the key=value pairs given with --set.
+func parseSubstitutions(pairs []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid substitution %q, must be key=value", pair)
+		}
+		if !placeholderKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid substitution key %q, must consist of letters, digits and underscores", key)
+		}
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("substitution key %q given more than once", key)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// substitutePlaceholders replaces the placeholders ${KEY} in content with the values of the keys
+// and $$ with $. Values are inserted verbatim, so placeholders inside of string literals are
+// replaced by the plain value. It fails if content contains placeholders without a value.
+func substitutePlaceholders(content []byte, values map[string]string) ([]byte, error) {
+	missing := map[string]bool{}
+	result := placeholderRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+		if string(match) == "$$" {
+			return []byte("$")
+		}
+		key := string(match[2 : len(match)-1])
+		value, ok := values[key]
+		if !ok {
+			missing[key] = true
+			return match
+		}
+		return []byte(value)
+	})
+	if len(missing) > 0 {
+		keys := make([]string, 0, len(missing))
+		for key := range missing {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("no value given for placeholders %s, set them with --set KEY=value", strings.Join(keys, ", "))
+	}
+	return result, nil
+}
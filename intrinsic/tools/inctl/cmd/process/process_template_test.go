@@ -0,0 +1,34 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package process
+
+import (
+	"testing"
+)
+
+func TestSubstitutePlaceholders(t *testing.T) {
+	values, err := parseSubstitutions([]string{"CELL_ID=cell_3", "TOOL_FRAME=gripper=tcp"})
+	if err != nil {
+		t.Fatalf("parseSubstitutions() failed: %v", err)
+	}
+	content := `name: "pick ${CELL_ID}" frame: "${TOOL_FRAME}" cost: "$$5"`
+	got, err := substitutePlaceholders([]byte(content), values)
+	if err != nil {
+		t.Fatalf("substitutePlaceholders() failed: %v", err)
+	}
+	if want := `name: "pick cell_3" frame: "gripper=tcp" cost: "$5"`; string(got) != want {
+		t.Errorf("substitutePlaceholders() = %q, want %q", got, want)
+	}
+
+	if _, err := substitutePlaceholders([]byte(`name: "${MISSING}"`), values); err == nil {
+		t.Errorf("substitutePlaceholders() succeeded with an undefined placeholder, want error")
+	}
+}
+
+func TestParseSubstitutionsInvalid(t *testing.T) {
+	for _, pairs := range [][]string{{"CELL_ID"}, {"1CELL=a"}, {"A}${B=c"}, {"A=1", "A=2"}} {
+		if _, err := parseSubstitutions(pairs); err == nil {
+			t.Errorf("parseSubstitutions(%q) succeeded, want error", pairs)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package root
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"flag"
+	"github.com/spf13/cobra"
+	"intrinsic/skills/tools/skill/cmd/dialerutil"
+	"intrinsic/tools/inctl/util/orgutil"
+)
+
+// envReauthenticated is set for a command which is retried after logging in again, so that inctl
+// asks to log in at most once.
+const envReauthenticated = "INCTL_REAUTHENTICATED"
+
+// loginArgs returns the arguments of 'inctl auth login' for the organization or project which cmd
+// used, nil if it used neither.
+func loginArgs(cmd *cobra.Command) []string {
+	value := func(name string) string {
+		if f := cmd.Flags().Lookup(name); f != nil {
+			return f.Value.String()
+		}
+		if f := cmd.InheritedFlags().Lookup(name); f != nil {
+			return f.Value.String()
+		}
+		return ""
+	}
+	project := value(orgutil.KeyProject)
+	switch org := value(orgutil.KeyOrganization); {
+	case org != "":
+		if project != "" {
+			org = orgutil.QualifiedOrg(project, org)
+		}
+		return []string{"auth", "login", "--" + orgutil.KeyOrganization, org}
+	case project != "":
+		return []string{"auth", "login", "--" + orgutil.KeyProject, project}
+	default:
+		return nil
+	}
+}
+
+func isInteractive() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// reauthenticateAndRetry asks the user to log in again if err shows that the stored credentials
+// were rejected, e.g., because the API key expired, and then runs the command again. The command
+// is retried in a new process, so it runs with fresh flags and connections. Returns if the command
+// is not retried.
+func reauthenticateAndRetry(err error, cmdNames []string) {
+	if !dialerutil.IsCredentialsRejected(err) || os.Getenv(envReauthenticated) != "" || !isInteractive() {
+		return
+	}
+	if len(cmdNames) == 0 || cmdNames[0] == "auth" {
+		return
+	}
+	cmd, _, findErr := RootCmd.Find(flag.Args())
+	if findErr != nil {
+		return
+	}
+	args := loginArgs(cmd)
+	if args == nil {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Run 'inctl %s' and retry the command? [y/N] ", strings.Join(args, " "))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		return
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: cannot find the inctl binary:", err)
+		return
+	}
+	login := exec.Command(self, args...)
+	login.Stdin, login.Stdout, login.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := login.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: login failed:", err)
+		return
+	}
+
+	env := append(os.Environ(), envReauthenticated+"=1")
+	if err := rerun(self, env); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: cannot retry the command:", err)
+	}
+}
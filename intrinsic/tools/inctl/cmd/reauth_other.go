@@ -0,0 +1,28 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+//go:build !unix
+
+package root
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// rerun runs the same command with env in a child process and exits with its exit code, since the
+// process cannot be replaced on this platform. Only returns on error.
+func rerun(self string, env []string) error {
+	c := exec.Command(self, os.Args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.Env = env
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		os.Exit(exitErr.ExitCode())
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package root
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLoginArgs(t *testing.T) {
+	tests := []struct {
+		org     string
+		project string
+		want    string
+	}{
+		{org: "my-org", project: "my-project", want: "auth login --org my-org"},
+		{org: "intrinsic", project: "my-project", want: "auth login --org intrinsic@my-project"},
+		{project: "my-project", want: "auth login --project my-project"},
+		{want: ""},
+	}
+	for _, tc := range tests {
+		parent := &cobra.Command{Use: "parent"}
+		parent.PersistentFlags().String("org", tc.org, "")
+		cmd := &cobra.Command{Use: "child"}
+		cmd.Flags().String("project", tc.project, "")
+		parent.AddCommand(cmd)
+
+		if got := strings.Join(loginArgs(cmd), " "); got != tc.want {
+			t.Errorf("loginArgs() with org %q and project %q = %q, want %q", tc.org, tc.project, got, tc.want)
+		}
+	}
+}
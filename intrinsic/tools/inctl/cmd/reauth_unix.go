@@ -0,0 +1,15 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+//go:build unix
+
+package root
+
+import (
+	"os"
+	"syscall"
+)
+
+// rerun replaces the process with the same command running with env. Only returns on error.
+func rerun(self string, env []string) error {
+	return syscall.Exec(self, os.Args, env)
+}
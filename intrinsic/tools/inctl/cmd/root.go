@@ -7,7 +7,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"flag"
 	log "github.com/golang/glog"
@@ -15,6 +17,7 @@ import (
 	"github.com/spf13/cobra"
 	"go.opencensus.io/trace"
 	"golang.org/x/exp/slices"
+	"intrinsic/assets/clientutils"
 	intrinsic "intrinsic/production/intrinsic"
 	"intrinsic/skills/tools/skill/cmd/dialerutil"
 	"intrinsic/tools/inctl/util/orgutil"
@@ -50,7 +53,7 @@ var (
 var RootCmd = &cobra.Command{
 	Use:   "inctl",
 	Short: "inctl is the Intrinsic commandline tool",
-	Long:  `inctl (pronounced "in control") provides access to high-level APIs and utilities of the Intrinsic stack to application developers.`,
+	Long:  "inctl (pronounced \"in control\") provides access to high-level APIs and utilities of the Intrinsic stack to application developers.\n\n" + exitCodeHelp,
 	// Do not print usage when a command exits with an error.
 	SilenceUsage: true,
 	// Silence errors so we can control how they are printed.
@@ -72,6 +75,12 @@ func (e *executionContext) RewriteError(err error, cmdNames []string) string {
 		return fmt.Sprintf("%v\nRun 'inctl --help' for usage.", err)
 	}
 
+	// Relay errors already carry a hint, which is more specific than the ones below.
+	var relayErr *clientutils.RelayError
+	if errors.As(err, &relayErr) {
+		return err.Error()
+	}
+
 	// This will also find wrapped gRPC error/statuses.
 	if grpcStatus, ok := grpcstatus.FromError(cause); ok {
 		if grpcStatus.Code() == grpccodes.Unauthenticated {
@@ -125,6 +134,12 @@ func (e *executionContext) RewriteError(err error, cmdNames []string) string {
 	return err.Error()
 }
 
+// RewriteError rewrites an error of a command into a helpful message, like inctl does before it
+// exits. cmdNames are the names of the command and its parents, e.g. ["skill", "list"].
+func RewriteError(err error, cmdNames []string) string {
+	return (&executionContext{}).RewriteError(err, cmdNames)
+}
+
 // getCommandNames returns a vector of subcommand names - e.g. ["app", "status"]
 // for "inctl app status" or [] for "inctl". Returns an error if there is no
 // matching command, e.g. because the user misspelled the command name(s).
@@ -145,35 +160,53 @@ func getCommandNames() ([]string, error) {
 	return names, nil
 }
 
+// ExitCodeError makes inctl exit with Code instead of the code of the class of Err if it is
+// returned by a command, e.g., to distinguish the findings of a check from a failure to run it.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
 // Execute is the top level function that runs the app and prints any errors.
-// It returns true if the command was successful.
-// rewriteError rewrites an error into a helpful string.
-func Execute(ec executionContext) bool {
-	ctx := context.Background()
+// It returns the exit code of inctl, 0 if the command was successful.
+func Execute(ec executionContext) int {
+	// The first interrupt cancels the context, so that commands can abort uploads and operations
+	// cleanly. Default signal handling is restored afterwards, so a second interrupt exits
+	// immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
 	RootCmd.SetArgs(flag.Args())
+	LoadCommands(flag.Args())
 
 	ctx, span := trace.StartSpan(ctx, "inctl", trace.WithSampler(trace.AlwaysSample()))
 	defer span.End()
 
-	success := true
 	if err := RootCmd.ExecuteContext(ctx); err != nil {
 		cmdNames, _ := getCommandNames() // ignore error, cmdNames will simply be nil
 		fmt.Fprintln(os.Stderr, "Error:", ec.RewriteError(err, cmdNames))
-		success = false
+		reauthenticateAndRetry(err, cmdNames)
+		return ExitCode(err)
 	}
 
-	return success
+	return ExitCodeOK
 }
 
 // Inctl launches inctl with the currently configured commands.
 func Inctl() {
 	intrinsic.Init()
 
-	success := Execute(executionContext{})
-
-	if !success {
+	if code := Execute(executionContext{}); code != ExitCodeOK {
 		log.Warning("Command failed")
-		os.Exit(1)
+		os.Exit(code)
 	}
 }
 
@@ -0,0 +1,103 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package shell
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// complete completes the word before pos in line to the longest common prefix of the matching
+// commands or flags. Only the end of the line is completed.
+func complete(rootCmd *cobra.Command, line string, pos int) (string, int, bool) {
+	if pos != len(line) {
+		return "", 0, false
+	}
+	words := strings.Fields(line)
+	prefix := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		prefix = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	candidates := completions(rootCmd, words, prefix)
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+	completion := longestCommonPrefix(candidates)
+	if len(candidates) == 1 {
+		completion += " "
+	}
+	if len(completion) <= len(prefix) {
+		return "", 0, false
+	}
+	newLine := line + completion[len(prefix):]
+	return newLine, len(newLine), true
+}
+
+// completions returns the sorted subcommands or flags of the command given by words which start
+// with prefix.
+func completions(rootCmd *cobra.Command, words []string, prefix string) []string {
+	cmd := rootCmd
+	for _, w := range words {
+		if strings.HasPrefix(w, "-") {
+			continue
+		}
+		sub := subcommand(cmd, w)
+		if sub == nil {
+			break
+		}
+		cmd = sub
+	}
+
+	var candidates []string
+	if strings.HasPrefix(prefix, "-") {
+		// Flags() also contains the inherited flags once they have been merged.
+		seen := map[string]bool{}
+		add := func(f *pflag.Flag) {
+			if name := "--" + f.Name; !f.Hidden && !seen[name] && strings.HasPrefix(name, prefix) {
+				seen[name] = true
+				candidates = append(candidates, name)
+			}
+		}
+		cmd.Flags().VisitAll(add)
+		cmd.InheritedFlags().VisitAll(add)
+	} else {
+		for _, c := range cmd.Commands() {
+			if c.IsAvailableCommand() && strings.HasPrefix(c.Name(), prefix) {
+				candidates = append(candidates, c.Name())
+			}
+		}
+		if cmd == rootCmd {
+			for name := range builtins {
+				if strings.HasPrefix(name, prefix) {
+					candidates = append(candidates, name)
+				}
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+func subcommand(cmd *cobra.Command, name string) *cobra.Command {
+	for _, c := range cmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return c
+		}
+	}
+	return nil
+}
+
+func longestCommonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
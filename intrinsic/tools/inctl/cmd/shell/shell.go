@@ -0,0 +1,309 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package shell implements 'inctl shell', an interactive mode which runs many inctl commands in a
+// single process.
+package shell
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/tools/inctl/cmd/root"
+)
+
+const shellCmdName = "shell"
+
+// contextKeys are the flags which can be set for all following commands with 'use', in the order
+// in which they are shown.
+var contextKeys = []string{cmdutils.KeyOrganization, cmdutils.KeyProject, cmdutils.KeySolution, cmdutils.KeyCluster}
+
+// builtins are the commands of the shell itself.
+var builtins = map[string]string{
+	"use":     "use FLAG [VALUE]: Passes --FLAG=VALUE to all following commands which have the flag. Omit VALUE to unset it. FLAG is one of " + strings.Join(contextKeys, ", ") + ".",
+	"context": "context: Shows the flags set with 'use'.",
+	"reset":   "reset: Forgets the clusters which solutions were resolved to.",
+	"help":    "help: Shows this help. Use 'COMMAND --help' for the help of an inctl command.",
+	"exit":    "exit: Leaves the shell. Ctrl-D does the same.",
+}
+
+// session is the state which is kept across the commands of a shell.
+type session struct {
+	root *cobra.Command
+	// context holds the values set with 'use'.
+	context map[string]string
+	out     io.Writer
+}
+
+func newSession(rootCmd *cobra.Command, out io.Writer) *session {
+	return &session{root: rootCmd, context: map[string]string{}, out: out}
+}
+
+func (s *session) prompt() string {
+	var parts []string
+	for _, k := range contextKeys {
+		if v := s.context[k]; v != "" {
+			parts = append(parts, v)
+		}
+	}
+	if len(parts) == 0 {
+		return "inctl> "
+	}
+	return fmt.Sprintf("inctl [%s]> ", strings.Join(parts, "/"))
+}
+
+// run runs a line of input. It returns false if the shell should exit.
+func (s *session) run(ctx context.Context, line string) bool {
+	args, err := shellquote.Split(line)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return true
+	}
+	if len(args) == 0 {
+		return true
+	}
+	if len(args) > 1 && args[0] == "inctl" {
+		args = args[1:]
+	}
+
+	switch args[0] {
+	case "exit", "quit":
+		return false
+	case "help":
+		if len(args) == 1 {
+			s.printHelp()
+			return true
+		}
+	case "context":
+		for _, k := range contextKeys {
+			if v := s.context[k]; v != "" {
+				fmt.Fprintf(s.out, "--%s=%s\n", k, v)
+			}
+		}
+		return true
+	case "use":
+		if err := s.use(args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		return true
+	case "reset":
+		clientutils.CacheSolutionClusters(true)
+		return true
+	case shellCmdName:
+		fmt.Fprintln(os.Stderr, "Error: already in an inctl shell")
+		return true
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	args = s.withContext(args)
+	resetFlags(s.root)
+	s.root.SetArgs(args)
+	if err := s.root.ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", root.RewriteError(err, commandNames(s.root, args)))
+	}
+	return true
+}
+
+func (s *session) use(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: %s", builtins["use"])
+	}
+	key := strings.TrimLeft(args[0], "-")
+	found := false
+	for _, k := range contextKeys {
+		found = found || k == key
+	}
+	if !found {
+		return fmt.Errorf("cannot use %q, must be one of %s", key, strings.Join(contextKeys, ", "))
+	}
+	if len(args) == 1 {
+		delete(s.context, key)
+		return nil
+	}
+	s.context[key] = args[1]
+	return nil
+}
+
+func (s *session) printHelp() {
+	fmt.Fprintln(s.out, "Run inctl commands without the leading 'inctl', e.g. 'skill list'. Shell commands:")
+	for _, name := range []string{"use", "context", "reset", "help", "exit"} {
+		fmt.Fprintln(s.out, "  "+builtins[name])
+	}
+}
+
+// withContext adds the flags set with 'use' to args if the command has them and they are not
+// already given.
+func (s *session) withContext(args []string) []string {
+	cmd, _, err := s.root.Find(args)
+	if err != nil {
+		return args
+	}
+	for _, k := range contextKeys {
+		v := s.context[k]
+		if v == "" || lookupFlag(cmd, k) == nil || hasFlag(args, k) {
+			continue
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", k, v))
+	}
+	return args
+}
+
+// lookupFlag returns the flag of cmd or one of its parents with the given name, nil if there is
+// none.
+func lookupFlag(cmd *cobra.Command, name string) *pflag.Flag {
+	if f := cmd.Flags().Lookup(name); f != nil {
+		return f
+	}
+	return cmd.InheritedFlags().Lookup(name)
+}
+
+func hasFlag(args []string, name string) bool {
+	for _, a := range args {
+		if a == "--" {
+			return false
+		}
+		if a == "--"+name || strings.HasPrefix(a, "--"+name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// resetFlags sets all flags of cmd and its subcommands back to their defaults, since the same
+// commands are executed repeatedly. This includes flags which were set by commands rather than on
+// the command line, e.g., the project derived from --org.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed && f.Value.String() == f.DefValue {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+// commandNames returns the names of the command which args run, e.g. ["skill", "list"].
+func commandNames(rootCmd *cobra.Command, args []string) []string {
+	cmd, _, err := rootCmd.Find(args)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for node := cmd; node.HasParent(); node = node.Parent() {
+		names = append([]string{node.Name()}, names...)
+	}
+	return names
+}
+
+// readLines runs s for each line of input until the input ends or 'exit' is entered. A terminal
+// gets a prompt, tab completion and history, other input is run as a script.
+func readLines(ctx context.Context, s *session, in *os.File) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			if !s.run(ctx, scanner.Text()) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, s.out}, s.prompt())
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return complete(s.root, line, pos)
+	}
+	for {
+		if w, h, err := term.GetSize(fd); err == nil {
+			t.SetSize(w, h)
+		}
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		line, err := t.ReadLine()
+		// Commands print with the terminal in its normal mode.
+		term.Restore(fd, state)
+		if err == io.EOF {
+			fmt.Fprintln(s.out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !s.run(ctx, line) {
+			return nil
+		}
+		t.SetPrompt(s.prompt())
+	}
+}
+
+var shellCmd = &cobra.Command{
+	Use:   shellCmdName,
+	Short: "Runs inctl commands interactively",
+	Long: `Runs inctl commands interactively in a single process.
+
+Commands are entered without the leading 'inctl'. Use 'use FLAG VALUE' to pass --org, --project,
+--solution or --cluster to all following commands which have the flag, e.g. 'use solution
+my-solution'. The cluster of each solution is only resolved once, so repeated commands against a
+solution start faster than separate inctl invocations. Connections are not reused, though: every
+command still dials the cluster and authenticates on its own.
+
+Tab completes commands and flags, the up and down keys recall the commands of the session. If the
+input is not a terminal, it is run as a script, one command per line.`,
+	Example: `
+	$ inctl shell --org my-org --solution my-solution
+	inctl [my-org/my-solution]> skill list
+	inctl [my-org/my-solution]> use solution other-solution
+	inctl [my-org/other-solution]> process get --output_file /tmp/process.textproto
+	`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Commands are looked up and completed before they run, so all of them are needed upfront.
+		root.LoadCommands(nil)
+		s := newSession(root.RootCmd, cmd.OutOrStdout())
+		for _, k := range contextKeys {
+			if v, _ := cmd.Flags().GetString(k); v != "" {
+				s.context[k] = v
+			}
+		}
+		clientutils.CacheSolutionClusters(true)
+		defer clientutils.CacheSolutionClusters(false)
+
+		// An interrupt only aborts the current command, not the shell.
+		return readLines(context.WithoutCancel(cmd.Context()), s, os.Stdin)
+	},
+}
+
+func init() {
+	for _, k := range contextKeys {
+		shellCmd.Flags().String(k, "", fmt.Sprintf("Initial value of --%s for all commands which have the flag.", k))
+	}
+	root.RootCmd.AddCommand(shellCmd)
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package shell
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+func testCommands() *cobra.Command {
+	rootCmd := &cobra.Command{Use: "inctl"}
+	rootCmd.PersistentFlags().String("output", "text", "")
+	skill := &cobra.Command{Use: "skill"}
+	list := &cobra.Command{Use: "list", Run: func(*cobra.Command, []string) {}}
+	list.Flags().String("solution", "", "")
+	list.Flags().String("filter", "", "")
+	list.Flags().StringSlice("tags", nil, "")
+	skill.AddCommand(list, &cobra.Command{Use: "logs", Run: func(*cobra.Command, []string) {}})
+	rootCmd.AddCommand(skill, &cobra.Command{Use: "solution", Run: func(*cobra.Command, []string) {}})
+	return rootCmd
+}
+
+func TestComplete(t *testing.T) {
+	rootCmd := testCommands()
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"sk", "skill "},
+		{"s", "s"}, // skill and solution
+		{"skill l", "skill l"},
+		{"skill li", "skill list "},
+		{"skill list --f", "skill list --filter "},
+		{"skill list --o", "skill list --output "},
+		{"us", "use "},
+		{"skill list --unknown", "skill list --unknown"},
+	}
+	for _, tc := range tests {
+		got, pos, ok := complete(rootCmd, tc.line, len(tc.line))
+		if !ok {
+			got = tc.line
+		} else if pos != len(got) {
+			t.Errorf("complete(%q) moved the cursor to %d, want the end of %q", tc.line, pos, got)
+		}
+		if got != tc.want {
+			t.Errorf("complete(%q) = %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	s := newSession(testCommands(), io.Discard)
+	if err := s.use([]string{"solution", "my-solution"}); err != nil {
+		t.Fatalf("use() failed: %v", err)
+	}
+	if err := s.use([]string{"--cluster", "my-cluster"}); err != nil {
+		t.Fatalf("use() failed: %v", err)
+	}
+	if err := s.use([]string{"filter", "x"}); err == nil {
+		t.Errorf("use(filter) succeeded, want error for a flag which cannot be used")
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"skill", "list"}, []string{"skill", "list", "--solution=my-solution"}},
+		{[]string{"skill", "list", "--solution=other"}, []string{"skill", "list", "--solution=other"}},
+		{[]string{"skill", "logs"}, []string{"skill", "logs"}},
+	}
+	for _, tc := range tests {
+		if diff := cmp.Diff(tc.want, s.withContext(tc.args)); diff != "" {
+			t.Errorf("withContext(%v) returned unexpected diff (-want +got):\n%s", tc.args, diff)
+		}
+	}
+	if got := s.prompt(); got != "inctl [my-solution/my-cluster]> " {
+		t.Errorf("prompt() = %q, want the solution and cluster", got)
+	}
+}
+
+func TestResetFlags(t *testing.T) {
+	rootCmd := testCommands()
+	rootCmd.SetArgs([]string{"skill", "list", "--filter=a", "--tags=x,y", "--output=json"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	// Commands may also set flags themselves.
+	lookupFlag(rootCmd, "output").Value.Set("yaml")
+	rootCmd.Flags().Lookup("output").Changed = false
+
+	resetFlags(rootCmd)
+
+	list, _, _ := rootCmd.Find([]string{"skill", "list"})
+	for name, want := range map[string]string{"filter": "", "tags": "[]", "output": "text"} {
+		f := lookupFlag(list, name)
+		if got := f.Value.String(); got != want || f.Changed {
+			t.Errorf("--%s = %q (changed: %v) after resetFlags(), want %q", name, got, f.Changed, want)
+		}
+	}
+	if got := strings.Join(commandNames(rootCmd, []string{"skill", "list", "--filter=a"}), " "); got != "skill list" {
+		t.Errorf("commandNames() = %q, want %q", got, "skill list")
+	}
+}
@@ -4,6 +4,7 @@
 package skill
 
 import (
+	"github.com/spf13/cobra"
 	"intrinsic/skills/tools/skill/cmd"
 	_ "intrinsic/skills/tools/skill/cmd/create"                    // Add subcommand "skill create"
 	_ "intrinsic/skills/tools/skill/cmd/defaults/cleardefault"     // Add subcommand "skill clear_default"
@@ -14,9 +15,10 @@ import (
 	_ "intrinsic/skills/tools/skill/cmd/list/listreleasedversions" // Add subcommand "skill list_released_versions".
 	_ "intrinsic/skills/tools/skill/cmd/logs"                      // Add subcommand "skill logs".
 	_ "intrinsic/skills/tools/skill/cmd/release"                   // Add subcommand "skill release".
+	_ "intrinsic/skills/tools/skill/cmd/test"                      // Add subcommand "skill test".
 	"intrinsic/tools/inctl/cmd/root"
 )
 
 func init() {
-	root.RootCmd.AddCommand(cmd.SkillCmd)
+	root.AddLazyCommand(cmd.SkillCmd.Use, cmd.SkillCmd.Short, func() *cobra.Command { return cmd.SkillCmd })
 }
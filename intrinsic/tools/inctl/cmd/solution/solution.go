@@ -26,5 +26,5 @@ var solutionCmd = orgutil.WrapCmd(&cobra.Command{
 }, viperLocal)
 
 func init() {
-	root.RootCmd.AddCommand(solutionCmd)
+	root.AddLazyCommand(solutionCmd.Use, solutionCmd.Short, func() *cobra.Command { return solutionCmd }, solutionCmd.Aliases...)
 }
@@ -0,0 +1,757 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package solution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	"intrinsic/assets/clientutils"
+	"intrinsic/assets/cmdutils"
+	"intrinsic/assets/idutils"
+	adgrpcpb "intrinsic/assets/proto/asset_deployment_go_grpc_proto"
+	atpb "intrinsic/assets/proto/asset_type_go_proto"
+	"intrinsic/executive/processclient"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+	rrgrpcpb "intrinsic/resources/proto/resource_registry_go_grpc_proto"
+	skillcataloggrpcpb "intrinsic/skills/catalog/proto/skill_catalog_go_grpc_proto"
+	srgrpcpb "intrinsic/skills/proto/skill_registry_go_grpc_proto"
+	"intrinsic/skills/tools/skill/cmd/listutil"
+	"intrinsic/tools/inctl/cmd/process"
+	"intrinsic/util/grpc/lroutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	keyFile  = "file"
+	keyPrune = "prune"
+)
+
+// Definition is a declarative description of the assets and the process of a
+// solution.  It is read from YAML or JSON files, e.g.:
+//
+//	skills:
+//	  - ai.intrinsic.move_robot.0.1.0
+//	  - ai.intrinsic.open_gripper@^1.2
+//	services:
+//	  - name: camera
+//	    id_version: ai.intrinsic.basler_camera.0.2.0
+//	    config: camera_config.binpb
+//	process:
+//	  file: process.textproto
+type Definition struct {
+	// Skills are the id_versions of the skills to install.  Instead of an
+	// id_version, a skill can be given as "id@constraint" (see
+	// idutils.ParseVersionConstraint), which is resolved to the latest released
+	// version in the catalog that satisfies the constraint.
+	Skills []string `json:"skills,omitempty"`
+	// Services are the service instances to add.
+	Services []ServiceInstance `json:"services,omitempty"`
+	// Process is the process to load into the executive.
+	Process *ProcessDefinition `json:"process,omitempty"`
+}
+
+// ServiceInstance describes a service instance of a solution definition.
+type ServiceInstance struct {
+	// Name is the name of the instance.
+	Name string `json:"name"`
+	// IDVersion is the id_version of the service.
+	IDVersion string `json:"id_version"`
+	// Config is the path of a binary-serialized Any proto containing the
+	// configuration of the instance.  The current configuration is kept if
+	// empty.
+	Config string `json:"config,omitempty"`
+}
+
+// ProcessDefinition references the process of a solution definition.
+type ProcessDefinition struct {
+	// File is the path of the serialized behavior tree.
+	File string `json:"file"`
+	// Format is the format of File, one of "textproto" (default) and
+	// "binaryproto".
+	Format string `json:"format,omitempty"`
+}
+
+// ReadDefinition reads a solution definition from path.  Relative paths in the
+// definition are resolved against the directory of path.
+func ReadDefinition(path string) (*Definition, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read solution definition: %w", err)
+	}
+	def := &Definition{}
+	if err := yaml.UnmarshalStrict(b, def); err != nil {
+		return nil, fmt.Errorf("invalid solution definition %q: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	for i := range def.Services {
+		def.Services[i].Config = resolve(def.Services[i].Config)
+	}
+	if def.Process != nil {
+		def.Process.File = resolve(def.Process.File)
+	}
+	return def, def.validate()
+}
+
+func (d *Definition) validate() error {
+	skillIDs := make(map[string]bool)
+	for _, skill := range d.Skills {
+		var id string
+		if c, ok := splitSkillConstraint(skill); ok {
+			id = c.id
+			if err := idutils.ValidateID(id); err != nil {
+				return fmt.Errorf("invalid skill: %w", err)
+			}
+			if _, err := idutils.ParseVersionConstraint(c.constraint); err != nil {
+				return fmt.Errorf("invalid version constraint of skill %q: %w", id, err)
+			}
+		} else {
+			p, err := idutils.NewIDVersionParts(skill)
+			if err != nil {
+				return fmt.Errorf("invalid skill: %w", err)
+			}
+			id = p.ID()
+		}
+		if skillIDs[id] {
+			return fmt.Errorf("skill %q is listed more than once", id)
+		}
+		skillIDs[id] = true
+	}
+	names := make(map[string]bool)
+	for _, s := range d.Services {
+		if s.Name == "" {
+			return fmt.Errorf("service %q has no name", s.IDVersion)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("service instance %q is listed more than once", s.Name)
+		}
+		names[s.Name] = true
+		if err := idutils.ValidateIDVersion(s.IDVersion); err != nil {
+			return fmt.Errorf("invalid service %q: %w", s.Name, err)
+		}
+	}
+	if d.Process != nil {
+		if d.Process.File == "" {
+			return fmt.Errorf("process must specify a file")
+		}
+		if d.Process.Format == "" {
+			d.Process.Format = process.TextProtoFormat
+		}
+		if d.Process.Format != process.TextProtoFormat && d.Process.Format != process.BinaryProtoFormat {
+			return fmt.Errorf("unsupported process format %q", d.Process.Format)
+		}
+	}
+	return nil
+}
+
+// skillConstraint is a skill of a definition given as "id@constraint".
+type skillConstraint struct {
+	id         string
+	constraint string
+}
+
+func splitSkillConstraint(skill string) (*skillConstraint, bool) {
+	id, constraint, ok := strings.Cut(skill, "@")
+	if !ok {
+		return nil, false
+	}
+	return &skillConstraint{id: id, constraint: constraint}, true
+}
+
+// hasSkillConstraints reports whether any skill is given with a version
+// constraint instead of an id_version.
+func (d *Definition) hasSkillConstraints() bool {
+	for _, skill := range d.Skills {
+		if _, ok := splitSkillConstraint(skill); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSkills replaces the skills given with a version constraint by the
+// id_version of the latest version returned by versions which satisfies the
+// constraint.  versions returns the released versions of a skill id.
+func (d *Definition) resolveSkills(versions func(id string) ([]string, error)) error {
+	for i, skill := range d.Skills {
+		c, ok := splitSkillConstraint(skill)
+		if !ok {
+			continue
+		}
+		vc, err := idutils.ParseVersionConstraint(c.constraint)
+		if err != nil {
+			return fmt.Errorf("invalid version constraint of skill %q: %w", c.id, err)
+		}
+		available, err := versions(c.id)
+		if err != nil {
+			return err
+		}
+		version, err := vc.Latest(available)
+		if err != nil {
+			return fmt.Errorf("could not resolve skill %q: %w", skill, err)
+		}
+		pkg, err := idutils.PackageFrom(c.id)
+		if err != nil {
+			return fmt.Errorf("invalid skill: %w", err)
+		}
+		name, err := idutils.NameFrom(c.id)
+		if err != nil {
+			return fmt.Errorf("invalid skill: %w", err)
+		}
+		if d.Skills[i], err = idutils.IDVersionFrom(pkg, name, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type actionKind int
+
+const (
+	actionAdd actionKind = iota
+	actionUpdate
+	actionReplace
+	actionRemove
+	actionSetProcess
+)
+
+func (k actionKind) symbol() string {
+	switch k {
+	case actionAdd:
+		return "+"
+	case actionUpdate:
+		return "~"
+	case actionReplace:
+		return "-/+"
+	case actionRemove:
+		return "-"
+	default:
+		return ">"
+	}
+}
+
+// action is a single step of a deployment plan.
+type action struct {
+	kind actionKind
+	// skill is the id (for removals) or id_version of a skill.
+	skill string
+	// service is the desired service instance.  For removals only the name is
+	// set.
+	service *ServiceInstance
+	// config is the desired configuration of service, if any.
+	config *anypb.Any
+	// from describes the current state for updates and replacements.
+	from string
+	// process is the definition of the process to set.
+	process *ProcessDefinition
+	// tree is the behavior tree parsed from process.
+	tree *btpb.BehaviorTree
+}
+
+func (a *action) String() string {
+	var desc string
+	switch {
+	case a.kind == actionSetProcess:
+		desc = fmt.Sprintf("set process from %s", a.process.File)
+	case a.skill != "" && a.kind == actionRemove:
+		desc = fmt.Sprintf("remove skill %s", a.skill)
+	case a.skill != "":
+		desc = fmt.Sprintf("install skill %s", a.skill)
+	case a.kind == actionRemove:
+		desc = fmt.Sprintf("remove service %q", a.service.Name)
+	case a.kind == actionAdd:
+		desc = fmt.Sprintf("add service %q (%s)", a.service.Name, a.service.IDVersion)
+	default:
+		desc = fmt.Sprintf("update service %q (%s)", a.service.Name, a.service.IDVersion)
+	}
+	if a.from != "" {
+		desc += fmt.Sprintf(", currently %s", a.from)
+	}
+	return fmt.Sprintf("%3s %s", a.kind.symbol(), desc)
+}
+
+// clusterState is the part of the state of a cluster managed by definitions.
+type clusterState struct {
+	// skills maps skill ids to the installed id_versions.
+	skills map[string]string
+	// services maps service ids to the installed id_versions.
+	services map[string]string
+	// instances maps instance names to their type ids.
+	instances map[string]string
+	// configs maps instance names to their configuration.
+	configs map[string]*anypb.Any
+	// process is the active process, or nil if none is set.
+	process *btpb.BehaviorTree
+}
+
+func readClusterState(ctx context.Context, conn *grpc.ClientConn) (*clusterState, error) {
+	state := &clusterState{
+		skills:    make(map[string]string),
+		services:  make(map[string]string),
+		instances: make(map[string]string),
+		configs:   make(map[string]*anypb.Any),
+	}
+
+	skillClient := srgrpcpb.NewSkillRegistryClient(conn)
+	for pageToken := ""; ; {
+		resp, err := skillClient.ListSkills(ctx, &srgrpcpb.ListSkillsRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, fmt.Errorf("could not list skills: %w", err)
+		}
+		for _, s := range resp.GetSkills() {
+			state.skills[s.GetId()] = s.GetIdVersion()
+		}
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+
+	rrClient := rrgrpcpb.NewResourceRegistryClient(conn)
+	for pageToken := ""; ; {
+		resp, err := rrClient.ListServices(ctx, &rrgrpcpb.ListServicesRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, fmt.Errorf("could not list services: %w", err)
+		}
+		for _, s := range resp.GetServices() {
+			idVersion, err := idutils.IDVersionFromProto(s.GetMetadata().GetIdVersion())
+			if err != nil {
+				return nil, fmt.Errorf("registry returned invalid id_version: %w", err)
+			}
+			id, err := idutils.RemoveVersionFrom(idVersion)
+			if err != nil {
+				return nil, err
+			}
+			state.services[id] = idVersion
+		}
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+	for pageToken := ""; ; {
+		resp, err := rrClient.ListResourceInstances(ctx, &rrgrpcpb.ListResourceInstanceRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, fmt.Errorf("could not list resource instances: %w", err)
+		}
+		for _, inst := range resp.GetInstances() {
+			state.instances[inst.GetName()] = inst.GetTypeId()
+			state.configs[inst.GetName()] = inst.GetConfiguration()
+		}
+		if pageToken = resp.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+
+	bt, err := processclient.New(conn).Get(ctx, processclient.GetOptions{})
+	if err != nil && !errors.Is(err, processclient.ErrNoProcess) {
+		return nil, fmt.Errorf("could not get the active process: %w", err)
+	}
+	state.process = bt
+	return state, nil
+}
+
+func readConfig(path string) (*anypb.Any, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration proto file %s: %w", path, err)
+	}
+	cfg := &anypb.Any{}
+	if err := proto.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("could not unmarshal configuration proto %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// sameProcess reports whether two behavior trees are equal, ignoring the tree
+// and node ids which the executive assigns.
+func sameProcess(a, b *btpb.BehaviorTree) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	a = proto.Clone(a).(*btpb.BehaviorTree)
+	b = proto.Clone(b).(*btpb.BehaviorTree)
+	processclient.ClearTree(a, true, true)
+	processclient.ClearTree(b, true, true)
+	return proto.Equal(a, b)
+}
+
+// plan computes the actions which move the cluster from state towards def.
+// tree is the behavior tree parsed from the process of def, if any.  Skills
+// and service instances which are not part of the definition are only removed
+// if prune is set.  Instances of resources other than services are never
+// removed.
+func plan(def *Definition, tree *btpb.BehaviorTree, state *clusterState, prune bool) ([]*action, error) {
+	var actions []*action
+
+	desiredSkills := make(map[string]bool)
+	for _, idVersion := range def.Skills {
+		id, err := idutils.RemoveVersionFrom(idVersion)
+		if err != nil {
+			return nil, err
+		}
+		desiredSkills[id] = true
+		switch installed, ok := state.skills[id]; {
+		case !ok:
+			actions = append(actions, &action{kind: actionAdd, skill: idVersion})
+		case installed != idVersion:
+			actions = append(actions, &action{kind: actionReplace, skill: idVersion, from: installed})
+		}
+	}
+	if prune {
+		var ids []string
+		for id := range state.skills {
+			if !desiredSkills[id] {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			actions = append(actions, &action{kind: actionRemove, skill: id})
+		}
+	}
+
+	desiredInstances := make(map[string]bool)
+	for i := range def.Services {
+		s := &def.Services[i]
+		desiredInstances[s.Name] = true
+		cfg, err := readConfig(s.Config)
+		if err != nil {
+			return nil, err
+		}
+		id, err := idutils.RemoveVersionFrom(s.IDVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		typeID, ok := state.instances[s.Name]
+		switch {
+		case !ok:
+			actions = append(actions, &action{kind: actionAdd, service: s, config: cfg})
+		case typeID != id:
+			actions = append(actions, &action{kind: actionReplace, service: s, config: cfg, from: typeID})
+		case state.services[id] != s.IDVersion:
+			if cfg == nil {
+				// Updating a service replaces its configuration, keep the current one.
+				cfg = state.configs[s.Name]
+			}
+			actions = append(actions, &action{kind: actionUpdate, service: s, config: cfg, from: state.services[id]})
+		case cfg != nil && !proto.Equal(cfg, state.configs[s.Name]):
+			actions = append(actions, &action{kind: actionUpdate, service: s, config: cfg, from: "a different configuration"})
+		}
+	}
+	if prune {
+		var names []string
+		for name, typeID := range state.instances {
+			if _, isService := state.services[typeID]; isService && !desiredInstances[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			actions = append(actions, &action{kind: actionRemove, service: &ServiceInstance{Name: name}})
+		}
+	}
+
+	if def.Process != nil && !sameProcess(tree, state.process) {
+		actions = append(actions, &action{kind: actionSetProcess, process: def.Process, tree: tree})
+	}
+
+	// Removals go first so that names and resources are free again, the
+	// process goes last so that all skills it uses are installed.
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actionOrder(actions[i]) < actionOrder(actions[j])
+	})
+	return actions, nil
+}
+
+func actionOrder(a *action) int {
+	switch {
+	case a.kind == actionRemove:
+		return 0
+	case a.kind == actionSetProcess:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// deployer applies the actions of a plan.
+type deployer struct {
+	conn    *grpc.ClientConn
+	client  adgrpcpb.AssetDeploymentServiceClient
+	authCtx context.Context
+}
+
+func (d *deployer) wait(ctx context.Context, op *lropb.Operation, desc string) error {
+	op, err := lroutil.WaitWithBackoff(ctx, d.client, op, lroutil.WaitOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to check status of operation to %s: %w", desc, err)
+	}
+	if err := op.GetError(); err != nil {
+		return fmt.Errorf("failed to %s: %v", desc, err)
+	}
+	return nil
+}
+
+func (d *deployer) deleteSkill(ctx context.Context, id string) error {
+	op, err := d.client.DeleteSkill(ctx, &adgrpcpb.DeleteSkillRequest{SkillId: id})
+	if err != nil {
+		return fmt.Errorf("could not remove skill %q: %w", id, err)
+	}
+	return d.wait(ctx, op, fmt.Sprintf("remove skill %q", id))
+}
+
+func (d *deployer) installSkill(ctx context.Context, idVersion string) error {
+	op, err := d.client.CreateSkillFromCatalog(d.authCtx, &adgrpcpb.CreateSkillFromCatalogRequest{IdVersion: idVersion})
+	if err != nil {
+		return fmt.Errorf("could not install skill %q: %w", idVersion, err)
+	}
+	return d.wait(ctx, op, fmt.Sprintf("install skill %q", idVersion))
+}
+
+func (d *deployer) deleteService(ctx context.Context, name string) error {
+	op, err := d.client.DeleteResource(ctx, &adgrpcpb.DeleteResourceRequest{
+		Name:             name,
+		DeletionStrategy: adgrpcpb.DeleteResourceRequest_DELETE_INSTANCE_ONLY,
+	})
+	if err != nil {
+		return fmt.Errorf("could not remove service %q: %w", name, err)
+	}
+	return d.wait(ctx, op, fmt.Sprintf("remove service %q", name))
+}
+
+func (d *deployer) addService(ctx context.Context, s *ServiceInstance, cfg *anypb.Any) error {
+	op, err := d.client.CreateResourceFromCatalog(d.authCtx, &adgrpcpb.CreateResourceFromCatalogRequest{
+		TypeIdVersion: s.IDVersion,
+		Configuration: &adgrpcpb.ResourceInstanceConfiguration{
+			Name:          s.Name,
+			Configuration: cfg,
+		},
+		AssetType: atpb.AssetType_ASSET_TYPE_SERVICE,
+	})
+	if err != nil {
+		return fmt.Errorf("could not add service %q of id version %q: %w", s.Name, s.IDVersion, err)
+	}
+	return d.wait(ctx, op, fmt.Sprintf("add service %q", s.Name))
+}
+
+// updateService updates the service instance s to its id_version.  cfg replaces the current
+// configuration of the instance, so it has to be the current configuration to keep it.
+func (d *deployer) updateService(ctx context.Context, s *ServiceInstance, cfg *anypb.Any) error {
+	p, err := idutils.NewIDVersionParts(s.IDVersion)
+	if err != nil {
+		return err
+	}
+	op, err := d.client.UpdateResource(d.authCtx, &adgrpcpb.UpdateResourceRequest{
+		Resource: &adgrpcpb.Resource{
+			Name:          s.Name,
+			IdVersion:     p.IDVersionProto(),
+			Configuration: &adgrpcpb.ResourceConfiguration{Configuration: cfg},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not update service %q: %w", s.Name, err)
+	}
+	return d.wait(ctx, op, fmt.Sprintf("update service %q", s.Name))
+}
+
+func (d *deployer) apply(ctx context.Context, a *action) error {
+	switch {
+	case a.kind == actionSetProcess:
+		return processclient.New(d.conn).Set(ctx, a.tree, processclient.SetOptions{})
+	case a.skill != "":
+		switch a.kind {
+		case actionRemove:
+			return d.deleteSkill(ctx, a.skill)
+		case actionReplace:
+			id, err := idutils.RemoveVersionFrom(a.skill)
+			if err != nil {
+				return err
+			}
+			if err := d.deleteSkill(ctx, id); err != nil {
+				return err
+			}
+		}
+		return d.installSkill(ctx, a.skill)
+	default:
+		switch a.kind {
+		case actionRemove:
+			return d.deleteService(ctx, a.service.Name)
+		case actionUpdate:
+			return d.updateService(ctx, a.service, a.config)
+		case actionReplace:
+			if err := d.deleteService(ctx, a.service.Name); err != nil {
+				return err
+			}
+		}
+		return d.addService(ctx, a.service, a.config)
+	}
+}
+
+func printPlan(cmd *cobra.Command, actions []*action) {
+	out := cmd.OutOrStdout()
+	if len(actions) == 0 {
+		fmt.Fprintln(out, "The cluster is up to date.")
+		return
+	}
+	lines := make([]string, len(actions))
+	for i, a := range actions {
+		lines[i] = a.String()
+	}
+	fmt.Fprintf(out, "Plan (%d actions):\n%s\n", len(actions), strings.Join(lines, "\n"))
+}
+
+var deployFlags = cmdutils.NewCmdFlagsWithViper(viperLocal)
+
+// resolveSkillsFromCatalog resolves the skills of def which are given with a
+// version constraint against the released versions in the catalog.
+func resolveSkillsFromCatalog(cmd *cobra.Command, def *Definition) error {
+	conn, err := clientutils.DialCatalogFromInctl(cmd, deployFlags)
+	if err != nil {
+		return fmt.Errorf("could not create connection to the catalog: %w", err)
+	}
+	defer conn.Close()
+
+	client := skillcataloggrpcpb.NewSkillCatalogClient(conn)
+	constrained := append([]string(nil), def.Skills...)
+	err = def.resolveSkills(func(id string) ([]string, error) {
+		released, err := listutil.ListReleasedVersions(cmd.Context(), client, id)
+		if err != nil {
+			return nil, err
+		}
+		var versions []string
+		for _, s := range released.Skills {
+			versions = append(versions, s.Version)
+		}
+		return versions, nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, skill := range def.Skills {
+		if skill != constrained[i] {
+			fmt.Fprintf(cmd.OutOrStdout(), "Resolved %s to %s\n", constrained[i], skill)
+		}
+	}
+	return nil
+}
+
+var solutionDeployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploys a solution definition to a cluster",
+	Long: `Reconciles the assets and the process of a running solution with a solution definition.
+
+The definition lists the skills and service instances the solution should contain and optionally a
+process. The plan of required changes is printed and has to be confirmed before it is applied. Use
+--dry_run to only print the plan and --yes to apply it without confirmation. Skills and service instances which are not part of the definition are only removed with
+--prune.
+
+Skills can also be given as "id@constraint", e.g., "ai.intrinsic.move_robot@^0.1" or
+"ai.intrinsic.move_robot@>=0.1.0 <0.3.0". They are resolved to the latest released version in the
+catalog which satisfies the constraint.
+
+Example definition (solution.yaml):
+  skills:
+    - ai.intrinsic.move_robot.0.1.0
+    - ai.intrinsic.open_gripper@^1.2
+  services:
+    - name: camera
+      id_version: ai.intrinsic.basler_camera.0.2.0
+      config: camera_config.binpb
+  process:
+    file: process.textproto
+
+Example:
+  inctl solution deploy --file solution.yaml --org my-org --cluster my-cluster`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		def, err := ReadDefinition(deployFlags.GetString(keyFile))
+		if err != nil {
+			return err
+		}
+
+		if def.hasSkillConstraints() {
+			if err := resolveSkillsFromCatalog(cmd, def); err != nil {
+				return err
+			}
+		}
+
+		ctx, conn, address, err := clientutils.DialClusterFromInctl(cmd.Context(), deployFlags)
+		if err != nil {
+			return fmt.Errorf("could not create connection to cluster: %w", err)
+		}
+		defer conn.Close()
+
+		var tree *btpb.BehaviorTree
+		if def.Process != nil {
+			content, err := os.ReadFile(def.Process.File)
+			if err != nil {
+				return fmt.Errorf("could not read process: %w", err)
+			}
+			if tree, err = process.ParseProcess(ctx, conn, def.Process.Format, content); err != nil {
+				return fmt.Errorf("invalid process %q: %w", def.Process.File, err)
+			}
+		}
+
+		state, err := readClusterState(ctx, conn)
+		if err != nil {
+			return err
+		}
+		actions, err := plan(def, tree, state, deployFlags.GetBool(keyPrune))
+		if err != nil {
+			return err
+		}
+		printPlan(cmd, actions)
+		if deployFlags.GetFlagDryRun() || len(actions) == 0 {
+			return nil
+		}
+		if err := deployFlags.Confirm(fmt.Sprintf("Apply %d actions to the cluster", len(actions))); err != nil {
+			return err
+		}
+
+		d := &deployer{
+			conn:   conn,
+			client: adgrpcpb.NewAssetDeploymentServiceClient(conn),
+			// This needs an authorized context to pull from the catalog if not available.
+			authCtx: clientutils.AuthInsecureConn(ctx, address, deployFlags.GetFlagProject()),
+		}
+		for i, a := range actions {
+			fmt.Fprintf(cmd.OutOrStdout(), "[%d/%d] %s\n", i+1, len(actions), strings.TrimSpace(a.String()))
+			if err := d.apply(ctx, a); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Deployment finished.")
+		return nil
+	},
+}
+
+func init() {
+	solutionCmd.AddCommand(solutionDeployCmd)
+
+	deployFlags.SetCommand(solutionDeployCmd)
+	deployFlags.AddFlagsAddressClusterSolution()
+	deployFlags.AddFlagDryRun()
+	deployFlags.AddFlagYes()
+	deployFlags.RequiredString(keyFile, "Path to the solution definition (YAML or JSON).")
+	deployFlags.OptionalBool(keyPrune, false, "Remove skills and service instances which are not part of the definition.")
+}
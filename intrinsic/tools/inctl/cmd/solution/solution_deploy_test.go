@@ -0,0 +1,303 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package solution
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	wrapperspb "google.golang.org/protobuf/types/known/wrapperspb"
+	btpb "intrinsic/executive/proto/behavior_tree_go_proto"
+)
+
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("WriteFile(%q) failed: %v", path, err)
+	}
+}
+
+func TestReadDefinition(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *Definition
+		wantErr string
+	}{
+		{
+			name: "full",
+			content: `
+skills:
+  - ai.intrinsic.move_robot.0.1.0
+services:
+  - name: camera
+    id_version: ai.intrinsic.basler_camera.0.2.0
+    config: camera_config.binpb
+process:
+  file: /abs/process.textproto
+`,
+			want: &Definition{
+				Skills: []string{"ai.intrinsic.move_robot.0.1.0"},
+				Services: []ServiceInstance{{
+					Name:      "camera",
+					IDVersion: "ai.intrinsic.basler_camera.0.2.0",
+					Config:    "camera_config.binpb",
+				}},
+				Process: &ProcessDefinition{File: "/abs/process.textproto", Format: "textproto"},
+			},
+		},
+		{
+			name:    "unknown field",
+			content: "skillz: []",
+			wantErr: "invalid solution definition",
+		},
+		{
+			name:    "invalid skill",
+			content: "skills: [ai.intrinsic.move_robot]",
+			wantErr: "invalid skill",
+		},
+		{
+			name:    "duplicate skill",
+			content: "skills: [ai.intrinsic.move_robot.0.1.0, ai.intrinsic.move_robot.0.2.0]",
+			wantErr: "listed more than once",
+		},
+		{
+			name:    "skill with version constraint",
+			content: "skills: ['ai.intrinsic.move_robot@>=0.1.0 <0.3.0']",
+			want:    &Definition{Skills: []string{"ai.intrinsic.move_robot@>=0.1.0 <0.3.0"}},
+		},
+		{
+			name:    "invalid version constraint",
+			content: "skills: [ai.intrinsic.move_robot@>=latest]",
+			wantErr: "invalid version constraint",
+		},
+		{
+			name:    "duplicate skill with version constraint",
+			content: "skills: [ai.intrinsic.move_robot.0.1.0, ai.intrinsic.move_robot@^0.1]",
+			wantErr: "listed more than once",
+		},
+		{
+			name:    "service without name",
+			content: "services: [{id_version: ai.intrinsic.camera.0.1.0}]",
+			wantErr: "has no name",
+		},
+		{
+			name: "duplicate service instance",
+			content: `
+services:
+  - {name: camera, id_version: ai.intrinsic.camera.0.1.0}
+  - {name: camera, id_version: ai.intrinsic.other_camera.0.1.0}
+`,
+			wantErr: "listed more than once",
+		},
+		{
+			name:    "invalid service",
+			content: "services: [{name: camera, id_version: camera}]",
+			wantErr: "invalid service",
+		},
+		{
+			name:    "process without file",
+			content: "process: {format: textproto}",
+			wantErr: "must specify a file",
+		},
+		{
+			name:    "unsupported process format",
+			content: "process: {file: process.py, format: python}",
+			wantErr: "unsupported process format",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "solution.yaml")
+			writeFile(t, path, []byte(tc.content))
+
+			got, err := ReadDefinition(path)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("ReadDefinition() returned error %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadDefinition() failed: %v", err)
+			}
+			// Relative paths are resolved against the directory of the definition.
+			for i := range tc.want.Services {
+				if c := tc.want.Services[i].Config; c != "" && !filepath.IsAbs(c) {
+					tc.want.Services[i].Config = filepath.Join(dir, c)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ReadDefinition() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveSkills(t *testing.T) {
+	released := map[string][]string{
+		"ai.intrinsic.move_robot":   {"0.3.0", "0.2.1", "0.2.0", "0.1.0"},
+		"ai.intrinsic.open_gripper": {"1.0.0"},
+	}
+	versions := func(id string) ([]string, error) {
+		return released[id], nil
+	}
+
+	def := &Definition{Skills: []string{
+		"ai.intrinsic.skill.0.1.0",
+		"ai.intrinsic.move_robot@>=0.1.0 <0.3.0",
+	}}
+	if err := def.resolveSkills(versions); err != nil {
+		t.Fatalf("resolveSkills() failed: %v", err)
+	}
+	want := []string{"ai.intrinsic.skill.0.1.0", "ai.intrinsic.move_robot.0.2.1"}
+	if diff := cmp.Diff(want, def.Skills); diff != "" {
+		t.Errorf("resolveSkills() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	def = &Definition{Skills: []string{"ai.intrinsic.open_gripper@^2"}}
+	if err := def.resolveSkills(versions); err == nil {
+		t.Errorf("resolveSkills() of an unsatisfiable constraint succeeded, want error")
+	}
+}
+
+func mustAny(t *testing.T, m proto.Message) *anypb.Any {
+	t.Helper()
+	a, err := anypb.New(m)
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	return a
+}
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	currentConfig := mustAny(t, wrapperspb.String("current"))
+	newConfig := mustAny(t, wrapperspb.String("new"))
+	configPath := filepath.Join(dir, "config.binpb")
+	b, err := proto.Marshal(newConfig)
+	if err != nil {
+		t.Fatalf("proto.Marshal() failed: %v", err)
+	}
+	writeFile(t, configPath, b)
+
+	tree := &btpb.BehaviorTree{Name: "process"}
+	processDef := &ProcessDefinition{File: "process.textproto", Format: "textproto"}
+	newState := func() *clusterState {
+		return &clusterState{
+			skills:    map[string]string{"ai.intrinsic.skill": "ai.intrinsic.skill.0.1.0"},
+			services:  map[string]string{"ai.intrinsic.camera": "ai.intrinsic.camera.0.1.0"},
+			instances: map[string]string{"camera": "ai.intrinsic.camera"},
+			configs:   map[string]*anypb.Any{"camera": currentConfig},
+			process:   &btpb.BehaviorTree{Name: "process", TreeId: proto.String("assigned")},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		def   *Definition
+		tree  *btpb.BehaviorTree
+		prune bool
+		want  []*action
+	}{
+		{
+			name: "no-op",
+			def: &Definition{
+				Skills:   []string{"ai.intrinsic.skill.0.1.0"},
+				Services: []ServiceInstance{{Name: "camera", IDVersion: "ai.intrinsic.camera.0.1.0"}},
+				Process:  processDef,
+			},
+			tree: tree,
+		},
+		{
+			name: "add",
+			def: &Definition{
+				Skills:   []string{"ai.intrinsic.skill.0.1.0", "ai.intrinsic.other_skill.0.1.0"},
+				Services: []ServiceInstance{{Name: "other_camera", IDVersion: "ai.intrinsic.camera.0.1.0", Config: configPath}},
+			},
+			want: []*action{
+				{kind: actionAdd, skill: "ai.intrinsic.other_skill.0.1.0"},
+				{kind: actionAdd, service: &ServiceInstance{Name: "other_camera", IDVersion: "ai.intrinsic.camera.0.1.0", Config: configPath}, config: newConfig},
+			},
+		},
+		{
+			name: "replace",
+			def: &Definition{
+				Skills:   []string{"ai.intrinsic.skill.0.2.0"},
+				Services: []ServiceInstance{{Name: "camera", IDVersion: "ai.intrinsic.other_camera.0.1.0"}},
+			},
+			want: []*action{
+				{kind: actionReplace, skill: "ai.intrinsic.skill.0.2.0", from: "ai.intrinsic.skill.0.1.0"},
+				{kind: actionReplace, service: &ServiceInstance{Name: "camera", IDVersion: "ai.intrinsic.other_camera.0.1.0"}, from: "ai.intrinsic.camera"},
+			},
+		},
+		{
+			name: "update version and config",
+			def: &Definition{
+				Services: []ServiceInstance{{Name: "camera", IDVersion: "ai.intrinsic.camera.0.2.0", Config: configPath}},
+			},
+			want: []*action{
+				{kind: actionUpdate, service: &ServiceInstance{Name: "camera", IDVersion: "ai.intrinsic.camera.0.2.0", Config: configPath}, config: newConfig, from: "ai.intrinsic.camera.0.1.0"},
+			},
+		},
+		{
+			name: "update config",
+			def: &Definition{
+				Services: []ServiceInstance{{Name: "camera", IDVersion: "ai.intrinsic.camera.0.1.0", Config: configPath}},
+			},
+			want: []*action{
+				{kind: actionUpdate, service: &ServiceInstance{Name: "camera", IDVersion: "ai.intrinsic.camera.0.1.0", Config: configPath}, config: newConfig, from: "a different configuration"},
+			},
+		},
+		{
+			name: "nil config keeps the current config",
+			def: &Definition{
+				Services: []ServiceInstance{{Name: "camera", IDVersion: "ai.intrinsic.camera.0.2.0"}},
+			},
+			want: []*action{
+				{kind: actionUpdate, service: &ServiceInstance{Name: "camera", IDVersion: "ai.intrinsic.camera.0.2.0"}, config: currentConfig, from: "ai.intrinsic.camera.0.1.0"},
+			},
+		},
+		{
+			name: "set changed process",
+			def:  &Definition{Process: processDef},
+			tree: &btpb.BehaviorTree{Name: "other process"},
+			want: []*action{
+				{kind: actionSetProcess, process: processDef, tree: &btpb.BehaviorTree{Name: "other process"}},
+			},
+		},
+		{
+			name:  "prune",
+			def:   &Definition{},
+			prune: true,
+			want: []*action{
+				{kind: actionRemove, skill: "ai.intrinsic.skill"},
+				{kind: actionRemove, service: &ServiceInstance{Name: "camera"}},
+			},
+		},
+		{
+			name: "without prune",
+			def:  &Definition{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := plan(tc.def, tc.tree, newState(), tc.prune)
+			if err != nil {
+				t.Fatalf("plan() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(action{}), protocmp.Transform()); diff != "" {
+				t.Errorf("plan() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package status contains commands to inspect (extended) status reports.
+package status
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	rpcpb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+	estpb "intrinsic/util/status/extended_status_go_proto"
+	sspb "intrinsic/util/status/status_specs_go_proto"
+)
+
+const indent = "  "
+
+var (
+	flagStatusSpecs []string
+
+	protoNameExtendedStatus = proto.MessageName(new(estpb.ExtendedStatus))
+)
+
+type specKey struct {
+	component string
+	code      uint32
+}
+
+// decodedStatus is a decoded google.rpc.Status or ExtendedStatus.
+type decodedStatus struct {
+	// status is nil if an ExtendedStatus was decoded directly.
+	status   *rpcpb.Status
+	extended []*estpb.ExtendedStatus
+	specs    map[specKey]*sspb.StatusSpec
+}
+
+// MarshalJSON implements json.Marshaler for --output=json.
+func (d *decodedStatus) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Status         json.RawMessage   `json:"status,omitempty"`
+		ExtendedStatus []json.RawMessage `json:"extendedStatus"`
+	}{}
+	if d.status != nil {
+		b, err := protojson.Marshal(d.status)
+		if err != nil {
+			return nil, err
+		}
+		out.Status = b
+	}
+	for _, es := range d.extended {
+		b, err := protojson.Marshal(es)
+		if err != nil {
+			return nil, err
+		}
+		out.ExtendedStatus = append(out.ExtendedStatus, b)
+	}
+	return json.Marshal(out)
+}
+
+// String pretty-prints the status for --output=text.
+func (d *decodedStatus) String() string {
+	var b strings.Builder
+	if d.status != nil {
+		fmt.Fprintf(&b, "gRPC status: %s: %s\n", codes.Code(d.status.GetCode()), d.status.GetMessage())
+		for _, detail := range d.status.GetDetails() {
+			if !strings.HasSuffix(detail.GetTypeUrl(), "/"+string(protoNameExtendedStatus)) {
+				fmt.Fprintf(&b, "Detail: %s\n", detail.GetTypeUrl())
+			}
+		}
+	}
+	for _, es := range d.extended {
+		d.writeExtendedStatus(&b, es, "")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (d *decodedStatus) writeExtendedStatus(b *strings.Builder, es *estpb.ExtendedStatus, prefix string) {
+	code := es.GetStatusCode()
+	fmt.Fprintf(b, "%s%s:%d (%s): %s\n", prefix, code.GetComponent(), code.GetCode(), es.GetSeverity(), es.GetTitle())
+	prefix += indent
+	if es.GetTimestamp() != nil {
+		fmt.Fprintf(b, "%sTimestamp: %s\n", prefix, es.GetTimestamp().AsTime())
+	}
+	if spec, ok := d.specs[specKey{component: code.GetComponent(), code: code.GetCode()}]; ok {
+		if spec.GetTitle() != "" && spec.GetTitle() != es.GetTitle() {
+			fmt.Fprintf(b, "%sSpec title: %s\n", prefix, spec.GetTitle())
+		}
+		if spec.GetRecoveryInstructions() != "" {
+			fmt.Fprintf(b, "%sRecovery instructions: %s\n", prefix, spec.GetRecoveryInstructions())
+		}
+	}
+	writeReport(b, "External report", es.GetExternalReport(), prefix)
+	writeReport(b, "Internal report", es.GetInternalReport(), prefix)
+	writeDebugReport(b, es.GetDebugReport(), prefix)
+	if node := es.GetRelatedTo().GetBehaviorTreeNode(); node != nil {
+		fmt.Fprintf(b, "%sBehavior tree node: tree %q, node %d\n", prefix, node.GetTreeId(), node.GetNodeId())
+	}
+	if lc := es.GetRelatedTo().GetLogContext(); lc != nil {
+		fmt.Fprintf(b, "%sLog context: %s\n", prefix, prototext.MarshalOptions{}.Format(lc))
+	}
+	if len(es.GetContext()) > 0 {
+		fmt.Fprintf(b, "%sContext:\n", prefix)
+		for _, c := range es.GetContext() {
+			d.writeExtendedStatus(b, c, prefix+indent)
+		}
+	}
+}
+
+func writeReport(b *strings.Builder, name string, r *estpb.ExtendedStatus_Report, prefix string) {
+	if r == nil {
+		return
+	}
+	fmt.Fprintf(b, "%s%s:\n", prefix, name)
+	for _, line := range strings.Split(r.GetMessage(), "\n") {
+		fmt.Fprintf(b, "%s%s%s\n", prefix, indent, line)
+	}
+	if r.GetInstructions() != "" {
+		fmt.Fprintf(b, "%s%sInstructions: %s\n", prefix, indent, r.GetInstructions())
+	}
+	if key := r.GetMessageKey(); key != nil {
+		fmt.Fprintf(b, "%s%sMessage key: %s %v\n", prefix, indent, key.GetKey(), key.GetArgs())
+	}
+}
+
+func writeDebugReport(b *strings.Builder, r *estpb.ExtendedStatus_DebugReport, prefix string) {
+	if r == nil {
+		return
+	}
+	fmt.Fprintf(b, "%sDebug report:\n", prefix)
+	if r.GetMessage() != "" {
+		for _, line := range strings.Split(r.GetMessage(), "\n") {
+			fmt.Fprintf(b, "%s%s%s\n", prefix, indent, line)
+		}
+	}
+	if len(r.GetStackTrace()) > 0 {
+		fmt.Fprintf(b, "%s%sStack trace:\n", prefix, indent)
+		for _, f := range r.GetStackTrace() {
+			fmt.Fprintf(b, "%s%s%s%s\n", prefix, indent, indent, f.GetFunction())
+			fmt.Fprintf(b, "%s%s%s%s%s:%d\n", prefix, indent, indent, indent, f.GetFile(), f.GetLine())
+		}
+	}
+}
+
+// decode parses a google.rpc.Status or ExtendedStatus in JSON, base64 or binary wire format.
+func decode(data []byte) (*decodedStatus, error) {
+	// Only trim text formats, binary data may start or end with bytes that look like whitespace.
+	text := bytes.TrimSpace(data)
+	if len(text) == 0 {
+		return nil, fmt.Errorf("no status given")
+	}
+
+	if text[0] == '{' {
+		es := &estpb.ExtendedStatus{}
+		if err := protojson.Unmarshal(text, es); err == nil {
+			return &decodedStatus{extended: []*estpb.ExtendedStatus{es}}, nil
+		}
+		st := &rpcpb.Status{}
+		if err := protojson.Unmarshal(text, st); err != nil {
+			return nil, fmt.Errorf("could not parse JSON as google.rpc.Status or %s: %w", protoNameExtendedStatus, err)
+		}
+		return fromRPCStatus(st)
+	}
+
+	if decoded, ok := decodeBase64(text); ok {
+		data = decoded
+	}
+	st := &rpcpb.Status{}
+	if err := proto.Unmarshal(data, st); err == nil && !hasUnknownFields(st.ProtoReflect()) {
+		return fromRPCStatus(st)
+	}
+	es := &estpb.ExtendedStatus{}
+	if err := proto.Unmarshal(data, es); err == nil && !hasUnknownFields(es.ProtoReflect()) {
+		return &decodedStatus{extended: []*estpb.ExtendedStatus{es}}, nil
+	}
+	return nil, fmt.Errorf("could not parse input as google.rpc.Status or %s in JSON, base64 or binary format", protoNameExtendedStatus)
+}
+
+func decodeBase64(data []byte) ([]byte, bool) {
+	s := string(data)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// hasUnknownFields reports whether m or any message nested in it has unknown fields, which
+// indicates that the data was not of the message's type.
+func hasUnknownFields(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) > 0 {
+		return true
+	}
+	found := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind || fd.IsMap() {
+			return true
+		}
+		if fd.IsList() {
+			for i := 0; i < v.List().Len() && !found; i++ {
+				found = hasUnknownFields(v.List().Get(i).Message())
+			}
+		} else {
+			found = hasUnknownFields(v.Message())
+		}
+		return !found
+	})
+	return found
+}
+
+func fromRPCStatus(st *rpcpb.Status) (*decodedStatus, error) {
+	d := &decodedStatus{status: st}
+	for _, detail := range st.GetDetails() {
+		if !strings.HasSuffix(detail.GetTypeUrl(), "/"+string(protoNameExtendedStatus)) {
+			continue
+		}
+		es := &estpb.ExtendedStatus{}
+		if err := detail.UnmarshalTo(es); err != nil {
+			return nil, fmt.Errorf("could not parse %s detail: %w", protoNameExtendedStatus, err)
+		}
+		d.extended = append(d.extended, es)
+	}
+	return d, nil
+}
+
+// readSpecs reads status spec files in textproto or binary format.
+func readSpecs(paths []string) (map[specKey]*sspb.StatusSpec, error) {
+	specs := map[specKey]*sspb.StatusSpec{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read status specs: %w", err)
+		}
+		s := &sspb.StatusSpecs{}
+		switch filepath.Ext(path) {
+		case ".binarypb", ".pb":
+			err = proto.Unmarshal(data, s)
+		default:
+			err = prototext.Unmarshal(data, s)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse status specs %q: %w", path, err)
+		}
+		for _, spec := range s.GetStatusInfo() {
+			specs[specKey{component: s.GetComponent(), code: spec.GetCode()}] = spec
+		}
+	}
+	return specs, nil
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Inspects status reports",
+}
+
+var decodeCmd = &cobra.Command{
+	Use:   "decode [file]",
+	Short: "Pretty-prints a serialized google.rpc.Status or ExtendedStatus",
+	Long: `Pretty-prints a google.rpc.Status or ExtendedStatus, e.g., as found in logs or pasted into a
+ticket, including all nested context.
+
+The status is read from the given file or from stdin, in JSON, base64 or binary wire format. Status
+codes are matched against the status specs given with --status_specs to show their recovery
+instructions.`,
+	Example: `
+	$ inctl status decode status.json
+	$ echo "CAoSBWVycm9y..." | inctl status decode --status_specs=my_service_status_specs.textproto
+	`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if len(args) == 0 || args[0] == "-" {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("could not read status: %w", err)
+		}
+
+		d, err := decode(data)
+		if err != nil {
+			return err
+		}
+		if d.specs, err = readSpecs(flagStatusSpecs); err != nil {
+			return err
+		}
+
+		prtr, err := printer.NewPrinterWithWriter(root.FlagOutput, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		prtr.Print(d)
+		return nil
+	},
+}
+
+func init() {
+	decodeCmd.Flags().StringSliceVar(&flagStatusSpecs, "status_specs", nil,
+		"Status spec files (StatusSpecs in textproto or binary format) to match status codes against.")
+	statusCmd.AddCommand(decodeCmd)
+	root.RootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package status
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	rpcpb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	estpb "intrinsic/util/status/extended_status_go_proto"
+	sspb "intrinsic/util/status/status_specs_go_proto"
+)
+
+func testExtendedStatus() *estpb.ExtendedStatus {
+	return &estpb.ExtendedStatus{
+		StatusCode: &estpb.StatusCode{Component: "ai.intrinsic.my_service", Code: 2343},
+		Severity:   estpb.ExtendedStatus_ERROR,
+		Title:      "Failed to move",
+		ExternalReport: &estpb.ExtendedStatus_Report{
+			Message: "The robot could not reach the pose",
+		},
+		Context: []*estpb.ExtendedStatus{{
+			StatusCode: &estpb.StatusCode{Component: "ai.intrinsic.planner", Code: 12},
+			Title:      "No solution",
+		}},
+	}
+}
+
+func testRPCStatus(t *testing.T) *rpcpb.Status {
+	t.Helper()
+	detail, err := anypb.New(testExtendedStatus())
+	if err != nil {
+		t.Fatalf("anypb.New() failed: %v", err)
+	}
+	return &rpcpb.Status{
+		Code:    int32(codes.Internal),
+		Message: "Failed to move",
+		Details: []*anypb.Any{detail},
+	}
+}
+
+func TestDecode(t *testing.T) {
+	rpcBinary, err := proto.Marshal(testRPCStatus(t))
+	if err != nil {
+		t.Fatalf("proto.Marshal() failed: %v", err)
+	}
+	rpcJSON, err := protojson.Marshal(testRPCStatus(t))
+	if err != nil {
+		t.Fatalf("protojson.Marshal() failed: %v", err)
+	}
+	esBinary, err := proto.Marshal(testExtendedStatus())
+	if err != nil {
+		t.Fatalf("proto.Marshal() failed: %v", err)
+	}
+	esJSON, err := protojson.Marshal(testExtendedStatus())
+	if err != nil {
+		t.Fatalf("protojson.Marshal() failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantStatus bool
+	}{
+		{name: "rpc binary", data: rpcBinary, wantStatus: true},
+		{name: "rpc base64", data: []byte(base64.StdEncoding.EncodeToString(rpcBinary)), wantStatus: true},
+		{name: "rpc json", data: rpcJSON, wantStatus: true},
+		{name: "extended binary", data: esBinary},
+		{name: "extended base64", data: []byte(base64.StdEncoding.EncodeToString(esBinary) + "\n")},
+		{name: "extended json", data: esJSON},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := decode(tc.data)
+			if err != nil {
+				t.Fatalf("decode() failed: %v", err)
+			}
+			if got := d.status != nil; got != tc.wantStatus {
+				t.Errorf("decode() returned google.rpc.Status = %v, want %v", got, tc.wantStatus)
+			}
+			if len(d.extended) != 1 || !proto.Equal(d.extended[0], testExtendedStatus()) {
+				t.Errorf("decode() returned extended status %v, want %v", d.extended, testExtendedStatus())
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, data := range []string{"", "{not json", "\xff\xff\xff"} {
+		if _, err := decode([]byte(data)); err == nil {
+			t.Errorf("decode(%q) succeeded, want error", data)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	d, err := fromRPCStatus(testRPCStatus(t))
+	if err != nil {
+		t.Fatalf("fromRPCStatus() failed: %v", err)
+	}
+	d.specs = map[specKey]*sspb.StatusSpec{
+		{component: "ai.intrinsic.planner", code: 12}: {RecoveryInstructions: "Move the obstacle"},
+	}
+
+	got := d.String()
+	for _, want := range []string{
+		"gRPC status: Internal: Failed to move",
+		"ai.intrinsic.my_service:2343 (ERROR): Failed to move",
+		"    The robot could not reach the pose",
+		"    ai.intrinsic.planner:12 (DEFAULT): No solution",
+		"      Recovery instructions: Move the obstacle",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("String() = %q, want it to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package world contains commands to inspect and edit the belief world of a deployed solution.
+package world
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	pointpb "intrinsic/math/proto/point_go_proto"
+	posepb "intrinsic/math/proto/pose_go_proto"
+	quaternionpb "intrinsic/math/proto/quaternion_go_proto"
+	"intrinsic/skills/tools/skill/cmd/dialerutil"
+	"intrinsic/skills/tools/skill/cmd/solutionutil"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/orgutil"
+	owrpb "intrinsic/world/proto/object_world_refs_go_proto"
+)
+
+const (
+	// defaultWorldID is the id of the belief world of a solution.
+	defaultWorldID = "world"
+)
+
+var (
+	flagServerAddress string
+	flagSolutionName  string
+	flagClusterName   string
+	flagWorldID       string
+)
+
+var (
+	viperLocal = viper.New()
+)
+
+// connectToCluster dials the cluster given by the flags, resolving --solution to the cluster it is
+// running on.
+func connectToCluster(ctx context.Context) (context.Context, *grpc.ClientConn, error) {
+	projectName := viperLocal.GetString(orgutil.KeyProject)
+	orgName := viperLocal.GetString(orgutil.KeyOrganization)
+	clusterName := flagClusterName
+	if flagSolutionName != "" {
+		// Look up solution name via cloud portal.
+		ctx, conn, err := dialerutil.DialConnectionCtx(ctx, dialerutil.DialInfoParams{
+			CredName: projectName,
+			CredOrg:  orgName,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create client connection: %w", err)
+		}
+		defer conn.Close()
+
+		if clusterName, err = solutionutil.GetClusterNameFromSolution(ctx, conn, flagSolutionName); err != nil {
+			return nil, nil, fmt.Errorf("could not resolve solution to cluster: %w", err)
+		}
+	}
+
+	ctx, conn, err := dialerutil.DialConnectionCtx(ctx, dialerutil.DialInfoParams{
+		Address:  flagServerAddress,
+		Cluster:  clusterName,
+		CredName: projectName,
+		CredOrg:  orgName,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client connection: %w", err)
+	}
+	return ctx, conn, nil
+}
+
+// protoOutput prints a proto as textproto for --output=text and as JSON for --output=json.
+type protoOutput struct {
+	m proto.Message
+}
+
+// MarshalJSON implements json.Marshaler for --output=json.
+func (p protoOutput) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(p.m)
+}
+
+func (p protoOutput) String() string {
+	return strings.TrimSuffix(prototext.MarshalOptions{Multiline: true, Indent: "  "}.Format(p.m), "\n")
+}
+
+// objectRef references an object by its globally unique name.
+func objectRef(name string) *owrpb.ObjectReference {
+	return &owrpb.ObjectReference{
+		ObjectReference: &owrpb.ObjectReference_ByName{
+			ByName: &owrpb.ObjectReferenceByName{ObjectName: name},
+		},
+	}
+}
+
+// transformNodeRef references an object by name, or a frame as OBJECT/FRAME.
+func transformNodeRef(node string) *owrpb.TransformNodeReference {
+	byName := &owrpb.TransformNodeReferenceByName{}
+	if object, frame, ok := strings.Cut(node, "/"); ok {
+		byName.TransformNodeReferenceByName = &owrpb.TransformNodeReferenceByName_Frame{
+			Frame: &owrpb.FrameReferenceByName{ObjectName: object, FrameName: frame},
+		}
+	} else {
+		byName.TransformNodeReferenceByName = &owrpb.TransformNodeReferenceByName_Object{
+			Object: &owrpb.ObjectReferenceByName{ObjectName: node},
+		}
+	}
+	return &owrpb.TransformNodeReference{
+		TransformNodeReference: &owrpb.TransformNodeReference_ByName{ByName: byName},
+	}
+}
+
+// parseFloats parses a comma separated list of exactly n numbers.
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("%q has %d values, want %d", s, len(parts), n)
+	}
+	values := make([]float64, n)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a list of numbers: %w", s, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// updatePose overrides the position ("x,y,z") and/or orientation ("qx,qy,qz,qw") of pose if they
+// are non-empty.
+func updatePose(pose *posepb.Pose, position string, orientation string) error {
+	if position != "" {
+		v, err := parseFloats(position, 3)
+		if err != nil {
+			return fmt.Errorf("invalid position: %w", err)
+		}
+		pose.Position = &pointpb.Point{X: v[0], Y: v[1], Z: v[2]}
+	}
+	if orientation != "" {
+		v, err := parseFloats(orientation, 4)
+		if err != nil {
+			return fmt.Errorf("invalid orientation: %w", err)
+		}
+		pose.Orientation = &quaternionpb.Quaternion{X: v[0], Y: v[1], Z: v[2], W: v[3]}
+	}
+	return nil
+}
+
+// formatPose formats a pose in the format accepted by updatePose.
+func formatPose(pose *posepb.Pose) string {
+	p, q := pose.GetPosition(), pose.GetOrientation()
+	return fmt.Sprintf("position=%g,%g,%g orientation=%g,%g,%g,%g", p.GetX(), p.GetY(), p.GetZ(), q.GetX(), q.GetY(), q.GetZ(), q.GetW())
+}
+
+var worldCmd = orgutil.WrapCmd(&cobra.Command{
+	Use:   "world",
+	Short: "Inspects and edits the belief world of a solution",
+	Long: `Inspects and edits the belief world of a deployed solution, e.g., to script calibration and
+frame adjustments.
+
+Objects are referenced by their unique name and frames as OBJECT/FRAME.`,
+}, viperLocal)
+
+func init() {
+	worldCmd.PersistentFlags().StringVar(&flagSolutionName, "solution", "", "Solution whose world to access.")
+	worldCmd.PersistentFlags().StringVar(&flagClusterName, "cluster", "", "Cluster whose world to access.")
+	worldCmd.PersistentFlags().StringVar(&flagServerAddress, "server", "", "Server address of the cluster. Format is {ADDRESS}:{PORT}, for example 'localhost:17080'")
+	worldCmd.PersistentFlags().StringVar(&flagWorldID, "world_id", defaultWorldID, "Id of the world to access.")
+	root.RootCmd.AddCommand(worldCmd)
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package world
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+	owsgrpcpb "intrinsic/world/proto/object_world_service_go_grpc_proto"
+	owupb "intrinsic/world/proto/object_world_updates_go_proto"
+)
+
+var worldGetCmd = &cobra.Command{
+	Use:   "get [OBJECT]",
+	Short: "Gets an object or the whole world",
+	Long: `Gets an object of the world with all its details, including its pose relative to its parent
+and its frames. Without an object, all objects of the world are returned.`,
+	Example: `
+	$ inctl world get --solution my-solution-id
+	$ inctl world get camera_0 --solution my-solution-id --output json
+	`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, conn, err := connectToCluster(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("could not dial connection: %w", err)
+		}
+		defer conn.Close()
+		client := owsgrpcpb.NewObjectWorldServiceClient(conn)
+
+		var out protoOutput
+		if len(args) == 0 {
+			resp, err := client.ListObjects(ctx, &owsgrpcpb.ListObjectsRequest{
+				WorldId: flagWorldID,
+				View:    owupb.ObjectView_FULL,
+			})
+			if err != nil {
+				return fmt.Errorf("could not list objects of world %q: %w", flagWorldID, err)
+			}
+			out.m = resp
+		} else {
+			obj, err := client.GetObject(ctx, &owsgrpcpb.GetObjectRequest{
+				WorldId:     flagWorldID,
+				ObjectQuery: &owsgrpcpb.GetObjectRequest_Object{Object: objectRef(args[0])},
+				View:        owupb.ObjectView_FULL,
+			})
+			if err != nil {
+				return fmt.Errorf("could not get object %q: %w", args[0], err)
+			}
+			out.m = obj
+		}
+
+		prtr, err := printer.NewPrinterWithWriter(root.FlagOutput, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		prtr.Print(out)
+		return nil
+	},
+}
+
+func init() {
+	worldCmd.AddCommand(worldGetCmd)
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package world
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+	owsgrpcpb "intrinsic/world/proto/object_world_service_go_grpc_proto"
+)
+
+// frameList prints frames as a table for --output=text.
+type frameList struct {
+	resp *owsgrpcpb.ListFramesResponse
+}
+
+// MarshalJSON implements json.Marshaler for --output=json.
+func (l frameList) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(l.resp)
+}
+
+func (l frameList) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 1, 1, 2, ' ', 0)
+	fmt.Fprintln(w, "FRAME\tPARENT\tPOSE")
+	for _, f := range l.resp.GetFrames() {
+		parent := f.GetObject().GetName()
+		if pf := f.GetParentFrame(); pf != nil {
+			parent += "/" + pf.GetName()
+		}
+		fmt.Fprintf(w, "%s/%s\t%s\t%s\n", f.GetObject().GetName(), f.GetName(), parent, formatPose(f.GetParentTThis()))
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+var worldListFramesCmd = &cobra.Command{
+	Use:   "list-frames [OBJECT]",
+	Short: "Lists the frames of an object or the whole world",
+	Long: `Lists frames as OBJECT/FRAME together with their parent and their pose relative to the
+parent. Without an object, the frames of all objects of the world are listed.`,
+	Example: `
+	$ inctl world list-frames --solution my-solution-id
+	$ inctl world list-frames workpiece --solution my-solution-id --output json
+	`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, conn, err := connectToCluster(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("could not dial connection: %w", err)
+		}
+		defer conn.Close()
+
+		req := &owsgrpcpb.ListFramesRequest{WorldId: flagWorldID}
+		if len(args) == 1 {
+			req.Object = objectRef(args[0])
+		}
+		resp, err := owsgrpcpb.NewObjectWorldServiceClient(conn).ListFrames(ctx, req)
+		if err != nil {
+			return fmt.Errorf("could not list frames of world %q: %w", flagWorldID, err)
+		}
+
+		prtr, err := printer.NewPrinterWithWriter(root.FlagOutput, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		prtr.Print(frameList{resp: resp})
+		return nil
+	},
+}
+
+func init() {
+	worldCmd.AddCommand(worldListFramesCmd)
+}
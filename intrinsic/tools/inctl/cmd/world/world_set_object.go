@@ -0,0 +1,111 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package world
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	posepb "intrinsic/math/proto/pose_go_proto"
+	"intrinsic/tools/inctl/cmd/root"
+	"intrinsic/tools/inctl/util/printer"
+	owrpb "intrinsic/world/proto/object_world_refs_go_proto"
+	owsgrpcpb "intrinsic/world/proto/object_world_service_go_grpc_proto"
+	owupb "intrinsic/world/proto/object_world_updates_go_proto"
+)
+
+var (
+	flagPosition    string
+	flagOrientation string
+	flagRelativeTo  string
+)
+
+var worldSetObjectCmd = &cobra.Command{
+	Use:   "set-object OBJECT",
+	Short: "Sets the pose of an object",
+	Long: `Sets the pose of an object relative to its parent or to another object or frame.
+
+The position is given in meters as x,y,z and the orientation as a quaternion qx,qy,qz,qw. If only
+one of them is given, the other one keeps its current value.`,
+	Example: `
+	$ inctl world set-object workpiece --position=0.5,0.1,0.02 --solution my-solution-id
+	$ inctl world set-object camera_0 --relative_to=robot/flange --position=0,0,0.1 --orientation=0,0,0,1 --solution my-solution-id
+	`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if flagPosition == "" && flagOrientation == "" {
+			return fmt.Errorf("at least one of --position and --orientation is required")
+		}
+
+		ctx, conn, err := connectToCluster(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("could not dial connection: %w", err)
+		}
+		defer conn.Close()
+		client := owsgrpcpb.NewObjectWorldServiceClient(conn)
+
+		var reference *owrpb.TransformNodeReference
+		if flagRelativeTo != "" {
+			reference = transformNodeRef(flagRelativeTo)
+		} else {
+			obj, err := client.GetObject(ctx, &owsgrpcpb.GetObjectRequest{
+				WorldId:     flagWorldID,
+				ObjectQuery: &owsgrpcpb.GetObjectRequest_Object{Object: objectRef(name)},
+			})
+			if err != nil {
+				return fmt.Errorf("could not get object %q: %w", name, err)
+			}
+			if obj.GetParent().GetId() == "" {
+				return fmt.Errorf("object %q has no parent, use --relative_to", name)
+			}
+			reference = &owrpb.TransformNodeReference{
+				TransformNodeReference: &owrpb.TransformNodeReference_Id{Id: obj.GetParent().GetId()},
+			}
+		}
+		node := transformNodeRef(name)
+
+		current, err := client.GetTransform(ctx, &owsgrpcpb.GetTransformRequest{
+			WorldId: flagWorldID,
+			NodeA:   reference,
+			NodeB:   node,
+		})
+		if err != nil {
+			return fmt.Errorf("could not get the current pose of %q: %w", name, err)
+		}
+		pose := &posepb.Pose{}
+		if current.GetATB() != nil {
+			pose = proto.Clone(current.GetATB()).(*posepb.Pose)
+		}
+		if err := updatePose(pose, flagPosition, flagOrientation); err != nil {
+			return err
+		}
+
+		resp, err := client.UpdateTransform(ctx, &owupb.UpdateTransformRequest{
+			WorldId:      flagWorldID,
+			NodeA:        reference,
+			NodeB:        node,
+			NodeToUpdate: node,
+			ATB:          pose,
+			View:         owupb.ObjectView_BASIC,
+		})
+		if err != nil {
+			return fmt.Errorf("could not update the pose of %q: %w", name, err)
+		}
+
+		prtr, err := printer.NewPrinterWithWriter(root.FlagOutput, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		prtr.PrintSf("Updated %q from %s to %s", resp.GetObject().GetName(), formatPose(current.GetATB()), formatPose(pose))
+		return nil
+	},
+}
+
+func init() {
+	worldSetObjectCmd.Flags().StringVar(&flagPosition, "position", "", "New position as x,y,z in meters.")
+	worldSetObjectCmd.Flags().StringVar(&flagOrientation, "orientation", "", "New orientation as quaternion qx,qy,qz,qw.")
+	worldSetObjectCmd.Flags().StringVar(&flagRelativeTo, "relative_to", "", "Object or OBJECT/FRAME the pose is relative to. Defaults to the parent of the object.")
+	worldCmd.AddCommand(worldSetObjectCmd)
+}
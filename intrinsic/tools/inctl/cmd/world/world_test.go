@@ -0,0 +1,64 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package world
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	pointpb "intrinsic/math/proto/point_go_proto"
+	posepb "intrinsic/math/proto/pose_go_proto"
+	quaternionpb "intrinsic/math/proto/quaternion_go_proto"
+	owsgrpcpb "intrinsic/world/proto/object_world_service_go_grpc_proto"
+)
+
+func TestUpdatePose(t *testing.T) {
+	pose := &posepb.Pose{
+		Position:    &pointpb.Point{X: 1, Y: 2, Z: 3},
+		Orientation: &quaternionpb.Quaternion{W: 1},
+	}
+	if err := updatePose(pose, "0.5, 0.1,0.02", ""); err != nil {
+		t.Fatalf("updatePose() failed: %v", err)
+	}
+	want := &posepb.Pose{
+		Position:    &pointpb.Point{X: 0.5, Y: 0.1, Z: 0.02},
+		Orientation: &quaternionpb.Quaternion{W: 1},
+	}
+	if !proto.Equal(pose, want) {
+		t.Errorf("updatePose() = %v, want %v", pose, want)
+	}
+
+	for _, tc := range []struct{ position, orientation string }{
+		{position: "1,2"},
+		{orientation: "0,0,0"},
+		{position: "1,2,x"},
+	} {
+		if err := updatePose(&posepb.Pose{}, tc.position, tc.orientation); err == nil {
+			t.Errorf("updatePose(%q, %q) succeeded, want error", tc.position, tc.orientation)
+		}
+	}
+}
+
+func TestTransformNodeRef(t *testing.T) {
+	if got := transformNodeRef("robot/flange").GetByName().GetFrame(); got.GetObjectName() != "robot" || got.GetFrameName() != "flange" {
+		t.Errorf("transformNodeRef(\"robot/flange\") = %v, want frame flange of robot", got)
+	}
+	if got := transformNodeRef("robot").GetByName().GetObject(); got.GetObjectName() != "robot" {
+		t.Errorf("transformNodeRef(\"robot\") = %v, want object robot", got)
+	}
+}
+
+func TestFrameListString(t *testing.T) {
+	l := frameList{resp: &owsgrpcpb.ListFramesResponse{
+		Frames: []*owsgrpcpb.Frame{{
+			Name:        "grasp",
+			Object:      &owsgrpcpb.IdAndName{Name: "workpiece"},
+			ParentFrame: &owsgrpcpb.IdAndName{Name: "top"},
+			ParentTThis: &posepb.Pose{Position: &pointpb.Point{Z: 0.1}, Orientation: &quaternionpb.Quaternion{W: 1}},
+		}},
+	}}
+	if got, want := l.String(), "workpiece/grasp  workpiece/top  position=0,0,0.1 orientation=0,0,0,1"; !strings.Contains(got, want) {
+		t.Errorf("String() = %q, want it to contain %q", got, want)
+	}
+}
@@ -4,17 +4,23 @@ package main
 
 import (
 	_ "intrinsic/assets/services/inctl/service"
+	_ "intrinsic/tools/inctl/cmd/asset"
 	_ "intrinsic/tools/inctl/cmd/auth"
 	_ "intrinsic/tools/inctl/cmd/bazel"
+	_ "intrinsic/tools/inctl/cmd/bench"
 	_ "intrinsic/tools/inctl/cmd/cluster"
 	_ "intrinsic/tools/inctl/cmd/device"
+	_ "intrinsic/tools/inctl/cmd/doctor"
 	_ "intrinsic/tools/inctl/cmd/logs"
 	_ "intrinsic/tools/inctl/cmd/notebook"
 	_ "intrinsic/tools/inctl/cmd/process"
 	"intrinsic/tools/inctl/cmd/root"
+	_ "intrinsic/tools/inctl/cmd/shell"
 	_ "intrinsic/tools/inctl/cmd/skill"
 	_ "intrinsic/tools/inctl/cmd/solution"
+	_ "intrinsic/tools/inctl/cmd/status"
 	_ "intrinsic/tools/inctl/cmd/version"
+	_ "intrinsic/tools/inctl/cmd/world"
 )
 
 func main() {
@@ -0,0 +1,17 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package main
+
+import (
+	_ "intrinsic/assets/services/inctl/service"
+	_ "intrinsic/tools/inctl/cmd/asset"
+	_ "intrinsic/tools/inctl/cmd/auth"
+	_ "intrinsic/tools/inctl/cmd/logs"
+	"intrinsic/tools/inctl/cmd/root"
+	_ "intrinsic/tools/inctl/cmd/skill"
+	_ "intrinsic/tools/inctl/cmd/version"
+)
+
+func main() {
+	root.Inctl()
+}
@@ -113,9 +113,24 @@ func PreRunOrganization(cmd *cobra.Command, vipr *viper.Viper) error {
 	projectFlag := cmd.PersistentFlags().Lookup(KeyProject)
 	orgFlag := cmd.PersistentFlags().Lookup(KeyOrganization)
 
+	// Forget the values derived by a previous run in the same process, e.g., in 'inctl shell'. A nil
+	// override is ignored by viper.
+	noOrg = false
+	vipr.Set(KeyProject, nil)
+	vipr.Set(KeyOrganization, nil)
+
 	org := vipr.GetString(KeyOrganization)
 	project := vipr.GetString(KeyProject)
 
+	// Fall back to the organization set with `inctl auth use-org`.
+	if project == "" && org == "" {
+		if info, err := authStore.ReadDefaultOrg(); err == nil && info != nil {
+			org = info.Organization
+			orgFlag.Value.Set(org)
+			vipr.Set(KeyOrganization, org)
+		}
+	}
+
 	if (project == "" && org == "") || (project != "" && org != "") {
 		return errNotXor
 	}
@@ -158,7 +173,8 @@ func WrapCmd(cmd *cobra.Command, vipr *viper.Viper) *cobra.Command {
 		INTRINSIC_PROJECT=project_name to set a default project name.`)
 	cmd.PersistentFlags().StringP(KeyOrganization, "", "",
 		`The Intrinsic organization to use. You can set the environment variable
-		INTRINSIC_ORGANIZATION=organization to set a default organization.`)
+		INTRINSIC_ORGANIZATION=organization or use 'inctl auth use-org' to set a default
+		organization.`)
 
 	oldPreRunE := cmd.PersistentPreRunE
 	cmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
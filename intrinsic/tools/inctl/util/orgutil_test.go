@@ -132,6 +132,39 @@ func TestWrapCmd(t *testing.T) {
 		}
 	})
 
+	t.Run("org-rerun", func(t *testing.T) {
+		// This one cannot be run in parallel as it touches the authStore
+		authStore = authtest.NewStoreForTest(t)
+		authStore.WriteOrgInfo(&auth.OrgInfo{Project: "example-project", Organization: "otherorg"})
+
+		var projectName, orgName string
+		vi := viper.New()
+		cmd := WrapCmd(&cobra.Command{
+			Run: func(*cobra.Command, []string) {
+				projectName = vi.GetString(KeyProject)
+				orgName = vi.GetString(KeyOrganization)
+			},
+		}, vi)
+
+		cmd.SetArgs([]string{"--org=otherorg"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Unexpected error during first run: %v", err)
+		}
+		// Run again in the same process with reset flags, like 'inctl shell' does.
+		for _, key := range []string{KeyProject, KeyOrganization} {
+			f := cmd.PersistentFlags().Lookup(key)
+			f.Value.Set(f.DefValue)
+			f.Changed = false
+		}
+		cmd.SetArgs([]string{"--project=other-project"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Unexpected error during second run: %v", err)
+		}
+		if projectName != "other-project" || orgName != "" {
+			t.Errorf("Second run got project %q and org %q, want other-project and no org", projectName, orgName)
+		}
+	})
+
 	t.Run("no-such-org", func(t *testing.T) {
 		// This one cannot be run in parallel as it touches the authStore
 		authStore = authtest.NewStoreForTest(t)
@@ -155,6 +188,36 @@ func TestWrapCmd(t *testing.T) {
 		}
 	})
 
+	t.Run("default-org", func(t *testing.T) {
+		// This one cannot be run in parallel as it touches the authStore
+		oldStore := authStore
+		defer func() { authStore = oldStore }()
+		authStore = authtest.NewStoreForTest(t)
+		authStore.WriteOrgInfo(&auth.OrgInfo{Project: "example-project", Organization: "otherorg"})
+		authStore.WriteDefaultOrg(&auth.OrgInfo{Project: "example-project", Organization: "otherorg"})
+
+		vi := viper.New()
+		cmd := WrapCmd(&cobra.Command{
+			Run: func(*cobra.Command, []string) {
+				projectName := vi.GetString(KeyProject)
+				orgName := vi.GetString(KeyOrganization)
+
+				if projectName != "example-project" {
+					t.Errorf("Expected project to be example-project. Got: %q", projectName)
+				}
+
+				if orgName != "otherorg" {
+					t.Errorf("Expect org to be otherorg. Instead got: %q", orgName)
+				}
+			},
+		}, vi)
+
+		cmd.SetArgs([]string{})
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("Unexpected error during test-run: %v", err)
+		}
+	})
+
 	t.Run("subcommand", func(t *testing.T) {
 		t.Parallel()
 
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"sigs.k8s.io/yaml"
 )
 
 const (
@@ -15,12 +17,14 @@ const (
 	KeyOutput = "output"
 	// JSONOutputFormat is a string indicating JSON output format.
 	JSONOutputFormat = "json"
+	// YAMLOutputFormat is a string indicating YAML output format.
+	YAMLOutputFormat = "yaml"
 	// TextOutputFormat is a string indicating human-readable text output format.
 	TextOutputFormat = ""
 )
 
 // AllowedFormats is a list of possible output formats.
-var AllowedFormats = []string{JSONOutputFormat}
+var AllowedFormats = []string{JSONOutputFormat, YAMLOutputFormat}
 
 type any = interface{}
 
@@ -62,6 +66,37 @@ func (p *JSONPrinter) PrintSf(format string, a ...any) {
 	p.PrintS(fmt.Sprintf(format, a...))
 }
 
+// YAMLPrinter implements Printer. Values are converted like in JSON format, so
+// their JSON field tags apply. Every value is printed as a separate document.
+type YAMLPrinter struct {
+	w io.Writer
+}
+
+func (p *YAMLPrinter) Write(c []byte) (n int, err error) {
+	p.PrintS(string(c))
+	return len(c), nil
+}
+
+// Print prints val as a YAML document.
+func (p *YAMLPrinter) Print(val any) {
+	b, err := yaml.Marshal(val)
+	if err != nil {
+		fmt.Fprintf(p.w, "---\n# could not convert to YAML: %v\n", err)
+		return
+	}
+	fmt.Fprintf(p.w, "---\n%s", b)
+}
+
+// PrintS prints a string as a YAML document with a single "msg" field.
+func (p *YAMLPrinter) PrintS(str string) {
+	p.Print(&Message{Msg: str})
+}
+
+// PrintSf prints the formatted string as a YAML document with a single "msg" field.
+func (p *YAMLPrinter) PrintSf(format string, a ...any) {
+	p.PrintS(fmt.Sprintf(format, a...))
+}
+
 // TextPrinter implements Printer.
 type TextPrinter struct {
 	w io.Writer
@@ -97,6 +132,8 @@ func (p *TextPrinter) PrintSf(format string, a ...any) {
 func NewPrinterWithWriter(outputFormat string, w io.Writer) (Printer, error) {
 	if outputFormat == JSONOutputFormat {
 		return &JSONPrinter{enc: json.NewEncoder(w)}, nil
+	} else if outputFormat == YAMLOutputFormat {
+		return &YAMLPrinter{w: w}, nil
 	} else if outputFormat == TextOutputFormat {
 		return &TextPrinter{w: w}, nil
 	}
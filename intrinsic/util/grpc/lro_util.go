@@ -0,0 +1,147 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package lroutil provides helpers for waiting on long-running operations.
+package lroutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	backoff "github.com/cenkalti/backoff/v4"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultInitialInterval = 15 * time.Millisecond
+	defaultMaxInterval     = 2 * time.Second
+	cancelTimeout          = 5 * time.Second
+)
+
+// OperationsClient is the subset of a long-running operations client that is
+// required to wait for an operation.  Most generated service clients that
+// expose operations implement it.
+type OperationsClient interface {
+	GetOperation(ctx context.Context, in *lropb.GetOperationRequest, opts ...grpc.CallOption) (*lropb.Operation, error)
+}
+
+// operationsCanceler is implemented by clients which can cancel operations.
+type operationsCanceler interface {
+	CancelOperation(ctx context.Context, in *lropb.CancelOperationRequest, opts ...grpc.CallOption) (*lropb.Operation, error)
+}
+
+// ProgressFunc is called with the latest state of the operation after every
+// poll and the time elapsed since waiting started.
+type ProgressFunc func(op *lropb.Operation, elapsed time.Duration)
+
+// WaitOptions configures WaitWithBackoff.
+type WaitOptions struct {
+	// InitialInterval is the delay before the first poll.  Defaults to 15ms.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between polls.  Defaults to 2s.
+	MaxInterval time.Duration
+	// Timeout is the maximum time to wait for the operation.  Zero means that
+	// only the deadline of the context applies.
+	Timeout time.Duration
+	// Progress is called after every poll if set.
+	Progress ProgressFunc
+	// CancelOnDone requests cancellation of the operation on the server if the
+	// context is canceled or the timeout expires before the operation is done.
+	// It only has an effect if the client implements CancelOperation.
+	CancelOnDone bool
+}
+
+// TimeoutError is returned by WaitWithBackoff if the operation does not
+// complete within WaitOptions.Timeout.
+type TimeoutError struct {
+	Name    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("operation %q did not complete within %v", e.Name, e.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded so that timeouts can be detected with
+// errors.Is.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// WaitWithBackoff polls op with exponential backoff until it is done and
+// returns its final state.  The result of a done operation, including its
+// error, is returned as is; the returned error only reports failures to wait
+// for the operation.
+func WaitWithBackoff(ctx context.Context, client OperationsClient, op *lropb.Operation, opts WaitOptions) (*lropb.Operation, error) {
+	if op.GetDone() {
+		return op, nil
+	}
+	name := op.GetName()
+
+	waitCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = defaultInitialInterval
+	if opts.InitialInterval > 0 {
+		b.InitialInterval = opts.InitialInterval
+	}
+	b.MaxInterval = defaultMaxInterval
+	if opts.MaxInterval > 0 {
+		b.MaxInterval = opts.MaxInterval
+	}
+	b.MaxElapsedTime = 0 // The context determines when to give up.
+	b.Reset()
+
+	start := time.Now()
+	for !op.GetDone() {
+		select {
+		case <-waitCtx.Done():
+			return nil, waitError(ctx, waitCtx, client, name, opts)
+		case <-time.After(b.NextBackOff()):
+		}
+
+		next, err := client.GetOperation(waitCtx, &lropb.GetOperationRequest{Name: name})
+		if err != nil {
+			if waitCtx.Err() != nil {
+				return nil, waitError(ctx, waitCtx, client, name, opts)
+			}
+			return nil, fmt.Errorf("unable to check status of operation %q: %w", name, err)
+		}
+		op = next
+		if opts.Progress != nil {
+			opts.Progress(op, time.Since(start))
+		}
+	}
+	return op, nil
+}
+
+// waitError builds the error returned when waiting stops before the operation
+// is done and cancels the operation if requested.
+func waitError(ctx, waitCtx context.Context, client OperationsClient, name string, opts WaitOptions) error {
+	if opts.CancelOnDone {
+		if c, ok := client.(operationsCanceler); ok {
+			// The original context may already be done, so use a fresh one to
+			// propagate the cancellation to the server.
+			cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelTimeout)
+			defer cancel()
+			if _, err := c.CancelOperation(cancelCtx, &lropb.CancelOperationRequest{Name: name}); err != nil {
+				return errors.Join(waitCtxError(ctx, waitCtx, name, opts), fmt.Errorf("unable to cancel operation %q: %w", name, err))
+			}
+		}
+	}
+	return waitCtxError(ctx, waitCtx, name, opts)
+}
+
+func waitCtxError(ctx, waitCtx context.Context, name string, opts WaitOptions) error {
+	if ctx.Err() == nil && errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Name: name, Timeout: opts.Timeout}
+	}
+	return fmt.Errorf("stopped waiting for operation %q: %w", name, ctx.Err())
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package lroutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	lropb "cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"google.golang.org/grpc"
+)
+
+type fakeOperationsClient struct {
+	// doneAfter is the number of polls after which the operation is done.
+	doneAfter int
+	polls     int
+	canceled  []string
+}
+
+func (c *fakeOperationsClient) GetOperation(ctx context.Context, in *lropb.GetOperationRequest, opts ...grpc.CallOption) (*lropb.Operation, error) {
+	c.polls++
+	return &lropb.Operation{Name: in.GetName(), Done: c.doneAfter > 0 && c.polls >= c.doneAfter}, nil
+}
+
+func (c *fakeOperationsClient) CancelOperation(ctx context.Context, in *lropb.CancelOperationRequest, opts ...grpc.CallOption) (*lropb.Operation, error) {
+	c.canceled = append(c.canceled, in.GetName())
+	return &lropb.Operation{Name: in.GetName()}, nil
+}
+
+func TestWaitWithBackoff(t *testing.T) {
+	client := &fakeOperationsClient{doneAfter: 3}
+	var progress int
+	op, err := WaitWithBackoff(context.Background(), client, &lropb.Operation{Name: "op"}, WaitOptions{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     time.Millisecond,
+		Progress:        func(*lropb.Operation, time.Duration) { progress++ },
+	})
+	if err != nil {
+		t.Fatalf("WaitWithBackoff() returned an unexpected error: %v", err)
+	}
+	if !op.GetDone() {
+		t.Errorf("WaitWithBackoff() returned operation %v, want done", op)
+	}
+	if client.polls != 3 || progress != 3 {
+		t.Errorf("WaitWithBackoff() polled %d times and reported progress %d times, want 3 each", client.polls, progress)
+	}
+}
+
+func TestWaitWithBackoffAlreadyDone(t *testing.T) {
+	client := &fakeOperationsClient{}
+	if _, err := WaitWithBackoff(context.Background(), client, &lropb.Operation{Name: "op", Done: true}, WaitOptions{}); err != nil {
+		t.Fatalf("WaitWithBackoff() returned an unexpected error: %v", err)
+	}
+	if client.polls != 0 {
+		t.Errorf("WaitWithBackoff() polled %d times, want 0", client.polls)
+	}
+}
+
+func TestWaitWithBackoffTimeout(t *testing.T) {
+	client := &fakeOperationsClient{}
+	_, err := WaitWithBackoff(context.Background(), client, &lropb.Operation{Name: "op"}, WaitOptions{
+		InitialInterval: time.Millisecond,
+		Timeout:         20 * time.Millisecond,
+		CancelOnDone:    true,
+	})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("WaitWithBackoff() returned error %v, want a TimeoutError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitWithBackoff() returned error %v, want it to match context.DeadlineExceeded", err)
+	}
+	if len(client.canceled) != 1 || client.canceled[0] != "op" {
+		t.Errorf("WaitWithBackoff() canceled %v, want [op]", client.canceled)
+	}
+}
+
+func TestWaitWithBackoffCanceled(t *testing.T) {
+	client := &fakeOperationsClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := WaitWithBackoff(ctx, client, &lropb.Operation{Name: "op"}, WaitOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitWithBackoff() returned error %v, want context.Canceled", err)
+	}
+	if len(client.canceled) != 0 {
+		t.Errorf("WaitWithBackoff() canceled %v without CancelOnDone", client.canceled)
+	}
+}
@@ -151,6 +151,10 @@ def _proto_source_code_info_transitive_descriptor_set(ctx):
 #     )
 #
 # Outputs a file named: my_proto_descriptors_transitive_set_sci.proto.bin
+#
+# Outside of Bazel, descriptorset.CollectTransitive in
+# //intrinsic/util/proto:descriptorset computes the same set, without duplicate
+# files and in a deterministic order.
 proto_source_code_info_transitive_descriptor_set = rule(
     implementation = _proto_source_code_info_transitive_descriptor_set,
     attrs = {
@@ -0,0 +1,513 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package descriptorexport exports pruned, comment-bearing file descriptor sets
+// for a set of root messages.  The exported sets are content-addressed so that
+// they can be served to and cached indefinitely by UIs which need to resolve
+// and document proto types.
+package descriptorexport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// fileExtension is the extension of exported descriptor sets.
+	fileExtension = ".binpb"
+	// indexFileName is the file in the output directory which records the
+	// inputs of previous exports for incremental regeneration.
+	indexFileName = "index.json"
+)
+
+// PruneSourceCodeInfo removes all source locations without comments from the
+// file.  Spans are only useful together with the original source file, so the
+// remaining locations are what a UI needs to document types and fields.
+func PruneSourceCodeInfo(file *descriptorpb.FileDescriptorProto) {
+	info := file.GetSourceCodeInfo()
+	if info == nil {
+		return
+	}
+	var kept []*descriptorpb.SourceCodeInfo_Location
+	for _, loc := range info.GetLocation() {
+		if loc.GetLeadingComments() == "" && loc.GetTrailingComments() == "" && len(loc.GetLeadingDetachedComments()) == 0 {
+			continue
+		}
+		kept = append(kept, loc)
+	}
+	if len(kept) == 0 {
+		file.SourceCodeInfo = nil
+		return
+	}
+	info.Location = kept
+}
+
+// Export returns the files required to resolve the given root messages.  These
+// are the files defining the roots and their transitive imports, in dependency
+// order.  Source code info is pruned to comments.  The input set is not
+// modified.
+func Export(set *descriptorpb.FileDescriptorSet, roots []string) (*descriptorpb.FileDescriptorSet, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("at least one root message is required")
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file descriptor set: %v", err)
+	}
+	byName := make(map[string]*descriptorpb.FileDescriptorProto, len(set.GetFile()))
+	for _, f := range set.GetFile() {
+		byName[f.GetName()] = f
+	}
+
+	var rootFiles []string
+	for _, root := range roots {
+		d, err := files.FindDescriptorByName(protoreflect.FullName(root))
+		if err != nil {
+			return nil, fmt.Errorf("root %q not found: %v", root, err)
+		}
+		if _, ok := d.(protoreflect.MessageDescriptor); !ok {
+			return nil, fmt.Errorf("root %q is not a message", root)
+		}
+		rootFiles = append(rootFiles, d.ParentFile().Path())
+	}
+	// Visit the roots in a stable order so that the output does not depend on
+	// the order in which the roots were given.
+	sort.Strings(rootFiles)
+
+	out := &descriptorpb.FileDescriptorSet{}
+	visited := make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		visited[name] = true
+		f, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("file %q not found", name)
+		}
+		for _, dep := range f.GetDependency() {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		pruned := proto.Clone(f).(*descriptorpb.FileDescriptorProto)
+		PruneSourceCodeInfo(pruned)
+		out.File = append(out.File, pruned)
+		return nil
+	}
+	for _, name := range rootFiles {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// File descriptor proto field numbers which source code info paths start with.
+const (
+	fileDependencyPath       = 3
+	fileMessageTypePath      = 4
+	fileEnumTypePath         = 5
+	fileServicePath          = 6
+	fileExtensionPath        = 7
+	filePublicDependencyPath = 10
+	fileWeakDependencyPath   = 11
+)
+
+// PruneFileDescriptorSet returns the subset of set needed to resolve the given
+// root messages.  Unlike Export, it removes not only files which the roots do
+// not import but also top level messages, enums, extensions and services which
+// are not reachable from the roots through field types.  Messages are kept as a
+// whole, including their nested types, and extensions are kept if they extend
+// a reachable message.  Files without any remaining definitions are removed and
+// imports are rewritten accordingly.  The input set is not modified.
+func PruneFileDescriptorSet(roots []string, set *descriptorpb.FileDescriptorSet) (*descriptorpb.FileDescriptorSet, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("at least one root message is required")
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file descriptor set: %v", err)
+	}
+
+	reachable := make(map[protoreflect.FullName]bool)
+	var visitMessage func(md protoreflect.MessageDescriptor)
+	var visitEnum func(ed protoreflect.EnumDescriptor)
+	visitParent := func(d protoreflect.Descriptor) {
+		// Nested types are only kept together with their containing message.
+		if md, ok := d.Parent().(protoreflect.MessageDescriptor); ok {
+			visitMessage(md)
+		}
+	}
+	visitField := func(fd protoreflect.FieldDescriptor) {
+		if md := fd.Message(); md != nil {
+			visitMessage(md)
+		}
+		if ed := fd.Enum(); ed != nil {
+			visitEnum(ed)
+		}
+		if fd.IsExtension() {
+			visitMessage(fd.ContainingMessage())
+		}
+	}
+	visitEnum = func(ed protoreflect.EnumDescriptor) {
+		if reachable[ed.FullName()] {
+			return
+		}
+		reachable[ed.FullName()] = true
+		visitParent(ed)
+	}
+	visitMessage = func(md protoreflect.MessageDescriptor) {
+		if reachable[md.FullName()] {
+			return
+		}
+		reachable[md.FullName()] = true
+		visitParent(md)
+		for i := 0; i < md.Fields().Len(); i++ {
+			visitField(md.Fields().Get(i))
+		}
+		for i := 0; i < md.Messages().Len(); i++ {
+			visitMessage(md.Messages().Get(i))
+		}
+		for i := 0; i < md.Enums().Len(); i++ {
+			visitEnum(md.Enums().Get(i))
+		}
+		for i := 0; i < md.Extensions().Len(); i++ {
+			visitField(md.Extensions().Get(i))
+		}
+	}
+	for _, root := range roots {
+		d, err := files.FindDescriptorByName(protoreflect.FullName(root))
+		if err != nil {
+			return nil, fmt.Errorf("root %q not found: %v", root, err)
+		}
+		md, ok := d.(protoreflect.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("root %q is not a message", root)
+		}
+		visitMessage(md)
+	}
+	// Top level extensions of reachable messages are kept, which can make
+	// further messages reachable.
+	for changed := true; changed; {
+		changed = false
+		files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			for i := 0; i < fd.Extensions().Len(); i++ {
+				xd := fd.Extensions().Get(i)
+				if !reachable[xd.FullName()] && reachable[xd.ContainingMessage().FullName()] {
+					reachable[xd.FullName()] = true
+					visitField(xd)
+					changed = true
+				}
+			}
+			return true
+		})
+	}
+
+	pruned := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, f := range set.GetFile() {
+		if p := pruneFile(f, reachable); p != nil {
+			pruned[f.GetName()] = p
+		}
+	}
+	byName := make(map[string]*descriptorpb.FileDescriptorProto, len(set.GetFile()))
+	for _, f := range set.GetFile() {
+		byName[f.GetName()] = f
+	}
+	// keptDeps returns the kept files which an import of name provides.  An
+	// import of a removed file is replaced by the kept files it publicly
+	// imports.
+	var keptDeps func(name string) []string
+	keptDeps = func(name string) []string {
+		if _, ok := pruned[name]; ok {
+			return []string{name}
+		}
+		f := byName[name]
+		var deps []string
+		for _, i := range f.GetPublicDependency() {
+			deps = append(deps, keptDeps(f.GetDependency()[i])...)
+		}
+		return deps
+	}
+
+	out := &descriptorpb.FileDescriptorSet{}
+	for _, f := range set.GetFile() {
+		p, ok := pruned[f.GetName()]
+		if !ok {
+			continue
+		}
+		public := make(map[int32]bool)
+		for _, i := range f.GetPublicDependency() {
+			public[i] = true
+		}
+		weak := make(map[int32]bool)
+		for _, i := range f.GetWeakDependency() {
+			weak[i] = true
+		}
+		p.Dependency, p.PublicDependency, p.WeakDependency = nil, nil, nil
+		depIndex := make(map[int]int)
+		seen := make(map[string]bool)
+		for i, dep := range f.GetDependency() {
+			for _, name := range keptDeps(dep) {
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				if name == dep {
+					depIndex[i] = len(p.Dependency)
+				}
+				idx := int32(len(p.Dependency))
+				p.Dependency = append(p.Dependency, name)
+				if public[int32(i)] {
+					p.PublicDependency = append(p.PublicDependency, idx)
+				}
+				if weak[int32(i)] {
+					p.WeakDependency = append(p.WeakDependency, idx)
+				}
+			}
+		}
+		remapSourceCodeInfo(p, fileDependencyPath, depIndex)
+		// The public and weak dependency lists are rebuilt without locations.
+		remapSourceCodeInfo(p, filePublicDependencyPath, nil)
+		remapSourceCodeInfo(p, fileWeakDependencyPath, nil)
+		out.File = append(out.File, p)
+	}
+	return out, nil
+}
+
+// pruneFile returns a copy of f with only the reachable top level definitions,
+// or nil if none of them is reachable.
+func pruneFile(f *descriptorpb.FileDescriptorProto, reachable map[protoreflect.FullName]bool) *descriptorpb.FileDescriptorProto {
+	fullName := func(name string) protoreflect.FullName {
+		if f.GetPackage() == "" {
+			return protoreflect.FullName(name)
+		}
+		return protoreflect.FullName(f.GetPackage() + "." + name)
+	}
+	p := proto.Clone(f).(*descriptorpb.FileDescriptorProto)
+	p.Service = nil
+
+	messages := make(map[int]int)
+	allMessages := p.GetMessageType()
+	p.MessageType = nil
+	for i, m := range allMessages {
+		if reachable[fullName(m.GetName())] {
+			messages[i] = len(p.MessageType)
+			p.MessageType = append(p.MessageType, m)
+		}
+	}
+	enums := make(map[int]int)
+	allEnums := p.GetEnumType()
+	p.EnumType = nil
+	for i, e := range allEnums {
+		if reachable[fullName(e.GetName())] {
+			enums[i] = len(p.EnumType)
+			p.EnumType = append(p.EnumType, e)
+		}
+	}
+	extensions := make(map[int]int)
+	allExtensions := p.GetExtension()
+	p.Extension = nil
+	for i, x := range allExtensions {
+		if reachable[fullName(x.GetName())] {
+			extensions[i] = len(p.Extension)
+			p.Extension = append(p.Extension, x)
+		}
+	}
+	if len(p.MessageType) == 0 && len(p.EnumType) == 0 && len(p.Extension) == 0 {
+		return nil
+	}
+
+	remapSourceCodeInfo(p, fileMessageTypePath, messages)
+	remapSourceCodeInfo(p, fileEnumTypePath, enums)
+	remapSourceCodeInfo(p, fileExtensionPath, extensions)
+	remapSourceCodeInfo(p, fileServicePath, nil)
+	return p
+}
+
+// remapSourceCodeInfo rewrites the index of the source locations of the
+// repeated file field with the given number according to indices, which maps
+// old to new indices.  Locations of removed elements are dropped, as are all
+// locations of the field if indices is nil.
+func remapSourceCodeInfo(f *descriptorpb.FileDescriptorProto, field int32, indices map[int]int) {
+	info := f.GetSourceCodeInfo()
+	if info == nil {
+		return
+	}
+	var kept []*descriptorpb.SourceCodeInfo_Location
+	for _, loc := range info.GetLocation() {
+		path := loc.GetPath()
+		if len(path) == 0 || path[0] != field {
+			kept = append(kept, loc)
+			continue
+		}
+		if len(path) == 1 {
+			if len(indices) > 0 {
+				kept = append(kept, loc)
+			}
+			continue
+		}
+		i, ok := indices[int(path[1])]
+		if !ok {
+			continue
+		}
+		loc.Path = append([]int32{field, int32(i)}, path[2:]...)
+		kept = append(kept, loc)
+	}
+	info.Location = kept
+}
+
+// Digest returns the content address of the given file descriptor set in the
+// form "sha256:<hex>".  Serialization is deterministic, so equal sets have
+// equal digests.
+func Digest(set *descriptorpb.FileDescriptorSet) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(set)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal file descriptor set: %v", err)
+	}
+	return digestBytes(b), nil
+}
+
+func digestBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// FileName returns the name of the content-addressed file for a digest.
+func FileName(digest string) string {
+	return strings.TrimPrefix(digest, "sha256:") + fileExtension
+}
+
+// Result describes an exported file descriptor set.
+type Result struct {
+	// Digest is the content address of the exported set.
+	Digest string `json:"digest"`
+	// Path is the path of the exported set.
+	Path string `json:"path"`
+	// Cached is true if the set was not regenerated because its inputs did not
+	// change.
+	Cached bool `json:"-"`
+}
+
+type indexEntry struct {
+	InputDigest string `json:"inputDigest"`
+	Digest      string `json:"digest"`
+}
+
+// Exporter writes exported sets to a directory.  It records the inputs of each
+// export in an index, so that unchanged exports are not regenerated.
+type Exporter struct {
+	dir string
+}
+
+// NewExporter returns an exporter writing to dir.
+func NewExporter(dir string) *Exporter {
+	return &Exporter{dir: dir}
+}
+
+// rootsKey identifies a set of roots independently of their order.
+func rootsKey(roots []string) string {
+	sorted := append([]string(nil), roots...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func (e *Exporter) readIndex() map[string]indexEntry {
+	index := make(map[string]indexEntry)
+	b, err := os.ReadFile(filepath.Join(e.dir, indexFileName))
+	if err != nil {
+		return index
+	}
+	// A corrupt index only means that everything is regenerated.
+	json.Unmarshal(b, &index)
+	return index
+}
+
+func (e *Exporter) writeIndex(index map[string]indexEntry) error {
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(e.dir, indexFileName), b)
+}
+
+// Export exports the given roots from set into the output directory and
+// returns where the result was written.  If the same roots were exported from
+// an identical set before and the result still exists, the previous result is
+// returned without regenerating it.
+func (e *Exporter) Export(set *descriptorpb.FileDescriptorSet, roots []string) (*Result, error) {
+	in, err := proto.MarshalOptions{Deterministic: true}.Marshal(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal file descriptor set: %v", err)
+	}
+	inputDigest := digestBytes(in)
+	key := rootsKey(roots)
+
+	index := e.readIndex()
+	if entry, ok := index[key]; ok && entry.InputDigest == inputDigest {
+		path := filepath.Join(e.dir, FileName(entry.Digest))
+		if _, err := os.Stat(path); err == nil {
+			return &Result{Digest: entry.Digest, Path: path, Cached: true}, nil
+		}
+	}
+
+	exported, err := Export(set, roots)
+	if err != nil {
+		return nil, err
+	}
+	out, err := proto.MarshalOptions{Deterministic: true}.Marshal(exported)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal exported file descriptor set: %v", err)
+	}
+	digest := digestBytes(out)
+	path := filepath.Join(e.dir, FileName(digest))
+
+	if err := os.MkdirAll(e.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %q: %v", e.dir, err)
+	}
+	// Content-addressed files never change, so an existing file can be reused.
+	if _, err := os.Stat(path); err != nil {
+		if err := writeFileAtomic(path, out); err != nil {
+			return nil, fmt.Errorf("failed to write %q: %v", path, err)
+		}
+	}
+
+	index[key] = indexEntry{InputDigest: inputDigest, Digest: digest}
+	if err := e.writeIndex(index); err != nil {
+		return nil, fmt.Errorf("failed to write index: %v", err)
+	}
+	return &Result{Digest: digest, Path: path}, nil
+}
+
+// writeFileAtomic writes b to path via a temporary file so that readers never
+// see partially written files.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
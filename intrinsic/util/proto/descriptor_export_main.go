@@ -0,0 +1,50 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// package main exports pruned, comment-bearing file descriptor sets for a set of
+// root messages into a content-addressed directory.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/golang/glog"
+	intrinsic "intrinsic/production/intrinsic"
+	"intrinsic/util/proto/descriptorexport"
+	"intrinsic/util/proto/registryutil"
+)
+
+var (
+	flagFileDescriptorSets = flag.String("file_descriptor_sets", "", "Comma separated paths to binary file descriptor sets containing the root messages and their dependencies.")
+	flagRoots              = flag.String("roots", "", "Comma separated full names of the root messages to export.")
+	flagOutputDir          = flag.String("output_dir", "", "Directory to write the content-addressed file descriptor set to.")
+)
+
+func run() error {
+	if *flagFileDescriptorSets == "" || *flagRoots == "" || *flagOutputDir == "" {
+		return fmt.Errorf("--file_descriptor_sets, --roots and --output_dir are required")
+	}
+	set, err := registryutil.LoadFileDescriptorSets(strings.Split(*flagFileDescriptorSets, ","))
+	if err != nil {
+		return err
+	}
+	result, err := descriptorexport.NewExporter(*flagOutputDir).Export(set, strings.Split(*flagRoots, ","))
+	if err != nil {
+		return err
+	}
+	if result.Cached {
+		log.Infof("inputs unchanged, reusing %s", result.Path)
+	}
+	// The result is printed so that callers can pick up the digest.
+	return json.NewEncoder(os.Stdout).Encode(result)
+}
+
+func main() {
+	intrinsic.Init()
+	if err := run(); err != nil {
+		log.Exitf("Failed to export file descriptor set: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package descriptorexport
+
+import (
+	"testing"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/testing/protocmp"
+	apb "intrinsic/util/proto/testing/diamond_a_go_proto"
+	bpb "intrinsic/util/proto/testing/diamond_b_go_proto"
+	cpb "intrinsic/util/proto/testing/diamond_c_go_proto"
+	dpb "intrinsic/util/proto/testing/diamond_d_go_proto"
+)
+
+func diamondSet() *descriptorpb.FileDescriptorSet {
+	return &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto((&apb.A{}).ProtoReflect().Descriptor().ParentFile()),
+			protodesc.ToFileDescriptorProto((&bpb.B{}).ProtoReflect().Descriptor().ParentFile()),
+			protodesc.ToFileDescriptorProto((&cpb.C{}).ProtoReflect().Descriptor().ParentFile()),
+			protodesc.ToFileDescriptorProto((&dpb.D{}).ProtoReflect().Descriptor().ParentFile()),
+		},
+	}
+}
+
+func fileNames(set *descriptorpb.FileDescriptorSet) []string {
+	var names []string
+	for _, f := range set.GetFile() {
+		names = append(names, f.GetName())
+	}
+	return names
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		name  string
+		roots []string
+		want  []string
+	}{
+		{
+			name:  "leaf",
+			roots: []string{"intrinsic_proto.test.A"},
+			want:  []string{"intrinsic/util/proto/testing/diamond_a.proto"},
+		},
+		{
+			name:  "diamond",
+			roots: []string{"intrinsic_proto.test.D"},
+			want: []string{
+				"intrinsic/util/proto/testing/diamond_a.proto",
+				"intrinsic/util/proto/testing/diamond_b.proto",
+				"intrinsic/util/proto/testing/diamond_c.proto",
+				"intrinsic/util/proto/testing/diamond_d.proto",
+			},
+		},
+		{
+			name:  "order independent",
+			roots: []string{"intrinsic_proto.test.C", "intrinsic_proto.test.B"},
+			want: []string{
+				"intrinsic/util/proto/testing/diamond_a.proto",
+				"intrinsic/util/proto/testing/diamond_b.proto",
+				"intrinsic/util/proto/testing/diamond_c.proto",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Export(diamondSet(), tc.roots)
+			if err != nil {
+				t.Fatalf("Export(%v) failed: %v", tc.roots, err)
+			}
+			if diff := cmp.Diff(tc.want, fileNames(got)); diff != "" {
+				t.Errorf("Export(%v) returned unexpected files (-want +got):\n%s", tc.roots, diff)
+			}
+		})
+	}
+}
+
+func TestExportUnknownRoot(t *testing.T) {
+	if _, err := Export(diamondSet(), []string{"intrinsic_proto.test.Unknown"}); err == nil {
+		t.Error("Export() succeeded for an unknown root, want error")
+	}
+}
+
+func TestPruneSourceCodeInfo(t *testing.T) {
+	file := &descriptorpb.FileDescriptorProto{
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{Path: []int32{4, 0}, Span: []int32{1, 0, 10}, LeadingComments: proto.String(" A message.\n")},
+				{Path: []int32{4, 0, 2, 0}, Span: []int32{2, 2, 20}},
+			},
+		},
+	}
+	PruneSourceCodeInfo(file)
+	if got := len(file.GetSourceCodeInfo().GetLocation()); got != 1 {
+		t.Errorf("PruneSourceCodeInfo() kept %d locations, want 1", got)
+	}
+}
+
+func TestPruneFileDescriptorSetRemovesFiles(t *testing.T) {
+	got, err := PruneFileDescriptorSet([]string{"intrinsic_proto.test.B"}, diamondSet())
+	if err != nil {
+		t.Fatalf("PruneFileDescriptorSet() failed: %v", err)
+	}
+	want := []string{
+		"intrinsic/util/proto/testing/diamond_a.proto",
+		"intrinsic/util/proto/testing/diamond_b.proto",
+	}
+	if diff := cmp.Diff(want, fileNames(got)); diff != "" {
+		t.Errorf("PruneFileDescriptorSet() returned unexpected files (-want +got):\n%s", diff)
+	}
+}
+
+func TestPruneFileDescriptorSetRemovesDefinitions(t *testing.T) {
+	messageField := func(name, typeName string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(1),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(typeName),
+		}
+	}
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("unused.proto"),
+				Package: proto.String("test"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{Name: proto.String("Unused")},
+				},
+			},
+			{
+				Name:       proto.String("root.proto"),
+				Package:    proto.String("test"),
+				Syntax:     proto.String("proto3"),
+				Dependency: []string{"unused.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{
+					{Name: proto.String("Other"), Field: []*descriptorpb.FieldDescriptorProto{messageField("unused", ".test.Unused")}},
+					{Name: proto.String("Root"), Field: []*descriptorpb.FieldDescriptorProto{messageField("leaf", ".test.Leaf")}},
+					{Name: proto.String("Leaf")},
+				},
+				EnumType: []*descriptorpb.EnumDescriptorProto{
+					{Name: proto.String("Kind"), Value: []*descriptorpb.EnumValueDescriptorProto{{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)}}},
+				},
+				Service: []*descriptorpb.ServiceDescriptorProto{
+					{Name: proto.String("Service")},
+				},
+				SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+					Location: []*descriptorpb.SourceCodeInfo_Location{
+						{Path: []int32{4, 0}, Span: []int32{1, 0, 10}, LeadingComments: proto.String(" Other.\n")},
+						{Path: []int32{4, 1}, Span: []int32{2, 0, 10}, LeadingComments: proto.String(" Root.\n")},
+						{Path: []int32{5, 0}, Span: []int32{3, 0, 10}, LeadingComments: proto.String(" Kind.\n")},
+					},
+				},
+			},
+		},
+	}
+
+	got, err := PruneFileDescriptorSet([]string{"test.Root"}, set)
+	if err != nil {
+		t.Fatalf("PruneFileDescriptorSet() failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"root.proto"}, fileNames(got)); diff != "" {
+		t.Fatalf("PruneFileDescriptorSet() returned unexpected files (-want +got):\n%s", diff)
+	}
+	f := got.GetFile()[0]
+	var messages []string
+	for _, m := range f.GetMessageType() {
+		messages = append(messages, m.GetName())
+	}
+	if diff := cmp.Diff([]string{"Root", "Leaf"}, messages); diff != "" {
+		t.Errorf("PruneFileDescriptorSet() kept unexpected messages (-want +got):\n%s", diff)
+	}
+	if len(f.GetEnumType()) != 0 || len(f.GetService()) != 0 || len(f.GetDependency()) != 0 {
+		t.Errorf("PruneFileDescriptorSet() kept enums %v, services %v and dependencies %v, want none", f.GetEnumType(), f.GetService(), f.GetDependency())
+	}
+	wantLocations := []*descriptorpb.SourceCodeInfo_Location{
+		{Path: []int32{4, 0}, Span: []int32{2, 0, 10}, LeadingComments: proto.String(" Root.\n")},
+	}
+	if diff := cmp.Diff(wantLocations, f.GetSourceCodeInfo().GetLocation(), protocmp.Transform()); diff != "" {
+		t.Errorf("PruneFileDescriptorSet() returned unexpected source locations (-want +got):\n%s", diff)
+	}
+	if _, err := protodesc.NewFiles(got); err != nil {
+		t.Errorf("PruneFileDescriptorSet() returned an invalid set: %v", err)
+	}
+	if len(set.GetFile()[1].GetMessageType()) != 3 {
+		t.Error("PruneFileDescriptorSet() modified its input")
+	}
+}
+
+func TestExporterIsIncremental(t *testing.T) {
+	e := NewExporter(t.TempDir())
+	roots := []string{"intrinsic_proto.test.D"}
+
+	first, err := e.Export(diamondSet(), roots)
+	if err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+	if first.Cached {
+		t.Error("first Export() returned a cached result")
+	}
+	second, err := e.Export(diamondSet(), roots)
+	if err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+	if !second.Cached || second.Digest != first.Digest {
+		t.Errorf("second Export() = %+v, want cached result with digest %q", second, first.Digest)
+	}
+}
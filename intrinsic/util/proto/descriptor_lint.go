@@ -0,0 +1,364 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package descriptorlint checks the file descriptor sets of skills and services for hygiene
+// problems, such as undocumented parameter fields.
+//
+// Findings can be suppressed with a baseline of known findings, so that the rules can be enabled
+// for existing protos and only new problems fail the build.
+package descriptorlint
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Rules checked by Lint.
+const (
+	// RuleFieldComment reports fields without a leading or trailing comment.
+	RuleFieldComment = "field-comment"
+	// RuleDisallowedType reports fields of a disallowed message type.
+	RuleDisallowedType = "disallowed-type"
+	// RulePackageName reports proto packages which do not follow the naming conventions.
+	RulePackageName = "package-name"
+)
+
+// Rules lists all rules in the order they are checked.
+var Rules = []string{RuleFieldComment, RuleDisallowedType, RulePackageName}
+
+// DefaultDisallowedTypes are message types which are not allowed in parameters by default, since
+// they are untyped and cannot be documented or rendered by UIs.
+var DefaultDisallowedTypes = []string{
+	"google.protobuf.Any",
+	"google.protobuf.ListValue",
+	"google.protobuf.Struct",
+	"google.protobuf.Value",
+}
+
+// DefaultExcludedPackages are the prefixes of packages which are not linted by default, since
+// their files are not owned by skill or service authors.
+var DefaultExcludedPackages = []string{"google.", "intrinsic_proto."}
+
+var packageNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)+$`)
+
+// Severity is the severity of the findings of a rule.
+type Severity int
+
+const (
+	// SeverityOff disables a rule.
+	SeverityOff Severity = iota
+	// SeverityWarning reports findings without failing.
+	SeverityWarning
+	// SeverityError reports findings and fails.
+	SeverityError
+)
+
+var severityNames = map[Severity]string{
+	SeverityOff:     "off",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses the name of a severity, one of "off", "warning" or "error".
+func ParseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return SeverityOff, fmt.Errorf("unknown severity %q, must be one of off, warning or error", name)
+}
+
+// DefaultSeverities are the severities of the rules unless they are overridden.
+var DefaultSeverities = map[string]Severity{
+	RuleFieldComment:   SeverityWarning,
+	RuleDisallowedType: SeverityError,
+	RulePackageName:    SeverityError,
+}
+
+// ParseSeverities parses a comma separated list of rule=severity pairs, e.g.,
+// "field-comment=error,package-name=off", and returns the default severities with these
+// overrides applied.
+func ParseSeverities(spec string) (map[string]Severity, error) {
+	severities := map[string]Severity{}
+	for rule, s := range DefaultSeverities {
+		severities[rule] = s
+	}
+	if spec == "" {
+		return severities, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		rule, name, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid severity %q, must be rule=severity", pair)
+		}
+		if _, ok := DefaultSeverities[rule]; !ok {
+			return nil, fmt.Errorf("unknown rule %q, must be one of %v", rule, Rules)
+		}
+		s, err := ParseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+		severities[rule] = s
+	}
+	return severities, nil
+}
+
+// Finding is a problem found by Lint.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	// File is the path of the proto file the element is defined in.
+	File string
+	// Element is the full name of the offending element, e.g., of a field or package.
+	Element string
+	Message string
+}
+
+// Key identifies the finding in a baseline. It does not depend on line numbers, so that a baseline
+// stays valid when unrelated parts of a file change.
+func (f Finding) Key() string {
+	return f.Rule + " " + f.Element
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s [%s]", f.Severity, f.File, f.Element, f.Message, f.Rule)
+}
+
+// Options configures Lint.
+type Options struct {
+	// Roots are the full names of the messages to lint, e.g., the parameter and return messages of
+	// a skill, together with all messages they reference. All messages of the set are linted if
+	// empty.
+	Roots []string
+	// ExcludedPackages are prefixes of packages which are not linted.
+	ExcludedPackages []string
+	// DisallowedTypes are the full names of message types which fields must not use.
+	DisallowedTypes []string
+	// Severities are the severities of the rules. Rules which are missing are disabled.
+	Severities map[string]Severity
+	// Baseline contains the keys of known findings, which are not reported.
+	Baseline map[string]bool
+}
+
+// linter collects the findings for a set of files.
+type linter struct {
+	opts       Options
+	disallowed map[protoreflect.FullName]bool
+	findings   []Finding
+	// linted are the messages which have been linted already.
+	linted map[protoreflect.FullName]bool
+	// files are the paths of the files which have been linted already.
+	files map[string]bool
+}
+
+func (l *linter) add(rule string, file protoreflect.FileDescriptor, element string, format string, args ...any) {
+	s := l.opts.Severities[rule]
+	f := Finding{
+		Rule:     rule,
+		Severity: s,
+		File:     file.Path(),
+		Element:  element,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if s == SeverityOff || l.opts.Baseline[f.Key()] {
+		return
+	}
+	l.findings = append(l.findings, f)
+}
+
+func (l *linter) excluded(file protoreflect.FileDescriptor) bool {
+	for _, prefix := range l.opts.ExcludedPackages {
+		if strings.HasPrefix(string(file.Package())+".", prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *linter) lintFile(file protoreflect.FileDescriptor) {
+	if l.files[file.Path()] || l.excluded(file) {
+		return
+	}
+	l.files[file.Path()] = true
+
+	if !packageNameRegex.MatchString(string(file.Package())) {
+		l.add(RulePackageName, file, string(file.Package()), "package %q must consist of at least two lower case components separated by dots", file.Package())
+	}
+	if file.SourceLocations().Len() == 0 {
+		l.add(RuleFieldComment, file, file.Path(), "has no source code info, build the descriptor set with source info to check comments")
+	}
+}
+
+// lintMessage lints m and, if follow is set, the messages referenced by its fields.
+func (l *linter) lintMessage(m protoreflect.MessageDescriptor, follow bool) {
+	if l.linted[m.FullName()] {
+		return
+	}
+	l.linted[m.FullName()] = true
+
+	file := m.ParentFile()
+	own := !l.excluded(file)
+	if own {
+		l.lintFile(file)
+	}
+	fields := m.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if own {
+			l.lintField(field)
+		}
+		if !follow {
+			continue
+		}
+		if field.IsMap() {
+			field = field.MapValue()
+		}
+		if field.Message() != nil {
+			l.lintMessage(field.Message(), follow)
+		}
+	}
+	if !follow {
+		nested := m.Messages()
+		for i := 0; i < nested.Len(); i++ {
+			if !nested.Get(i).IsMapEntry() {
+				l.lintMessage(nested.Get(i), follow)
+			}
+		}
+	}
+}
+
+func (l *linter) lintField(field protoreflect.FieldDescriptor) {
+	file := field.ParentFile()
+	if file.SourceLocations().Len() > 0 {
+		loc := file.SourceLocations().ByDescriptor(field)
+		if strings.TrimSpace(loc.LeadingComments) == "" && strings.TrimSpace(loc.TrailingComments) == "" {
+			l.add(RuleFieldComment, file, string(field.FullName()), "field has no comment")
+		}
+	}
+	value := field
+	if field.IsMap() {
+		value = field.MapValue()
+	}
+	if value.Message() != nil && l.disallowed[value.Message().FullName()] {
+		l.add(RuleDisallowedType, file, string(field.FullName()), "field uses disallowed type %q", value.Message().FullName())
+	}
+}
+
+// Lint checks the files of set for hygiene problems. The set must contain all dependencies of its
+// files. Findings are sorted by file and element.
+func Lint(set *descriptorpb.FileDescriptorSet, opts Options) ([]Finding, error) {
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file descriptor set: %w", err)
+	}
+	l := &linter{
+		opts:       opts,
+		disallowed: map[protoreflect.FullName]bool{},
+		linted:     map[protoreflect.FullName]bool{},
+		files:      map[string]bool{},
+	}
+	for _, name := range opts.DisallowedTypes {
+		l.disallowed[protoreflect.FullName(name)] = true
+	}
+
+	if len(opts.Roots) > 0 {
+		for _, root := range opts.Roots {
+			d, err := files.FindDescriptorByName(protoreflect.FullName(root))
+			if err != nil {
+				return nil, fmt.Errorf("root %q not found: %w", root, err)
+			}
+			m, ok := d.(protoreflect.MessageDescriptor)
+			if !ok {
+				return nil, fmt.Errorf("root %q is not a message", root)
+			}
+			l.lintMessage(m, true)
+		}
+	} else {
+		files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+			if l.excluded(file) {
+				return true
+			}
+			l.lintFile(file)
+			for i := 0; i < file.Messages().Len(); i++ {
+				l.lintMessage(file.Messages().Get(i), false)
+			}
+			return true
+		})
+	}
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if l.findings[i].File != l.findings[j].File {
+			return l.findings[i].File < l.findings[j].File
+		}
+		return l.findings[i].Element < l.findings[j].Element
+	})
+	return l.findings, nil
+}
+
+// HasErrors returns whether any of the findings has SeverityError.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadBaseline reads a baseline file with one finding key per line. Empty lines and lines starting
+// with '#' are ignored.
+func ReadBaseline(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read baseline: %w", err)
+	}
+	defer f.Close()
+
+	baseline := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		baseline[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read baseline: %w", err)
+	}
+	return baseline, nil
+}
+
+// WriteBaseline writes the keys of the findings to a baseline file, which suppresses them in
+// later runs.
+func WriteBaseline(path string, findings []Finding) error {
+	keys := make([]string, 0, len(findings))
+	for _, f := range findings {
+		keys = append(keys, f.Key())
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString("# Known descriptor lint findings, one per line. Remove lines once they are fixed.\n")
+	for _, key := range keys {
+		sb.WriteString(key + "\n")
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("could not write baseline: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// package main checks file descriptor sets of skills and services for hygiene problems, such as
+// undocumented parameter fields or untyped parameters.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/golang/glog"
+	intrinsic "intrinsic/production/intrinsic"
+	"intrinsic/util/proto/descriptorlint"
+	"intrinsic/util/proto/registryutil"
+)
+
+var (
+	flagFileDescriptorSets = flag.String("file_descriptor_sets", "", "Comma separated paths to binary file descriptor sets with source info to lint.")
+	flagRoots              = flag.String("roots", "", "Optional comma separated full names of the messages to lint together with the messages they reference. All messages are linted if empty.")
+	flagExcludedPackages   = flag.String("excluded_packages", strings.Join(descriptorlint.DefaultExcludedPackages, ","), "Comma separated prefixes of proto packages which are not linted.")
+	flagDisallowedTypes    = flag.String("disallowed_types", strings.Join(descriptorlint.DefaultDisallowedTypes, ","), "Comma separated full names of message types which fields must not use.")
+	flagSeverities         = flag.String("severities", "", fmt.Sprintf("Comma separated rule=severity overrides, severity is one of off, warning or error. Rules are %v.", descriptorlint.Rules))
+	flagBaseline           = flag.String("baseline", "", "Optional file with known findings, which are not reported.")
+	flagWriteBaseline      = flag.String("write_baseline", "", "Optional file to write all current findings to as a baseline. Nothing fails if set.")
+	flagOutput             = flag.String("output", "", "Optional path of a file which is written if there are no errors.")
+)
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func run() error {
+	if *flagFileDescriptorSets == "" {
+		return fmt.Errorf("--file_descriptor_sets is required")
+	}
+	set, err := registryutil.LoadFileDescriptorSets(splitList(*flagFileDescriptorSets))
+	if err != nil {
+		return err
+	}
+	severities, err := descriptorlint.ParseSeverities(*flagSeverities)
+	if err != nil {
+		return err
+	}
+	opts := descriptorlint.Options{
+		Roots:            splitList(*flagRoots),
+		ExcludedPackages: splitList(*flagExcludedPackages),
+		DisallowedTypes:  splitList(*flagDisallowedTypes),
+		Severities:       severities,
+	}
+
+	if *flagWriteBaseline != "" {
+		findings, err := descriptorlint.Lint(set, opts)
+		if err != nil {
+			return err
+		}
+		if err := descriptorlint.WriteBaseline(*flagWriteBaseline, findings); err != nil {
+			return err
+		}
+		log.Infof("wrote %d finding(s) to %s", len(findings), *flagWriteBaseline)
+		return nil
+	}
+
+	if *flagBaseline != "" {
+		if opts.Baseline, err = descriptorlint.ReadBaseline(*flagBaseline); err != nil {
+			return err
+		}
+	}
+	findings, err := descriptorlint.Lint(set, opts)
+	if err != nil {
+		return err
+	}
+	for _, f := range findings {
+		fmt.Fprintln(os.Stderr, f)
+	}
+	if descriptorlint.HasErrors(findings) {
+		return fmt.Errorf("found %d problem(s), fix them or add them to a baseline with --write_baseline", len(findings))
+	}
+
+	if *flagOutput != "" {
+		if err := os.WriteFile(*flagOutput, nil, 0644); err != nil {
+			return fmt.Errorf("could not write %q: %v", *flagOutput, err)
+		}
+	}
+	return nil
+}
+
+func main() {
+	intrinsic.Init()
+	if err := run(); err != nil {
+		log.Exitf("Descriptor lint failed: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package descriptorlint
+
+import (
+	"path/filepath"
+	"testing"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// paramsSet returns a set with the message Params in the given package. Its field "documented"
+// has a comment, the fields "undocumented" and "untyped" do not.
+func paramsSet(pkg string) *descriptorpb.FileDescriptorSet {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("params.proto"),
+		Package:    proto.String(pkg),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/struct.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Params"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:   proto.String("documented"),
+					Number: proto.Int32(1),
+					Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				},
+				{
+					Name:   proto.String("undocumented"),
+					Number: proto.Int32(2),
+					Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				},
+				{
+					Name:     proto.String("untyped"),
+					Number:   proto.Int32(3),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+					TypeName: proto.String(".google.protobuf.Struct"),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				},
+			},
+		}},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{Path: []int32{4, 0}, Span: []int32{0, 0, 10}},
+				{Path: []int32{4, 0, 2, 0}, Span: []int32{1, 0, 10}, LeadingComments: proto.String(" A documented field.\n")},
+				{Path: []int32{4, 0, 2, 1}, Span: []int32{2, 0, 10}},
+				{Path: []int32{4, 0, 2, 2}, Span: []int32{3, 0, 10}},
+			},
+		},
+	}
+	return &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(structpb.File_google_protobuf_struct_proto),
+			file,
+		},
+	}
+}
+
+func defaultOptions() Options {
+	return Options{
+		ExcludedPackages: DefaultExcludedPackages,
+		DisallowedTypes:  DefaultDisallowedTypes,
+		Severities:       DefaultSeverities,
+	}
+}
+
+func keys(findings []Finding) []string {
+	var keys []string
+	for _, f := range findings {
+		keys = append(keys, f.Key())
+	}
+	return keys
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name string
+		pkg  string
+		opts func(*Options)
+		want []string
+	}{
+		{
+			name: "defaults",
+			pkg:  "com.example",
+			want: []string{
+				"field-comment com.example.Params.undocumented",
+				"field-comment com.example.Params.untyped",
+				"disallowed-type com.example.Params.untyped",
+			},
+		},
+		{
+			name: "roots",
+			pkg:  "com.example",
+			opts: func(o *Options) { o.Roots = []string{"com.example.Params"} },
+			want: []string{
+				"field-comment com.example.Params.undocumented",
+				"field-comment com.example.Params.untyped",
+				"disallowed-type com.example.Params.untyped",
+			},
+		},
+		{
+			name: "bad package",
+			pkg:  "Example",
+			opts: func(o *Options) { o.Severities = map[string]Severity{RulePackageName: SeverityError} },
+			want: []string{"package-name Example"},
+		},
+		{
+			name: "excluded package",
+			pkg:  "intrinsic_proto.example",
+			want: nil,
+		},
+		{
+			name: "baseline",
+			pkg:  "com.example",
+			opts: func(o *Options) {
+				o.Baseline = map[string]bool{"field-comment com.example.Params.untyped": true}
+				o.Severities = map[string]Severity{RuleFieldComment: SeverityWarning}
+			},
+			want: []string{"field-comment com.example.Params.undocumented"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			findings, err := Lint(paramsSet(tc.pkg), opts)
+			if err != nil {
+				t.Fatalf("Lint() failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, keys(findings)); diff != "" {
+				t.Errorf("Lint() returned unexpected findings (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLintUnknownRoot(t *testing.T) {
+	opts := defaultOptions()
+	opts.Roots = []string{"com.example.Missing"}
+	if _, err := Lint(paramsSet("com.example"), opts); err == nil {
+		t.Errorf("Lint() succeeded for an unknown root, want error")
+	}
+}
+
+func TestParseSeverities(t *testing.T) {
+	got, err := ParseSeverities("field-comment=error,package-name=off")
+	if err != nil {
+		t.Fatalf("ParseSeverities() failed: %v", err)
+	}
+	want := map[string]Severity{
+		RuleFieldComment:   SeverityError,
+		RuleDisallowedType: SeverityError,
+		RulePackageName:    SeverityOff,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseSeverities() returned unexpected severities (-want +got):\n%s", diff)
+	}
+
+	for _, spec := range []string{"field-comment", "unknown=error", "field-comment=fatal"} {
+		if _, err := ParseSeverities(spec); err == nil {
+			t.Errorf("ParseSeverities(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestBaselineRoundTrip(t *testing.T) {
+	findings, err := Lint(paramsSet("com.example"), defaultOptions())
+	if err != nil {
+		t.Fatalf("Lint() failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "baseline.txt")
+	if err := WriteBaseline(path, findings); err != nil {
+		t.Fatalf("WriteBaseline() failed: %v", err)
+	}
+	baseline, err := ReadBaseline(path)
+	if err != nil {
+		t.Fatalf("ReadBaseline() failed: %v", err)
+	}
+
+	opts := defaultOptions()
+	opts.Baseline = baseline
+	findings, err = Lint(paramsSet("com.example"), opts)
+	if err != nil {
+		t.Fatalf("Lint() failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Lint() with baseline returned %v, want no findings", findings)
+	}
+}
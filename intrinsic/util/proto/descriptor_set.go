@@ -0,0 +1,124 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+// Package descriptorset collects transitive file descriptor sets like the
+// proto_source_code_info_transitive_descriptor_set build rule does, so that
+// pipelines and tests outside of Bazel can produce the same descriptor sets.
+package descriptorset
+
+import (
+	"fmt"
+	"sort"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/protobuf/proto"
+	"intrinsic/util/proto/protoio"
+)
+
+// CollectTransitive returns the files with the given names and all files they
+// import, looked up in sources.  If roots is empty, all files of the sources
+// are collected.
+//
+// The result is deterministic: every file occurs once and after all of its
+// imports, roots are visited in sorted order and imports in the order in
+// which they are declared.  A file may occur in several sources, e.g., when
+// the descriptor sets of several targets are concatenated.  Copies with
+// source code info are preferred, other differences are an error.  The
+// sources are not modified.
+func CollectTransitive(roots []string, sources []*descriptorpb.FileDescriptorSet) (*descriptorpb.FileDescriptorSet, error) {
+	byName := map[string]*descriptorpb.FileDescriptorProto{}
+	for _, set := range sources {
+		for _, f := range set.GetFile() {
+			existing, ok := byName[f.GetName()]
+			if !ok {
+				byName[f.GetName()] = f
+				continue
+			}
+			preferred, err := dedupe(existing, f)
+			if err != nil {
+				return nil, err
+			}
+			byName[f.GetName()] = preferred
+		}
+	}
+
+	if len(roots) == 0 {
+		for name := range byName {
+			roots = append(roots, name)
+		}
+	}
+	sorted := append([]string(nil), roots...)
+	sort.Strings(sorted)
+
+	out := &descriptorpb.FileDescriptorSet{}
+	// visiting detects import cycles, visited files are done.
+	visiting := map[string]bool{}
+	visited := map[string]bool{}
+	var visit func(name, importedBy string) error
+	visit = func(name, importedBy string) error {
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("import cycle through %q", name)
+		}
+		f, ok := byName[name]
+		if !ok {
+			if importedBy != "" {
+				return fmt.Errorf("file %q imported by %q not found in the sources", name, importedBy)
+			}
+			return fmt.Errorf("root file %q not found in the sources", name)
+		}
+		visiting[name] = true
+		for _, dep := range f.GetDependency() {
+			if err := visit(dep, name); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+		out.File = append(out.File, proto.Clone(f).(*descriptorpb.FileDescriptorProto))
+		return nil
+	}
+	for _, name := range sorted {
+		if err := visit(name, ""); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// ReadTransitive reads the binary file descriptor sets at paths, e.g., the
+// transitive descriptor sets of the proto deps of a build rule, and combines
+// them with CollectTransitive.  Returns nil if paths is empty.
+func ReadTransitive(paths []string) (*descriptorpb.FileDescriptorSet, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	var sources []*descriptorpb.FileDescriptorSet
+	for _, path := range paths {
+		set := &descriptorpb.FileDescriptorSet{}
+		if err := protoio.ReadBinaryProto(path, set); err != nil {
+			return nil, fmt.Errorf("failed to read file descriptor set %q: %v", path, err)
+		}
+		sources = append(sources, set)
+	}
+	return CollectTransitive(nil, sources)
+}
+
+// dedupe returns which of two copies of the same file to keep.
+func dedupe(a, b *descriptorpb.FileDescriptorProto) (*descriptorpb.FileDescriptorProto, error) {
+	if proto.Equal(a, b) {
+		return a, nil
+	}
+	strippedA := proto.Clone(a).(*descriptorpb.FileDescriptorProto)
+	strippedA.SourceCodeInfo = nil
+	strippedB := proto.Clone(b).(*descriptorpb.FileDescriptorProto)
+	strippedB.SourceCodeInfo = nil
+	if !proto.Equal(strippedA, strippedB) {
+		return nil, fmt.Errorf("conflicting definitions of file %q in the sources", a.GetName())
+	}
+	if a.GetSourceCodeInfo() == nil {
+		return b, nil
+	}
+	return a, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package descriptorset
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"intrinsic/util/proto/protoio"
+)
+
+func file(name string, deps ...string) *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(name),
+		Package:    proto.String("test"),
+		Dependency: deps,
+	}
+}
+
+func names(set *descriptorpb.FileDescriptorSet) []string {
+	var names []string
+	for _, f := range set.GetFile() {
+		names = append(names, f.GetName())
+	}
+	return names
+}
+
+func TestCollectTransitive(t *testing.T) {
+	withInfo := file("b.proto", "c.proto")
+	withInfo.SourceCodeInfo = &descriptorpb.SourceCodeInfo{}
+	sources := []*descriptorpb.FileDescriptorSet{
+		{File: []*descriptorpb.FileDescriptorProto{file("a.proto", "b.proto", "c.proto"), file("b.proto", "c.proto")}},
+		{File: []*descriptorpb.FileDescriptorProto{file("c.proto"), withInfo, file("unused.proto")}},
+	}
+
+	got, err := CollectTransitive([]string{"a.proto"}, sources)
+	if err != nil {
+		t.Fatalf("CollectTransitive() failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"c.proto", "b.proto", "a.proto"}, names(got)); diff != "" {
+		t.Errorf("CollectTransitive() returned unexpected files (-want +got):\n%s", diff)
+	}
+	if got.GetFile()[1].GetSourceCodeInfo() == nil {
+		t.Errorf("CollectTransitive() did not prefer the copy of b.proto with source code info")
+	}
+
+	all, err := CollectTransitive(nil, sources)
+	if err != nil {
+		t.Fatalf("CollectTransitive() failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"c.proto", "b.proto", "a.proto", "unused.proto"}, names(all)); diff != "" {
+		t.Errorf("CollectTransitive() without roots returned unexpected files (-want +got):\n%s", diff)
+	}
+}
+
+func TestCollectTransitiveErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		roots   []string
+		sources []*descriptorpb.FileDescriptorSet
+	}{
+		{
+			name:    "missing import",
+			roots:   []string{"a.proto"},
+			sources: []*descriptorpb.FileDescriptorSet{{File: []*descriptorpb.FileDescriptorProto{file("a.proto", "b.proto")}}},
+		},
+		{
+			name:  "conflict",
+			roots: []string{"a.proto"},
+			sources: []*descriptorpb.FileDescriptorSet{
+				{File: []*descriptorpb.FileDescriptorProto{file("a.proto")}},
+				{File: []*descriptorpb.FileDescriptorProto{file("a.proto", "b.proto")}},
+			},
+		},
+		{
+			name:    "cycle",
+			roots:   []string{"a.proto"},
+			sources: []*descriptorpb.FileDescriptorSet{{File: []*descriptorpb.FileDescriptorProto{file("a.proto", "b.proto"), file("b.proto", "a.proto")}}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := CollectTransitive(tc.roots, tc.sources); err == nil {
+				t.Errorf("CollectTransitive() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestReadTransitive(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i, set := range []*descriptorpb.FileDescriptorSet{
+		{File: []*descriptorpb.FileDescriptorProto{file("b.proto"), file("a.proto", "b.proto")}},
+		{File: []*descriptorpb.FileDescriptorProto{file("b.proto")}},
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("set%d.pbbin", i))
+		if err := protoio.WriteBinaryProto(path, set); err != nil {
+			t.Fatalf("WriteBinaryProto() failed: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	got, err := ReadTransitive(paths)
+	if err != nil {
+		t.Fatalf("ReadTransitive() failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"b.proto", "a.proto"}, names(got)); diff != "" {
+		t.Errorf("ReadTransitive() returned unexpected files (-want +got):\n%s", diff)
+	}
+
+	if got, err := ReadTransitive(nil); got != nil || err != nil {
+		t.Errorf("ReadTransitive(nil) = %v, %v, want nil, nil", got, err)
+	}
+	if _, err := ReadTransitive([]string{filepath.Join(dir, "missing.pbbin")}); err == nil {
+		t.Errorf("ReadTransitive() of a missing file succeeded, want error")
+	}
+}
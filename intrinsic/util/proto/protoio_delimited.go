@@ -0,0 +1,114 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package protoio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// DelimitedWriter writes a stream of binary encoded proto messages, each prefixed with its size as
+// a varint. This is the format of parseDelimitedFrom/writeDelimitedTo in Java and of
+// google::protobuf::util::ParseDelimitedFromZeroCopyStream in C++.
+type DelimitedWriter struct {
+	w       io.Writer
+	options proto.MarshalOptions
+}
+
+// NewDelimitedWriter returns a DelimitedWriter which writes to w.
+func NewDelimitedWriter(w io.Writer, opts ...BinaryWriteOption) *DelimitedWriter {
+	dw := &DelimitedWriter{w: w}
+	for _, opt := range opts {
+		opt(&dw.options)
+	}
+	return dw
+}
+
+// Write writes a single message to the stream.
+func (w *DelimitedWriter) Write(p proto.Message) error {
+	if _, err := (protodelim.MarshalOptions{MarshalOptions: w.options}).MarshalTo(w.w, p); err != nil {
+		return fmt.Errorf("failed to write delimited message: %w", err)
+	}
+	return nil
+}
+
+// DelimitedReader reads a stream of messages written by DelimitedWriter.
+type DelimitedReader struct {
+	r       *bufio.Reader
+	options protodelim.UnmarshalOptions
+}
+
+// NewDelimitedReader returns a DelimitedReader which reads from r.
+//
+// The size of single messages is not limited, the stream is expected to come from a trusted
+// source such as a local file.
+func NewDelimitedReader(r io.Reader, opts ...BinaryReadOption) *DelimitedReader {
+	dr := &DelimitedReader{r: bufio.NewReader(r), options: protodelim.UnmarshalOptions{MaxSize: -1}}
+	for _, opt := range opts {
+		opt(&dr.options.UnmarshalOptions)
+	}
+	return dr
+}
+
+// Read reads the next message of the stream into p. It returns io.EOF if the stream ends before
+// the next message and io.ErrUnexpectedEOF if it ends within a message.
+func (r *DelimitedReader) Read(p proto.Message) error {
+	err := r.options.UnmarshalFrom(r.r, p)
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+	return fmt.Errorf("failed to read delimited message: %w", err)
+}
+
+// WriteDelimitedProtos writes the messages as a length-delimited stream to a file, see
+// DelimitedWriter.
+func WriteDelimitedProtos(path string, ps []proto.Message, opts ...BinaryWriteOption) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", path, err)
+	}
+	bw := bufio.NewWriter(f)
+	w := NewDelimitedWriter(bw, opts...)
+	for _, p := range ps {
+		if err := w.Write(p); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write %q: %w", path, err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+	return nil
+}
+
+// ReadDelimitedProtos reads all messages of a length-delimited stream from a file. newMessage is
+// called for every message in the stream and returns the message to read it into.
+func ReadDelimitedProtos(path string, newMessage func() proto.Message, opts ...BinaryReadOption) ([]proto.Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	defer f.Close()
+
+	r := NewDelimitedReader(f, opts...)
+	var ps []proto.Message
+	for {
+		p := newMessage()
+		if err := r.Read(p); errors.Is(err, io.EOF) {
+			return ps, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing message %d from %q failed: %w", len(ps), path, err)
+		}
+		ps = append(ps, p)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package protoio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestDelimitedRoundTrip(t *testing.T) {
+	want := []proto.Message{
+		&timestamppb.Timestamp{Seconds: 1},
+		// Empty messages are encoded as a single zero size.
+		&timestamppb.Timestamp{},
+		&timestamppb.Timestamp{Seconds: 123, Nanos: 456},
+	}
+	path := filepath.Join(t.TempDir(), "stream.binpb")
+
+	if err := WriteDelimitedProtos(path, want); err != nil {
+		t.Fatalf("WriteDelimitedProtos(%q) = %v, want nil", path, err)
+	}
+	got, err := ReadDelimitedProtos(path, func() proto.Message { return &timestamppb.Timestamp{} })
+	if err != nil {
+		t.Fatalf("ReadDelimitedProtos(%q) = %v, want nil", path, err)
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("ReadDelimitedProtos(%q) returned unexpected diff (-want +got):\n%s", path, diff)
+	}
+}
+
+func TestDelimitedReaderTruncated(t *testing.T) {
+	var b bytes.Buffer
+	if err := NewDelimitedWriter(&b).Write(&timestamppb.Timestamp{Seconds: 123}); err != nil {
+		t.Fatalf("Write() = %v, want nil", err)
+	}
+
+	r := NewDelimitedReader(bytes.NewReader(b.Bytes()[:b.Len()-1]))
+	if err := r.Read(&timestamppb.Timestamp{}); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Read() of truncated message = %v, want a non-EOF error", err)
+	}
+
+	r = NewDelimitedReader(bytes.NewReader(nil))
+	if err := r.Read(&timestamppb.Timestamp{}); err != io.EOF {
+		t.Errorf("Read() of empty stream = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestReadDelimitedProtosMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.binpb")
+	_, err := ReadDelimitedProtos(path, func() proto.Message { return &timestamppb.Timestamp{} })
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadDelimitedProtos(%q) = %v, want an error wrapping %v", path, err, os.ErrNotExist)
+	}
+}
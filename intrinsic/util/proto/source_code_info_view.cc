@@ -6,6 +6,7 @@
 #include <memory>
 #include <string>
 
+#include "absl/container/flat_hash_set.h"
 #include "absl/status/status.h"
 #include "absl/status/statusor.h"
 #include "absl/strings/str_cat.h"
@@ -21,6 +22,8 @@ namespace intrinsic {
 absl::Status SourceCodeInfoView::Init(
     const google::protobuf::FileDescriptorSet& file_descriptor_set) {
   pool_ = std::make_unique<Pool>();
+  nested_comment_maps_.clear();
+  num_walked_messages_ = 0;
   if (!std::all_of(
           file_descriptor_set.file().begin(), file_descriptor_set.file().end(),
           [pool =
@@ -115,14 +118,28 @@ SourceCodeInfoView::GetNestedFieldCommentMap(absl::string_view message_name) {
         absl::StrCat("Message does not exist with: ", message_name));
   }
 
+  if (auto it = nested_comment_maps_.find(message->full_name());
+      it != nested_comment_maps_.end()) {
+    return it->second;
+  }
+
   google::protobuf::Map<std::string, std::string> comment_map;
-  INTR_RETURN_IF_ERROR(GetNestedFieldCommentMap(message, comment_map));
+  absl::flat_hash_set<std::string> in_progress;
+  absl::flat_hash_set<std::string> cut;
+  INTR_RETURN_IF_ERROR(
+      GetNestedFieldCommentMap(message, comment_map, in_progress, cut));
+  // Only `message` itself can have been cut, so the map is complete.
+  nested_comment_maps_.insert({message->full_name(), comment_map});
   return comment_map;
 }
 
 absl::Status SourceCodeInfoView::GetNestedFieldCommentMap(
     const google::protobuf::Descriptor* message,
-    google::protobuf::Map<std::string, std::string>& comment_map) {
+    google::protobuf::Map<std::string, std::string>& comment_map,
+    absl::flat_hash_set<std::string>& in_progress,
+    absl::flat_hash_set<std::string>& cut) {
+  ++num_walked_messages_;
+  in_progress.insert(message->full_name());
   for (int field_index = 0; field_index < message->field_count();
        ++field_index) {
     const google::protobuf::FieldDescriptor* field =
@@ -143,22 +160,46 @@ absl::Status SourceCodeInfoView::GetNestedFieldCommentMap(
           field->message_type();
       field_to_recursively_process = msg_descriptor->map_value();
     }
-    if (field_to_recursively_process->cpp_type() ==
+    if (field_to_recursively_process->cpp_type() !=
         google::protobuf::FieldDescriptor::CPPTYPE_MESSAGE) {
-      const google::protobuf::Descriptor* msg_descriptor =
-          field_to_recursively_process->message_type();
-      auto msg_iter = comment_map.find(msg_descriptor->full_name());
-      if (msg_iter == comment_map.end()) {
-        // Get top-level message comments
-        INTR_ASSIGN_OR_RETURN(
-            std::string comment,
-            GetLeadingCommentsByMessageType(msg_descriptor->full_name()));
-        comment_map.insert({msg_descriptor->full_name(), comment});
-        INTR_RETURN_IF_ERROR(
-            GetNestedFieldCommentMap(msg_descriptor, comment_map));
-      }
+      continue;
+    }
+    const google::protobuf::Descriptor* msg_descriptor =
+        field_to_recursively_process->message_type();
+    const std::string& msg_name = msg_descriptor->full_name();
+    if (comment_map.find(msg_name) != comment_map.end()) {
+      // Already added via another field.
+      continue;
+    }
+    // Get top-level message comments
+    INTR_ASSIGN_OR_RETURN(std::string msg_comment,
+                          GetLeadingCommentsByMessageType(msg_name));
+    comment_map.insert({msg_name, msg_comment});
+
+    if (auto cached = nested_comment_maps_.find(msg_name);
+        cached != nested_comment_maps_.end()) {
+      comment_map.insert(cached->second.begin(), cached->second.end());
+      continue;
+    }
+    if (in_progress.contains(msg_name)) {
+      // A recursive message. Its fields are added by its own walk further up.
+      cut.insert(msg_name);
+      continue;
+    }
+
+    google::protobuf::Map<std::string, std::string> nested_map;
+    absl::flat_hash_set<std::string> nested_cut;
+    INTR_RETURN_IF_ERROR(GetNestedFieldCommentMap(msg_descriptor, nested_map,
+                                                  in_progress, nested_cut));
+    nested_cut.erase(msg_name);
+    if (nested_cut.empty()) {
+      nested_comment_maps_.insert({msg_name, nested_map});
+    } else {
+      cut.insert(nested_cut.begin(), nested_cut.end());
     }
+    comment_map.insert(nested_map.begin(), nested_map.end());
   }
+  in_progress.erase(message->full_name());
 
   return absl::OkStatus();
 }
@@ -7,6 +7,7 @@
 #include <string>
 
 #include "absl/container/flat_hash_map.h"
+#include "absl/container/flat_hash_set.h"
 #include "absl/status/status.h"
 #include "absl/status/statusor.h"
 #include "absl/strings/string_view.h"
@@ -67,13 +68,30 @@ class SourceCodeInfoView {
   // Retrieves all field comments and message comments of the given message and
   // all of its nested submessages. They keys of the map are the full name of
   // the message or field that the comment applies to, the value is the comment.
+  //
+  // Results are cached per message, including the submessages reached while
+  // walking a message, so shared submessages are walked once per view, e.g.,
+  // across the parameter and return messages of a skill that share a file
+  // descriptor set.
   absl::StatusOr<google::protobuf::Map<std::string, std::string>>
   GetNestedFieldCommentMap(absl::string_view message_name);
 
+  // Returns how many messages had their fields walked by
+  // GetNestedFieldCommentMap since the last Init().
+  int NumWalkedMessagesForTesting() const { return num_walked_messages_; }
+
  private:
+  // Adds the comments of the fields of `message` and of all nested
+  // submessages to `comment_map`. `in_progress` holds the messages currently
+  // being walked. Recursive messages among them are only referenced, not
+  // walked again; their names are added to `cut`, since the comment maps of
+  // the messages in between are incomplete without them and must not be
+  // cached.
   absl::Status GetNestedFieldCommentMap(
       const google::protobuf::Descriptor* message,
-      google::protobuf::Map<std::string, std::string>& comment_map);
+      google::protobuf::Map<std::string, std::string>& comment_map,
+      absl::flat_hash_set<std::string>& in_progress,
+      absl::flat_hash_set<std::string>& cut);
 
   struct Pool {
     Pool() : descriptor_pool(&descriptor_database) {}
@@ -82,6 +100,14 @@ class SourceCodeInfoView {
   };
 
   std::unique_ptr<Pool> pool_;
+
+  // Comment maps of messages that have been fully processed, keyed by the full
+  // name of the message.
+  absl::flat_hash_map<std::string,
+                      google::protobuf::Map<std::string, std::string>>
+      nested_comment_maps_;
+
+  int num_walked_messages_ = 0;
 };
 
 }  // namespace intrinsic
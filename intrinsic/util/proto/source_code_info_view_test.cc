@@ -0,0 +1,137 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+#include "intrinsic/util/proto/source_code_info_view.h"
+
+#include <gmock/gmock.h>
+#include <gtest/gtest.h>
+
+#include <string>
+
+#include "absl/status/statusor.h"
+#include "google/protobuf/descriptor.pb.h"
+#include "google/protobuf/map.h"
+#include "google/protobuf/text_format.h"
+#include "intrinsic/util/testing/gtest_wrapper.h"
+
+namespace intrinsic {
+namespace {
+
+using ::testing::Pair;
+using ::testing::UnorderedElementsAre;
+
+// Parent references Shared directly and through Middle, Node references
+// itself.
+constexpr char kFileDescriptorSet[] = R"pb(
+  file {
+    name: "shared.proto"
+    package: "test"
+    message_type {
+      name: "Shared"
+      field { name: "value" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 }
+    }
+    message_type {
+      name: "Middle"
+      field {
+        name: "shared"
+        number: 1
+        label: LABEL_OPTIONAL
+        type: TYPE_MESSAGE
+        type_name: ".test.Shared"
+      }
+    }
+    message_type {
+      name: "Parent"
+      field {
+        name: "shared"
+        number: 1
+        label: LABEL_OPTIONAL
+        type: TYPE_MESSAGE
+        type_name: ".test.Shared"
+      }
+      field {
+        name: "middle"
+        number: 2
+        label: LABEL_OPTIONAL
+        type: TYPE_MESSAGE
+        type_name: ".test.Middle"
+      }
+    }
+    message_type {
+      name: "Node"
+      field {
+        name: "child"
+        number: 1
+        label: LABEL_OPTIONAL
+        type: TYPE_MESSAGE
+        type_name: ".test.Node"
+      }
+    }
+    source_code_info {
+      location { path: [ 4, 0 ] leading_comments: " Shared." }
+      location { path: [ 4, 0, 2, 0 ] leading_comments: " Shared.value." }
+      location { path: [ 4, 1 ] leading_comments: " Middle." }
+      location { path: [ 4, 1, 2, 0 ] leading_comments: " Middle.shared." }
+      location { path: [ 4, 2 ] leading_comments: " Parent." }
+      location { path: [ 4, 2, 2, 0 ] leading_comments: " Parent.shared." }
+      location { path: [ 4, 2, 2, 1 ] leading_comments: " Parent.middle." }
+      location { path: [ 4, 3 ] leading_comments: " Node." }
+      location { path: [ 4, 3, 2, 0 ] leading_comments: " Node.child." }
+    }
+  }
+)pb";
+
+SourceCodeInfoView InitView() {
+  google::protobuf::FileDescriptorSet file_descriptor_set;
+  EXPECT_TRUE(google::protobuf::TextFormat::ParseFromString(
+      kFileDescriptorSet, &file_descriptor_set));
+  SourceCodeInfoView view;
+  EXPECT_TRUE(view.InitStrict(file_descriptor_set).ok());
+  return view;
+}
+
+TEST(SourceCodeInfoViewTest, GetNestedFieldCommentMapWalksSharedMessagesOnce) {
+  SourceCodeInfoView view = InitView();
+
+  absl::StatusOr<google::protobuf::Map<std::string, std::string>> parent =
+      view.GetNestedFieldCommentMap("test.Parent");
+  ASSERT_TRUE(parent.ok()) << parent.status();
+  EXPECT_THAT(*parent, UnorderedElementsAre(
+                           Pair("test.Parent.shared", " Parent.shared."),
+                           Pair("test.Parent.middle", " Parent.middle."),
+                           Pair("test.Shared", " Shared."),
+                           Pair("test.Shared.value", " Shared.value."),
+                           Pair("test.Middle", " Middle."),
+                           Pair("test.Middle.shared", " Middle.shared.")));
+  // Parent, Shared and Middle. Shared is not walked again below Middle.
+  EXPECT_EQ(view.NumWalkedMessagesForTesting(), 3);
+
+  // The submessages are resolved from the walk of Parent.
+  absl::StatusOr<google::protobuf::Map<std::string, std::string>> middle =
+      view.GetNestedFieldCommentMap("test.Middle");
+  ASSERT_TRUE(middle.ok()) << middle.status();
+  EXPECT_THAT(*middle, UnorderedElementsAre(
+                           Pair("test.Middle.shared", " Middle.shared."),
+                           Pair("test.Shared", " Shared."),
+                           Pair("test.Shared.value", " Shared.value.")));
+  absl::StatusOr<google::protobuf::Map<std::string, std::string>> shared =
+      view.GetNestedFieldCommentMap("test.Shared");
+  ASSERT_TRUE(shared.ok()) << shared.status();
+  EXPECT_THAT(*shared,
+              UnorderedElementsAre(Pair("test.Shared.value", " Shared.value.")));
+  EXPECT_EQ(view.NumWalkedMessagesForTesting(), 3);
+}
+
+TEST(SourceCodeInfoViewTest, GetNestedFieldCommentMapRecursiveMessage) {
+  SourceCodeInfoView view = InitView();
+
+  absl::StatusOr<google::protobuf::Map<std::string, std::string>> node =
+      view.GetNestedFieldCommentMap("test.Node");
+  ASSERT_TRUE(node.ok()) << node.status();
+  EXPECT_THAT(*node,
+              UnorderedElementsAre(Pair("test.Node.child", " Node.child."),
+                                   Pair("test.Node", " Node.")));
+  EXPECT_EQ(view.NumWalkedMessagesForTesting(), 1);
+}
+
+}  // namespace
+}  // namespace intrinsic
@@ -45,10 +45,38 @@ type Info struct {
 	Context           []*estpb.ExtendedStatus
 	ContextFromErrors []error
 	LogContext        *ctxpb.Context
+	// Compact, if set, is applied to the context of the new status, see
+	// Compact for details.
+	Compact *CompactOptions
+}
+
+// CompactOptions configures how the context of an ExtendedStatus is reduced in
+// size. The zero value leaves the context unchanged.
+type CompactOptions struct {
+	// MaxDepth is the maximum nesting depth of context entries. Deeper entries
+	// are removed. Zero means unlimited.
+	MaxDepth int
+	// MaxBytes is the maximum serialized size of the status. Context is removed
+	// level by level, starting with the deepest, until the status fits. Zero
+	// means unlimited.
+	MaxBytes int
+	// Dedupe removes context entries with the same status code, title and
+	// external message as an entry earlier in the status. The context of
+	// removed entries is kept.
+	Dedupe bool
 }
 
 // New creates an ExtendedStatus with the given StatusCode (component + numeric code).
+//
+// If stack trace capture is enabled with SetStackTraceCapture, the call stack
+// is recorded into the debug report.
 func New(component string, code uint32, info *Info) *ExtendedStatus {
+	return newStatus(component, code, info, 1)
+}
+
+// newStatus implements New. skip is the number of frames between the caller
+// of New or NewError and newStatus.
+func newStatus(component string, code uint32, info *Info, skip int) *ExtendedStatus {
 	p := &estpb.ExtendedStatus{StatusCode: &estpb.StatusCode{
 		Code: code, Component: component}}
 	if info.Title != "" {
@@ -64,24 +92,37 @@ func New(component string, code uint32, info *Info) *ExtendedStatus {
 		p.Context = append(p.Context, context)
 	}
 	for _, errContext := range info.ContextFromErrors {
-		context, err := FromError(errContext)
-		if err != nil {
-			// Failed to convert error to extended status, do it the
-			// "old-fashioned" way from the error interface
-			context = New("unknown-downstream", 0,
-				&Info{Title: errContext.Error()})
-		}
-		p.Context = append(p.Context, context.Proto())
+		p.Context = append(p.Context, contextFromError(errContext).Proto())
 	}
 	if info.LogContext != nil {
 		p.RelatedTo = &estpb.ExtendedStatus_Relations{LogContext: info.LogContext}
 	}
+	if captureStackTraces.Load() {
+		p.DebugReport = &estpb.ExtendedStatus_DebugReport{StackTrace: stackTrace(skip + 1)}
+	}
+	if info.Compact != nil {
+		// Context entries are shared with the caller, so compact a copy.
+		p = Compact(p, info.Compact)
+	}
+	report(EventCreated, component, code)
 	return &ExtendedStatus{s: p}
 }
 
+// contextFromError converts an error to an ExtendedStatus to use as context.
+func contextFromError(errContext error) *ExtendedStatus {
+	context, err := FromError(errContext)
+	if err != nil {
+		// Failed to convert error to extended status, do it the
+		// "old-fashioned" way from the error interface
+		context = New("unknown-downstream", 0,
+			&Info{Title: errContext.Error()})
+	}
+	return context
+}
+
 // NewError creates an ExtendedStatus wrapped in an error.
 func NewError(component string, code uint32, info *Info) error {
-	return New(component, code, info).Err()
+	return newStatus(component, code, info, 1).Err()
 }
 
 // FromProto creates a new ExtendedStatus from a given ExtendedStatus proto.
@@ -89,6 +130,120 @@ func FromProto(es *estpb.ExtendedStatus) *ExtendedStatus {
 	return &ExtendedStatus{s: proto.Clone(es).(*estpb.ExtendedStatus)}
 }
 
+// Compact returns a copy of the given ExtendedStatus proto with its context
+// reduced according to opts, for example to keep it within gRPC message size
+// limits. Whenever context entries are removed, the internal report of their
+// parent notes how many entries were omitted.
+func Compact(es *estpb.ExtendedStatus, opts *CompactOptions) *estpb.ExtendedStatus {
+	c := proto.Clone(es).(*estpb.ExtendedStatus)
+	if opts != nil {
+		compact(c, opts)
+	}
+	return c
+}
+
+func compact(es *estpb.ExtendedStatus, opts *CompactOptions) {
+	if opts.Dedupe {
+		seen := map[statusKey]bool{keyOf(es): true}
+		es.Context = dedupeContext(es.GetContext(), seen)
+	}
+	if opts.MaxDepth > 0 {
+		truncateContext(es, opts.MaxDepth)
+	}
+	if opts.MaxBytes > 0 && proto.Size(es) > opts.MaxBytes {
+		// Truncate a copy each time, so that the notes count all omitted entries.
+		for depth := contextDepth(es) - 1; depth >= 0; depth-- {
+			t := proto.Clone(es).(*estpb.ExtendedStatus)
+			truncateContext(t, depth)
+			if proto.Size(t) <= opts.MaxBytes || depth == 0 {
+				proto.Reset(es)
+				proto.Merge(es, t)
+				break
+			}
+		}
+	}
+}
+
+// statusKey identifies duplicate statuses. Besides the status code it
+// includes the title and external message, since statuses converted from
+// plain errors all share the code 0 of "unknown-downstream".
+type statusKey struct {
+	component string
+	code      uint32
+	title     string
+	message   string
+}
+
+func keyOf(es *estpb.ExtendedStatus) statusKey {
+	return statusKey{
+		component: es.GetStatusCode().GetComponent(),
+		code:      es.GetStatusCode().GetCode(),
+		title:     es.GetTitle(),
+		message:   es.GetExternalReport().GetMessage(),
+	}
+}
+
+// dedupeContext removes entries with a key in seen from context and adds the
+// keys of all remaining entries to seen. The context of removed entries takes
+// their place as it may carry additional information.
+func dedupeContext(context []*estpb.ExtendedStatus, seen map[statusKey]bool) []*estpb.ExtendedStatus {
+	var result []*estpb.ExtendedStatus
+	for _, c := range context {
+		key := keyOf(c)
+		if seen[key] {
+			result = append(result, dedupeContext(c.GetContext(), seen)...)
+			continue
+		}
+		seen[key] = true
+		c.Context = dedupeContext(c.GetContext(), seen)
+		result = append(result, c)
+	}
+	return result
+}
+
+// contextDepth returns the maximum nesting depth of the context of es.
+func contextDepth(es *estpb.ExtendedStatus) int {
+	depth := 0
+	for _, c := range es.GetContext() {
+		if d := contextDepth(c) + 1; d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// countContext returns the total number of (nested) context entries of es.
+func countContext(es *estpb.ExtendedStatus) int {
+	n := len(es.GetContext())
+	for _, c := range es.GetContext() {
+		n += countContext(c)
+	}
+	return n
+}
+
+// truncateContext removes all context entries nested deeper than maxDepth.
+func truncateContext(es *estpb.ExtendedStatus, maxDepth int) {
+	if maxDepth > 0 {
+		for _, c := range es.GetContext() {
+			truncateContext(c, maxDepth-1)
+		}
+		return
+	}
+	n := countContext(es)
+	if n == 0 {
+		return
+	}
+	es.Context = nil
+	note := fmt.Sprintf("(%d context entries omitted)", n)
+	if es.GetInternalReport() == nil {
+		es.InternalReport = &estpb.ExtendedStatus_Report{}
+	}
+	if es.InternalReport.Message != "" {
+		note = es.InternalReport.Message + "\n" + note
+	}
+	es.InternalReport.Message = note
+}
+
 // FromError converts an error to an ExtendedStatus. This may fail if the error
 // was not created from an ExtendedStatus.
 func FromError(err error) (*ExtendedStatus, error) {
@@ -167,8 +322,12 @@ func (e *Error) GRPCStatus() *status.Status {
 }
 
 // Is implements future error.Is functionality.
-// A Error is equivalent if StatusCodes are identical.
+// A Error is equivalent if StatusCodes are identical. A Matcher is equivalent
+// if it matches the StatusCode.
 func (e *Error) Is(target error) bool {
+	if m, ok := target.(*Matcher); ok {
+		return m.Matches(e.es.s)
+	}
 	tse, ok := target.(*Error)
 	if !ok {
 		return false
@@ -14,6 +14,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/local"
 	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 	ctxpb "intrinsic/logging/proto/context_go_proto"
@@ -246,6 +247,46 @@ func TestErrorIs(t *testing.T) {
 	}
 }
 
+func TestErrorIsMatcher(t *testing.T) {
+	err := fmt.Errorf("moving: %w", NewError("ai.intrinsic.icon", 2042, &Info{Title: "test error"}))
+	tests := []struct {
+		matcher *Matcher
+		want    bool
+	}{
+		{matcher: Code("ai.intrinsic.icon", 2042), want: true},
+		{matcher: Code("ai.intrinsic.icon", 2043), want: false},
+		{matcher: CodeRange("ai.intrinsic.icon", 2000, 2999), want: true},
+		{matcher: CodeRange("ai.intrinsic.icon", 3000, 3999), want: false},
+		{matcher: CodeRange("ai.intrinsic.test", 2000, 2999), want: false},
+		{matcher: Component("ai.intrinsic.icon"), want: true},
+	}
+	for _, tc := range tests {
+		if got := errors.Is(err, tc.matcher); got != tc.want {
+			t.Errorf("errors.Is(%v, %v) = %v, want %v", err, tc.matcher, got, tc.want)
+		}
+	}
+	if errors.Is(fmt.Errorf("test error"), Component("ai.intrinsic.icon")) {
+		t.Errorf("errors.Is() matched an error without extended status")
+	}
+}
+
+func TestMatchGRPCError(t *testing.T) {
+	gs, err := grpcstatus.New(codes.Internal, "test error").WithDetails(
+		statusWithCode("ai.intrinsic.icon", 2042))
+	if err != nil {
+		t.Fatalf("Failed to create GRPC status: %v", err)
+	}
+	if !Match(gs.Err(), CodeRange("ai.intrinsic.icon", 2000, 2999)) {
+		t.Errorf("Match() did not match the extended status of a gRPC error")
+	}
+	if Match(gs.Err(), Code("ai.intrinsic.icon", 2000)) {
+		t.Errorf("Match() matched the wrong code")
+	}
+	if Match(grpcstatus.Error(codes.Internal, "test error"), Component("ai.intrinsic.icon")) {
+		t.Errorf("Match() matched a gRPC error without extended status")
+	}
+}
+
 func TestFromGRPCErrorSkipsUnrelatedDetails(t *testing.T) {
 	extStProto := &estpb.ExtendedStatus{
 		StatusCode: &estpb.StatusCode{
@@ -314,3 +355,155 @@ func TestGrpcServiceCall(t *testing.T) {
 		t.Errorf("Status proto returned unexpected diff (-want +got):\n%s", diff)
 	}
 }
+
+func statusWithCode(component string, code uint32, context ...*estpb.ExtendedStatus) *estpb.ExtendedStatus {
+	return &estpb.ExtendedStatus{
+		StatusCode: &estpb.StatusCode{Component: component, Code: code},
+		Context:    context}
+}
+
+func TestCompact(t *testing.T) {
+	chain := statusWithCode("ai.intrinsic.test", 1,
+		statusWithCode("ai.intrinsic.a", 2,
+			statusWithCode("ai.intrinsic.b", 3,
+				statusWithCode("ai.intrinsic.c", 4))))
+
+	tests := []struct {
+		name string
+		es   *estpb.ExtendedStatus
+		opts *CompactOptions
+		want *estpb.ExtendedStatus
+	}{
+		{"NoOptions", chain, &CompactOptions{}, chain},
+		{"MaxDepth", chain, &CompactOptions{MaxDepth: 1},
+			statusWithCode("ai.intrinsic.test", 1,
+				&estpb.ExtendedStatus{
+					StatusCode:     &estpb.StatusCode{Component: "ai.intrinsic.a", Code: 2},
+					InternalReport: &estpb.ExtendedStatus_Report{Message: "(2 context entries omitted)"}})},
+		{"Dedupe",
+			statusWithCode("ai.intrinsic.test", 1,
+				statusWithCode("ai.intrinsic.a", 2,
+					statusWithCode("ai.intrinsic.a", 2,
+						statusWithCode("ai.intrinsic.b", 3))),
+				statusWithCode("ai.intrinsic.b", 3)),
+			&CompactOptions{Dedupe: true},
+			statusWithCode("ai.intrinsic.test", 1,
+				statusWithCode("ai.intrinsic.a", 2,
+					statusWithCode("ai.intrinsic.b", 3)))},
+		{"MaxBytes", chain, &CompactOptions{MaxBytes: proto.Size(statusWithCode("ai.intrinsic.test", 1)) + 40},
+			&estpb.ExtendedStatus{
+				StatusCode:     &estpb.StatusCode{Component: "ai.intrinsic.test", Code: 1},
+				InternalReport: &estpb.ExtendedStatus_Report{Message: "(3 context entries omitted)"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := proto.Clone(test.es)
+			got := Compact(test.es, test.opts)
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Compact() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(before, test.es, protocmp.Transform()); diff != "" {
+				t.Errorf("Compact() modified its input (-before +after):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewWithCompact(t *testing.T) {
+	err := NewError("ai.intrinsic.backend", 2, &Info{})
+	es := New("ai.intrinsic.test", 1, &Info{
+		ContextFromErrors: []error{err, err},
+		Compact:           &CompactOptions{Dedupe: true}})
+
+	want := statusWithCode("ai.intrinsic.test", 1, statusWithCode("ai.intrinsic.backend", 2))
+	if diff := cmp.Diff(want, es.Proto(), protocmp.Transform()); diff != "" {
+		t.Errorf("New() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewWithCompactKeepsDistinctErrors(t *testing.T) {
+	errA := fmt.Errorf("read config: %w", errors.New("file not found"))
+	errB := fmt.Errorf("connect: %w", errors.New("connection refused"))
+	es := New("ai.intrinsic.test", 1, &Info{
+		ContextFromErrors: []error{errA, errB, errA},
+		Compact:           &CompactOptions{Dedupe: true}})
+
+	var got []string
+	for _, c := range es.Proto().GetContext() {
+		got = append(got, c.GetTitle())
+	}
+	want := []string{"read config: file not found", "connect: connection refused"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("New() returned unexpected context titles (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	r := NewRenderer("en")
+	r.AddCatalog("en", Catalog{
+		"test.not_found": "Skill {0} not found in {1}",
+		"test.en_only":   "Only {0}",
+	})
+	r.AddCatalog("de", Catalog{"test.not_found": "Skill {0} in {1} nicht gefunden"})
+
+	es := New("ai.intrinsic.test", 2342, &Info{
+		ExternalMessage: "pre-rendered",
+		Context: []*estpb.ExtendedStatus{
+			New("ai.intrinsic.test", 2343, &Info{}).With(WithUserMessageKey("test.en_only", 42)).Proto(),
+			New("ai.intrinsic.test", 2344, &Info{ExternalMessage: "fallback"}).With(WithUserMessageKey("test.unknown")).Proto(),
+			New("ai.intrinsic.test", 2345, &Info{}).With(WithUserMessageKey("test.unknown")).Proto(),
+		},
+	}).With(WithUserMessageKey("test.not_found", "foo", "bar"))
+
+	got := r.Localize(es.Proto(), "de_CH")
+	want := &estpb.ExtendedStatus{
+		StatusCode: &estpb.StatusCode{Component: "ai.intrinsic.test", Code: 2342},
+		ExternalReport: &estpb.ExtendedStatus_Report{
+			Message:    "Skill foo in bar nicht gefunden",
+			MessageKey: &estpb.ExtendedStatus_MessageKey{Key: "test.not_found", Args: []string{"foo", "bar"}},
+		},
+		Context: []*estpb.ExtendedStatus{
+			{
+				StatusCode: &estpb.StatusCode{Component: "ai.intrinsic.test", Code: 2343},
+				ExternalReport: &estpb.ExtendedStatus_Report{
+					Message:    "Only 42",
+					MessageKey: &estpb.ExtendedStatus_MessageKey{Key: "test.en_only", Args: []string{"42"}},
+				},
+			},
+			{
+				StatusCode: &estpb.StatusCode{Component: "ai.intrinsic.test", Code: 2344},
+				ExternalReport: &estpb.ExtendedStatus_Report{
+					Message:    "fallback",
+					MessageKey: &estpb.ExtendedStatus_MessageKey{Key: "test.unknown"},
+				},
+			},
+			{
+				StatusCode: &estpb.StatusCode{Component: "ai.intrinsic.test", Code: 2345},
+				ExternalReport: &estpb.ExtendedStatus_Report{
+					Message:    "test.unknown",
+					MessageKey: &estpb.ExtendedStatus_MessageKey{Key: "test.unknown"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Localize() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if msg := es.Proto().GetExternalReport().GetMessage(); msg != "pre-rendered" {
+		t.Errorf("Localize() modified its input, got message %q", msg)
+	}
+}
+
+func TestParseCatalog(t *testing.T) {
+	c, err := ParseCatalog([]byte(`{"test.key": "Message {0}"}`))
+	if err != nil {
+		t.Fatalf("ParseCatalog() failed: %v", err)
+	}
+	if diff := cmp.Diff(Catalog{"test.key": "Message {0}"}, c); diff != "" {
+		t.Errorf("ParseCatalog() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if _, err := ParseCatalog([]byte(`["not", "a", "map"]`)); err == nil {
+		t.Errorf("ParseCatalog() succeeded for a list, want error")
+	}
+}
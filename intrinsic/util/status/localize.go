@@ -0,0 +1,127 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package extstatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	estpb "intrinsic/util/status/extended_status_go_proto"
+)
+
+var placeholderRegex = regexp.MustCompile(`\{(\d+)\}`)
+
+// Catalog maps message keys to the messages of one language. Messages may
+// contain the placeholders {0}, {1}, ... which are replaced by the arguments
+// of the message key.
+type Catalog map[string]string
+
+// ParseCatalog parses a catalog from a JSON object which maps message keys to
+// messages.
+func ParseCatalog(b []byte) (Catalog, error) {
+	c := Catalog{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse message catalog: %v", err)
+	}
+	return c, nil
+}
+
+// Renderer renders user messages from the catalogs of several languages.
+type Renderer struct {
+	catalogs      map[string]Catalog
+	defaultLocale string
+}
+
+// NewRenderer creates a renderer which falls back to the catalog of
+// defaultLocale if a message is not available in the requested language.
+func NewRenderer(defaultLocale string) *Renderer {
+	return &Renderer{
+		catalogs:      map[string]Catalog{},
+		defaultLocale: normalizeLocale(defaultLocale),
+	}
+}
+
+// AddCatalog adds the messages of c to the catalog of locale, e.g., "en" or
+// "de-CH". Messages which already exist are replaced.
+func (r *Renderer) AddCatalog(locale string, c Catalog) {
+	locale = normalizeLocale(locale)
+	if r.catalogs[locale] == nil {
+		r.catalogs[locale] = Catalog{}
+	}
+	for key, msg := range c {
+		r.catalogs[locale][key] = msg
+	}
+}
+
+// normalizeLocale converts locales such as "de_CH" to "de-ch".
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+// lookup finds the message for key, trying locale, its parent locales and then
+// the default locale.
+func (r *Renderer) lookup(key, locale string) (string, bool) {
+	for l := normalizeLocale(locale); l != ""; {
+		if msg, ok := r.catalogs[l][key]; ok {
+			return msg, true
+		}
+		i := strings.LastIndex(l, "-")
+		if i < 0 {
+			break
+		}
+		l = l[:i]
+	}
+	msg, ok := r.catalogs[r.defaultLocale][key]
+	return msg, ok
+}
+
+// Render returns the message of report in the given locale. The pre-rendered
+// message of the report is returned if it has no message key or the key is not
+// in any matching catalog. If there is no pre-rendered message either, the key
+// itself is returned so that the user sees something searchable.
+func (r *Renderer) Render(report *estpb.ExtendedStatus_Report, locale string) string {
+	mk := report.GetMessageKey()
+	if mk.GetKey() == "" {
+		return report.GetMessage()
+	}
+	if msg, ok := r.lookup(mk.GetKey(), locale); ok {
+		return expandPlaceholders(msg, mk.GetArgs())
+	}
+	if report.GetMessage() != "" {
+		return report.GetMessage()
+	}
+	return mk.GetKey()
+}
+
+// expandPlaceholders replaces the placeholders {i} in msg by args[i].
+// Placeholders without an argument are kept.
+func expandPlaceholders(msg string, args []string) string {
+	return placeholderRegex.ReplaceAllStringFunc(msg, func(p string) string {
+		i, err := strconv.Atoi(p[1 : len(p)-1])
+		if err != nil || i >= len(args) {
+			return p
+		}
+		return args[i]
+	})
+}
+
+// Localize returns a copy of es in which the messages of all external reports,
+// including those of the context, are rendered in the given locale.
+func (r *Renderer) Localize(es *estpb.ExtendedStatus, locale string) *estpb.ExtendedStatus {
+	c := proto.Clone(es).(*estpb.ExtendedStatus)
+	r.localize(c, locale)
+	return c
+}
+
+func (r *Renderer) localize(es *estpb.ExtendedStatus, locale string) {
+	if report := es.GetExternalReport(); report != nil {
+		report.Message = r.Render(report, locale)
+	}
+	for _, c := range es.GetContext() {
+		r.localize(c, locale)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package extstatus
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	estpb "intrinsic/util/status/extended_status_go_proto"
+)
+
+// Matcher matches extended status errors by component and a range of codes.
+// It is used as the target of errors.Is, which reports whether an error in
+// the chain is an extended status error with a matching status code.
+//
+// Example:
+//
+//	if errors.Is(err, extstatus.CodeRange("ai.intrinsic.icon", 2000, 2999)) {
+//		// Handle all motion errors.
+//	}
+type Matcher struct {
+	component string
+	min, max  uint32
+}
+
+// Code returns a matcher for the status code with the given component and
+// code.
+func Code(component string, code uint32) *Matcher {
+	return &Matcher{component: component, min: code, max: code}
+}
+
+// CodeRange returns a matcher for the status codes of the given component
+// from min to max, both inclusive.
+func CodeRange(component string, min, max uint32) *Matcher {
+	return &Matcher{component: component, min: min, max: max}
+}
+
+// Component returns a matcher for all status codes of the given component.
+func Component(component string) *Matcher {
+	return &Matcher{component: component, min: 0, max: math.MaxUint32}
+}
+
+// Error implements the error interface so that matchers can be passed to
+// errors.Is.
+func (m *Matcher) Error() string {
+	if m.min == m.max {
+		return fmt.Sprintf("%s:%d", m.component, m.min)
+	}
+	return fmt.Sprintf("%s:%d-%d", m.component, m.min, m.max)
+}
+
+// Matches reports whether the status code of es matches. The context of es is
+// not considered.
+func (m *Matcher) Matches(es *estpb.ExtendedStatus) bool {
+	code := es.GetStatusCode()
+	return code.GetComponent() == m.component && code.GetCode() >= m.min && code.GetCode() <= m.max
+}
+
+// Match reports whether err is an extended status error which m matches.
+// Unlike errors.Is, it also matches errors returned by gRPC calls which carry
+// an extended status in their details.
+func Match(err error, m *Matcher) bool {
+	if errors.Is(err, m) {
+		return true
+	}
+	es, convErr := FromGRPCError(err)
+	return convErr == nil && m.Matches(es.Proto())
+}
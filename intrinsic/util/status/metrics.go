@@ -0,0 +1,125 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package extstatus
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	estpb "intrinsic/util/status/extended_status_go_proto"
+)
+
+// Event is the kind of event reported to a Hook.
+type Event int
+
+const (
+	// EventCreated is reported when an ExtendedStatus is created with New or
+	// NewError.
+	EventCreated Event = iota
+	// EventConvertedToGRPC is reported when a gRPC service returns an
+	// ExtendedStatus as error. It is only reported by the server interceptors,
+	// see UnaryServerInterceptor.
+	EventConvertedToGRPC
+)
+
+func (e Event) String() string {
+	switch e {
+	case EventCreated:
+		return "created"
+	case EventConvertedToGRPC:
+		return "grpc"
+	default:
+		return fmt.Sprintf("Event(%d)", int(e))
+	}
+}
+
+// Hook is called for every Event with the status code of the ExtendedStatus,
+// e.g., to count errors per code. It is called synchronously and may be called
+// concurrently, so it must be fast and safe for concurrent use.
+type Hook func(event Event, component string, code uint32)
+
+var hook atomic.Pointer[Hook]
+
+// SetHook installs h as the process wide hook, replacing the previous one. A
+// nil h removes the hook. Example:
+//
+//	func main() {
+//		extstatus.SetHook(extstatus.ExpvarHook("extended_status_events"))
+//		...
+//	}
+func SetHook(h Hook) {
+	if h == nil {
+		hook.Store(nil)
+		return
+	}
+	hook.Store(&h)
+}
+
+func report(event Event, component string, code uint32) {
+	if h := hook.Load(); h != nil {
+		(*h)(event, component, code)
+	}
+}
+
+// ExpvarHook returns a Hook which counts events in the published expvar.Map
+// with the given name, keyed by "<event> <component>:<code>", e.g., "grpc
+// ai.intrinsic.my_service:2343". The map is created if it does not exist yet.
+// Exporters of expvar, e.g., for Prometheus, turn it into error rates per
+// status code.
+func ExpvarHook(name string) Hook {
+	m, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		m = expvar.NewMap(name)
+	}
+	return func(event Event, component string, code uint32) {
+		m.Add(fmt.Sprintf("%v %s:%d", event, component, code), 1)
+	}
+}
+
+// reportReturned reports EventConvertedToGRPC if err is or carries an
+// ExtendedStatus.
+func reportReturned(err error) {
+	if err == nil {
+		return
+	}
+	var es *estpb.ExtendedStatus
+	var e *Error
+	if errors.As(err, &e) {
+		es = e.es.s
+	} else if st, ok := status.FromError(err); ok {
+		for _, detail := range st.Details() {
+			if d, ok := detail.(*estpb.ExtendedStatus); ok {
+				es = d
+				break
+			}
+		}
+	}
+	if es != nil {
+		report(EventConvertedToGRPC, es.GetStatusCode().GetComponent(), es.GetStatusCode().GetCode())
+	}
+}
+
+// UnaryServerInterceptor reports EventConvertedToGRPC for every ExtendedStatus
+// which a unary method of the server returns. Example:
+//
+//	grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(extstatus.UnaryServerInterceptor),
+//		grpc.ChainStreamInterceptor(extstatus.StreamServerInterceptor))
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	reportReturned(err)
+	return resp, err
+}
+
+// StreamServerInterceptor is like UnaryServerInterceptor for streaming
+// methods.
+func StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	reportReturned(err)
+	return err
+}
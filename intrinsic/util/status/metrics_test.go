@@ -0,0 +1,66 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package extstatus
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func TestHook(t *testing.T) {
+	var got []string
+	SetHook(func(event Event, component string, code uint32) {
+		got = append(got, fmt.Sprintf("%v %s:%d", event, component, code))
+	})
+	defer SetHook(nil)
+
+	err := NewError("ai.intrinsic.test", 2342, &Info{Title: "title"})
+	// gRPC may convert an error several times, which must not be counted.
+	for i := 0; i < 2; i++ {
+		if _, ok := grpcstatus.FromError(err); !ok {
+			t.Fatalf("FromError(%v) failed", err)
+		}
+	}
+	handler := func(ctx context.Context, req any) (any, error) { return nil, err }
+	if _, gotErr := UnaryServerInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler); gotErr != err {
+		t.Errorf("UnaryServerInterceptor() returned %v, want %v", gotErr, err)
+	}
+	want := []string{"created ai.intrinsic.test:2342", "grpc ai.intrinsic.test:2342"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Hook got unexpected events (-want +got):\n%s", diff)
+	}
+
+	SetHook(nil)
+	New("ai.intrinsic.test", 1, &Info{})
+	if len(got) != len(want) {
+		t.Errorf("Hook got %v after SetHook(nil), want no further events", got)
+	}
+}
+
+func TestExpvarHook(t *testing.T) {
+	SetHook(ExpvarHook("extstatus_test_events"))
+	defer SetHook(nil)
+
+	New("ai.intrinsic.test", 7, &Info{})
+	// A service may also return the gRPC status error directly.
+	err := New("ai.intrinsic.test", 7, &Info{}).GRPCStatus().Err()
+	StreamServerInterceptor(nil, nil, &grpc.StreamServerInfo{}, func(srv any, ss grpc.ServerStream) error { return err })
+	StreamServerInterceptor(nil, nil, &grpc.StreamServerInfo{}, func(srv any, ss grpc.ServerStream) error { return nil })
+
+	m := expvar.Get("extstatus_test_events").(*expvar.Map)
+	for key, want := range map[string]string{"created ai.intrinsic.test:7": "2", "grpc ai.intrinsic.test:7": "1"} {
+		if got := m.Get(key); got == nil || got.String() != want {
+			t.Errorf("extstatus_test_events[%q] = %v, want %s", key, got, want)
+		}
+	}
+	// Using the same name again continues counting in the same map.
+	if ExpvarHook("extstatus_test_events") == nil {
+		t.Errorf("ExpvarHook() with an existing name returned nil")
+	}
+}
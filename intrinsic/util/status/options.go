@@ -0,0 +1,161 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package extstatus
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	ctxpb "intrinsic/logging/proto/context_go_proto"
+	estpb "intrinsic/util/status/extended_status_go_proto"
+)
+
+// An Option modifies the copy of an ExtendedStatus created by With.
+type Option func(*estpb.ExtendedStatus)
+
+// With returns a copy of the ExtendedStatus with the options applied. The
+// original status is not modified, so that a layer which receives a status
+// from downstream can enrich it while it propagates. Example:
+//
+//	es, err := extstatus.FromGRPCError(err)
+//	if err != nil {
+//		return err
+//	}
+//	return es.With(extstatus.WithMinSeverity(estpb.ExtendedStatus_ERROR),
+//		extstatus.WithLogContext(logContext)).Err()
+func (e *ExtendedStatus) With(opts ...Option) *ExtendedStatus {
+	p := proto.Clone(e.s).(*estpb.ExtendedStatus)
+	for _, opt := range opts {
+		opt(p)
+	}
+	return &ExtendedStatus{s: p}
+}
+
+// WithTitle replaces the title.
+func WithTitle(title string) Option {
+	return func(p *estpb.ExtendedStatus) {
+		p.Title = title
+	}
+}
+
+// WithInternalMessage replaces the message of the internal report. Its
+// instructions are kept.
+func WithInternalMessage(message string) Option {
+	return func(p *estpb.ExtendedStatus) {
+		if p.InternalReport == nil {
+			p.InternalReport = &estpb.ExtendedStatus_Report{}
+		}
+		p.InternalReport.Message = message
+	}
+}
+
+// WithExternalMessage replaces the message of the external report. Its
+// instructions and message key are kept.
+func WithExternalMessage(message string) Option {
+	return func(p *estpb.ExtendedStatus) {
+		if p.ExternalReport == nil {
+			p.ExternalReport = &estpb.ExtendedStatus_Report{}
+		}
+		p.ExternalReport.Message = message
+	}
+}
+
+// WithUserMessageKey sets the message key of the external report and its
+// arguments, so that frontends can show the message in the language of the
+// user. Arguments are converted to strings with fmt.Sprint.
+//
+// The external message should still be set, it is shown if the key cannot be
+// rendered. Example:
+//
+//	return nil, extstatus.New("ai.intrinsic.my_service", 2343,
+//		&extstatus.Info{ExternalMessage: fmt.Sprintf("Skill %q not found", id)}).
+//		With(extstatus.WithUserMessageKey("ai.intrinsic.my_service.skill_not_found", id)).Err()
+func WithUserMessageKey(key string, args ...any) Option {
+	mk := &estpb.ExtendedStatus_MessageKey{Key: key}
+	for _, arg := range args {
+		mk.Args = append(mk.Args, fmt.Sprint(arg))
+	}
+	return func(p *estpb.ExtendedStatus) {
+		if p.ExternalReport == nil {
+			p.ExternalReport = &estpb.ExtendedStatus_Report{}
+		}
+		p.ExternalReport.MessageKey = proto.Clone(mk).(*estpb.ExtendedStatus_MessageKey)
+	}
+}
+
+// WithContext appends copies of the given statuses to the context.
+func WithContext(context ...*estpb.ExtendedStatus) Option {
+	return func(p *estpb.ExtendedStatus) {
+		for _, c := range context {
+			p.Context = append(p.Context, proto.Clone(c).(*estpb.ExtendedStatus))
+		}
+	}
+}
+
+// WithContextFromErrors appends the given errors to the context, like
+// Info.ContextFromErrors does for New.
+func WithContextFromErrors(errs ...error) Option {
+	return func(p *estpb.ExtendedStatus) {
+		for _, err := range errs {
+			p.Context = append(p.Context, proto.Clone(contextFromError(err).Proto()).(*estpb.ExtendedStatus))
+		}
+	}
+}
+
+// WithLogContext relates the status to the given log context. Other relations
+// are kept.
+func WithLogContext(logContext *ctxpb.Context) Option {
+	return func(p *estpb.ExtendedStatus) {
+		if p.RelatedTo == nil {
+			p.RelatedTo = &estpb.ExtendedStatus_Relations{}
+		}
+		p.RelatedTo.LogContext = proto.Clone(logContext).(*ctxpb.Context)
+	}
+}
+
+// WithSeverity replaces the severity.
+func WithSeverity(severity estpb.ExtendedStatus_Severity) Option {
+	return func(p *estpb.ExtendedStatus) {
+		p.Severity = severity
+	}
+}
+
+// WithMinSeverity raises the severity to at least the given severity. A higher
+// severity is kept.
+func WithMinSeverity(severity estpb.ExtendedStatus_Severity) Option {
+	return func(p *estpb.ExtendedStatus) {
+		if p.Severity < severity {
+			p.Severity = severity
+		}
+	}
+}
+
+// WithStackTrace records the call stack at which the option is created into
+// the debug report, replacing a stack recorded before. Example:
+//
+//	return es.With(extstatus.WithStackTrace()).Err()
+func WithStackTrace() Option {
+	trace := stackTrace(1)
+	return func(p *estpb.ExtendedStatus) {
+		debugReport(p).StackTrace = trace
+	}
+}
+
+// WithErrorChain records err and all errors it wraps into the message of the
+// debug report, one per line with its type, so that the origin of an error
+// which was wrapped on its way remains visible.
+func WithErrorChain(err error) Option {
+	chain := errorChain(err)
+	return func(p *estpb.ExtendedStatus) {
+		debugReport(p).Message = chain
+	}
+}
+
+// WithCompact reduces the context, see Compact for details.
+func WithCompact(opts *CompactOptions) Option {
+	return func(p *estpb.ExtendedStatus) {
+		if opts != nil {
+			compact(p, opts)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package extstatus
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	ctxpb "intrinsic/logging/proto/context_go_proto"
+	estpb "intrinsic/util/status/extended_status_go_proto"
+)
+
+func TestWith(t *testing.T) {
+	downstream := FromProto(&estpb.ExtendedStatus{
+		StatusCode: &estpb.StatusCode{Component: "ai.intrinsic.downstream", Code: 1},
+		Severity:   estpb.ExtendedStatus_WARNING,
+		Title:      "downstream failed",
+		InternalReport: &estpb.ExtendedStatus_Report{
+			Message:      "internal",
+			Instructions: "check the logs",
+		},
+	})
+	original := proto.Clone(downstream.Proto())
+
+	got := downstream.With(
+		WithTitle("request failed"),
+		WithInternalMessage("internal details"),
+		WithExternalMessage("external"),
+		WithUserMessageKey("ai.intrinsic.downstream.failed", "foo", 2),
+		WithMinSeverity(estpb.ExtendedStatus_ERROR),
+		WithContextFromErrors(NewError("ai.intrinsic.other", 2, &Info{Title: "other"})),
+		WithLogContext(&ctxpb.Context{ExecutiveSessionId: 5}),
+	)
+
+	want := &estpb.ExtendedStatus{
+		StatusCode: &estpb.StatusCode{Component: "ai.intrinsic.downstream", Code: 1},
+		Severity:   estpb.ExtendedStatus_ERROR,
+		Title:      "request failed",
+		InternalReport: &estpb.ExtendedStatus_Report{
+			Message:      "internal details",
+			Instructions: "check the logs",
+		},
+		ExternalReport: &estpb.ExtendedStatus_Report{
+			Message: "external",
+			MessageKey: &estpb.ExtendedStatus_MessageKey{
+				Key:  "ai.intrinsic.downstream.failed",
+				Args: []string{"foo", "2"},
+			},
+		},
+		Context: []*estpb.ExtendedStatus{{
+			StatusCode: &estpb.StatusCode{Component: "ai.intrinsic.other", Code: 2},
+			Title:      "other",
+		}},
+		RelatedTo: &estpb.ExtendedStatus_Relations{
+			LogContext: &ctxpb.Context{ExecutiveSessionId: 5},
+		},
+	}
+	if diff := cmp.Diff(want, got.Proto(), protocmp.Transform()); diff != "" {
+		t.Errorf("With() returned unexpected status (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(original, downstream.Proto(), protocmp.Transform()); diff != "" {
+		t.Errorf("With() modified the original status (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithMinSeverity(t *testing.T) {
+	es := New("ai.intrinsic.test", 1, &Info{}).With(WithSeverity(estpb.ExtendedStatus_FATAL))
+	if got := es.With(WithMinSeverity(estpb.ExtendedStatus_WARNING)).Proto().GetSeverity(); got != estpb.ExtendedStatus_FATAL {
+		t.Errorf("WithMinSeverity(WARNING) changed severity FATAL to %v", got)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package extstatus
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	estpb "intrinsic/util/status/extended_status_go_proto"
+)
+
+// maxStackDepth is the maximum number of frames recorded in a stack trace.
+const maxStackDepth = 64
+
+var captureStackTraces atomic.Bool
+
+// SetStackTraceCapture enables or disables recording the call stack into the
+// debug report of every status created with New or NewError. It is disabled
+// by default, since capturing the stack is comparatively expensive. Use
+// WithStackTrace to record the stack of individual statuses.
+func SetStackTraceCapture(enabled bool) {
+	captureStackTraces.Store(enabled)
+}
+
+// stackTrace returns the call stack of its caller, skipping skip further
+// frames, innermost frame first.
+func stackTrace(skip int) []*estpb.ExtendedStatus_StackFrame {
+	pcs := make([]uintptr, maxStackDepth)
+	// Skip runtime.Callers and stackTrace.
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var trace []*estpb.ExtendedStatus_StackFrame
+	for {
+		f, more := frames.Next()
+		trace = append(trace, &estpb.ExtendedStatus_StackFrame{
+			Function: f.Function,
+			File:     f.File,
+			Line:     int32(f.Line),
+		})
+		if !more {
+			break
+		}
+	}
+	return trace
+}
+
+// errorChain describes err and all errors it wraps, one per line, outermost
+// error first.
+func errorChain(err error) string {
+	var lines []string
+	var walk func(err error, depth int)
+	walk = func(err error, depth int) {
+		if err == nil {
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s%T: %v", strings.Repeat("  ", depth), err, err))
+		if u, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range u.Unwrap() {
+				walk(e, depth+1)
+			}
+			return
+		}
+		walk(errors.Unwrap(err), depth+1)
+	}
+	walk(err, 0)
+	return strings.Join(lines, "\n")
+}
+
+func debugReport(p *estpb.ExtendedStatus) *estpb.ExtendedStatus_DebugReport {
+	if p.DebugReport == nil {
+		p.DebugReport = &estpb.ExtendedStatus_DebugReport{}
+	}
+	return p.DebugReport
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Intrinsic Innovation LLC
+
+package extstatus
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	estpb "intrinsic/util/status/extended_status_go_proto"
+)
+
+// topFunction returns the function of the innermost frame of the stack trace
+// in the debug report of es.
+func topFunction(t *testing.T, es *estpb.ExtendedStatus) string {
+	t.Helper()
+	trace := es.GetDebugReport().GetStackTrace()
+	if len(trace) == 0 {
+		t.Fatalf("status has no stack trace: %v", es)
+	}
+	return trace[0].GetFunction()
+}
+
+func TestWithStackTrace(t *testing.T) {
+	es := New("ai.intrinsic.test", 1, &Info{Title: "failed"})
+	if es.Proto().GetDebugReport() != nil {
+		t.Fatalf("New() recorded a debug report without stack trace capture: %v", es.Proto())
+	}
+
+	got := es.With(WithStackTrace()).Proto()
+	if fn := topFunction(t, got); !strings.HasSuffix(fn, ".TestWithStackTrace") {
+		t.Errorf("WithStackTrace() recorded innermost function %q, want TestWithStackTrace", fn)
+	}
+	if line := got.GetDebugReport().GetStackTrace()[0].GetLine(); line <= 0 {
+		t.Errorf("WithStackTrace() recorded line %d, want a positive line", line)
+	}
+}
+
+func TestSetStackTraceCapture(t *testing.T) {
+	SetStackTraceCapture(true)
+	t.Cleanup(func() { SetStackTraceCapture(false) })
+
+	for _, tc := range []struct {
+		name string
+		es   *estpb.ExtendedStatus
+	}{
+		{
+			name: "New",
+			es:   New("ai.intrinsic.test", 1, &Info{Title: "failed"}).Proto(),
+		},
+		{
+			name: "NewError",
+			es: func() *estpb.ExtendedStatus {
+				es, err := FromError(NewError("ai.intrinsic.test", 1, &Info{Title: "failed"}))
+				if err != nil {
+					t.Fatalf("FromError() failed: %v", err)
+				}
+				return es.Proto()
+			}(),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if fn := topFunction(t, tc.es); !strings.Contains(fn, ".TestSetStackTraceCapture") {
+				t.Errorf("%s recorded innermost function %q, want TestSetStackTraceCapture", tc.name, fn)
+			}
+		})
+	}
+}
+
+type testError struct{}
+
+func (testError) Error() string { return "disk full" }
+
+func TestWithErrorChain(t *testing.T) {
+	err := fmt.Errorf("write state: %w", errors.Join(testError{}, errors.New("retry failed")))
+
+	got := New("ai.intrinsic.test", 1, &Info{}).With(WithErrorChain(err)).Proto().GetDebugReport().GetMessage()
+
+	want := strings.Join([]string{
+		"*fmt.wrapError: write state: disk full\nretry failed",
+		"  *errors.joinError: disk full\nretry failed",
+		"    extstatus.testError: disk full",
+		"    *errors.errorString: retry failed",
+	}, "\n")
+	if got != want {
+		t.Errorf("WithErrorChain() recorded message\n%s\nwant\n%s", got, want)
+	}
+}